@@ -0,0 +1,244 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// conditions that raise an alert
+const (
+	MasterQuorumLost = "master_quorum_lost"
+	VolumeDiskFull   = "volume_disk_full"
+	ChecksumError    = "checksum_error"
+)
+
+const RateLimitInterval = 5 * time.Minute
+
+var (
+	PagerDutyEventsUrl = "https://events.pagerduty.com/v2/enqueue"
+	OpsgenieAlertsUrl  = "https://api.opsgenie.com/v2/alerts"
+)
+
+type Alert struct {
+	Condition string
+	Source    string
+	Summary   string
+	Details   map[string]string
+}
+
+type Alerter struct {
+	pagerDutyKey string
+	opsgenieKey  string
+	source       string
+	client       *http.Client
+
+	lastSentLock sync.Mutex
+	lastSent     map[string]time.Time
+}
+
+var defaultAlerter = &Alerter{}
+
+// Configure sets up the process wide alerter. Alerts are dropped if both keys are empty.
+func Configure(pagerDutyKey, opsgenieKey, source string) {
+	defaultAlerter = NewAlerter(pagerDutyKey, opsgenieKey, source)
+	if defaultAlerter.IsEnabled() {
+		glog.V(0).Infof("alerting enabled for %s, pagerduty:%v opsgenie:%v", source, pagerDutyKey != "", opsgenieKey != "")
+	}
+}
+
+// Raise sends an alert in the background, at most once per condition every RateLimitInterval.
+func Raise(condition string, summary string, details map[string]string) {
+	a := defaultAlerter
+	if !a.IsEnabled() || !a.allow(condition, time.Now()) {
+		return
+	}
+	go func() {
+		if err := a.Send(&Alert{
+			Condition: condition,
+			Source:    a.source,
+			Summary:   summary,
+			Details:   details,
+		}); err != nil {
+			glog.Errorf("send alert %s: %v", condition, err)
+		}
+	}()
+}
+
+// Resolve closes the alert raised for the condition, and lets the next Raise send it right away.
+// It does nothing if the condition was not raised.
+func Resolve(condition string, summary string) {
+	a := defaultAlerter
+	if !a.IsEnabled() || !a.clear(condition) {
+		return
+	}
+	go func() {
+		if err := a.SendResolve(&Alert{
+			Condition: condition,
+			Source:    a.source,
+			Summary:   summary,
+		}); err != nil {
+			glog.Errorf("resolve alert %s: %v", condition, err)
+		}
+	}()
+}
+
+func NewAlerter(pagerDutyKey, opsgenieKey, source string) *Alerter {
+	return &Alerter{
+		pagerDutyKey: pagerDutyKey,
+		opsgenieKey:  opsgenieKey,
+		source:       source,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		lastSent:     make(map[string]time.Time),
+	}
+}
+
+func (a *Alerter) IsEnabled() bool {
+	return a.pagerDutyKey != "" || a.opsgenieKey != ""
+}
+
+func (a *Alerter) allow(condition string, now time.Time) bool {
+	a.lastSentLock.Lock()
+	defer a.lastSentLock.Unlock()
+	if last, found := a.lastSent[condition]; found && now.Sub(last) < RateLimitInterval {
+		return false
+	}
+	a.lastSent[condition] = now
+	return true
+}
+
+// clear returns whether the condition was raised.
+func (a *Alerter) clear(condition string) bool {
+	a.lastSentLock.Lock()
+	defer a.lastSentLock.Unlock()
+	_, found := a.lastSent[condition]
+	delete(a.lastSent, condition)
+	return found
+}
+
+// Send posts the alert to every configured service, without rate limiting.
+func (a *Alerter) Send(alert *Alert) error {
+	var lastErr error
+	if a.pagerDutyKey != "" {
+		if err := a.post(PagerDutyEventsUrl, "", pagerDutyEvent(a.pagerDutyKey, alert)); err != nil {
+			lastErr = fmt.Errorf("pagerduty: %v", err)
+		}
+	}
+	if a.opsgenieKey != "" {
+		if err := a.post(OpsgenieAlertsUrl, "GenieKey "+a.opsgenieKey, opsgenieAlert(alert)); err != nil {
+			lastErr = fmt.Errorf("opsgenie: %v", err)
+		}
+	}
+	return lastErr
+}
+
+// SendResolve closes the alert on every configured service.
+func (a *Alerter) SendResolve(alert *Alert) error {
+	var lastErr error
+	if a.pagerDutyKey != "" {
+		event := pagerDutyEvent(a.pagerDutyKey, alert)
+		event.EventAction = "resolve"
+		if err := a.post(PagerDutyEventsUrl, "", event); err != nil {
+			lastErr = fmt.Errorf("pagerduty: %v", err)
+		}
+	}
+	if a.opsgenieKey != "" {
+		closeUrl := OpsgenieAlertsUrl + "/" + url.PathEscape(alert.Source+"/"+alert.Condition) + "/close?identifierType=alias"
+		if err := a.post(closeUrl, "GenieKey "+a.opsgenieKey, &OpsgenieClose{Source: alert.Source, Note: alert.Summary}); err != nil {
+			lastErr = fmt.Errorf("opsgenie: %v", err)
+		}
+	}
+	return lastErr
+}
+
+func (a *Alerter) post(url string, authorization string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
+
+// https://developer.pagerduty.com/docs/events-api-v2/trigger-events/
+type PagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     PagerDutyPayload `json:"payload"`
+}
+
+type PagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func pagerDutyEvent(routingKey string, alert *Alert) *PagerDutyEvent {
+	return &PagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: "trigger",
+		DedupKey:    alert.Source + "/" + alert.Condition,
+		Payload: PagerDutyPayload{
+			Summary:       alert.Summary,
+			Source:        alert.Source,
+			Severity:      "critical",
+			Component:     "seaweedfs",
+			CustomDetails: alert.Details,
+		},
+	}
+}
+
+// https://docs.opsgenie.com/docs/alert-api#create-alert
+type OpsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"`
+	Tags        []string          `json:"tags"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+func opsgenieAlert(alert *Alert) *OpsgenieAlert {
+	return &OpsgenieAlert{
+		Message:     alert.Summary,
+		Alias:       alert.Source + "/" + alert.Condition,
+		Description: fmt.Sprintf("%s on %s: %s", alert.Condition, alert.Source, alert.Summary),
+		Source:      alert.Source,
+		Priority:    "P1",
+		Tags:        []string{"seaweedfs", alert.Condition},
+		Details:     alert.Details,
+	}
+}
+
+// https://docs.opsgenie.com/docs/alert-api#close-alert
+type OpsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
@@ -0,0 +1,129 @@
+package alert
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendPayload(t *testing.T) {
+
+	received := make(map[string]map[string]interface{})
+	authorizations := make(map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s request with content type %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := make(map[string]interface{})
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("unmarshal %s: %v", string(body), err)
+		}
+		received[r.URL.Path] = payload
+		authorizations[r.URL.Path] = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	PagerDutyEventsUrl = ts.URL + "/pagerduty"
+	OpsgenieAlertsUrl = ts.URL + "/opsgenie"
+
+	a := NewAlerter("pdkey", "ogkey", "volume@10.0.0.1:8080")
+	err := a.Send(&Alert{
+		Condition: VolumeDiskFull,
+		Source:    "volume@10.0.0.1:8080",
+		Summary:   "disk /data is full",
+		Details:   map[string]string{"dir": "/data"},
+	})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	pd := received["/pagerduty"]
+	if pd["routing_key"] != "pdkey" || pd["event_action"] != "trigger" || pd["dedup_key"] != "volume@10.0.0.1:8080/volume_disk_full" {
+		t.Errorf("unexpected pagerduty event: %+v", pd)
+	}
+	pdPayload, _ := pd["payload"].(map[string]interface{})
+	if pdPayload["summary"] != "disk /data is full" || pdPayload["severity"] != "critical" || pdPayload["source"] != "volume@10.0.0.1:8080" {
+		t.Errorf("unexpected pagerduty payload: %+v", pdPayload)
+	}
+	if details, _ := pdPayload["custom_details"].(map[string]interface{}); details["dir"] != "/data" {
+		t.Errorf("unexpected pagerduty custom details: %+v", pdPayload["custom_details"])
+	}
+
+	og := received["/opsgenie"]
+	if authorizations["/opsgenie"] != "GenieKey ogkey" {
+		t.Errorf("unexpected opsgenie authorization: %s", authorizations["/opsgenie"])
+	}
+	if og["message"] != "disk /data is full" || og["alias"] != "volume@10.0.0.1:8080/volume_disk_full" || og["priority"] != "P1" {
+		t.Errorf("unexpected opsgenie alert: %+v", og)
+	}
+
+}
+
+func TestRateLimit(t *testing.T) {
+
+	a := NewAlerter("pdkey", "", "master")
+	now := time.Now()
+
+	if !a.allow(MasterQuorumLost, now) {
+		t.Errorf("first alert should be allowed")
+	}
+	if a.allow(MasterQuorumLost, now.Add(time.Minute)) {
+		t.Errorf("repeated alert within %v should be dropped", RateLimitInterval)
+	}
+	if !a.allow(ChecksumError, now.Add(time.Minute)) {
+		t.Errorf("other conditions should not be limited")
+	}
+	if !a.allow(MasterQuorumLost, now.Add(RateLimitInterval)) {
+		t.Errorf("alert after %v should be allowed", RateLimitInterval)
+	}
+
+}
+
+func TestResolve(t *testing.T) {
+
+	received := make(map[string]map[string]interface{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := make(map[string]interface{})
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("unmarshal %s: %v", string(body), err)
+		}
+		received[r.URL.EscapedPath()+"?"+r.URL.RawQuery] = payload
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	PagerDutyEventsUrl = ts.URL + "/pagerduty"
+	OpsgenieAlertsUrl = ts.URL + "/opsgenie"
+
+	a := NewAlerter("pdkey", "ogkey", "master")
+	if err := a.SendResolve(&Alert{Condition: MasterQuorumLost, Source: "master", Summary: "leader elected"}); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	pd := received["/pagerduty?"]
+	if pd["event_action"] != "resolve" || pd["dedup_key"] != "master/master_quorum_lost" {
+		t.Errorf("unexpected pagerduty event: %+v", pd)
+	}
+	og, found := received["/opsgenie/master%2Fmaster_quorum_lost/close?identifierType=alias"]
+	if !found || og["source"] != "master" {
+		t.Errorf("unexpected opsgenie requests: %+v", received)
+	}
+
+	if a.clear(MasterQuorumLost) {
+		t.Errorf("condition not raised should not be cleared")
+	}
+	a.allow(MasterQuorumLost, time.Now())
+	if !a.clear(MasterQuorumLost) {
+		t.Errorf("raised condition should be cleared")
+	}
+	if !a.allow(MasterQuorumLost, time.Now()) {
+		t.Errorf("alert after clearing should be allowed")
+	}
+
+}
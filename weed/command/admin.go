@@ -0,0 +1,326 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/shell"
+	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func init() {
+	cmdAdmin.Run = runAdmin // break init cycle
+}
+
+var cmdAdmin = &Command{
+	UsageLine: "admin status|logs|reassign -master=localhost:9333",
+	Short:     "print a health summary of the cluster, stream the logs of all servers, or move chunks off a volume",
+	Long: `Print a health summary of the cluster, for monitoring plugins such as nagios or icinga.
+
+  weed admin status -master=localhost:9333,localhost:9334,localhost:9335
+
+  The first line is the overall state, followed by a summary table.
+  The exit code follows the nagios plugin convention:
+    0  OK        all masters and volume servers respond, and all volumes have all their replicas
+    1  DEGRADED  a master or a volume server does not respond, or some volumes are under-replicated
+    2  CRITICAL  the masters have no leader, or less than a quorum of masters respond
+
+  Volume servers are counted offline if they are registered with the master but do not respond.
+  Volume servers that lost the heartbeat to the master are not known to the master, so use
+  -volumeServers to also count the missing ones as offline.
+
+  weed admin logs -master=localhost:9333 -follow -level=WARNING -component=volume,filer
+
+  Print the recent logs of all masters, volume servers, and filers, each line prefixed with [host:port].
+  The servers keep the last 1000 log lines in memory. With -follow, keep printing the new lines.
+  Only the lines logged at the servers' own -v verbosity are available.
+
+  weed admin reassign -master=localhost:9333 -filer=localhost:8888 -vid=42 -path=/data -dryRun
+
+  Move the file chunks stored on volume 42 to newly assigned volumes, for the files under -path,
+  to empty the volume before it is decommissioned. This is "fs.reassign" of "weed shell", holding
+  the admin lock while it runs. Mark the volume readonly first, so the new chunks go elsewhere.
+  With -dryRun, only list the files with chunks on the volume.
+
+  `,
+}
+
+var (
+	adminMaster           = cmdAdmin.Flag.String("master", "localhost:9333", "comma-separated master servers")
+	adminVolumeServers    = cmdAdmin.Flag.Int("volumeServers", 0, "the expected number of volume servers, if positive")
+	adminGarbageThreshold = cmdAdmin.Flag.Float64("garbageThreshold", 0.3, "count the volumes with more garbage than this ratio as needing vacuum")
+	adminTimeout          = cmdAdmin.Flag.Duration("timeout", 5*time.Second, "timeout to contact each server")
+	adminLogsFollow       = cmdAdmin.Flag.Bool("follow", false, "logs: keep printing new log lines")
+	adminLogsLevel        = cmdAdmin.Flag.String("level", "INFO", "logs: the minimum log level, INFO, WARNING, ERROR, or FATAL")
+	adminLogsComponent    = cmdAdmin.Flag.String("component", "", "logs: comma-separated server types, master, volume, or filer, default to all")
+	adminLogsTail         = cmdAdmin.Flag.Int("tail", 100, "logs: the number of recent log lines from each server, -1 for all")
+	adminReassignFiler    = cmdAdmin.Flag.String("filer", "localhost:8888", "reassign: filer host and port, or host:port.grpcPort for a filer with -port.grpc")
+	adminReassignVid      = cmdAdmin.Flag.Uint("vid", 0, "reassign: the volume id to move chunks away from")
+	adminReassignPath     = cmdAdmin.Flag.String("path", "/", "reassign: only move the chunks of the files under this directory")
+	adminReassignDryRun   = cmdAdmin.Flag.Bool("dryRun", false, "reassign: only list the files with chunks on the volume")
+)
+
+const (
+	adminStatusOk       = 0
+	adminStatusDegraded = 1
+	adminStatusCritical = 2
+)
+
+var adminStatusNames = []string{"OK", "DEGRADED", "CRITICAL"}
+
+type adminClusterStatus struct {
+	IsLeader bool     `json:"IsLeader,omitempty"`
+	Leader   string   `json:"Leader,omitempty"`
+	Peers    []string `json:"Peers,omitempty"`
+}
+
+type adminStatusReport struct {
+	status   int
+	problems []string
+	rows     [][2]string
+}
+
+func (r *adminStatusReport) degrade(status int, format string, a ...interface{}) {
+	if status > r.status {
+		r.status = status
+	}
+	r.problems = append(r.problems, fmt.Sprintf(format, a...))
+}
+
+func (r *adminStatusReport) addRow(name string, format string, a ...interface{}) {
+	r.rows = append(r.rows, [2]string{name, fmt.Sprintf(format, a...)})
+}
+
+func runAdmin(cmd *Command, args []string) bool {
+
+	// allow "weed admin status -master=..." with the flags after the sub command
+	if len(args) > 0 && (args[0] == "status" || args[0] == "logs" || args[0] == "reassign") {
+		cmd.Flag.Parse(args[1:])
+		args = append([]string{args[0]}, cmd.Flag.Args()...)
+	}
+	if len(args) != 1 || (args[0] != "status" && args[0] != "logs" && args[0] != "reassign") {
+		return false
+	}
+
+	util.LoadConfiguration("security", false)
+	grpcDialOption := security.LoadClientTLS(util.GetViper(), "grpc.client")
+
+	if args[0] == "logs" {
+		if err := runAdminLogs(grpcDialOption); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return true
+	}
+
+	if args[0] == "reassign" {
+		if err := runAdminReassign(grpcDialOption); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return true
+	}
+
+	report := &adminStatusReport{}
+	if leader := adminCheckMasters(report, strings.Split(*adminMaster, ",")); leader != "" {
+		adminCheckTopology(report, leader, grpcDialOption)
+	}
+
+	fmt.Printf("%s", adminStatusNames[report.status])
+	if len(report.problems) > 0 {
+		fmt.Printf(" - %s", strings.Join(report.problems, "; "))
+	}
+	fmt.Println()
+	for _, row := range report.rows {
+		fmt.Printf("  %-26s %s\n", row[0], row[1])
+	}
+
+	os.Exit(report.status)
+	return true
+}
+
+// adminCheckMasters returns the leader, or empty if the masters have no leader.
+func adminCheckMasters(report *adminStatusReport, masters []string) (leader string) {
+	client := &http.Client{Timeout: *adminTimeout}
+	responded, clusterSize := 0, len(masters)
+	for _, master := range masters {
+		status, err := adminGetClusterStatus(client, master)
+		if err != nil {
+			report.degrade(adminStatusDegraded, "master %s: %v", master, err)
+			continue
+		}
+		responded++
+		if len(status.Peers)+1 > clusterSize {
+			clusterSize = len(status.Peers) + 1
+		}
+		if status.IsLeader {
+			leader = master
+		} else if leader == "" {
+			leader = status.Leader
+		}
+	}
+
+	report.addRow("masters responding", "%d/%d", responded, clusterSize)
+	if leader == "" {
+		report.addRow("master leader", "none")
+		report.degrade(adminStatusCritical, "no master leader")
+		return ""
+	}
+	report.addRow("master leader", "%s", leader)
+	if responded <= clusterSize/2 {
+		report.degrade(adminStatusCritical, "only %d of %d masters respond, no quorum", responded, clusterSize)
+	}
+	return leader
+}
+
+func adminGetClusterStatus(client *http.Client, master string) (status *adminClusterStatus, err error) {
+	resp, err := client.Get("http://" + pb.ServerToHttpAddress(master) + "/cluster/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	status = &adminClusterStatus{}
+	if err = json.Unmarshal(data, status); err != nil {
+		return nil, fmt.Errorf("parse cluster status: %v", err)
+	}
+	return status, nil
+}
+
+func adminCheckTopology(report *adminStatusReport, leader string, grpcDialOption grpc.DialOption) {
+
+	var resp *master_pb.VolumeListResponse
+	err := pb.WithMasterClient(leader, grpcDialOption, func(client master_pb.SeaweedClient) error {
+		ctx, cancel := context.WithTimeout(context.Background(), *adminTimeout)
+		defer cancel()
+		var listErr error
+		resp, listErr = client.VolumeList(ctx, &master_pb.VolumeListRequest{})
+		return listErr
+	})
+	if err != nil {
+		report.degrade(adminStatusCritical, "list volumes from %s: %v", leader, err)
+		return
+	}
+
+	volumeSizeLimit := resp.VolumeSizeLimitMb * 1024 * 1024
+	var servers []string
+	var maxVolumeCount, usedBytes, vacuumCount, readOnlyCount uint64
+	replicas := make(map[uint32]int)
+	copyCounts := make(map[uint32]int)
+	for _, dc := range resp.TopologyInfo.DataCenterInfos {
+		for _, rack := range dc.RackInfos {
+			for _, dn := range rack.DataNodeInfos {
+				servers = append(servers, dn.Id)
+				maxVolumeCount += dn.MaxVolumeCount
+				for _, v := range dn.VolumeInfos {
+					usedBytes += v.Size
+					if v.Size > 0 && float64(v.DeletedByteCount) > *adminGarbageThreshold*float64(v.Size) {
+						vacuumCount++
+					}
+					if v.ReadOnly {
+						readOnlyCount++
+					}
+					replicas[v.Id]++
+					if rp, err := super_block.NewReplicaPlacementFromByte(byte(v.ReplicaPlacement)); err == nil {
+						copyCounts[v.Id] = rp.GetCopyCount()
+					}
+				}
+			}
+		}
+	}
+
+	offline := 0
+	for _, server := range servers {
+		if err := adminPingVolumeServer(server, grpcDialOption); err != nil {
+			offline++
+			report.degrade(adminStatusDegraded, "volume server %s: %v", server, err)
+		}
+	}
+	expected := len(servers)
+	if *adminVolumeServers > expected {
+		missing := *adminVolumeServers - expected
+		offline += missing
+		expected = *adminVolumeServers
+		report.degrade(adminStatusDegraded, "%d volume servers not registered with the master", missing)
+	}
+	report.addRow("volume servers", "%d online, %d offline", expected-offline, offline)
+
+	capacity := maxVolumeCount * volumeSizeLimit
+	usedPercent := 0.0
+	if capacity > 0 {
+		usedPercent = float64(usedBytes) * 100 / float64(capacity)
+	}
+	report.addRow("capacity", "%s", util.BytesToHumanReadable(capacity))
+	report.addRow("used", "%s (%.1f%%)", util.BytesToHumanReadable(usedBytes), usedPercent)
+
+	underReplicated := 0
+	for vid, count := range replicas {
+		if count < copyCounts[vid] {
+			underReplicated++
+		}
+	}
+	report.addRow("volumes", "%d", len(replicas))
+	report.addRow("volumes needing vacuum", "%d", vacuumCount)
+	report.addRow("volumes under-replicated", "%d", underReplicated)
+	report.addRow("read-only volume replicas", "%d", readOnlyCount)
+	if underReplicated > 0 {
+		report.degrade(adminStatusDegraded, "%d volumes under-replicated", underReplicated)
+	}
+}
+
+func adminPingVolumeServer(server string, grpcDialOption grpc.DialOption) error {
+	return operation.WithVolumeServerClient(server, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		ctx, cancel := context.WithTimeout(context.Background(), *adminTimeout)
+		defer cancel()
+		_, err := client.VolumeServerStatus(ctx, &volume_server_pb.VolumeServerStatusRequest{})
+		return err
+	})
+}
+
+// runAdminReassign runs fs.reassign of weed shell with the flags of weed admin.
+func runAdminReassign(grpcDialOption grpc.DialOption) (err error) {
+	if *adminReassignVid == 0 {
+		return fmt.Errorf("missing -vid")
+	}
+
+	options := shell.ShellOptions{
+		Masters:        adminMaster,
+		GrpcDialOption: grpcDialOption,
+		Directory:      "/",
+	}
+	options.FilerHost, options.FilerPort, err = util.ParseHostPort(pb.ServerToHttpAddress(*adminReassignFiler))
+	if err != nil {
+		return fmt.Errorf("parse filer %s: %v", *adminReassignFiler, err)
+	}
+	if options.FilerGrpcAddress, err = pb.ParseFilerGrpcAddress(*adminReassignFiler); err != nil {
+		return fmt.Errorf("parse filer %s: %v", *adminReassignFiler, err)
+	}
+
+	args := []string{"fs.reassign", fmt.Sprintf("-volumeId=%d", *adminReassignVid)}
+	if *adminReassignDryRun {
+		args = append(args, "-dryRun")
+	}
+	args = append(args, *adminReassignPath)
+
+	return shell.RunCommand(options, args, os.Stdout)
+}
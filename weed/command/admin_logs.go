@@ -0,0 +1,196 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+)
+
+type adminLogSource struct {
+	component string // master, volume, or filer
+	address   string // <host>:<port>, or <host>:<port>.<grpcPort> if the grpc port is not 10000 higher
+}
+
+func runAdminLogs(grpcDialOption grpc.DialOption) error {
+	if _, err := glog.ParseLevel(*adminLogsLevel); err != nil {
+		return err
+	}
+	components := make(map[string]bool)
+	for _, component := range strings.Split(*adminLogsComponent, ",") {
+		switch component = strings.TrimSpace(component); component {
+		case "":
+		case "master", "volume", "filer":
+			components[component] = true
+		default:
+			return fmt.Errorf("unknown component %q, expect master, volume, or filer", component)
+		}
+	}
+
+	sources, err := adminDiscoverLogSources(grpcDialOption, strings.Split(*adminMaster, ","))
+	if err != nil {
+		return err
+	}
+
+	req := &master_pb.StreamLogsRequest{
+		Level:  *adminLogsLevel,
+		Follow: *adminLogsFollow,
+		Tail:   int32(*adminLogsTail),
+	}
+	var printLock sync.Mutex
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		if len(components) > 0 && !components[source.component] {
+			continue
+		}
+		wg.Add(1)
+		go func(source adminLogSource) {
+			defer wg.Done()
+			prefix := fmt.Sprintf("[%s] ", source.address)
+			err := adminStreamLogs(grpcDialOption, source, req, func(lines []string) {
+				printLock.Lock()
+				defer printLock.Unlock()
+				for _, line := range lines {
+					fmt.Printf("%s%s\n", prefix, strings.Replace(line, "\n", "\n"+prefix, -1))
+				}
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s%s logs: %v\n", prefix, source.component, err)
+			}
+		}(source)
+	}
+	wg.Wait()
+	return nil
+}
+
+// adminDiscoverLogSources finds all masters from the raft peers, and the volume servers and filers from the leader.
+func adminDiscoverLogSources(grpcDialOption grpc.DialOption, masters []string) (sources []adminLogSource, err error) {
+	client := &http.Client{Timeout: *adminTimeout}
+	knownMasters := make(map[string]bool)
+	leader := ""
+	for _, master := range masters {
+		knownMasters[master] = true
+		status, statusErr := adminGetClusterStatus(client, master)
+		if statusErr != nil {
+			fmt.Fprintf(os.Stderr, "master %s: %v\n", master, statusErr)
+			continue
+		}
+		for _, peer := range status.Peers {
+			knownMasters[peer] = true
+		}
+		if status.IsLeader {
+			leader = master
+		} else if leader == "" {
+			leader = status.Leader
+		}
+	}
+	if leader == "" {
+		return nil, fmt.Errorf("no master leader found from %v", masters)
+	}
+	knownMasters[leader] = true
+
+	for master := range knownMasters {
+		sources = append(sources, adminLogSource{component: "master", address: master})
+	}
+
+	var volumeServers, filers []string
+	err = pb.WithMasterClient(leader, grpcDialOption, func(client master_pb.SeaweedClient) error {
+		ctx, cancel := context.WithTimeout(context.Background(), *adminTimeout)
+		defer cancel()
+		resp, listErr := client.VolumeList(ctx, &master_pb.VolumeListRequest{})
+		if listErr != nil {
+			return listErr
+		}
+		for _, dc := range resp.TopologyInfo.DataCenterInfos {
+			for _, rack := range dc.RackInfos {
+				for _, dn := range rack.DataNodeInfos {
+					volumeServers = append(volumeServers, dn.Id)
+				}
+			}
+		}
+		// the filers register their http address, with the grpc port if it is not 10000 higher
+		clients, listErr := client.ListMasterClients(ctx, &master_pb.ListMasterClientsRequest{ClientType: "filer"})
+		if listErr != nil {
+			return listErr
+		}
+		filers = clients.GrpcAddresses
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list servers from master %s: %v", leader, err)
+	}
+
+	for _, volumeServer := range volumeServers {
+		sources = append(sources, adminLogSource{component: "volume", address: volumeServer})
+	}
+	for _, filer := range filers {
+		sources = append(sources, adminLogSource{component: "filer", address: filer})
+	}
+
+	return sources, nil
+}
+
+func adminStreamLogs(grpcDialOption grpc.DialOption, source adminLogSource, req *master_pb.StreamLogsRequest, print func(lines []string)) error {
+	ctx := context.Background()
+	switch source.component {
+	case "master":
+		return pb.WithMasterClient(source.address, grpcDialOption, func(client master_pb.SeaweedClient) error {
+			stream, err := client.StreamLogs(ctx, req)
+			if err != nil {
+				return err
+			}
+			return adminReceiveLogs(func() ([]string, error) {
+				resp, err := stream.Recv()
+				return resp.GetLines(), err
+			}, print)
+		})
+	case "volume":
+		return operation.WithVolumeServerClient(source.address, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+			stream, err := client.StreamLogs(ctx, &volume_server_pb.StreamLogsRequest{Level: req.Level, Follow: req.Follow, Tail: req.Tail})
+			if err != nil {
+				return err
+			}
+			return adminReceiveLogs(func() ([]string, error) {
+				resp, err := stream.Recv()
+				return resp.GetLines(), err
+			}, print)
+		})
+	case "filer":
+		return pb.WithFilerClient(source.address, grpcDialOption, func(client filer_pb.SeaweedFilerClient) error {
+			stream, err := client.StreamLogs(ctx, &filer_pb.StreamLogsRequest{Level: req.Level, Follow: req.Follow, Tail: req.Tail})
+			if err != nil {
+				return err
+			}
+			return adminReceiveLogs(func() ([]string, error) {
+				resp, err := stream.Recv()
+				return resp.GetLines(), err
+			}, print)
+		})
+	}
+	return fmt.Errorf("unknown component %s", source.component)
+}
+
+func adminReceiveLogs(recv func() ([]string, error), print func(lines []string)) error {
+	for {
+		lines, err := recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		print(lines)
+	}
+}
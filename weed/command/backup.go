@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/security/kms"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
 	"github.com/chrislusf/seaweedfs/weed/util"
@@ -65,6 +66,7 @@ func runBackup(cmd *Command, args []string) bool {
 
 	util.LoadConfiguration("security", false)
 	grpcDialOption := security.LoadClientTLS(util.GetViper(), "grpc.client")
+	kms.LoadConfiguration(util.GetViper(), "volume.encryption.")
 
 	if *s.volumeId == -1 {
 		return false
@@ -77,7 +79,7 @@ func runBackup(cmd *Command, args []string) bool {
 		fmt.Printf("Error looking up volume %d: %v\n", vid, err)
 		return true
 	}
-	volumeServer := lookup.Locations[0].Url
+	volumeServer := lookup.Locations[0].ServerAddress()
 
 	stats, err := operation.GetVolumeSyncStatus(volumeServer, grpcDialOption, uint32(vid))
 	if err != nil {
@@ -119,7 +121,7 @@ func runBackup(cmd *Command, args []string) bool {
 	}
 
 	if v.SuperBlock.CompactionRevision < uint16(stats.CompactRevision) {
-		if err = v.Compact2(30*1024*1024*1024, 0); err != nil {
+		if err = v.Compact2(30*1024*1024*1024, util.NewWriteThrottler(0)); err != nil {
 			fmt.Printf("Compact Volume before synchronizing %v\n", err)
 			return true
 		}
@@ -143,6 +145,22 @@ func runBackup(cmd *Command, args []string) bool {
 			return true
 		}
 	}
+
+	if v.EncryptionKeyId() != stats.EncryptionKeyId {
+		// the copied needles are kept encrypted as is, with the key of the source volume
+		if v.FileCount() > 0 {
+			v.Destroy()
+			v, err = storage.NewVolume(util.ResolvePath(*s.dir), *s.collection, vid, storage.NeedleMapInMemory, replication, ttl, 0, 0)
+			if err != nil {
+				fmt.Printf("Error creating or reading from volume %d: %v\n", vid, err)
+				return true
+			}
+		}
+		if err = v.SetEncryptionKeyId(stats.EncryptionKeyId); err != nil {
+			fmt.Printf("Error setting volume %d encryption key %s: %v\n", vid, stats.EncryptionKeyId, err)
+			return true
+		}
+	}
 	defer v.Close()
 
 	if err := v.IncrementalBackup(volumeServer, grpcDialOption); err != nil {
@@ -8,6 +8,7 @@ import (
 )
 
 var Commands = []*Command{
+	cmdAdmin,
 	cmdBenchmark,
 	cmdBackup,
 	cmdCompact,
@@ -15,6 +16,9 @@ var Commands = []*Command{
 	cmdDownload,
 	cmdExport,
 	cmdFiler,
+	cmdFilerGc,
+	cmdFilerLs,
+	cmdFilerMetaBackup,
 	cmdFilerReplicate,
 	cmdFilerSynchronize,
 	cmdFix,
@@ -29,6 +33,8 @@ var Commands = []*Command{
 	cmdUpload,
 	cmdVersion,
 	cmdVolume,
+	cmdVolumeCopy,
+	cmdVolumeMirror,
 	cmdWebDav,
 }
 
@@ -47,11 +47,11 @@ func runCompact(cmd *Command, args []string) bool {
 		glog.Fatalf("Load Volume [ERROR] %s\n", err)
 	}
 	if *compactMethod == 0 {
-		if err = v.Compact(preallocate, 0); err != nil {
+		if err = v.Compact(preallocate, util.NewWriteThrottler(0)); err != nil {
 			glog.Fatalf("Compact Volume [ERROR] %s\n", err)
 		}
 	} else {
-		if err = v.Compact2(preallocate, 0); err != nil {
+		if err = v.Compact2(preallocate, util.NewWriteThrottler(0)); err != nil {
 			glog.Fatalf("Compact Volume [ERROR] %s\n", err)
 		}
 	}
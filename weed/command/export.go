@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/security/kms"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle_map"
@@ -95,6 +96,7 @@ type VolumeFileScanner4Export struct {
 	counter   int
 	needleMap *needle_map.MemDb
 	vid       needle.VolumeId
+	decrypt   func(n *needle.Needle) error
 }
 
 func (scanner *VolumeFileScanner4Export) VisitSuperBlock(superBlock super_block.SuperBlock) error {
@@ -124,6 +126,9 @@ func (scanner *VolumeFileScanner4Export) VisitNeedle(n *needle.Needle, offset in
 			return io.EOF
 		}
 		if tarOutputFile != nil {
+			if err := scanner.decrypt(n); err != nil {
+				return err
+			}
 			return writeFile(vid, n)
 		} else {
 			printNeedle(vid, n, scanner.version, false, offset, n.DiskSize(scanner.version))
@@ -204,9 +209,17 @@ func runExport(cmd *Command, args []string) bool {
 		glog.Fatalf("cannot load needle map from %s.idx: %s", fileName, err)
 	}
 
+	util.LoadConfiguration("security", false)
+	kms.LoadConfiguration(util.GetViper(), "volume.encryption.")
+	decrypt, err := storage.NeedleDecrypter(util.ResolvePath(*export.dir), *export.collection, vid)
+	if err != nil {
+		glog.Fatalf("cannot export volume %d: %v", vid, err)
+	}
+
 	volumeFileScanner := &VolumeFileScanner4Export{
 		needleMap: needleMap,
 		vid:       vid,
+		decrypt:   decrypt,
 	}
 
 	if tarOutputFile == nil {
@@ -248,8 +261,9 @@ func writeFile(vid needle.VolumeId, n *needle.Needle) (err error) {
 	if n.IsCompressed() {
 		if util.IsGzippedContent(n.Data) && path.Ext(fileName) != ".gz" {
 			fileName = fileName + ".gz"
+		} else if n.IsZstd() && path.Ext(fileName) != ".zst" {
+			fileName = fileName + ".zst"
 		}
-		// TODO other compression method
 	}
 
 	tarHeader.Name, tarHeader.Size = fileName, int64(len(n.Data))
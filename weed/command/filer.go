@@ -9,12 +9,15 @@ import (
 
 	"google.golang.org/grpc/reflection"
 
+	"github.com/chrislusf/seaweedfs/weed/alert"
+	"github.com/chrislusf/seaweedfs/weed/filer"
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/server"
 	stats_collect "github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -29,6 +32,7 @@ type FilerOptions struct {
 	ip                      *string
 	bindIp                  *string
 	port                    *int
+	portGrpc                *int
 	publicPort              *int
 	collection              *string
 	defaultReplicaPlacement *string
@@ -42,6 +46,18 @@ type FilerOptions struct {
 	cipher                  *bool
 	peers                   *string
 	metricsHttpPort         *int
+	searchEnabled           *bool
+	searchDir               *string
+	hedgeReadAfterMs        *int
+	alertPagerDutyKey       *string
+	alertOpsgenieKey        *string
+	tracingEndpoint         *string
+	twoPhaseCommit          *bool
+	pendingNeedleTtlMinutes *int
+	maxBodySizeBytes        *int64
+	maxSymlinkDepth         *int
+	tlsMinVersion           *string
+	tlsCipherSuites         *string
 
 	// default leveldb directory, used in "weed server" mode
 	defaultLevelDbDirectory *string
@@ -54,6 +70,7 @@ func init() {
 	f.ip = cmdFiler.Flag.String("ip", util.DetectedHostAddress(), "filer server http listen ip address")
 	f.bindIp = cmdFiler.Flag.String("ip.bind", "0.0.0.0", "ip address to bind to")
 	f.port = cmdFiler.Flag.Int("port", 8888, "filer server http listen port")
+	f.portGrpc = cmdFiler.Flag.Int("port.grpc", 0, "filer server grpc listen port, default to http port + 10000")
 	f.publicPort = cmdFiler.Flag.Int("port.readonly", 0, "readonly port opened to public")
 	f.defaultReplicaPlacement = cmdFiler.Flag.String("defaultReplicaPlacement", "", "default replication type. If not specified, use master setting.")
 	f.disableDirListing = cmdFiler.Flag.Bool("disableDirListing", false, "turn off directory listing")
@@ -63,8 +80,20 @@ func init() {
 	f.rack = cmdFiler.Flag.String("rack", "", "prefer to write to volumes in this rack")
 	f.disableHttp = cmdFiler.Flag.Bool("disableHttp", false, "disable http request, only gRpc operations are allowed")
 	f.cipher = cmdFiler.Flag.Bool("encryptVolumeData", false, "encrypt data on volume servers")
+	f.maxBodySizeBytes = cmdFiler.Flag.Int64("maxBodySizeBytes", 0, "if positive, reject uploads with a larger request body with 413")
+	f.maxSymlinkDepth = cmdFiler.Flag.Int("maxSymlinkDepth", 8, "follow at most this many symlinks when reading a file over http, 0 to not follow symlinks")
+	f.tlsMinVersion = cmdFiler.Flag.String("tls.minVersion", "1.2", "minimum TLS version of the grpc server: 1.0, 1.1, 1.2, or 1.3")
+	f.tlsCipherSuites = cmdFiler.Flag.String("tls.cipherSuites", "", "comma separated TLS 1.2 cipher suites of the grpc server, default to the Go defaults")
+	f.twoPhaseCommit = cmdFiler.Flag.Bool("twoPhaseCommit", false, "write files as pending on volume servers, and confirm them after the metadata is saved, so uploads interrupted by a crash do not leave orphaned files")
+	f.pendingNeedleTtlMinutes = cmdFiler.Flag.Int("twoPhaseCommit.pendingTtlMinutes", 60, "the -pendingNeedleTtlMinutes of the volume servers, uploads taking longer fail with -twoPhaseCommit")
 	f.peers = cmdFiler.Flag.String("peers", "", "all filers sharing the same filer store in comma separated ip:port list")
 	f.metricsHttpPort = cmdFiler.Flag.Int("metricsPort", 0, "Prometheus metrics listen port")
+	f.searchEnabled = cmdFiler.Flag.Bool("search.enabled", false, "maintain a full-text index of .txt, .json, and .html files, served at /filer/search?q=..., a path reserved while enabled, also on the readonly port")
+	f.searchDir = cmdFiler.Flag.String("search.dir", "", "directory to store the full-text index, default to ./filersearch")
+	f.hedgeReadAfterMs = cmdFiler.Flag.Int("hedgeReadAfterMs", 0, "if positive, also read a chunk from another replica when the first one does not respond in this many milliseconds")
+	f.alertPagerDutyKey = cmdFiler.Flag.String("alert.pagerduty.apiKey", "", "PagerDuty Events API v2 routing key to alert on critical conditions")
+	f.alertOpsgenieKey = cmdFiler.Flag.String("alert.opsgenie.apiKey", "", "Opsgenie API key to alert on critical conditions")
+	f.tracingEndpoint = cmdFiler.Flag.String("tracing.otlpEndpoint", "", "OpenTelemetry collector to export traces to with OTLP over http, e.g. localhost:4318")
 
 	// start s3 on filer
 	filerStartS3 = cmdFiler.Flag.Bool("s3", false, "whether to start S3 gateway")
@@ -100,10 +129,17 @@ func runFiler(cmd *Command, args []string) bool {
 
 	util.LoadConfiguration("security", false)
 
+	if err := security.SetServerTlsOptions(*f.tlsMinVersion, *f.tlsCipherSuites); err != nil {
+		glog.Fatalf("filer tls options: %v", err)
+	}
+
 	go stats_collect.StartMetricsServer(*f.metricsHttpPort)
 
+	alert.Configure(*f.alertPagerDutyKey, *f.alertOpsgenieKey, fmt.Sprintf("filer@%s:%d", *f.ip, *f.port))
+	tracing.Configure(*f.tracingEndpoint, "filer")
+
 	if *filerStartS3 {
-		filerAddress := fmt.Sprintf("%s:%d", *f.ip, *f.port)
+		filerAddress := pb.NewServerAddress(*f.ip, *f.port, f.grpcPort())
 		filerS3Options.filer = &filerAddress
 		go func() {
 			time.Sleep(2 * time.Second)
@@ -118,6 +154,8 @@ func runFiler(cmd *Command, args []string) bool {
 
 func (fo *FilerOptions) startFiler() {
 
+	filer.HedgeReadAfter = time.Duration(*fo.hedgeReadAfterMs) * time.Millisecond
+
 	defaultMux := http.NewServeMux()
 	publicVolumeMux := defaultMux
 
@@ -130,6 +168,17 @@ func (fo *FilerOptions) startFiler() {
 		defaultLevelDbDirectory = util.ResolvePath(*fo.defaultLevelDbDirectory + "/filerldb2")
 	}
 
+	var searchIndexDir string
+	if fo.searchEnabled != nil && *fo.searchEnabled {
+		searchIndexDir = "./filersearch"
+		if fo.defaultLevelDbDirectory != nil {
+			searchIndexDir = util.ResolvePath(*fo.defaultLevelDbDirectory + "/filersearch")
+		}
+		if fo.searchDir != nil && *fo.searchDir != "" {
+			searchIndexDir = util.ResolvePath(*fo.searchDir)
+		}
+	}
+
 	var peers []string
 	if *fo.peers != "" {
 		peers = strings.Split(*fo.peers, ",")
@@ -148,8 +197,14 @@ func (fo *FilerOptions) startFiler() {
 		DisableHttp:        *fo.disableHttp,
 		Host:               *fo.ip,
 		Port:               uint32(*fo.port),
+		GrpcPort:           uint32(fo.grpcPort()),
 		Cipher:             *fo.cipher,
 		Filers:             peers,
+		SearchIndexDir:     searchIndexDir,
+		TwoPhaseCommit:     *fo.twoPhaseCommit,
+		PendingNeedleTtl:   time.Duration(*fo.pendingNeedleTtlMinutes) * time.Minute,
+		MaxBodySizeBytes:   *fo.maxBodySizeBytes,
+		MaxSymlinkDepth:    *fo.maxSymlinkDepth,
 	})
 	if nfs_err != nil {
 		glog.Fatalf("Filer startup error: %v", nfs_err)
@@ -179,7 +234,7 @@ func (fo *FilerOptions) startFiler() {
 	}
 
 	// starting grpc server
-	grpcPort := *fo.port + 10000
+	grpcPort := fo.grpcPort()
 	grpcL, err := util.NewListener(*fo.bindIp+":"+strconv.Itoa(grpcPort), 0)
 	if err != nil {
 		glog.Fatalf("failed to listen on grpc port %d: %v", grpcPort, err)
@@ -195,3 +250,10 @@ func (fo *FilerOptions) startFiler() {
 	}
 
 }
+
+func (fo *FilerOptions) grpcPort() int {
+	if *fo.portGrpc != 0 {
+		return *fo.portGrpc
+	}
+	return *fo.port + 10000
+}
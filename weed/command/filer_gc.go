@@ -0,0 +1,269 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle_map"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func init() {
+	cmdFilerGc.Run = runFilerGc // break init cycle
+}
+
+var cmdFilerGc = &Command{
+	UsageLine: "filer.gc [-filer=localhost:8888] [-master=localhost:9333] [-dryRun]",
+	Short:     "delete volume needles not referenced by any filer entry",
+	Long: `Delete volume needles that are not referenced by any filer entry.
+
+  Filer entries can be deleted while their needles stay in the volumes, e.g., when the filer crashes mid-delete.
+  This command works this way:
+  1. snapshot the live needles of each volume from its .idx file
+  2. walk the filer metadata, and drop every needle referenced by an entry
+  3. walk the filer metadata again, to catch needles whose entries were still being created during the first walk
+  4. delete the remaining needles from all replicas of the volume
+
+  Important assumption!!!
+    the volumes are used only by this filer.
+  Erasure coded volumes are skipped.
+
+  `,
+}
+
+var (
+	filerGcFiler   = cmdFilerGc.Flag.String("filer", "localhost:8888", "filer hostname:port")
+	filerGcMaster  = cmdFilerGc.Flag.String("master", "localhost:9333", "master hostname:port")
+	filerGcDryRun  = cmdFilerGc.Flag.Bool("dryRun", false, "only report the orphan needles, without deleting them")
+	filerGcVerbose = cmdFilerGc.Flag.Bool("v", false, "print each orphan file id")
+)
+
+type filerGcVolume struct {
+	servers []string
+	needles *needle_map.MemDb
+}
+
+func runFilerGc(cmd *Command, args []string) bool {
+
+	util.LoadConfiguration("security", false)
+	grpcDialOption := security.LoadClientTLS(util.GetViper(), "grpc.client")
+
+	tempFolder, err := ioutil.TempDir("", "sw_filer_gc")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create temp folder: %v\n", err)
+		return true
+	}
+	defer os.RemoveAll(tempFolder)
+
+	volumes, err := filerGcCollectVolumes(*filerGcMaster, grpcDialOption, tempFolder)
+	defer func() {
+		for _, v := range volumes {
+			if v.needles != nil {
+				v.needles.Close()
+			}
+		}
+	}()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "collect volume needles: %v\n", err)
+		return true
+	}
+
+	client := &filerLsClient{
+		filer:          *filerGcFiler,
+		grpcDialOption: grpcDialOption,
+	}
+
+	// the second pass keeps needles whose filer entries were created after the first pass visited their directory
+	for pass := 1; pass <= 2; pass++ {
+		fmt.Printf("pass %d: collecting file ids from filer %s ...\n", pass, *filerGcFiler)
+		if err = filerGcDropReferenced(client, volumes); err != nil {
+			fmt.Fprintf(os.Stderr, "pass %d: %v\n", pass, err)
+			return true
+		}
+	}
+
+	var totalOrphanCount, totalOrphanSize uint64
+	for vid, v := range volumes {
+		var fileIds []string
+		var orphanSize uint64
+		v.needles.AscendingVisit(func(n needle_map.NeedleValue) error {
+			fileIds = append(fileIds, fmt.Sprintf("%d,%s", vid, n.Key.String()))
+			orphanSize += uint64(n.Size)
+			return nil
+		})
+		if len(fileIds) == 0 {
+			continue
+		}
+		fmt.Printf("volume:%d\torphan:%d\t%dB\n", vid, len(fileIds), orphanSize)
+		if *filerGcVerbose {
+			for _, fid := range fileIds {
+				fmt.Printf("%s\n", fid)
+			}
+		}
+		totalOrphanCount += uint64(len(fileIds))
+		totalOrphanSize += orphanSize
+
+		if *filerGcDryRun {
+			continue
+		}
+		for _, server := range v.servers {
+			results, deleteErr := operation.DeleteFilesAtOneVolumeServer(server, grpcDialOption, fileIds, false)
+			if deleteErr != nil {
+				fmt.Fprintf(os.Stderr, "delete orphans of volume %d on %s: %v\n", vid, server, deleteErr)
+				continue
+			}
+			for _, result := range results {
+				if result.Error != "" {
+					fmt.Fprintf(os.Stderr, "delete %s on %s: %s\n", result.FileId, server, result.Error)
+				}
+			}
+		}
+	}
+
+	if *filerGcDryRun {
+		fmt.Printf("total orphan:%d\t%dB, not deleted in dry run mode\n", totalOrphanCount, totalOrphanSize)
+	} else {
+		fmt.Printf("total orphan:%d\t%dB deleted\n", totalOrphanCount, totalOrphanSize)
+	}
+
+	return true
+}
+
+// filerGcCollectVolumes loads the live needles of each normal volume from the .idx file of one of its replicas.
+func filerGcCollectVolumes(master string, grpcDialOption grpc.DialOption, tempFolder string) (volumes map[uint32]*filerGcVolume, err error) {
+
+	var resp *master_pb.VolumeListResponse
+	err = pb.WithMasterClient(master, grpcDialOption, func(client master_pb.SeaweedClient) error {
+		resp, err = client.VolumeList(context.Background(), &master_pb.VolumeListRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	volumes = make(map[uint32]*filerGcVolume)
+	collections := make(map[uint32]string)
+	for _, dc := range resp.TopologyInfo.DataCenterInfos {
+		for _, rack := range dc.RackInfos {
+			for _, dn := range rack.DataNodeInfos {
+				for _, vi := range dn.VolumeInfos {
+					v, found := volumes[vi.Id]
+					if !found {
+						v = &filerGcVolume{}
+						volumes[vi.Id] = v
+						collections[vi.Id] = vi.Collection
+					}
+					v.servers = append(v.servers, dn.Id)
+				}
+			}
+		}
+	}
+
+	for vid, v := range volumes {
+		idxFileName := filepath.Join(tempFolder, fmt.Sprintf("%d.idx", vid))
+		if err = filerGcCopyIndex(v.servers[0], grpcDialOption, vid, collections[vid], idxFileName); err != nil {
+			return volumes, fmt.Errorf("copy volume %d index from %s: %v", vid, v.servers[0], err)
+		}
+		v.needles = needle_map.NewMemDb()
+		if err = v.needles.LoadFromIdx(idxFileName); err != nil {
+			return volumes, fmt.Errorf("load volume %d index: %v", vid, err)
+		}
+		os.Remove(idxFileName)
+	}
+
+	return volumes, nil
+}
+
+func filerGcCopyIndex(server string, grpcDialOption grpc.DialOption, vid uint32, collection string, idxFileName string) error {
+
+	return operation.WithVolumeServerClient(server, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+
+		copyFileClient, err := client.CopyFile(context.Background(), &volume_server_pb.CopyFileRequest{
+			VolumeId:           vid,
+			Ext:                ".idx",
+			CompactionRevision: math.MaxUint32,
+			StopOffset:         math.MaxInt64,
+			Collection:         collection,
+		})
+		if err != nil {
+			return err
+		}
+
+		dst, err := os.OpenFile(idxFileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+
+		for {
+			resp, receiveErr := copyFileClient.Recv()
+			if receiveErr == io.EOF {
+				return nil
+			}
+			if receiveErr != nil {
+				return receiveErr
+			}
+			if _, err = dst.Write(resp.FileContent); err != nil {
+				return err
+			}
+		}
+	})
+
+}
+
+// filerGcDropReferenced walks all filer entries and removes the needles they reference.
+// Any entry whose chunks can not be resolved fails the walk, so that its needles are never deleted.
+func filerGcDropReferenced(client filer_pb.FilerClient, volumes map[uint32]*filerGcVolume) error {
+
+	lookupFn := filer.LookupFn(client)
+
+	var errLock sync.Mutex
+	var firstErr error
+
+	err := filer_pb.TraverseBfs(client, util.FullPath("/"), func(parentPath util.FullPath, entry *filer_pb.Entry) {
+		dataChunks, manifestChunks, resolveErr := filer.ResolveChunkManifest(lookupFn, entry.Chunks)
+		if resolveErr != nil {
+			errLock.Lock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("resolve chunks of %s: %v", parentPath.Child(entry.Name), resolveErr)
+			}
+			errLock.Unlock()
+			return
+		}
+		for _, chunk := range append(dataChunks, manifestChunks...) {
+			filer_pb.EnsureFid(chunk)
+			if chunk.Fid == nil {
+				errLock.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("parse chunk %s of %s", chunk.FileId, parentPath.Child(entry.Name))
+				}
+				errLock.Unlock()
+				return
+			}
+			if v, found := volumes[chunk.Fid.VolumeId]; found {
+				v.needles.Delete(types.NeedleId(chunk.Fid.FileKey))
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return firstErr
+}
@@ -0,0 +1,193 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func init() {
+	cmdFilerLs.Run = runFilerLs // break init cycle
+}
+
+var cmdFilerLs = &Command{
+	UsageLine: "filer.ls [-filer=localhost:8888] [-path=/] [-recursive] [-sortBy=name|size|mtime] [-desc] [-json]",
+	Short:     "list a filer directory with sizes and modification times",
+	Long: `List a filer directory with sizes and modification times, like "ls -la".
+
+  The listing is read through the filer gRPC API.
+  In recursive mode, sub directories are listed concurrently, and the entries are shown with their full paths.
+
+  `,
+}
+
+var (
+	filerLsFiler       = cmdFilerLs.Flag.String("filer", "localhost:8888", "filer hostname:port")
+	filerLsPath        = cmdFilerLs.Flag.String("path", "/", "the directory to list")
+	filerLsRecursive   = cmdFilerLs.Flag.Bool("recursive", false, "also list all sub directories")
+	filerLsSortBy      = cmdFilerLs.Flag.String("sortBy", "name", "sort by name, size, or mtime")
+	filerLsDesc        = cmdFilerLs.Flag.Bool("desc", false, "sort in descending order")
+	filerLsJson        = cmdFilerLs.Flag.Bool("json", false, "output in json")
+	filerLsParallelism = cmdFilerLs.Flag.Int("parallelism", 8, "number of directories listed concurrently in recursive mode")
+)
+
+type FilerLsEntry struct {
+	Path        string    `json:"path"`
+	IsDirectory bool      `json:"isDirectory"`
+	Size        uint64    `json:"size"`
+	Mtime       time.Time `json:"mtime"`
+	Collection  string    `json:"collection"`
+	Replication string    `json:"replication"`
+}
+
+func runFilerLs(cmd *Command, args []string) bool {
+
+	util.LoadConfiguration("security", false)
+
+	var less func(a, b *FilerLsEntry) bool
+	switch *filerLsSortBy {
+	case "name":
+		less = func(a, b *FilerLsEntry) bool { return a.Path < b.Path }
+	case "size":
+		less = func(a, b *FilerLsEntry) bool { return a.Size < b.Size }
+	case "mtime":
+		less = func(a, b *FilerLsEntry) bool { return a.Mtime.Before(b.Mtime) }
+	default:
+		fmt.Fprintf(os.Stderr, "unknown -sortBy=%s\n", *filerLsSortBy)
+		return false
+	}
+
+	client := &filerLsClient{
+		filer:          *filerLsFiler,
+		grpcDialOption: security.LoadClientTLS(util.GetViper(), "grpc.client"),
+	}
+
+	var entriesLock sync.Mutex
+	var entries []*FilerLsEntry
+	err := filerLsTraverse(client, util.FullPath(*filerLsPath), *filerLsRecursive, *filerLsParallelism, func(dir util.FullPath, entry *filer_pb.Entry) {
+		lsEntry := &FilerLsEntry{
+			Path:        string(dir.Child(entry.Name)),
+			IsDirectory: entry.IsDirectory,
+			Size:        filer.FileSize(entry),
+			Mtime:       time.Unix(entry.Attributes.GetMtime(), 0),
+			Collection:  entry.Attributes.GetCollection(),
+			Replication: entry.Attributes.GetReplication(),
+		}
+		if !*filerLsRecursive {
+			lsEntry.Path = entry.Name
+		}
+		entriesLock.Lock()
+		entries = append(entries, lsEntry)
+		entriesLock.Unlock()
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "list %s: %v\n", *filerLsPath, err)
+		return true
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if *filerLsDesc {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+
+	if *filerLsJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if entries == nil {
+			entries = []*FilerLsEntry{}
+		}
+		if err = encoder.Encode(entries); err != nil {
+			fmt.Fprintf(os.Stderr, "encode json: %v\n", err)
+		}
+		return true
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tSIZE\tMTIME\tCOLLECTION\tREPLICATION\n")
+	for _, entry := range entries {
+		name, size := entry.Path, util.BytesToHumanReadable(entry.Size)
+		if entry.IsDirectory {
+			name, size = name+"/", "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, size, entry.Mtime.Format("2006-01-02 15:04:05"), entry.Collection, entry.Replication)
+	}
+	w.Flush()
+
+	return true
+}
+
+// filerLsTraverse lists the directory, and in recursive mode all its sub directories,
+// with at most parallelism directories listed at the same time. fn can be called concurrently.
+func filerLsTraverse(client filer_pb.FilerClient, dir util.FullPath, recursive bool, parallelism int, fn func(dir util.FullPath, entry *filer_pb.Entry)) error {
+
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	limiter := make(chan struct{}, parallelism)
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var firstErr error
+
+	var listDir func(dir util.FullPath)
+	listDir = func(dir util.FullPath) {
+		defer wg.Done()
+
+		var subDirs []util.FullPath
+		limiter <- struct{}{}
+		err := filer_pb.ReadDirAllEntries(client, dir, "", func(entry *filer_pb.Entry, isLast bool) error {
+			fn(dir, entry)
+			if recursive && entry.IsDirectory {
+				subDirs = append(subDirs, dir.Child(entry.Name))
+			}
+			return nil
+		})
+		<-limiter
+
+		if err != nil {
+			errLock.Lock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("list %s: %v", dir, err)
+			}
+			errLock.Unlock()
+			return
+		}
+		for _, subDir := range subDirs {
+			wg.Add(1)
+			go listDir(subDir)
+		}
+	}
+
+	wg.Add(1)
+	listDir(dir)
+	wg.Wait()
+
+	return firstErr
+}
+
+type filerLsClient struct {
+	filer          string
+	grpcDialOption grpc.DialOption
+}
+
+func (c *filerLsClient) WithFilerClient(fn func(filer_pb.SeaweedFilerClient) error) error {
+	return pb.WithFilerClient(c.filer, c.grpcDialOption, fn)
+}
+
+func (c *filerLsClient) AdjustedUrl(location *filer_pb.Location) string {
+	return location.Url
+}
@@ -0,0 +1,276 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+type FilerMetaBackupOptions struct {
+	grpcDialOption grpc.DialOption
+	filerAddress   *string
+	filerDirectory *string
+	restart        *bool
+	configFile     *string
+	verbose        *bool
+
+	store filer.VirtualFilerStore
+}
+
+var (
+	metaBackup FilerMetaBackupOptions
+)
+
+func init() {
+	cmdFilerMetaBackup.Run = runFilerMetaBackup // break init cycle
+	metaBackup.filerAddress = cmdFilerMetaBackup.Flag.String("filer", "localhost:8888", "filer hostname:port")
+	metaBackup.filerDirectory = cmdFilerMetaBackup.Flag.String("filerDir", "/", "a folder on the filer")
+	metaBackup.restart = cmdFilerMetaBackup.Flag.Bool("restart", false, "copy the full metadata again before following the changes")
+	metaBackup.configFile = cmdFilerMetaBackup.Flag.String("config", "", "path to a toml file enabling the backup filer store, in the same format as filer.toml")
+	metaBackup.verbose = cmdFilerMetaBackup.Flag.Bool("v", false, "print out each copied entry")
+}
+
+var cmdFilerMetaBackup = &Command{
+	UsageLine: "filer.meta.backup [-filer=localhost:8888] [-filerDir=/] [-restart] -config=/path/to/backup_filer.toml",
+	Short:     "copy filer metadata to another filer store and keep following the changes",
+	Long: `copy filer metadata to another filer store, while the filer keeps serving
+
+	The backup filer store is configured in a toml file in the same format as filer.toml,
+	with exactly one store enabled. It can be generated by "weed scaffold -config=filer".
+
+	weed filer.meta.backup -config=/path/to/backup_filer.toml -filer=localhost:8888
+	weed filer.meta.backup -config=/path/to/backup_filer.toml -filer=localhost:8888 -restart
+
+	The first run copies the metadata tree, then replays the metadata changes made since
+	the copy started, and keeps following new changes. The progress is saved in the backup
+	store, so a restarted backup resumes from there.
+
+	To migrate to another filer store, e.g. from leveldb2 to postgres, run the backup to
+	the new store until it catches up, stop the writes to the filer, wait a few seconds,
+	then restart the filer with the new store in filer.toml.
+
+`,
+}
+
+// the key in the backup store keeping the timestamp of the last copied change
+var MetaBackupOffsetKey = []byte("metaBackup.offset")
+
+func runFilerMetaBackup(cmd *Command, args []string) bool {
+
+	metaBackup.grpcDialOption = security.LoadClientTLS(util.GetViper(), "grpc.client")
+
+	if *metaBackup.configFile == "" {
+		glog.Errorf("missing -config for the backup filer store")
+		return false
+	}
+	v := viper.New()
+	v.SetConfigFile(*metaBackup.configFile)
+	if err := v.ReadInConfig(); err != nil {
+		glog.Errorf("read %s: %v", *metaBackup.configFile, err)
+		return true
+	}
+	store, err := filer.LoadStore(v)
+	if err != nil {
+		glog.Errorf("backup filer store in %s: %v", *metaBackup.configFile, err)
+		return true
+	}
+	metaBackup.store = filer.NewFilerStoreWrapper(store)
+	defer metaBackup.store.Shutdown()
+
+	_, offsetErr := metaBackup.getOffset()
+	if *metaBackup.restart || offsetErr != nil {
+		// changes made during the copy are replayed from the start time
+		startTime := time.Now()
+		glog.V(0).Infof("copy metadata under %s from %s", *metaBackup.filerDirectory, *metaBackup.filerAddress)
+		if err := metaBackup.traverseMetadata(); err != nil {
+			glog.Errorf("copy metadata: %v", err)
+			return true
+		}
+		if err := metaBackup.setOffset(startTime.UnixNano()); err != nil {
+			glog.Errorf("save backup offset: %v", err)
+			return true
+		}
+		glog.V(0).Infof("copied metadata, follow changes since %v", startTime)
+	}
+
+	for {
+		if err := metaBackup.streamMetadataBackup(); err != nil {
+			glog.Errorf("filer meta backup from %s: %v", *metaBackup.filerAddress, err)
+			time.Sleep(1747 * time.Millisecond)
+		}
+	}
+}
+
+func (metaBackup *FilerMetaBackupOptions) traverseMetadata() (err error) {
+	var dirCount, fileCount int64
+	var saveErr error
+
+	traverseErr := filer_pb.TraverseBfs(metaBackup, util.FullPath(*metaBackup.filerDirectory), func(parentPath util.FullPath, entry *filer_pb.Entry) {
+		if err := metaBackup.store.InsertEntry(context.Background(), filer.FromPbEntry(string(parentPath), entry)); err != nil {
+			saveErr = fmt.Errorf("insert %s: %v", parentPath.Child(entry.Name), err)
+			return
+		}
+		if *metaBackup.verbose {
+			fmt.Printf("+ %s\n", parentPath.Child(entry.Name))
+		}
+		if entry.IsDirectory {
+			dirCount++
+		} else {
+			fileCount++
+		}
+	})
+	if traverseErr != nil {
+		return fmt.Errorf("traverse: %v", traverseErr)
+	}
+
+	glog.V(0).Infof("copied %d directories, %d files", dirCount, fileCount)
+	return saveErr
+}
+
+func (metaBackup *FilerMetaBackupOptions) streamMetadataBackup() error {
+
+	sinceNs, err := metaBackup.getOffset()
+	if err != nil {
+		return fmt.Errorf("read backup offset: %v", err)
+	}
+	glog.V(0).Infof("follow metadata changes since %v", time.Unix(0, sinceNs))
+
+	return pb.WithFilerClient(*metaBackup.filerAddress, metaBackup.grpcDialOption, func(client filer_pb.SeaweedFilerClient) error {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := client.SubscribeMetadata(ctx, &filer_pb.SubscribeMetadataRequest{
+			ClientName: "meta_backup",
+			PathPrefix: *metaBackup.filerDirectory,
+			SinceNs:    sinceNs,
+		})
+		if err != nil {
+			return fmt.Errorf("listen: %v", err)
+		}
+
+		var counter int64
+		var lastWriteTime time.Time
+		for {
+			resp, listenErr := stream.Recv()
+			if listenErr == io.EOF {
+				return nil
+			}
+			if listenErr != nil {
+				return listenErr
+			}
+
+			if err := metaBackup.applyEvent(resp); err != nil {
+				return err
+			}
+
+			counter++
+			if lastWriteTime.Add(3 * time.Second).Before(time.Now()) {
+				glog.V(0).Infof("meta backup %s progressed to %v %0.2f/sec", *metaBackup.filerAddress, time.Unix(0, resp.TsNs), float64(counter)/float64(3))
+				counter = 0
+				lastWriteTime = time.Now()
+				if err := metaBackup.setOffset(resp.TsNs); err != nil {
+					return fmt.Errorf("save backup offset: %v", err)
+				}
+			}
+		}
+
+	})
+}
+
+func (metaBackup *FilerMetaBackupOptions) applyEvent(resp *filer_pb.SubscribeMetadataResponse) error {
+	ctx := context.Background()
+	message := resp.EventNotification
+
+	var oldKey, newKey util.FullPath
+	if message.OldEntry != nil {
+		oldKey = util.FullPath(resp.Directory).Child(message.OldEntry.Name)
+	}
+	if message.NewEntry != nil {
+		newKey = util.FullPath(message.NewParentPath).Child(message.NewEntry.Name)
+	}
+
+	// a rename into or out of the backed up folder only has one side in it
+	if message.OldEntry != nil && metaBackup.isOutside(oldKey) {
+		message.OldEntry, oldKey = nil, ""
+	}
+	if message.NewEntry != nil && metaBackup.isOutside(newKey) {
+		message.NewEntry, newKey = nil, ""
+	}
+
+	if message.OldEntry != nil && oldKey != newKey {
+		if *metaBackup.verbose {
+			fmt.Printf("- %s\n", oldKey)
+		}
+		var err error
+		if message.NewEntry != nil {
+			// renamed, the children follow in their own events
+			err = metaBackup.store.DeleteMovedEntry(ctx, oldKey)
+		} else {
+			if message.OldEntry.IsDirectory {
+				if err = metaBackup.store.DeleteFolderChildren(ctx, oldKey); err != nil {
+					return fmt.Errorf("delete %s children: %v", oldKey, err)
+				}
+			}
+			err = metaBackup.store.DeleteEntry(ctx, oldKey)
+		}
+		if err != nil && err != filer_pb.ErrNotFound {
+			return fmt.Errorf("delete %s: %v", oldKey, err)
+		}
+	}
+
+	if message.NewEntry != nil {
+		if *metaBackup.verbose {
+			fmt.Printf("+ %s\n", newKey)
+		}
+		if err := metaBackup.store.InsertEntry(ctx, filer.FromPbEntry(message.NewParentPath, message.NewEntry)); err != nil {
+			return fmt.Errorf("insert %s: %v", newKey, err)
+		}
+	}
+
+	return nil
+}
+
+func (metaBackup *FilerMetaBackupOptions) isOutside(p util.FullPath) bool {
+	dir := strings.TrimSuffix(*metaBackup.filerDirectory, "/")
+	return dir != "" && string(p) != dir && !strings.HasPrefix(string(p), dir+"/")
+}
+
+func (metaBackup *FilerMetaBackupOptions) getOffset() (offsetTsNs int64, err error) {
+	value, err := metaBackup.store.KvGet(context.Background(), MetaBackupOffsetKey)
+	if err != nil {
+		return 0, err
+	}
+	if len(value) < 8 {
+		return 0, fmt.Errorf("invalid backup offset %x", value)
+	}
+	return int64(util.BytesToUint64(value)), nil
+}
+
+func (metaBackup *FilerMetaBackupOptions) setOffset(offsetTsNs int64) error {
+	valueBuf := make([]byte, 8)
+	util.Uint64toBytes(valueBuf, uint64(offsetTsNs))
+	return metaBackup.store.KvPut(context.Background(), MetaBackupOffsetKey, valueBuf)
+}
+
+var _ = filer_pb.FilerClient(&FilerMetaBackupOptions{})
+
+func (metaBackup *FilerMetaBackupOptions) WithFilerClient(fn func(filer_pb.SeaweedFilerClient) error) error {
+	return pb.WithFilerClient(*metaBackup.filerAddress, metaBackup.grpcDialOption, fn)
+}
+
+func (metaBackup *FilerMetaBackupOptions) AdjustedUrl(location *filer_pb.Location) string {
+	return location.Url
+}
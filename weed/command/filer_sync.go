@@ -164,10 +164,6 @@ func doSubscribeFilerMetaChanges(grpcDialOption grpc.DialOption, sourceFiler, so
 			fmt.Printf("%s check %s change %s,%s sig %v, target sig: %v\n", targetFiler, sourceFiler, sourceOldKey, sourceNewKey, message.Signatures, targetFilerSignature)
 		}
 
-		if !strings.HasPrefix(resp.Directory, sourcePath) {
-			return nil
-		}
-
 		// handle deletions
 		if message.OldEntry != nil && message.NewEntry == nil {
 			if !strings.HasPrefix(string(sourceOldKey), sourcePath) {
@@ -1,8 +1,11 @@
 package command
 
 import (
+	"context"
+	"fmt"
 	"github.com/chrislusf/raft/protobuf"
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"net/http"
 	"os"
@@ -14,12 +17,16 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/util/grace"
 
+	"github.com/chrislusf/seaweedfs/weed/alert"
+	"github.com/chrislusf/seaweedfs/weed/consul"
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/server"
+	stats_collect "github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage/backend"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -29,6 +36,7 @@ var (
 
 type MasterOptions struct {
 	port              *int
+	portGrpc          *int
 	ip                *string
 	ipBind            *string
 	metaFolder        *string
@@ -36,22 +44,37 @@ type MasterOptions struct {
 	volumeSizeLimitMB *uint
 	volumePreallocate *bool
 	// pulseSeconds       *int
-	defaultReplication *string
-	garbageThreshold   *float64
-	whiteList          *string
-	disableHttp        *bool
-	metricsAddress     *string
-	metricsIntervalSec *int
-	raftResumeState    *bool
+	defaultReplication   *string
+	garbageThreshold     *float64
+	whiteList            *string
+	disableHttp          *bool
+	metricsAddress       *string
+	metricsIntervalSec   *int
+	metricsJobLabel      *string
+	metricsInstanceLabel *string
+	metricsHttpPort      *int
+	metricsByCollection  *bool
+	tlsMinVersion        *string
+	tlsCipherSuites      *string
+	raftResumeState      *bool
+	raftSnapshotInterval *time.Duration
+	alertPagerDutyKey    *string
+	alertOpsgenieKey     *string
+	tracingEndpoint      *string
+	consulEnabled        *bool
+	consulAgentAddress   *string
+	consulCheckInterval  *time.Duration
+	sequencerType        *string
 }
 
 func init() {
 	cmdMaster.Run = runMaster // break init cycle
 	m.port = cmdMaster.Flag.Int("port", 9333, "http listen port")
+	m.portGrpc = cmdMaster.Flag.Int("port.grpc", 0, "grpc listen port, default to http port + 10000")
 	m.ip = cmdMaster.Flag.String("ip", util.DetectedHostAddress(), "master <ip>|<server> address")
 	m.ipBind = cmdMaster.Flag.String("ip.bind", "0.0.0.0", "ip address to bind to")
 	m.metaFolder = cmdMaster.Flag.String("mdir", os.TempDir(), "data directory to store meta data")
-	m.peers = cmdMaster.Flag.String("peers", "", "all master nodes in comma separated ip:port list, example: 127.0.0.1:9093,127.0.0.1:9094,127.0.0.1:9095")
+	m.peers = cmdMaster.Flag.String("peers", "", "all master nodes in comma separated ip:port list, example: 127.0.0.1:9093,127.0.0.1:9094,127.0.0.1:9095, or ip:port.grpcPort for masters with -port.grpc")
 	m.volumeSizeLimitMB = cmdMaster.Flag.Uint("volumeSizeLimitMB", 30*1000, "Master stops directing writes to oversized volumes.")
 	m.volumePreallocate = cmdMaster.Flag.Bool("volumePreallocate", false, "Preallocate disk space for volumes.")
 	// m.pulseSeconds = cmdMaster.Flag.Int("pulseSeconds", 5, "number of seconds between heartbeats")
@@ -61,7 +84,21 @@ func init() {
 	m.disableHttp = cmdMaster.Flag.Bool("disableHttp", false, "disable http requests, only gRPC operations are allowed.")
 	m.metricsAddress = cmdMaster.Flag.String("metrics.address", "", "Prometheus gateway address <host>:<port>")
 	m.metricsIntervalSec = cmdMaster.Flag.Int("metrics.intervalSeconds", 15, "Prometheus push interval in seconds")
+	m.metricsJobLabel = cmdMaster.Flag.String("metrics.jobLabel", "", "Prometheus job label for pushed metrics, default to the component name, e.g. volumeServer, filer, s3")
+	m.metricsInstanceLabel = cmdMaster.Flag.String("metrics.instanceLabel", "", "prefix of the Prometheus instance label for pushed metrics, e.g. cluster1 gives cluster1-<host>:<port>")
+	m.metricsHttpPort = cmdMaster.Flag.Int("metricsPort", 0, "Prometheus metrics listen port")
+	m.metricsByCollection = cmdMaster.Flag.Bool("metricsLabelByCollection", false, "label the volume metrics by collection, for at most 100 collections")
+	m.tlsMinVersion = cmdMaster.Flag.String("tls.minVersion", "1.2", "minimum TLS version of the grpc server: 1.0, 1.1, 1.2, or 1.3")
+	m.tlsCipherSuites = cmdMaster.Flag.String("tls.cipherSuites", "", "comma separated TLS 1.2 cipher suites of the grpc server, default to the Go defaults")
+	m.alertPagerDutyKey = cmdMaster.Flag.String("alert.pagerduty.apiKey", "", "PagerDuty Events API v2 routing key to alert on losing raft quorum")
+	m.alertOpsgenieKey = cmdMaster.Flag.String("alert.opsgenie.apiKey", "", "Opsgenie API key to alert on losing raft quorum")
+	m.tracingEndpoint = cmdMaster.Flag.String("tracing.otlpEndpoint", "", "OpenTelemetry collector to export traces to with OTLP over http, e.g. localhost:4318")
 	m.raftResumeState = cmdMaster.Flag.Bool("resumeState", false, "resume previous state on start master server")
+	m.raftSnapshotInterval = cmdMaster.Flag.Duration("raftSnapshotInterval", time.Hour, "how often to snapshot the master state and compact the raft log, 0 to only snapshot when the log grows over 400 entries")
+	m.consulEnabled = cmdMaster.Flag.Bool("consul.enabled", false, "register the master as the seaweedfs-master service with the consul agent")
+	m.consulAgentAddress = cmdMaster.Flag.String("consul.agentAddress", "localhost:8500", "consul agent http address")
+	m.consulCheckInterval = cmdMaster.Flag.Duration("consul.healthCheckInterval", 10*time.Second, "how often consul checks the master health")
+	m.sequencerType = cmdMaster.Flag.String("sequencerType", "", "file id sequencer: raft, etcd, or snowflake, overrides master.sequencer.type in master.toml")
 }
 
 var cmdMaster = &Command{
@@ -73,6 +110,9 @@ var cmdMaster = &Command{
 
 	The example security.toml configuration file can be generated by "weed scaffold -config=security"
 
+	With -consul.enabled, the master registers itself as the "seaweedfs-master" service with the local
+	consul agent, so volume servers and filers can use e.g. -mserver=seaweedfs-master.service.consul:9333
+
   `,
 }
 
@@ -105,6 +145,15 @@ func runMaster(cmd *Command, args []string) bool {
 		glog.Fatalf("volumeSizeLimitMB should be smaller than 30000")
 	}
 
+	stats_collect.LabelByCollection = *m.metricsByCollection
+	if err := security.SetServerTlsOptions(*m.tlsMinVersion, *m.tlsCipherSuites); err != nil {
+		glog.Fatalf("master tls options: %v", err)
+	}
+	go stats_collect.StartMetricsServer(*m.metricsHttpPort)
+
+	alert.Configure(*m.alertPagerDutyKey, *m.alertOpsgenieKey, fmt.Sprintf("master@%s:%d", *m.ip, *m.port))
+	tracing.Configure(*m.tracingEndpoint, "master")
+
 	startMaster(m, masterWhiteList)
 
 	return true
@@ -114,7 +163,8 @@ func startMaster(masterOption MasterOptions, masterWhiteList []string) {
 
 	backend.LoadConfiguration(util.GetViper())
 
-	myMasterAddress, peers := checkPeers(*masterOption.ip, *masterOption.port, *masterOption.peers)
+	grpcPort := masterOption.grpcPort()
+	myMasterAddress, peers := checkPeers(*masterOption.ip, *masterOption.port, grpcPort, *masterOption.peers)
 
 	r := mux.NewRouter()
 	ms := weed_server.NewMasterServer(r, masterOption.toMasterOption(masterWhiteList), peers)
@@ -126,14 +176,14 @@ func startMaster(masterOption MasterOptions, masterWhiteList []string) {
 	}
 	// start raftServer
 	raftServer, err := weed_server.NewRaftServer(security.LoadClientTLS(util.GetViper(), "grpc.master"),
-		peers, myMasterAddress, util.ResolvePath(*masterOption.metaFolder), ms.Topo, *masterOption.raftResumeState)
+		peers, myMasterAddress, util.ResolvePath(*masterOption.metaFolder), ms.Topo, *masterOption.raftResumeState, *masterOption.raftSnapshotInterval)
 	if raftServer == nil {
 		glog.Fatalf("please verify %s is writable, see https://github.com/chrislusf/seaweedfs/issues/717: %s", *masterOption.metaFolder, err)
 	}
 	ms.SetRaftServer(raftServer)
 	r.HandleFunc("/cluster/status", raftServer.StatusHandler).Methods("GET")
+	r.HandleFunc("/cluster/healthz", raftServer.HealthzHandler).Methods("GET")
 	// starting grpc server
-	grpcPort := *masterOption.port + 10000
 	grpcL, err := util.NewListener(*masterOption.ipBind+":"+strconv.Itoa(grpcPort), 0)
 	if err != nil {
 		glog.Fatalf("master failed to listen on grpc port %d: %v", grpcPort, err)
@@ -161,12 +211,85 @@ func startMaster(masterOption MasterOptions, masterWhiteList []string) {
 	httpS := &http.Server{Handler: r}
 	go httpS.Serve(masterListener)
 
+	if *masterOption.consulEnabled {
+		masterOption.registerWithConsul(grpcPort)
+	}
+
+	grace.OnInterrupt(func() {
+		shutdownMaster(ms, httpS, grpcS)
+	})
+
 	select {}
 }
 
-func checkPeers(masterIp string, masterPort int, peers string) (masterAddress string, cleanedPeers []string) {
+// how long in-flight requests can take to finish when the master shuts down
+const (
+	masterHttpShutdownTimeout = 10 * time.Second
+	// the heartbeat and KeepConnected streams never end by themselves,
+	// and are cut after this, so the clients move to the new leader
+	masterGrpcShutdownTimeout = 2 * time.Second
+)
+
+func shutdownMaster(ms *weed_server.MasterServer, httpS *http.Server, grpcS *grpc.Server) {
+
+	// firstly, let a follower take over, so the cluster elects the new leader without waiting for the election timeout
+	glog.V(0).Infof("stop raft server ...")
+	ms.StepDown()
+
+	glog.V(0).Infof("graceful stop http server ...")
+	ctx, cancel := context.WithTimeout(context.Background(), masterHttpShutdownTimeout)
+	defer cancel()
+	if err := httpS.Shutdown(ctx); err != nil {
+		glog.Warningf("stop the http server: %v", err)
+	}
+
+	glog.V(0).Infof("graceful stop gRPC ...")
+	stopped := make(chan struct{})
+	go func() {
+		grpcS.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(masterGrpcShutdownTimeout):
+		grpcS.Stop()
+	}
+
+	if err := ms.Topo.Sequence.Close(); err != nil {
+		glog.Warningf("close sequencer: %v", err)
+	}
+}
+
+func (m MasterOptions) registerWithConsul(grpcPort int) {
+	agent := consul.NewAgent(*m.consulAgentAddress)
+	serviceId := fmt.Sprintf("seaweedfs-master-%s-%d", *m.ip, *m.port)
+	err := agent.Register(consul.Service{
+		Id:      serviceId,
+		Name:    "seaweedfs-master",
+		Address: *m.ip,
+		Port:    *m.port,
+		Tags:    []string{"seaweedfs", "master"},
+		Meta: map[string]string{
+			"grpc_port": strconv.Itoa(grpcPort),
+			"version":   util.Version(),
+		},
+		CheckPath:     "/cluster/healthz",
+		CheckInterval: *m.consulCheckInterval,
+	})
+	if err != nil {
+		glog.Errorf("%v", err)
+		return
+	}
+	grace.OnInterrupt(func() {
+		if err := agent.Deregister(serviceId); err != nil {
+			glog.Errorf("%v", err)
+		}
+	})
+}
+
+func checkPeers(masterIp string, masterPort int, masterGrpcPort int, peers string) (masterAddress string, cleanedPeers []string) {
 	glog.V(0).Infof("current: %s:%d peers:%s", masterIp, masterPort, peers)
-	masterAddress = masterIp + ":" + strconv.Itoa(masterPort)
+	masterAddress = pb.NewServerAddress(masterIp, masterPort, masterGrpcPort)
 	if peers != "" {
 		cleanedPeers = strings.Split(peers, ",")
 	}
@@ -183,7 +306,8 @@ func checkPeers(masterIp string, masterPort int, peers string) (masterAddress st
 		cleanedPeers = append(cleanedPeers, masterAddress)
 	}
 	if len(cleanedPeers)%2 == 0 {
-		glog.Fatalf("Only odd number of masters are supported!")
+		// allowed while growing or shrinking the cluster one master at a time
+		glog.Warningf("%d masters tolerate no more failures than %d masters, use an odd number of masters", len(cleanedPeers), len(cleanedPeers)-1)
 	}
 	return
 }
@@ -196,10 +320,18 @@ func isTheFirstOne(self string, peers []string) bool {
 	return self == peers[0]
 }
 
+func (m *MasterOptions) grpcPort() int {
+	if *m.portGrpc != 0 {
+		return *m.portGrpc
+	}
+	return *m.port + 10000
+}
+
 func (m *MasterOptions) toMasterOption(whiteList []string) *weed_server.MasterOption {
 	return &weed_server.MasterOption{
 		Host:              *m.ip,
 		Port:              *m.port,
+		GrpcPort:          m.grpcPort(),
 		MetaFolder:        *m.metaFolder,
 		VolumeSizeLimitMB: *m.volumeSizeLimitMB,
 		VolumePreallocate: *m.volumePreallocate,
@@ -210,5 +342,8 @@ func (m *MasterOptions) toMasterOption(whiteList []string) *weed_server.MasterOp
 		DisableHttp:             *m.disableHttp,
 		MetricsAddress:          *m.metricsAddress,
 		MetricsIntervalSec:      *m.metricsIntervalSec,
+		MetricsJobLabel:         *m.metricsJobLabel,
+		MetricsInstanceLabel:    *m.metricsInstanceLabel,
+		SequencerType:           *m.sequencerType,
 	}
 }
@@ -148,6 +148,7 @@ func (s3opt *S3Options) startS3Server() bool {
 	// metrics read from the filer
 	var metricsAddress string
 	var metricsIntervalSec int
+	var metricsJobLabel, metricsInstanceLabel string
 
 	for {
 		err = pb.WithGrpcFilerClient(filerGrpcAddress, grpcDialOption, func(client filer_pb.SeaweedFilerClient) error {
@@ -157,6 +158,7 @@ func (s3opt *S3Options) startS3Server() bool {
 			}
 			filerBucketsPath = resp.DirBuckets
 			metricsAddress, metricsIntervalSec = resp.MetricsAddress, int(resp.MetricsIntervalSec)
+			metricsJobLabel, metricsInstanceLabel = resp.MetricsJobLabel, resp.MetricsInstanceLabel
 			glog.V(0).Infof("S3 read filer buckets dir: %s", filerBucketsPath)
 			return nil
 		})
@@ -169,12 +171,12 @@ func (s3opt *S3Options) startS3Server() bool {
 		}
 	}
 
-	go stats_collect.LoopPushingMetric("s3", stats_collect.SourceName(uint32(*s3opt.port)), metricsAddress, metricsIntervalSec)
+	go stats_collect.LoopPushingMetric("s3", stats_collect.SourceName(uint32(*s3opt.port)), metricsAddress, metricsIntervalSec, metricsJobLabel, metricsInstanceLabel)
 
 	router := mux.NewRouter().SkipClean(true)
 
 	_, s3ApiServer_err := s3api.NewS3ApiServer(router, &s3api.S3ApiServerOption{
-		Filer:            *s3opt.filer,
+		Filer:            pb.ServerToHttpAddress(*s3opt.filer),
 		Port:             *s3opt.port,
 		FilerGrpcAddress: filerGrpcAddress,
 		Config:           *s3opt.config,
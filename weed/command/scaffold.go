@@ -66,26 +66,47 @@ const (
 #    ./filer.toml
 #    $HOME/.seaweedfs/filer.toml
 #    /etc/seaweedfs/filer.toml
+#
+# Without a filer.toml, the filer uses leveldb2 in the -defaultStoreDir folder.
+# Each option is described as: type, the value if the option is left out, and what it does.
+# Every option can be overwritten by an environment variable, e.g. WEED_MYSQL_PASSWORD for mysql.password.
+#
+# Other filer settings are in separate files:
+#    notification.toml  where to send the metadata change events, see "weed scaffold -config=notification"
+#    security.toml      TLS for gRPC and JWT for http, see "weed scaffold -config=security"
 
 ####################################################
 # Customizable filer server options
 ####################################################
 [filer.options]
-# with http DELETE, by default the filer would check whether a folder is empty.
+# bool, false: with http DELETE, by default the filer would check whether a folder is empty.
 # recursive_delete will delete all sub folders and files, similar to "rm -Rf"
 recursive_delete = false
-# directories under this folder will be automatically creating a separate bucket
+# string, "/buckets": directories under this folder will be automatically creating a separate bucket
 buckets_folder = "/buckets"
+# list of strings, empty: deprecated, use "fsync" of "weed shell fs.configure" instead.
+# fsync the writes to the buckets in this list
+# buckets_fsync = [ "important_bucket" ]
 
 ####################################################
 # The following are filer store options
+# Enable exactly one of them.
 ####################################################
 
 [leveldb2]
 # local on disk, mostly for simple single-machine setup, fairly scalable
 # faster than previous leveldb, recommended.
+# bool, false: use this store
 enabled = true
-dir = "."					# directory to store level db files
+# string, empty: directory to store level db files
+dir = "."
+
+[leveldb]
+# the previous local on disk store, kept for existing setups. Use leveldb2 for new setups.
+# bool, false: use this store
+enabled = false
+# string, empty: directory to store level db files
+dir = "./filerldb"
 
 [mysql]  # or tidb
 # CREATE TABLE IF NOT EXISTS filemeta (
@@ -96,32 +117,50 @@ dir = "."					# directory to store level db files
 #   PRIMARY KEY (dirhash, name)
 # ) DEFAULT CHARSET=utf8;
 
+# bool, false: use this store
 enabled = false
+# string, empty: mysql server host name or ip
 hostname = "localhost"
+# int, 0: mysql server port
 port = 3306
+# string, empty: user name
 username = "root"
+# string, empty: password, better set with WEED_MYSQL_PASSWORD
 password = ""
-database = ""              # create or use an existing database
+# string, empty: create or use an existing database
+database = ""
+# int, 0: maximum number of idle connections
 connection_max_idle = 2
+# int, 0 for unlimited: maximum number of open connections
 connection_max_open = 100
+# bool, false: let the driver interpolate the query parameters, saving a round trip per query
 interpolateParams = false
 
 [postgres] # or cockroachdb
 # CREATE TABLE IF NOT EXISTS filemeta (
 #   dirhash     BIGINT,
-#   name        VARCHAR(65535),
+#   name        VARCHAR(65535) COLLATE "C", -- lets the primary key serve the prefixed listings
 #   directory   VARCHAR(65535),
 #   meta        bytea,
 #   PRIMARY KEY (dirhash, name)
 # );
+# bool, false: use this store
 enabled = false
+# string, empty: postgres server host name or ip
 hostname = "localhost"
+# int, 0: postgres server port
 port = 5432
+# string, empty: user name
 username = "postgres"
+# string, empty: password, better set with WEED_POSTGRES_PASSWORD
 password = ""
-database = ""              # create or use an existing database
+# string, empty: create or use an existing database
+database = ""
+# string, empty: disable, allow, prefer, require, verify-ca, or verify-full
 sslmode = "disable"
+# int, 0: maximum number of idle connections
 connection_max_idle = 100
+# int, 0 for unlimited: maximum number of open connections
 connection_max_open = 100
 
 [cassandra]
@@ -131,22 +170,33 @@ connection_max_open = 100
 #    meta blob,
 #    PRIMARY KEY (directory, name)
 # ) WITH CLUSTERING ORDER BY (name ASC);
+# bool, false: use this store
 enabled = false
+# string, empty: the keyspace with the filemeta table
 keyspace="seaweedfs"
+# list of strings, empty: cassandra nodes as host:port
 hosts=[
 	"localhost:9042",
 ]
+# string, empty: user name, if the cluster requires authentication
 username=""
+# string, empty: password, better set with WEED_CASSANDRA_PASSWORD
 password=""
 
 [redis2]
+# bool, false: use this store
 enabled = false
+# string, empty: redis server host:port
 address  = "localhost:6379"
+# string, empty: password, better set with WEED_REDIS2_PASSWORD
 password = ""
+# int, 0: the redis database number
 database = 0
 
 [redis_cluster2]
+# bool, false: use this store
 enabled = false
+# list of strings, empty: redis cluster nodes as host:port
 addresses = [
     "localhost:30001",
     "localhost:30002",
@@ -155,34 +205,121 @@ addresses = [
     "localhost:30005",
     "localhost:30006",
 ]
+# string, empty: password, better set with WEED_REDIS_CLUSTER2_PASSWORD
 password = ""
-# allows reads from slave servers or the master, but all writes still go to the master
-readOnly = true
-# automatically use the closest Redis server for reads
+# bool, false: allows reads from slave servers or the master, but all writes still go to the master
+useReadOnly = true
+# bool, false: automatically use the closest Redis server for reads
+routeByLatency = true
+
+[redis3]
+# like redis2, with the children of each directory split into sorted sets, for directories with millions of entries
+# bool, false: use this store
+enabled = false
+# string, empty: redis server host:port
+address  = "localhost:6379"
+# string, empty: password, better set with WEED_REDIS3_PASSWORD
+password = ""
+# int, 0: the redis database number
+database = 0
+# int, 8: number of sorted sets holding the children of each directory. Do not change it once files are stored.
+directory_shards = 8
+
+[redis_cluster3]
+# like redis_cluster2, with the children of each directory spread over the cluster, for directories with millions of entries
+# bool, false: use this store
+enabled = false
+# list of strings, empty: redis cluster nodes as host:port
+addresses = [
+    "localhost:30001",
+    "localhost:30002",
+    "localhost:30003",
+    "localhost:30004",
+    "localhost:30005",
+    "localhost:30006",
+]
+# string, empty: password, better set with WEED_REDIS_CLUSTER3_PASSWORD
+password = ""
+# bool, false: allows reads from slave servers or the master, but all writes still go to the master
+useReadOnly = true
+# bool, false: automatically use the closest Redis server for reads
+routeByLatency = true
+# int, 8: number of sorted sets holding the children of each directory. Do not change it once files are stored.
+directory_shards = 8
+
+[redis]
+# the previous redis store, kept for existing setups. Use redis2 for new setups.
+# bool, false: use this store
+enabled = false
+# string, empty: redis server host:port
+address  = "localhost:6379"
+# string, empty: password, better set with WEED_REDIS_PASSWORD
+password = ""
+# int, 0: the redis database number
+database = 0
+
+[redis_cluster]
+# the previous redis cluster store, kept for existing setups. Use redis_cluster2 for new setups.
+# bool, false: use this store
+enabled = false
+# list of strings, empty: redis cluster nodes as host:port
+addresses = [
+    "localhost:30001",
+    "localhost:30002",
+    "localhost:30003",
+]
+# string, empty: password, better set with WEED_REDIS_CLUSTER_PASSWORD
+password = ""
+# bool, false: allows reads from slave servers or the master, but all writes still go to the master
+useReadOnly = true
+# bool, false: automatically use the closest Redis server for reads
 routeByLatency = true
 
 [etcd]
+# bool, false: use this store
 enabled = false
+# string, empty: comma separated etcd servers as host:port
 servers = "localhost:2379"
+# string, empty: timeout to connect to etcd, as a duration like "3s"
 timeout = "3s"
+# string, empty: prefix of all the filer keys, to share the etcd cluster with other applications
+key_prefix = ""
+
+[tikv]
+# only available in a weed binary built with "go build -tags tikv"
+# bool, false: use this store
+enabled = false
+# string, empty: comma separated placement driver addresses of the TiKV cluster
+pdaddrs = "localhost:2379"
 
 [mongodb]
+# bool, false: use this store
 enabled = false
+# string, empty: mongodb connection string
 uri = "mongodb://localhost:27017"
+# int, 0 for the driver default: maximum number of connections
 option_pool_size = 0
+# string, empty: the database for the filemeta collection
 database = "seaweedfs"
 
 [elastic7]
+# bool, false: use this store
 enabled = false
+# list of strings, empty: elastic search nodes as urls
 servers = [
     "http://localhost1:9200",
     "http://localhost2:9200",
     "http://localhost3:9200",
 ]
+# string, empty: user name, if the cluster requires authentication
 username = ""
+# string, empty: password, better set with WEED_ELASTIC7_PASSWORD
 password = ""
+# bool, false: discover the other nodes of the cluster
 sniff_enabled = false
+# bool, false: periodically check the health of the nodes
 healthcheck_enabled = false
+# int, 0: page size to list a directory.
 # increase the value is recommend, be sure the value in Elastic is greater or equal here
 index.max_result_window = 10000
 `
@@ -258,6 +395,12 @@ grpcAddress = "localhost:18888"
 # this is not a directory on your hard drive, but on your filer.
 # i.e., all files with this "prefix" are sent to notification message queue.
 directory = "/buckets"
+# optionally, only replicate these sub directories, e.g. ["/buckets/important"]
+includeDirectories = []
+# optionally, skip these sub directories, e.g. ["/buckets/important/tmp"]
+excludeDirectories = []
+# optionally, only replicate the files in these collections, e.g. ["important"]
+collections = []
 
 [sink.filer]
 enabled = false
@@ -293,7 +436,7 @@ directory = "/"                     # destination directory
 enabled = false
 account_name = ""
 account_key  = ""
-container = "mycontainer"      # an existing container
+container = "mycontainer"      # created if missing
 directory = "/"                # destination directory
 
 [sink.backblaze]
@@ -323,6 +466,25 @@ expires_after_seconds = 10           # seconds
 key = ""
 expires_after_seconds = 10           # seconds
 
+# encrypt the needle data of the new volumes at rest with AES-256-GCM, read by volume server.
+# The id of the key is kept in the .vif file of each volume, so the volumes encrypted with
+# the older keys can still be read. To rotate the key, add a new key to the front, and vacuum the
+# volumes with "curl http://<master>:9333/vol/vacuum?garbageThreshold=0" to re-encrypt them with the new key.
+# Keep the older keys until all volumes are re-encrypted.
+[volume.encryption.local]
+enabled = false
+# each key is "<key id>:<base64 encoded 32 bytes>", e.g. from "openssl rand -base64 32".
+# the first key encrypts the new volumes and the vacuumed volumes.
+keys = [
+  # "2021-01:...",
+]
+
+# basic authentication for "weed webdav" and "weed server -webdav", not required if the user is empty.
+# serve webdav with https to keep the password private. Windows clients also only accept basic authentication over https.
+[webdav]
+user = ""
+password = ""
+
 # all grpc tls authentications are mutual
 # the values for the following ca, cert, and key are paths to the PERM files.
 # the host name is not checked, so the PERM files can be shared.
@@ -383,16 +545,21 @@ scripts = """
   unlock
 """
 sleep_minutes = 17          # sleep minutes between each script execution
+ec_rebuild_delay_minutes = 10  # run "ec.rebuild -force" this long after a volume server with ec shards is gone, 0 to disable
+replication_repair_delay_minutes = 0  # run "volume.fix.replication" this long after a volume server with volumes is gone, 0 to disable
 
 [master.filer]
 default = "localhost:8888"    # used by maintenance scripts if the scripts needs to use fs related commands
 
 
 [master.sequencer]
-type = "raft"     # Choose [raft|etcd] type for storing the file id sequence
+type = "raft"     # Choose [raft|etcd|snowflake] type for storing the file id sequence
 # when sequencer.type = etcd, set listen client urls of etcd cluster that store file id sequence
 # example : http://127.0.0.1:2379,http://127.0.0.1:2389
 sequencer_etcd_urls = "http://127.0.0.1:2379"
+# when sequencer.type = snowflake, the file ids are generated locally on each master,
+# set a different id, 1~1023, for each master. If 0, it is derived from the master address.
+sequencer_snowflake_id = 0
 
 
 # configurations for tiered cloud storage
@@ -405,6 +572,14 @@ sequencer_etcd_urls = "http://127.0.0.1:2379"
 	region = "us-east-2"
 	bucket = "your_bucket_name"    # an existing bucket
 	endpoint = ""
+	# Google Cloud Storage, with an HMAC key from its interoperability settings
+	[storage.backend.s3.gcs]
+	enabled = false
+	aws_access_key_id     = ""     # the HMAC access key
+	aws_secret_access_key = ""     # the HMAC secret
+	region = "auto"
+	bucket = "your_bucket_name"    # an existing bucket
+	endpoint = "https://storage.googleapis.com"
 
 # create this number of logical volumes if no more writable volumes
 # count_x means how many copies of data.
@@ -426,5 +601,29 @@ copy_other = 1            # create n x 1 = n actual volumes
 # if you are doing your own replication or periodic sync of volumes.
 treat_replication_as_minimums = false
 
+# rules to place the replicas of new volumes, by the tags of the volume servers, "weed volume -tags=ssd,chassis=c7"
+# the rules of all enabled [master.placement.<rule name>] sections apply together
+[master.placement]
+	[master.placement.hot]
+	enabled = false
+	collection = ""           # empty for all collections
+	require_tags = ["ssd"]    # only use servers with all these tags
+	exclude_tags = []         # never use servers with any of these tags
+	prefer_tags = []          # prefer servers with all these tags, but fall back to the other servers
+	distinct_tag = "chassis"  # never place two replicas on servers with the same chassis=<value> tag
+
+# send the topology events from the master leader as json, so monitoring can react without polling /dir/status
+# event types: volume_server_up, volume_server_down, volume_full, volume_read_only, leader_change
+[master.notification]
+webhook_urls = []         # e.g. ["http://localhost:9000/seaweedfs/events"]
+events = []               # empty for all event types
+	# also send the events to a message queue, configured the same as in notification.toml
+	[master.notification.kafka]
+	enabled = false
+	hosts = [
+	  "localhost:9092"
+	]
+	topic = "seaweedfs_master"
+
 `
 )
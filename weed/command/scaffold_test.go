@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/spf13/viper"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
 )
 
 func TestReadingTomlConfiguration(t *testing.T) {
@@ -42,3 +44,19 @@ enabled = true
 
 	fmt.Printf("alpha ip is %v\n", alpha.GetString("ip"))
 }
+
+func TestFilerTomlExample(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(bytes.NewBufferString(FILER_TOML_EXAMPLE)); err != nil {
+		t.Fatalf("parse filer.toml example: %v", err)
+	}
+	for _, name := range filer.MetadataBackendNames() {
+		if !v.IsSet(name + ".enabled") {
+			t.Errorf("filer.toml example has no [%s] section", name)
+		}
+		if v.GetBool(name+".enabled") != (name == "leveldb2") {
+			t.Errorf("filer.toml example: %s.enabled is %v", name, v.GetBool(name+".enabled"))
+		}
+	}
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"runtime/pprof"
@@ -10,7 +11,11 @@ import (
 
 	stats_collect "github.com/chrislusf/seaweedfs/weed/stats"
 
+	"github.com/chrislusf/seaweedfs/weed/alert"
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -24,6 +29,7 @@ var (
 	masterOptions    MasterOptions
 	filerOptions     FilerOptions
 	s3Options        S3Options
+	webdavOptions    WebDavOption
 	msgBrokerOptions MessageBrokerOptions
 )
 
@@ -42,7 +48,7 @@ var cmdServer = &Command{
   So other volume servers can connect to this master server also.
 
   Optionally, a filer server can be started.
-  Also optionally, a S3 gateway can be started.
+  Also optionally, a S3 gateway and a WebDAV server can be started.
 
   `,
 }
@@ -59,11 +65,18 @@ var (
 	volumeMaxDataVolumeCounts = cmdServer.Flag.String("volume.max", "8", "maximum numbers of volumes, count[,count]... If set to zero, the limit will be auto configured.")
 	volumeMinFreeSpacePercent = cmdServer.Flag.String("volume.minFreeSpacePercent", "1", "minimum free disk space (default to 1%). Low disk space will mark all volumes as ReadOnly.")
 	serverMetricsHttpPort     = cmdServer.Flag.Int("metricsPort", 0, "Prometheus metrics listen port")
+	serverMetricsByCollection = cmdServer.Flag.Bool("metricsLabelByCollection", false, "label the volume metrics by collection, for at most 100 collections")
+	serverTlsMinVersion       = cmdServer.Flag.String("tls.minVersion", "1.2", "minimum TLS version of the grpc and https servers: 1.0, 1.1, 1.2, or 1.3")
+	serverTlsCipherSuites     = cmdServer.Flag.String("tls.cipherSuites", "", "comma separated TLS 1.2 cipher suites of the grpc and https servers, default to the Go defaults")
+	serverAlertPagerDutyKey   = cmdServer.Flag.String("alert.pagerduty.apiKey", "", "PagerDuty Events API v2 routing key to alert on critical conditions")
+	serverAlertOpsgenieKey    = cmdServer.Flag.String("alert.opsgenie.apiKey", "", "Opsgenie API key to alert on critical conditions")
+	serverTracingEndpoint     = cmdServer.Flag.String("tracing.otlpEndpoint", "", "OpenTelemetry collector to export traces to with OTLP over http, e.g. localhost:4318")
 
 	// pulseSeconds              = cmdServer.Flag.Int("pulseSeconds", 5, "number of seconds between heartbeats")
 	isStartingVolumeServer = cmdServer.Flag.Bool("volume", true, "whether to start volume server")
 	isStartingFiler        = cmdServer.Flag.Bool("filer", false, "whether to start filer")
 	isStartingS3           = cmdServer.Flag.Bool("s3", false, "whether to start S3 gateway")
+	isStartingWebDav       = cmdServer.Flag.Bool("webdav", false, "whether to start WebDAV gateway")
 	isStartingMsgBroker    = cmdServer.Flag.Bool("msgBroker", false, "whether to start message broker")
 
 	serverWhiteList []string
@@ -75,6 +88,7 @@ func init() {
 	serverOptions.cpuprofile = cmdServer.Flag.String("cpuprofile", "", "cpu profile output file")
 
 	masterOptions.port = cmdServer.Flag.Int("master.port", 9333, "master server http listen port")
+	masterOptions.portGrpc = cmdServer.Flag.Int("master.port.grpc", 0, "master server grpc listen port, default to http port + 10000")
 	masterOptions.metaFolder = cmdServer.Flag.String("master.dir", "", "data directory to store meta data, default to same as -dir specified")
 	masterOptions.peers = cmdServer.Flag.String("master.peers", "", "all master nodes in comma separated ip:masterPort list")
 	masterOptions.volumeSizeLimitMB = cmdServer.Flag.Uint("master.volumeSizeLimitMB", 30*1000, "Master stops directing writes to oversized volumes.")
@@ -83,28 +97,66 @@ func init() {
 	masterOptions.garbageThreshold = cmdServer.Flag.Float64("garbageThreshold", 0.3, "threshold to vacuum and reclaim spaces")
 	masterOptions.metricsAddress = cmdServer.Flag.String("metrics.address", "", "Prometheus gateway address")
 	masterOptions.metricsIntervalSec = cmdServer.Flag.Int("metrics.intervalSeconds", 15, "Prometheus push interval in seconds")
+	masterOptions.metricsJobLabel = cmdServer.Flag.String("metrics.jobLabel", "", "Prometheus job label for pushed metrics, default to the component name")
+	masterOptions.metricsInstanceLabel = cmdServer.Flag.String("metrics.instanceLabel", "", "prefix of the Prometheus instance label for pushed metrics")
 	masterOptions.raftResumeState = cmdServer.Flag.Bool("resumeState", false, "resume previous state on start master server")
+	masterOptions.raftSnapshotInterval = cmdServer.Flag.Duration("master.raftSnapshotInterval", time.Hour, "how often to snapshot the master state and compact the raft log, 0 to only snapshot when the log grows over 400 entries")
+	masterOptions.consulEnabled = cmdServer.Flag.Bool("master.consul.enabled", false, "register the master as the seaweedfs-master service with the consul agent")
+	masterOptions.consulAgentAddress = cmdServer.Flag.String("master.consul.agentAddress", "localhost:8500", "consul agent http address")
+	masterOptions.consulCheckInterval = cmdServer.Flag.Duration("master.consul.healthCheckInterval", 10*time.Second, "how often consul checks the master health")
+	masterOptions.sequencerType = cmdServer.Flag.String("master.sequencerType", "", "file id sequencer: raft, etcd, or snowflake, overrides master.sequencer.type in master.toml")
 
 	filerOptions.collection = cmdServer.Flag.String("filer.collection", "", "all data will be stored in this collection")
 	filerOptions.port = cmdServer.Flag.Int("filer.port", 8888, "filer server http listen port")
+	filerOptions.portGrpc = cmdServer.Flag.Int("filer.port.grpc", 0, "filer server grpc listen port, default to http port + 10000")
 	filerOptions.publicPort = cmdServer.Flag.Int("filer.port.public", 0, "filer server public http listen port")
 	filerOptions.defaultReplicaPlacement = cmdServer.Flag.String("filer.defaultReplicaPlacement", "", "default replication type. If not specified, use master setting.")
 	filerOptions.disableDirListing = cmdServer.Flag.Bool("filer.disableDirListing", false, "turn off directory listing")
 	filerOptions.maxMB = cmdServer.Flag.Int("filer.maxMB", 32, "split files larger than the limit")
 	filerOptions.dirListingLimit = cmdServer.Flag.Int("filer.dirListLimit", 1000, "limit sub dir listing size")
 	filerOptions.cipher = cmdServer.Flag.Bool("filer.encryptVolumeData", false, "encrypt data on volume servers")
+	filerOptions.maxBodySizeBytes = cmdServer.Flag.Int64("filer.maxBodySizeBytes", 0, "if positive, reject uploads with a larger request body with 413")
+	filerOptions.maxSymlinkDepth = cmdServer.Flag.Int("filer.maxSymlinkDepth", 8, "follow at most this many symlinks when reading a file over http, 0 to not follow symlinks")
+	filerOptions.twoPhaseCommit = cmdServer.Flag.Bool("filer.twoPhaseCommit", false, "write files as pending on volume servers, and confirm them after the metadata is saved, so uploads interrupted by a crash do not leave orphaned files")
 	filerOptions.peers = cmdServer.Flag.String("filer.peers", "", "all filers sharing the same filer store in comma separated ip:port list")
+	filerOptions.searchEnabled = cmdServer.Flag.Bool("filer.search.enabled", false, "maintain a full-text index of .txt, .json, and .html files, served at /filer/search?q=..., a path reserved while enabled, also on the readonly port")
+	filerOptions.hedgeReadAfterMs = cmdServer.Flag.Int("filer.hedgeReadAfterMs", 0, "if positive, also read a chunk from another replica when the first one does not respond in this many milliseconds")
+	filerOptions.searchDir = cmdServer.Flag.String("filer.search.dir", "", "directory to store the full-text index")
 
 	serverOptions.v.port = cmdServer.Flag.Int("volume.port", 8080, "volume server http listen port")
+	serverOptions.v.diskType = cmdServer.Flag.String("volume.disk", "", "disk type of each -dir, type[,type]..., e.g. ssd or hdd. Default to hdd")
+	serverOptions.v.tags = cmdServer.Flag.String("volume.tags", "", "comma separated tags of the volume server for the master placement rules, e.g. ssd,chassis=c7")
+	serverOptions.v.portGrpc = cmdServer.Flag.Int("volume.port.grpc", 0, "volume server grpc listen port, default to http port + 10000")
 	serverOptions.v.publicPort = cmdServer.Flag.Int("volume.port.public", 0, "volume server public port")
 	serverOptions.v.indexType = cmdServer.Flag.String("volume.index", "memory", "Choose [memory|leveldb|leveldbMedium|leveldbLarge] mode for memory~performance balance.")
 	serverOptions.v.fixJpgOrientation = cmdServer.Flag.Bool("volume.images.fix.orientation", false, "Adjust jpg orientation when uploading.")
 	serverOptions.v.readRedirect = cmdServer.Flag.Bool("volume.read.redirect", true, "Redirect moved or non-local volumes.")
 	serverOptions.v.compactionMBPerSecond = cmdServer.Flag.Int("volume.compactionMBps", 0, "limit compaction speed in mega bytes per second")
+	serverOptions.v.resumeFreeSpacePercent = cmdServer.Flag.String("volume.resumeFreeSpacePercent", "", "after low disk space, mark the volumes writable again above this free disk space. Default to twice the -volume.minFreeSpacePercent")
+	serverOptions.v.replicationMBPerSecond = cmdServer.Flag.Int("volume.replicationMBps", 0, "limit copying volumes to and from other volume servers in mega bytes per second")
+	serverOptions.v.ecMBPerSecond = cmdServer.Flag.Int("volume.ecMBps", 0, "limit copying erasure coding shards to and from other volume servers in mega bytes per second")
 	serverOptions.v.fileSizeLimitMB = cmdServer.Flag.Int("volume.fileSizeLimitMB", 256, "limit file size to avoid out of memory")
 	serverOptions.v.publicUrl = cmdServer.Flag.String("volume.publicUrl", "", "publicly accessible address")
 	serverOptions.v.preStopSeconds = cmdServer.Flag.Int("volume.preStopSeconds", 10, "number of seconds between stop send heartbeats and stop volume server")
 	serverOptions.v.pprof = cmdServer.Flag.Bool("volume.pprof", false, "enable pprof http handlers. precludes --memprofile and --cpuprofile")
+	serverOptions.v.recentWriteCacheSize = cmdServer.Flag.Int("volume.recentWriteCacheSize", 1000, "number of most recently written files kept in memory to serve reads right after writes, 0 to disable")
+	serverOptions.v.recentWriteCacheSizeMB = cmdServer.Flag.Int("volume.recentWriteCacheSizeMB", 64, "limit the memory of the recent write cache, files larger than 1/8 of it are not cached, 0 to disable")
+	serverOptions.v.startupJitterMs = cmdServer.Flag.Int("volume.startupJitterMs", 0, "wait a random duration up to this many milliseconds before the first heartbeat")
+	serverOptions.v.idleConnTimeoutSec = cmdServer.Flag.Int("volume.idleConnTimeoutSec", 30, "close keep-alive http connections idle for this many seconds")
+	serverOptions.v.k8sLabelsFile = cmdServer.Flag.String("volume.k8sLabelsFile", "/etc/podinfo/labels", "the pod labels file mounted by the kubernetes downward API")
+	serverOptions.v.k8sNodeLabel = cmdServer.Flag.String("volume.k8sNodeLabel", "", "if -rack is empty, use the value of this pod label as the rack name")
+	serverOptions.v.k8sZoneLabel = cmdServer.Flag.String("volume.k8sZoneLabel", "", "if -dataCenter is empty, use the value of this pod label as the data center name")
+	serverOptions.v.prewarmCacheOnStart = cmdServer.Flag.Bool("volume.prewarmCacheOnStart", false, "track the recently accessed files, and read them back into the cache in the background after a restart")
+	serverOptions.v.prewarmMaxMBPS = cmdServer.Flag.Int("volume.prewarmMaxMBPS", 10, "limit the prewarming reads in mega bytes per second")
+	serverOptions.v.indexPreallocSize = cmdServer.Flag.Int("volume.indexPreallocSize", 100000, "for -index=memory, allocate the needle index of each volume in blocks of this many entries")
+	serverOptions.v.indexExactSize = cmdServer.Flag.Int("volume.indexExactSize", 0, "for -index=memory, if positive, allocate the needle index of each volume for exactly this many entries up front")
+	serverOptions.v.scrubCron = cmdServer.Flag.String("volume.scrubCron", "", "read all needles to check the checksums at these times in cron format, e.g. \"0 2 * * *\" for 2am every day")
+	serverOptions.v.scrubMaxMBPS = cmdServer.Flag.Int("volume.scrubMaxMBPS", 10, "limit the scrubbing reads in mega bytes per second")
+	serverOptions.v.autoCompressThresholdBytes = cmdServer.Flag.Int("volume.autoCompressThresholdBytes", 0, "compress the uploaded text, json, and xml files larger than this size, if not compressed by the client")
+	serverOptions.v.compression = cmdServer.Flag.String("volume.compression", "gzip", "the codec to compress the uploaded files, gzip, zstd or none. Overridden by the collection settings and the \"compression\" upload parameter")
+	serverOptions.v.pendingNeedleTtlMinutes = cmdServer.Flag.Int("volume.pendingNeedleTtlMinutes", 60, "delete the files written by a filer with -twoPhaseCommit if not confirmed within this time")
+	serverOptions.v.maxHeaderBytes = cmdServer.Flag.Int("volume.maxHeaderBytes", http.DefaultMaxHeaderBytes, "maximum size of http request headers in bytes")
+	serverOptions.v.cdnOriginSecret = cmdServer.Flag.String("volume.cdnOriginSecret", "", "if set, reads on the public port require a CDN signed url with token and expires parameters, requires a separate -volume.port.public")
 
 	s3Options.port = cmdServer.Flag.Int("s3.port", 8333, "s3 server http listen port")
 	s3Options.domainName = cmdServer.Flag.String("s3.domainName", "", "suffix of the host name in comma separated list, {bucket}.{domainName}")
@@ -112,6 +164,13 @@ func init() {
 	s3Options.tlsCertificate = cmdServer.Flag.String("s3.cert.file", "", "path to the TLS certificate file")
 	s3Options.config = cmdServer.Flag.String("s3.config", "", "path to the config file")
 
+	webdavOptions.port = cmdServer.Flag.Int("webdav.port", 7333, "webdav server http listen port")
+	webdavOptions.collection = cmdServer.Flag.String("webdav.collection", "", "collection to create the files")
+	webdavOptions.tlsPrivateKey = cmdServer.Flag.String("webdav.key.file", "", "path to the TLS private key file")
+	webdavOptions.tlsCertificate = cmdServer.Flag.String("webdav.cert.file", "", "path to the TLS certificate file")
+	webdavOptions.cacheDir = cmdServer.Flag.String("webdav.cacheDir", os.TempDir(), "local cache directory for file chunks")
+	webdavOptions.cacheSizeMB = cmdServer.Flag.Int64("webdav.cacheCapacityMB", 1000, "local cache capacity in MB")
+
 	msgBrokerOptions.port = cmdServer.Flag.Int("msgBroker.port", 17777, "broker gRPC listen port")
 
 }
@@ -133,11 +192,14 @@ func runServer(cmd *Command, args []string) bool {
 	if *isStartingS3 {
 		*isStartingFiler = true
 	}
+	if *isStartingWebDav {
+		*isStartingFiler = true
+	}
 	if *isStartingMsgBroker {
 		*isStartingFiler = true
 	}
 
-	_, peerList := checkPeers(*serverIp, *masterOptions.port, *masterOptions.peers)
+	_, peerList := checkPeers(*serverIp, *masterOptions.port, masterOptions.grpcPort(), *masterOptions.peers)
 	peers := strings.Join(peerList, ",")
 	masterOptions.peers = &peers
 
@@ -162,14 +224,23 @@ func runServer(cmd *Command, args []string) bool {
 
 	filerOptions.dataCenter = serverDataCenter
 	filerOptions.rack = serverRack
+	filerOptions.pendingNeedleTtlMinutes = serverOptions.v.pendingNeedleTtlMinutes
 	filerOptions.disableHttp = serverDisableHttp
 	masterOptions.disableHttp = serverDisableHttp
 
-	filerAddress := fmt.Sprintf("%s:%d", *serverIp, *filerOptions.port)
+	alert.Configure(*serverAlertPagerDutyKey, *serverAlertOpsgenieKey, fmt.Sprintf("server@%s", *serverIp))
+	tracing.Configure(*serverTracingEndpoint, "seaweedfs")
+
+	filerAddress := pb.NewServerAddress(*serverIp, *filerOptions.port, filerOptions.grpcPort())
 	s3Options.filer = &filerAddress
+	webdavOptions.filer = &filerAddress
 	msgBrokerOptions.filer = &filerAddress
 
 	runtime.GOMAXPROCS(runtime.NumCPU())
+	stats_collect.LabelByCollection = *serverMetricsByCollection
+	if err := security.SetServerTlsOptions(*serverTlsMinVersion, *serverTlsCipherSuites); err != nil {
+		glog.Fatalf("server tls options: %v", err)
+	}
 	go stats_collect.StartMetricsServer(*serverMetricsHttpPort)
 
 	folders := strings.Split(*volumeDataFolders, ",")
@@ -208,6 +279,15 @@ func runServer(cmd *Command, args []string) bool {
 		}()
 	}
 
+	if *isStartingWebDav {
+		go func() {
+			time.Sleep(2 * time.Second)
+
+			webdavOptions.startWebDav()
+
+		}()
+	}
+
 	if *isStartingMsgBroker {
 		go func() {
 			time.Sleep(2 * time.Second)
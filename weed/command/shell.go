@@ -3,6 +3,7 @@ package command
 import (
 	"fmt"
 
+	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/shell"
 	"github.com/chrislusf/seaweedfs/weed/util"
@@ -16,7 +17,7 @@ var (
 func init() {
 	cmdShell.Run = runShell // break init cycle
 	shellOptions.Masters = cmdShell.Flag.String("master", "localhost:9333", "comma-separated master servers")
-	shellInitialFiler = cmdShell.Flag.String("filer", "localhost:8888", "filer host and port")
+	shellInitialFiler = cmdShell.Flag.String("filer", "localhost:8888", "filer host and port, or host:port.grpcPort for a filer with -port.grpc")
 }
 
 var cmdShell = &Command{
@@ -33,11 +34,15 @@ func runShell(command *Command, args []string) bool {
 	shellOptions.GrpcDialOption = security.LoadClientTLS(util.GetViper(), "grpc.client")
 
 	var err error
-	shellOptions.FilerHost, shellOptions.FilerPort, err = util.ParseHostPort(*shellInitialFiler)
+	shellOptions.FilerHost, shellOptions.FilerPort, err = util.ParseHostPort(pb.ServerToHttpAddress(*shellInitialFiler))
 	if err != nil {
 		fmt.Printf("failed to parse filer %s: %v\n", *shellInitialFiler, err)
 		return false
 	}
+	if shellOptions.FilerGrpcAddress, err = pb.ParseFilerGrpcAddress(*shellInitialFiler); err != nil {
+		fmt.Printf("failed to parse filer %s: %v\n", *shellInitialFiler, err)
+		return false
+	}
 	shellOptions.Directory = "/"
 
 	shell.RunShell(shellOptions)
@@ -18,16 +18,20 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util/httpdown"
 
 	"google.golang.org/grpc/reflection"
 
+	"github.com/chrislusf/seaweedfs/weed/alert"
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
 	"github.com/chrislusf/seaweedfs/weed/server"
 	stats_collect "github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle_map"
 	"github.com/chrislusf/seaweedfs/weed/util"
+	"github.com/chrislusf/seaweedfs/weed/util/cpuaffinity"
 )
 
 var (
@@ -35,35 +39,69 @@ var (
 )
 
 type VolumeServerOptions struct {
-	port                  *int
-	publicPort            *int
-	folders               []string
-	folderMaxLimits       []int
-	ip                    *string
-	publicUrl             *string
-	bindIp                *string
-	masters               *string
-	idleConnectionTimeout *int
-	dataCenter            *string
-	rack                  *string
-	whiteList             []string
-	indexType             *string
-	fixJpgOrientation     *bool
-	readRedirect          *bool
-	cpuProfile            *string
-	memProfile            *string
-	compactionMBPerSecond *int
-	fileSizeLimitMB       *int
-	minFreeSpacePercents  []float32
-	pprof                 *bool
-	preStopSeconds        *int
-	metricsHttpPort       *int
+	port                       *int
+	portGrpc                   *int
+	publicPort                 *int
+	folders                    []string
+	folderMaxLimits            []int
+	ip                         *string
+	publicUrl                  *string
+	bindIp                     *string
+	masters                    *string
+	idleConnectionTimeout      *int
+	idleConnTimeoutSec         *int
+	maxHeaderBytes             *int
+	dataCenter                 *string
+	rack                       *string
+	tags                       *string
+	k8sLabelsFile              *string
+	k8sNodeLabel               *string
+	k8sZoneLabel               *string
+	whiteList                  []string
+	indexType                  *string
+	fixJpgOrientation          *bool
+	readRedirect               *bool
+	cpuProfile                 *string
+	memProfile                 *string
+	compactionMBPerSecond      *int
+	replicationMBPerSecond     *int
+	ecMBPerSecond              *int
+	fileSizeLimitMB            *int
+	minFreeSpacePercents       []float32
+	resumeFreeSpacePercent     *string
+	resumeFreeSpacePercents    []float32
+	diskType                   *string
+	diskTypes                  []string
+	pprof                      *bool
+	preStopSeconds             *int
+	metricsHttpPort            *int
+	metricsByCollection        *bool
+	tlsMinVersion              *string
+	tlsCipherSuites            *string
+	cdnOriginSecret            *string
+	recentWriteCacheSize       *int
+	recentWriteCacheSizeMB     *int
+	cpuAffinity                *string
+	startupJitterMs            *int
+	prewarmCacheOnStart        *bool
+	prewarmMaxMBPS             *int
+	pendingNeedleTtlMinutes    *int
+	autoCompressThresholdBytes *int
+	compression                *string
+	indexPreallocSize          *int
+	indexExactSize             *int
+	scrubCron                  *string
+	scrubMaxMBPS               *int
+	alertPagerDutyKey          *string
+	alertOpsgenieKey           *string
+	tracingEndpoint            *string
 	// pulseSeconds          *int
 }
 
 func init() {
 	cmdVolume.Run = runVolume // break init cycle
 	v.port = cmdVolume.Flag.Int("port", 8080, "http listen port")
+	v.portGrpc = cmdVolume.Flag.Int("port.grpc", 0, "grpc listen port, default to http port + 10000")
 	v.publicPort = cmdVolume.Flag.Int("port.public", 0, "port opened to public")
 	v.ip = cmdVolume.Flag.String("ip", util.DetectedHostAddress(), "ip or server name")
 	v.publicUrl = cmdVolume.Flag.String("publicUrl", "", "Publicly accessible address")
@@ -72,17 +110,47 @@ func init() {
 	v.preStopSeconds = cmdVolume.Flag.Int("preStopSeconds", 10, "number of seconds between stop send heartbeats and stop volume server")
 	// v.pulseSeconds = cmdVolume.Flag.Int("pulseSeconds", 5, "number of seconds between heartbeats, must be smaller than or equal to the master's setting")
 	v.idleConnectionTimeout = cmdVolume.Flag.Int("idleTimeout", 30, "connection idle seconds")
+	v.idleConnTimeoutSec = cmdVolume.Flag.Int("idleConnTimeoutSec", 30, "close keep-alive http connections idle for this many seconds")
+	v.maxHeaderBytes = cmdVolume.Flag.Int("maxHeaderBytes", http.DefaultMaxHeaderBytes, "maximum size of http request headers in bytes")
 	v.dataCenter = cmdVolume.Flag.String("dataCenter", "", "current volume server's data center name")
 	v.rack = cmdVolume.Flag.String("rack", "", "current volume server's rack name")
+	v.diskType = cmdVolume.Flag.String("disk", "", "disk type of each -dir, type[,type]..., e.g. ssd or hdd. The master places the volumes by the disk type asked for. Default to hdd")
+	v.tags = cmdVolume.Flag.String("tags", "", "comma separated tags of this server for the master placement rules, e.g. ssd,chassis=c7")
+	v.k8sLabelsFile = cmdVolume.Flag.String("k8sLabelsFile", "/etc/podinfo/labels", "the pod labels file mounted by the kubernetes downward API")
+	v.k8sNodeLabel = cmdVolume.Flag.String("k8sNodeLabel", "", "if -rack is empty, use the value of this pod label as the rack name")
+	v.k8sZoneLabel = cmdVolume.Flag.String("k8sZoneLabel", "", "if -dataCenter is empty, use the value of this pod label as the data center name")
 	v.indexType = cmdVolume.Flag.String("index", "memory", "Choose [memory|leveldb|leveldbMedium|leveldbLarge] mode for memory~performance balance.")
 	v.fixJpgOrientation = cmdVolume.Flag.Bool("images.fix.orientation", false, "Adjust jpg orientation when uploading.")
 	v.readRedirect = cmdVolume.Flag.Bool("read.redirect", true, "Redirect moved or non-local volumes.")
 	v.cpuProfile = cmdVolume.Flag.String("cpuprofile", "", "cpu profile output file")
 	v.memProfile = cmdVolume.Flag.String("memprofile", "", "memory profile output file")
-	v.compactionMBPerSecond = cmdVolume.Flag.Int("compactionMBps", 0, "limit background compaction or copying speed in mega bytes per second")
+	v.compactionMBPerSecond = cmdVolume.Flag.Int("compactionMBps", 0, "limit background compaction speed in mega bytes per second, shared by all compactions")
+	v.replicationMBPerSecond = cmdVolume.Flag.Int("replicationMBps", 0, "limit copying volumes to and from other volume servers in mega bytes per second, e.g. for replication, balancing and backup")
+	v.ecMBPerSecond = cmdVolume.Flag.Int("ecMBps", 0, "limit copying erasure coding shards to and from other volume servers in mega bytes per second")
 	v.fileSizeLimitMB = cmdVolume.Flag.Int("fileSizeLimitMB", 256, "limit file size to avoid out of memory")
+	v.resumeFreeSpacePercent = cmdVolume.Flag.String("resumeFreeSpacePercent", "", "after low disk space, mark the volumes writable again above this free disk space, percent[,percent]... Default to twice the -minFreeSpacePercent")
 	v.pprof = cmdVolume.Flag.Bool("pprof", false, "enable pprof http handlers. precludes --memprofile and --cpuprofile")
 	v.metricsHttpPort = cmdVolume.Flag.Int("metricsPort", 0, "Prometheus metrics listen port")
+	v.metricsByCollection = cmdVolume.Flag.Bool("metricsLabelByCollection", false, "label the volume metrics by collection, for at most 100 collections")
+	v.tlsMinVersion = cmdVolume.Flag.String("tls.minVersion", "1.2", "minimum TLS version of the grpc and https servers: 1.0, 1.1, 1.2, or 1.3")
+	v.tlsCipherSuites = cmdVolume.Flag.String("tls.cipherSuites", "", "comma separated TLS 1.2 cipher suites of the grpc and https servers, default to the Go defaults")
+	v.cdnOriginSecret = cmdVolume.Flag.String("cdnOriginSecret", "", "if set, reads on the public port require a CDN signed url with token and expires parameters, requires a separate -port.public")
+	v.recentWriteCacheSize = cmdVolume.Flag.Int("recentWriteCacheSize", 1000, "number of most recently written files kept in memory to serve reads right after writes, 0 to disable")
+	v.recentWriteCacheSizeMB = cmdVolume.Flag.Int("recentWriteCacheSizeMB", 64, "limit the memory of the recent write cache, files larger than 1/8 of it are not cached, 0 to disable")
+	v.startupJitterMs = cmdVolume.Flag.Int("startupJitterMs", 0, "wait a random duration up to this many milliseconds before the first heartbeat, to avoid flooding the master when many volume servers restart together")
+	v.prewarmCacheOnStart = cmdVolume.Flag.Bool("prewarmCacheOnStart", false, "track the recently accessed files, and read them back into the cache in the background after a restart")
+	v.prewarmMaxMBPS = cmdVolume.Flag.Int("prewarmMaxMBPS", 10, "limit the prewarming reads in mega bytes per second")
+	v.indexPreallocSize = cmdVolume.Flag.Int("indexPreallocSize", 100000, "for -index=memory, allocate the needle index of each volume in blocks of this many entries")
+	v.indexExactSize = cmdVolume.Flag.Int("indexExactSize", 0, "for -index=memory, if positive, allocate the needle index of each volume for exactly this many entries up front")
+	v.scrubCron = cmdVolume.Flag.String("scrubCron", "", "read all needles to check the checksums at these times in cron format, e.g. \"0 2 * * *\" for 2am every day")
+	v.scrubMaxMBPS = cmdVolume.Flag.Int("scrubMaxMBPS", 10, "limit the scrubbing reads in mega bytes per second")
+	v.autoCompressThresholdBytes = cmdVolume.Flag.Int("autoCompressThresholdBytes", 0, "compress the uploaded text, json, and xml files larger than this size, if not compressed by the client")
+	v.compression = cmdVolume.Flag.String("compression", "gzip", "the codec to compress the uploaded files, gzip, zstd or none. Overridden by the collection settings and the \"compression\" upload parameter")
+	v.pendingNeedleTtlMinutes = cmdVolume.Flag.Int("pendingNeedleTtlMinutes", 60, "delete the files written by a filer with -twoPhaseCommit if not confirmed within this time")
+	v.cpuAffinity = cmdVolume.Flag.String("cpuAffinity", "", "pin the volume server to these cpu cores, e.g. 0,1,2,3 or 0-3. Linux only")
+	v.alertPagerDutyKey = cmdVolume.Flag.String("alert.pagerduty.apiKey", "", "PagerDuty Events API v2 routing key to alert on full disks and checksum errors")
+	v.alertOpsgenieKey = cmdVolume.Flag.String("alert.opsgenie.apiKey", "", "Opsgenie API key to alert on full disks and checksum errors")
+	v.tracingEndpoint = cmdVolume.Flag.String("tracing.otlpEndpoint", "", "OpenTelemetry collector to export traces to with OTLP over http, e.g. localhost:4318")
 }
 
 var cmdVolume = &Command{
@@ -102,18 +170,46 @@ var (
 
 func runVolume(cmd *Command, args []string) bool {
 
+	// "weed volume copy ..." is the same as "weed volume.copy ..."
+	if len(args) > 0 && args[0] == "copy" {
+		cmdVolumeCopy.Flag.Usage = func() { cmdVolumeCopy.Usage() }
+		cmdVolumeCopy.Flag.Parse(args[1:])
+		if !cmdVolumeCopy.Run(cmdVolumeCopy, cmdVolumeCopy.Flag.Args()) {
+			cmdVolumeCopy.Usage()
+		}
+		return true
+	}
+
 	util.LoadConfiguration("security", false)
 
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
+	if *v.cpuAffinity != "" {
+		if cpus, err := cpuaffinity.ParseCpuList(*v.cpuAffinity); err != nil {
+			glog.Fatalf("cpuAffinity: %v", err)
+		} else if err = cpuaffinity.Set(cpus); err != nil {
+			glog.Warningf("ignoring cpuAffinity %s: %v", *v.cpuAffinity, err)
+		} else {
+			runtime.GOMAXPROCS(len(cpus))
+			glog.V(0).Infof("pinned to cpus %v", cpus)
+		}
+	}
+
 	// If --pprof is set we assume the caller wants to be able to collect
 	// cpu and memory profiles via go tool pprof
 	if !*v.pprof {
 		grace.SetupProfiling(*v.cpuProfile, *v.memProfile)
 	}
 
+	stats_collect.LabelByCollection = *v.metricsByCollection
+	if err := security.SetServerTlsOptions(*v.tlsMinVersion, *v.tlsCipherSuites); err != nil {
+		glog.Fatalf("volume server tls options: %v", err)
+	}
 	go stats_collect.StartMetricsServer(*v.metricsHttpPort)
 
+	alert.Configure(*v.alertPagerDutyKey, *v.alertOpsgenieKey, fmt.Sprintf("volume@%s:%d", *v.ip, *v.port))
+	tracing.Configure(*v.tracingEndpoint, "volume")
+
 	v.startVolumeServer(*volumeFolders, *maxVolumeCounts, *volumeWhiteListOption, *minFreeSpacePercent)
 
 	return true
@@ -165,6 +261,46 @@ func (v VolumeServerOptions) startVolumeServer(volumeFolders, maxVolumeCounts, v
 		glog.Fatalf("%d directories by -dir, but only %d minFreeSpacePercent is set by -minFreeSpacePercent", len(v.folders), len(v.minFreeSpacePercents))
 	}
 
+	// set resumeFreeSpacePercent
+	if *v.resumeFreeSpacePercent == "" {
+		for _, minFree := range v.minFreeSpacePercents {
+			v.resumeFreeSpacePercents = append(v.resumeFreeSpacePercents, 2*minFree)
+		}
+	} else {
+		for _, freeString := range strings.Split(*v.resumeFreeSpacePercent, ",") {
+			if value, e := strconv.ParseFloat(freeString, 32); e == nil {
+				v.resumeFreeSpacePercents = append(v.resumeFreeSpacePercents, float32(value))
+			} else {
+				glog.Fatalf("The value specified in -resumeFreeSpacePercent not a valid value %s", freeString)
+			}
+		}
+	}
+	if len(v.resumeFreeSpacePercents) == 1 && len(v.folders) > 1 {
+		for i := 0; i < len(v.folders)-1; i++ {
+			v.resumeFreeSpacePercents = append(v.resumeFreeSpacePercents, v.resumeFreeSpacePercents[0])
+		}
+	}
+	if len(v.folders) != len(v.resumeFreeSpacePercents) {
+		glog.Fatalf("%d directories by -dir, but only %d resumeFreeSpacePercent is set by -resumeFreeSpacePercent", len(v.folders), len(v.resumeFreeSpacePercents))
+	}
+
+	// set disk type
+	for _, diskType := range strings.Split(*v.diskType, ",") {
+		v.diskTypes = append(v.diskTypes, storage.ToDiskType(diskType))
+	}
+	if len(v.diskTypes) == 1 && len(v.folders) > 1 {
+		for i := 0; i < len(v.folders)-1; i++ {
+			v.diskTypes = append(v.diskTypes, v.diskTypes[0])
+		}
+	}
+	if len(v.folders) != len(v.diskTypes) {
+		glog.Fatalf("%d directories by -dir, but only %d disk type is set by -disk", len(v.folders), len(v.diskTypes))
+	}
+
+	if !util.IsValidCompression(*v.compression) {
+		glog.Fatalf("unsupported -compression %s, should be gzip, zstd or none", *v.compression)
+	}
+
 	// security related white list configuration
 	if volumeWhiteListOption != "" {
 		v.whiteList = strings.Split(volumeWhiteListOption, ",")
@@ -178,6 +314,12 @@ func (v VolumeServerOptions) startVolumeServer(volumeFolders, maxVolumeCounts, v
 	if *v.publicPort == 0 {
 		*v.publicPort = *v.port
 	}
+	if *v.cdnOriginSecret != "" && !v.isSeparatedPublicPort() {
+		// the filers and replicas read from the same port, without cdn tokens
+		glog.Fatalf("-cdnOriginSecret requires a separate -port.public")
+	}
+
+	v.detectK8sTopology()
 	if *v.publicUrl == "" {
 		*v.publicUrl = *v.ip + ":" + strconv.Itoa(*v.publicPort)
 	}
@@ -196,27 +338,47 @@ func (v VolumeServerOptions) startVolumeServer(volumeFolders, maxVolumeCounts, v
 		volumeMux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
 	}
 
+	needle_map.IndexSectionSize = *v.indexPreallocSize
+	needle_map.IndexExactSize = *v.indexExactSize
+
 	volumeNeedleMapKind := storage.NeedleMapInMemory
 	switch *v.indexType {
+	case "memory":
 	case "leveldb":
 		volumeNeedleMapKind = storage.NeedleMapLevelDb
 	case "leveldbMedium":
 		volumeNeedleMapKind = storage.NeedleMapLevelDbMedium
 	case "leveldbLarge":
 		volumeNeedleMapKind = storage.NeedleMapLevelDbLarge
+	default:
+		glog.Fatalf("unknown -index %s, should be memory, leveldb, leveldbMedium or leveldbLarge", *v.indexType)
 	}
 
 	masters := *v.masters
 
+	var tags []string
+	for _, tag := range strings.Split(*v.tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
 	volumeServer := weed_server.NewVolumeServer(volumeMux, publicVolumeMux,
-		*v.ip, *v.port, *v.publicUrl,
-		v.folders, v.folderMaxLimits, v.minFreeSpacePercents,
+		*v.ip, *v.port, v.grpcPort(), *v.publicUrl,
+		v.folders, v.folderMaxLimits, v.minFreeSpacePercents, v.resumeFreeSpacePercents, v.diskTypes,
 		volumeNeedleMapKind,
-		strings.Split(masters, ","), 5, *v.dataCenter, *v.rack,
+		strings.Split(masters, ","), 5, *v.dataCenter, *v.rack, tags,
 		v.whiteList,
 		*v.fixJpgOrientation, *v.readRedirect,
-		*v.compactionMBPerSecond,
+		*v.compactionMBPerSecond, *v.replicationMBPerSecond, *v.ecMBPerSecond,
 		*v.fileSizeLimitMB,
+		*v.cdnOriginSecret,
+		*v.recentWriteCacheSize, *v.recentWriteCacheSizeMB,
+		*v.startupJitterMs,
+		*v.prewarmCacheOnStart, *v.prewarmMaxMBPS,
+		*v.pendingNeedleTtlMinutes,
+		*v.autoCompressThresholdBytes, *v.compression,
+		*v.scrubCron, *v.scrubMaxMBPS,
 	)
 	// starting grpc server
 	grpcS := v.startGrpcService(volumeServer)
@@ -282,8 +444,15 @@ func (v VolumeServerOptions) isSeparatedPublicPort() bool {
 	return *v.publicPort != *v.port
 }
 
+func (v VolumeServerOptions) grpcPort() int {
+	if *v.portGrpc != 0 {
+		return *v.portGrpc
+	}
+	return *v.port + 10000
+}
+
 func (v VolumeServerOptions) startGrpcService(vs volume_server_pb.VolumeServerServer) *grpc.Server {
-	grpcPort := *v.port + 10000
+	grpcPort := v.grpcPort()
 	grpcL, err := util.NewListener(*v.bindIp+":"+strconv.Itoa(grpcPort), 0)
 	if err != nil {
 		glog.Fatalf("failed to listen on grpc port %d: %v", grpcPort, err)
@@ -299,6 +468,14 @@ func (v VolumeServerOptions) startGrpcService(vs volume_server_pb.VolumeServerSe
 	return grpcS
 }
 
+func (v VolumeServerOptions) newHttpServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:        handler,
+		IdleTimeout:    time.Duration(*v.idleConnTimeoutSec) * time.Second,
+		MaxHeaderBytes: *v.maxHeaderBytes,
+	}
+}
+
 func (v VolumeServerOptions) startPublicHttpService(handler http.Handler) httpdown.Server {
 	publicListeningAddress := *v.bindIp + ":" + strconv.Itoa(*v.publicPort)
 	glog.V(0).Infoln("Start Seaweed volume server", util.Version(), "public at", publicListeningAddress)
@@ -308,7 +485,7 @@ func (v VolumeServerOptions) startPublicHttpService(handler http.Handler) httpdo
 	}
 
 	pubHttp := httpdown.HTTP{StopTimeout: 5 * time.Minute, KillTimeout: 5 * time.Minute}
-	publicHttpDown := pubHttp.Serve(&http.Server{Handler: handler}, publicListener)
+	publicHttpDown := pubHttp.Serve(v.newHttpServer(handler), publicListener)
 	go func() {
 		if err := publicHttpDown.Wait(); err != nil {
 			glog.Errorf("public http down wait failed, %v", err)
@@ -339,7 +516,11 @@ func (v VolumeServerOptions) startClusterHttpService(handler http.Handler) httpd
 		StopTimeout: 5 * time.Minute,
 		CertFile:    certFile,
 		KeyFile:     keyFile}
-	clusterHttpServer := httpDown.Serve(&http.Server{Handler: handler}, listener)
+	httpServer := v.newHttpServer(handler)
+	if certFile != "" {
+		httpServer.TLSConfig = security.ServerTlsConfig(nil)
+	}
+	clusterHttpServer := httpDown.Serve(httpServer, listener)
 	go func() {
 		if e := clusterHttpServer.Wait(); e != nil {
 			glog.Fatalf("Volume server fail to serve: %v", e)
@@ -347,3 +528,22 @@ func (v VolumeServerOptions) startClusterHttpService(handler http.Handler) httpd
 	}()
 	return clusterHttpServer
 }
+
+// detectK8sTopology sets the empty data center and rack from the pod labels.
+func (v VolumeServerOptions) detectK8sTopology() {
+	if (*v.k8sZoneLabel == "" || *v.dataCenter != "") && (*v.k8sNodeLabel == "" || *v.rack != "") {
+		return
+	}
+	labels, err := util.ReadDownwardApiLabels(*v.k8sLabelsFile)
+	if err != nil {
+		glog.Fatalf("read pod labels from %s: %v", *v.k8sLabelsFile, err)
+	}
+	if *v.k8sZoneLabel != "" && *v.dataCenter == "" {
+		*v.dataCenter = labels[*v.k8sZoneLabel]
+		glog.V(0).Infof("data center from pod label %s: %s", *v.k8sZoneLabel, *v.dataCenter)
+	}
+	if *v.k8sNodeLabel != "" && *v.rack == "" {
+		*v.rack = labels[*v.k8sNodeLabel]
+		glog.V(0).Infof("rack from pod label %s: %s", *v.k8sNodeLabel, *v.rack)
+	}
+}
@@ -0,0 +1,218 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func init() {
+	cmdVolumeCopy.Run = runVolumeCopy // break init cycle
+}
+
+var cmdVolumeCopy = &Command{
+	UsageLine: "volume.copy -from=host1:8080 -vid=42 -to=host2:8080 [-replaceSource]",
+	Short:     "copy a volume to another volume server",
+	Long: `Copy a volume to another volume server. Also available as "weed volume copy ...".
+
+  weed volume copy -from=host1:8080 -vid=42 -to=host2:8080
+  weed volume copy -from=host1:8080 -vid=42 -to=host2:8080 -replaceSource
+
+  The source volume is read-only during the copy. The destination server pulls
+  the .dat and .idx files from the source, mounts the volume, and reports it to
+  the master with its next heartbeat. The copy is then verified by comparing the
+  checksums of the .dat and .idx files on both servers, and this command waits
+  until the master lists the destination as a location of the volume.
+
+  With -replaceSource, the source volume is deleted after a successful copy, to
+  move the volume instead. Otherwise the copy is an additional replica, so the
+  volume will have more copies than its replication setting.
+
+  Use the volume server addresses as registered with the master.
+
+  `,
+}
+
+var (
+	volumeCopyFrom          = cmdVolumeCopy.Flag.String("from", "", "the source volume server <host>:<port>")
+	volumeCopyTo            = cmdVolumeCopy.Flag.String("to", "", "the destination volume server <host>:<port>")
+	volumeCopyVolumeId      = cmdVolumeCopy.Flag.Uint("vid", 0, "the volume id")
+	volumeCopyReplaceSource = cmdVolumeCopy.Flag.Bool("replaceSource", false, "delete the source volume after the copy is verified")
+	volumeCopyMaster        = cmdVolumeCopy.Flag.String("master", "localhost:9333", "the master server, to wait for the new volume location")
+	volumeCopyTimeout       = cmdVolumeCopy.Flag.Duration("timeout", time.Minute, "how long to wait for the master to see the new volume location")
+)
+
+func runVolumeCopy(cmd *Command, args []string) bool {
+
+	if *volumeCopyFrom == "" || *volumeCopyTo == "" || *volumeCopyVolumeId == 0 {
+		return false
+	}
+	if *volumeCopyFrom == *volumeCopyTo {
+		fmt.Fprintf(os.Stderr, "the source and destination are both %s\n", *volumeCopyFrom)
+		os.Exit(1)
+	}
+
+	util.LoadConfiguration("security", false)
+	grpcDialOption := security.LoadClientTLS(util.GetViper(), "grpc.client")
+
+	if err := copyVolumeToServer(grpcDialOption, uint32(*volumeCopyVolumeId), *volumeCopyFrom, *volumeCopyTo, *volumeCopyReplaceSource); err != nil {
+		fmt.Fprintf(os.Stderr, "copy volume %d from %s to %s: %v\n", *volumeCopyVolumeId, *volumeCopyFrom, *volumeCopyTo, err)
+		os.Exit(1)
+	}
+	return true
+}
+
+func copyVolumeToServer(grpcDialOption grpc.DialOption, volumeId uint32, source, target string, replaceSource bool) (err error) {
+
+	var status *volume_server_pb.ReadVolumeFileStatusResponse
+	err = operation.WithVolumeServerClient(source, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		var statusErr error
+		status, statusErr = client.ReadVolumeFileStatus(context.Background(), &volume_server_pb.ReadVolumeFileStatusRequest{
+			VolumeId: volumeId,
+		})
+		return statusErr
+	})
+	if err != nil {
+		return fmt.Errorf("read source volume status: %v", err)
+	}
+
+	// keep the source read-only until the copy is verified, so both checksums cover the same content
+	if err = markVolumeServerVolumeWritable(grpcDialOption, source, volumeId, false); err != nil {
+		return err
+	}
+	sourceDeleted := false
+	defer func() {
+		if sourceDeleted {
+			return
+		}
+		if restoreErr := markVolumeServerVolumeWritable(grpcDialOption, source, volumeId, true); restoreErr != nil {
+			fmt.Fprintf(os.Stderr, "restore source volume %d writable: %v\n", volumeId, restoreErr)
+		}
+	}()
+
+	fmt.Printf("copying volume %d (%s) from %s to %s ...\n", volumeId, util.BytesToHumanReadable(status.DatFileSize), source, target)
+	startTime := time.Now()
+	err = operation.WithVolumeServerClient(target, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		_, copyErr := client.VolumeCopy(context.Background(), &volume_server_pb.VolumeCopyRequest{
+			VolumeId:       volumeId,
+			Collection:     status.Collection,
+			SourceDataNode: source,
+		})
+		return copyErr
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("copied in %v\n", time.Since(startTime).Round(time.Millisecond))
+
+	sourceChecksum, err := readVolumeChecksum(grpcDialOption, source, volumeId)
+	if err != nil {
+		return err
+	}
+	targetChecksum, err := readVolumeChecksum(grpcDialOption, target, volumeId)
+	if err != nil {
+		return err
+	}
+	if sourceChecksum.DatFileSize != targetChecksum.DatFileSize || sourceChecksum.DatFileCrc != targetChecksum.DatFileCrc ||
+		sourceChecksum.IdxFileSize != targetChecksum.IdxFileSize || sourceChecksum.IdxFileCrc != targetChecksum.IdxFileCrc {
+		deleteVolumeOnServer(grpcDialOption, target, volumeId)
+		return fmt.Errorf("checksum mismatch, source .dat %d bytes crc %x .idx %d bytes crc %x, copy .dat %d bytes crc %x .idx %d bytes crc %x",
+			sourceChecksum.DatFileSize, sourceChecksum.DatFileCrc, sourceChecksum.IdxFileSize, sourceChecksum.IdxFileCrc,
+			targetChecksum.DatFileSize, targetChecksum.DatFileCrc, targetChecksum.IdxFileSize, targetChecksum.IdxFileCrc)
+	}
+	fmt.Printf("verified .dat %d bytes crc %x, .idx %d bytes crc %x\n",
+		targetChecksum.DatFileSize, targetChecksum.DatFileCrc, targetChecksum.IdxFileSize, targetChecksum.IdxFileCrc)
+
+	if err = waitForVolumeLocation(grpcDialOption, *volumeCopyMaster, volumeId, target, *volumeCopyTimeout); err != nil {
+		return err
+	}
+	fmt.Printf("master %s lists volume %d on %s\n", *volumeCopyMaster, volumeId, target)
+
+	if !replaceSource {
+		return nil
+	}
+	if err = deleteVolumeOnServer(grpcDialOption, source, volumeId); err != nil {
+		return fmt.Errorf("delete source volume: %v", err)
+	}
+	sourceDeleted = true
+	fmt.Printf("deleted volume %d from %s\n", volumeId, source)
+	return nil
+}
+
+func readVolumeChecksum(grpcDialOption grpc.DialOption, server string, volumeId uint32) (resp *volume_server_pb.VolumeChecksumResponse, err error) {
+	err = operation.WithVolumeServerClient(server, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		resp, err = client.VolumeChecksum(context.Background(), &volume_server_pb.VolumeChecksumRequest{
+			VolumeId: volumeId,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checksum volume %d on %s: %v", volumeId, server, err)
+	}
+	return resp, nil
+}
+
+func markVolumeServerVolumeWritable(grpcDialOption grpc.DialOption, server string, volumeId uint32, writable bool) error {
+	return operation.WithVolumeServerClient(server, grpcDialOption, func(client volume_server_pb.VolumeServerClient) (err error) {
+		if writable {
+			_, err = client.VolumeMarkWritable(context.Background(), &volume_server_pb.VolumeMarkWritableRequest{
+				VolumeId: volumeId,
+			})
+		} else {
+			_, err = client.VolumeMarkReadonly(context.Background(), &volume_server_pb.VolumeMarkReadonlyRequest{
+				VolumeId: volumeId,
+			})
+		}
+		return err
+	})
+}
+
+func deleteVolumeOnServer(grpcDialOption grpc.DialOption, server string, volumeId uint32) error {
+	return operation.WithVolumeServerClient(server, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		_, err := client.VolumeDelete(context.Background(), &volume_server_pb.VolumeDeleteRequest{
+			VolumeId: volumeId,
+		})
+		return err
+	})
+}
+
+// waitForVolumeLocation waits for the heartbeat of the server to report the volume to the master.
+func waitForVolumeLocation(grpcDialOption grpc.DialOption, master string, volumeId uint32, server string, timeout time.Duration) error {
+	vid := fmt.Sprintf("%d", volumeId)
+	deadline := time.Now().Add(timeout)
+	for {
+		err := pb.WithMasterClient(master, grpcDialOption, func(client master_pb.SeaweedClient) error {
+			resp, err := client.LookupVolume(context.Background(), &master_pb.LookupVolumeRequest{
+				VolumeIds: []string{vid},
+			})
+			if err != nil {
+				return err
+			}
+			for _, vidLocations := range resp.VolumeIdLocations {
+				for _, location := range vidLocations.Locations {
+					if location.Url == pb.ServerToHttpAddress(server) {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("volume %d is not on %s yet", volumeId, server)
+		})
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("master %s: %v", master, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,329 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+type VolumeMirrorOptions struct {
+	sourceMaster       *string
+	targetMaster       *string
+	collection         *string
+	replication        *string
+	checkpoint         *string
+	interval           *time.Duration
+	idleTimeoutSeconds *int
+}
+
+var (
+	mirrorOptions VolumeMirrorOptions
+)
+
+func init() {
+	cmdVolumeMirror.Run = runVolumeMirror // break init cycle
+	mirrorOptions.sourceMaster = cmdVolumeMirror.Flag.String("source.master", "", "the master of the source cluster, <host>:<port>")
+	mirrorOptions.targetMaster = cmdVolumeMirror.Flag.String("target.master", "", "the master of the follower cluster, <host>:<port>")
+	mirrorOptions.collection = cmdVolumeMirror.Flag.String("collection", "", "comma separated collections to mirror, empty for all collections")
+	mirrorOptions.replication = cmdVolumeMirror.Flag.String("target.replication", "000", "replication of the mirrored volumes in the follower cluster")
+	mirrorOptions.checkpoint = cmdVolumeMirror.Flag.String("checkpoint", "", "file to keep the mirrored position of each volume, to resume after restarts")
+	mirrorOptions.interval = cmdVolumeMirror.Flag.Duration("interval", time.Minute, "how often to look for new volumes in the source cluster")
+	mirrorOptions.idleTimeoutSeconds = cmdVolumeMirror.Flag.Int("idleTimeoutSeconds", 30, "restart tailing a volume after it has no new writes for this long, to save the checkpoint")
+}
+
+var cmdVolumeMirror = &Command{
+	UsageLine: "volume.mirror -source.master=<host>:<port> -target.master=<host>:<port> [-collection=c1,c2]",
+	Short:     "asynchronously mirror the volumes of some collections to a follower cluster",
+	Long: `Asynchronously mirror the volumes of some collections to a follower cluster, for disaster recovery.
+
+  weed volume.mirror -source.master=dc1-master:9333 -target.master=dc2-master:9333 -collection=pictures,docs
+  weed volume.mirror -source.master=dc1-master:9333 -target.master=dc2-master:9333 -checkpoint=/data/mirror.json
+
+  The volumes of the selected collections are created in the follower cluster with the same volume ids,
+  and each of them tails the needle append log of the source volume. The volume server in the follower
+  cluster pulls the new writes and deletions directly from a volume server holding the source volume,
+  so the writes to the source cluster do not wait for the mirror.
+
+  The source cluster is checked for new volumes every -interval. The mirrored position of each volume is
+  saved to the -checkpoint file whenever the volume has caught up. Without a checkpoint, or after a crash,
+  the volumes are tailed again from an earlier position. Replaying is safe, since the unchanged needles
+  are not written again.
+
+  The follower cluster should not take writes itself, or its master could create volumes with the ids
+  of future source volumes. Mirrored volumes deleted in the source cluster are kept in the follower
+  cluster. A needle deleted and then vacuumed away before the deletion is mirrored stays in the follower.
+
+  `,
+}
+
+func runVolumeMirror(cmd *Command, args []string) bool {
+
+	if *mirrorOptions.sourceMaster == "" || *mirrorOptions.targetMaster == "" {
+		return false
+	}
+	if _, err := super_block.NewReplicaPlacementFromString(*mirrorOptions.replication); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -target.replication %s: %v\n", *mirrorOptions.replication, err)
+		return false
+	}
+
+	util.LoadConfiguration("security", false)
+	grpcDialOption := security.LoadClientTLS(util.GetViper(), "grpc.client")
+
+	m := &volumeMirror{
+		grpcDialOption:     grpcDialOption,
+		sourceMaster:       *mirrorOptions.sourceMaster,
+		targetMaster:       *mirrorOptions.targetMaster,
+		collections:        make(map[string]bool),
+		replication:        *mirrorOptions.replication,
+		checkpointFile:     *mirrorOptions.checkpoint,
+		idleTimeoutSeconds: *mirrorOptions.idleTimeoutSeconds,
+		sinceNs:            make(map[uint32]uint64),
+		tailing:            make(map[uint32]bool),
+	}
+	for _, c := range strings.Split(*mirrorOptions.collection, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			m.collections[c] = true
+		}
+	}
+	if err := m.loadCheckpoint(); err != nil {
+		fmt.Fprintf(os.Stderr, "load checkpoint %s: %v\n", m.checkpointFile, err)
+		return true
+	}
+
+	for {
+		if err := m.mirrorNewVolumes(); err != nil {
+			glog.Errorf("mirror %s to %s: %v", m.sourceMaster, m.targetMaster, err)
+		}
+		if err := m.saveCheckpoint(); err != nil {
+			glog.Errorf("save checkpoint %s: %v", m.checkpointFile, err)
+		}
+		time.Sleep(*mirrorOptions.interval)
+	}
+}
+
+type volumeMirror struct {
+	grpcDialOption     grpc.DialOption
+	sourceMaster       string
+	targetMaster       string
+	collections        map[string]bool
+	replication        string
+	checkpointFile     string
+	idleTimeoutSeconds int
+
+	sync.Mutex
+	// the source append time each volume is mirrored up to
+	sinceNs map[uint32]uint64
+	tailing map[uint32]bool
+}
+
+// mirroredVolume is a volume, and one of the volume servers holding it.
+type mirroredVolume struct {
+	info   *master_pb.VolumeInformationMessage
+	server string
+}
+
+func (m *volumeMirror) mirrorNewVolumes() error {
+
+	sourceTopology, err := listClusterTopology(m.grpcDialOption, m.sourceMaster)
+	if err != nil {
+		return fmt.Errorf("list source volumes: %v", err)
+	}
+	targetTopology, err := listClusterTopology(m.grpcDialOption, m.targetMaster)
+	if err != nil {
+		return fmt.Errorf("list target volumes: %v", err)
+	}
+
+	sourceVolumes := collectMirroredVolumes(sourceTopology)
+	targetVolumes := collectMirroredVolumes(targetTopology)
+
+	var vids []uint32
+	for vid, v := range sourceVolumes {
+		if len(m.collections) > 0 && !m.collections[v.info.Collection] {
+			continue
+		}
+		vids = append(vids, vid)
+	}
+	sort.Slice(vids, func(i, j int) bool {
+		return vids[i] < vids[j]
+	})
+
+	for _, vid := range vids {
+		m.Lock()
+		isTailing := m.tailing[vid]
+		m.Unlock()
+		if isTailing {
+			continue
+		}
+
+		source := sourceVolumes[vid]
+		target, found := targetVolumes[vid]
+		if found && target.info.Collection != source.info.Collection {
+			glog.Errorf("volume %d is in collection %q in the source cluster, but %q in the follower cluster", vid, source.info.Collection, target.info.Collection)
+			continue
+		}
+		if !found {
+			targetServer, allocateErr := m.allocateVolume(targetTopology, source.info)
+			if allocateErr != nil {
+				glog.Errorf("create volume %d in the follower cluster: %v", vid, allocateErr)
+				continue
+			}
+			glog.V(0).Infof("created volume %d of collection %q on %s", vid, source.info.Collection, targetServer)
+			target = mirroredVolume{info: source.info, server: targetServer}
+		}
+
+		m.Lock()
+		m.tailing[vid] = true
+		m.Unlock()
+		go m.tailVolume(vid, source.server, target.server)
+	}
+
+	return nil
+}
+
+// tailVolume keeps the volume on the target server tailing the source volume, until it fails.
+// The volume is picked up again by the next mirrorNewVolumes, maybe from another source server.
+func (m *volumeMirror) tailVolume(vid uint32, source, target string) {
+	defer func() {
+		m.Lock()
+		delete(m.tailing, vid)
+		m.Unlock()
+	}()
+
+	for {
+		m.Lock()
+		sinceNs := m.sinceNs[vid]
+		m.Unlock()
+
+		var resp *volume_server_pb.VolumeTailReceiverResponse
+		err := operation.WithVolumeServerClient(target, m.grpcDialOption, func(client volume_server_pb.VolumeServerClient) (err error) {
+			resp, err = client.VolumeTailReceiver(context.Background(), &volume_server_pb.VolumeTailReceiverRequest{
+				VolumeId:           vid,
+				SinceNs:            sinceNs,
+				IdleTimeoutSeconds: uint32(m.idleTimeoutSeconds),
+				SourceVolumeServer: source,
+			})
+			return
+		})
+		if err != nil {
+			glog.Errorf("mirror volume %d from %s to %s: %v", vid, source, target, err)
+			return
+		}
+
+		m.Lock()
+		m.sinceNs[vid] = resp.LastAppendAtNs
+		m.Unlock()
+		if resp.LastAppendAtNs != sinceNs {
+			glog.V(1).Infof("mirrored volume %d from %s to %s up to %v", vid, source, target, time.Unix(0, int64(resp.LastAppendAtNs)))
+		}
+	}
+}
+
+// allocateVolume creates an empty volume on the target server with the most free volume slots.
+func (m *volumeMirror) allocateVolume(topology *master_pb.TopologyInfo, info *master_pb.VolumeInformationMessage) (string, error) {
+	var freest *master_pb.DataNodeInfo
+	for _, dc := range topology.DataCenterInfos {
+		for _, rack := range dc.RackInfos {
+			for _, dn := range rack.DataNodeInfos {
+				if freest == nil || dn.FreeVolumeCount > freest.FreeVolumeCount {
+					freest = dn
+				}
+			}
+		}
+	}
+	if freest == nil || freest.FreeVolumeCount == 0 {
+		return "", fmt.Errorf("no free volume slot")
+	}
+
+	err := operation.WithVolumeServerClient(freest.Id, m.grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		_, err := client.AllocateVolume(context.Background(), &volume_server_pb.AllocateVolumeRequest{
+			VolumeId:    info.Id,
+			Collection:  info.Collection,
+			Replication: m.replication,
+			Ttl:         needle.LoadTTLFromUint32(info.Ttl).String(),
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("allocate on %s: %v", freest.Id, err)
+	}
+	freest.FreeVolumeCount--
+	return freest.Id, nil
+}
+
+func (m *volumeMirror) loadCheckpoint() error {
+	if m.checkpointFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(m.checkpointFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &m.sinceNs)
+}
+
+func (m *volumeMirror) saveCheckpoint() error {
+	if m.checkpointFile == "" {
+		return nil
+	}
+	m.Lock()
+	data, err := json.Marshal(m.sinceNs)
+	m.Unlock()
+	if err != nil {
+		return err
+	}
+	tmpFile := m.checkpointFile + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, m.checkpointFile)
+}
+
+func listClusterTopology(grpcDialOption grpc.DialOption, master string) (topology *master_pb.TopologyInfo, err error) {
+	err = pb.WithMasterClient(master, grpcDialOption, func(client master_pb.SeaweedClient) error {
+		resp, err := client.VolumeList(context.Background(), &master_pb.VolumeListRequest{})
+		if err != nil {
+			return err
+		}
+		topology = resp.TopologyInfo
+		return nil
+	})
+	return
+}
+
+// collectMirroredVolumes lists the volumes in the topology, with the first server found for each volume.
+func collectMirroredVolumes(topology *master_pb.TopologyInfo) map[uint32]mirroredVolume {
+	volumes := make(map[uint32]mirroredVolume)
+	for _, dc := range topology.DataCenterInfos {
+		for _, rack := range dc.RackInfos {
+			for _, dn := range rack.DataNodeInfos {
+				for _, info := range dn.VolumeInfos {
+					if _, found := volumes[info.Id]; !found {
+						volumes[info.Id] = mirroredVolume{info: info, server: dn.Id}
+					}
+				}
+			}
+		}
+	}
+	return volumes
+}
@@ -47,6 +47,9 @@ var cmdWebDav = &Command{
 	Short:     "start a webdav server that is backed by a filer",
 	Long: `start a webdav server that is backed by a filer.
 
+	The clients can be required to log in with the user and password in the [webdav]
+	section of security.toml. Use -key.file and -cert.file to keep the password private.
+
 `,
 }
 
@@ -103,7 +106,7 @@ func (wo *WebDavOption) startWebDav() bool {
 	}
 
 	ws, webdavServer_err := weed_server.NewWebDavServer(&weed_server.WebDavOption{
-		Filer:            *wo.filer,
+		Filer:            pb.ServerToHttpAddress(*wo.filer),
 		FilerGrpcAddress: filerGrpcAddress,
 		GrpcDialOption:   grpcDialOption,
 		Collection:       *wo.collection,
@@ -117,7 +120,7 @@ func (wo *WebDavOption) startWebDav() bool {
 		glog.Fatalf("WebDav Server startup error: %v", webdavServer_err)
 	}
 
-	httpS := &http.Server{Handler: ws.Handler}
+	httpS := &http.Server{Handler: ws}
 
 	listenAddress := fmt.Sprintf(":%d", *wo.port)
 	webDavListener, err := util.NewListener(listenAddress, time.Duration(10)*time.Second)
@@ -0,0 +1,124 @@
+package consul
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// registration with a local consul agent, see https://www.consul.io/api-docs/agent/service
+
+// Service describes one server to register. The health check is an http check on CheckPath.
+type Service struct {
+	Id            string
+	Name          string
+	Address       string
+	Port          int
+	Tags          []string
+	Meta          map[string]string
+	CheckPath     string
+	CheckInterval time.Duration
+}
+
+type Agent struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+// NewAgent connects to the consul agent at host:port. The acl token is read from CONSUL_HTTP_TOKEN.
+func NewAgent(address string) *Agent {
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+	return &Agent{
+		address: strings.TrimSuffix(address, "/"),
+		token:   os.Getenv("CONSUL_HTTP_TOKEN"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type agentServiceCheck struct {
+	HTTP                           string `json:"HTTP"`
+	Interval                       string `json:"Interval"`
+	Timeout                        string `json:"Timeout"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter"`
+}
+
+type agentServiceRegistration struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Tags    []string          `json:"Tags,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Check   agentServiceCheck `json:"Check"`
+}
+
+func (a *Agent) Register(s Service) error {
+	interval := s.CheckInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	registration := agentServiceRegistration{
+		ID:      s.Id,
+		Name:    s.Name,
+		Address: s.Address,
+		Port:    s.Port,
+		Tags:    s.Tags,
+		Meta:    s.Meta,
+		Check: agentServiceCheck{
+			HTTP:     fmt.Sprintf("http://%s:%d%s", s.Address, s.Port, s.CheckPath),
+			Interval: interval.String(),
+			Timeout:  interval.String(),
+			// remove the servers that crashed without deregistering
+			DeregisterCriticalServiceAfter: "30m",
+		},
+	}
+	body, err := json.Marshal(registration)
+	if err != nil {
+		return err
+	}
+	if err = a.put("/v1/agent/service/register", body); err != nil {
+		return fmt.Errorf("register %s with consul agent %s: %v", s.Id, a.address, err)
+	}
+	glog.V(0).Infof("registered %s with consul agent %s", s.Id, a.address)
+	return nil
+}
+
+func (a *Agent) Deregister(serviceId string) error {
+	if err := a.put("/v1/agent/service/deregister/"+url.PathEscape(serviceId), nil); err != nil {
+		return fmt.Errorf("deregister %s with consul agent %s: %v", serviceId, a.address, err)
+	}
+	glog.V(0).Infof("deregistered %s with consul agent %s", serviceId, a.address)
+	return nil
+}
+
+func (a *Agent) put(path string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPut, a.address+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Set("X-Consul-Token", a.token)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
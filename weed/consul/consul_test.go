@@ -0,0 +1,56 @@
+package consul
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegisterAndDeregister(t *testing.T) {
+
+	var paths []string
+	var registration agentServiceRegistration
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("unexpected %s request", r.Method)
+		}
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/v1/agent/service/register" {
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &registration); err != nil {
+				t.Errorf("unmarshal %s: %v", string(body), err)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	agent := NewAgent(ts.URL)
+	err := agent.Register(Service{
+		Id:            "seaweedfs-master-10.0.0.1-9333",
+		Name:          "seaweedfs-master",
+		Address:       "10.0.0.1",
+		Port:          9333,
+		Meta:          map[string]string{"grpc_port": "19333"},
+		CheckPath:     "/cluster/healthz",
+		CheckInterval: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if registration.Name != "seaweedfs-master" || registration.Port != 9333 || registration.Meta["grpc_port"] != "19333" {
+		t.Errorf("unexpected registration %+v", registration)
+	}
+	if registration.Check.HTTP != "http://10.0.0.1:9333/cluster/healthz" || registration.Check.Interval != "5s" {
+		t.Errorf("unexpected check %+v", registration.Check)
+	}
+
+	if err = agent.Deregister("seaweedfs-master-10.0.0.1-9333"); err != nil {
+		t.Fatalf("deregister: %v", err)
+	}
+	if len(paths) != 2 || paths[1] != "/v1/agent/service/deregister/seaweedfs-master-10.0.0.1-9333" {
+		t.Errorf("unexpected requests %v", paths)
+	}
+}
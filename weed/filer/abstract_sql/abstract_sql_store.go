@@ -17,6 +17,7 @@ type AbstractSqlStore struct {
 	SqlUpdate               string
 	SqlFind                 string
 	SqlDelete               string
+	SqlDeleteIfUnchanged    string
 	SqlDeleteFolderChildren string
 	SqlListExclusive        string
 	SqlListInclusive        string
@@ -171,13 +172,38 @@ func (store *AbstractSqlStore) DeleteFolderChildren(ctx context.Context, fullpat
 	return nil
 }
 
+// listBatchSize limits the rows of one listing query, so a large listing does not run as one long query.
+const listBatchSize = 1024
+
 func (store *AbstractSqlStore) ListDirectoryPrefixedEntries(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (entries []*filer.Entry, err error) {
+
+	for limit > 0 {
+		batchLimit := limit
+		if batchLimit > listBatchSize {
+			batchLimit = listBatchSize
+		}
+		batch, listErr := store.listDirectoryBatch(ctx, fullpath, startFileName, inclusive, batchLimit, prefix)
+		if listErr != nil {
+			return nil, listErr
+		}
+		entries = append(entries, batch...)
+		if len(batch) < batchLimit {
+			break
+		}
+		limit -= len(batch)
+		startFileName, inclusive = batch[len(batch)-1].Name(), false
+	}
+
+	return entries, nil
+}
+
+func (store *AbstractSqlStore) listDirectoryBatch(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (entries []*filer.Entry, err error) {
 	sqlText := store.SqlListExclusive
 	if inclusive {
 		sqlText = store.SqlListInclusive
 	}
 
-	rows, err := store.getTxOrDB(ctx).QueryContext(ctx, sqlText, util.HashStringToLong(string(fullpath)), startFileName, string(fullpath), prefix+"%", limit)
+	rows, err := store.getTxOrDB(ctx).QueryContext(ctx, sqlText, util.HashStringToLong(string(fullpath)), startFileName, string(fullpath), likePrefixPattern(prefix), limit)
 	if err != nil {
 		return nil, fmt.Errorf("list %s : %v", fullpath, err)
 	}
@@ -202,7 +228,13 @@ func (store *AbstractSqlStore) ListDirectoryPrefixedEntries(ctx context.Context,
 		entries = append(entries, entry)
 	}
 
-	return entries, nil
+	return entries, rows.Err()
+}
+
+// likePrefixPattern matches the names starting with the prefix in a LIKE clause.
+// The wildcards in the prefix are escaped with the default escape character of MySQL and PostgreSQL.
+func likePrefixPattern(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
 }
 
 func (store *AbstractSqlStore) ListDirectoryEntries(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int) (entries []*filer.Entry, err error) {
@@ -75,6 +75,24 @@ func (store *AbstractSqlStore) KvDelete(ctx context.Context, key []byte) (err er
 
 }
 
+func (store *AbstractSqlStore) KvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (deleted bool, err error) {
+
+	dirStr, dirHash, name := genDirAndName(key)
+
+	res, err := store.getTxOrDB(ctx).ExecContext(ctx, store.SqlDeleteIfUnchanged, dirHash, name, dirStr, value)
+	if err != nil {
+		return false, fmt.Errorf("kv delete if unchanged: %s", err)
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("kv delete if unchanged no rows affected: %s", err)
+	}
+
+	return affectedRows > 0, nil
+
+}
+
 func genDirAndName(key []byte) (dirStr string, dirHash int64, name string) {
 	for len(key) < 8 {
 		key = append(key, 0)
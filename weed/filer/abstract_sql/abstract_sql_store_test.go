@@ -0,0 +1,16 @@
+package abstract_sql
+
+import "testing"
+
+func TestLikePrefixPattern(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"":          `%`,
+		"photo":     `photo%`,
+		"100%_done": `100\%\_done%`,
+		`a\b`:       `a\\b%`,
+	} {
+		if got := likePrefixPattern(prefix); got != want {
+			t.Errorf("likePrefixPattern(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
@@ -12,7 +12,10 @@ import (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &CassandraStore{})
+	filer.RegisterMetadataBackend("cassandra", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &CassandraStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type CassandraStore struct {
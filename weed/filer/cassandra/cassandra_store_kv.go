@@ -50,6 +50,21 @@ func (store *CassandraStore) KvDelete(ctx context.Context, key []byte) (err erro
 	return nil
 }
 
+// a lightweight transaction, so it is applied only if no other write changed the value
+func (store *CassandraStore) KvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (deleted bool, err error) {
+	dir, name := genDirAndName(key)
+
+	var current []byte
+	deleted, err = store.session.Query(
+		"DELETE FROM filemeta WHERE directory=? AND name=? IF meta=?",
+		dir, name, value).ScanCAS(&current)
+	if err != nil {
+		return false, fmt.Errorf("kv delete if unchanged: %v", err)
+	}
+
+	return deleted, nil
+}
+
 func genDirAndName(key []byte) (dir string, name string) {
 	for len(key) < 8 {
 		key = append(key, 0)
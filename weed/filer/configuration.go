@@ -1,36 +1,77 @@
 package filer
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/spf13/viper"
 )
 
+// MetadataBackendFactory creates and initializes a filer store
+// from the filer.toml section under the given prefix.
+type MetadataBackendFactory func(configuration util.Configuration, prefix string) (FilerStore, error)
+
 var (
-	Stores []FilerStore
+	metadataBackendNames []string
+	metadataBackends     = make(map[string]MetadataBackendFactory)
 )
 
+// RegisterMetadataBackend makes a filer store selectable by name in filer.toml.
+// It is expected to be called from an init() function, so a custom filer binary
+// can add its own store by importing the package that registers it.
+func RegisterMetadataBackend(name string, factory MetadataBackendFactory) {
+	if _, found := metadataBackends[name]; found {
+		glog.Fatalf("filer store %s is registered twice", name)
+	}
+	metadataBackendNames = append(metadataBackendNames, name)
+	metadataBackends[name] = factory
+}
+
+// MetadataBackendNames lists the registered filer stores.
+func MetadataBackendNames() []string {
+	return metadataBackendNames
+}
+
+// LoadStore creates the one filer store enabled in the configuration, without a Filer.
+func LoadStore(config util.Configuration) (FilerStore, error) {
+	var enabled []string
+	for _, name := range metadataBackendNames {
+		if config.GetBool(name + ".enabled") {
+			enabled = append(enabled, name)
+		}
+	}
+	if len(enabled) != 1 {
+		return nil, fmt.Errorf("expect one enabled filer store, found %d %v, supported filer stores are %v", len(enabled), enabled, metadataBackendNames)
+	}
+	store, err := metadataBackends[enabled[0]](config, enabled[0]+".")
+	if err != nil {
+		return nil, fmt.Errorf("initialize store for %s: %v", enabled[0], err)
+	}
+	return store, nil
+}
+
 func (f *Filer) LoadConfiguration(config *viper.Viper) {
 
 	validateOneEnabledStore(config)
 
-	for _, store := range Stores {
-		if config.GetBool(store.GetName() + ".enabled") {
-			if err := store.Initialize(config, store.GetName()+"."); err != nil {
-				glog.Fatalf("Failed to initialize store for %s: %+v",
-					store.GetName(), err)
+	for _, name := range metadataBackendNames {
+		if config.GetBool(name + ".enabled") {
+			store, err := metadataBackends[name](config, name+".")
+			if err != nil {
+				glog.Fatalf("Failed to initialize store for %s: %+v", name, err)
 			}
 			f.SetStore(store)
-			glog.V(0).Infof("Configure filer for %s", store.GetName())
+			glog.V(0).Infof("Configure filer for %s", name)
 			return
 		}
 	}
 
 	println()
 	println("Supported filer stores are:")
-	for _, store := range Stores {
-		println("    " + store.GetName())
+	for _, name := range metadataBackendNames {
+		println("    " + name)
 	}
 
 	os.Exit(-1)
@@ -38,12 +79,12 @@ func (f *Filer) LoadConfiguration(config *viper.Viper) {
 
 func validateOneEnabledStore(config *viper.Viper) {
 	enabledStore := ""
-	for _, store := range Stores {
-		if config.GetBool(store.GetName() + ".enabled") {
+	for _, name := range metadataBackendNames {
+		if config.GetBool(name + ".enabled") {
 			if enabledStore == "" {
-				enabledStore = store.GetName()
+				enabledStore = name
 			} else {
-				glog.Fatalf("Filer store is enabled for both %s and %s", enabledStore, store.GetName())
+				glog.Fatalf("Filer store is enabled for both %s and %s", enabledStore, name)
 			}
 		}
 	}
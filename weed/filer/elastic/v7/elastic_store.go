@@ -40,7 +40,10 @@ type ESKVEntry struct {
 }
 
 func init() {
-	filer.Stores = append(filer.Stores, &ElasticStore{})
+	filer.RegisterMetadataBackend("elastic7", func(configuration weed_util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &ElasticStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type ElasticStore struct {
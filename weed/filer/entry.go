@@ -29,6 +29,10 @@ func (attr Attr) IsDirectory() bool {
 	return attr.Mode&os.ModeDir > 0
 }
 
+func (attr Attr) IsSymlink() bool {
+	return attr.Mode&os.ModeSymlink > 0
+}
+
 type Entry struct {
 	util.FullPath
 
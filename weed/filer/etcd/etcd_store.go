@@ -19,11 +19,15 @@ const (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &EtcdStore{})
+	filer.RegisterMetadataBackend("etcd", func(configuration weed_util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &EtcdStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type EtcdStore struct {
-	client *clientv3.Client
+	client    *clientv3.Client
+	keyPrefix string
 }
 
 func (store *EtcdStore) GetName() string {
@@ -41,6 +45,8 @@ func (store *EtcdStore) Initialize(configuration weed_util.Configuration, prefix
 		timeout = "3s"
 	}
 
+	store.keyPrefix = configuration.GetString(prefix + "key_prefix")
+
 	return store.initialize(servers, timeout)
 }
 
@@ -74,7 +80,7 @@ func (store *EtcdStore) RollbackTransaction(ctx context.Context) error {
 }
 
 func (store *EtcdStore) InsertEntry(ctx context.Context, entry *filer.Entry) (err error) {
-	key := genKey(entry.DirAndName())
+	key := store.genKey(entry.DirAndName())
 
 	meta, err := entry.EncodeAttributesAndChunks()
 	if err != nil {
@@ -97,11 +103,11 @@ func (store *EtcdStore) UpdateEntry(ctx context.Context, entry *filer.Entry) (er
 }
 
 func (store *EtcdStore) FindEntry(ctx context.Context, fullpath weed_util.FullPath) (entry *filer.Entry, err error) {
-	key := genKey(fullpath.DirAndName())
+	key := store.genKey(fullpath.DirAndName())
 
 	resp, err := store.client.Get(ctx, string(key))
 	if err != nil {
-		return nil, fmt.Errorf("get %s : %v", entry.FullPath, err)
+		return nil, fmt.Errorf("get %s : %v", fullpath, err)
 	}
 
 	if len(resp.Kvs) == 0 {
@@ -120,7 +126,7 @@ func (store *EtcdStore) FindEntry(ctx context.Context, fullpath weed_util.FullPa
 }
 
 func (store *EtcdStore) DeleteEntry(ctx context.Context, fullpath weed_util.FullPath) (err error) {
-	key := genKey(fullpath.DirAndName())
+	key := store.genKey(fullpath.DirAndName())
 
 	if _, err := store.client.Delete(ctx, string(key)); err != nil {
 		return fmt.Errorf("delete %s : %v", fullpath, err)
@@ -130,7 +136,7 @@ func (store *EtcdStore) DeleteEntry(ctx context.Context, fullpath weed_util.Full
 }
 
 func (store *EtcdStore) DeleteFolderChildren(ctx context.Context, fullpath weed_util.FullPath) (err error) {
-	directoryPrefix := genDirectoryKeyPrefix(fullpath, "")
+	directoryPrefix := store.genDirectoryKeyPrefix(fullpath, "")
 
 	if _, err := store.client.Delete(ctx, string(directoryPrefix), clientv3.WithPrefix()); err != nil {
 		return fmt.Errorf("deleteFolderChildren %s : %v", fullpath, err)
@@ -139,54 +145,75 @@ func (store *EtcdStore) DeleteFolderChildren(ctx context.Context, fullpath weed_
 	return nil
 }
 
-func (store *EtcdStore) ListDirectoryPrefixedEntries(ctx context.Context, fullpath weed_util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (entries []*filer.Entry, err error) {
-	return nil, filer.ErrUnsupportedListDirectoryPrefixed
-}
+// listBatchSize limits the keys of one range request, so a large listing is read in batches.
+const listBatchSize = 1024
 
-func (store *EtcdStore) ListDirectoryEntries(ctx context.Context, fullpath weed_util.FullPath, startFileName string, inclusive bool, limit int) (entries []*filer.Entry, err error) {
-	directoryPrefix := genDirectoryKeyPrefix(fullpath, "")
+func (store *EtcdStore) ListDirectoryPrefixedEntries(ctx context.Context, fullpath weed_util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (entries []*filer.Entry, err error) {
+	directoryPrefix := store.genDirectoryKeyPrefix(fullpath, "")
+	rangeEnd := clientv3.GetPrefixRangeEnd(string(store.genDirectoryKeyPrefix(fullpath, prefix)))
 
-	resp, err := store.client.Get(ctx, string(directoryPrefix),
-		clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend))
-	if err != nil {
-		return nil, fmt.Errorf("list %s : %v", fullpath, err)
+	startKey := store.genDirectoryKeyPrefix(fullpath, startFileName)
+	if startFileName < prefix {
+		startKey, inclusive = store.genDirectoryKeyPrefix(fullpath, prefix), true
 	}
 
-	for _, kv := range resp.Kvs {
-		fileName := getNameFromKey(kv.Key)
-		if fileName == "" {
-			continue
+	for limit > 0 {
+		batchLimit := limit
+		if batchLimit > listBatchSize {
+			batchLimit = listBatchSize
 		}
-		if fileName == startFileName && !inclusive {
-			continue
-		}
-		limit--
-		if limit < 0 {
-			break
+		// one more key in case the start key is excluded
+		resp, getErr := store.client.Get(ctx, string(startKey),
+			clientv3.WithRange(rangeEnd), clientv3.WithLimit(int64(batchLimit+1)),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+		if getErr != nil {
+			return nil, fmt.Errorf("list %s : %v", fullpath, getErr)
 		}
-		entry := &filer.Entry{
-			FullPath: weed_util.NewFullPath(string(fullpath), fileName),
+
+		var listed int
+		for _, kv := range resp.Kvs {
+			if !inclusive && string(kv.Key) == string(startKey) {
+				continue
+			}
+			if listed == batchLimit {
+				break
+			}
+			fileName := string(kv.Key[len(directoryPrefix):])
+			entry := &filer.Entry{
+				FullPath: weed_util.NewFullPath(string(fullpath), fileName),
+			}
+			if decodeErr := entry.DecodeAttributesAndChunks(weed_util.MaybeDecompressData(kv.Value)); decodeErr != nil {
+				glog.V(0).Infof("list %s : %v", entry.FullPath, decodeErr)
+				return nil, fmt.Errorf("decode %s : %v", entry.FullPath, decodeErr)
+			}
+			entries = append(entries, entry)
+			listed++
+			startKey = kv.Key
 		}
-		if decodeErr := entry.DecodeAttributesAndChunks(weed_util.MaybeDecompressData(kv.Value)); decodeErr != nil {
-			err = decodeErr
-			glog.V(0).Infof("list %s : %v", entry.FullPath, err)
+
+		if listed < batchLimit {
 			break
 		}
-		entries = append(entries, entry)
+		limit -= listed
+		inclusive = false
 	}
 
-	return entries, err
+	return entries, nil
+}
+
+func (store *EtcdStore) ListDirectoryEntries(ctx context.Context, fullpath weed_util.FullPath, startFileName string, inclusive bool, limit int) (entries []*filer.Entry, err error) {
+	return store.ListDirectoryPrefixedEntries(ctx, fullpath, startFileName, inclusive, limit, "")
 }
 
-func genKey(dirPath, fileName string) (key []byte) {
-	key = []byte(dirPath)
+func (store *EtcdStore) genKey(dirPath, fileName string) (key []byte) {
+	key = []byte(store.keyPrefix + dirPath)
 	key = append(key, DIR_FILE_SEPARATOR)
 	key = append(key, []byte(fileName)...)
 	return key
 }
 
-func genDirectoryKeyPrefix(fullpath weed_util.FullPath, startFileName string) (keyPrefix []byte) {
-	keyPrefix = []byte(string(fullpath))
+func (store *EtcdStore) genDirectoryKeyPrefix(fullpath weed_util.FullPath, startFileName string) (keyPrefix []byte) {
+	keyPrefix = []byte(store.keyPrefix + string(fullpath))
 	keyPrefix = append(keyPrefix, DIR_FILE_SEPARATOR)
 	if len(startFileName) > 0 {
 		keyPrefix = append(keyPrefix, []byte(startFileName)...)
@@ -194,15 +221,6 @@ func genDirectoryKeyPrefix(fullpath weed_util.FullPath, startFileName string) (k
 	return keyPrefix
 }
 
-func getNameFromKey(key []byte) string {
-	sepIndex := len(key) - 1
-	for sepIndex >= 0 && key[sepIndex] != DIR_FILE_SEPARATOR {
-		sepIndex--
-	}
-
-	return string(key[sepIndex+1:])
-}
-
 func (store *EtcdStore) Shutdown() {
 	store.client.Close()
 }
@@ -3,12 +3,15 @@ package etcd
 import (
 	"context"
 	"fmt"
+
+	"go.etcd.io/etcd/clientv3"
+
 	"github.com/chrislusf/seaweedfs/weed/filer"
 )
 
 func (store *EtcdStore) KvPut(ctx context.Context, key []byte, value []byte) (err error) {
 
-	_, err = store.client.Put(ctx, string(key), string(value))
+	_, err = store.client.Put(ctx, store.keyPrefix+string(key), string(value))
 
 	if err != nil {
 		return fmt.Errorf("kv put: %v", err)
@@ -19,7 +22,7 @@ func (store *EtcdStore) KvPut(ctx context.Context, key []byte, value []byte) (er
 
 func (store *EtcdStore) KvGet(ctx context.Context, key []byte) (value []byte, err error) {
 
-	resp, err := store.client.Get(ctx, string(key))
+	resp, err := store.client.Get(ctx, store.keyPrefix+string(key))
 
 	if err != nil {
 		return nil, fmt.Errorf("kv get: %v", err)
@@ -34,7 +37,7 @@ func (store *EtcdStore) KvGet(ctx context.Context, key []byte) (value []byte, er
 
 func (store *EtcdStore) KvDelete(ctx context.Context, key []byte) (err error) {
 
-	_, err = store.client.Delete(ctx, string(key))
+	_, err = store.client.Delete(ctx, store.keyPrefix+string(key))
 
 	if err != nil {
 		return fmt.Errorf("kv delete: %v", err)
@@ -42,3 +45,18 @@ func (store *EtcdStore) KvDelete(ctx context.Context, key []byte) (err error) {
 
 	return nil
 }
+
+func (store *EtcdStore) KvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (deleted bool, err error) {
+
+	k := store.keyPrefix + string(key)
+	resp, err := store.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(k), "=", string(value))).
+		Then(clientv3.OpDelete(k)).
+		Commit()
+
+	if err != nil {
+		return false, fmt.Errorf("kv delete if unchanged: %v", err)
+	}
+
+	return resp.Succeeded, nil
+}
@@ -0,0 +1,125 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/embed"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func newEmbeddedEtcdStore(t *testing.T, keyPrefix string) (store *EtcdStore, cleanup func()) {
+	dir, err := ioutil.TempDir("", "seaweedfs_etcd_test")
+	if err != nil {
+		t.Fatalf("temp dir creation: %v", err)
+	}
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	clientUrl, _ := url.Parse("http://127.0.0.1:0")
+	peerUrl, _ := url.Parse("http://127.0.0.1:0")
+	cfg.LCUrls, cfg.ACUrls = []url.URL{*clientUrl}, []url.URL{*clientUrl}
+	cfg.LPUrls, cfg.APUrls = []url.URL{*peerUrl}, []url.URL{*peerUrl}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("start etcd: %v", err)
+	}
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		e.Close()
+		os.RemoveAll(dir)
+		t.Fatalf("etcd is not ready")
+	}
+
+	store = &EtcdStore{keyPrefix: keyPrefix}
+	if err = store.initialize(e.Clients[0].Addr().String(), "3s"); err != nil {
+		t.Fatalf("connect to etcd: %v", err)
+	}
+	return store, func() {
+		store.Shutdown()
+		e.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func listNames(t *testing.T, store *EtcdStore, dir util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (names []string) {
+	entries, err := store.ListDirectoryPrefixedEntries(context.Background(), dir, startFileName, inclusive, limit, prefix)
+	if err != nil {
+		t.Fatalf("list %s from %s: %v", dir, startFileName, err)
+	}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return
+}
+
+func TestEtcdStoreListing(t *testing.T) {
+	store, cleanup := newEmbeddedEtcdStore(t, "seaweedfs.")
+	defer cleanup()
+	ctx := context.Background()
+
+	const count = 2500
+	for i := 0; i < count; i++ {
+		entry := &filer.Entry{FullPath: util.NewFullPath("/dir", fmt.Sprintf("f%04d", i)), Attr: filer.Attr{Mode: 0644}}
+		if err := store.InsertEntry(ctx, entry); err != nil {
+			t.Fatalf("insert %s: %v", entry.FullPath, err)
+		}
+	}
+	// an entry of a sibling directory sharing the name prefix
+	if err := store.InsertEntry(ctx, &filer.Entry{FullPath: "/dir2/f0000", Attr: filer.Attr{Mode: 0644}}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	names := listNames(t, store, "/dir", "", false, count+10, "")
+	if len(names) != count {
+		t.Fatalf("listed %d entries, expected %d", len(names), count)
+	}
+	for i, name := range names {
+		if expected := fmt.Sprintf("f%04d", i); name != expected {
+			t.Fatalf("entry %d is %s, expected %s", i, name, expected)
+		}
+	}
+
+	// across the batch boundary
+	if names = listNames(t, store, "/dir", "f0100", false, 1500, ""); len(names) != 1500 || names[0] != "f0101" || names[1499] != "f1600" {
+		t.Errorf("exclusive page: %d entries from %v", len(names), names[:1])
+	}
+	if names = listNames(t, store, "/dir", "f0100", true, 2, ""); len(names) != 2 || names[0] != "f0100" || names[1] != "f0101" {
+		t.Errorf("inclusive page: %v", names)
+	}
+	if names = listNames(t, store, "/dir", "", false, 100, "f12"); len(names) != 100 || names[0] != "f1200" || names[99] != "f1299" {
+		t.Errorf("prefix listing: %d entries from %v", len(names), names[:1])
+	}
+	if names = listNames(t, store, "/dir", "f1250", false, 100, "f12"); len(names) != 49 || names[0] != "f1251" {
+		t.Errorf("prefix listing from a start name: %d entries from %v", len(names), names[:1])
+	}
+
+	// all keys are under the key prefix
+	if err := store.KvPut(ctx, []byte("key"), []byte("value")); err != nil {
+		t.Fatalf("kv put: %v", err)
+	}
+	resp, err := store.client.Get(ctx, "", clientv3.WithFromKey(), clientv3.WithKeysOnly())
+	if err != nil {
+		t.Fatalf("get all keys: %v", err)
+	}
+	for _, kv := range resp.Kvs {
+		if !bytes.HasPrefix(kv.Key, []byte(store.keyPrefix)) {
+			t.Fatalf("key %q is outside of the key prefix", kv.Key)
+		}
+	}
+	if resp.Count != count+2 {
+		t.Errorf("%d keys, expected %d", resp.Count, count+2)
+	}
+}
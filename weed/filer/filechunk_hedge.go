@@ -0,0 +1,76 @@
+package filer
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// HedgeReadAfter, if positive, sends the same chunk read to the next replica
+// when the previous one has not responded within this duration.
+var HedgeReadAfter time.Duration
+
+type hedgedReadResult struct {
+	urlString   string
+	data        []byte
+	shouldRetry bool
+	err         error
+}
+
+// hedgedFetchChunkData returns the first successful read among the replicas.
+// The slower reads are cancelled.
+func hedgedFetchChunkData(urlStrings []string, cipherKey []byte, isGzipped bool, isFullChunk bool, offset int64, size int) (data []byte, shouldRetry bool, err error) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(chan *hedgedReadResult, len(urlStrings))
+	launched := 0
+	launchNext := func() {
+		urlString := urlStrings[launched]
+		launched++
+		go func() {
+			var buffer bytes.Buffer
+			shouldRetry, err := util.ReadUrlAsStreamWithContext(ctx, urlString+"?readDeleted=true", cipherKey, isGzipped, isFullChunk, offset, size, func(data []byte) {
+				buffer.Write(data)
+			})
+			results <- &hedgedReadResult{urlString, buffer.Bytes(), shouldRetry, err}
+		}()
+	}
+
+	launchNext()
+	timer := time.NewTimer(HedgeReadAfter)
+	defer timer.Stop()
+
+	var lastErr error
+	for received := 0; received < len(urlStrings); {
+		select {
+		case <-timer.C:
+			if launched < len(urlStrings) {
+				glog.V(4).Infof("hedge read to %s after %v", urlStrings[launched], HedgeReadAfter)
+				stats.FilerRequestCounter.WithLabelValues("hedgeRead").Inc()
+				launchNext()
+				timer.Reset(HedgeReadAfter)
+			}
+		case result := <-results:
+			received++
+			if result.err == nil {
+				return result.data, false, nil
+			}
+			glog.V(0).Infof("read %s failed, err: %v", result.urlString, result.err)
+			lastErr = result.err
+			if !result.shouldRetry {
+				return nil, false, result.err
+			}
+			if launched < len(urlStrings) {
+				launchNext()
+			}
+		}
+	}
+
+	return nil, true, lastErr
+}
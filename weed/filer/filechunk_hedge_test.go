@@ -0,0 +1,41 @@
+package filer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHedgedFetchChunkData(t *testing.T) {
+
+	stalled := make(chan struct{})
+	defer close(stalled)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stalled:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data"))
+	}))
+	defer fast.Close()
+
+	HedgeReadAfter = 10 * time.Millisecond
+	defer func() { HedgeReadAfter = 0 }()
+
+	start := time.Now()
+	data, _, err := hedgedFetchChunkData([]string{slow.URL, fast.URL}, nil, false, true, 0, 0)
+	if err != nil {
+		t.Fatalf("hedged read: %v", err)
+	}
+	if string(data) != "data" {
+		t.Errorf("unexpected data %q", string(data))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("hedged read took %v", elapsed)
+	}
+
+}
@@ -100,18 +100,24 @@ func retriedFetchChunkData(urlStrings []string, cipherKey []byte, isGzipped bool
 	var shouldRetry bool
 
 	for waitTime := time.Second; waitTime < util.RetryWaitTime; waitTime += waitTime / 2 {
-		for _, urlString := range urlStrings {
-			shouldRetry, err = util.ReadUrlAsStream(urlString+"?readDeleted=true", cipherKey, isGzipped, isFullChunk, offset, size, func(data []byte) {
-				buffer.Write(data)
-			})
-			if !shouldRetry {
-				break
-			}
-			if err != nil {
-				glog.V(0).Infof("read %s failed, err: %v", urlString, err)
-				buffer.Reset()
-			} else {
-				break
+		if HedgeReadAfter > 0 && len(urlStrings) > 1 {
+			var data []byte
+			data, shouldRetry, err = hedgedFetchChunkData(urlStrings, cipherKey, isGzipped, isFullChunk, offset, size)
+			buffer.Write(data)
+		} else {
+			for _, urlString := range urlStrings {
+				shouldRetry, err = util.ReadUrlAsStream(urlString+"?readDeleted=true", cipherKey, isGzipped, isFullChunk, offset, size, func(data []byte) {
+					buffer.Write(data)
+				})
+				if !shouldRetry {
+					break
+				}
+				if err != nil {
+					glog.V(0).Infof("read %s failed, err: %v", urlString, err)
+					buffer.Reset()
+				} else {
+					break
+				}
 			}
 		}
 		if err != nil && shouldRetry {
@@ -40,8 +40,12 @@ type Filer struct {
 	metaLogCollection   string
 	metaLogReplication  string
 	MetaAggregator      *MetaAggregator
-	Signature           int32
+	Signature           int32 // shared by the filers on the same store
+	address             string
 	FilerConf           *FilerConf
+	backgroundDeletions backgroundDeletions
+	renameJournal       renameJournal
+	quotas              filerQuotas
 }
 
 func NewFiler(masters []string, grpcDialOption grpc.DialOption,
@@ -50,7 +54,11 @@ func NewFiler(masters []string, grpcDialOption grpc.DialOption,
 		MasterClient:        wdclient.NewMasterClient(grpcDialOption, "filer", filerHost, filerGrpcPort, dataCenter, masters),
 		fileIdDeletionQueue: util.NewUnboundedQueue(),
 		GrpcDialOption:      grpcDialOption,
+		address:             fmt.Sprintf("%s:%d", filerHost, filerGrpcPort),
 		FilerConf:           NewFilerConf(),
+		backgroundDeletions: backgroundDeletions{deletions: make(map[util.FullPath]*BackgroundDeletion)},
+		renameJournal:       renameJournal{intents: make(map[util.FullPath]*RenameIntent)},
+		quotas:              filerQuotas{counted: make(map[string]bool)},
 	}
 	f.LocalMetaLogBuffer = log_buffer.NewLogBuffer(LogFlushInterval, f.logFlushFunc, notifyFn)
 	f.metaLogCollection = collection
@@ -80,7 +88,9 @@ func (f *Filer) AggregateFromPeers(self string, filers []string) {
 }
 
 func (f *Filer) SetStore(store FilerStore) {
-	f.Store = NewFilerStoreWrapper(store)
+	fsw := NewFilerStoreWrapper(store)
+	fsw.queueHardLinksToReap = true
+	f.Store = fsw
 
 	f.setOrLoadFilerStoreSignature(store)
 
@@ -104,6 +114,12 @@ func (f *Filer) setOrLoadFilerStoreSignature(store FilerStore) {
 	}
 }
 
+// LocalKvKey returns the key of a value only this filer reads and writes,
+// so the filers sharing a store do not overwrite each other's value.
+func (f *Filer) LocalKvKey(prefix string) []byte {
+	return []byte(prefix + "." + f.address)
+}
+
 func (f *Filer) GetStore() (store FilerStore) {
 	return f.Store
 }
@@ -208,6 +224,19 @@ func (f *Filer) CreateEntry(ctx context.Context, entry *Entry, o_excl bool, isFr
 
 	oldEntry, _ := f.FindEntry(ctx, entry.FullPath)
 
+	if !entry.IsDirectory() && !isFromOtherCluster {
+		addBytes, addFiles := int64(entry.Size()), int64(1)
+		if oldEntry != nil {
+			addBytes, addFiles = addBytes-int64(oldEntry.Size()), 0
+		}
+		if err := f.CheckQuota(entry.FullPath, addBytes, addFiles); err != nil {
+			glog.V(1).Infof("CreateEntry %s: %v", entry.FullPath, err)
+			return err
+		}
+	}
+
+	f.maybeSetTtl(entry)
+
 	if oldEntry == nil {
 		glog.V(4).Infof("InsertEntry %s: new entry: %v", entry.FullPath, entry.Name())
 		if err := f.Store.InsertEntry(ctx, entry); err != nil {
@@ -267,11 +296,9 @@ func (f *Filer) FindEntry(ctx context.Context, p util.FullPath) (entry *Entry, e
 		}, nil
 	}
 	entry, err = f.Store.FindEntry(ctx, p)
-	if entry != nil && entry.TtlSec > 0 {
-		if entry.Crtime.Add(time.Duration(entry.TtlSec) * time.Second).Before(time.Now()) {
-			f.Store.DeleteEntry(ctx, p.Child(entry.Name()))
-			return nil, filer_pb.ErrNotFound
-		}
+	if entry != nil && isTtlExpired(entry, time.Now()) {
+		f.deleteTtlExpiredEntry(ctx, entry)
+		return nil, filer_pb.ErrNotFound
 	}
 	return
 
@@ -301,12 +328,10 @@ func (f *Filer) doListDirectoryEntries(ctx context.Context, p util.FullPath, sta
 	}
 	for _, entry := range listedEntries {
 		lastFileName = entry.Name()
-		if entry.TtlSec > 0 {
-			if entry.Crtime.Add(time.Duration(entry.TtlSec) * time.Second).Before(time.Now()) {
-				f.Store.DeleteEntry(ctx, p.Child(entry.Name()))
-				expiredCount++
-				continue
-			}
+		if isTtlExpired(entry, time.Now()) {
+			f.deleteTtlExpiredEntry(ctx, entry)
+			expiredCount++
+			continue
 		}
 		entries = append(entries, entry)
 	}
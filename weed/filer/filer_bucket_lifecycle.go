@@ -0,0 +1,183 @@
+package filer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+The S3 gateway saves the lifecycle rules of a bucket as json in the bucket entry. The filer
+applies them periodically: the files under the prefix of a rule are deleted, with their
+chunks, once older than the expiration days or after the expiration date, and the multipart
+uploads started longer ago than the abort days are removed.
+
+The objects in a bucket with versioning are not expired, since removing the current version
+there needs a delete marker, which is up to the S3 gateway. The S3 gateway refuses expiration
+rules on such buckets, and versioning on buckets with expiration rules.
+*/
+
+const (
+	// BucketLifecycleKey is the extended attribute of a bucket entry with its lifecycle rules
+	BucketLifecycleKey = "s3-lifecycle"
+	// BucketVersioningKey is the extended attribute of a bucket entry with its versioning status
+	BucketVersioningKey = "s3-versioning"
+
+	bucketUploadsFolder  = ".uploads"
+	bucketVersionsFolder = ".versions"
+)
+
+type LifecycleRule struct {
+	ID                       string
+	Prefix                   string
+	Enabled                  bool
+	ExpirationDays           int       `json:",omitempty"`
+	ExpirationDate           time.Time `json:",omitempty"`
+	AbortMultipartUploadDays int       `json:",omitempty"`
+}
+
+// expires tells whether a file modified at mtime is expired by the rule at now.
+func (rule *LifecycleRule) expires(mtime, now time.Time) bool {
+	if !rule.ExpirationDate.IsZero() && !now.Before(rule.ExpirationDate) {
+		return true
+	}
+	if rule.ExpirationDays > 0 && !now.Before(mtime.Add(time.Duration(rule.ExpirationDays)*24*time.Hour)) {
+		return true
+	}
+	return false
+}
+
+// LoopApplyingBucketLifecycles expires the objects and the multipart uploads by the bucket lifecycle rules.
+func (f *Filer) LoopApplyingBucketLifecycles(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		f.ApplyBucketLifecycles(time.Now())
+	}
+}
+
+func (f *Filer) ApplyBucketLifecycles(now time.Time) {
+	ctx := context.Background()
+	lastFileName := ""
+	for {
+		buckets, err := f.ListDirectoryEntries(ctx, util.FullPath(f.DirBucketsPath), lastFileName, false, PaginationSize, "")
+		if err != nil {
+			if err != filer_pb.ErrNotFound {
+				glog.Errorf("list buckets: %v", err)
+			}
+			return
+		}
+		for _, bucket := range buckets {
+			lastFileName = bucket.Name()
+			data, found := bucket.Extended[BucketLifecycleKey]
+			if !bucket.IsDirectory() || !found {
+				continue
+			}
+			var rules []*LifecycleRule
+			if err = json.Unmarshal(data, &rules); err != nil {
+				glog.Errorf("lifecycle rules of bucket %s: %v", bucket.Name(), err)
+				continue
+			}
+			isVersioned := len(bucket.Extended[BucketVersioningKey]) > 0
+			for _, rule := range rules {
+				if rule.Enabled {
+					f.applyLifecycleRule(ctx, bucket.FullPath, rule, isVersioned, now)
+				}
+			}
+		}
+		if len(buckets) < PaginationSize {
+			return
+		}
+	}
+}
+
+func (f *Filer) applyLifecycleRule(ctx context.Context, bucketPath util.FullPath, rule *LifecycleRule, isVersioned bool, now time.Time) {
+	if rule.ExpirationDays > 0 || !rule.ExpirationDate.IsZero() {
+		if isVersioned {
+			glog.V(1).Infof("bucket %s has versioning, skip expiring objects by rule %s", bucketPath, rule.ID)
+		} else {
+			dir, namePrefix := splitLocationPrefix(string(bucketPath) + "/" + rule.Prefix)
+			if err := f.expireBucketObjects(ctx, bucketPath, dir, namePrefix, rule, now); err != nil {
+				glog.Errorf("expire objects in %s by rule %s: %v", bucketPath, rule.ID, err)
+			}
+		}
+	}
+	if rule.AbortMultipartUploadDays > 0 {
+		if err := f.abortStaleUploads(ctx, bucketPath, rule, now); err != nil {
+			glog.Errorf("abort uploads in %s by rule %s: %v", bucketPath, rule.ID, err)
+		}
+	}
+}
+
+func (f *Filer) expireBucketObjects(ctx context.Context, bucketPath, dir util.FullPath, namePrefix string, rule *LifecycleRule, now time.Time) error {
+	lastFileName := ""
+	for {
+		entries, err := f.ListDirectoryEntries(ctx, dir, lastFileName, false, expiredEntriesListLimit, namePrefix)
+		if err != nil {
+			if err == filer_pb.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			lastFileName = entry.Name()
+			if entry.IsDirectory() {
+				if dir == bucketPath && (entry.Name() == bucketUploadsFolder || entry.Name() == bucketVersionsFolder) {
+					continue
+				}
+				if err = f.expireBucketObjects(ctx, bucketPath, entry.FullPath, "", rule, now); err != nil {
+					return err
+				}
+				continue
+			}
+			if !rule.expires(entry.Mtime, now) {
+				continue
+			}
+			if err = f.DeleteEntryMetaAndData(ctx, entry.FullPath, false, false, true, false, nil); err != nil {
+				glog.V(0).Infof("expire %s: %v", entry.FullPath, err)
+				continue
+			}
+			stats.FilerAutoDeletedFilesCounter.Inc()
+			glog.V(3).Infof("expired %s by lifecycle rule %s", entry.FullPath, rule.ID)
+		}
+		if len(entries) < expiredEntriesListLimit {
+			return nil
+		}
+	}
+}
+
+// abortStaleUploads removes the multipart uploads of the keys under the rule prefix, started before the abort days.
+func (f *Filer) abortStaleUploads(ctx context.Context, bucketPath util.FullPath, rule *LifecycleRule, now time.Time) error {
+	uploadsDir := bucketPath.Child(bucketUploadsFolder)
+	startedBefore := now.Add(-time.Duration(rule.AbortMultipartUploadDays) * 24 * time.Hour)
+	lastFileName := ""
+	for {
+		uploads, err := f.ListDirectoryEntries(ctx, uploadsDir, lastFileName, false, expiredEntriesListLimit, "")
+		if err != nil {
+			if err == filer_pb.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		for _, upload := range uploads {
+			lastFileName = upload.Name()
+			key := strings.TrimPrefix(string(upload.Extended["key"]), "/")
+			if !upload.IsDirectory() || !strings.HasPrefix(key, rule.Prefix) || upload.Crtime.After(startedBefore) {
+				continue
+			}
+			if err = f.DeleteEntryMetaAndData(ctx, upload.FullPath, true, false, true, false, nil); err != nil {
+				glog.V(0).Infof("abort upload %s: %v", upload.FullPath, err)
+				continue
+			}
+			glog.V(1).Infof("aborted upload %s of %s by lifecycle rule %s", upload.Name(), key, rule.ID)
+		}
+		if len(uploads) < expiredEntriesListLimit {
+			return nil
+		}
+	}
+}
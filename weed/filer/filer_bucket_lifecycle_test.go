@@ -0,0 +1,26 @@
+package filer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifecycleRuleExpires(t *testing.T) {
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	rule := &LifecycleRule{ExpirationDays: 7}
+	if rule.expires(now.Add(-6*24*time.Hour), now) {
+		t.Errorf("6 days old file should not expire in 7 days")
+	}
+	if !rule.expires(now.Add(-7*24*time.Hour), now) {
+		t.Errorf("7 days old file should expire in 7 days")
+	}
+
+	rule = &LifecycleRule{ExpirationDate: now.Add(time.Hour)}
+	if rule.expires(now.Add(-100*24*time.Hour), now) {
+		t.Errorf("file should not expire before the date")
+	}
+	if !rule.expires(now, now.Add(time.Hour)) {
+		t.Errorf("file should expire at the date")
+	}
+}
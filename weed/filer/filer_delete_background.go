@@ -0,0 +1,211 @@
+package filer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+A recursive delete of a directory with millions of entries takes longer than a client
+is willing to wait. DeleteInBackground records the directory in the filer store and
+returns. A background goroutine then deletes the directory bottom up, one entry and its
+chunks at a time. The directories still being deleted are resumed when the filer restarts.
+Each filer keeps its own list, so a filer sharing the store with others only resumes its
+own deletions.
+*/
+
+// the key prefix of the directories waiting to be deleted in the background, see LocalKvKey
+const BackgroundDeletionsKey = "filer.deletions.pending"
+
+type BackgroundDeletion struct {
+	Path               util.FullPath
+	ShouldDeleteChunks bool
+	StartedAt          time.Time
+	DeletedCount       int64
+	ErrorCount         int64
+	LastError          string
+}
+
+type backgroundDeletions struct {
+	sync.Mutex
+	deletions map[util.FullPath]*BackgroundDeletion
+}
+
+// DeleteInBackground deletes the directory and everything under it after returning.
+// A file is deleted right away.
+func (f *Filer) DeleteInBackground(ctx context.Context, p util.FullPath, shouldDeleteChunks bool) error {
+	if p == "/" {
+		return fmt.Errorf("can not delete the root folder")
+	}
+
+	entry, err := f.FindEntry(ctx, p)
+	if err != nil {
+		return err
+	}
+	if !entry.IsDirectory() {
+		return f.DeleteEntryMetaAndData(ctx, p, false, false, shouldDeleteChunks, false, nil)
+	}
+
+	f.backgroundDeletions.Lock()
+	if _, found := f.backgroundDeletions.deletions[p]; found {
+		f.backgroundDeletions.Unlock()
+		return nil
+	}
+	d := &BackgroundDeletion{
+		Path:               p,
+		ShouldDeleteChunks: shouldDeleteChunks,
+		StartedAt:          time.Now(),
+	}
+	f.backgroundDeletions.deletions[p] = d
+	err = f.saveBackgroundDeletions(ctx)
+	f.backgroundDeletions.Unlock()
+	if err != nil {
+		return fmt.Errorf("save background deletion %s: %v", p, err)
+	}
+
+	go f.runBackgroundDeletion(d)
+
+	return nil
+}
+
+// ResumeBackgroundDeletions restarts the background deletions not finished before the filer stopped.
+func (f *Filer) ResumeBackgroundDeletions() {
+	ctx := context.Background()
+	value, err := f.Store.KvGet(ctx, f.LocalKvKey(BackgroundDeletionsKey))
+	if err == ErrKvNotFound {
+		return
+	}
+	if err != nil {
+		glog.Errorf("read background deletions: %v", err)
+		return
+	}
+	var pending []*BackgroundDeletion
+	if err = json.Unmarshal(value, &pending); err != nil {
+		glog.Errorf("decode background deletions: %v", err)
+		return
+	}
+
+	f.backgroundDeletions.Lock()
+	defer f.backgroundDeletions.Unlock()
+	for _, d := range pending {
+		if _, found := f.backgroundDeletions.deletions[d.Path]; found {
+			continue
+		}
+		glog.V(0).Infof("resume deleting %s in background", d.Path)
+		d.StartedAt = time.Now()
+		f.backgroundDeletions.deletions[d.Path] = d
+		go f.runBackgroundDeletion(d)
+	}
+}
+
+// ListBackgroundDeletions returns the running background deletions, oldest first.
+func (f *Filer) ListBackgroundDeletions() (deletions []BackgroundDeletion) {
+	f.backgroundDeletions.Lock()
+	for _, d := range f.backgroundDeletions.deletions {
+		deletions = append(deletions, BackgroundDeletion{
+			Path:               d.Path,
+			ShouldDeleteChunks: d.ShouldDeleteChunks,
+			StartedAt:          d.StartedAt,
+			DeletedCount:       atomic.LoadInt64(&d.DeletedCount),
+			ErrorCount:         atomic.LoadInt64(&d.ErrorCount),
+			LastError:          d.LastError,
+		})
+	}
+	f.backgroundDeletions.Unlock()
+
+	sort.Slice(deletions, func(i, j int) bool {
+		return deletions[i].StartedAt.Before(deletions[j].StartedAt)
+	})
+	return
+}
+
+func (f *Filer) runBackgroundDeletion(d *BackgroundDeletion) {
+	ctx := context.Background()
+
+	glog.V(0).Infof("start deleting %s in background", d.Path)
+
+	// the chunks of a bucket go away together with its collection
+	shouldDeleteChunks := d.ShouldDeleteChunks
+	if entry, err := f.FindEntry(ctx, d.Path); err == nil && f.isBucket(entry) {
+		shouldDeleteChunks = false
+	}
+
+	f.deleteFolderInBackground(ctx, d, d.Path, shouldDeleteChunks)
+	if err := f.DeleteEntryMetaAndData(ctx, d.Path, false, false, shouldDeleteChunks, false, nil); err != nil && err != filer_pb.ErrNotFound {
+		f.recordBackgroundDeletionError(d, err)
+	} else {
+		atomic.AddInt64(&d.DeletedCount, 1)
+	}
+
+	f.backgroundDeletions.Lock()
+	delete(f.backgroundDeletions.deletions, d.Path)
+	if err := f.saveBackgroundDeletions(ctx); err != nil {
+		glog.Errorf("save background deletions: %v", err)
+	}
+	f.backgroundDeletions.Unlock()
+
+	glog.V(0).Infof("finish deleting %s in background: %d deleted, %d errors", d.Path, atomic.LoadInt64(&d.DeletedCount), atomic.LoadInt64(&d.ErrorCount))
+}
+
+func (f *Filer) deleteFolderInBackground(ctx context.Context, d *BackgroundDeletion, dir util.FullPath, shouldDeleteChunks bool) {
+	lastFileName := ""
+	for {
+		entries, err := f.ListDirectoryEntries(ctx, dir, lastFileName, false, PaginationSize, "")
+		if err != nil {
+			if err != filer_pb.ErrNotFound {
+				f.recordBackgroundDeletionError(d, fmt.Errorf("list folder %s: %v", dir, err))
+			}
+			return
+		}
+		for _, entry := range entries {
+			lastFileName = entry.Name()
+			if entry.IsDirectory() {
+				f.deleteFolderInBackground(ctx, d, entry.FullPath, shouldDeleteChunks)
+			}
+			if err = f.DeleteEntryMetaAndData(ctx, entry.FullPath, false, false, shouldDeleteChunks, false, nil); err != nil {
+				f.recordBackgroundDeletionError(d, err)
+				continue
+			}
+			atomic.AddInt64(&d.DeletedCount, 1)
+		}
+		if len(entries) < PaginationSize {
+			return
+		}
+	}
+}
+
+func (f *Filer) recordBackgroundDeletionError(d *BackgroundDeletion, err error) {
+	glog.V(0).Infof("delete %s in background: %v", d.Path, err)
+	atomic.AddInt64(&d.ErrorCount, 1)
+	f.backgroundDeletions.Lock()
+	d.LastError = err.Error()
+	f.backgroundDeletions.Unlock()
+}
+
+// saveBackgroundDeletions persists the running deletions. The caller holds the lock.
+func (f *Filer) saveBackgroundDeletions(ctx context.Context) error {
+	var pending []*BackgroundDeletion
+	for _, d := range f.backgroundDeletions.deletions {
+		pending = append(pending, &BackgroundDeletion{
+			Path:               d.Path,
+			ShouldDeleteChunks: d.ShouldDeleteChunks,
+		})
+	}
+	if len(pending) == 0 {
+		return f.Store.KvDelete(ctx, f.LocalKvKey(BackgroundDeletionsKey))
+	}
+	value, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return f.Store.KvPut(ctx, f.LocalKvKey(BackgroundDeletionsKey), value)
+}
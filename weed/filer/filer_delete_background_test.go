@@ -0,0 +1,119 @@
+package filer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+type memStore struct {
+	kvOnlyStore
+	sync.Mutex
+	entries map[util.FullPath]*Entry
+}
+
+func (s *memStore) GetName() string { return "mem" }
+func (s *memStore) InsertEntry(ctx context.Context, entry *Entry) error {
+	s.Lock()
+	defer s.Unlock()
+	s.entries[entry.FullPath] = entry
+	return nil
+}
+func (s *memStore) UpdateEntry(ctx context.Context, entry *Entry) error {
+	return s.InsertEntry(ctx, entry)
+}
+func (s *memStore) FindEntry(ctx context.Context, p util.FullPath) (*Entry, error) {
+	s.Lock()
+	defer s.Unlock()
+	if entry, found := s.entries[p]; found {
+		return entry, nil
+	}
+	return nil, filer_pb.ErrNotFound
+}
+func (s *memStore) DeleteEntry(ctx context.Context, p util.FullPath) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.entries, p)
+	return nil
+}
+func (s *memStore) DeleteFolderChildren(ctx context.Context, p util.FullPath) error {
+	s.Lock()
+	defer s.Unlock()
+	for child := range s.entries {
+		if strings.HasPrefix(string(child), string(p)+"/") {
+			delete(s.entries, child)
+		}
+	}
+	return nil
+}
+func (s *memStore) ListDirectoryPrefixedEntries(ctx context.Context, dirPath util.FullPath, startFileName string, includeStartFile bool, limit int, prefix string) ([]*Entry, error) {
+	return nil, ErrUnsupportedListDirectoryPrefixed
+}
+func (s *memStore) ListDirectoryEntries(ctx context.Context, dirPath util.FullPath, startFileName string, includeStartFile bool, limit int) (entries []*Entry, err error) {
+	s.Lock()
+	defer s.Unlock()
+	for p, entry := range s.entries {
+		if dir, name := p.DirAndName(); dir == string(dirPath) && (name > startFileName || includeStartFile && name == startFileName) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return
+}
+
+func TestResumeBackgroundDeletions(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{kvOnlyStore: kvOnlyStore{kv: make(map[string][]byte)}, entries: make(map[util.FullPath]*Entry)}
+	f := NewFiler(nil, nil, "", 0, "", "", "", nil)
+	f.SetStore(store)
+
+	for _, p := range []util.FullPath{"/dir", "/dir/a", "/other"} {
+		store.InsertEntry(ctx, &Entry{FullPath: p, Attr: Attr{Mode: os.ModeDir | 0755}})
+	}
+	for _, p := range []util.FullPath{"/dir/a/x", "/dir/a/y", "/dir/b", "/other/c"} {
+		store.InsertEntry(ctx, &Entry{FullPath: p, Attr: Attr{Mode: 0644}})
+	}
+
+	// the filer stopped before deleting /dir, and another filer on the same store is deleting /other
+	value, _ := json.Marshal([]*BackgroundDeletion{{Path: "/dir"}})
+	store.KvPut(ctx, f.LocalKvKey(BackgroundDeletionsKey), value)
+	other := NewFiler(nil, nil, "other", 8888, "", "", "", nil)
+	value, _ = json.Marshal([]*BackgroundDeletion{{Path: "/other"}})
+	store.KvPut(ctx, other.LocalKvKey(BackgroundDeletionsKey), value)
+
+	f.ResumeBackgroundDeletions()
+
+	// finished deletions are removed
+	deletions := f.ListBackgroundDeletions()
+	for start := time.Now(); time.Since(start) < 10*time.Second && len(deletions) > 0; time.Sleep(10 * time.Millisecond) {
+		deletions = f.ListBackgroundDeletions()
+	}
+	if len(deletions) != 0 {
+		t.Fatalf("finished background deletions: %+v", deletions)
+	}
+
+	for _, p := range []util.FullPath{"/dir", "/dir/a", "/dir/a/x", "/dir/a/y", "/dir/b"} {
+		if _, err := store.FindEntry(ctx, p); err != filer_pb.ErrNotFound {
+			t.Errorf("%s is not deleted", p)
+		}
+	}
+	for _, p := range []util.FullPath{"/other", "/other/c"} {
+		if _, err := store.FindEntry(ctx, p); err != nil {
+			t.Errorf("%s is deleted", p)
+		}
+	}
+	if _, err := store.KvGet(ctx, f.LocalKvKey(BackgroundDeletionsKey)); err != ErrKvNotFound {
+		t.Errorf("finished deletion is still pending: %v", err)
+	}
+}
@@ -26,7 +26,10 @@ func (f *Filer) DeleteEntryMetaAndData(ctx context.Context, p util.FullPath, isR
 
 	var chunks []*filer_pb.FileChunk
 	var hardLinkIds []HardLinkId
-	chunks = append(chunks, entry.Chunks...)
+	if len(entry.HardLinkId) == 0 {
+		// hard link chunk data are deleted by the reaper when no longer referenced
+		chunks = append(chunks, entry.Chunks...)
+	}
 	if entry.IsDirectory() {
 		// delete the folder children, not including the folder itself
 		var dirChunks []*filer_pb.FileChunk
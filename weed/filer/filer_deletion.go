@@ -20,6 +20,7 @@ func LookupByMasterClientFn(masterClient *wdclient.MasterClient) func(vids []str
 				locations = append(locations, operation.Location{
 					Url:       loc.Url,
 					PublicUrl: loc.PublicUrl,
+					GrpcPort:  loc.GrpcPort,
 				})
 			}
 			m[vid] = operation.LookupResult{
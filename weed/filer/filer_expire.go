@@ -0,0 +1,66 @@
+package filer
+
+import (
+	"context"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+The files under a location with a ttl in filer.conf expire together with their chunks,
+and are deleted when they are next looked up or listed. Files never visited again
+would stay in the filer store, so the locations with a ttl are listed periodically.
+*/
+
+const expiredEntriesListLimit = 1024
+
+// LoopDeletingExpiredEntries deletes the expired files under the locations with a ttl.
+func (f *Filer) LoopDeletingExpiredEntries(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		f.DeleteExpiredEntries()
+	}
+}
+
+func (f *Filer) DeleteExpiredEntries() {
+	var prefixes []string
+	for _, location := range f.FilerConf.ToProto().Locations {
+		if location.Ttl != "" {
+			prefixes = append(prefixes, location.LocationPrefix)
+		}
+	}
+	for _, prefix := range prefixes {
+		dir, namePrefix := splitLocationPrefix(prefix)
+		if err := f.deleteExpiredEntries(context.Background(), dir, namePrefix); err != nil {
+			glog.Errorf("delete expired entries under %s: %v", prefix, err)
+		}
+	}
+}
+
+// deleteExpiredEntries lists the directory tree, which deletes the expired files found.
+func (f *Filer) deleteExpiredEntries(ctx context.Context, dir util.FullPath, namePrefix string) error {
+	lastFileName := ""
+	for {
+		entries, err := f.ListDirectoryEntries(ctx, dir, lastFileName, false, expiredEntriesListLimit, namePrefix)
+		if err != nil {
+			if err == filer_pb.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			lastFileName = entry.Name()
+			if entry.IsDirectory() {
+				if err = f.deleteExpiredEntries(ctx, entry.FullPath, ""); err != nil {
+					return err
+				}
+			}
+		}
+		if len(entries) < expiredEntriesListLimit {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package filer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func TestMaybeSetTtl(t *testing.T) {
+	f := &Filer{FilerConf: NewFilerConf()}
+	f.FilerConf.doLoadConf(&filer_pb.FilerConf{Locations: []*filer_pb.FilerConf_PathConf{
+		{
+			LocationPrefix: "/logs/",
+			Ttl:            "7d",
+		},
+	}})
+
+	entry := &Entry{FullPath: util.FullPath("/logs/app/1.log"), Attr: Attr{Crtime: time.Now().Add(-time.Hour)}}
+	f.maybeSetTtl(entry)
+	if entry.TtlSec != 7*24*3600 {
+		t.Errorf("ttl %d, expected 7 days", entry.TtlSec)
+	}
+	if isTtlExpired(entry, time.Now().Add(7*24*time.Hour-time.Minute)) || !isTtlExpired(entry, time.Now().Add(7*24*time.Hour+time.Minute)) {
+		t.Errorf("ttl should count from the write, created at %v", entry.Crtime)
+	}
+
+	entry = &Entry{FullPath: util.FullPath("/logs/app/2.log"), Attr: Attr{TtlSec: 60}}
+	f.maybeSetTtl(entry)
+	if entry.TtlSec != 60 {
+		t.Errorf("ttl of the upload %d, expected 60", entry.TtlSec)
+	}
+
+	entry = &Entry{FullPath: util.FullPath("/data/1.log")}
+	f.maybeSetTtl(entry)
+	if entry.TtlSec != 0 {
+		t.Errorf("entry outside of the location should not expire")
+	}
+}
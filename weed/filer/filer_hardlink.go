@@ -0,0 +1,41 @@
+package filer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// hardLinkMarker ends a hard link id, same as the ids created by weed mount
+const hardLinkMarker = '\x01'
+
+// CreateHardLink links newPath to the file at oldPath. Both entries then share
+// the attributes and chunks kept under the hard link id.
+func (f *Filer) CreateHardLink(ctx context.Context, oldPath, newPath util.FullPath) error {
+	oldEntry, err := f.FindEntry(ctx, oldPath)
+	if err != nil {
+		return err
+	}
+	if oldEntry.IsDirectory() {
+		return fmt.Errorf("hard link %s: is a directory", oldPath)
+	}
+	if _, err = f.FindEntry(ctx, newPath); err == nil {
+		return fmt.Errorf("EEXIST: entry %s already exists", newPath)
+	}
+
+	updatedEntry := oldEntry.Clone()
+	if len(updatedEntry.HardLinkId) == 0 {
+		updatedEntry.HardLinkId = append(util.RandomBytes(16), hardLinkMarker)
+		updatedEntry.HardLinkCounter = 1
+	}
+	updatedEntry.HardLinkCounter++
+	if err = f.UpdateEntry(ctx, oldEntry, updatedEntry); err != nil {
+		return fmt.Errorf("hard link %s: %v", oldPath, err)
+	}
+	f.NotifyUpdateEvent(ctx, oldEntry, updatedEntry, false, false, nil)
+
+	newEntry := updatedEntry.Clone()
+	newEntry.FullPath = newPath
+	return f.CreateEntry(ctx, newEntry, true, false, nil)
+}
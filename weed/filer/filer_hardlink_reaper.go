@@ -0,0 +1,142 @@
+package filer
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+A hard link whose counter reaches zero is queued by a marker entry named after its id,
+written to the store directly, so filers sharing the store queue and reap independently.
+The reaper removes the marker first, so a counter reaching zero again meanwhile queues the
+hard link again. Then it deletes the hard link record only if it is unchanged since read,
+so a link created meanwhile by any filer keeps the record and its chunks.
+*/
+
+// the directory of the markers of hard links whose counter reached zero
+const HardLinksToReapDir = TopicsDir + "/.system/hardlinks_to_reap"
+
+func (f *Filer) LoopReapingHardLinks(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		f.ReapHardLinks()
+	}
+}
+
+// ReapHardLinks deletes the chunks and the records of hard links that are no longer referenced.
+func (f *Filer) ReapHardLinks() {
+	ctx := context.Background()
+	hardLinkIds, err := f.Store.ListHardLinksToReap(ctx)
+	if err != nil {
+		glog.Errorf("list hard links to reap: %v", err)
+		return
+	}
+	for _, hardLinkId := range hardLinkIds {
+		chunks, err := f.Store.ReapHardLink(ctx, hardLinkId)
+		if err != nil {
+			glog.Errorf("reap hard link %x: %v", hardLinkId, err)
+			continue
+		}
+		if len(chunks) > 0 {
+			glog.V(2).Infof("reap hard link %x: delete %d chunks", hardLinkId, len(chunks))
+			f.DirectDeleteChunks(chunks)
+		}
+	}
+}
+
+func (fsw *FilerStoreWrapper) ListHardLinksToReap(ctx context.Context) (ids []HardLinkId, err error) {
+	lastFileName := ""
+	for {
+		entries, err := fsw.ActualStore.ListDirectoryEntries(ctx, HardLinksToReapDir, lastFileName, false, PaginationSize)
+		if err == filer_pb.ErrNotFound {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			lastFileName = entry.Name()
+			id, decodeErr := hex.DecodeString(lastFileName)
+			if decodeErr != nil {
+				glog.Warningf("hard link to reap %s: %v", entry.FullPath, decodeErr)
+				continue
+			}
+			ids = append(ids, id)
+		}
+		if len(entries) < PaginationSize {
+			return ids, nil
+		}
+	}
+}
+
+// ReapHardLink removes the hard link record if its counter is zero, and returns its chunks to be deleted.
+func (fsw *FilerStoreWrapper) ReapHardLink(ctx context.Context, hardLinkId HardLinkId) (chunks []*filer_pb.FileChunk, err error) {
+	markerPath := hardLinkToReapPath(hardLinkId)
+	if err = fsw.ActualStore.DeleteEntry(ctx, markerPath); err != nil && err != filer_pb.ErrNotFound {
+		return nil, err
+	}
+
+	value, err := fsw.KvGet(ctx, hardLinkId)
+	if err == ErrKvNotFound {
+		return nil, nil
+	}
+	if err == nil {
+		entry := &Entry{}
+		if err = entry.DecodeAttributesAndChunks(value); err != nil {
+			return nil, err
+		}
+		if entry.HardLinkCounter > 0 {
+			return nil, nil
+		}
+		var deleted bool
+		if deleted, err = fsw.kvDeleteIfUnchanged(ctx, hardLinkId, value); err == nil {
+			if deleted {
+				return entry.Chunks, nil
+			}
+			// changed since read, check again in the next round
+		}
+	}
+
+	if queueErr := fsw.queueHardLinkToReap(ctx, hardLinkId); queueErr != nil {
+		glog.Errorf("queue hard link %x to reap: %v", hardLinkId, queueErr)
+	}
+	return nil, err
+}
+
+func (fsw *FilerStoreWrapper) kvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (deleted bool, err error) {
+	if deleter, ok := fsw.ActualStore.(KvConditionalDeleter); ok {
+		return deleter.KvDeleteIfUnchanged(ctx, key, value)
+	}
+
+	fsw.hardLinkLock.Lock()
+	defer fsw.hardLinkLock.Unlock()
+
+	current, err := fsw.KvGet(ctx, key)
+	if err == ErrKvNotFound {
+		return false, nil
+	}
+	if err != nil || string(current) != string(value) {
+		return false, err
+	}
+	return true, fsw.KvDelete(ctx, key)
+}
+
+func (fsw *FilerStoreWrapper) queueHardLinkToReap(ctx context.Context, hardLinkId HardLinkId) error {
+	return fsw.ActualStore.InsertEntry(ctx, &Entry{
+		FullPath: hardLinkToReapPath(hardLinkId),
+		Attr: Attr{
+			Mtime:  time.Now(),
+			Crtime: time.Now(),
+			Mode:   0600,
+		},
+	})
+}
+
+func hardLinkToReapPath(hardLinkId HardLinkId) util.FullPath {
+	return util.NewFullPath(HardLinksToReapDir, hex.EncodeToString(hardLinkId))
+}
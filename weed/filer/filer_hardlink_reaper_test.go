@@ -0,0 +1,162 @@
+package filer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+type kvOnlyStore struct {
+	FilerStore
+	kv map[string][]byte
+}
+
+func (s *kvOnlyStore) GetName() string { return "kv" }
+func (s *kvOnlyStore) KvPut(ctx context.Context, key []byte, value []byte) error {
+	s.kv[string(key)] = value
+	return nil
+}
+func (s *kvOnlyStore) KvGet(ctx context.Context, key []byte) ([]byte, error) {
+	if value, found := s.kv[string(key)]; found {
+		return value, nil
+	}
+	return nil, ErrKvNotFound
+}
+func (s *kvOnlyStore) KvDelete(ctx context.Context, key []byte) error {
+	delete(s.kv, string(key))
+	return nil
+}
+
+func newHardLinkTestStore() *FilerStoreWrapper {
+	fsw := NewFilerStoreWrapper(&memStore{kvOnlyStore: kvOnlyStore{kv: make(map[string][]byte)}, entries: make(map[util.FullPath]*Entry)})
+	fsw.queueHardLinksToReap = true
+	return fsw
+}
+
+func TestReapHardLink(t *testing.T) {
+	ctx := context.Background()
+	fsw := newHardLinkTestStore()
+
+	relinked, unlinked := HardLinkId("relinked"), HardLinkId("unlinked")
+	for _, id := range []HardLinkId{relinked, unlinked} {
+		entry := &Entry{
+			HardLinkId:      id,
+			HardLinkCounter: 1,
+			Chunks:          []*filer_pb.FileChunk{{FileId: "3,01637037d6"}},
+		}
+		if err := fsw.setHardLink(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsw.DeleteHardLink(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a new link is created before the reaper runs
+	entry := &Entry{HardLinkId: relinked, HardLinkCounter: 1}
+	if err := fsw.setHardLink(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := fsw.ListHardLinksToReap(ctx)
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("hard links to reap: %v %v", ids, err)
+	}
+	for _, id := range ids {
+		chunks, err := fsw.ReapHardLink(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(id) == string(relinked) && len(chunks) != 0 {
+			t.Errorf("relinked hard link is reaped")
+		}
+		if string(id) == string(unlinked) && len(chunks) != 1 {
+			t.Errorf("unlinked hard link chunks: %v", chunks)
+		}
+	}
+
+	if _, err = fsw.KvGet(ctx, relinked); err != nil {
+		t.Errorf("relinked hard link: %v", err)
+	}
+	if _, err = fsw.KvGet(ctx, unlinked); err != ErrKvNotFound {
+		t.Errorf("unlinked hard link is not deleted: %v", err)
+	}
+	if ids, _ = fsw.ListHardLinksToReap(ctx); len(ids) != 0 {
+		t.Errorf("hard links left to reap: %v", ids)
+	}
+}
+
+// the store changes between reading the hard link record and deleting it
+type relinkingStore struct {
+	*memStore
+	relink func()
+}
+
+func (s *relinkingStore) KvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (bool, error) {
+	s.relink()
+	if current, found := s.kv[string(key)]; !found || string(current) != string(value) {
+		return false, nil
+	}
+	delete(s.kv, string(key))
+	return true, nil
+}
+
+func TestReapRelinkedHardLink(t *testing.T) {
+	ctx := context.Background()
+	relinked := HardLinkId("relinked")
+	rs := &relinkingStore{memStore: &memStore{kvOnlyStore: kvOnlyStore{kv: make(map[string][]byte)}, entries: make(map[util.FullPath]*Entry)}}
+	fsw := NewFilerStoreWrapper(rs)
+	fsw.queueHardLinksToReap = true
+	rs.relink = func() {
+		// another filer on the same store links the file again
+		if err := fsw.setHardLink(ctx, &Entry{HardLinkId: relinked, HardLinkCounter: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fsw.setHardLink(ctx, &Entry{HardLinkId: relinked, HardLinkCounter: 1, Chunks: []*filer_pb.FileChunk{{FileId: "3,01637037d6"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsw.DeleteHardLink(ctx, relinked); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := fsw.ReapHardLink(ctx, relinked)
+	if err != nil || len(chunks) != 0 {
+		t.Fatalf("reap relinked hard link: %v %v", chunks, err)
+	}
+	if _, err = fsw.KvGet(ctx, relinked); err != nil {
+		t.Errorf("relinked hard link: %v", err)
+	}
+
+	// checked again in the next round, and dropped since it is linked
+	rs.relink = func() {}
+	if ids, _ := fsw.ListHardLinksToReap(ctx); len(ids) != 1 {
+		t.Fatalf("hard links to reap: %v", ids)
+	}
+	if chunks, err = fsw.ReapHardLink(ctx, relinked); err != nil || len(chunks) != 0 {
+		t.Fatalf("reap linked hard link: %v %v", chunks, err)
+	}
+	if ids, _ := fsw.ListHardLinksToReap(ctx); len(ids) != 0 {
+		t.Errorf("hard links left to reap: %v", ids)
+	}
+}
+
+func TestMetaCacheDoesNotQueueHardLinks(t *testing.T) {
+	ctx := context.Background()
+	fsw := newHardLinkTestStore()
+	fsw.queueHardLinksToReap = false
+
+	id := HardLinkId("unlinked")
+	if err := fsw.setHardLink(ctx, &Entry{HardLinkId: id, HardLinkCounter: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsw.DeleteHardLink(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := fsw.ListHardLinksToReap(ctx); len(ids) != 0 {
+		t.Errorf("hard links queued to reap: %v", ids)
+	}
+}
@@ -30,6 +30,8 @@ func (f *Filer) NotifyUpdateEvent(ctx context.Context, oldEntry, newEntry *Entry
 	if strings.HasPrefix(fullpath, SystemLogDir) {
 		return
 	}
+	f.updateQuotaUsage(oldEntry, newEntry)
+
 	foundSelf := false
 	for _, sig := range signatures {
 		if sig == f.Signature {
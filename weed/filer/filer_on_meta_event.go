@@ -46,6 +46,8 @@ func (f *Filer) reloadFilerConfiguration(entry *filer_pb.Entry) {
 		return
 	}
 	f.FilerConf = fc
+
+	go f.CountQuotaUsage(true)
 }
 
 func (f *Filer) LoadFilerConf() {
@@ -0,0 +1,276 @@
+package filer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+A location in filer.conf can have max_bytes and max_file_count. The files under the
+location prefix, including the ones in sub directories, are counted when the filer
+starts or the configuration changes, and recounted periodically. The usage is kept in
+the filer store, so it survives restarts and is shared by the filers on the same store.
+In between the counts, each filer updates it on every change made through it. Two filers
+changing the same location at once may lose an update, until the next recount.
+
+A write going over the quota of any location containing it is rejected. A location is
+not enforced until it is counted.
+*/
+
+// the key prefix of the usage of a location with a quota, followed by the location prefix
+const QuotaUsageKey = "filer.quota.usage"
+
+// ErrQuotaExceeded is in the error of a write rejected by a quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+type QuotaUsage struct {
+	LocationPrefix string
+	MaxBytes       int64
+	MaxFileCount   int64
+	UsedBytes      int64
+	UsedFileCount  int64
+	IsCounted      bool
+}
+
+type quotaUsage struct {
+	Bytes     int64
+	FileCount int64
+}
+
+type filerQuotas struct {
+	// serializes the usage updates of this filer
+	sync.Mutex
+	// the location prefixes counted by this filer, to drop the usage of the ones no longer configured
+	counted map[string]bool
+}
+
+func (f *Filer) quotaLocations() (locations []*filer_pb.FilerConf_PathConf) {
+	for _, location := range f.FilerConf.ToProto().Locations {
+		if location.MaxBytes > 0 || location.MaxFileCount > 0 {
+			locations = append(locations, location)
+		}
+	}
+	return
+}
+
+func quotaUsageKey(locationPrefix string) []byte {
+	return []byte(QuotaUsageKey + ":" + locationPrefix)
+}
+
+// getQuotaUsage returns nil if the location is not counted yet.
+func (f *Filer) getQuotaUsage(ctx context.Context, locationPrefix string) (*quotaUsage, error) {
+	value, err := f.Store.KvGet(ctx, quotaUsageKey(locationPrefix))
+	if err == ErrKvNotFound || err == nil && len(value) == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	usage := &quotaUsage{}
+	if err = json.Unmarshal(value, usage); err != nil {
+		return nil, fmt.Errorf("usage of %s: %v", locationPrefix, err)
+	}
+	return usage, nil
+}
+
+func (f *Filer) putQuotaUsage(ctx context.Context, locationPrefix string, usage *quotaUsage) error {
+	value, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	return f.Store.KvPut(ctx, quotaUsageKey(locationPrefix), value)
+}
+
+// CheckQuota returns an error if adding the bytes and files under p goes over a quota.
+func (f *Filer) CheckQuota(p util.FullPath, addBytes, addFiles int64) error {
+	locations := f.quotaLocations()
+	if len(locations) == 0 {
+		return nil
+	}
+
+	for _, location := range locations {
+		if !strings.HasPrefix(string(p), location.LocationPrefix) {
+			continue
+		}
+		usage, err := f.getQuotaUsage(context.Background(), location.LocationPrefix)
+		if err != nil {
+			glog.Errorf("check quota of %s: %v", location.LocationPrefix, err)
+			continue
+		}
+		if usage == nil {
+			continue
+		}
+		if location.MaxBytes > 0 && addBytes > 0 && usage.Bytes+addBytes > location.MaxBytes {
+			return fmt.Errorf("%v: %s uses %d of %d bytes, can not add %d bytes", ErrQuotaExceeded, location.LocationPrefix, usage.Bytes, location.MaxBytes, addBytes)
+		}
+		if location.MaxFileCount > 0 && addFiles > 0 && usage.FileCount+addFiles > location.MaxFileCount {
+			return fmt.Errorf("%v: %s has %d of %d files", ErrQuotaExceeded, location.LocationPrefix, usage.FileCount, location.MaxFileCount)
+		}
+	}
+	return nil
+}
+
+// updateQuotaUsage moves the usage of a changed file from its old to its new locations.
+func (f *Filer) updateQuotaUsage(oldEntry, newEntry *Entry) {
+	locations := f.quotaLocations()
+	if len(locations) == 0 {
+		return
+	}
+
+	f.quotas.Lock()
+	defer f.quotas.Unlock()
+	for _, location := range locations {
+		var bytes, files int64
+		if oldEntry != nil && !oldEntry.IsDirectory() && strings.HasPrefix(string(oldEntry.FullPath), location.LocationPrefix) {
+			bytes, files = bytes-int64(oldEntry.Size()), files-1
+		}
+		if newEntry != nil && !newEntry.IsDirectory() && strings.HasPrefix(string(newEntry.FullPath), location.LocationPrefix) {
+			bytes, files = bytes+int64(newEntry.Size()), files+1
+		}
+		if bytes == 0 && files == 0 {
+			continue
+		}
+		if err := f.addQuotaUsage(location.LocationPrefix, bytes, files); err != nil {
+			glog.Errorf("update usage of %s: %v", location.LocationPrefix, err)
+		}
+	}
+}
+
+// addQuotaUsage is called with f.quotas locked.
+func (f *Filer) addQuotaUsage(locationPrefix string, bytes, files int64) error {
+	ctx := context.Background()
+	usage, err := f.getQuotaUsage(ctx, locationPrefix)
+	if err != nil || usage == nil {
+		return err
+	}
+	usage.Bytes += bytes
+	usage.FileCount += files
+	return f.putQuotaUsage(ctx, locationPrefix, usage)
+}
+
+// ListQuotaUsage returns the usage of the locations with a quota, sorted by location prefix.
+func (f *Filer) ListQuotaUsage() (usages []QuotaUsage) {
+	for _, location := range f.quotaLocations() {
+		quotaUsage := QuotaUsage{
+			LocationPrefix: location.LocationPrefix,
+			MaxBytes:       location.MaxBytes,
+			MaxFileCount:   location.MaxFileCount,
+		}
+		usage, err := f.getQuotaUsage(context.Background(), location.LocationPrefix)
+		if err != nil {
+			glog.Errorf("list usage of %s: %v", location.LocationPrefix, err)
+		}
+		if usage != nil {
+			quotaUsage.UsedBytes = usage.Bytes
+			quotaUsage.UsedFileCount = usage.FileCount
+			quotaUsage.IsCounted = true
+		}
+		usages = append(usages, quotaUsage)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].LocationPrefix < usages[j].LocationPrefix
+	})
+	return
+}
+
+// LoopCountingQuotaUsage recounts the usage of the locations with a quota.
+func (f *Filer) LoopCountingQuotaUsage(interval time.Duration) {
+	for {
+		f.CountQuotaUsage(false)
+		time.Sleep(interval)
+	}
+}
+
+// CountQuotaUsage counts the usage of the locations with a quota, or only the ones not counted yet.
+func (f *Filer) CountQuotaUsage(onlyNew bool) {
+	ctx := context.Background()
+	locations := f.quotaLocations()
+
+	f.quotas.Lock()
+	configured := make(map[string]bool)
+	for _, location := range locations {
+		configured[location.LocationPrefix] = true
+	}
+	for locationPrefix := range f.quotas.counted {
+		if !configured[locationPrefix] {
+			if err := f.Store.KvDelete(ctx, quotaUsageKey(locationPrefix)); err != nil {
+				glog.Errorf("drop usage of %s: %v", locationPrefix, err)
+				continue
+			}
+			delete(f.quotas.counted, locationPrefix)
+		}
+	}
+	f.quotas.Unlock()
+
+	for _, location := range locations {
+		if onlyNew {
+			if usage, err := f.getQuotaUsage(ctx, location.LocationPrefix); err != nil || usage != nil {
+				continue
+			}
+		}
+		usage := &quotaUsage{}
+		dir, namePrefix := splitLocationPrefix(location.LocationPrefix)
+		if err := f.countFiles(ctx, dir, namePrefix, usage); err != nil {
+			glog.Errorf("count usage of %s: %v", location.LocationPrefix, err)
+			continue
+		}
+		glog.V(1).Infof("quota %s: %d bytes, %d files", location.LocationPrefix, usage.Bytes, usage.FileCount)
+		f.quotas.Lock()
+		if err := f.putQuotaUsage(ctx, location.LocationPrefix, usage); err != nil {
+			glog.Errorf("save usage of %s: %v", location.LocationPrefix, err)
+		} else {
+			f.quotas.counted[location.LocationPrefix] = true
+		}
+		f.quotas.Unlock()
+	}
+}
+
+func (f *Filer) countFiles(ctx context.Context, dir util.FullPath, namePrefix string, usage *quotaUsage) error {
+	lastFileName := ""
+	for {
+		entries, err := f.ListDirectoryEntries(ctx, dir, lastFileName, false, PaginationSize, namePrefix)
+		if err != nil {
+			if err == filer_pb.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			lastFileName = entry.Name()
+			if entry.IsDirectory() {
+				if err = f.countFiles(ctx, entry.FullPath, "", usage); err != nil {
+					return err
+				}
+				continue
+			}
+			usage.Bytes += int64(entry.Size())
+			usage.FileCount++
+		}
+		if len(entries) < PaginationSize {
+			return nil
+		}
+	}
+}
+
+// splitLocationPrefix splits a location prefix, which may end in the middle of a file name.
+func splitLocationPrefix(prefix string) (dir util.FullPath, namePrefix string) {
+	d, namePrefix := util.FullPath(prefix).DirAndName()
+	if strings.HasSuffix(prefix, "/") {
+		d, namePrefix = strings.TrimSuffix(prefix, "/"), ""
+	}
+	if d == "" {
+		d = "/"
+	}
+	return util.FullPath(d), namePrefix
+}
@@ -0,0 +1,74 @@
+package filer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func TestQuotaUsage(t *testing.T) {
+	store := NewFilerStoreWrapper(&kvOnlyStore{kv: make(map[string][]byte)})
+	f := &Filer{
+		Store:     store,
+		FilerConf: NewFilerConf(),
+		quotas:    filerQuotas{counted: make(map[string]bool)},
+	}
+	f.FilerConf.AddLocationConf(&filer_pb.FilerConf_PathConf{LocationPrefix: "/home/", MaxBytes: 100})
+	f.FilerConf.AddLocationConf(&filer_pb.FilerConf_PathConf{LocationPrefix: "/home/chris/", MaxFileCount: 2})
+
+	// not enforced before counted
+	if err := f.CheckQuota("/home/a", 1000, 1); err != nil {
+		t.Fatalf("uncounted quota enforced: %v", err)
+	}
+	ctx := context.Background()
+	f.putQuotaUsage(ctx, "/home/", &quotaUsage{})
+	f.putQuotaUsage(ctx, "/home/chris/", &quotaUsage{})
+
+	file := func(path string, size uint64) *Entry {
+		return &Entry{FullPath: util.FullPath("/home/" + path), Attr: Attr{FileSize: size}}
+	}
+	f.updateQuotaUsage(nil, file("chris/a", 60))
+	f.updateQuotaUsage(nil, file("b", 30))
+
+	if err := f.CheckQuota("/home/b", 20, 1); err == nil {
+		t.Errorf("expected bytes over quota")
+	}
+	if err := f.CheckQuota("/home/b", 10, 1); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if err := f.CheckQuota("/other/c", 1000, 1); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+
+	// rename out of /home/chris/, then it takes one more file
+	f.updateQuotaUsage(file("chris/a", 60), file("a", 60))
+	f.updateQuotaUsage(nil, file("chris/c", 1))
+	if err := f.CheckQuota("/home/chris/d", 0, 1); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	f.updateQuotaUsage(nil, file("chris/d", 1))
+	if err := f.CheckQuota("/home/chris/e", 0, 1); err == nil {
+		t.Errorf("expected files over quota")
+	}
+
+	usages := f.ListQuotaUsage()
+	if len(usages) != 2 || usages[0].UsedBytes != 92 || usages[1].UsedFileCount != 2 {
+		t.Errorf("unexpected usages %+v", usages)
+	}
+
+	// the usage is kept in the store, for another filer on it
+	other := &Filer{
+		Store:     store,
+		FilerConf: f.FilerConf,
+		quotas:    filerQuotas{counted: make(map[string]bool)},
+	}
+	if err := other.CheckQuota("/home/chris/e", 0, 1); err == nil {
+		t.Errorf("expected files over quota on another filer")
+	}
+	other.updateQuotaUsage(file("chris/d", 1), nil)
+	if err := f.CheckQuota("/home/chris/e", 0, 1); err != nil {
+		t.Errorf("unexpected %v after a delete on another filer", err)
+	}
+}
@@ -0,0 +1,102 @@
+package filer
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+Most filer stores can not move a directory tree in one transaction, so a rename
+interrupted by a crash would leave the entries split between the old and the new
+directory. Each rename is recorded in the filer store before any entry is moved,
+and removed after the move completes or fails. The renames still recorded when the
+filer starts are moved again, so a rename interrupted by a crash is rolled forward.
+Each filer keeps its own journal, so a filer sharing the store with others only
+resumes its own renames.
+*/
+
+// the key prefix of the renames not completed yet, see LocalKvKey
+const RenameJournalKey = "filer.renames.pending"
+
+type RenameIntent struct {
+	OldPath   util.FullPath
+	NewPath   util.FullPath
+	StartedAt time.Time
+}
+
+type renameJournal struct {
+	sync.Mutex
+	intents map[util.FullPath]*RenameIntent // by the old path
+}
+
+// BeginRename records the rename before its entries are moved.
+func (f *Filer) BeginRename(ctx context.Context, oldPath, newPath util.FullPath) (*RenameIntent, error) {
+	intent := &RenameIntent{
+		OldPath:   oldPath,
+		NewPath:   newPath,
+		StartedAt: time.Now(),
+	}
+	f.renameJournal.Lock()
+	defer f.renameJournal.Unlock()
+	f.renameJournal.intents[oldPath] = intent
+	if err := f.saveRenameJournal(ctx); err != nil {
+		delete(f.renameJournal.intents, oldPath)
+		return nil, err
+	}
+	return intent, nil
+}
+
+// EndRename removes the rename once all its entries are moved, or the move failed.
+func (f *Filer) EndRename(ctx context.Context, intent *RenameIntent) error {
+	f.renameJournal.Lock()
+	defer f.renameJournal.Unlock()
+	if f.renameJournal.intents[intent.OldPath] != intent {
+		return nil
+	}
+	delete(f.renameJournal.intents, intent.OldPath)
+	return f.saveRenameJournal(ctx)
+}
+
+// PendingRenames loads the renames recorded and not completed before the filer stopped.
+func (f *Filer) PendingRenames(ctx context.Context) ([]*RenameIntent, error) {
+	value, err := f.Store.KvGet(ctx, f.LocalKvKey(RenameJournalKey))
+	if err == ErrKvNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var intents []*RenameIntent
+	if err = json.Unmarshal(value, &intents); err != nil {
+		return nil, err
+	}
+
+	f.renameJournal.Lock()
+	defer f.renameJournal.Unlock()
+	for _, intent := range intents {
+		if _, found := f.renameJournal.intents[intent.OldPath]; !found {
+			f.renameJournal.intents[intent.OldPath] = intent
+		}
+	}
+	return intents, nil
+}
+
+// saveRenameJournal is called with the journal locked.
+func (f *Filer) saveRenameJournal(ctx context.Context) error {
+	if len(f.renameJournal.intents) == 0 {
+		return f.Store.KvDelete(ctx, f.LocalKvKey(RenameJournalKey))
+	}
+	var intents []*RenameIntent
+	for _, intent := range f.renameJournal.intents {
+		intents = append(intents, intent)
+	}
+	value, err := json.Marshal(intents)
+	if err != nil {
+		return err
+	}
+	return f.Store.KvPut(ctx, f.LocalKvKey(RenameJournalKey), value)
+}
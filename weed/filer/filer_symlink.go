@@ -0,0 +1,57 @@
+package filer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+var ErrTooManySymlinks = errors.New("too many levels of symbolic links")
+
+// FindEntryFollowingSymlinks finds the entry, following at most maxDepth symlinks.
+// A dangling symlink returns filer_pb.ErrNotFound.
+func (f *Filer) FindEntryFollowingSymlinks(ctx context.Context, p util.FullPath, maxDepth int) (entry *Entry, err error) {
+	entry, err = f.FindEntry(ctx, p)
+	for depth := 0; err == nil && entry.IsSymlink(); depth++ {
+		if depth >= maxDepth {
+			return nil, ErrTooManySymlinks
+		}
+		entry, err = f.FindEntry(ctx, SymlinkTargetPath(entry.FullPath, entry.SymlinkTarget))
+	}
+	return
+}
+
+// SymlinkTargetPath resolves the symlink target, which is relative to the symlink's directory unless absolute.
+func SymlinkTargetPath(link util.FullPath, target string) util.FullPath {
+	if strings.HasPrefix(target, "/") {
+		return util.FullPath(path.Clean(target))
+	}
+	dir, _ := link.DirAndName()
+	return util.FullPath(path.Join("/", dir, target))
+}
+
+// CreateSymlink creates a symlink at p pointing to the target, which is not required to exist.
+func (f *Filer) CreateSymlink(ctx context.Context, p util.FullPath, target string, uid, gid uint32) error {
+	if target == "" {
+		return fmt.Errorf("symlink %s: empty target", p)
+	}
+	now := time.Now()
+	entry := &Entry{
+		FullPath: p,
+		Attr: Attr{
+			Mtime:         now,
+			Crtime:        now,
+			Mode:          os.ModeSymlink | 0777,
+			Uid:           uid,
+			Gid:           gid,
+			SymlinkTarget: target,
+		},
+	}
+	return f.CreateEntry(ctx, entry, true, false, nil)
+}
@@ -0,0 +1,26 @@
+package filer
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func TestSymlinkTargetPath(t *testing.T) {
+	tests := []struct {
+		link   util.FullPath
+		target string
+		want   util.FullPath
+	}{
+		{"/a/b/link", "file", "/a/b/file"},
+		{"/a/b/link", "../c/file", "/a/c/file"},
+		{"/a/b/link", "/x/y/", "/x/y"},
+		{"/link", "file", "/file"},
+		{"/a/link", "../../../file", "/file"},
+	}
+	for _, tt := range tests {
+		if got := SymlinkTargetPath(tt.link, tt.target); got != tt.want {
+			t.Errorf("SymlinkTargetPath(%s, %s) = %s, want %s", tt.link, tt.target, got, tt.want)
+		}
+	}
+}
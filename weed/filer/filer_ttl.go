@@ -0,0 +1,49 @@
+package filer
+
+import (
+	"context"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+)
+
+/*
+Under a location with a ttl in filer.conf, or when uploaded with a ttl, the chunks are
+written to ttl volumes, and the file entry gets the same ttl. The entry expires ttl after
+its last write, as the chunks do, and is deleted when it is next looked up or listed.
+*/
+
+// maybeSetTtl gives a file written without a ttl the ttl of its location, counted from now.
+func (f *Filer) maybeSetTtl(entry *Entry) {
+	if entry.IsDirectory() || entry.TtlSec > 0 {
+		return
+	}
+	rule := f.FilerConf.MatchStorageRule(string(entry.FullPath))
+	if rule.Ttl == "" {
+		return
+	}
+	ttl, err := needle.ReadTTL(rule.Ttl)
+	if err != nil || ttl.Minutes() == 0 {
+		glog.Warningf("invalid ttl %s for %s: %v", rule.Ttl, entry.FullPath, err)
+		return
+	}
+	entry.TtlSec = int32(ttl.Minutes()) * 60
+	entry.Crtime = time.Now()
+}
+
+func isTtlExpired(entry *Entry, now time.Time) bool {
+	return entry.TtlSec > 0 && entry.Crtime.Add(time.Duration(entry.TtlSec)*time.Second).Before(now)
+}
+
+// deleteTtlExpiredEntry removes the entry, the chunks on the ttl volumes expire by themselves.
+func (f *Filer) deleteTtlExpiredEntry(ctx context.Context, entry *Entry) {
+	if err := f.Store.DeleteEntry(ctx, entry.FullPath); err != nil {
+		glog.V(0).Infof("delete expired %s: %v", entry.FullPath, err)
+		return
+	}
+	stats.FilerAutoDeletedFilesCounter.Inc()
+	glog.V(3).Infof("deleted expired %s", entry.FullPath)
+	f.NotifyUpdateEvent(ctx, entry, nil, false, false, nil)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
@@ -42,13 +43,30 @@ type FilerStore interface {
 	Shutdown()
 }
 
+// KvConditionalDeleter is implemented by the stores that can delete a kv value only if it is unchanged,
+// so a filer can not delete a value another filer on the same store just updated. Without it, the
+// value is compared and deleted under a lock of this filer only, enough for a store opened by one filer.
+type KvConditionalDeleter interface {
+	// KvDeleteIfUnchanged returns false if the value is changed or gone.
+	KvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (deleted bool, err error)
+}
+
 type VirtualFilerStore interface {
 	FilerStore
 	DeleteHardLink(ctx context.Context, hardLinkId HardLinkId) error
+	// DeleteMovedEntry removes the entry left behind by a rename, keeping its hard link record
+	DeleteMovedEntry(ctx context.Context, fp util.FullPath) error
+	ListHardLinksToReap(ctx context.Context) ([]HardLinkId, error)
+	ReapHardLink(ctx context.Context, hardLinkId HardLinkId) (chunks []*filer_pb.FileChunk, err error)
 }
 
 type FilerStoreWrapper struct {
 	ActualStore FilerStore
+
+	// serializes the read-modify-write of hard link records in this process
+	hardLinkLock sync.Mutex
+	// only the filer reaps hard links, not a copy of its store like the mount meta cache
+	queueHardLinksToReap bool
 }
 
 func NewFilerStoreWrapper(store FilerStore) *FilerStoreWrapper {
@@ -145,6 +163,16 @@ func (fsw *FilerStoreWrapper) DeleteEntry(ctx context.Context, fp util.FullPath)
 	return fsw.ActualStore.DeleteEntry(ctx, fp)
 }
 
+func (fsw *FilerStoreWrapper) DeleteMovedEntry(ctx context.Context, fp util.FullPath) (err error) {
+	stats.FilerStoreCounter.WithLabelValues(fsw.ActualStore.GetName(), "delete").Inc()
+	start := time.Now()
+	defer func() {
+		stats.FilerStoreHistogram.WithLabelValues(fsw.ActualStore.GetName(), "delete").Observe(time.Since(start).Seconds())
+	}()
+
+	return fsw.ActualStore.DeleteEntry(ctx, fp)
+}
+
 func (fsw *FilerStoreWrapper) DeleteFolderChildren(ctx context.Context, fp util.FullPath) (err error) {
 	stats.FilerStoreCounter.WithLabelValues(fsw.ActualStore.GetName(), "deleteFolderChildren").Inc()
 	start := time.Now()
@@ -179,6 +207,10 @@ func (fsw *FilerStoreWrapper) ListDirectoryPrefixedEntries(ctx context.Context,
 	defer func() {
 		stats.FilerStoreHistogram.WithLabelValues(fsw.ActualStore.GetName(), "prefixList").Observe(time.Since(start).Seconds())
 	}()
+	if startFileName < prefix {
+		// the stores iterate from the start file name, and stop at the first entry not matching the prefix
+		startFileName, includeStartFile = prefix, true
+	}
 	entries, err := fsw.ActualStore.ListDirectoryPrefixedEntries(ctx, dirPath, startFileName, includeStartFile, limit, prefix)
 	if err == ErrUnsupportedListDirectoryPrefixed {
 		entries, err = fsw.prefixFilterEntries(ctx, dirPath, startFileName, includeStartFile, limit, prefix)
@@ -193,6 +225,8 @@ func (fsw *FilerStoreWrapper) ListDirectoryPrefixedEntries(ctx context.Context,
 	return entries, nil
 }
 
+// prefixFilterEntries lists the prefixed entries on stores without prefixed listing.
+// The entries are sorted by name, so the listing stops past the prefix.
 func (fsw *FilerStoreWrapper) prefixFilterEntries(ctx context.Context, dirPath util.FullPath, startFileName string, includeStartFile bool, limit int, prefix string) (entries []*Entry, err error) {
 	entries, err = fsw.ActualStore.ListDirectoryEntries(ctx, dirPath, startFileName, includeStartFile, limit)
 	if err != nil {
@@ -210,12 +244,16 @@ func (fsw *FilerStoreWrapper) prefixFilterEntries(ctx context.Context, dirPath u
 	for count < limit && len(notPrefixed) > 0 {
 		for _, entry := range notPrefixed {
 			lastFileName = entry.Name()
-			if strings.HasPrefix(entry.Name(), prefix) {
-				count++
-				entries = append(entries, entry)
-				if count >= limit {
-					break
+			if !strings.HasPrefix(entry.Name(), prefix) {
+				if entry.Name() > prefix {
+					return
 				}
+				continue
+			}
+			count++
+			entries = append(entries, entry)
+			if count >= limit {
+				break
 			}
 		}
 		if count < limit {
@@ -43,6 +43,9 @@ func (fsw *FilerStoreWrapper) setHardLink(ctx context.Context, entry *Entry) err
 		return encodeErr
 	}
 
+	fsw.hardLinkLock.Lock()
+	defer fsw.hardLinkLock.Unlock()
+
 	return fsw.KvPut(ctx, key, newBlob)
 }
 
@@ -66,7 +69,12 @@ func (fsw *FilerStoreWrapper) maybeReadHardLink(ctx context.Context, entry *Entr
 	return nil
 }
 
+// DeleteHardLink decrements the hard link counter. When it reaches zero, the record is kept
+// and queued for the reaper, which deletes the chunks unless a new link is created meanwhile.
 func (fsw *FilerStoreWrapper) DeleteHardLink(ctx context.Context, hardLinkId HardLinkId) error {
+	fsw.hardLinkLock.Lock()
+	defer fsw.hardLinkLock.Unlock()
+
 	key := hardLinkId
 	value, err := fsw.KvGet(ctx, key)
 	if err == ErrKvNotFound {
@@ -82,8 +90,8 @@ func (fsw *FilerStoreWrapper) DeleteHardLink(ctx context.Context, hardLinkId Har
 	}
 
 	entry.HardLinkCounter--
-	if entry.HardLinkCounter <= 0 {
-		return fsw.KvDelete(ctx, key)
+	if entry.HardLinkCounter < 0 {
+		entry.HardLinkCounter = 0
 	}
 
 	newBlob, encodeErr := entry.EncodeAttributesAndChunks()
@@ -91,6 +99,12 @@ func (fsw *FilerStoreWrapper) DeleteHardLink(ctx context.Context, hardLinkId Har
 		return encodeErr
 	}
 
-	return fsw.KvPut(ctx, key, newBlob)
+	if err = fsw.KvPut(ctx, key, newBlob); err != nil {
+		return err
+	}
 
+	if entry.HardLinkCounter == 0 && fsw.queueHardLinksToReap {
+		return fsw.queueHardLinkToReap(ctx, hardLinkId)
+	}
+	return nil
 }
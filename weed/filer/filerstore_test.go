@@ -0,0 +1,53 @@
+package filer
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+type listOnlyStore struct {
+	FilerStore
+	names     []string
+	listCount int
+}
+
+func (s *listOnlyStore) GetName() string { return "list" }
+func (s *listOnlyStore) ListDirectoryPrefixedEntries(ctx context.Context, dirPath util.FullPath, startFileName string, includeStartFile bool, limit int, prefix string) ([]*Entry, error) {
+	return nil, ErrUnsupportedListDirectoryPrefixed
+}
+func (s *listOnlyStore) ListDirectoryEntries(ctx context.Context, dirPath util.FullPath, startFileName string, includeStartFile bool, limit int) (entries []*Entry, err error) {
+	s.listCount++
+	i := sort.SearchStrings(s.names, startFileName)
+	if i < len(s.names) && s.names[i] == startFileName && !includeStartFile {
+		i++
+	}
+	for ; i < len(s.names) && len(entries) < limit; i++ {
+		entries = append(entries, &Entry{FullPath: dirPath.Child(s.names[i])})
+	}
+	return
+}
+
+func TestPrefixFilterEntries(t *testing.T) {
+	store := &listOnlyStore{names: []string{"a1", "a2", "b1", "b2", "b3", "c1", "c2", "c3", "c4"}}
+	fsw := NewFilerStoreWrapper(store)
+
+	entries, err := fsw.ListDirectoryPrefixedEntries(context.Background(), "/dir", "", false, 2, "b")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "b1" || entries[1].Name() != "b2" {
+		t.Fatalf("unexpected %v %v", entries, err)
+	}
+	if store.listCount != 1 {
+		t.Errorf("listed %d times, expected to start at the prefix", store.listCount)
+	}
+
+	store.listCount = 0
+	entries, err = fsw.ListDirectoryPrefixedEntries(context.Background(), "/dir", "b2", false, 10, "b")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "b3" {
+		t.Fatalf("unexpected %v %v", entries, err)
+	}
+	if store.listCount != 1 {
+		t.Errorf("listed %d times, expected to stop past the prefix", store.listCount)
+	}
+}
@@ -20,7 +20,10 @@ const (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &LevelDBStore{})
+	filer.RegisterMetadataBackend("leveldb", func(configuration weed_util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &LevelDBStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type LevelDBStore struct {
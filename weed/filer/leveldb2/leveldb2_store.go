@@ -19,7 +19,10 @@ import (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &LevelDB2Store{})
+	filer.RegisterMetadataBackend("leveldb2", func(configuration weed_util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &LevelDB2Store{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type LevelDB2Store struct {
@@ -11,11 +11,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/x/bsonx"
+	"regexp"
 	"time"
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &MongodbStore{})
+	filer.RegisterMetadataBackend("mongodb", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &MongodbStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type MongodbStore struct {
@@ -133,10 +137,12 @@ func (store *MongodbStore) FindEntry(ctx context.Context, fullpath util.FullPath
 
 	var where = bson.M{"directory": dir, "name": name}
 	err = store.connect.Database(store.database).Collection(store.collectionName).FindOne(ctx, where).Decode(&data)
-	if err != mongo.ErrNoDocuments && err != nil {
-		glog.Errorf("find %s: %v", fullpath, err)
+	if err == mongo.ErrNoDocuments {
 		return nil, filer_pb.ErrNotFound
 	}
+	if err != nil {
+		return nil, fmt.Errorf("find %s: %v", fullpath, err)
+	}
 
 	if len(data.Meta) == 0 {
 		return nil, filer_pb.ErrNotFound
@@ -169,7 +175,7 @@ func (store *MongodbStore) DeleteEntry(ctx context.Context, fullpath util.FullPa
 
 func (store *MongodbStore) DeleteFolderChildren(ctx context.Context, fullpath util.FullPath) error {
 
-	where := bson.M{"directory": fullpath}
+	where := bson.M{"directory": string(fullpath)}
 	_, err := store.connect.Database(store.database).Collection(store.collectionName).DeleteMany(ctx, where)
 	if err != nil {
 		return fmt.Errorf("delete %s : %v", fullpath, err)
@@ -178,45 +184,60 @@ func (store *MongodbStore) DeleteFolderChildren(ctx context.Context, fullpath ut
 	return nil
 }
 
-func (store *MongodbStore) ListDirectoryPrefixedEntries(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (entries []*filer.Entry, err error) {
-	return nil, filer.ErrUnsupportedListDirectoryPrefixed
-}
+// listBatchSize is the number of entries fetched from the cursor at a time, so large listings are streamed.
+const listBatchSize = 1024
 
-func (store *MongodbStore) ListDirectoryEntries(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int) (entries []*filer.Entry, err error) {
+func (store *MongodbStore) ListDirectoryPrefixedEntries(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (entries []*filer.Entry, err error) {
 
-	var where = bson.M{"directory": string(fullpath), "name": bson.M{"$gt": startFileName}}
+	nameCondition := bson.M{"$gt": startFileName}
 	if inclusive {
-		where["name"] = bson.M{
-			"$gte": startFileName,
-		}
+		nameCondition = bson.M{"$gte": startFileName}
+	}
+	if prefix != "" {
+		// an anchored regular expression is served by the (directory, name) index
+		nameCondition["$regex"] = "^" + regexp.QuoteMeta(prefix)
 	}
+	var where = bson.M{"directory": string(fullpath), "name": nameCondition}
+
 	optLimit := int64(limit)
-	opts := &options.FindOptions{Limit: &optLimit, Sort: bson.M{"name": 1}}
+	optBatchSize := int32(listBatchSize)
+	opts := &options.FindOptions{Limit: &optLimit, BatchSize: &optBatchSize, Sort: bson.M{"name": 1}}
 	cur, err := store.connect.Database(store.database).Collection(store.collectionName).Find(ctx, where, opts)
+	if err != nil {
+		return nil, fmt.Errorf("list %s : %v", fullpath, err)
+	}
+	defer func() {
+		if closeErr := cur.Close(ctx); closeErr != nil {
+			glog.V(0).Infof("list iterator close: %v", closeErr)
+		}
+	}()
+
 	for cur.Next(ctx) {
 		var data Model
-		err := cur.Decode(&data)
-		if err != nil && err != mongo.ErrNoDocuments {
-			return nil, err
+		if err = cur.Decode(&data); err != nil {
+			return nil, fmt.Errorf("list %s : %v", fullpath, err)
 		}
 
 		entry := &filer.Entry{
 			FullPath: util.NewFullPath(string(fullpath), data.Name),
 		}
-		if decodeErr := entry.DecodeAttributesAndChunks(util.MaybeDecompressData(data.Meta)); decodeErr != nil {
-			err = decodeErr
+		if err = entry.DecodeAttributesAndChunks(util.MaybeDecompressData(data.Meta)); err != nil {
 			glog.V(0).Infof("list %s : %v", entry.FullPath, err)
-			break
+			return nil, fmt.Errorf("decode %s : %v", entry.FullPath, err)
 		}
 
 		entries = append(entries, entry)
 	}
 
-	if err := cur.Close(ctx); err != nil {
-		glog.V(0).Infof("list iterator close: %v", err)
+	if err = cur.Err(); err != nil {
+		return nil, fmt.Errorf("list %s : %v", fullpath, err)
 	}
 
-	return entries, err
+	return entries, nil
+}
+
+func (store *MongodbStore) ListDirectoryEntries(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int) (entries []*filer.Entry, err error) {
+	return store.ListDirectoryPrefixedEntries(ctx, fullpath, startFileName, inclusive, limit, "")
 }
 
 func (store *MongodbStore) Shutdown() {
@@ -60,6 +60,19 @@ func (store *MongodbStore) KvDelete(ctx context.Context, key []byte) (err error)
 	return nil
 }
 
+func (store *MongodbStore) KvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (deleted bool, err error) {
+
+	dir, name := genDirAndName(key)
+
+	where := bson.M{"directory": dir, "name": name, "meta": value}
+	result, err := store.connect.Database(store.database).Collection(store.collectionName).DeleteOne(ctx, where)
+	if err != nil {
+		return false, fmt.Errorf("kv delete if unchanged: %v", err)
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
 func genDirAndName(key []byte) (dir string, name string) {
 	for len(key) < 8 {
 		key = append(key, 0)
@@ -15,7 +15,10 @@ const (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &MysqlStore{})
+	filer.RegisterMetadataBackend("mysql", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &MysqlStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type MysqlStore struct {
@@ -46,6 +49,7 @@ func (store *MysqlStore) initialize(user, password, hostname string, port int, d
 	store.SqlUpdate = "UPDATE filemeta SET meta=? WHERE dirhash=? AND name=? AND directory=?"
 	store.SqlFind = "SELECT meta FROM filemeta WHERE dirhash=? AND name=? AND directory=?"
 	store.SqlDelete = "DELETE FROM filemeta WHERE dirhash=? AND name=? AND directory=?"
+	store.SqlDeleteIfUnchanged = "DELETE FROM filemeta WHERE dirhash=? AND name=? AND directory=? AND meta=?"
 	store.SqlDeleteFolderChildren = "DELETE FROM filemeta WHERE dirhash=? AND directory=?"
 	store.SqlListExclusive = "SELECT NAME, meta FROM filemeta WHERE dirhash=? AND name>? AND directory=? AND name like ? ORDER BY NAME ASC LIMIT ?"
 	store.SqlListInclusive = "SELECT NAME, meta FROM filemeta WHERE dirhash=? AND name>=? AND directory=? AND name like ? ORDER BY NAME ASC LIMIT ?"
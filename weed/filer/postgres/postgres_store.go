@@ -15,7 +15,10 @@ const (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &PostgresStore{})
+	filer.RegisterMetadataBackend("postgres", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &PostgresStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type PostgresStore struct {
@@ -45,6 +48,7 @@ func (store *PostgresStore) initialize(user, password, hostname string, port int
 	store.SqlUpdate = "UPDATE filemeta SET meta=$1 WHERE dirhash=$2 AND name=$3 AND directory=$4"
 	store.SqlFind = "SELECT meta FROM filemeta WHERE dirhash=$1 AND name=$2 AND directory=$3"
 	store.SqlDelete = "DELETE FROM filemeta WHERE dirhash=$1 AND name=$2 AND directory=$3"
+	store.SqlDeleteIfUnchanged = "DELETE FROM filemeta WHERE dirhash=$1 AND name=$2 AND directory=$3 AND meta=$4"
 	store.SqlDeleteFolderChildren = "DELETE FROM filemeta WHERE dirhash=$1 AND directory=$2"
 	store.SqlListExclusive = "SELECT NAME, meta FROM filemeta WHERE dirhash=$1 AND name>$2 AND directory=$3 AND name like $4 ORDER BY NAME ASC LIMIT $5"
 	store.SqlListInclusive = "SELECT NAME, meta FROM filemeta WHERE dirhash=$1 AND name>=$2 AND directory=$3 AND name like $4 ORDER BY NAME ASC LIMIT $5"
@@ -62,9 +66,8 @@ func (store *PostgresStore) initialize(user, password, hostname string, port int
 	var dbErr error
 	store.DB, dbErr = sql.Open("postgres", sqlUrl)
 	if dbErr != nil {
-		store.DB.Close()
 		store.DB = nil
-		return fmt.Errorf("can not connect to %s error:%v", sqlUrl, err)
+		return fmt.Errorf("can not connect to %s error:%v", sqlUrl, dbErr)
 	}
 
 	store.DB.SetMaxIdleConns(maxIdle)
@@ -7,7 +7,10 @@ import (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &RedisClusterStore{})
+	filer.RegisterMetadataBackend("redis_cluster", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &RedisClusterStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type RedisClusterStore struct {
@@ -7,7 +7,10 @@ import (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &RedisStore{})
+	filer.RegisterMetadataBackend("redis", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &RedisStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type RedisStore struct {
@@ -40,3 +40,21 @@ func (store *UniversalRedisStore) KvDelete(ctx context.Context, key []byte) (err
 
 	return nil
 }
+
+// deletes the key only if it still has the value
+var deleteIfUnchangedScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func (store *UniversalRedisStore) KvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (deleted bool, err error) {
+
+	n, err := deleteIfUnchangedScript.Run(store.Client, []string{string(key)}, value).Int64()
+
+	if err != nil {
+		return false, fmt.Errorf("kv delete if unchanged: %v", err)
+	}
+
+	return n > 0, nil
+}
@@ -7,7 +7,10 @@ import (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &RedisCluster2Store{})
+	filer.RegisterMetadataBackend("redis_cluster2", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &RedisCluster2Store{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type RedisCluster2Store struct {
@@ -7,7 +7,10 @@ import (
 )
 
 func init() {
-	filer.Stores = append(filer.Stores, &Redis2Store{})
+	filer.RegisterMetadataBackend("redis2", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &Redis2Store{}
+		return store, store.Initialize(configuration, prefix)
+	})
 }
 
 type Redis2Store struct {
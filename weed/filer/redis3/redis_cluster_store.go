@@ -0,0 +1,53 @@
+package redis3
+
+import (
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/util"
+	"github.com/go-redis/redis"
+)
+
+func init() {
+	filer.RegisterMetadataBackend("redis_cluster3", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &RedisCluster3Store{}
+		return store, store.Initialize(configuration, prefix)
+	})
+}
+
+type RedisCluster3Store struct {
+	UniversalRedis3Store
+}
+
+func (store *RedisCluster3Store) GetName() string {
+	return "redis_cluster3"
+}
+
+func (store *RedisCluster3Store) Initialize(configuration util.Configuration, prefix string) (err error) {
+
+	configuration.SetDefault(prefix+"useReadOnly", true)
+	configuration.SetDefault(prefix+"routeByLatency", true)
+	configuration.SetDefault(prefix+"directory_shards", 8)
+
+	return store.initialize(
+		configuration.GetStringSlice(prefix+"addresses"),
+		configuration.GetString(prefix+"password"),
+		configuration.GetBool(prefix+"useReadOnly"),
+		configuration.GetBool(prefix+"routeByLatency"),
+		configuration.GetInt(prefix+"directory_shards"),
+	)
+}
+
+func (store *RedisCluster3Store) initialize(addresses []string, password string, readOnly, routeByLatency bool, directoryShards int) (err error) {
+	if directoryShards <= 0 {
+		return fmt.Errorf("directory_shards should be positive: %d", directoryShards)
+	}
+	store.DirectoryShards = directoryShards
+	store.Client = redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:          addresses,
+		Password:       password,
+		ReadOnly:       readOnly,
+		RouteByLatency: routeByLatency,
+	})
+	return
+}
@@ -0,0 +1,49 @@
+package redis3
+
+import (
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/util"
+	"github.com/go-redis/redis"
+)
+
+func init() {
+	filer.RegisterMetadataBackend("redis3", func(configuration util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &Redis3Store{}
+		return store, store.Initialize(configuration, prefix)
+	})
+}
+
+type Redis3Store struct {
+	UniversalRedis3Store
+}
+
+func (store *Redis3Store) GetName() string {
+	return "redis3"
+}
+
+func (store *Redis3Store) Initialize(configuration util.Configuration, prefix string) (err error) {
+
+	configuration.SetDefault(prefix+"directory_shards", 8)
+
+	return store.initialize(
+		configuration.GetString(prefix+"address"),
+		configuration.GetString(prefix+"password"),
+		configuration.GetInt(prefix+"database"),
+		configuration.GetInt(prefix+"directory_shards"),
+	)
+}
+
+func (store *Redis3Store) initialize(hostPort string, password string, database, directoryShards int) (err error) {
+	if directoryShards <= 0 {
+		return fmt.Errorf("directory_shards should be positive: %d", directoryShards)
+	}
+	store.DirectoryShards = directoryShards
+	store.Client = redis.NewClient(&redis.Options{
+		Addr:     hostPort,
+		Password: password,
+		DB:       database,
+	})
+	return
+}
@@ -0,0 +1,296 @@
+package redis3
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+const (
+	DIR_LIST_MARKER = "\x00"
+	// listBatchSize limits the names read from each shard at a time
+	listBatchSize = 1024
+)
+
+// UniversalRedis3Store keeps the children names of a directory in several sorted sets, the shards.
+// A child goes to the shard picked by the hash of its name, so a huge directory spreads over the
+// redis cluster. All members have score 0, and are listed in name order with ZRANGEBYLEX,
+// continuing after the last listed name, and merged across the shards.
+type UniversalRedis3Store struct {
+	Client redis.UniversalClient
+	// the number of sorted sets for the children of each directory, fixed once the data is stored
+	DirectoryShards int
+}
+
+func (store *UniversalRedis3Store) BeginTransaction(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+func (store *UniversalRedis3Store) CommitTransaction(ctx context.Context) error {
+	return nil
+}
+func (store *UniversalRedis3Store) RollbackTransaction(ctx context.Context) error {
+	return nil
+}
+
+func (store *UniversalRedis3Store) InsertEntry(ctx context.Context, entry *filer.Entry) (err error) {
+
+	value, err := entry.EncodeAttributesAndChunks()
+	if err != nil {
+		return fmt.Errorf("encoding %s %+v: %v", entry.FullPath, entry.Attr, err)
+	}
+
+	if len(entry.Chunks) > 50 {
+		value = util.MaybeGzipData(value)
+	}
+
+	if err = store.Client.Set(string(entry.FullPath), value, time.Duration(entry.TtlSec)*time.Second).Err(); err != nil {
+		return fmt.Errorf("persisting %s : %v", entry.FullPath, err)
+	}
+
+	dir, name := entry.FullPath.DirAndName()
+	if name != "" {
+		if err = store.Client.ZAddNX(store.shardKey(dir, name), redis.Z{Score: 0, Member: name}).Err(); err != nil {
+			return fmt.Errorf("persisting %s in parent dir: %v", entry.FullPath, err)
+		}
+	}
+
+	return nil
+}
+
+func (store *UniversalRedis3Store) UpdateEntry(ctx context.Context, entry *filer.Entry) (err error) {
+
+	return store.InsertEntry(ctx, entry)
+}
+
+func (store *UniversalRedis3Store) FindEntry(ctx context.Context, fullpath util.FullPath) (entry *filer.Entry, err error) {
+
+	data, err := store.Client.Get(string(fullpath)).Result()
+	if err == redis.Nil {
+		return nil, filer_pb.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get %s : %v", fullpath, err)
+	}
+
+	entry = &filer.Entry{
+		FullPath: fullpath,
+	}
+	err = entry.DecodeAttributesAndChunks(util.MaybeDecompressData([]byte(data)))
+	if err != nil {
+		return entry, fmt.Errorf("decode %s : %v", entry.FullPath, err)
+	}
+
+	return entry, nil
+}
+
+func (store *UniversalRedis3Store) DeleteEntry(ctx context.Context, fullpath util.FullPath) (err error) {
+
+	// the shards are in different cluster slots, so they are deleted one by one
+	pipe := store.Client.Pipeline()
+	for shard := 0; shard < store.DirectoryShards; shard++ {
+		pipe.Del(genDirectoryListKey(string(fullpath), shard))
+	}
+	pipe.Del(string(fullpath))
+	if _, err = pipe.Exec(); err != nil {
+		return fmt.Errorf("delete %s : %v", fullpath, err)
+	}
+
+	dir, name := fullpath.DirAndName()
+	if name != "" {
+		_, err = store.Client.ZRem(store.shardKey(dir, name), name).Result()
+		if err != nil {
+			return fmt.Errorf("DeleteEntry %s in parent dir: %v", fullpath, err)
+		}
+	}
+
+	return nil
+}
+
+func (store *UniversalRedis3Store) DeleteFolderChildren(ctx context.Context, fullpath util.FullPath) (err error) {
+
+	for shard := 0; shard < store.DirectoryShards; shard++ {
+		dirListKey := genDirectoryListKey(string(fullpath), shard)
+		for {
+			names, err := store.Client.ZRangeByLex(dirListKey, redis.ZRangeBy{Min: "-", Max: "+", Count: listBatchSize}).Result()
+			if err != nil {
+				return fmt.Errorf("DeleteFolderChildren %s : %v", fullpath, err)
+			}
+			if len(names) == 0 {
+				break
+			}
+			pipe := store.Client.Pipeline()
+			for _, name := range names {
+				pipe.Del(string(util.NewFullPath(string(fullpath), name)))
+			}
+			members := make([]interface{}, len(names))
+			for i, name := range names {
+				members[i] = name
+			}
+			pipe.ZRem(dirListKey, members...)
+			if _, err = pipe.Exec(); err != nil {
+				return fmt.Errorf("DeleteFolderChildren %s in parent dir: %v", fullpath, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (store *UniversalRedis3Store) ListDirectoryPrefixedEntries(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (entries []*filer.Entry, err error) {
+
+	min, max := lexRange(startFileName, inclusive, prefix)
+
+	for limit > 0 {
+		batchLimit := limit
+		if batchLimit > listBatchSize {
+			batchLimit = listBatchSize
+		}
+		names, listErr := store.listNames(string(fullpath), min, max, batchLimit)
+		if listErr != nil {
+			return nil, fmt.Errorf("list %s : %v", fullpath, listErr)
+		}
+		batch, fetchErr := store.fetchEntries(fullpath, names)
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		entries = append(entries, batch...)
+		if len(names) < batchLimit {
+			break
+		}
+		limit -= len(batch)
+		min = "(" + names[len(names)-1]
+	}
+
+	return entries, nil
+}
+
+func (store *UniversalRedis3Store) ListDirectoryEntries(ctx context.Context, fullpath util.FullPath, startFileName string, inclusive bool, limit int) (entries []*filer.Entry, err error) {
+	return store.ListDirectoryPrefixedEntries(ctx, fullpath, startFileName, inclusive, limit, "")
+}
+
+// listNames reads up to limit names in the lex range from every shard, and keeps the first limit names in order.
+func (store *UniversalRedis3Store) listNames(dir, min, max string, limit int) ([]string, error) {
+	pipe := store.Client.Pipeline()
+	var cmds []*redis.StringSliceCmd
+	for shard := 0; shard < store.DirectoryShards; shard++ {
+		cmds = append(cmds, pipe.ZRangeByLex(genDirectoryListKey(dir, shard), redis.ZRangeBy{Min: min, Max: max, Count: int64(limit)}))
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	var shardNames [][]string
+	for _, cmd := range cmds {
+		names, err := cmd.Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		shardNames = append(shardNames, names)
+	}
+	return mergeSortedNames(shardNames, limit), nil
+}
+
+// fetchEntries reads the entries of the names, skipping the ones deleted meanwhile, and deleting the expired ones.
+func (store *UniversalRedis3Store) fetchEntries(fullpath util.FullPath, names []string) (entries []*filer.Entry, err error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	pipe := store.Client.Pipeline()
+	var cmds []*redis.StringCmd
+	for _, name := range names {
+		cmds = append(cmds, pipe.Get(string(util.NewFullPath(string(fullpath), name))))
+	}
+	if _, err = pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("list %s : %v", fullpath, err)
+	}
+
+	for i, cmd := range cmds {
+		path := util.NewFullPath(string(fullpath), names[i])
+		data, getErr := cmd.Result()
+		if getErr == redis.Nil {
+			continue
+		}
+		if getErr != nil {
+			return nil, fmt.Errorf("list %s : %v", path, getErr)
+		}
+		entry := &filer.Entry{
+			FullPath: path,
+		}
+		if decodeErr := entry.DecodeAttributesAndChunks(util.MaybeDecompressData([]byte(data))); decodeErr != nil {
+			glog.V(0).Infof("list %s : %v", path, decodeErr)
+			continue
+		}
+		if entry.TtlSec > 0 {
+			if entry.Attr.Crtime.Add(time.Duration(entry.TtlSec) * time.Second).Before(time.Now()) {
+				store.Client.Del(string(path)).Result()
+				store.Client.ZRem(store.shardKey(string(fullpath), names[i]), names[i]).Result()
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (store *UniversalRedis3Store) shardKey(dir, name string) string {
+	return genDirectoryListKey(dir, int(crc32.ChecksumIEEE([]byte(name))%uint32(store.DirectoryShards)))
+}
+
+func genDirectoryListKey(dir string, shard int) (dirList string) {
+	return dir + DIR_LIST_MARKER + strconv.Itoa(shard)
+}
+
+// lexRange is the ZRANGEBYLEX range of the names after startFileName, and starting with the prefix.
+func lexRange(startFileName string, inclusive bool, prefix string) (min, max string) {
+	min, max = "-", "+"
+	if startFileName != "" {
+		if inclusive {
+			min = "[" + startFileName
+		} else {
+			min = "(" + startFileName
+		}
+	}
+	if prefix == "" {
+		return
+	}
+	if startFileName < prefix {
+		min = "[" + prefix
+	}
+	// the smallest string after all the names starting with the prefix
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return min, "(" + string(end[:i+1])
+		}
+	}
+	return
+}
+
+// mergeSortedNames merges the sorted names of the shards, and keeps the first limit names.
+func mergeSortedNames(shardNames [][]string, limit int) []string {
+	var names []string
+	for _, n := range shardNames {
+		names = append(names, n...)
+	}
+	sort.Strings(names)
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	return names
+}
+
+func (store *UniversalRedis3Store) Shutdown() {
+	store.Client.Close()
+}
@@ -0,0 +1,36 @@
+package redis3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexRange(t *testing.T) {
+	for _, tc := range []struct {
+		startFileName string
+		inclusive     bool
+		prefix        string
+		min, max      string
+	}{
+		{"", false, "", "-", "+"},
+		{"b", false, "", "(b", "+"},
+		{"b", true, "", "[b", "+"},
+		{"", false, "ab", "[ab", "(ac"},
+		{"abc", false, "ab", "(abc", "(ac"},
+		{"a", true, "ab", "[ab", "(ac"},
+		{"", false, "a\xff", "[a\xff", "(b"},
+		{"", false, "\xff", "[\xff", "+"},
+	} {
+		min, max := lexRange(tc.startFileName, tc.inclusive, tc.prefix)
+		if min != tc.min || max != tc.max {
+			t.Errorf("lexRange(%q, %v, %q) = %q %q, want %q %q", tc.startFileName, tc.inclusive, tc.prefix, min, max, tc.min, tc.max)
+		}
+	}
+}
+
+func TestMergeSortedNames(t *testing.T) {
+	names := mergeSortedNames([][]string{{"b", "e", "f"}, nil, {"a", "c", "d"}}, 4)
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("merged %v, want %v", names, want)
+	}
+}
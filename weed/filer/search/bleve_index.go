@@ -0,0 +1,118 @@
+package search
+
+import (
+	"os"
+	"strings"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/search/query"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+var (
+	lastProcessedTsNsKey = []byte("lastProcessedTsNs")
+)
+
+type document struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+type SearchHit struct {
+	Path  string  `json:"path"`
+	Score float64 `json:"score"`
+}
+
+type Index struct {
+	index bleve.Index
+}
+
+func NewIndex(dir string) (*Index, error) {
+
+	if _, err := os.Stat(dir); err == nil {
+		index, err := bleve.Open(dir)
+		if err != nil {
+			return nil, err
+		}
+		glog.V(0).Infof("open search index %s", dir)
+		return &Index{index: index}, nil
+	}
+
+	pathField := bleve.NewTextFieldMapping()
+	pathField.Analyzer = keyword.Name
+	contentField := bleve.NewTextFieldMapping()
+	documentMapping := bleve.NewDocumentMapping()
+	documentMapping.AddFieldMappingsAt("path", pathField)
+	documentMapping.AddFieldMappingsAt("content", contentField)
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = documentMapping
+
+	index, err := bleve.New(dir, indexMapping)
+	if err != nil {
+		return nil, err
+	}
+	glog.V(0).Infof("create search index %s", dir)
+	return &Index{index: index}, nil
+}
+
+func (idx *Index) IndexFile(fullpath util.FullPath, content string) error {
+	return idx.index.Index(string(fullpath), document{
+		Path:    string(fullpath),
+		Content: content,
+	})
+}
+
+func (idx *Index) DeleteFile(fullpath util.FullPath) error {
+	return idx.index.Delete(string(fullpath))
+}
+
+// Search finds files under dirPath matching the query string, ordered by relevance
+func (idx *Index) Search(queryString string, dirPath string, limit int) (hits []*SearchHit, err error) {
+
+	contentQuery := bleve.NewMatchQuery(queryString)
+	contentQuery.SetField("content")
+	var q query.Query = contentQuery
+	if dirPath != "" && dirPath != "/" {
+		pathQuery := bleve.NewPrefixQuery(strings.TrimSuffix(dirPath, "/") + "/")
+		pathQuery.SetField("path")
+		q = bleve.NewConjunctionQuery(contentQuery, pathQuery)
+	}
+
+	request := bleve.NewSearchRequestOptions(q, limit, 0, false)
+	result, err := idx.index.Search(request)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range result.Hits {
+		hits = append(hits, &SearchHit{
+			Path:  hit.ID,
+			Score: hit.Score,
+		})
+	}
+	return
+}
+
+// LastProcessedTsNs is the time of the last metadata event reflected in the index
+func (idx *Index) LastProcessedTsNs() int64 {
+	value, err := idx.index.GetInternal(lastProcessedTsNsKey)
+	if err != nil || len(value) != 8 {
+		return 0
+	}
+	return int64(util.BytesToUint64(value))
+}
+
+func (idx *Index) SetLastProcessedTsNs(tsNs int64) error {
+	value := make([]byte, 8)
+	util.Uint64toBytes(value, uint64(tsNs))
+	return idx.index.SetInternal(lastProcessedTsNsKey, value)
+}
+
+func (idx *Index) Close() {
+	if err := idx.index.Close(); err != nil {
+		glog.Errorf("close search index: %v", err)
+	}
+}
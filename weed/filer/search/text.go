@@ -0,0 +1,107 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+type textExtractor func(data []byte) (string, error)
+
+var extractorsByExt = map[string]textExtractor{
+	".txt":  extractPlainText,
+	".json": extractJsonText,
+	".html": extractHtmlText,
+	".htm":  extractHtmlText,
+}
+
+var extractorsByMime = map[string]textExtractor{
+	"text/plain":       extractPlainText,
+	"application/json": extractJsonText,
+	"text/html":        extractHtmlText,
+}
+
+func findExtractor(name, mime string) textExtractor {
+	if extractor, found := extractorsByExt[strings.ToLower(filepath.Ext(name))]; found {
+		return extractor
+	}
+	if semicolon := strings.Index(mime, ";"); semicolon > 0 {
+		mime = mime[:semicolon]
+	}
+	return extractorsByMime[strings.TrimSpace(strings.ToLower(mime))]
+}
+
+// IsIndexable checks whether the text of a file with this name or mime type can be extracted
+func IsIndexable(name, mime string) bool {
+	return findExtractor(name, mime) != nil
+}
+
+// ExtractText returns the searchable text of a .txt, .json, or .html file
+func ExtractText(name, mime string, data []byte) (string, error) {
+	extractor := findExtractor(name, mime)
+	if extractor == nil {
+		return "", nil
+	}
+	return extractor(data)
+}
+
+func extractPlainText(data []byte) (string, error) {
+	return string(data), nil
+}
+
+func extractJsonText(data []byte) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	collectJsonStrings(&sb, v)
+	return sb.String(), nil
+}
+
+func collectJsonStrings(sb *strings.Builder, v interface{}) {
+	switch t := v.(type) {
+	case string:
+		sb.WriteString(t)
+		sb.WriteString("\n")
+	case []interface{}:
+		for _, x := range t {
+			collectJsonStrings(sb, x)
+		}
+	case map[string]interface{}:
+		for k, x := range t {
+			sb.WriteString(k)
+			sb.WriteString("\n")
+			collectJsonStrings(sb, x)
+		}
+	}
+}
+
+func extractHtmlText(data []byte) (string, error) {
+	var sb strings.Builder
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	skipping := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if tokenizer.Err() == io.EOF {
+				return sb.String(), nil
+			}
+			return sb.String(), tokenizer.Err()
+		case html.StartTagToken:
+			tagName, _ := tokenizer.TagName()
+			skipping = string(tagName) == "script" || string(tagName) == "style"
+		case html.EndTagToken:
+			skipping = false
+		case html.TextToken:
+			if !skipping {
+				sb.Write(bytes.TrimSpace(tokenizer.Text()))
+				sb.WriteString("\n")
+			}
+		}
+	}
+}
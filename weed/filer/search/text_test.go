@@ -0,0 +1,29 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractText(t *testing.T) {
+
+	text, err := ExtractText("a.json", "", []byte(`{"title":"quarterly report","tags":["finance",3]}`))
+	if err != nil {
+		t.Fatalf("extract json: %v", err)
+	}
+	if !strings.Contains(text, "quarterly report") || !strings.Contains(text, "finance") {
+		t.Errorf("unexpected json text: %q", text)
+	}
+
+	text, err = ExtractText("index", "text/html; charset=utf-8", []byte(`<html><script>var hidden=1</script><body><p>Hello <b>world</b></p></body></html>`))
+	if err != nil {
+		t.Fatalf("extract html: %v", err)
+	}
+	if !strings.Contains(text, "Hello") || !strings.Contains(text, "world") || strings.Contains(text, "hidden") {
+		t.Errorf("unexpected html text: %q", text)
+	}
+
+	if IsIndexable("photo.jpg", "image/jpeg") {
+		t.Errorf("images should not be indexable")
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Package tikv is the filer store on TiKV, a distributed transactional key-value database.
+
+The store is only built with "go build -tags tikv", to keep the TiKV client out of the default binary.
+*/
+package tikv
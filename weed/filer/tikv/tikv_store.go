@@ -0,0 +1,298 @@
+// +build tikv
+
+package tikv
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tikv/client-go/config"
+	"github.com/tikv/client-go/txnkv"
+	"github.com/tikv/client-go/txnkv/kv"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	weed_util "github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// deleteBatchSize is the number of keys deleted in one transaction, when DeleteFolderChildren runs outside of a transaction
+const deleteBatchSize = 1024
+
+func init() {
+	filer.RegisterMetadataBackend("tikv", func(configuration weed_util.Configuration, prefix string) (filer.FilerStore, error) {
+		store := &TikvStore{}
+		return store, store.Initialize(configuration, prefix)
+	})
+}
+
+// TikvStore keeps each entry under the md5 of its directory followed by its name,
+// so the entries of a directory are one key range, listed in name order.
+// Transactions are TiKV transactions, so a rename is atomic.
+type TikvStore struct {
+	client *txnkv.Client
+}
+
+func (store *TikvStore) GetName() string {
+	return "tikv"
+}
+
+func (store *TikvStore) Initialize(configuration weed_util.Configuration, prefix string) (err error) {
+	pdAddrs := configuration.GetString(prefix + "pdaddrs")
+	if pdAddrs == "" {
+		pdAddrs = "localhost:2379"
+	}
+	return store.initialize(strings.Split(pdAddrs, ","))
+}
+
+func (store *TikvStore) initialize(pdAddrs []string) (err error) {
+	glog.Infof("filer store tikv pd address: %v", pdAddrs)
+
+	store.client, err = txnkv.NewClient(context.Background(), pdAddrs, config.Default())
+	if err != nil {
+		return fmt.Errorf("connect to tikv %v: %v", pdAddrs, err)
+	}
+	return nil
+}
+
+type txnKey struct{}
+
+func (store *TikvStore) BeginTransaction(ctx context.Context) (context.Context, error) {
+	txn, err := store.client.Begin(ctx)
+	if err != nil {
+		return ctx, fmt.Errorf("begin transaction: %v", err)
+	}
+	return context.WithValue(ctx, txnKey{}, txn), nil
+}
+
+func (store *TikvStore) CommitTransaction(ctx context.Context) error {
+	if txn, ok := ctx.Value(txnKey{}).(*txnkv.Transaction); ok {
+		return txn.Commit(ctx)
+	}
+	return nil
+}
+
+func (store *TikvStore) RollbackTransaction(ctx context.Context) error {
+	if txn, ok := ctx.Value(txnKey{}).(*txnkv.Transaction); ok {
+		return txn.Rollback()
+	}
+	return nil
+}
+
+// withTxn runs fn in the transaction of the context, or else in its own transaction.
+func (store *TikvStore) withTxn(ctx context.Context, fn func(txn *txnkv.Transaction) error) error {
+	if txn, ok := ctx.Value(txnKey{}).(*txnkv.Transaction); ok {
+		return fn(txn)
+	}
+	txn, err := store.client.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+	if err = fn(txn); err != nil {
+		txn.Rollback()
+		return err
+	}
+	return txn.Commit(ctx)
+}
+
+func (store *TikvStore) InsertEntry(ctx context.Context, entry *filer.Entry) (err error) {
+	dir, name := entry.DirAndName()
+	key := genKey(dir, name)
+
+	value, err := entry.EncodeAttributesAndChunks()
+	if err != nil {
+		return fmt.Errorf("encoding %s %+v: %v", entry.FullPath, entry.Attr, err)
+	}
+
+	if len(entry.Chunks) > 50 {
+		value = weed_util.MaybeGzipData(value)
+	}
+
+	err = store.withTxn(ctx, func(txn *txnkv.Transaction) error {
+		return txn.Set(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("persisting %s : %v", entry.FullPath, err)
+	}
+
+	return nil
+}
+
+func (store *TikvStore) UpdateEntry(ctx context.Context, entry *filer.Entry) (err error) {
+	return store.InsertEntry(ctx, entry)
+}
+
+func (store *TikvStore) FindEntry(ctx context.Context, fullpath weed_util.FullPath) (entry *filer.Entry, err error) {
+	dir, name := fullpath.DirAndName()
+	key := genKey(dir, name)
+
+	var data []byte
+	err = store.withTxn(ctx, func(txn *txnkv.Transaction) (getErr error) {
+		data, getErr = txn.Get(ctx, key)
+		return
+	})
+	if kv.IsErrNotFound(err) {
+		return nil, filer_pb.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get %s : %v", fullpath, err)
+	}
+
+	entry = &filer.Entry{
+		FullPath: fullpath,
+	}
+	err = entry.DecodeAttributesAndChunks(weed_util.MaybeDecompressData(data))
+	if err != nil {
+		return entry, fmt.Errorf("decode %s : %v", entry.FullPath, err)
+	}
+
+	return entry, nil
+}
+
+func (store *TikvStore) DeleteEntry(ctx context.Context, fullpath weed_util.FullPath) (err error) {
+	dir, name := fullpath.DirAndName()
+	key := genKey(dir, name)
+
+	err = store.withTxn(ctx, func(txn *txnkv.Transaction) error {
+		return txn.Delete(key)
+	})
+	if err != nil {
+		return fmt.Errorf("delete %s : %v", fullpath, err)
+	}
+
+	return nil
+}
+
+func (store *TikvStore) DeleteFolderChildren(ctx context.Context, fullpath weed_util.FullPath) (err error) {
+	directoryPrefix := genDirectoryKeyPrefix(fullpath, "")
+	upperBound := prefixUpperBound(directoryPrefix)
+
+	if txn, ok := ctx.Value(txnKey{}).(*txnkv.Transaction); ok {
+		_, err = deleteRange(ctx, txn, directoryPrefix, upperBound, -1)
+		if err != nil {
+			return fmt.Errorf("deleteFolderChildren %s : %v", fullpath, err)
+		}
+		return nil
+	}
+
+	// without a transaction, a huge directory is deleted in several transactions
+	for {
+		var deleted int
+		err = store.withTxn(ctx, func(txn *txnkv.Transaction) (deleteErr error) {
+			deleted, deleteErr = deleteRange(ctx, txn, directoryPrefix, upperBound, deleteBatchSize)
+			return
+		})
+		if err != nil {
+			return fmt.Errorf("deleteFolderChildren %s : %v", fullpath, err)
+		}
+		if deleted < deleteBatchSize {
+			return nil
+		}
+	}
+}
+
+// deleteRange deletes up to limit keys in [start, end), all of them if limit is negative.
+func deleteRange(ctx context.Context, txn *txnkv.Transaction, start, end []byte, limit int) (deleted int, err error) {
+	iter, err := txn.Iter(ctx, start, end)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	var keys [][]byte
+	for ; iter.Valid() && (limit < 0 || len(keys) < limit); err = iter.Next(ctx) {
+		keys = append(keys, append([]byte(nil), iter.Key()...))
+	}
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		if err = txn.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+func (store *TikvStore) ListDirectoryPrefixedEntries(ctx context.Context, fullpath weed_util.FullPath, startFileName string, inclusive bool, limit int, prefix string) (entries []*filer.Entry, err error) {
+	directoryPrefix := genDirectoryKeyPrefix(fullpath, "")
+	upperBound := prefixUpperBound(genDirectoryKeyPrefix(fullpath, prefix))
+
+	startKey := genDirectoryKeyPrefix(fullpath, startFileName)
+	if startFileName < prefix {
+		startKey, inclusive = genDirectoryKeyPrefix(fullpath, prefix), true
+	}
+
+	err = store.withTxn(ctx, func(txn *txnkv.Transaction) error {
+		iter, iterErr := txn.Iter(ctx, startKey, upperBound)
+		if iterErr != nil {
+			return iterErr
+		}
+		defer iter.Close()
+
+		for ; iter.Valid() && len(entries) < limit; iterErr = iter.Next(ctx) {
+			key := iter.Key()
+			if !inclusive && bytes.Equal(key, startKey) {
+				continue
+			}
+			entry := &filer.Entry{
+				FullPath: weed_util.NewFullPath(string(fullpath), string(key[len(directoryPrefix):])),
+			}
+			if decodeErr := entry.DecodeAttributesAndChunks(weed_util.MaybeDecompressData(iter.Value())); decodeErr != nil {
+				glog.V(0).Infof("list %s : %v", entry.FullPath, decodeErr)
+				return fmt.Errorf("decode %s : %v", entry.FullPath, decodeErr)
+			}
+			entries = append(entries, entry)
+		}
+		return iterErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list %s : %v", fullpath, err)
+	}
+
+	return entries, nil
+}
+
+func (store *TikvStore) ListDirectoryEntries(ctx context.Context, fullpath weed_util.FullPath, startFileName string, inclusive bool, limit int) (entries []*filer.Entry, err error) {
+	return store.ListDirectoryPrefixedEntries(ctx, fullpath, startFileName, inclusive, limit, "")
+}
+
+func genKey(dirPath, fileName string) (key []byte) {
+	key = hashToBytes(dirPath)
+	key = append(key, []byte(fileName)...)
+	return key
+}
+
+func genDirectoryKeyPrefix(fullpath weed_util.FullPath, startFileName string) (keyPrefix []byte) {
+	keyPrefix = hashToBytes(string(fullpath))
+	if len(startFileName) > 0 {
+		keyPrefix = append(keyPrefix, []byte(startFileName)...)
+	}
+	return keyPrefix
+}
+
+func hashToBytes(dir string) []byte {
+	h := md5.New()
+	io.WriteString(h, dir)
+	return h.Sum(nil)
+}
+
+// prefixUpperBound is the smallest key after all the keys with the prefix, or nil for no bound.
+func prefixUpperBound(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func (store *TikvStore) Shutdown() {
+	store.client.Close()
+}
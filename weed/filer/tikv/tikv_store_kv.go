@@ -0,0 +1,83 @@
+// +build tikv
+
+package tikv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/tikv/client-go/txnkv"
+	"github.com/tikv/client-go/txnkv/kv"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+)
+
+func (store *TikvStore) KvPut(ctx context.Context, key []byte, value []byte) (err error) {
+
+	err = store.withTxn(ctx, func(txn *txnkv.Transaction) error {
+		return txn.Set(key, value)
+	})
+
+	if err != nil {
+		return fmt.Errorf("kv put: %v", err)
+	}
+
+	return nil
+}
+
+func (store *TikvStore) KvGet(ctx context.Context, key []byte) (value []byte, err error) {
+
+	err = store.withTxn(ctx, func(txn *txnkv.Transaction) (getErr error) {
+		value, getErr = txn.Get(ctx, key)
+		return
+	})
+
+	if kv.IsErrNotFound(err) {
+		return nil, filer.ErrKvNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("kv get: %v", err)
+	}
+
+	return value, nil
+}
+
+func (store *TikvStore) KvDelete(ctx context.Context, key []byte) (err error) {
+
+	err = store.withTxn(ctx, func(txn *txnkv.Transaction) error {
+		return txn.Delete(key)
+	})
+
+	if err != nil {
+		return fmt.Errorf("kv delete: %v", err)
+	}
+
+	return nil
+}
+
+// the transaction fails to commit if the value is changed meanwhile
+func (store *TikvStore) KvDeleteIfUnchanged(ctx context.Context, key []byte, value []byte) (deleted bool, err error) {
+
+	err = store.withTxn(ctx, func(txn *txnkv.Transaction) error {
+		current, getErr := txn.Get(ctx, key)
+		if kv.IsErrNotFound(getErr) {
+			return nil
+		}
+		if getErr != nil {
+			return getErr
+		}
+		if !bytes.Equal(current, value) {
+			return nil
+		}
+		deleted = true
+		return txn.Delete(key)
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("kv delete if unchanged: %v", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,61 @@
+// +build tikv
+
+package tikv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func TestRegistered(t *testing.T) {
+	for _, name := range filer.MetadataBackendNames() {
+		if name == "tikv" {
+			return
+		}
+	}
+	t.Errorf("tikv is not in the registered filer stores %v", filer.MetadataBackendNames())
+}
+
+func TestDirectoryKeyRange(t *testing.T) {
+	dir := util.FullPath("/home/chris")
+	start := genDirectoryKeyPrefix(dir, "")
+	end := prefixUpperBound(start)
+
+	for _, name := range []string{"a", "file1.jpg", "\xff\xff"} {
+		key := genKey(string(dir), name)
+		if bytes.Compare(key, start) < 0 || bytes.Compare(key, end) >= 0 {
+			t.Errorf("key of %s/%s is outside of the directory range", dir, name)
+		}
+	}
+	for _, other := range []string{"/home", "/home/chris/a", "/home/chrisx"} {
+		key := genKey(other, "a")
+		if bytes.Compare(key, start) >= 0 && bytes.Compare(key, end) < 0 {
+			t.Errorf("key of %s/a is inside of the %s range", other, dir)
+		}
+	}
+
+	prefixed := prefixUpperBound(genDirectoryKeyPrefix(dir, "fi"))
+	if bytes.Compare(genKey(string(dir), "file1.jpg"), prefixed) >= 0 {
+		t.Errorf("file1.jpg is after the upper bound of prefix fi")
+	}
+	if bytes.Compare(genKey(string(dir), "fj"), prefixed) < 0 {
+		t.Errorf("fj is before the upper bound of prefix fi")
+	}
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	for _, tt := range []struct {
+		prefix, want []byte
+	}{
+		{[]byte("ab"), []byte("ac")},
+		{[]byte{'a', 0xff}, []byte("b")},
+		{[]byte{0xff, 0xff}, nil},
+	} {
+		if got := prefixUpperBound(tt.prefix); !bytes.Equal(got, tt.want) {
+			t.Errorf("prefixUpperBound(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
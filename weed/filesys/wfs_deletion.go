@@ -70,6 +70,7 @@ func (wfs *WFS) deleteFileIds(grpcDialOption grpc.DialOption, client filer_pb.Se
 				lr.Locations = append(lr.Locations, operation.Location{
 					Url:       wfs.AdjustedUrl(loc),
 					PublicUrl: loc.PublicUrl,
+					GrpcPort:  int(loc.GrpcPort),
 				})
 			}
 			m[vid] = lr
@@ -703,6 +703,7 @@ func (l *loggingT) output(s severity, buf *buffer, file string, line int, alsoTo
 			l.file[infoLog].Write(data)
 		}
 	}
+	logs.publish(s, data)
 	if s == fatalLog {
 		// If we got here via Exit rather than Fatal, print no stacks.
 		if atomic.LoadUint32(&fatalNoStacks) > 0 {
@@ -0,0 +1,81 @@
+package glog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LogLine is one log entry, including its header.
+// Level is the severity: 0 INFO, 1 WARNING, 2 ERROR, 3 FATAL.
+type LogLine struct {
+	Level int
+	Text  string
+}
+
+// the number of recent log lines kept in memory for log streaming
+const recentLogLineCount = 1000
+
+// subscribers are sent the lines without blocking, and miss lines if they fall behind
+type logStream struct {
+	sync.Mutex
+	recent      []LogLine
+	next        int
+	subscribers map[chan LogLine]struct{}
+}
+
+var logs = &logStream{
+	subscribers: make(map[chan LogLine]struct{}),
+}
+
+func (s *logStream) publish(sev severity, data []byte) {
+	line := LogLine{Level: int(sev), Text: strings.TrimSuffix(string(data), "\n")}
+
+	s.Lock()
+	defer s.Unlock()
+	if len(s.recent) < recentLogLineCount {
+		s.recent = append(s.recent, line)
+	} else {
+		s.recent[s.next] = line
+		s.next = (s.next + 1) % recentLogLineCount
+	}
+	for ch := range s.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// SubscribeLogs returns up to tail most recent log lines, and a channel of the lines logged after them.
+// Call cancel to stop the subscription.
+func SubscribeLogs(tail int, follow bool) (recent []LogLine, lines <-chan LogLine, cancel func()) {
+	logs.Lock()
+	defer logs.Unlock()
+
+	ordered := append(append([]LogLine{}, logs.recent[logs.next:]...), logs.recent[:logs.next]...)
+	if tail >= 0 && tail < len(ordered) {
+		ordered = ordered[len(ordered)-tail:]
+	}
+	if !follow {
+		return ordered, nil, func() {}
+	}
+
+	ch := make(chan LogLine, 1024)
+	logs.subscribers[ch] = struct{}{}
+	return ordered, ch, func() {
+		logs.Lock()
+		delete(logs.subscribers, ch)
+		logs.Unlock()
+	}
+}
+
+// ParseLevel parses the severity name, e.g. "ERROR", case insensitive.
+func ParseLevel(name string) (int, error) {
+	for i, severityName := range severityName {
+		if strings.EqualFold(name, severityName) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, expect INFO, WARNING, ERROR, or FATAL", name)
+}
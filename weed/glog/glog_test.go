@@ -413,3 +413,25 @@ func BenchmarkHeader(b *testing.B) {
 		logging.putBuffer(buf)
 	}
 }
+
+func TestSubscribeLogs(t *testing.T) {
+	setFlags()
+	defer logging.swap(logging.newBuffers())
+
+	Info("stream-test before")
+	recent, lines, cancel := SubscribeLogs(1, true)
+	defer cancel()
+	if len(recent) != 1 || !strings.HasSuffix(recent[0].Text, "stream-test before") {
+		t.Errorf("recent logs: %+v", recent)
+	}
+
+	Warning("stream-test after")
+	line := <-lines
+	if line.Level != int(warningLog) || !strings.HasSuffix(line.Text, "stream-test after") {
+		t.Errorf("followed log: %+v", line)
+	}
+
+	if level, err := ParseLevel("error"); err != nil || level != int(errorLog) {
+		t.Errorf("parse level error: %d %v", level, err)
+	}
+}
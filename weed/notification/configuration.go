@@ -1,6 +1,9 @@
 package notification
 
 import (
+	"fmt"
+	"reflect"
+
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/golang/protobuf/proto"
@@ -43,6 +46,22 @@ func LoadConfiguration(config *viper.Viper, prefix string) {
 
 }
 
+// NewMessageQueue creates a message queue of the enabled <prefix><queue name> section, separate from
+// the process wide Queue for the filer events. It returns nil if no queue is enabled.
+func NewMessageQueue(config *viper.Viper, prefix string) (MessageQueue, error) {
+	for _, queue := range MessageQueues {
+		if !config.GetBool(prefix + queue.GetName() + ".enabled") {
+			continue
+		}
+		q := reflect.New(reflect.TypeOf(queue).Elem()).Interface().(MessageQueue)
+		if err := q.Initialize(config, prefix+queue.GetName()+"."); err != nil {
+			return nil, fmt.Errorf("initialize %s: %v", queue.GetName(), err)
+		}
+		return q, nil
+	}
+	return nil, nil
+}
+
 func validateOneEnabledQueue(config *viper.Viper) {
 	enabledQueue := ""
 	for _, queue := range MessageQueues {
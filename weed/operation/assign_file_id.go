@@ -8,8 +8,10 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"google.golang.org/grpc"
 
+	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -22,6 +24,8 @@ type VolumeAssignRequest struct {
 	Rack                string
 	DataNode            string
 	WritableVolumeCount uint32
+	DiskType            string // e.g. ssd, empty for the collection default
+	Traceparent         string // passes the caller's trace to the master
 }
 
 type AssignResult struct {
@@ -58,8 +62,9 @@ func Assign(server string, grpcDialOption grpc.DialOption, primaryRequest *Volum
 				Rack:                request.Rack,
 				DataNode:            request.DataNode,
 				WritableVolumeCount: request.WritableVolumeCount,
+				DiskType:            request.DiskType,
 			}
-			resp, grpcErr := masterClient.Assign(context.Background(), req)
+			resp, grpcErr := masterClient.Assign(tracing.OutgoingGrpcContext(context.Background(), request.Traceparent), req)
 			if grpcErr != nil {
 				return grpcErr
 			}
@@ -76,6 +81,10 @@ func Assign(server string, grpcDialOption grpc.DialOption, primaryRequest *Volum
 		})
 
 		if lastError != nil {
+			if IsMasterError(lastError, master_pb.ErrorDetail_INVALID_ARGUMENT) {
+				// alternative requests share the same replication and ttl
+				break
+			}
 			continue
 		}
 
@@ -93,7 +102,7 @@ func LookupJwt(master string, fileId string) security.EncodedJwt {
 
 	tokenStr := ""
 
-	if h, e := util.Head(fmt.Sprintf("http://%s/dir/lookup?fileId=%s", master, fileId)); e == nil {
+	if h, e := util.Head(fmt.Sprintf("http://%s/dir/lookup?fileId=%s", pb.ServerToHttpAddress(master), fileId)); e == nil {
 		bearer := h.Get("Authorization")
 		if len(bearer) > 7 && strings.ToUpper(bearer[0:6]) == "BEARER" {
 			tokenStr = bearer[7:]
@@ -0,0 +1,68 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+)
+
+// ConfirmFilesWithLookupVolumeId confirms the files written as pending on all their replicas,
+// so the volume servers keep them after the pending ttl.
+func ConfirmFilesWithLookupVolumeId(grpcDialOption grpc.DialOption, fileIds []string, lookupFunc func(vid []string) (map[string]LookupResult, error)) error {
+
+	vid_to_fileIds := make(map[string][]string)
+	var vids []string
+	for _, fileId := range fileIds {
+		vid, _, err := ParseFileId(fileId)
+		if err != nil {
+			return fmt.Errorf("parse %s: %v", fileId, err)
+		}
+		if _, ok := vid_to_fileIds[vid]; !ok {
+			vids = append(vids, vid)
+		}
+		vid_to_fileIds[vid] = append(vid_to_fileIds[vid], fileId)
+	}
+
+	lookupResults, err := lookupFunc(vids)
+	if err != nil {
+		return err
+	}
+
+	server_to_fileIds := make(map[string][]string)
+	for _, vid := range vids {
+		result, found := lookupResults[vid]
+		if !found || result.Error != "" || len(result.Locations) == 0 {
+			return fmt.Errorf("lookup volume %s: %s", vid, result.Error)
+		}
+		for _, location := range result.Locations {
+			server_to_fileIds[location.ServerAddress()] = append(server_to_fileIds[location.ServerAddress()], vid_to_fileIds[vid]...)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	for server, fidList := range server_to_fileIds {
+		wg.Add(1)
+		go func(server string, fidList []string) {
+			defer wg.Done()
+			confirmErr := WithVolumeServerClient(server, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+				_, err := client.ConfirmNeedles(context.Background(), &volume_server_pb.ConfirmNeedlesRequest{
+					FileIds: fidList,
+				})
+				return err
+			})
+			if confirmErr != nil {
+				errLock.Lock()
+				err = fmt.Errorf("confirm on %s: %v", server, confirmErr)
+				errLock.Unlock()
+			}
+		}(server, fidList)
+	}
+	wg.Wait()
+
+	return err
+}
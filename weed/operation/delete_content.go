@@ -86,11 +86,12 @@ func DeleteFilesWithLookupVolumeId(grpcDialOption grpc.DialOption, fileIds []str
 			continue
 		}
 		for _, location := range result.Locations {
-			if _, ok := server_to_fileIds[location.Url]; !ok {
-				server_to_fileIds[location.Url] = make([]string, 0)
+			serverAddress := location.ServerAddress()
+			if _, ok := server_to_fileIds[serverAddress]; !ok {
+				server_to_fileIds[serverAddress] = make([]string, 0)
 			}
-			server_to_fileIds[location.Url] = append(
-				server_to_fileIds[location.Url], vid_to_fileIds[vid]...)
+			server_to_fileIds[serverAddress] = append(
+				server_to_fileIds[serverAddress], vid_to_fileIds[vid]...)
 		}
 	}
 
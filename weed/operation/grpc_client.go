@@ -2,12 +2,9 @@ package operation
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
 
 	"google.golang.org/grpc"
 
-	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
@@ -16,7 +13,7 @@ import (
 
 func WithVolumeServerClient(volumeServer string, grpcDialOption grpc.DialOption, fn func(volume_server_pb.VolumeServerClient) error) error {
 
-	grpcAddress, err := toVolumeServerGrpcAddress(volumeServer)
+	grpcAddress, err := pb.ParseServerToGrpcAddress(volumeServer)
 	if err != nil {
 		return fmt.Errorf("failed to parse volume server %v: %v", volumeServer, err)
 	}
@@ -28,16 +25,6 @@ func WithVolumeServerClient(volumeServer string, grpcDialOption grpc.DialOption,
 
 }
 
-func toVolumeServerGrpcAddress(volumeServer string) (grpcAddress string, err error) {
-	sepIndex := strings.LastIndex(volumeServer, ":")
-	port, err := strconv.Atoi(volumeServer[sepIndex+1:])
-	if err != nil {
-		glog.Errorf("failed to parse volume server address: %v", volumeServer)
-		return "", err
-	}
-	return fmt.Sprintf("%s:%d", volumeServer[0:sepIndex], port+10000), nil
-}
-
 func WithMasterServerClient(masterServer string, grpcDialOption grpc.DialOption, fn func(masterClient master_pb.SeaweedClient) error) error {
 
 	masterGrpcAddress, parseErr := pb.ParseServerToGrpcAddress(masterServer)
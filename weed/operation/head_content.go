@@ -0,0 +1,74 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+)
+
+// HeadFilesWithLookupVolumeId checks that the files are stored, by looking them up in the needle index
+// of one replica each. The file content is not read.
+func HeadFilesWithLookupVolumeId(grpcDialOption grpc.DialOption, fileIds []string, lookupFunc func(vid []string) (map[string]LookupResult, error)) error {
+
+	vid_to_fileIds := make(map[string][]*needle.FileId)
+	var vids []string
+	for _, fileId := range fileIds {
+		fid, err := needle.ParseFileIdFromString(fileId)
+		if err != nil {
+			return fmt.Errorf("parse %s: %v", fileId, err)
+		}
+		vid := fid.VolumeId.String()
+		if _, ok := vid_to_fileIds[vid]; !ok {
+			vids = append(vids, vid)
+		}
+		vid_to_fileIds[vid] = append(vid_to_fileIds[vid], fid)
+	}
+
+	lookupResults, err := lookupFunc(vids)
+	if err != nil {
+		return err
+	}
+
+	server_to_fileIds := make(map[string][]*needle.FileId)
+	for _, vid := range vids {
+		result, found := lookupResults[vid]
+		if !found || result.Error != "" || len(result.Locations) == 0 {
+			return fmt.Errorf("lookup volume %s: %s", vid, result.Error)
+		}
+		server := result.Locations[0].ServerAddress()
+		server_to_fileIds[server] = append(server_to_fileIds[server], vid_to_fileIds[vid]...)
+	}
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	for server, fidList := range server_to_fileIds {
+		wg.Add(1)
+		go func(server string, fidList []*needle.FileId) {
+			defer wg.Done()
+			headErr := WithVolumeServerClient(server, grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+				for _, fid := range fidList {
+					if _, err := client.VolumeNeedleHead(context.Background(), &volume_server_pb.VolumeNeedleHeadRequest{
+						VolumeId: uint32(fid.VolumeId),
+						NeedleId: uint64(fid.Key),
+					}); err != nil {
+						return fmt.Errorf("%s: %v", fid, err)
+					}
+				}
+				return nil
+			})
+			if headErr != nil {
+				errLock.Lock()
+				err = fmt.Errorf("head on %s: %v", server, headErr)
+				errLock.Unlock()
+			}
+		}(server, fidList)
+	}
+	wg.Wait()
+
+	return err
+}
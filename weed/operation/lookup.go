@@ -11,14 +11,24 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
 type Location struct {
-	Url       string `json:"url,omitempty"`
-	PublicUrl string `json:"publicUrl,omitempty"`
+	Url        string `json:"url,omitempty"`
+	PublicUrl  string `json:"publicUrl,omitempty"`
+	GrpcPort   int    `json:"grpcPort,omitempty"`
+	DataCenter string `json:"dataCenter,omitempty"`
+	Rack       string `json:"rack,omitempty"`
 }
+
+// ServerAddress is the address to reach the volume server with grpc
+func (l Location) ServerAddress() string {
+	return pb.NewServerAddressWithGrpcPort(l.Url, l.GrpcPort)
+}
+
 type LookupResult struct {
 	VolumeId  string     `json:"volumeId,omitempty"`
 	Locations []Location `json:"locations,omitempty"`
@@ -48,7 +58,7 @@ func Lookup(server string, vid string) (ret *LookupResult, err error) {
 func do_lookup(server string, vid string) (*LookupResult, error) {
 	values := make(url.Values)
 	values.Add("volumeId", vid)
-	jsonBlob, err := util.Post("http://"+server+"/dir/lookup", values)
+	jsonBlob, err := util.Post("http://"+pb.ServerToHttpAddress(server)+"/dir/lookup", values)
 	if err != nil {
 		return nil, err
 	}
@@ -114,8 +124,11 @@ func LookupVolumeIds(server string, grpcDialOption grpc.DialOption, vids []strin
 			var locations []Location
 			for _, loc := range vidLocations.Locations {
 				locations = append(locations, Location{
-					Url:       loc.Url,
-					PublicUrl: loc.PublicUrl,
+					Url:        loc.Url,
+					PublicUrl:  loc.PublicUrl,
+					GrpcPort:   int(loc.GrpcPort),
+					DataCenter: loc.DataCenter,
+					Rack:       loc.Rack,
 				})
 			}
 			if vidLocations.Error != "" {
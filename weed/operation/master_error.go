@@ -0,0 +1,29 @@
+package operation
+
+import (
+	"google.golang.org/grpc/status"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+// MasterErrorCode returns the error code the master attached to a gRPC error,
+// or ErrorDetail_UNKNOWN if there is none.
+func MasterErrorCode(err error) master_pb.ErrorDetail_ErrorCode {
+	if err == nil {
+		return master_pb.ErrorDetail_UNKNOWN
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return master_pb.ErrorDetail_UNKNOWN
+	}
+	for _, detail := range st.Details() {
+		if errorDetail, ok := detail.(*master_pb.ErrorDetail); ok {
+			return errorDetail.Code
+		}
+	}
+	return master_pb.ErrorDetail_UNKNOWN
+}
+
+func IsMasterError(err error, code master_pb.ErrorDetail_ErrorCode) bool {
+	return err != nil && MasterErrorCode(err) == code
+}
@@ -158,7 +158,7 @@ func doUploadData(uploadUrl string, filename string, cipher bool, data []byte, i
 		uploadResult, err = upload_content(uploadUrl, func(w io.Writer) (err error) {
 			_, err = w.Write(encryptedData)
 			return
-		}, "", false, len(encryptedData), "", nil, jwt)
+		}, "", "", len(encryptedData), "", nil, jwt)
 		if uploadResult != nil {
 			uploadResult.Name = filename
 			uploadResult.Mime = mtype
@@ -169,7 +169,7 @@ func doUploadData(uploadUrl string, filename string, cipher bool, data []byte, i
 		uploadResult, err = upload_content(uploadUrl, func(w io.Writer) (err error) {
 			_, err = w.Write(data)
 			return
-		}, filename, contentIsGzipped, len(data), mtype, pairMap, jwt)
+		}, filename, contentEncoding(data, contentIsGzipped), len(data), mtype, pairMap, jwt)
 	}
 
 	if uploadResult == nil {
@@ -184,7 +184,18 @@ func doUploadData(uploadUrl string, filename string, cipher bool, data []byte, i
 	return uploadResult, err
 }
 
-func upload_content(uploadUrl string, fillBufferFunction func(w io.Writer) error, filename string, isGzipped bool, originalDataSize int, mtype string, pairMap map[string]string, jwt security.EncodedJwt) (*UploadResult, error) {
+// contentEncoding tells the codec of the compressed data, by its magic bytes.
+func contentEncoding(data []byte, isCompressed bool) string {
+	if !isCompressed {
+		return ""
+	}
+	if util.IsZstdContent(data) {
+		return util.ZstdCompression
+	}
+	return util.GzipCompression
+}
+
+func upload_content(uploadUrl string, fillBufferFunction func(w io.Writer) error, filename string, contentEncoding string, originalDataSize int, mtype string, pairMap map[string]string, jwt security.EncodedJwt) (*UploadResult, error) {
 	buf := bytebufferpool.Get()
 	defer bytebufferpool.Put(buf)
 	body_writer := multipart.NewWriter(buf)
@@ -197,8 +208,8 @@ func upload_content(uploadUrl string, fillBufferFunction func(w io.Writer) error
 	if mtype != "" {
 		h.Set("Content-Type", mtype)
 	}
-	if isGzipped {
-		h.Set("Content-Encoding", "gzip")
+	if contentEncoding != "" {
+		h.Set("Content-Encoding", contentEncoding)
 	}
 
 	file_writer, cp_err := body_writer.CreatePart(h)
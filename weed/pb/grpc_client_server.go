@@ -106,36 +106,66 @@ func WithCachedGrpcClient(fn func(*grpc.ClientConn) error, address string, opts
 	return fn(grpcConnection)
 }
 
+// NewServerAddress formats the address of a server as <host>:<port>, or as <host>:<port>.<grpcPort>
+// if the grpc port is not the default port + 10000, so the grpc port is known wherever the address goes.
+func NewServerAddress(host string, port int, grpcPort int) string {
+	if grpcPort == 0 || grpcPort == port+10000 {
+		return fmt.Sprintf("%s:%d", host, port)
+	}
+	return fmt.Sprintf("%s:%d.%d", host, port, grpcPort)
+}
+
+// NewServerAddressWithGrpcPort adds the grpc port to the <host>:<port> address if it is not the default port + 10000.
+func NewServerAddressWithGrpcPort(address string, grpcPort int) string {
+	colonIndex := strings.LastIndex(address, ":")
+	if grpcPort == 0 || colonIndex < 0 {
+		return address
+	}
+	if port, err := strconv.Atoi(address[colonIndex+1:]); err == nil && grpcPort == port+10000 {
+		return address
+	}
+	return fmt.Sprintf("%s.%d", address, grpcPort)
+}
+
+// ServerToHttpAddress removes the grpc port from the server address.
+func ServerToHttpAddress(server string) string {
+	colonIndex := strings.LastIndex(server, ":")
+	if dotIndex := strings.LastIndex(server, "."); colonIndex >= 0 && dotIndex > colonIndex {
+		return server[:dotIndex]
+	}
+	return server
+}
+
 func ParseServerToGrpcAddress(server string) (serverGrpcAddress string, err error) {
 	colonIndex := strings.LastIndex(server, ":")
 	if colonIndex < 0 {
 		return "", fmt.Errorf("server should have hostname:port format: %v", server)
 	}
 
-	port, parseErr := strconv.ParseUint(server[colonIndex+1:], 10, 64)
+	ports := strings.SplitN(server[colonIndex+1:], ".", 2)
+	port, parseErr := strconv.ParseUint(ports[0], 10, 64)
 	if parseErr != nil {
 		return "", fmt.Errorf("server port parse error: %v", parseErr)
 	}
 
 	grpcPort := int(port) + 10000
+	if len(ports) == 2 {
+		explicitPort, parseErr := strconv.ParseUint(ports[1], 10, 64)
+		if parseErr != nil {
+			return "", fmt.Errorf("server grpc port parse error: %v", parseErr)
+		}
+		grpcPort = int(explicitPort)
+	}
 
 	return fmt.Sprintf("%s:%d", server[:colonIndex], grpcPort), nil
 }
 
 func ServerToGrpcAddress(server string) (serverGrpcAddress string) {
-	hostnameAndPort := strings.Split(server, ":")
-	if len(hostnameAndPort) != 2 {
-		return fmt.Sprintf("unexpected server address: %s", server)
-	}
-
-	port, parseErr := strconv.ParseUint(hostnameAndPort[1], 10, 64)
-	if parseErr != nil {
-		return fmt.Sprintf("failed to parse port for %s:%s", hostnameAndPort[0], hostnameAndPort[1])
+	serverGrpcAddress, err := ParseServerToGrpcAddress(server)
+	if err != nil {
+		return fmt.Sprintf("unexpected server address %s: %v", server, err)
 	}
-
-	grpcPort := int(port) + 10000
-
-	return fmt.Sprintf("%s:%d", hostnameAndPort[0], grpcPort)
+	return serverGrpcAddress
 }
 
 func WithMasterClient(master string, grpcDialOption grpc.DialOption, fn func(client master_pb.SeaweedClient) error) error {
@@ -182,17 +212,5 @@ func WithGrpcFilerClient(filerGrpcAddress string, grpcDialOption grpc.DialOption
 }
 
 func ParseFilerGrpcAddress(filer string) (filerGrpcAddress string, err error) {
-	hostnameAndPort := strings.Split(filer, ":")
-	if len(hostnameAndPort) != 2 {
-		return "", fmt.Errorf("filer should have hostname:port format: %v", hostnameAndPort)
-	}
-
-	filerPort, parseErr := strconv.ParseUint(hostnameAndPort[1], 10, 64)
-	if parseErr != nil {
-		return "", fmt.Errorf("filer port parse error: %v", parseErr)
-	}
-
-	filerGrpcPort := int(filerPort) + 10000
-
-	return fmt.Sprintf("%s:%d", hostnameAndPort[0], filerGrpcPort), nil
+	return ParseServerToGrpcAddress(filer)
 }
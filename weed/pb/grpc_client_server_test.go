@@ -0,0 +1,43 @@
+package pb
+
+import (
+	"testing"
+)
+
+func TestServerAddress(t *testing.T) {
+	tests := []struct {
+		server      string
+		httpAddress string
+		grpcAddress string
+	}{
+		{"localhost:9333", "localhost:9333", "localhost:19333"},
+		{"localhost:9333.18888", "localhost:9333", "localhost:18888"},
+		{"10.0.0.1:8080.9090", "10.0.0.1:8080", "10.0.0.1:9090"},
+		{"seaweed.example.com:9333", "seaweed.example.com:9333", "seaweed.example.com:19333"},
+		{"seaweed.example.com:9333.9334", "seaweed.example.com:9333", "seaweed.example.com:9334"},
+	}
+	for _, tt := range tests {
+		if httpAddress := ServerToHttpAddress(tt.server); httpAddress != tt.httpAddress {
+			t.Errorf("%s http address: %s, expected %s", tt.server, httpAddress, tt.httpAddress)
+		}
+		if grpcAddress, err := ParseServerToGrpcAddress(tt.server); err != nil || grpcAddress != tt.grpcAddress {
+			t.Errorf("%s grpc address: %s %v, expected %s", tt.server, grpcAddress, err, tt.grpcAddress)
+		}
+	}
+
+	if address := NewServerAddress("localhost", 9333, 19333); address != "localhost:9333" {
+		t.Errorf("default grpc port address: %s", address)
+	}
+	if address := NewServerAddress("localhost", 9333, 18888); address != "localhost:9333.18888" {
+		t.Errorf("explicit grpc port address: %s", address)
+	}
+	if address := NewServerAddressWithGrpcPort("localhost:8080", 0); address != "localhost:8080" {
+		t.Errorf("unknown grpc port address: %s", address)
+	}
+	if address := NewServerAddressWithGrpcPort("localhost:8080", 9090); address != "localhost:8080.9090" {
+		t.Errorf("explicit grpc port address: %s", address)
+	}
+	if _, err := ParseServerToGrpcAddress("localhost:9333.abc"); err == nil {
+		t.Errorf("invalid grpc port is accepted")
+	}
+}
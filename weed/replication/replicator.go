@@ -17,6 +17,14 @@ import (
 type Replicator struct {
 	sink   sink.ReplicationSink
 	source *source.FilerSource
+	filter replicationFilter
+}
+
+// replicationFilter selects the replicated entries under the source directory
+type replicationFilter struct {
+	includeDirectories []string
+	excludeDirectories []string
+	collections        map[string]bool
 }
 
 func NewReplicator(sourceConfig util.Configuration, configPrefix string, dataSink sink.ReplicationSink) *Replicator {
@@ -29,7 +37,65 @@ func NewReplicator(sourceConfig util.Configuration, configPrefix string, dataSin
 	return &Replicator{
 		sink:   dataSink,
 		source: source,
+		filter: newReplicationFilter(
+			sourceConfig.GetStringSlice(configPrefix+"includeDirectories"),
+			sourceConfig.GetStringSlice(configPrefix+"excludeDirectories"),
+			sourceConfig.GetStringSlice(configPrefix+"collections"),
+		),
+	}
+}
+
+func newReplicationFilter(includeDirectories, excludeDirectories, collections []string) (filter replicationFilter) {
+	for _, dir := range includeDirectories {
+		filter.includeDirectories = append(filter.includeDirectories, strings.TrimSuffix(dir, "/"))
+	}
+	for _, dir := range excludeDirectories {
+		filter.excludeDirectories = append(filter.excludeDirectories, strings.TrimSuffix(dir, "/"))
+	}
+	if len(collections) > 0 {
+		filter.collections = make(map[string]bool)
+		for _, collection := range collections {
+			filter.collections[collection] = true
+		}
+	}
+	return
+}
+
+// isReplicated checks the path against the directory filters, and a file's collection against the collection filter.
+func (filter replicationFilter) isReplicated(key string, entry *filer_pb.Entry) bool {
+	if len(filter.includeDirectories) > 0 && !isUnderAny(key, filter.includeDirectories) {
+		// keep the parent directories of the included ones
+		if !entry.IsDirectory || !isParentOfAny(key, filter.includeDirectories) {
+			return false
+		}
+	}
+	if isUnderAny(key, filter.excludeDirectories) {
+		return false
+	}
+	if filter.collections != nil && !entry.IsDirectory {
+		if entry.Attributes == nil || !filter.collections[entry.Attributes.Collection] {
+			return false
+		}
+	}
+	return true
+}
+
+func isUnderAny(key string, dirs []string) bool {
+	for _, dir := range dirs {
+		if key == dir || strings.HasPrefix(key, dir+"/") || dir == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isParentOfAny(key string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(dir, strings.TrimSuffix(key, "/")+"/") {
+			return true
+		}
 	}
+	return false
 }
 
 func (r *Replicator) Replicate(ctx context.Context, key string, message *filer_pb.EventNotification) error {
@@ -40,6 +106,35 @@ func (r *Replicator) Replicate(ctx context.Context, key string, message *filer_p
 		glog.V(4).Infof("skipping %v outside of %v", key, r.source.Dir)
 		return nil
 	}
+
+	// a change moving an entry into or out of the filtered entries is a creation or a deletion
+	if message.OldEntry != nil && !r.filter.isReplicated(key, message.OldEntry) {
+		if message.NewEntry == nil {
+			glog.V(4).Infof("skipping filtered %v", key)
+			return nil
+		}
+		message = &filer_pb.EventNotification{
+			NewEntry:      message.NewEntry,
+			NewParentPath: message.NewParentPath,
+			Signatures:    message.Signatures,
+		}
+		key = string(util.NewFullPath(message.NewParentPath, message.NewEntry.Name))
+		if !strings.HasPrefix(key, r.source.Dir) {
+			return nil
+		}
+	}
+	if message.NewEntry != nil && !r.filter.isReplicated(string(util.NewFullPath(message.NewParentPath, message.NewEntry.Name)), message.NewEntry) {
+		if message.OldEntry == nil {
+			glog.V(4).Infof("skipping filtered %v", key)
+			return nil
+		}
+		message = &filer_pb.EventNotification{
+			OldEntry:     message.OldEntry,
+			DeleteChunks: message.DeleteChunks,
+			Signatures:   message.Signatures,
+		}
+	}
+
 	newKey := util.Join(r.sink.GetSinkToDirectory(), key[len(r.source.Dir):])
 	glog.V(3).Infof("replicate %s => %s", key, newKey)
 	key = newKey
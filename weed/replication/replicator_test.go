@@ -0,0 +1,64 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/replication/sink"
+	"github.com/chrislusf/seaweedfs/weed/replication/source"
+)
+
+type recordingSink struct {
+	sink.ReplicationSink
+	calls []string
+}
+
+func (s *recordingSink) GetName() string            { return "recording" }
+func (s *recordingSink) GetSinkToDirectory() string { return "/" }
+func (s *recordingSink) DeleteEntry(key string, isDirectory, deleteIncludeChunks bool, signatures []int32) error {
+	s.calls = append(s.calls, "delete "+key)
+	return nil
+}
+func (s *recordingSink) CreateEntry(key string, entry *filer_pb.Entry, signatures []int32) error {
+	s.calls = append(s.calls, "create "+key)
+	return nil
+}
+func (s *recordingSink) UpdateEntry(key string, oldEntry *filer_pb.Entry, newParentPath string, newEntry *filer_pb.Entry, deleteIncludeChunks bool, signatures []int32) (bool, error) {
+	s.calls = append(s.calls, "update "+key)
+	return true, nil
+}
+
+func TestReplicationFilter(t *testing.T) {
+	recorder := &recordingSink{}
+	r := &Replicator{
+		sink:   recorder,
+		source: &source.FilerSource{Dir: "/buckets"},
+		filter: newReplicationFilter([]string{"/buckets/a/"}, []string{"/buckets/a/tmp"}, []string{"a"}),
+	}
+
+	file := func(name, collection string) *filer_pb.Entry {
+		return &filer_pb.Entry{Name: name, Attributes: &filer_pb.FuseAttributes{Collection: collection}}
+	}
+	create := func(dir string, entry *filer_pb.Entry) {
+		r.Replicate(context.Background(), dir+"/"+entry.Name, &filer_pb.EventNotification{NewEntry: entry, NewParentPath: dir})
+	}
+
+	create("/buckets", &filer_pb.Entry{Name: "a", IsDirectory: true})
+	create("/buckets", &filer_pb.Entry{Name: "b", IsDirectory: true})
+	create("/buckets/a", file("x", "a"))
+	create("/buckets/a", file("other", "b"))
+	create("/buckets/a/tmp", file("y", "a"))
+	create("/buckets/b", file("z", "a"))
+	// moved out of the excluded directory, and into it
+	r.Replicate(context.Background(), "/buckets/a/tmp/y", &filer_pb.EventNotification{
+		OldEntry: file("y", "a"), NewEntry: file("y", "a"), NewParentPath: "/buckets/a"})
+	r.Replicate(context.Background(), "/buckets/a/x", &filer_pb.EventNotification{
+		OldEntry: file("x", "a"), NewEntry: file("x", "a"), NewParentPath: "/buckets/a/tmp"})
+
+	expected := []string{"create /a", "create /a/x", "create /a/y", "delete /a/x"}
+	if fmt.Sprint(recorder.calls) != fmt.Sprint(expected) {
+		t.Errorf("replicated %v, expected %v", recorder.calls, expected)
+	}
+}
@@ -1,10 +1,10 @@
 package azuresink
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/replication/repl_util"
+	"io"
 	"net/url"
 	"strings"
 
@@ -17,6 +17,9 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
+// the block size of uploads, a block blob has at most 50,000 blocks
+const blockSize = 8 * 1024 * 1024
+
 type AzureSink struct {
 	containerURL azblob.ContainerURL
 	container    string
@@ -56,7 +59,7 @@ func (g *AzureSink) initialize(accountName, accountKey, container, dir string) e
 	// Use your Storage account's name and key to create a credential object.
 	credential, err := azblob.NewSharedKeyCredential(accountName, accountKey)
 	if err != nil {
-		glog.Fatalf("failed to create Azure credential with account name:%s key:%s", accountName, accountKey)
+		return fmt.Errorf("create Azure credential with account name %s: %v", accountName, err)
 	}
 
 	// Create a request pipeline that is used to process HTTP(S) requests and responses.
@@ -68,6 +71,12 @@ func (g *AzureSink) initialize(accountName, accountKey, container, dir string) e
 
 	g.containerURL = serviceURL.NewContainerURL(g.container)
 
+	// create the container if missing, the key may not be allowed to, when the container exists already
+	_, err = g.containerURL.Create(context.Background(), azblob.Metadata{}, azblob.PublicAccessNone)
+	if err != nil && !isStorageError(err, azblob.ServiceCodeContainerAlreadyExists) {
+		glog.Warningf("create Azure container %s: %v", g.container, err)
+	}
+
 	return nil
 }
 
@@ -80,7 +89,7 @@ func (g *AzureSink) DeleteEntry(key string, isDirectory, deleteIncludeChunks boo
 	}
 
 	if _, err := g.containerURL.NewBlobURL(key).Delete(context.Background(),
-		azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{}); err != nil {
+		azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{}); err != nil && !isStorageError(err, azblob.ServiceCodeBlobNotFound) {
 		return fmt.Errorf("azure delete %s/%s: %v", g.container, key, err)
 	}
 
@@ -99,22 +108,22 @@ func (g *AzureSink) CreateEntry(key string, entry *filer_pb.Entry, signatures []
 	totalSize := filer.FileSize(entry)
 	chunkViews := filer.ViewFromChunks(g.filerSource.LookupFileId, entry.Chunks, 0, int64(totalSize))
 
-	// Create a URL that references a to-be-created blob in your
-	// Azure Storage account's container.
-	appendBlobURL := g.containerURL.NewAppendBlobURL(key)
-
-	_, err := appendBlobURL.Create(context.Background(), azblob.BlobHTTPHeaders{}, azblob.Metadata{}, azblob.BlobAccessConditions{})
+	// stream the chunks into a block blob, uploaded in blocks of blockSize
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(repl_util.CopyFromChunkViews(chunkViews, g.filerSource, func(data []byte) error {
+			_, writeErr := writer.Write(data)
+			return writeErr
+		}))
+	}()
+
+	_, err := azblob.UploadStreamToBlockBlob(context.Background(), reader, g.containerURL.NewBlockBlobURL(key), azblob.UploadStreamToBlockBlobOptions{
+		BufferSize: blockSize,
+		MaxBuffers: 4,
+	})
+	reader.CloseWithError(err)
 	if err != nil {
-		return err
-	}
-
-	writeFunc := func(data []byte) error {
-		_, writeErr := appendBlobURL.AppendBlock(context.Background(), bytes.NewReader(data), azblob.AppendBlobAccessConditions{}, nil)
-		return writeErr
-	}
-
-	if err := repl_util.CopyFromChunkViews(chunkViews, g.filerSource, writeFunc); err != nil {
-		return err
+		return fmt.Errorf("azure upload %s/%s: %v", g.container, key, err)
 	}
 
 	return nil
@@ -127,6 +136,11 @@ func (g *AzureSink) UpdateEntry(key string, oldEntry *filer_pb.Entry, newParentP
 	return false, nil
 }
 
+func isStorageError(err error, code azblob.ServiceCodeType) bool {
+	storageErr, ok := err.(azblob.StorageError)
+	return ok && storageErr.ServiceCode() == code
+}
+
 func cleanKey(key string) string {
 	if strings.HasPrefix(key, "/") {
 		key = key[1:]
@@ -2,10 +2,12 @@ package B2Sink
 
 import (
 	"context"
+	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/replication/repl_util"
 	"strings"
 
 	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
 	"github.com/chrislusf/seaweedfs/weed/replication/sink"
 	"github.com/chrislusf/seaweedfs/weed/replication/source"
@@ -73,7 +75,10 @@ func (g *B2Sink) DeleteEntry(key string, isDirectory, deleteIncludeChunks bool,
 
 	targetObject := bucket.Object(key)
 
-	return targetObject.Delete(context.Background())
+	if err := targetObject.Delete(context.Background()); err != nil && !b2.IsNotExist(err) {
+		return fmt.Errorf("b2 delete %s/%s: %v", g.bucket, key, err)
+	}
+	return nil
 
 }
 
@@ -93,18 +98,31 @@ func (g *B2Sink) CreateEntry(key string, entry *filer_pb.Entry, signatures []int
 		return err
 	}
 
+	// files over the writer's ChunkSize go through a large file upload session,
+	// which is cancelled if the copy fails, instead of leaving unfinished parts
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	targetObject := bucket.Object(key)
-	writer := targetObject.NewWriter(context.Background())
+	writer := targetObject.NewWriter(ctx, b2.WithCancelOnError(context.Background, func(err error) {
+		if err != nil {
+			glog.V(0).Infof("b2 cancel large file %s/%s: %v", g.bucket, key, err)
+		}
+	}))
+	writer.ConcurrentUploads = 2
 
 	writeFunc := func(data []byte) error {
 		_, writeErr := writer.Write(data)
 		return writeErr
 	}
 
-	defer writer.Close()
-
 	if err := repl_util.CopyFromChunkViews(chunkViews, g.filerSource, writeFunc); err != nil {
-		return err
+		cancel()
+		writer.Close()
+		return fmt.Errorf("b2 write %s/%s: %v", g.bucket, key, err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("b2 write %s/%s: %v", g.bucket, key, err)
 	}
 
 	return nil
@@ -94,6 +94,10 @@ func (fs *FilerSink) CreateEntry(key string, entry *filer_pb.Entry, signatures [
 				glog.V(3).Infof("already replicated %s", key)
 				return nil
 			}
+			if !entry.IsDirectory && keepExistingEntry(resp.Entry, entry) {
+				glog.V(2).Infof("keep newer %s", key)
+				return nil
+			}
 		}
 
 		replicatedChunks, err := fs.replicateChunks(entry.Chunks, key)
@@ -112,6 +116,7 @@ func (fs *FilerSink) CreateEntry(key string, entry *filer_pb.Entry, signatures [
 				IsDirectory: entry.IsDirectory,
 				Attributes:  entry.Attributes,
 				Chunks:      replicatedChunks,
+				Extended:    entry.Extended,
 			},
 			IsFromOtherCluster: true,
 			Signatures:         signatures,
@@ -158,11 +163,13 @@ func (fs *FilerSink) UpdateEntry(key string, oldEntry *filer_pb.Entry, newParent
 
 	glog.V(4).Infof("oldEntry %+v, newEntry %+v, existingEntry: %+v", oldEntry, newEntry, existingEntry)
 
-	if existingEntry.Attributes.Mtime > newEntry.Attributes.Mtime {
-		// skip if already changed
-		// this usually happens when the messages are not ordered
+	if keepExistingEntry(existingEntry, newEntry) {
+		// skip if already changed, by a late message or a write in this cluster
 		glog.V(2).Infof("late updates %s", key)
-	} else if filer.ETag(newEntry) == filer.ETag(existingEntry) {
+		return true, nil
+	}
+
+	if filer.ETag(newEntry) == filer.ETag(existingEntry) {
 		// skip if no change
 		// this usually happens when retrying the replication
 		glog.V(3).Infof("already replicated %s", key)
@@ -205,6 +212,19 @@ func (fs *FilerSink) UpdateEntry(key string, oldEntry *filer_pb.Entry, newParent
 	})
 
 }
+
+// keepExistingEntry resolves a conflicting write by last writer wins on mtime.
+// Ties go to the larger etag, so both clusters keep the same version.
+func keepExistingEntry(existingEntry, replicatedEntry *filer_pb.Entry) bool {
+	if existingEntry.Attributes == nil || replicatedEntry.Attributes == nil {
+		return false
+	}
+	if existingEntry.Attributes.Mtime != replicatedEntry.Attributes.Mtime {
+		return existingEntry.Attributes.Mtime > replicatedEntry.Attributes.Mtime
+	}
+	return filer.ETag(existingEntry) > filer.ETag(replicatedEntry)
+}
+
 func compareChunks(lookupFileIdFn filer.LookupFileIdFunctionType, oldEntry, newEntry *filer_pb.Entry) (deletedChunks, newChunks []*filer_pb.FileChunk, err error) {
 	aData, aMeta, aErr := filer.ResolveChunkManifest(lookupFileIdFn, oldEntry.Chunks)
 	if aErr != nil {
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/replication/repl_util"
 	"os"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 
 	"github.com/chrislusf/seaweedfs/weed/filer"
@@ -57,12 +60,12 @@ func (g *GcsSink) initialize(google_application_credentials, bucketName, dir str
 		var found bool
 		google_application_credentials, found = os.LookupEnv("GOOGLE_APPLICATION_CREDENTIALS")
 		if !found {
-			glog.Fatalf("need to specific GOOGLE_APPLICATION_CREDENTIALS env variable or google_application_credentials in replication.toml")
+			return fmt.Errorf("need to specific GOOGLE_APPLICATION_CREDENTIALS env variable or google_application_credentials in replication.toml")
 		}
 	}
 	client, err := storage.NewClient(context.Background(), option.WithCredentialsFile(google_application_credentials))
 	if err != nil {
-		glog.Fatalf("Failed to create client: %v", err)
+		return fmt.Errorf("create gcs client: %v", err)
 	}
 
 	g.client = client
@@ -76,7 +79,10 @@ func (g *GcsSink) DeleteEntry(key string, isDirectory, deleteIncludeChunks bool,
 		key = key + "/"
 	}
 
-	if err := g.client.Bucket(g.bucket).Object(key).Delete(context.Background()); err != nil {
+	err := retryGcs("delete "+key, func() error {
+		return g.client.Bucket(g.bucket).Object(key).Delete(context.Background())
+	})
+	if err != nil && err != storage.ErrObjectNotExist {
 		return fmt.Errorf("gcs delete %s%s: %v", g.bucket, key, err)
 	}
 
@@ -93,16 +99,26 @@ func (g *GcsSink) CreateEntry(key string, entry *filer_pb.Entry, signatures []in
 	totalSize := filer.FileSize(entry)
 	chunkViews := filer.ViewFromChunks(g.filerSource.LookupFileId, entry.Chunks, 0, int64(totalSize))
 
-	wc := g.client.Bucket(g.bucket).Object(key).NewWriter(context.Background())
-	defer wc.Close()
+	err := retryGcs("write "+key, func() error {
+		// the object is committed on Close, or dropped if the write is cancelled
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		wc := g.client.Bucket(g.bucket).Object(key).NewWriter(ctx)
 
-	writeFunc := func(data []byte) error {
-		_, writeErr := wc.Write(data)
-		return writeErr
-	}
+		writeFunc := func(data []byte) error {
+			_, writeErr := wc.Write(data)
+			return writeErr
+		}
 
-	if err := repl_util.CopyFromChunkViews(chunkViews, g.filerSource, writeFunc); err != nil {
-		return err
+		if err := repl_util.CopyFromChunkViews(chunkViews, g.filerSource, writeFunc); err != nil {
+			cancel()
+			wc.Close()
+			return err
+		}
+		return wc.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("gcs write %s%s: %v", g.bucket, key, err)
 	}
 
 	return nil
@@ -113,3 +129,23 @@ func (g *GcsSink) UpdateEntry(key string, oldEntry *filer_pb.Entry, newParentPat
 	// TODO improve efficiency
 	return false, nil
 }
+
+// retryGcs retries the job with backoff on rate limiting, server and transport errors
+func retryGcs(name string, job func() error) (err error) {
+	waitTime := time.Second
+	for i := 0; ; i++ {
+		if err = job(); err == nil || i >= 5 || !isRetryableGcsError(err) {
+			return err
+		}
+		glog.V(0).Infof("retry gcs %s in %v: %v", name, waitTime, err)
+		time.Sleep(waitTime)
+		waitTime *= 2
+	}
+}
+
+func isRetryableGcsError(err error) bool {
+	if e, ok := err.(*googleapi.Error); ok {
+		return e.Code == 429 || e.Code >= 500
+	}
+	return strings.Contains(err.Error(), "transport") || strings.Contains(err.Error(), "connection reset")
+}
@@ -121,6 +121,50 @@ func TestCheckAdminRequestAuthType(t *testing.T) {
 	}
 }
 
+// TestPresignedExpiry checks the validity window of presigned urls.
+func TestPresignedExpiry(t *testing.T) {
+	iam := NewIdentityAccessManagement("", "")
+	iam.identities = []*Identity{
+		{
+			Name: "someone",
+			Credentials: []*Credential{
+				{
+					AccessKey: "access_key_1",
+					SecretKey: "secret_key_1",
+				},
+			},
+		},
+	}
+
+	now := time.Now().UTC()
+	testCases := []struct {
+		signedAt time.Time
+		expires  time.Duration
+		ErrCode  s3err.ErrorCode
+	}{
+		{signedAt: now.Add(-5 * time.Minute), expires: 10 * time.Minute, ErrCode: s3err.ErrNone},
+		{signedAt: now.Add(-20 * time.Minute), expires: 10 * time.Minute, ErrCode: s3err.ErrExpiredPresignRequest},
+		{signedAt: now.Add(5 * time.Minute), expires: 10 * time.Minute, ErrCode: s3err.ErrNone},
+		{signedAt: now.Add(time.Hour), expires: 10 * time.Minute, ErrCode: s3err.ErrRequestNotReadyYet},
+	}
+	for i, testCase := range testCases {
+		req := mustNewRequest("PUT", "http://127.0.0.1:9000/bucket/object", 0, nil, t)
+		if err := preSignV4AtTime(req, "access_key_1", "secret_key_1", testCase.signedAt, int64(testCase.expires.Seconds())); err != nil {
+			t.Fatalf("presign: %v", err)
+		}
+		if _, s3Error := iam.reqSignatureV4Verify(req); s3Error != testCase.ErrCode {
+			t.Errorf("Test %d: Unexpected s3error returned wanted %d, got %d", i, testCase.ErrCode, s3Error)
+		}
+	}
+
+	// the signature covers the path
+	req := mustNewPresignedRequest("GET", "http://127.0.0.1:9000/bucket/object", 0, nil, t)
+	req.URL.Path = "/bucket/other"
+	if _, s3Error := iam.reqSignatureV4Verify(req); s3Error != s3err.ErrSignatureDoesNotMatch {
+		t.Errorf("changed path: wanted %d, got %d", s3err.ErrSignatureDoesNotMatch, s3Error)
+	}
+}
+
 // Provides a fully populated http request instance, fails otherwise.
 func mustNewRequest(method string, urlStr string, contentLength int64, body io.ReadSeeker, t *testing.T) *http.Request {
 	req, err := newTestRequest(method, urlStr, contentLength, body)
@@ -342,13 +386,17 @@ func signRequestV4(req *http.Request, accessKey, secretKey string) error {
 // preSignV4 presign the request, in accordance with
 // http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html.
 func preSignV4(req *http.Request, accessKeyID, secretAccessKey string, expires int64) error {
+	return preSignV4AtTime(req, accessKeyID, secretAccessKey, time.Now().UTC(), expires)
+}
+
+// preSignV4AtTime presigns the request as if it was signed at the date.
+func preSignV4AtTime(req *http.Request, accessKeyID, secretAccessKey string, date time.Time, expires int64) error {
 	// Presign is not needed for anonymous credentials.
 	if accessKeyID == "" || secretAccessKey == "" {
 		return errors.New("Presign cannot be generated without access and secret keys")
 	}
 
 	region := "us-east-1"
-	date := time.Now().UTC()
 	scope := getScope(date, region)
 	credential := fmt.Sprintf("%s/%s", accessKeyID, scope)
 
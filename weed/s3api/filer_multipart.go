@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -68,23 +69,27 @@ func (s3a *S3ApiServer) completeMultipartUpload(input *s3.CompleteMultipartUploa
 		return nil, s3err.ErrNoSuchUpload
 	}
 
+	partEntries, code := completedPartEntries(entries, input.MultipartUpload)
+	if code != s3err.ErrNone {
+		glog.V(1).Infof("completeMultipartUpload %s %s: %v", *input.Bucket, *input.UploadId, code)
+		return nil, code
+	}
+
 	var finalParts []*filer_pb.FileChunk
 	var offset int64
 
-	for _, entry := range entries {
-		if strings.HasSuffix(entry.Name, ".part") && !entry.IsDirectory {
-			for _, chunk := range entry.Chunks {
-				p := &filer_pb.FileChunk{
-					FileId:    chunk.GetFileIdString(),
-					Offset:    offset,
-					Size:      chunk.Size,
-					Mtime:     chunk.Mtime,
-					CipherKey: chunk.CipherKey,
-					ETag:      chunk.ETag,
-				}
-				finalParts = append(finalParts, p)
-				offset += int64(chunk.Size)
+	for _, entry := range partEntries {
+		for _, chunk := range entry.Chunks {
+			p := &filer_pb.FileChunk{
+				FileId:    chunk.GetFileIdString(),
+				Offset:    offset,
+				Size:      chunk.Size,
+				Mtime:     chunk.Mtime,
+				CipherKey: chunk.CipherKey,
+				ETag:      chunk.ETag,
 			}
+			finalParts = append(finalParts, p)
+			offset += int64(chunk.Size)
 		}
 	}
 
@@ -126,6 +131,53 @@ func (s3a *S3ApiServer) completeMultipartUpload(input *s3.CompleteMultipartUploa
 	return
 }
 
+// completedPartEntries returns the part entries in the completed upload, in order.
+// Without a part list, all uploaded parts are used, ordered by part number.
+func completedPartEntries(entries []*filer_pb.Entry, completedUpload *s3.CompletedMultipartUpload) (partEntries []*filer_pb.Entry, code s3err.ErrorCode) {
+
+	uploadedParts := make(map[int64]*filer_pb.Entry)
+	var partNumbers []int64
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name, ".part") || entry.IsDirectory {
+			continue
+		}
+		partNumber, err := strconv.ParseInt(strings.TrimSuffix(entry.Name, ".part"), 10, 64)
+		if err != nil {
+			continue
+		}
+		uploadedParts[partNumber] = entry
+		partNumbers = append(partNumbers, partNumber)
+	}
+
+	if completedUpload == nil || len(completedUpload.Parts) == 0 {
+		sort.Slice(partNumbers, func(i, j int) bool {
+			return partNumbers[i] < partNumbers[j]
+		})
+		for _, partNumber := range partNumbers {
+			partEntries = append(partEntries, uploadedParts[partNumber])
+		}
+		return partEntries, s3err.ErrNone
+	}
+
+	var lastPartNumber int64
+	for _, part := range completedUpload.Parts {
+		partNumber := aws.Int64Value(part.PartNumber)
+		if partNumber <= lastPartNumber {
+			return nil, s3err.ErrInvalidPartOrder
+		}
+		lastPartNumber = partNumber
+		entry, found := uploadedParts[partNumber]
+		if !found {
+			return nil, s3err.ErrInvalidPart
+		}
+		if etag := strings.Trim(aws.StringValue(part.ETag), "\""); etag != "" && etag != filer.ETag(entry) {
+			return nil, s3err.ErrInvalidPart
+		}
+		partEntries = append(partEntries, entry)
+	}
+	return partEntries, s3err.ErrNone
+}
+
 func (s3a *S3ApiServer) abortMultipartUpload(input *s3.AbortMultipartUploadInput) (output *s3.AbortMultipartUploadOutput, code s3err.ErrorCode) {
 
 	glog.V(2).Infof("abortMultipartUpload input %v", input)
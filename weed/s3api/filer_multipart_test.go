@@ -1,10 +1,14 @@
 package s3api
 
 import (
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"testing"
 	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
 )
 
 func TestInitiateMultipartUploadResult(t *testing.T) {
@@ -47,3 +51,42 @@ func TestListPartsResult(t *testing.T) {
 	}
 
 }
+
+func TestCompletedPartEntries(t *testing.T) {
+	part := func(name, md5 string) *filer_pb.Entry {
+		return &filer_pb.Entry{Name: name, Attributes: &filer_pb.FuseAttributes{Md5: []byte(md5)}}
+	}
+	entries := []*filer_pb.Entry{part("0001.part", "a"), part("0002.part", "b"), part("10000.part", "c"), part("1001.part", "d")}
+	names := func(partEntries []*filer_pb.Entry) (names []string) {
+		for _, entry := range partEntries {
+			names = append(names, entry.Name)
+		}
+		return
+	}
+
+	partEntries, code := completedPartEntries(entries, nil)
+	if code != s3err.ErrNone || fmt.Sprint(names(partEntries)) != "[0001.part 0002.part 1001.part 10000.part]" {
+		t.Errorf("all parts: %v %v", names(partEntries), code)
+	}
+
+	completed := func(parts ...interface{}) *s3.CompletedMultipartUpload {
+		upload := &s3.CompletedMultipartUpload{}
+		for i := 0; i < len(parts); i += 2 {
+			upload.Parts = append(upload.Parts, &s3.CompletedPart{PartNumber: aws.Int64(int64(parts[i].(int))), ETag: aws.String(parts[i+1].(string))})
+		}
+		return upload
+	}
+	partEntries, code = completedPartEntries(entries, completed(1, "\"61\"", 1001, ""))
+	if code != s3err.ErrNone || fmt.Sprint(names(partEntries)) != "[0001.part 1001.part]" {
+		t.Errorf("listed parts: %v %v", names(partEntries), code)
+	}
+	if _, code = completedPartEntries(entries, completed(2, "", 1, "")); code != s3err.ErrInvalidPartOrder {
+		t.Errorf("unordered parts: %v", code)
+	}
+	if _, code = completedPartEntries(entries, completed(1, "\"62\"")); code != s3err.ErrInvalidPart {
+		t.Errorf("wrong etag: %v", code)
+	}
+	if _, code = completedPartEntries(entries, completed(3, "")); code != s3err.ErrInvalidPart {
+		t.Errorf("missing part: %v", code)
+	}
+}
@@ -27,6 +27,13 @@ const (
 	// S3 object tagging
 	AmzObjectTagging = "X-Amz-Tagging"
 	AmzTagCount      = "x-amz-tagging-count"
+
+	// S3 object versioning
+	AmzVersionId    = "x-amz-version-id"
+	AmzDeleteMarker = "x-amz-delete-marker"
+
+	// S3 bucket location
+	AmzBucketRegion = "x-amz-bucket-region"
 )
 
 // Non-Standard S3 HTTP request constants
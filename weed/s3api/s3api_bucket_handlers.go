@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math"
 	"net/http"
+	"strings"
 	"time"
 
 	xhttp "github.com/chrislusf/seaweedfs/weed/s3api/http"
@@ -36,6 +39,7 @@ func (s3a *S3ApiServer) ListBucketsHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	identityId := r.Header.Get(xhttp.AmzIdentityId)
+	filter := newListBucketsFilter(r)
 
 	var buckets []*s3.Bucket
 	for _, entry := range entries {
@@ -43,6 +47,9 @@ func (s3a *S3ApiServer) ListBucketsHandler(w http.ResponseWriter, r *http.Reques
 			if !s3a.hasAccess(r, entry) {
 				continue
 			}
+			if !filter.matches(entry) {
+				continue
+			}
 			buckets = append(buckets, &s3.Bucket{
 				Name:         aws.String(entry.Name),
 				CreationDate: aws.Time(time.Unix(entry.Attributes.Crtime, 0).UTC()),
@@ -95,6 +102,13 @@ func (s3a *S3ApiServer) PutBucketHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	region, err := parseLocationConstraint(r)
+	if err != nil {
+		glog.Errorf("PutBucketHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrMalformedXML, r.URL)
+		return
+	}
+
 	fn := func(entry *filer_pb.Entry) {
 		if identityId := r.Header.Get(xhttp.AmzIdentityId); identityId != "" {
 			if entry.Extended == nil {
@@ -102,6 +116,12 @@ func (s3a *S3ApiServer) PutBucketHandler(w http.ResponseWriter, r *http.Request)
 			}
 			entry.Extended[xhttp.AmzIdentityId] = []byte(identityId)
 		}
+		if region != "" {
+			if entry.Extended == nil {
+				entry.Extended = make(map[string][]byte)
+			}
+			entry.Extended[xhttp.AmzBucketRegion] = []byte(region)
+		}
 	}
 
 	// create the folder for bucket, but lazily create actual collection
@@ -189,3 +209,67 @@ func (s3a *S3ApiServer) hasAccess(r *http.Request, entry *filer_pb.Entry) bool {
 	}
 	return true
 }
+
+// DefaultBucketRegion is reported for buckets created without a LocationConstraint.
+const DefaultBucketRegion = "us-east-1"
+
+func parseLocationConstraint(r *http.Request) (string, error) {
+	if r.ContentLength <= 0 {
+		return "", nil
+	}
+	input, err := ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+	if err != nil {
+		return "", err
+	}
+	config := &CreateBucketConfiguration{}
+	if err = xml.Unmarshal(input, config); err != nil {
+		return "", err
+	}
+	return config.LocationConstraint, nil
+}
+
+// listBucketsFilter narrows ListBuckets by ?prefix=, ?bucket-region= and ?tag-key=&tag-value=
+type listBucketsFilter struct {
+	prefix   string
+	region   string
+	tagKey   string
+	tagValue string
+	hasValue bool
+}
+
+func newListBucketsFilter(r *http.Request) listBucketsFilter {
+	query := r.URL.Query()
+	_, hasValue := query["tag-value"]
+	return listBucketsFilter{
+		prefix:   query.Get("prefix"),
+		region:   query.Get("bucket-region"),
+		tagKey:   query.Get("tag-key"),
+		tagValue: query.Get("tag-value"),
+		hasValue: hasValue,
+	}
+}
+
+func (f listBucketsFilter) matches(entry *filer_pb.Entry) bool {
+	if !strings.HasPrefix(entry.Name, f.prefix) {
+		return false
+	}
+	if f.region != "" {
+		region := DefaultBucketRegion
+		if v, ok := entry.Extended[xhttp.AmzBucketRegion]; ok && len(v) > 0 {
+			region = string(v)
+		}
+		if region != f.region {
+			return false
+		}
+	}
+	if f.tagKey != "" {
+		v, ok := entry.Extended[S3TAG_PREFIX+f.tagKey]
+		if !ok {
+			return false
+		}
+		if f.hasValue && string(v) != f.tagValue {
+			return false
+		}
+	}
+	return true
+}
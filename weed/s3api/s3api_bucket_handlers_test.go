@@ -1,11 +1,15 @@
 package s3api
 
 import (
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	xhttp "github.com/chrislusf/seaweedfs/weed/s3api/http"
 )
 
 func TestListBucketsHandler(t *testing.T) {
@@ -37,3 +41,40 @@ func TestListBucketsHandler(t *testing.T) {
 		t.Errorf("unexpected output: %s\nexpecting:%s", encoded, expected)
 	}
 }
+
+func TestListBucketsFilter(t *testing.T) {
+
+	entry := &filer_pb.Entry{
+		Name: "prod-logs",
+		Extended: map[string][]byte{
+			xhttp.AmzBucketRegion:  []byte("eu-west-1"),
+			S3TAG_PREFIX + "env":   []byte("production"),
+			S3TAG_PREFIX + "owner": []byte("ops"),
+		},
+	}
+	plain := &filer_pb.Entry{Name: "prod-tmp"}
+
+	tests := []struct {
+		query string
+		entry *filer_pb.Entry
+		match bool
+	}{
+		{"", entry, true},
+		{"prefix=prod", entry, true},
+		{"prefix=dev", entry, false},
+		{"bucket-region=eu-west-1", entry, true},
+		{"bucket-region=us-east-1", entry, false},
+		{"bucket-region=us-east-1", plain, true},
+		{"tag-key=env&tag-value=production", entry, true},
+		{"tag-key=env&tag-value=staging", entry, false},
+		{"tag-key=owner", entry, true},
+		{"tag-key=env", plain, false},
+		{"prefix=prod&tag-key=env&tag-value=production", plain, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/?"+tt.query, nil)
+		if got := newListBucketsFilter(r).matches(tt.entry); got != tt.match {
+			t.Errorf("%s on %s: got %v, expected %v", tt.query, tt.entry.Name, got, tt.match)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package s3api
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
+)
+
+// the lifecycle rules are applied by the filer, see filer/filer_bucket_lifecycle.go
+
+const maxLifecycleRules = 1000
+
+type LifecycleConfiguration struct {
+	Rules []LifecycleRule `xml:"Rule"`
+}
+
+type LifecycleConfigurationResult struct {
+	XMLName xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LifecycleConfiguration"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+type LifecycleRule struct {
+	ID                             string                          `xml:"ID,omitempty"`
+	Prefix                         *string                         `xml:"Prefix"`
+	Filter                         *LifecycleFilter                `xml:"Filter"`
+	Status                         string                          `xml:"Status"`
+	Expiration                     *LifecycleExpiration            `xml:"Expiration"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload"`
+
+	// not supported
+	Transition                  *struct{} `xml:"Transition"`
+	NoncurrentVersionExpiration *struct{} `xml:"NoncurrentVersionExpiration"`
+	NoncurrentVersionTransition *struct{} `xml:"NoncurrentVersionTransition"`
+}
+
+type LifecycleFilter struct {
+	Prefix string `xml:"Prefix"`
+
+	// not supported
+	Tag *Tag      `xml:"Tag"`
+	And *struct{} `xml:"And"`
+}
+
+type LifecycleExpiration struct {
+	Days int    `xml:"Days,omitempty"`
+	Date string `xml:"Date,omitempty"`
+
+	// not supported
+	ExpiredObjectDeleteMarker *bool `xml:"ExpiredObjectDeleteMarker"`
+}
+
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+// GetBucketLifecycleConfigurationHandler - GET bucket lifecycle
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLifecycleConfiguration.html
+func (s3a *S3ApiServer) GetBucketLifecycleConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	entry, err := s3a.getEntry(s3a.option.BucketsPath, bucket)
+	if err != nil || entry == nil {
+		glog.Errorf("GetBucketLifecycleConfigurationHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+	data, found := entry.Extended[filer.BucketLifecycleKey]
+	if !found {
+		writeErrorResponse(w, s3err.ErrNoSuchLifecycleConfiguration, r.URL)
+		return
+	}
+	var rules []*filer.LifecycleRule
+	if err = json.Unmarshal(data, &rules); err != nil {
+		glog.Errorf("GetBucketLifecycleConfigurationHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
+	writeSuccessResponseXML(w, encodeResponse(toLifecycleConfiguration(rules)))
+}
+
+// PutBucketLifecycleConfigurationHandler - PUT bucket lifecycle, replacing the existing rules
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketLifecycleConfiguration.html
+func (s3a *S3ApiServer) PutBucketLifecycleConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	input, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		glog.Errorf("PutBucketLifecycleConfigurationHandler read input %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+	configuration := &LifecycleConfiguration{}
+	if err = xml.Unmarshal(input, configuration); err != nil {
+		glog.Errorf("PutBucketLifecycleConfigurationHandler Unmarshal %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrMalformedXML, r.URL)
+		return
+	}
+	rules, errCode := fromLifecycleConfiguration(configuration)
+	if errCode != s3err.ErrNone {
+		writeErrorResponse(w, errCode, r.URL)
+		return
+	}
+	// the filer does not add the delete markers needed to expire versioned objects
+	if hasExpirationRules(rules) {
+		versioning, err := s3a.getVersioning(bucket)
+		if err != nil {
+			glog.Errorf("PutBucketLifecycleConfigurationHandler %s: %v", r.URL, err)
+			writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+			return
+		}
+		if versioning != "" {
+			writeErrorResponse(w, s3err.ErrVersionedLifecycleExpiration, r.URL)
+			return
+		}
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		glog.Errorf("PutBucketLifecycleConfigurationHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
+	if err = s3a.updateBucketExtended(bucket, func(extended map[string][]byte) {
+		extended[filer.BucketLifecycleKey] = data
+	}); err != nil {
+		glog.Errorf("PutBucketLifecycleConfigurationHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
+	writeSuccessResponseEmpty(w)
+}
+
+// DeleteBucketLifecycleHandler - DELETE bucket lifecycle
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketLifecycle.html
+func (s3a *S3ApiServer) DeleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	if err := s3a.updateBucketExtended(bucket, func(extended map[string][]byte) {
+		delete(extended, filer.BucketLifecycleKey)
+	}); err != nil {
+		if err == filer_pb.ErrNotFound {
+			writeErrorResponse(w, s3err.ErrNoSuchBucket, r.URL)
+		} else {
+			glog.Errorf("DeleteBucketLifecycleHandler %s: %v", r.URL, err)
+			writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// fromLifecycleConfiguration validates the rules, only expiration and aborting multipart uploads by prefix are supported.
+func fromLifecycleConfiguration(configuration *LifecycleConfiguration) (rules []*filer.LifecycleRule, code s3err.ErrorCode) {
+	if len(configuration.Rules) == 0 || len(configuration.Rules) > maxLifecycleRules {
+		return nil, s3err.ErrMalformedXML
+	}
+	ids := make(map[string]bool)
+	for i, r := range configuration.Rules {
+		if r.Transition != nil || r.NoncurrentVersionExpiration != nil || r.NoncurrentVersionTransition != nil {
+			return nil, s3err.ErrNotImplemented
+		}
+		if r.Filter != nil && (r.Filter.Tag != nil || r.Filter.And != nil) {
+			return nil, s3err.ErrNotImplemented
+		}
+		if r.Expiration != nil && r.Expiration.ExpiredObjectDeleteMarker != nil {
+			return nil, s3err.ErrNotImplemented
+		}
+		if r.Status != "Enabled" && r.Status != "Disabled" {
+			return nil, s3err.ErrMalformedXML
+		}
+		if r.Prefix != nil && r.Filter != nil {
+			return nil, s3err.ErrMalformedXML
+		}
+
+		rule := &filer.LifecycleRule{
+			ID:      r.ID,
+			Enabled: r.Status == "Enabled",
+		}
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule%d", i+1)
+		}
+		if ids[rule.ID] {
+			return nil, s3err.ErrInvalidRequest
+		}
+		ids[rule.ID] = true
+		if r.Prefix != nil {
+			rule.Prefix = *r.Prefix
+		} else if r.Filter != nil {
+			rule.Prefix = r.Filter.Prefix
+		}
+
+		if r.Expiration != nil {
+			if r.Expiration.Days < 0 || (r.Expiration.Days > 0) == (r.Expiration.Date != "") {
+				return nil, s3err.ErrInvalidRequest
+			}
+			rule.ExpirationDays = r.Expiration.Days
+			if r.Expiration.Date != "" {
+				date, err := time.Parse(time.RFC3339, r.Expiration.Date)
+				if err != nil {
+					return nil, s3err.ErrMalformedXML
+				}
+				rule.ExpirationDate = date.UTC()
+			}
+		}
+		if r.AbortIncompleteMultipartUpload != nil {
+			if r.AbortIncompleteMultipartUpload.DaysAfterInitiation <= 0 {
+				return nil, s3err.ErrInvalidRequest
+			}
+			rule.AbortMultipartUploadDays = r.AbortIncompleteMultipartUpload.DaysAfterInitiation
+		}
+		if rule.ExpirationDays == 0 && rule.ExpirationDate.IsZero() && rule.AbortMultipartUploadDays == 0 {
+			return nil, s3err.ErrInvalidRequest
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, s3err.ErrNone
+}
+
+// hasExpirationRules checks whether any rule expires objects, which is not supported with versioning.
+func hasExpirationRules(rules []*filer.LifecycleRule) bool {
+	for _, rule := range rules {
+		if rule.ExpirationDays > 0 || !rule.ExpirationDate.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// bucketHasExpirationRules checks the lifecycle rules saved in the bucket entry.
+func (s3a *S3ApiServer) bucketHasExpirationRules(bucket string) (bool, error) {
+	entry, err := s3a.getEntry(s3a.option.BucketsPath, bucket)
+	if err != nil || entry == nil {
+		return false, err
+	}
+	data, found := entry.Extended[filer.BucketLifecycleKey]
+	if !found {
+		return false, nil
+	}
+	var rules []*filer.LifecycleRule
+	if err = json.Unmarshal(data, &rules); err != nil {
+		return false, err
+	}
+	return hasExpirationRules(rules), nil
+}
+
+func toLifecycleConfiguration(rules []*filer.LifecycleRule) (configuration *LifecycleConfigurationResult) {
+	configuration = &LifecycleConfigurationResult{}
+	for _, rule := range rules {
+		r := LifecycleRule{
+			ID:     rule.ID,
+			Filter: &LifecycleFilter{Prefix: rule.Prefix},
+			Status: "Disabled",
+		}
+		if rule.Enabled {
+			r.Status = "Enabled"
+		}
+		if rule.ExpirationDays > 0 || !rule.ExpirationDate.IsZero() {
+			r.Expiration = &LifecycleExpiration{Days: rule.ExpirationDays}
+			if !rule.ExpirationDate.IsZero() {
+				r.Expiration.Date = rule.ExpirationDate.Format(time.RFC3339)
+			}
+		}
+		if rule.AbortMultipartUploadDays > 0 {
+			r.AbortIncompleteMultipartUpload = &AbortIncompleteMultipartUpload{
+				DaysAfterInitiation: rule.AbortMultipartUploadDays,
+			}
+		}
+		configuration.Rules = append(configuration.Rules, r)
+	}
+	return
+}
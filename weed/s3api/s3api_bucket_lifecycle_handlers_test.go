@@ -0,0 +1,56 @@
+package s3api
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
+)
+
+func TestFromLifecycleConfiguration(t *testing.T) {
+
+	tests := []struct {
+		input string
+		code  s3err.ErrorCode
+	}{
+		{`<LifecycleConfiguration><Rule><ID>logs</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>7</Days></Expiration></Rule></LifecycleConfiguration>`, s3err.ErrNone},
+		{`<LifecycleConfiguration><Rule><Prefix></Prefix><Status>Enabled</Status><AbortIncompleteMultipartUpload><DaysAfterInitiation>2</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`, s3err.ErrNone},
+		{`<LifecycleConfiguration><Rule><Filter></Filter><Status>Disabled</Status><Expiration><Date>2030-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, s3err.ErrNone},
+		{`<LifecycleConfiguration><Rule><Filter></Filter><Status>On</Status><Expiration><Days>7</Days></Expiration></Rule></LifecycleConfiguration>`, s3err.ErrMalformedXML},
+		{`<LifecycleConfiguration><Rule><Filter></Filter><Status>Enabled</Status></Rule></LifecycleConfiguration>`, s3err.ErrInvalidRequest},
+		{`<LifecycleConfiguration><Rule><Filter></Filter><Status>Enabled</Status><Expiration><Days>7</Days><Date>2030-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, s3err.ErrInvalidRequest},
+		{`<LifecycleConfiguration><Rule><ID>a</ID><Filter></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule><Rule><ID>a</ID><Filter></Filter><Status>Enabled</Status><Expiration><Days>2</Days></Expiration></Rule></LifecycleConfiguration>`, s3err.ErrInvalidRequest},
+		{`<LifecycleConfiguration><Rule><Filter><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><Expiration><Days>7</Days></Expiration></Rule></LifecycleConfiguration>`, s3err.ErrNotImplemented},
+		{`<LifecycleConfiguration><Rule><Filter></Filter><Status>Enabled</Status><Transition><Days>7</Days><StorageClass>GLACIER</StorageClass></Transition></Rule></LifecycleConfiguration>`, s3err.ErrNotImplemented},
+	}
+
+	for i, test := range tests {
+		configuration := &LifecycleConfiguration{}
+		if err := xml.Unmarshal([]byte(test.input), configuration); err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if _, code := fromLifecycleConfiguration(configuration); code != test.code {
+			t.Errorf("test %d: expected %v, got %v", i, test.code, code)
+		}
+	}
+
+	configuration := &LifecycleConfiguration{}
+	xml.Unmarshal([]byte(tests[0].input), configuration)
+	rules, _ := fromLifecycleConfiguration(configuration)
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<LifecycleConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Rule><ID>logs</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>7</Days></Expiration></Rule></LifecycleConfiguration>`
+	if actual := string(encodeResponse(toLifecycleConfiguration(rules))); actual != expected {
+		t.Errorf("unexpected configuration: %s", actual)
+	}
+
+	// only the expiration rules are refused on buckets with versioning
+	if !hasExpirationRules(rules) {
+		t.Errorf("expiration rule not detected")
+	}
+	configuration = &LifecycleConfiguration{}
+	xml.Unmarshal([]byte(tests[1].input), configuration)
+	rules, _ = fromLifecycleConfiguration(configuration)
+	if hasExpirationRules(rules) {
+		t.Errorf("abort rule detected as expiration")
+	}
+}
@@ -0,0 +1,97 @@
+package s3api
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
+)
+
+// GetBucketTaggingHandler - GET bucket tagging
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketTagging.html
+func (s3a *S3ApiServer) GetBucketTaggingHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	tags, err := s3a.getTags(s3a.option.BucketsPath, bucket)
+	if err != nil {
+		glog.Errorf("GetBucketTaggingHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
+	writeSuccessResponseXML(w, encodeResponse(FromTags(tags)))
+
+}
+
+// PutBucketTaggingHandler Put bucket tagging
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketTagging.html
+func (s3a *S3ApiServer) PutBucketTaggingHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	tagging := &Tagging{}
+	input, err := ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+	if err != nil {
+		glog.Errorf("PutBucketTaggingHandler read input %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+	if err = xml.Unmarshal(input, tagging); err != nil {
+		glog.Errorf("PutBucketTaggingHandler Unmarshal %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrMalformedXML, r.URL)
+		return
+	}
+	if err = validateTags(tagging.ToTags()); err != nil {
+		glog.Errorf("PutBucketTaggingHandler tags %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInvalidTag, r.URL)
+		return
+	}
+
+	if err = s3a.setTags(s3a.option.BucketsPath, bucket, tagging.ToTags()); err != nil {
+		glog.Errorf("PutBucketTaggingHandler setTags %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+}
+
+// DeleteBucketTaggingHandler Delete bucket tagging
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketTagging.html
+func (s3a *S3ApiServer) DeleteBucketTaggingHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	if err := s3a.rmTags(s3a.option.BucketsPath, bucket); err != nil {
+		if err == filer_pb.ErrNotFound {
+			writeErrorResponse(w, s3err.ErrNoSuchBucket, r.URL)
+		} else {
+			glog.Errorf("DeleteBucketTaggingHandler %s: %v", r.URL, err)
+			writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -54,6 +54,25 @@ func (s3a *S3ApiServer) AdjustedUrl(location *filer_pb.Location) string {
 	return location.Url
 }
 
+// setCorsHeaders lets browsers read the responses, e.g. of presigned urls, from any origin.
+func setCorsHeaders(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Origin") == "" {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "*")
+}
+
+// OptionsHandler answers the CORS preflight requests sent by browsers before a cross origin request.
+func (s3a *S3ApiServer) OptionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, PUT, POST, DELETE")
+	if requestHeaders := r.Header.Get("Access-Control-Request-Headers"); requestHeaders != "" {
+		w.Header().Set("Access-Control-Allow-Headers", requestHeaders)
+	}
+	w.Header().Set("Access-Control-Max-Age", "3600")
+	writeSuccessResponseEmpty(w)
+}
+
 // If none of the http routes match respond with MethodNotAllowed
 func notFoundHandler(w http.ResponseWriter, r *http.Request) {
 	glog.V(0).Infof("unsupported %s %s", r.Method, r.RequestURI)
@@ -3,6 +3,7 @@ package s3api
 import (
 	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	xhttp "github.com/chrislusf/seaweedfs/weed/s3api/http"
 	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
 	"net/http"
 	"net/url"
@@ -36,8 +37,6 @@ func (s3a *S3ApiServer) CopyObjectHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dstUrl := fmt.Sprintf("http://%s%s/%s%s?collection=%s",
-		s3a.option.Filer, s3a.option.BucketsPath, dstBucket, dstObject, dstBucket)
 	srcUrl := fmt.Sprintf("http://%s%s/%s%s",
 		s3a.option.Filer, s3a.option.BucketsPath, srcBucket, srcObject)
 
@@ -48,15 +47,35 @@ func (s3a *S3ApiServer) CopyObjectHandler(w http.ResponseWriter, r *http.Request
 	}
 	defer util.CloseResponse(resp)
 
+	target, versionId, err := s3a.newVersionTarget(dstBucket, dstObject)
+	if err != nil {
+		glog.Errorf("CopyObjectHandler %s%s: %v", dstBucket, dstObject, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+	r.Header.Set(xhttp.AmzVersionId, versionId)
+
+	dstUrl := fmt.Sprintf("http://%s%s/%s%s?collection=%s",
+		s3a.option.Filer, s3a.option.BucketsPath, dstBucket, target, dstBucket)
+
 	glog.V(2).Infof("copy from %s to %s", srcUrl, dstUrl)
 	etag, errCode := s3a.putToFiler(r, dstUrl, resp.Body)
 
 	if errCode != s3err.ErrNone {
+		s3a.discardNewVersion(dstBucket, dstObject, target)
 		writeErrorResponse(w, errCode, r.URL)
 		return
 	}
 
+	if err = s3a.commitNewVersion(dstBucket, dstObject, target, versionId); err != nil {
+		glog.Errorf("CopyObjectHandler %s%s: %v", dstBucket, dstObject, err)
+		s3a.discardNewVersion(dstBucket, dstObject, target)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
 	setEtag(w, etag)
+	setVersionId(w, versionId)
 
 	response := CopyObjectResult{
 		ETag:         etag,
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"strings"
 
+	xhttp "github.com/chrislusf/seaweedfs/weed/s3api/http"
 	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
 
 	"github.com/gorilla/mux"
@@ -42,6 +43,14 @@ func (s3a *S3ApiServer) PutObjectHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if tags := r.Header.Get(xhttp.AmzObjectTagging); tags != "" {
+		if _, err := parseTagsHeader(tags); err != nil {
+			glog.V(1).Infof("PutObjectHandler %s: %v", r.URL, err)
+			writeErrorResponse(w, s3err.ErrInvalidTag, r.URL)
+			return
+		}
+	}
+
 	dataReader := r.Body
 	if s3a.iam.isEnabled() {
 		rAuthType := getRequestAuthType(r)
@@ -67,16 +76,33 @@ func (s3a *S3ApiServer) PutObjectHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 	} else {
-		uploadUrl := fmt.Sprintf("http://%s%s/%s%s", s3a.option.Filer, s3a.option.BucketsPath, bucket, object)
+		target, versionId, err := s3a.newVersionTarget(bucket, object)
+		if err != nil {
+			glog.Errorf("PutObjectHandler %s%s: %v", bucket, object, err)
+			writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+			return
+		}
+		r.Header.Set(xhttp.AmzVersionId, versionId)
+
+		uploadUrl := fmt.Sprintf("http://%s%s/%s%s", s3a.option.Filer, s3a.option.BucketsPath, bucket, target)
 
 		etag, errCode := s3a.putToFiler(r, uploadUrl, dataReader)
 
 		if errCode != s3err.ErrNone {
+			s3a.discardNewVersion(bucket, object, target)
 			writeErrorResponse(w, errCode, r.URL)
 			return
 		}
 
+		if err = s3a.commitNewVersion(bucket, object, target, versionId); err != nil {
+			glog.Errorf("PutObjectHandler %s%s: %v", bucket, object, err)
+			s3a.discardNewVersion(bucket, object, target)
+			writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+			return
+		}
+
 		setEtag(w, etag)
+		setVersionId(w, versionId)
 	}
 
 	writeSuccessResponseEmpty(w)
@@ -91,8 +117,11 @@ func (s3a *S3ApiServer) GetObjectHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	destUrl := fmt.Sprintf("http://%s%s/%s%s",
-		s3a.option.Filer, s3a.option.BucketsPath, bucket, object)
+	destUrl, errCode := s3a.objectUrl(r, bucket, object)
+	if errCode != s3err.ErrNone {
+		writeErrorResponse(w, errCode, r.URL)
+		return
+	}
 
 	s3a.proxyToFiler(w, r, destUrl, passThroughResponse)
 
@@ -102,8 +131,11 @@ func (s3a *S3ApiServer) HeadObjectHandler(w http.ResponseWriter, r *http.Request
 
 	bucket, object := getBucketAndObject(r)
 
-	destUrl := fmt.Sprintf("http://%s%s/%s%s",
-		s3a.option.Filer, s3a.option.BucketsPath, bucket, object)
+	destUrl, errCode := s3a.objectUrl(r, bucket, object)
+	if errCode != s3err.ErrNone {
+		writeErrorResponse(w, errCode, r.URL)
+		return
+	}
 
 	s3a.proxyToFiler(w, r, destUrl, passThroughResponse)
 
@@ -113,6 +145,35 @@ func (s3a *S3ApiServer) DeleteObjectHandler(w http.ResponseWriter, r *http.Reque
 
 	bucket, object := getBucketAndObject(r)
 
+	versioning, err := s3a.getVersioning(bucket)
+	if err != nil && err != filer_pb.ErrNotFound {
+		glog.Errorf("DeleteObjectHandler %s%s: %v", bucket, object, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+	if versionId := r.URL.Query().Get("versionId"); versionId != "" || versioning != "" {
+		if versionId != "" && !isValidVersionId(versionId) {
+			writeErrorResponse(w, s3err.ErrInvalidVersionId, r.URL)
+			return
+		}
+		deleted, err := s3a.deleteVersioned(bucket, object, versionId, versioning)
+		if err != nil {
+			glog.Errorf("DeleteObjectHandler %s%s: %v", bucket, object, err)
+			writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+			return
+		}
+		if deleted.DeleteMarker {
+			w.Header().Set(xhttp.AmzDeleteMarker, "true")
+		}
+		if deleted.DeleteMarkerVersionId != "" {
+			setVersionId(w, deleted.DeleteMarkerVersionId)
+		} else {
+			setVersionId(w, deleted.VersionId)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	destUrl := fmt.Sprintf("http://%s%s/%s%s?recursive=true",
 		s3a.option.Filer, s3a.option.BucketsPath, bucket, object)
 
@@ -126,7 +187,10 @@ func (s3a *S3ApiServer) DeleteObjectHandler(w http.ResponseWriter, r *http.Reque
 
 // / ObjectIdentifier carries key name for the object to delete.
 type ObjectIdentifier struct {
-	ObjectName string `xml:"Key"`
+	ObjectName            string `xml:"Key"`
+	VersionId             string `xml:"VersionId,omitempty"`
+	DeleteMarker          bool   `xml:"DeleteMarker,omitempty"`
+	DeleteMarkerVersionId string `xml:"DeleteMarkerVersionId,omitempty"`
 }
 
 // DeleteObjectsRequest - xml carrying the object key names which needs to be deleted.
@@ -175,9 +239,47 @@ func (s3a *S3ApiServer) DeleteMultipleObjectsHandler(w http.ResponseWriter, r *h
 	var deletedObjects []ObjectIdentifier
 	var deleteErrors []DeleteError
 
+	versioning, err := s3a.getVersioning(bucket)
+	if err != nil && err != filer_pb.ErrNotFound {
+		glog.Errorf("DeleteMultipleObjectsHandler %s: %v", bucket, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
 	s3a.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
 
 		for _, object := range deleteObjects.Objects {
+			if isReservedObjectKey(object.ObjectName) {
+				deleteErrors = append(deleteErrors, DeleteError{
+					Code:    "InvalidArgument",
+					Message: s3err.GetAPIError(s3err.ErrReservedObjectKey).Description,
+					Key:     object.ObjectName,
+				})
+				continue
+			}
+			if object.VersionId != "" && !isValidVersionId(object.VersionId) {
+				deleteErrors = append(deleteErrors, DeleteError{
+					Code:    "InvalidArgument",
+					Message: s3err.GetAPIError(s3err.ErrInvalidVersionId).Description,
+					Key:     object.ObjectName,
+				})
+				continue
+			}
+			if object.VersionId != "" || versioning != "" {
+				deleted, err := s3a.deleteVersioned(bucket, "/"+strings.TrimPrefix(object.ObjectName, "/"), object.VersionId, versioning)
+				if err == nil {
+					deleted.ObjectName = object.ObjectName
+					deletedObjects = append(deletedObjects, deleted)
+				} else {
+					deleteErrors = append(deleteErrors, DeleteError{
+						Code:    "",
+						Message: err.Error(),
+						Key:     object.ObjectName,
+					})
+				}
+				continue
+			}
+
 			response, _ := s3a.listFilerEntries(bucket, object.ObjectName, 1, "", "/")
 			if len(response.Contents) != 0 && strings.HasSuffix(object.ObjectName, "/") {
 				continue
@@ -344,6 +446,12 @@ func setEtag(w http.ResponseWriter, etag string) {
 	}
 }
 
+func setVersionId(w http.ResponseWriter, versionId string) {
+	if versionId != "" {
+		w.Header().Set(xhttp.AmzVersionId, versionId)
+	}
+}
+
 func getBucketAndObject(r *http.Request) (bucket, object string) {
 	vars := mux.Vars(r)
 	bucket = vars["bucket"]
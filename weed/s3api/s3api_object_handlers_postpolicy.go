@@ -53,6 +53,10 @@ func (s3a *S3ApiServer) PostPolicyBucketHandler(w http.ResponseWriter, r *http.R
 		formValues.Set("Key", strings.Replace(formValues.Get("Key"), "${filename}", fileName, -1))
 	}
 	object := formValues.Get("Key")
+	if isReservedObjectKey(object) {
+		writeErrorResponse(w, s3err.ErrReservedObjectKey, r.URL)
+		return
+	}
 
 	successRedirect := formValues.Get("success_action_redirect")
 	successStatus := formValues.Get("success_action_status")
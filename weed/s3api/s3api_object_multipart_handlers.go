@@ -1,9 +1,12 @@
 package s3api
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -47,23 +50,81 @@ func (s3a *S3ApiServer) CompleteMultipartUploadHandler(w http.ResponseWriter, r
 	// Get upload id.
 	uploadID, _, _, _ := getObjectResources(r.URL.Query())
 
+	completedUpload, errCode := parseCompleteMultipartUpload(r)
+	if errCode != s3err.ErrNone {
+		writeErrorResponse(w, errCode, r.URL)
+		return
+	}
+
+	target, versionId, err := s3a.newVersionTarget(bucket, object)
+	if err != nil {
+		glog.Errorf("CompleteMultipartUploadHandler %s%s: %v", bucket, object, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
 	response, errCode := s3a.completeMultipartUpload(&s3.CompleteMultipartUploadInput{
-		Bucket:   aws.String(bucket),
-		Key:      objectKey(aws.String(object)),
-		UploadId: aws.String(uploadID),
+		Bucket:          aws.String(bucket),
+		Key:             objectKey(aws.String(target)),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: completedUpload,
 	})
 
 	glog.V(2).Info("CompleteMultipartUploadHandler", string(encodeResponse(response)), errCode)
 
 	if errCode != s3err.ErrNone {
+		s3a.discardNewVersion(bucket, object, target)
 		writeErrorResponse(w, errCode, r.URL)
 		return
 	}
 
+	if err = s3a.saveVersionId(bucket, target, versionId); err != nil {
+		glog.Errorf("CompleteMultipartUploadHandler %s%s version %s: %v", bucket, object, versionId, err)
+	}
+	if err = s3a.commitNewVersion(bucket, object, target, versionId); err != nil {
+		glog.Errorf("CompleteMultipartUploadHandler %s%s: %v", bucket, object, err)
+		s3a.discardNewVersion(bucket, object, target)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+	response.Key = objectKey(aws.String(object))
+	response.Location = aws.String(fmt.Sprintf("http://%s%s/%s%s", s3a.option.Filer, s3a.option.BucketsPath, bucket, object))
+	setVersionId(w, versionId)
+
 	writeSuccessResponseXML(w, encodeResponse(response))
 
 }
 
+// CompleteMultipartUpload is the request body listing the parts of the object
+type CompleteMultipartUpload struct {
+	Parts []struct {
+		PartNumber int64
+		ETag       string
+	} `xml:"Part"`
+}
+
+func parseCompleteMultipartUpload(r *http.Request) (*s3.CompletedMultipartUpload, s3err.ErrorCode) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, s3err.ErrInternalError
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, s3err.ErrNone
+	}
+	completeUpload := &CompleteMultipartUpload{}
+	if err = xml.Unmarshal(body, completeUpload); err != nil {
+		return nil, s3err.ErrMalformedXML
+	}
+	completedUpload := &s3.CompletedMultipartUpload{}
+	for _, part := range completeUpload.Parts {
+		completedUpload.Parts = append(completedUpload.Parts, &s3.CompletedPart{
+			PartNumber: aws.Int64(part.PartNumber),
+			ETag:       aws.String(part.ETag),
+		})
+	}
+	return completedUpload, s3err.ErrNone
+}
+
 // AbortMultipartUploadHandler - Aborts multipart upload.
 func (s3a *S3ApiServer) AbortMultipartUploadHandler(w http.ResponseWriter, r *http.Request) {
 	bucket, object := getBucketAndObject(r)
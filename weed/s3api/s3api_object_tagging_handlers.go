@@ -18,8 +18,11 @@ func (s3a *S3ApiServer) GetObjectTaggingHandler(w http.ResponseWriter, r *http.R
 
 	bucket, object := getBucketAndObject(r)
 
-	target := util.FullPath(fmt.Sprintf("%s/%s%s", s3a.option.BucketsPath, bucket, object))
-	dir, name := target.DirAndName()
+	dir, name, errCode := s3a.taggingTarget(r, bucket, object)
+	if errCode != s3err.ErrNone {
+		writeErrorResponse(w, errCode, r.URL)
+		return
+	}
 
 	tags, err := s3a.getTags(dir, name)
 	if err != nil {
@@ -43,8 +46,11 @@ func (s3a *S3ApiServer) PutObjectTaggingHandler(w http.ResponseWriter, r *http.R
 
 	bucket, object := getBucketAndObject(r)
 
-	target := util.FullPath(fmt.Sprintf("%s/%s%s", s3a.option.BucketsPath, bucket, object))
-	dir, name := target.DirAndName()
+	dir, name, errCode := s3a.taggingTarget(r, bucket, object)
+	if errCode != s3err.ErrNone {
+		writeErrorResponse(w, errCode, r.URL)
+		return
+	}
 
 	tagging := &Tagging{}
 	input, err := ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
@@ -58,24 +64,11 @@ func (s3a *S3ApiServer) PutObjectTaggingHandler(w http.ResponseWriter, r *http.R
 		writeErrorResponse(w, s3err.ErrMalformedXML, r.URL)
 		return
 	}
-	tags := tagging.ToTags()
-	if len(tags) > 10 {
-		glog.Errorf("PutObjectTaggingHandler tags %s: %d tags more than 10", r.URL, len(tags))
+	if err = validateTags(tagging.ToTags()); err != nil {
+		glog.Errorf("PutObjectTaggingHandler tags %s: %v", r.URL, err)
 		writeErrorResponse(w, s3err.ErrInvalidTag, r.URL)
 		return
 	}
-	for k, v := range tags {
-		if len(k) > 128 {
-			glog.Errorf("PutObjectTaggingHandler tags %s: tag key %s longer than 128", r.URL, k)
-			writeErrorResponse(w, s3err.ErrInvalidTag, r.URL)
-			return
-		}
-		if len(v) > 256 {
-			glog.Errorf("PutObjectTaggingHandler tags %s: tag value %s longer than 256", r.URL, v)
-			writeErrorResponse(w, s3err.ErrInvalidTag, r.URL)
-			return
-		}
-	}
 
 	if err = s3a.setTags(dir, name, tagging.ToTags()); err != nil {
 		if err == filer_pb.ErrNotFound {
@@ -98,8 +91,11 @@ func (s3a *S3ApiServer) DeleteObjectTaggingHandler(w http.ResponseWriter, r *htt
 
 	bucket, object := getBucketAndObject(r)
 
-	target := util.FullPath(fmt.Sprintf("%s/%s%s", s3a.option.BucketsPath, bucket, object))
-	dir, name := target.DirAndName()
+	dir, name, errCode := s3a.taggingTarget(r, bucket, object)
+	if errCode != s3err.ErrNone {
+		writeErrorResponse(w, errCode, r.URL)
+		return
+	}
 
 	err := s3a.rmTags(dir, name)
 	if err != nil {
@@ -115,3 +111,12 @@ func (s3a *S3ApiServer) DeleteObjectTaggingHandler(w http.ResponseWriter, r *htt
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// taggingTarget returns the folder and the name of the object, or of the version in the versionId query parameter.
+func (s3a *S3ApiServer) taggingTarget(r *http.Request, bucket, object string) (dir, name string, code s3err.ErrorCode) {
+	if versionId := r.URL.Query().Get("versionId"); versionId != "" {
+		return s3a.versionPath(bucket, object, versionId)
+	}
+	dir, name = util.FullPath(fmt.Sprintf("%s/%s%s", s3a.option.BucketsPath, bucket, object)).DirAndName()
+	return dir, name, s3err.ErrNone
+}
@@ -0,0 +1,705 @@
+package s3api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	xhttp "github.com/chrislusf/seaweedfs/weed/s3api/http"
+	"github.com/chrislusf/seaweedfs/weed/s3api/s3err"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+The older versions of the objects in a bucket with versioning are kept under the .versions folder:
+
+	<bucket>/<key>                          the current version
+	<bucket>/.versions/<key>/<version id>   the older versions and the delete markers
+
+A new version is written into .versions first, with a ".new" suffix hiding it from the listings. Once written, the current version is moved
+into .versions, and the new version takes its place. Deleting an object without a version id
+moves the current version into .versions and adds a delete marker there.
+Objects written while versioning is off or suspended have the version id "null".
+*/
+
+const (
+	versionsFolder      = ".versions"
+	nullVersionId       = "null"
+	newVersionSuffix    = ".new"
+	versioningEnabled   = "Enabled"
+	versioningSuspended = "Suspended"
+)
+
+type VersioningConfigurationResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ VersioningConfiguration"`
+	Status  string   `xml:"Status,omitempty"`
+}
+
+type ListObjectVersionsResult struct {
+	XMLName         xml.Name            `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListVersionsResult"`
+	Name            string              `xml:"Name"`
+	Prefix          string              `xml:"Prefix"`
+	KeyMarker       string              `xml:"KeyMarker"`
+	VersionIdMarker string              `xml:"VersionIdMarker"`
+	NextKeyMarker   string              `xml:"NextKeyMarker,omitempty"`
+	MaxKeys         int                 `xml:"MaxKeys"`
+	IsTruncated     bool                `xml:"IsTruncated"`
+	Versions        []VersionEntry      `xml:"Version,omitempty"`
+	DeleteMarkers   []DeleteMarkerEntry `xml:"DeleteMarker,omitempty"`
+}
+
+// GetBucketVersioningHandler - GET bucket versioning
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketVersioning.html
+func (s3a *S3ApiServer) GetBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	status, err := s3a.getVersioning(bucket)
+	if err != nil {
+		glog.Errorf("GetBucketVersioningHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
+	writeSuccessResponseXML(w, encodeResponse(VersioningConfigurationResult{Status: status}))
+}
+
+// PutBucketVersioningHandler - PUT bucket versioning
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketVersioning.html
+func (s3a *S3ApiServer) PutBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	input, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		glog.Errorf("PutBucketVersioningHandler read input %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+	configuration := &struct {
+		Status string `xml:"Status"`
+	}{}
+	if err = xml.Unmarshal(input, configuration); err != nil {
+		glog.Errorf("PutBucketVersioningHandler Unmarshal %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrMalformedXML, r.URL)
+		return
+	}
+	if configuration.Status != versioningEnabled && configuration.Status != versioningSuspended {
+		writeErrorResponse(w, s3err.ErrIllegalVersioningConfiguration, r.URL)
+		return
+	}
+	if hasExpiration, err := s3a.bucketHasExpirationRules(bucket); err != nil {
+		glog.Errorf("PutBucketVersioningHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	} else if hasExpiration {
+		writeErrorResponse(w, s3err.ErrVersionedLifecycleExpiration, r.URL)
+		return
+	}
+
+	if err = s3a.setVersioning(bucket, configuration.Status); err != nil {
+		glog.Errorf("PutBucketVersioningHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
+	writeSuccessResponseEmpty(w)
+}
+
+// ListObjectVersionsHandler - GET bucket versions
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html
+// max-keys limits the number of keys, not the number of versions.
+func (s3a *S3ApiServer) ListObjectVersionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	bucket, _ := getBucketAndObject(r)
+
+	values := r.URL.Query()
+	prefix, keyMarker := values.Get("prefix"), values.Get("key-marker")
+	maxKeys := maxObjectListSizeLimit
+	if values.Get("max-keys") != "" {
+		maxKeys, _ = strconv.Atoi(values.Get("max-keys"))
+	}
+	if maxKeys <= 0 {
+		writeErrorResponse(w, s3err.ErrInvalidMaxKeys, r.URL)
+		return
+	}
+	if values.Get("delimiter") != "" {
+		writeErrorResponse(w, s3err.ErrNotImplemented, r.URL)
+		return
+	}
+
+	if err := s3a.checkBucket(r, bucket); err != s3err.ErrNone {
+		writeErrorResponse(w, err, r.URL)
+		return
+	}
+
+	response, err := s3a.listObjectVersions(bucket, prefix, keyMarker, maxKeys)
+	if err != nil {
+		glog.Errorf("ListObjectVersionsHandler %s: %v", r.URL, err)
+		writeErrorResponse(w, s3err.ErrInternalError, r.URL)
+		return
+	}
+
+	writeSuccessResponseXML(w, encodeResponse(response))
+}
+
+func (s3a *S3ApiServer) listObjectVersions(bucket, prefix, keyMarker string, maxKeys int) (response ListObjectVersionsResult, err error) {
+
+	response = ListObjectVersionsResult{
+		Name:      bucket,
+		Prefix:    prefix,
+		KeyMarker: keyMarker,
+		MaxKeys:   maxKeys,
+	}
+
+	// the keys with a current version
+	current, err := s3a.listFilerEntries(bucket, prefix, maxKeys, keyMarker, "")
+	if err != nil {
+		return
+	}
+	keys := make(map[string]bool)
+	for _, content := range current.Contents {
+		keys[content.Key] = true
+	}
+	// the keys after the last listed current version are in the next page
+	lastKey := ""
+	if current.IsTruncated && len(current.Contents) > 0 {
+		lastKey = current.Contents[len(current.Contents)-1].Key
+	}
+
+	// the keys with older versions or delete markers
+	if err = s3a.walkVersionedKeys(s3a.versionsRoot(bucket), "", prefix, keyMarker, func(key string) {
+		if key > keyMarker && (lastKey == "" || key <= lastKey) {
+			keys[key] = true
+		}
+	}); err != nil {
+		return
+	}
+
+	var sortedKeys []string
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	response.IsTruncated = current.IsTruncated
+	if len(sortedKeys) > maxKeys {
+		sortedKeys = sortedKeys[:maxKeys]
+		response.IsTruncated = true
+	}
+	if response.IsTruncated && len(sortedKeys) > 0 {
+		response.NextKeyMarker = sortedKeys[len(sortedKeys)-1]
+	}
+
+	for _, key := range sortedKeys {
+		versions, err := s3a.listVersions(bucket, "/"+key)
+		if err != nil {
+			return response, err
+		}
+		for i, entry := range versions {
+			owner := CanonicalUser{
+				ID:          fmt.Sprintf("%x", entry.Attributes.Uid),
+				DisplayName: entry.Attributes.UserName,
+			}
+			lastModified := time.Unix(entry.Attributes.Mtime, 0).UTC()
+			if isDeleteMarker(entry) {
+				response.DeleteMarkers = append(response.DeleteMarkers, DeleteMarkerEntry{
+					Key:          key,
+					VersionId:    versionIdOf(entry),
+					IsLatest:     i == 0,
+					LastModified: lastModified,
+					Owner:        owner,
+				})
+				continue
+			}
+			response.Versions = append(response.Versions, VersionEntry{
+				Key:          key,
+				VersionId:    versionIdOf(entry),
+				IsLatest:     i == 0,
+				LastModified: lastModified,
+				ETag:         "\"" + filer.ETag(entry) + "\"",
+				Size:         int64(filer.FileSize(entry)),
+				Owner:        owner,
+				StorageClass: "STANDARD",
+			})
+		}
+	}
+
+	return
+}
+
+// walkVersionedKeys calls fn with the keys having files under the versions folder, and matching the prefix.
+// The listing starts from the key marker, skipping the keys sorting before it.
+func (s3a *S3ApiServer) walkVersionedKeys(dir, key, prefix, keyMarker string, fn func(key string)) error {
+
+	hasVersions := false
+	var subKeys []string
+	startFrom := versionedKeysStartFrom(key, keyMarker)
+	err := filer_pb.List(s3a, dir, "", func(entry *filer_pb.Entry, isLast bool) error {
+		if !entry.IsDirectory {
+			hasVersions = hasVersions || !isNewVersion(entry)
+			return nil
+		}
+		subKey := entry.Name
+		if key != "" {
+			subKey = key + "/" + entry.Name
+		}
+		if strings.HasPrefix(subKey, prefix) || strings.HasPrefix(prefix, subKey) {
+			subKeys = append(subKeys, subKey)
+		}
+		return nil
+	}, startFrom, true, math.MaxUint32)
+	if err != nil {
+		return err
+	}
+
+	if hasVersions && strings.HasPrefix(key, prefix) {
+		fn(key)
+	}
+	for _, subKey := range subKeys {
+		if err = s3a.walkVersionedKeys(dir+"/"+subKey[strings.LastIndex(subKey, "/")+1:], subKey, prefix, keyMarker, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionedKeysStartFrom returns the name to list the folder of the key from, so that no key after the key marker is missed.
+func versionedKeysStartFrom(key, keyMarker string) string {
+	marker := keyMarker
+	if key != "" {
+		if !strings.HasPrefix(keyMarker, key+"/") {
+			return ""
+		}
+		marker = keyMarker[len(key)+1:]
+	}
+	if i := strings.Index(marker, "/"); i >= 0 {
+		marker = marker[:i]
+	}
+	// a name n sorting before the marker still has keys n/... after it, if the marker continues n with a byte below '/'
+	for i := 0; i < len(marker); i++ {
+		if marker[i] < '/' {
+			return marker[:i]
+		}
+	}
+	return marker
+}
+
+// isReservedObjectKey checks whether the key is in the versions folder, which user objects would collide with.
+func isReservedObjectKey(object string) bool {
+	object = strings.TrimPrefix(object, "/")
+	return object == versionsFolder || strings.HasPrefix(object, versionsFolder+"/")
+}
+
+// rejectReservedObjectKeys refuses the requests on keys in the versions folder, including as copy source.
+// The older versions are only accessible with a versionId.
+func rejectReservedObjectKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, object := getBucketAndObject(r)
+		var srcObject string
+		if copySource := r.Header.Get("X-Amz-Copy-Source"); copySource != "" {
+			if unescaped, err := url.QueryUnescape(copySource); err == nil {
+				copySource = unescaped
+			}
+			_, srcObject = pathToBucketAndObject(copySource)
+		}
+		if isReservedObjectKey(object) || isReservedObjectKey(srcObject) {
+			writeErrorResponse(w, s3err.ErrReservedObjectKey, r.URL)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getVersioning returns the versioning status of the bucket, Enabled, Suspended, or empty if never enabled.
+func (s3a *S3ApiServer) getVersioning(bucket string) (string, error) {
+	entry, err := s3a.getEntry(s3a.option.BucketsPath, bucket)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", filer_pb.ErrNotFound
+	}
+	return string(entry.Extended[filer.BucketVersioningKey]), nil
+}
+
+func (s3a *S3ApiServer) setVersioning(bucket, status string) error {
+	return s3a.updateBucketExtended(bucket, func(extended map[string][]byte) {
+		extended[filer.BucketVersioningKey] = []byte(status)
+	})
+}
+
+func (s3a *S3ApiServer) versionsRoot(bucket string) string {
+	return fmt.Sprintf("%s/%s/%s", s3a.option.BucketsPath, bucket, versionsFolder)
+}
+
+// objectPaths returns the folder and the name of the current version, and the folder of the older versions.
+func (s3a *S3ApiServer) objectPaths(bucket, object string) (dir, name, versionsDir string) {
+	dir, name = util.FullPath(fmt.Sprintf("%s/%s%s", s3a.option.BucketsPath, bucket, object)).DirAndName()
+	versionsDir = s3a.versionsRoot(bucket) + object
+	return
+}
+
+// newVersionId returns ids sorting the newer versions first.
+func newVersionId() string {
+	return fmt.Sprintf("%016x", math.MaxInt64-time.Now().UnixNano())
+}
+
+// isValidVersionId checks the version id is one generated by newVersionId, or "null",
+// before it is used in a path.
+func isValidVersionId(versionId string) bool {
+	if versionId == nullVersionId {
+		return true
+	}
+	if len(versionId) != 16 {
+		return false
+	}
+	for _, c := range versionId {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func versionIdOf(entry *filer_pb.Entry) string {
+	if versionId, found := entry.Extended[xhttp.AmzVersionId]; found {
+		return string(versionId)
+	}
+	return nullVersionId
+}
+
+// isNewVersion checks whether the entry is a new version still being written.
+func isNewVersion(entry *filer_pb.Entry) bool {
+	return strings.HasSuffix(entry.Name, newVersionSuffix)
+}
+
+func isDeleteMarker(entry *filer_pb.Entry) bool {
+	return string(entry.Extended[xhttp.AmzDeleteMarker]) == "true"
+}
+
+// listVersions returns the current and the older versions of the object, newest first.
+func (s3a *S3ApiServer) listVersions(bucket, object string) (versions []*filer_pb.Entry, err error) {
+	dir, name, versionsDir := s3a.objectPaths(bucket, object)
+
+	current, err := s3a.getEntry(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && !current.IsDirectory {
+		versions = append(versions, current)
+	}
+
+	var older []*filer_pb.Entry
+	err = filer_pb.ReadDirAllEntries(s3a, util.FullPath(versionsDir), "", func(entry *filer_pb.Entry, isLast bool) error {
+		if !entry.IsDirectory && !isNewVersion(entry) {
+			older = append(older, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(older, func(i, j int) bool {
+		if older[i].Attributes.Mtime != older[j].Attributes.Mtime {
+			return older[i].Attributes.Mtime > older[j].Attributes.Mtime
+		}
+		return older[i].Name < older[j].Name
+	})
+
+	return append(versions, older...), nil
+}
+
+// newVersionTarget returns the key to write a new version of the object to, and its version id.
+// Without versioning, the object is written in place and the version id is empty. Otherwise the new
+// version is staged in the versions folder and moved in place by commitNewVersion, so the current
+// version stays readable while the new one is written, and is kept if the write fails.
+func (s3a *S3ApiServer) newVersionTarget(bucket, object string) (target, versionId string, err error) {
+	status, err := s3a.getVersioning(bucket)
+	if err == filer_pb.ErrNotFound {
+		return object, "", nil
+	}
+	if err != nil || status == "" {
+		return object, "", err
+	}
+	versionId = newVersionId()
+	stagedName := versionId + newVersionSuffix
+	if status == versioningSuspended {
+		versionId = nullVersionId
+	}
+	return "/" + versionsFolder + object + "/" + stagedName, versionId, nil
+}
+
+// commitNewVersion archives the current version of the object, and moves the new version in its place.
+func (s3a *S3ApiServer) commitNewVersion(bucket, object, target, versionId string) error {
+	if target == object {
+		return nil
+	}
+	status := versioningEnabled
+	if versionId == nullVersionId {
+		status = versioningSuspended
+	}
+	if err := s3a.archiveCurrentVersion(bucket, object, status); err != nil {
+		return err
+	}
+	dir, name, _ := s3a.objectPaths(bucket, object)
+	parent, dirName := util.FullPath(dir).DirAndName()
+	if exists, err := s3a.exists(parent, dirName, true); err != nil {
+		return err
+	} else if !exists {
+		if err = s3a.mkdir(parent, dirName, nil); err != nil {
+			return err
+		}
+	}
+	stagedDir, stagedName, _ := s3a.objectPaths(bucket, target)
+	return s3a.rename(stagedDir, stagedName, dir, name)
+}
+
+// discardNewVersion removes what is left of a new version failed to write or to commit.
+func (s3a *S3ApiServer) discardNewVersion(bucket, object, target string) {
+	if target == object {
+		return
+	}
+	stagedDir, stagedName, _ := s3a.objectPaths(bucket, target)
+	if exists, err := s3a.exists(stagedDir, stagedName, false); err != nil || !exists {
+		return
+	}
+	if err := s3a.rm(stagedDir, stagedName, true, false); err != nil {
+		glog.Errorf("discard new version of %s%s: %v", bucket, object, err)
+	}
+}
+
+// archiveCurrentVersion moves the current version into the versions folder.
+// With versioning suspended, the "null" version is replaced instead.
+func (s3a *S3ApiServer) archiveCurrentVersion(bucket, object, status string) error {
+	dir, name, versionsDir := s3a.objectPaths(bucket, object)
+
+	if status == versioningSuspended {
+		if exists, err := s3a.exists(versionsDir, nullVersionId, false); err != nil {
+			return err
+		} else if exists {
+			if err = s3a.rm(versionsDir, nullVersionId, true, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	current, err := s3a.getEntry(dir, name)
+	if err != nil || current == nil || current.IsDirectory {
+		return err
+	}
+
+	versionId := versionIdOf(current)
+	if status == versioningSuspended && versionId == nullVersionId {
+		return s3a.rm(dir, name, true, false)
+	}
+
+	versionsParent, versionsName := util.FullPath(versionsDir).DirAndName()
+	if err = s3a.mkdir(versionsParent, versionsName, nil); err != nil {
+		return err
+	}
+	return s3a.rename(dir, name, versionsDir, versionId)
+}
+
+// promoteLatestVersion makes the newest older version current, if the object has no current version.
+func (s3a *S3ApiServer) promoteLatestVersion(bucket, object string) error {
+	dir, name, versionsDir := s3a.objectPaths(bucket, object)
+	if current, err := s3a.getEntry(dir, name); err != nil || current != nil {
+		return err
+	}
+	versions, err := s3a.listVersions(bucket, object)
+	if err != nil || len(versions) == 0 {
+		return err
+	}
+	if isDeleteMarker(versions[0]) {
+		return nil
+	}
+	return s3a.rename(versionsDir, versions[0].Name, dir, name)
+}
+
+// addDeleteMarker deletes the object in a bucket with versioning, and returns the version id of the delete marker.
+func (s3a *S3ApiServer) addDeleteMarker(bucket, object, status string) (versionId string, err error) {
+	if err = s3a.archiveCurrentVersion(bucket, object, status); err != nil {
+		return "", err
+	}
+
+	versionId = newVersionId()
+	if status == versioningSuspended {
+		versionId = nullVersionId
+	}
+	_, _, versionsDir := s3a.objectPaths(bucket, object)
+
+	err = s3a.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
+		now := time.Now().Unix()
+		return filer_pb.CreateEntry(client, &filer_pb.CreateEntryRequest{
+			Directory: versionsDir,
+			Entry: &filer_pb.Entry{
+				Name: versionId,
+				Attributes: &filer_pb.FuseAttributes{
+					Mtime:    now,
+					Crtime:   now,
+					FileMode: uint32(0770),
+					Uid:      filer_pb.OS_UID,
+					Gid:      filer_pb.OS_GID,
+				},
+				Extended: map[string][]byte{
+					xhttp.AmzVersionId:    []byte(versionId),
+					xhttp.AmzDeleteMarker: []byte("true"),
+				},
+			},
+		})
+	})
+	return
+}
+
+// deleteVersion permanently deletes one version of the object.
+func (s3a *S3ApiServer) deleteVersion(bucket, object, versionId string) (wasDeleteMarker bool, err error) {
+	if !isValidVersionId(versionId) {
+		return false, fmt.Errorf("invalid version id %q", versionId)
+	}
+	dir, name, versionsDir := s3a.objectPaths(bucket, object)
+
+	current, err := s3a.getEntry(dir, name)
+	if err != nil {
+		return false, err
+	}
+	if current != nil && !current.IsDirectory && versionIdOf(current) == versionId {
+		if err = s3a.rm(dir, name, true, false); err != nil {
+			return false, err
+		}
+		return false, s3a.promoteLatestVersion(bucket, object)
+	}
+
+	version, err := s3a.getEntry(versionsDir, versionId)
+	if err != nil || version == nil {
+		return false, err
+	}
+	if err = s3a.rm(versionsDir, versionId, true, false); err != nil {
+		return false, err
+	}
+	return isDeleteMarker(version), s3a.promoteLatestVersion(bucket, object)
+}
+
+// deleteVersioned deletes one version of the object, or adds a delete marker without a version id.
+func (s3a *S3ApiServer) deleteVersioned(bucket, object, versionId, status string) (deleted ObjectIdentifier, err error) {
+	if versionId != "" {
+		deleted.VersionId = versionId
+		deleted.DeleteMarker, err = s3a.deleteVersion(bucket, object, versionId)
+		return
+	}
+	deleted.DeleteMarker = true
+	deleted.DeleteMarkerVersionId, err = s3a.addDeleteMarker(bucket, object, status)
+	return
+}
+
+// saveVersionId sets the version id of an object not written through the filer http api.
+func (s3a *S3ApiServer) saveVersionId(bucket, object, versionId string) error {
+	if versionId == "" {
+		return nil
+	}
+	dir, name, _ := s3a.objectPaths(bucket, object)
+
+	return s3a.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
+
+		resp, err := filer_pb.LookupEntry(client, &filer_pb.LookupDirectoryEntryRequest{
+			Directory: dir,
+			Name:      name,
+		})
+		if err != nil {
+			return err
+		}
+
+		if resp.Entry.Extended == nil {
+			resp.Entry.Extended = make(map[string][]byte)
+		}
+		resp.Entry.Extended[xhttp.AmzVersionId] = []byte(versionId)
+
+		return filer_pb.UpdateEntry(client, &filer_pb.UpdateEntryRequest{
+			Directory: dir,
+			Entry:     resp.Entry,
+		})
+
+	})
+}
+
+// objectUrl returns the filer url of the object, or of the version in the versionId query parameter.
+func (s3a *S3ApiServer) objectUrl(r *http.Request, bucket, object string) (string, s3err.ErrorCode) {
+	versionId := r.URL.Query().Get("versionId")
+	if versionId == "" {
+		return fmt.Sprintf("http://%s%s/%s%s", s3a.option.Filer, s3a.option.BucketsPath, bucket, object), s3err.ErrNone
+	}
+	dir, name, code := s3a.versionPath(bucket, object, versionId)
+	if code != s3err.ErrNone {
+		return "", code
+	}
+	return fmt.Sprintf("http://%s%s/%s", s3a.option.Filer, dir, name), s3err.ErrNone
+}
+
+// versionPath returns the folder and the name of one version of the object.
+func (s3a *S3ApiServer) versionPath(bucket, object, versionId string) (dir, name string, code s3err.ErrorCode) {
+	if !isValidVersionId(versionId) {
+		return "", "", s3err.ErrInvalidVersionId
+	}
+	dir, name, versionsDir := s3a.objectPaths(bucket, object)
+
+	current, err := s3a.getEntry(dir, name)
+	if err != nil {
+		glog.Errorf("versionPath %s%s: %v", bucket, object, err)
+		return "", "", s3err.ErrInternalError
+	}
+	if current != nil && !current.IsDirectory && versionIdOf(current) == versionId {
+		return dir, name, s3err.ErrNone
+	}
+
+	version, err := s3a.getEntry(versionsDir, versionId)
+	if err != nil {
+		glog.Errorf("versionPath %s%s: %v", bucket, object, err)
+		return "", "", s3err.ErrInternalError
+	}
+	if version == nil {
+		return "", "", s3err.ErrNoSuchVersion
+	}
+	if isDeleteMarker(version) {
+		return "", "", s3err.ErrMethodNotAllowed
+	}
+	return versionsDir, versionId, s3err.ErrNone
+}
+
+func (s3a *S3ApiServer) rename(oldDir, oldName, newDir, newName string) error {
+
+	return s3a.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
+
+		request := &filer_pb.AtomicRenameEntryRequest{
+			OldDirectory: oldDir,
+			OldName:      oldName,
+			NewDirectory: newDir,
+			NewName:      newName,
+		}
+
+		glog.V(1).Infof("rename %s/%s to %s/%s", oldDir, oldName, newDir, newName)
+		if _, err := client.AtomicRenameEntry(context.Background(), request); err != nil {
+			return fmt.Errorf("rename %s/%s to %s/%s: %v", oldDir, oldName, newDir, newName, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,395 @@
+package s3api
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	xhttp "github.com/chrislusf/seaweedfs/weed/s3api/http"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// memFiler serves the filer entries from memory, for the filer calls used by object versioning.
+type memFiler struct {
+	filer_pb.UnimplementedSeaweedFilerServer
+	sync.Mutex
+	entries map[util.FullPath]*filer_pb.Entry
+}
+
+func (mf *memFiler) LookupDirectoryEntry(ctx context.Context, req *filer_pb.LookupDirectoryEntryRequest) (*filer_pb.LookupDirectoryEntryResponse, error) {
+	mf.Lock()
+	defer mf.Unlock()
+	entry, found := mf.entries[util.NewFullPath(req.Directory, req.Name)]
+	if !found {
+		return nil, filer_pb.ErrNotFound
+	}
+	return &filer_pb.LookupDirectoryEntryResponse{Entry: proto.Clone(entry).(*filer_pb.Entry)}, nil
+}
+
+func (mf *memFiler) ListEntries(req *filer_pb.ListEntriesRequest, stream filer_pb.SeaweedFiler_ListEntriesServer) error {
+	mf.Lock()
+	var entries []*filer_pb.Entry
+	for p, entry := range mf.entries {
+		if dir, name := p.DirAndName(); dir == req.Directory && strings.HasPrefix(name, req.Prefix) && p != "/" {
+			if name < req.StartFromFileName || name == req.StartFromFileName && !req.InclusiveStartFrom {
+				continue
+			}
+			entries = append(entries, proto.Clone(entry).(*filer_pb.Entry))
+		}
+	}
+	mf.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	for _, entry := range entries {
+		if err := stream.Send(&filer_pb.ListEntriesResponse{Entry: entry}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mf *memFiler) CreateEntry(ctx context.Context, req *filer_pb.CreateEntryRequest) (*filer_pb.CreateEntryResponse, error) {
+	mf.Lock()
+	defer mf.Unlock()
+	mf.put(req.Directory, req.Entry)
+	return &filer_pb.CreateEntryResponse{}, nil
+}
+
+func (mf *memFiler) UpdateEntry(ctx context.Context, req *filer_pb.UpdateEntryRequest) (*filer_pb.UpdateEntryResponse, error) {
+	mf.Lock()
+	defer mf.Unlock()
+	mf.put(req.Directory, req.Entry)
+	return &filer_pb.UpdateEntryResponse{}, nil
+}
+
+func (mf *memFiler) DeleteEntry(ctx context.Context, req *filer_pb.DeleteEntryRequest) (*filer_pb.DeleteEntryResponse, error) {
+	mf.Lock()
+	defer mf.Unlock()
+	mf.move(util.NewFullPath(req.Directory, req.Name), "")
+	return &filer_pb.DeleteEntryResponse{}, nil
+}
+
+func (mf *memFiler) AtomicRenameEntry(ctx context.Context, req *filer_pb.AtomicRenameEntryRequest) (*filer_pb.AtomicRenameEntryResponse, error) {
+	mf.Lock()
+	defer mf.Unlock()
+	oldPath := util.NewFullPath(req.OldDirectory, req.OldName)
+	if _, found := mf.entries[oldPath]; !found {
+		return nil, filer_pb.ErrNotFound
+	}
+	mf.move(oldPath, util.NewFullPath(req.NewDirectory, req.NewName))
+	return &filer_pb.AtomicRenameEntryResponse{}, nil
+}
+
+// put saves the entry and creates its parent directories, as the filer does.
+func (mf *memFiler) put(dir string, entry *filer_pb.Entry) {
+	for parent := util.FullPath(dir); parent != "/"; {
+		if _, found := mf.entries[parent]; !found {
+			mf.entries[parent] = &filer_pb.Entry{Name: parent.Name(), IsDirectory: true}
+		}
+		grandParent, _ := parent.DirAndName()
+		parent = util.FullPath(grandParent)
+	}
+	mf.entries[util.NewFullPath(dir, entry.Name)] = proto.Clone(entry).(*filer_pb.Entry)
+}
+
+// move moves the entry and everything under it, or deletes them if newPath is empty.
+func (mf *memFiler) move(oldPath, newPath util.FullPath) {
+	for p, entry := range mf.entries {
+		if p != oldPath && !strings.HasPrefix(string(p), string(oldPath)+"/") {
+			continue
+		}
+		delete(mf.entries, p)
+		if newPath != "" {
+			moved := newPath + p[len(oldPath):]
+			movedDir, movedName := moved.DirAndName()
+			entry.Name = movedName
+			mf.put(movedDir, entry)
+		}
+	}
+}
+
+func newVersioningTestServer(t *testing.T, status string) (s3a *S3ApiServer, mf *memFiler, stop func()) {
+	mf = &memFiler{entries: make(map[util.FullPath]*filer_pb.Entry)}
+	mf.put("/buckets", &filer_pb.Entry{
+		Name:        "b",
+		IsDirectory: true,
+		Extended:    map[string][]byte{filer.BucketVersioningKey: []byte(status)},
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	filer_pb.RegisterSeaweedFilerServer(grpcServer, mf)
+	go grpcServer.Serve(listener)
+
+	s3a = &S3ApiServer{option: &S3ApiServerOption{
+		FilerGrpcAddress: listener.Addr().String(),
+		BucketsPath:      "/buckets",
+		GrpcDialOption:   grpc.WithInsecure(),
+	}}
+	return s3a, mf, grpcServer.Stop
+}
+
+// putTestObject writes the object as the filer does for a PUT through the s3 gateway.
+func putTestObject(t *testing.T, s3a *S3ApiServer, mf *memFiler, object, content string) string {
+	target, versionId := writeTestVersion(t, s3a, mf, object, content)
+	if err := s3a.commitNewVersion("b", object, target, versionId); err != nil {
+		t.Fatalf("commit new version of %s: %v", object, err)
+	}
+	return versionId
+}
+
+// writeTestVersion writes a new version of the object, without putting it in place.
+func writeTestVersion(t *testing.T, s3a *S3ApiServer, mf *memFiler, object, content string) (target, versionId string) {
+	target, versionId, err := s3a.newVersionTarget("b", object)
+	if err != nil {
+		t.Fatalf("new version of %s: %v", object, err)
+	}
+	dir, name, _ := s3a.objectPaths("b", target)
+	entry := &filer_pb.Entry{
+		Name:       name,
+		Attributes: &filer_pb.FuseAttributes{FileSize: uint64(len(content)), Mime: content},
+	}
+	if versionId != "" {
+		entry.Extended = map[string][]byte{xhttp.AmzVersionId: []byte(versionId)}
+	}
+	mf.Lock()
+	mf.put(dir, entry)
+	mf.Unlock()
+	return target, versionId
+}
+
+// describeVersions lists the versions of the object, newest first, as content or "marker",
+// with "*" after the current version.
+func describeVersions(t *testing.T, s3a *S3ApiServer, object string) string {
+	versions, err := s3a.listVersions("b", object)
+	if err != nil {
+		t.Fatalf("list versions of %s: %v", object, err)
+	}
+	dir, name, _ := s3a.objectPaths("b", object)
+	current, _ := s3a.getEntry(dir, name)
+	var described []string
+	for _, version := range versions {
+		d := version.Attributes.GetMime()
+		if isDeleteMarker(version) {
+			d = "marker"
+		}
+		if current != nil && versionIdOf(version) == versionIdOf(current) && version.Name == current.Name {
+			d += "*"
+		}
+		described = append(described, d)
+	}
+	return strings.Join(described, " ")
+}
+
+func TestObjectVersioning(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		status string
+		steps  func(t *testing.T, s3a *S3ApiServer, mf *memFiler)
+		want   string
+	}{
+		{
+			name:   "overwrite archives the current version",
+			status: versioningEnabled,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				putTestObject(t, s3a, mf, "/a/o", "v2")
+			},
+			want: "v2* v1",
+		},
+		{
+			name:   "delete adds a delete marker",
+			status: versioningEnabled,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				if _, err := s3a.deleteVersioned("b", "/a/o", "", versioningEnabled); err != nil {
+					t.Fatalf("delete: %v", err)
+				}
+			},
+			want: "marker v1",
+		},
+		{
+			name:   "deleting the delete marker promotes the previous version",
+			status: versioningEnabled,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				deleted, err := s3a.deleteVersioned("b", "/a/o", "", versioningEnabled)
+				if err != nil {
+					t.Fatalf("delete: %v", err)
+				}
+				if deleted, err = s3a.deleteVersioned("b", "/a/o", deleted.DeleteMarkerVersionId, versioningEnabled); err != nil || !deleted.DeleteMarker {
+					t.Fatalf("delete the delete marker: %+v %v", deleted, err)
+				}
+			},
+			want: "v1*",
+		},
+		{
+			name:   "deleting the current version promotes the previous version",
+			status: versioningEnabled,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				v2 := putTestObject(t, s3a, mf, "/a/o", "v2")
+				putTestObject(t, s3a, mf, "/a/o", "v3")
+				if _, err := s3a.deleteVersioned("b", "/a/o", v2, versioningEnabled); err != nil {
+					t.Fatalf("delete v2: %v", err)
+				}
+				dir, name, _ := s3a.objectPaths("b", "/a/o")
+				current, _ := s3a.getEntry(dir, name)
+				if _, err := s3a.deleteVersioned("b", "/a/o", versionIdOf(current), versioningEnabled); err != nil {
+					t.Fatalf("delete v3: %v", err)
+				}
+			},
+			want: "v1*",
+		},
+		{
+			name:   "the current version stays until the new version is committed",
+			status: versioningEnabled,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				writeTestVersion(t, s3a, mf, "/a/o", "v2")
+			},
+			want: "v1*",
+		},
+		{
+			name:   "a failed write keeps the current version",
+			status: versioningEnabled,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				target, _ := writeTestVersion(t, s3a, mf, "/a/o", "v2")
+				s3a.discardNewVersion("b", "/a/o", target)
+				putTestObject(t, s3a, mf, "/a/o", "v3")
+			},
+			want: "v3* v1",
+		},
+		{
+			name:   "suspended versioning replaces the null version",
+			status: versioningSuspended,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				putTestObject(t, s3a, mf, "/a/o", "v2")
+			},
+			want: "v2*",
+		},
+		{
+			name:   "suspended versioning keeps the versions written while enabled",
+			status: versioningEnabled,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				if err := s3a.setVersioning("b", versioningSuspended); err != nil {
+					t.Fatalf("suspend versioning: %v", err)
+				}
+				putTestObject(t, s3a, mf, "/a/o", "null1")
+				putTestObject(t, s3a, mf, "/a/o", "null2")
+			},
+			want: "null2* v1",
+		},
+		{
+			name:   "suspended delete replaces the null version with a delete marker",
+			status: versioningSuspended,
+			steps: func(t *testing.T, s3a *S3ApiServer, mf *memFiler) {
+				putTestObject(t, s3a, mf, "/a/o", "v1")
+				deleted, err := s3a.deleteVersioned("b", "/a/o", "", versioningSuspended)
+				if err != nil || deleted.DeleteMarkerVersionId != nullVersionId {
+					t.Fatalf("delete: %+v %v", deleted, err)
+				}
+			},
+			want: "marker",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s3a, mf, stop := newVersioningTestServer(t, tt.status)
+			defer stop()
+			tt.steps(t, s3a, mf)
+			if got := describeVersions(t, s3a, "/a/o"); got != tt.want {
+				t.Errorf("versions: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+}
+
+func TestReservedObjectKeys(t *testing.T) {
+	for key, want := range map[string]bool{
+		"/.versions":     true,
+		"/.versions/a/o": true,
+		".versions/a":    true,
+		"/.versionsx":    false,
+		"/a/.versions/o": false,
+		"/.versions.txt": false,
+		"/":              false,
+	} {
+		if got := isReservedObjectKey(key); got != want {
+			t.Errorf("isReservedObjectKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestWalkVersionedKeysFromMarker(t *testing.T) {
+	s3a, mf, stop := newVersioningTestServer(t, versioningEnabled)
+	defer stop()
+	for _, key := range []string{"/a", "/a-b", "/a/c", "/ab", "/b/c/d", "/c"} {
+		putTestObject(t, s3a, mf, key, "v1")
+		putTestObject(t, s3a, mf, key, "v2")
+	}
+	writeTestVersion(t, s3a, mf, "/d", "v1")
+
+	for _, tt := range []struct {
+		keyMarker string
+		want      string
+	}{
+		{"", "a a-b a/c ab b/c/d c"},
+		{"a", "a-b a/c ab b/c/d c"},
+		{"a-b", "a/c ab b/c/d c"},
+		{"a/c", "ab b/c/d c"},
+		{"b/c", "b/c/d c"},
+		{"b/c/d", "c"},
+		{"c", ""},
+	} {
+		var keys []string
+		startFrom := versionedKeysStartFrom("", tt.keyMarker)
+		err := s3a.walkVersionedKeys(s3a.versionsRoot("b"), "", "", tt.keyMarker, func(key string) {
+			if key < startFrom {
+				t.Errorf("walk from %q visited %q", tt.keyMarker, key)
+			}
+			if key > tt.keyMarker {
+				keys = append(keys, key)
+			}
+		})
+		if err != nil {
+			t.Fatalf("walk from %q: %v", tt.keyMarker, err)
+		}
+		sort.Strings(keys)
+		if got := strings.Join(keys, " "); got != tt.want {
+			t.Errorf("walk from %q: got %q, want %q", tt.keyMarker, got, tt.want)
+		}
+	}
+}
+
+func TestValidVersionIds(t *testing.T) {
+	for versionId, want := range map[string]bool{
+		newVersionId():      true,
+		nullVersionId:       true,
+		"../x":              false,
+		"../../a/o":         false,
+		"7fffffffffffffff/": false,
+		"7FFFFFFFFFFFFFFF":  false,
+		"":                  false,
+	} {
+		if got := isValidVersionId(versionId); got != want {
+			t.Errorf("isValidVersionId(%q) = %v, want %v", versionId, got, want)
+		}
+	}
+}
@@ -31,6 +31,7 @@ type ListBucketResultV2 struct {
 	NextContinuationToken string        `xml:"NextContinuationToken,omitempty"`
 	KeyCount              int           `xml:"KeyCount"`
 	StartAfter            string        `xml:"StartAfter,omitempty"`
+	EncodingType          string        `xml:"EncodingType,omitempty"`
 }
 
 func (s3a *S3ApiServer) ListObjectsV2Handler(w http.ResponseWriter, r *http.Request) {
@@ -41,6 +42,7 @@ func (s3a *S3ApiServer) ListObjectsV2Handler(w http.ResponseWriter, r *http.Requ
 	bucket, _ := getBucketAndObject(r)
 
 	originalPrefix, continuationToken, startAfter, delimiter, _, maxKeys := getListObjectsV2Args(r.URL.Query())
+	encodingType := r.URL.Query().Get("encoding-type")
 
 	if maxKeys < 0 {
 		writeErrorResponse(w, s3err.ErrInvalidMaxKeys, r.URL)
@@ -50,9 +52,14 @@ func (s3a *S3ApiServer) ListObjectsV2Handler(w http.ResponseWriter, r *http.Requ
 		writeErrorResponse(w, s3err.ErrNotImplemented, r.URL)
 		return
 	}
+	if encodingType != "" && encodingType != "url" {
+		writeErrorResponse(w, s3err.ErrInvalidEncodingMethod, r.URL)
+		return
+	}
 
+	// both are keys to list after, the continuation token is never before start-after
 	marker := continuationToken
-	if continuationToken == "" {
+	if startAfter > marker {
 		marker = startAfter
 	}
 
@@ -70,12 +77,15 @@ func (s3a *S3ApiServer) ListObjectsV2Handler(w http.ResponseWriter, r *http.Requ
 		ContinuationToken:     continuationToken,
 		Delimiter:             response.Delimiter,
 		IsTruncated:           response.IsTruncated,
-		KeyCount:              len(response.Contents),
+		KeyCount:              len(response.Contents) + len(response.CommonPrefixes),
 		MaxKeys:               response.MaxKeys,
 		NextContinuationToken: response.NextMarker,
 		Prefix:                response.Prefix,
 		StartAfter:            startAfter,
 	}
+	if encodingType == "url" {
+		urlEncodeListResult(responseV2)
+	}
 
 	writeSuccessResponseXML(w, encodeResponse(responseV2))
 }
@@ -114,6 +124,7 @@ func (s3a *S3ApiServer) listFilerEntries(bucket string, originalPrefix string, m
 	if strings.HasPrefix(reqDir, "/") {
 		reqDir = reqDir[1:]
 	}
+	keyDir := reqDir
 	bucketPrefix := fmt.Sprintf("%s/%s/", s3a.option.BucketsPath, bucket)
 	reqDir = fmt.Sprintf("%s%s", bucketPrefix, reqDir)
 	if strings.HasSuffix(reqDir, "/") {
@@ -121,6 +132,20 @@ func (s3a *S3ApiServer) listFilerEntries(bucket string, originalPrefix string, m
 		reqDir = reqDir[:len(reqDir)-1]
 	}
 
+	response = ListBucketResult{
+		Name:      bucket,
+		Prefix:    originalPrefix,
+		Marker:    marker,
+		MaxKeys:   maxKeys,
+		Delimiter: delimiter,
+	}
+
+	// the marker is a full key, while doListFilerEntries starts from reqDir
+	dirMarker, isPastDir := markerUnderDir(keyDir, marker)
+	if isPastDir || isReservedObjectKey(keyDir) {
+		return
+	}
+
 	var contents []ListEntry
 	var commonPrefixes []PrefixEntry
 	var isTruncated bool
@@ -130,7 +155,7 @@ func (s3a *S3ApiServer) listFilerEntries(bucket string, originalPrefix string, m
 	// check filer
 	err = s3a.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
 
-		_, isTruncated, nextMarker, doErr = s3a.doListFilerEntries(client, reqDir, prefix, maxKeys, marker, delimiter, func(dir string, entry *filer_pb.Entry) {
+		_, isTruncated, nextMarker, doErr = s3a.doListFilerEntries(client, reqDir, prefix, maxKeys, dirMarker, delimiter, func(dir string, entry *filer_pb.Entry) {
 			if entry.IsDirectory {
 				if delimiter == "/" {
 					commonPrefixes = append(commonPrefixes, PrefixEntry{
@@ -159,21 +184,12 @@ func (s3a *S3ApiServer) listFilerEntries(bucket string, originalPrefix string, m
 			return doErr
 		}
 
-		if !isTruncated {
-			nextMarker = ""
-		}
-
-		response = ListBucketResult{
-			Name:           bucket,
-			Prefix:         originalPrefix,
-			Marker:         marker,
-			NextMarker:     nextMarker,
-			MaxKeys:        maxKeys,
-			Delimiter:      delimiter,
-			IsTruncated:    isTruncated,
-			Contents:       contents,
-			CommonPrefixes: commonPrefixes,
+		if isTruncated {
+			response.NextMarker = keyDir + nextMarker
 		}
+		response.IsTruncated = isTruncated
+		response.Contents = contents
+		response.CommonPrefixes = commonPrefixes
 
 		return nil
 	})
@@ -197,23 +213,32 @@ func (s3a *S3ApiServer) doListFilerEntries(client filer_pb.SeaweedFilerClient, d
 		sepIndex := strings.Index(marker, "/")
 		subDir, subMarker := marker[0:sepIndex], marker[sepIndex+1:]
 		// println("doListFilerEntries dir", dir+"/"+subDir, "subMarker", subMarker, "maxKeys", maxKeys)
-		subCounter, _, subNextMarker, subErr := s3a.doListFilerEntries(client, dir+"/"+subDir, "", maxKeys, subMarker, delimiter, eachEntryFn)
+		subCounter, subIsTruncated, subNextMarker, subErr := s3a.doListFilerEntries(client, dir+"/"+subDir, "", maxKeys, subMarker, delimiter, eachEntryFn)
 		if subErr != nil {
 			err = subErr
 			return
 		}
-		maxKeys -= subCounter
-		nextMarker = subDir + "/" + subNextMarker
 		counter += subCounter
+		nextMarker = subDir + "/" + subNextMarker
+		if subIsTruncated {
+			isTruncated = true
+			return
+		}
 		// finished processing this sub directory
 		marker = subDir
 	}
 
 	// now marker is also a direct child of dir
+	isBucketRoot := filepath.Dir(dir) == s3a.option.BucketsPath
+	// one more entry to tell whether truncated, and room for the skipped folders at the bucket root
+	limit := maxKeys - counter + 1
+	if isBucketRoot {
+		limit += 2
+	}
 	request := &filer_pb.ListEntriesRequest{
 		Directory:          dir,
 		Prefix:             prefix,
-		Limit:              uint32(maxKeys + 1),
+		Limit:              uint32(limit),
 		StartFromFileName:  marker,
 		InclusiveStartFrom: false,
 	}
@@ -236,33 +261,34 @@ func (s3a *S3ApiServer) doListFilerEntries(client filer_pb.SeaweedFilerClient, d
 				return
 			}
 		}
+		entry := resp.Entry
+		if entry.IsDirectory && isBucketRoot && (entry.Name == ".uploads" || entry.Name == versionsFolder) {
+			continue
+		}
 		if counter >= maxKeys {
 			isTruncated = true
 			return
 		}
-		entry := resp.Entry
 		nextMarker = entry.Name
 		if entry.IsDirectory {
 			// println("ListEntries", dir, "dir:", entry.Name)
-			if entry.Name != ".uploads" { // FIXME no need to apply to all directories. this extra also affects maxKeys
-				eachEntryFn(dir, entry)
-				if delimiter != "/" {
-					// println("doListFilerEntries2 dir", dir+"/"+entry.Name, "maxKeys", maxKeys-counter)
-					subCounter, subIsTruncated, subNextMarker, subErr := s3a.doListFilerEntries(client, dir+"/"+entry.Name, "", maxKeys-counter, "", delimiter, eachEntryFn)
-					if subErr != nil {
-						err = fmt.Errorf("doListFilerEntries2: %v", subErr)
-						return
-					}
-					// println("doListFilerEntries2 dir", dir+"/"+entry.Name, "maxKeys", maxKeys-counter, "subCounter", subCounter, "subNextMarker", subNextMarker, "subIsTruncated", subIsTruncated)
-					counter += subCounter
-					nextMarker = entry.Name + "/" + subNextMarker
-					if subIsTruncated {
-						isTruncated = true
-						return
-					}
-				} else {
-					counter++
+			eachEntryFn(dir, entry)
+			if delimiter != "/" {
+				// println("doListFilerEntries2 dir", dir+"/"+entry.Name, "maxKeys", maxKeys-counter)
+				subCounter, subIsTruncated, subNextMarker, subErr := s3a.doListFilerEntries(client, dir+"/"+entry.Name, "", maxKeys-counter, "", delimiter, eachEntryFn)
+				if subErr != nil {
+					err = fmt.Errorf("doListFilerEntries2: %v", subErr)
+					return
 				}
+				// println("doListFilerEntries2 dir", dir+"/"+entry.Name, "maxKeys", maxKeys-counter, "subCounter", subCounter, "subNextMarker", subNextMarker, "subIsTruncated", subIsTruncated)
+				counter += subCounter
+				nextMarker = entry.Name + "/" + subNextMarker
+				if subIsTruncated {
+					isTruncated = true
+					return
+				}
+			} else {
+				counter++
 			}
 		} else {
 			// println("ListEntries", dir, "file:", entry.Name)
@@ -273,6 +299,35 @@ func (s3a *S3ApiServer) doListFilerEntries(client filer_pb.SeaweedFilerClient, d
 	return
 }
 
+// markerUnderDir converts the marker key to be relative to the key directory, isPastDir if all keys there are before the marker.
+func markerUnderDir(keyDir, marker string) (dirMarker string, isPastDir bool) {
+	if keyDir == "" || strings.HasPrefix(marker, keyDir) {
+		return marker[len(keyDir):], false
+	}
+	if marker < keyDir {
+		return "", false
+	}
+	return "", true
+}
+
+// urlEncodeListResult encodes the keys for encoding-type=url, so keys with characters invalid in xml can be listed.
+func urlEncodeListResult(response *ListBucketResultV2) {
+	response.EncodingType = "url"
+	response.Prefix = urlEncodeKey(response.Prefix)
+	response.Delimiter = urlEncodeKey(response.Delimiter)
+	response.StartAfter = urlEncodeKey(response.StartAfter)
+	for i := range response.Contents {
+		response.Contents[i].Key = urlEncodeKey(response.Contents[i].Key)
+	}
+	for i := range response.CommonPrefixes {
+		response.CommonPrefixes[i].Prefix = urlEncodeKey(response.CommonPrefixes[i].Prefix)
+	}
+}
+
+func urlEncodeKey(key string) string {
+	return strings.Replace(url.QueryEscape(key), "%2F", "/", -1)
+}
+
 func getListObjectsV2Args(values url.Values) (prefix, token, startAfter, delimiter string, fetchOwner bool, maxkeys int) {
 	prefix = values.Get("prefix")
 	token = values.Get("continuation-token")
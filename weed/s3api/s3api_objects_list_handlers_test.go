@@ -36,3 +36,32 @@ func TestListObjectsHandler(t *testing.T) {
 		t.Errorf("unexpected output: %s\nexpecting:%s", encoded, expected)
 	}
 }
+
+func TestMarkerUnderDir(t *testing.T) {
+
+	tests := []struct {
+		keyDir, marker string
+		dirMarker      string
+		isPastDir      bool
+	}{
+		{"", "a/b", "a/b", false},
+		{"a/", "", "", false},
+		{"a/", "a/x", "x", false},
+		{"a/", "a/x/y", "x/y", false},
+		{"a/", "a", "", false},
+		{"a/", "Z", "", false},
+		{"a/", "a0", "", true},
+		{"a/", "b/x", "", true},
+	}
+
+	for i, test := range tests {
+		dirMarker, isPastDir := markerUnderDir(test.keyDir, test.marker)
+		if dirMarker != test.dirMarker || isPastDir != test.isPastDir {
+			t.Errorf("test %d: expected %q %v, got %q %v", i, test.dirMarker, test.isPastDir, dirMarker, isPastDir)
+		}
+	}
+
+	if encoded := urlEncodeKey("a b/c+d&\x01"); encoded != "a+b/c%2Bd%26%01" {
+		t.Errorf("unexpected encoded key: %s", encoded)
+	}
+}
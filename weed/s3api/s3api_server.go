@@ -38,6 +38,10 @@ func NewS3ApiServer(router *mux.Router, option *S3ApiServerOption) (s3ApiServer
 func (s3a *S3ApiServer) registerRouter(router *mux.Router) {
 	// API Router
 	apiRouter := router.PathPrefix("/").Subrouter()
+
+	// CORS preflight, not authenticated since browsers send no credentials with it
+	apiRouter.Methods("OPTIONS").HandlerFunc(track(s3a.OptionsHandler, "OPTIONS"))
+
 	var routers []*mux.Router
 	if s3a.option.DomainName != "" {
 		domainNames := strings.Split(s3a.option.DomainName, ",")
@@ -52,6 +56,8 @@ func (s3a *S3ApiServer) registerRouter(router *mux.Router) {
 
 	for _, bucket := range routers {
 
+		bucket.Use(rejectReservedObjectKeys)
+
 		// HeadObject
 		bucket.Methods("HEAD").Path("/{object:.+}").HandlerFunc(track(s3a.iam.Auth(s3a.HeadObjectHandler, ACTION_READ), "GET"))
 		// HeadBucket
@@ -79,6 +85,27 @@ func (s3a *S3ApiServer) registerRouter(router *mux.Router) {
 		// DeleteObjectTagging
 		bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(track(s3a.iam.Auth(s3a.DeleteObjectTaggingHandler, ACTION_TAGGING), "DELETE")).Queries("tagging", "")
 
+		// GetBucketVersioning
+		bucket.Methods("GET").HandlerFunc(track(s3a.iam.Auth(s3a.GetBucketVersioningHandler, ACTION_READ), "GET")).Queries("versioning", "")
+		// PutBucketVersioning
+		bucket.Methods("PUT").HandlerFunc(track(s3a.iam.Auth(s3a.PutBucketVersioningHandler, ACTION_ADMIN), "PUT")).Queries("versioning", "")
+		// ListObjectVersions
+		bucket.Methods("GET").HandlerFunc(track(s3a.iam.Auth(s3a.ListObjectVersionsHandler, ACTION_LIST), "LIST")).Queries("versions", "")
+
+		// GetBucketLifecycleConfiguration
+		bucket.Methods("GET").HandlerFunc(track(s3a.iam.Auth(s3a.GetBucketLifecycleConfigurationHandler, ACTION_READ), "GET")).Queries("lifecycle", "")
+		// PutBucketLifecycleConfiguration
+		bucket.Methods("PUT").HandlerFunc(track(s3a.iam.Auth(s3a.PutBucketLifecycleConfigurationHandler, ACTION_ADMIN), "PUT")).Queries("lifecycle", "")
+		// DeleteBucketLifecycle
+		bucket.Methods("DELETE").HandlerFunc(track(s3a.iam.Auth(s3a.DeleteBucketLifecycleHandler, ACTION_ADMIN), "DELETE")).Queries("lifecycle", "")
+
+		// GetBucketTagging
+		bucket.Methods("GET").HandlerFunc(track(s3a.iam.Auth(s3a.GetBucketTaggingHandler, ACTION_READ), "GET")).Queries("tagging", "")
+		// PutBucketTagging
+		bucket.Methods("PUT").HandlerFunc(track(s3a.iam.Auth(s3a.PutBucketTaggingHandler, ACTION_TAGGING), "PUT")).Queries("tagging", "")
+		// DeleteBucketTagging
+		bucket.Methods("DELETE").HandlerFunc(track(s3a.iam.Auth(s3a.DeleteBucketTaggingHandler, ACTION_TAGGING), "DELETE")).Queries("tagging", "")
+
 		// CopyObject
 		bucket.Methods("PUT").Path("/{object:.+}").HeadersRegexp("X-Amz-Copy-Source", ".*?(\\/|%2F).*?").HandlerFunc(track(s3a.iam.Auth(s3a.CopyObjectHandler, ACTION_WRITE), "COPY"))
 		// PutObject
@@ -50,18 +50,26 @@ const (
 	ErrBucketAlreadyOwnedByYou
 	ErrNoSuchBucket
 	ErrNoSuchKey
+	ErrNoSuchVersion
+	ErrInvalidVersionId
 	ErrNoSuchUpload
+	ErrNoSuchLifecycleConfiguration
 	ErrInvalidBucketName
 	ErrInvalidDigest
 	ErrInvalidMaxKeys
+	ErrInvalidEncodingMethod
 	ErrInvalidMaxUploads
 	ErrInvalidMaxParts
 	ErrInvalidPartNumberMarker
 	ErrInvalidPart
+	ErrInvalidPartOrder
 	ErrInternalError
 	ErrInvalidCopyDest
 	ErrInvalidCopySource
 	ErrInvalidTag
+	ErrIllegalVersioningConfiguration
+	ErrReservedObjectKey
+	ErrVersionedLifecycleExpiration
 	ErrAuthHeaderEmpty
 	ErrSignatureVersionNotSupported
 	ErrMalformedPOSTRequest
@@ -144,6 +152,11 @@ var errorCodeResponse = map[ErrorCode]APIError{
 		Description:    "Argument maxKeys must be an integer between 0 and 2147483647",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	ErrInvalidEncodingMethod: {
+		Code:           "InvalidArgument",
+		Description:    "Invalid Encoding Method specified in Request",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 	ErrInvalidMaxParts: {
 		Code:           "InvalidArgument",
 		Description:    "Argument max-parts must be an integer between 0 and 2147483647",
@@ -164,6 +177,21 @@ var errorCodeResponse = map[ErrorCode]APIError{
 		Description:    "The specified key does not exist.",
 		HTTPStatusCode: http.StatusNotFound,
 	},
+	ErrNoSuchVersion: {
+		Code:           "NoSuchVersion",
+		Description:    "The specified version does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidVersionId: {
+		Code:           "InvalidArgument",
+		Description:    "Invalid version id specified",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchLifecycleConfiguration: {
+		Code:           "NoSuchLifecycleConfiguration",
+		Description:    "The lifecycle configuration does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
 	ErrNoSuchUpload: {
 		Code:           "NoSuchUpload",
 		Description:    "The specified multipart upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed.",
@@ -181,6 +209,28 @@ var errorCodeResponse = map[ErrorCode]APIError{
 		HTTPStatusCode: http.StatusBadRequest,
 	},
 
+	ErrIllegalVersioningConfiguration: {
+		Code:           "IllegalVersioningConfigurationException",
+		Description:    "The versioning configuration specified in the request is invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrReservedObjectKey: {
+		Code:           "InvalidArgument",
+		Description:    "The .versions folder at the bucket root is reserved for the older object versions.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrVersionedLifecycleExpiration: {
+		Code:           "InvalidRequest",
+		Description:    "Lifecycle expiration rules are not supported on buckets with versioning.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+
+	ErrInvalidPartOrder: {
+		Code:           "InvalidPartOrder",
+		Description:    "The list of parts was not in ascending order. The parts list must be specified in order by part number.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+
 	ErrInvalidCopyDest: {
 		Code:           "InvalidRequest",
 		Description:    "This copy request is illegal because it is trying to copy an object to itself without changing the object's metadata, storage class, website redirect location or encryption attributes.",
@@ -29,6 +29,7 @@ func (r *StatusRecorder) Flush() {
 func track(f http.HandlerFunc, action string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Server", "SeaweedFS S3 "+util.VERSION)
+		setCorsHeaders(w, r)
 		recorder := NewStatusResponseWriter(w)
 		start := time.Now()
 		f(recorder, r)
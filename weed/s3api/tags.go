@@ -2,6 +2,9 @@ package s3api
 
 import (
 	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
 )
 
 type Tag struct {
@@ -34,5 +37,39 @@ func FromTags(tags map[string]string) (t *Tagging) {
 			Value: v,
 		})
 	}
+	sort.Slice(t.TagSet.Tag, func(i, j int) bool {
+		return t.TagSet.Tag[i].Key < t.TagSet.Tag[j].Key
+	})
 	return
 }
+
+// parseTagsHeader parses the url query encoded tags in the x-amz-tagging header.
+func parseTagsHeader(header string) (map[string]string, error) {
+	values, err := url.ParseQuery(header)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	for k, v := range values {
+		if len(v) > 1 {
+			return nil, fmt.Errorf("tag key %s repeated", k)
+		}
+		tags[k] = v[0]
+	}
+	return tags, validateTags(tags)
+}
+
+func validateTags(tags map[string]string) error {
+	if len(tags) > 10 {
+		return fmt.Errorf("%d tags more than 10", len(tags))
+	}
+	for k, v := range tags {
+		if len(k) > 128 {
+			return fmt.Errorf("tag key %s longer than 128", k)
+		}
+		if len(v) > 256 {
+			return fmt.Errorf("tag value %s longer than 256", v)
+		}
+	}
+	return nil
+}
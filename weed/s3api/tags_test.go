@@ -48,3 +48,15 @@ func TestXMLMarshall(t *testing.T) {
 	assert.Equal(t, expected, actual)
 
 }
+
+func TestParseTagsHeader(t *testing.T) {
+	tags, err := parseTagsHeader("key1=value1&key2=with%20space")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]string{"key1": "value1", "key2": "with space"}, tags)
+
+	_, err = parseTagsHeader("key1=a&key1=b")
+	assert.NotEqual(t, nil, err)
+
+	_, err = parseTagsHeader("k1=1&k2=2&k3=3&k4=4&k5=5&k6=6&k7=7&k8=8&k9=9&k10=10&k11=11")
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,62 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrCdnTokenMissing = errors.New("missing cdn token")
+	ErrCdnTokenExpired = errors.New("cdn token expired")
+	ErrCdnTokenInvalid = errors.New("invalid cdn token")
+)
+
+/*
+CDN signed urls let a volume server act as the origin of a CDN.
+
+The CDN's origin signing service appends two query parameters to each url:
+1. "expires", the Unix time in seconds after which the url is rejected
+2. "token", the hex encoded HMAC-SHA256 of "<fileId>:<expires>" keyed by the shared secret
+
+The fileId is the "<volumeId>,<needleIdAndCookie>" part of the url path.
+*/
+
+func GenCdnToken(secret string, fileId string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", fileId, expiresAt)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifyCdnToken(r *http.Request, secret string, fileId string) error {
+
+	tokenStr := r.URL.Query().Get("token")
+	expiresStr := r.URL.Query().Get("expires")
+	if tokenStr == "" || expiresStr == "" {
+		return ErrCdnTokenMissing
+	}
+
+	expiresAt, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil {
+		return ErrCdnTokenInvalid
+	}
+	if time.Now().Unix() > expiresAt {
+		return ErrCdnTokenExpired
+	}
+
+	token, err := hex.DecodeString(tokenStr)
+	if err != nil {
+		return ErrCdnTokenInvalid
+	}
+	expected, _ := hex.DecodeString(GenCdnToken(secret, fileId, expiresAt))
+	if !hmac.Equal(token, expected) {
+		return ErrCdnTokenInvalid
+	}
+
+	return nil
+}
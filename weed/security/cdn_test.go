@@ -0,0 +1,37 @@
+package security
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerifyCdnToken(t *testing.T) {
+
+	secret, fileId := "origin-secret", "3,01637037d6"
+	expiresAt := time.Now().Add(time.Minute).Unix()
+	token := GenCdnToken(secret, fileId, expiresAt)
+
+	r := httptest.NewRequest("GET", fmt.Sprintf("/%s?token=%s&expires=%d", fileId, token, expiresAt), nil)
+	if err := VerifyCdnToken(r, secret, fileId); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+	if err := VerifyCdnToken(r, secret, "3,01637037d7"); err != ErrCdnTokenInvalid {
+		t.Errorf("token for another file: %v", err)
+	}
+	if err := VerifyCdnToken(r, "another-secret", fileId); err != ErrCdnTokenInvalid {
+		t.Errorf("token with another secret: %v", err)
+	}
+
+	expiredAt := time.Now().Add(-time.Minute).Unix()
+	r = httptest.NewRequest("GET", fmt.Sprintf("/%s?token=%s&expires=%d", fileId, GenCdnToken(secret, fileId, expiredAt), expiredAt), nil)
+	if err := VerifyCdnToken(r, secret, fileId); err != ErrCdnTokenExpired {
+		t.Errorf("expired token: %v", err)
+	}
+
+	r = httptest.NewRequest("GET", "/"+fileId, nil)
+	if err := VerifyCdnToken(r, secret, fileId); err != ErrCdnTokenMissing {
+		t.Errorf("missing token: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package kms
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// KeyManager provides the keys to encrypt the volume data at rest. Each key has an id, which is
+// kept with the encrypted data, so the data encrypted with older keys can still be read after
+// the current key is rotated.
+type KeyManager interface {
+	// GetName gets the name to locate the configuration in security.toml file
+	GetName() string
+	// Initialize initializes the key manager
+	Initialize(configuration util.Configuration, prefix string) error
+	// CurrentKeyId is the id of the key to encrypt new data with
+	CurrentKeyId() string
+	// GetKey returns the 32 bytes AES-256 key of the key id
+	GetKey(keyId string) (util.CipherKey, error)
+}
+
+var (
+	KeyManagers []KeyManager
+
+	// Keys is the enabled key manager, nil if encryption at rest is not configured
+	Keys KeyManager
+)
+
+func LoadConfiguration(config *viper.Viper, prefix string) {
+
+	if config == nil {
+		return
+	}
+
+	for _, keyManager := range KeyManagers {
+		if config.GetBool(prefix + keyManager.GetName() + ".enabled") {
+			if err := keyManager.Initialize(config, prefix+keyManager.GetName()+"."); err != nil {
+				glog.Fatalf("Failed to initialize key manager %s: %+v", keyManager.GetName(), err)
+			}
+			Keys = keyManager
+			glog.V(0).Infof("Configure encryption at rest with key manager %s, current key %s", keyManager.GetName(), keyManager.CurrentKeyId())
+			return
+		}
+	}
+
+}
+
+// CurrentKeyId returns the id of the key to encrypt new data with, or empty if not configured.
+func CurrentKeyId() string {
+	if Keys == nil {
+		return ""
+	}
+	return Keys.CurrentKeyId()
+}
+
+// GetKey returns the key of the key id from the configured key manager.
+func GetKey(keyId string) (util.CipherKey, error) {
+	if Keys == nil {
+		return nil, fmt.Errorf("key %s: no key manager is configured in security.toml", keyId)
+	}
+	key, err := Keys.GetKey(keyId)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %v", keyId, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,65 @@
+package kms
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func init() {
+	KeyManagers = append(KeyManagers, &LocalKeyManager{})
+}
+
+// LocalKeyManager reads the keys from security.toml, as a list of "<key id>:<base64 encoded key>".
+// The first key is the current key.
+type LocalKeyManager struct {
+	currentKeyId string
+	keys         map[string]util.CipherKey
+}
+
+func (m *LocalKeyManager) GetName() string {
+	return "local"
+}
+
+func (m *LocalKeyManager) Initialize(configuration util.Configuration, prefix string) error {
+	m.currentKeyId = ""
+	m.keys = make(map[string]util.CipherKey)
+	for _, entry := range configuration.GetStringSlice(prefix + "keys") {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("key %q should be <key id>:<base64 encoded key>", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return fmt.Errorf("key %s: %v", parts[0], err)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("key %s has %d bytes, should be 32 bytes for AES-256", parts[0], len(key))
+		}
+		if _, found := m.keys[parts[0]]; found {
+			return fmt.Errorf("duplicated key %s", parts[0])
+		}
+		m.keys[parts[0]] = key
+		if m.currentKeyId == "" {
+			m.currentKeyId = parts[0]
+		}
+	}
+	if m.currentKeyId == "" {
+		return fmt.Errorf("no keys in %skeys", prefix)
+	}
+	return nil
+}
+
+func (m *LocalKeyManager) CurrentKeyId() string {
+	return m.currentKeyId
+}
+
+func (m *LocalKeyManager) GetKey(keyId string) (util.CipherKey, error) {
+	key, found := m.keys[keyId]
+	if !found {
+		return nil, fmt.Errorf("not found in the local keys")
+	}
+	return key, nil
+}
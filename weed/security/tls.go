@@ -3,7 +3,9 @@ package security
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/spf13/viper"
 
@@ -13,6 +15,61 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/glog"
 )
 
+// the TLS versions and cipher suites accepted by the grpc and https servers
+var (
+	ServerTlsMinVersion   uint16 = tls.VersionTLS12
+	ServerTlsCipherSuites []uint16
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// SetServerTlsOptions parses the minimum TLS version, e.g. "1.2", and an optional
+// comma separated list of cipher suite names, e.g. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+// The cipher suites only apply to TLS 1.2 and below; TLS 1.3 suites are not configurable.
+func SetServerTlsOptions(minVersion, cipherSuites string) error {
+	version, found := tlsVersions[minVersion]
+	if !found {
+		return fmt.Errorf("unsupported tls version %q, expect 1.0, 1.1, 1.2, or 1.3", minVersion)
+	}
+
+	var suites []uint16
+	if cipherSuites != "" {
+		known := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			known[suite.Name] = suite.ID
+		}
+		for _, suite := range tls.InsecureCipherSuites() {
+			known[suite.Name] = suite.ID
+		}
+		for _, name := range strings.Split(cipherSuites, ",") {
+			name = strings.TrimSpace(name)
+			id, found := known[name]
+			if !found {
+				return fmt.Errorf("unknown tls cipher suite %q", name)
+			}
+			suites = append(suites, id)
+		}
+	}
+
+	ServerTlsMinVersion, ServerTlsCipherSuites = version, suites
+	return nil
+}
+
+// ServerTlsConfig applies the minimum TLS version and cipher suites to the server tls config.
+func ServerTlsConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.MinVersion = ServerTlsMinVersion
+	config.CipherSuites = ServerTlsCipherSuites
+	return config
+}
+
 func LoadServerTLS(config *viper.Viper, component string) grpc.ServerOption {
 	if config == nil {
 		return nil
@@ -31,11 +88,11 @@ func LoadServerTLS(config *viper.Viper, component string) grpc.ServerOption {
 	}
 	caCertPool := x509.NewCertPool()
 	caCertPool.AppendCertsFromPEM(caCert)
-	ta := credentials.NewTLS(&tls.Config{
+	ta := credentials.NewTLS(ServerTlsConfig(&tls.Config{
 		Certificates: []tls.Certificate{cert},
 		ClientCAs:    caCertPool,
 		ClientAuth:   tls.RequireAndVerifyClientCert,
-	})
+	}))
 
 	return grpc.Creds(ta)
 }
@@ -0,0 +1,36 @@
+package security
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestSetServerTlsOptions(t *testing.T) {
+	defer func() {
+		ServerTlsMinVersion, ServerTlsCipherSuites = tls.VersionTLS12, nil
+	}()
+
+	if err := SetServerTlsOptions("1.3", ""); err != nil || ServerTlsMinVersion != tls.VersionTLS13 || ServerTlsCipherSuites != nil {
+		t.Errorf("1.3: %v %x %v", err, ServerTlsMinVersion, ServerTlsCipherSuites)
+	}
+
+	err := SetServerTlsOptions("1.2", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384")
+	if err != nil || ServerTlsMinVersion != tls.VersionTLS12 || len(ServerTlsCipherSuites) != 2 ||
+		ServerTlsCipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("1.2 with cipher suites: %v %x %v", err, ServerTlsMinVersion, ServerTlsCipherSuites)
+	}
+
+	config := ServerTlsConfig(nil)
+	if config.MinVersion != tls.VersionTLS12 || len(config.CipherSuites) != 2 {
+		t.Errorf("server tls config: %+v", config)
+	}
+
+	for _, bad := range [][2]string{{"1.4", ""}, {"tls1.2", ""}, {"1.2", "TLS_NO_SUCH_SUITE"}} {
+		if err := SetServerTlsOptions(bad[0], bad[1]); err == nil {
+			t.Errorf("expect error for %v", bad)
+		}
+	}
+	if ServerTlsMinVersion != tls.VersionTLS12 || len(ServerTlsCipherSuites) != 2 {
+		t.Errorf("invalid options changed the settings")
+	}
+}
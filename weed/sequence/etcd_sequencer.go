@@ -135,6 +135,13 @@ func (es *EtcdSequencer) Peek() uint64 {
 	return es.currentSeqId
 }
 
+// Close waits for the sequence being allocated, and closes the local sequence file.
+func (es *EtcdSequencer) Close() error {
+	es.sequenceLock.Lock()
+	defer es.sequenceLock.Unlock()
+	return es.seqFile.Close()
+}
+
 func batchGetSequenceFromEtcd(kvApi client.KeysAPI, step uint64) (uint64, error) {
 	if step <= 0 {
 		return 0, fmt.Errorf("the step must be large than 1")
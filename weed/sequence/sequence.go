@@ -4,4 +4,5 @@ type Sequencer interface {
 	NextFileId(count uint64) uint64
 	SetMax(uint64)
 	Peek() uint64
+	Close() error
 }
@@ -0,0 +1,98 @@
+package sequence
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+/*
+Note :
+(1) the file id is generated locally, from 41 bits of milliseconds since the epoch, 10 bits of node id, and 12 bits of sequence
+(2) each master needs a different node id, so the ids stay unique when the leader changes, without any external storage
+(3) a batch of count ids is allocated from the same millisecond, so the ids in [fileId, fileId+count) are consecutive
+*/
+
+const (
+	SnowflakeEpoch        int64 = 1577836800000 // 2020-01-01 00:00:00 UTC in milliseconds
+	SnowflakeNodeBits           = 10
+	SnowflakeSequenceBits       = 12
+	MaxSnowflakeNodeId          = 1<<SnowflakeNodeBits - 1
+	maxSnowflakeSequence        = 1 << SnowflakeSequenceBits
+)
+
+type SnowflakeSequencer struct {
+	sequenceLock sync.Mutex
+
+	nodeId     uint64
+	lastMillis int64
+	sequence   uint64 // the next sequence in lastMillis
+}
+
+// NewSnowflakeSequencer creates a sequencer with the node id, 1 to 1023.
+// If the node id is 0, it is derived from the hash of the node name, e.g. the master address.
+func NewSnowflakeSequencer(nodeName string, nodeId int) (*SnowflakeSequencer, error) {
+	if nodeId == 0 {
+		h := fnv.New32a()
+		h.Write([]byte(nodeName))
+		nodeId = int(h.Sum32()%MaxSnowflakeNodeId) + 1
+	}
+	if nodeId < 1 || nodeId > MaxSnowflakeNodeId {
+		return nil, fmt.Errorf("snowflake node id %d should be between 1 and %d", nodeId, MaxSnowflakeNodeId)
+	}
+	glog.V(0).Infof("snowflake sequencer %s uses node id %d", nodeName, nodeId)
+	return &SnowflakeSequencer{
+		nodeId: uint64(nodeId),
+	}, nil
+}
+
+func (s *SnowflakeSequencer) NextFileId(count uint64) uint64 {
+	if count > maxSnowflakeSequence {
+		glog.Errorf("snowflake sequencer can not allocate %d file ids at once, at most %d", count, maxSnowflakeSequence)
+		return 0
+	}
+
+	s.sequenceLock.Lock()
+	defer s.sequenceLock.Unlock()
+
+	millis := time.Now().UnixNano() / int64(time.Millisecond)
+	if millis < s.lastMillis {
+		// the clock went backwards, keep using the last millisecond
+		millis = s.lastMillis
+	}
+	if millis == s.lastMillis && s.sequence+count > maxSnowflakeSequence {
+		// the sequence of this millisecond is used up, borrow the next one
+		millis++
+	}
+	if millis != s.lastMillis {
+		s.lastMillis, s.sequence = millis, 0
+	}
+
+	ret := s.fileId(s.lastMillis, s.sequence)
+	s.sequence += count
+	return ret
+}
+
+// SetMax is not needed, the ids only grow with the time
+func (s *SnowflakeSequencer) SetMax(seenValue uint64) {
+}
+
+func (s *SnowflakeSequencer) Peek() uint64 {
+	s.sequenceLock.Lock()
+	defer s.sequenceLock.Unlock()
+	if s.sequence >= maxSnowflakeSequence {
+		return s.fileId(s.lastMillis+1, 0)
+	}
+	return s.fileId(s.lastMillis, s.sequence)
+}
+
+func (s *SnowflakeSequencer) Close() error {
+	return nil
+}
+
+func (s *SnowflakeSequencer) fileId(millis int64, sequence uint64) uint64 {
+	return uint64(millis-SnowflakeEpoch)<<(SnowflakeNodeBits+SnowflakeSequenceBits) | s.nodeId<<SnowflakeSequenceBits | sequence
+}
@@ -0,0 +1,43 @@
+package sequence
+
+import (
+	"testing"
+)
+
+func TestSnowflakeSequencer(t *testing.T) {
+	seq, err := NewSnowflakeSequencer("127.0.0.1:9333", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewSnowflakeSequencer("127.0.0.1:9334", 6)
+
+	seen := make(map[uint64]bool)
+	var last uint64
+	for i := 0; i < 10000; i++ {
+		count := uint64(i%7 + 1)
+		fileId := seq.NextFileId(count)
+		if fileId <= last {
+			t.Fatalf("file id %d is not larger than %d", fileId, last)
+		}
+		for k := uint64(0); k < count; k++ {
+			if seen[fileId+k] {
+				t.Fatalf("duplicated file id %d", fileId+k)
+			}
+			seen[fileId+k] = true
+		}
+		last = fileId + count - 1
+		if (fileId+count-1)>>SnowflakeSequenceBits&MaxSnowflakeNodeId != 5 {
+			t.Fatalf("file ids %d+%d cross the node id bits", fileId, count)
+		}
+		if otherId := other.NextFileId(1); seen[otherId] {
+			t.Fatalf("file id %d from another node is duplicated", otherId)
+		}
+	}
+
+	if fileId := seq.NextFileId(maxSnowflakeSequence + 1); fileId != 0 {
+		t.Errorf("allocated too many file ids at once: %d", fileId)
+	}
+	if _, err := NewSnowflakeSequencer("127.0.0.1:9333", MaxSnowflakeNodeId+1); err == nil {
+		t.Errorf("node id %d should be rejected", MaxSnowflakeNodeId+1)
+	}
+}
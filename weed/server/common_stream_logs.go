@@ -0,0 +1,72 @@
+package weed_server
+
+import (
+	"context"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+)
+
+func (ms *MasterServer) StreamLogs(req *master_pb.StreamLogsRequest, stream master_pb.Seaweed_StreamLogsServer) error {
+	return streamLogs(stream.Context(), req.Level, req.Follow, int(req.Tail), func(lines []string) error {
+		return stream.Send(&master_pb.StreamLogsResponse{Lines: lines})
+	})
+}
+
+func (vs *VolumeServer) StreamLogs(req *volume_server_pb.StreamLogsRequest, stream volume_server_pb.VolumeServer_StreamLogsServer) error {
+	return streamLogs(stream.Context(), req.Level, req.Follow, int(req.Tail), func(lines []string) error {
+		return stream.Send(&volume_server_pb.StreamLogsResponse{Lines: lines})
+	})
+}
+
+func (fs *FilerServer) StreamLogs(req *filer_pb.StreamLogsRequest, stream filer_pb.SeaweedFiler_StreamLogsServer) error {
+	return streamLogs(stream.Context(), req.Level, req.Follow, int(req.Tail), func(lines []string) error {
+		return stream.Send(&filer_pb.StreamLogsResponse{Lines: lines})
+	})
+}
+
+// streamLogs sends the last tail log lines at or above the level, and with follow,
+// keeps sending the new lines until the client disconnects.
+func streamLogs(ctx context.Context, level string, follow bool, tail int, send func(lines []string) error) error {
+	minLevel := 0
+	if level != "" {
+		var err error
+		if minLevel, err = glog.ParseLevel(level); err != nil {
+			return err
+		}
+	}
+
+	recent, newLines, cancel := glog.SubscribeLogs(tail, follow)
+	defer cancel()
+
+	var lines []string
+	for _, line := range recent {
+		if line.Level >= minLevel {
+			lines = append(lines, line.Text)
+		}
+	}
+	if len(lines) > 0 {
+		if err := send(lines); err != nil {
+			return err
+		}
+	}
+
+	if !follow {
+		return nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line := <-newLines:
+			if line.Level < minLevel {
+				continue
+			}
+			if err := send([]string{line.Text}); err != nil {
+				return err
+			}
+		}
+	}
+}
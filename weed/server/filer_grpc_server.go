@@ -125,6 +125,7 @@ func (fs *FilerServer) LookupVolume(ctx context.Context, req *filer_pb.LookupVol
 			locs = append(locs, &filer_pb.Location{
 				Url:       loc.Url,
 				PublicUrl: loc.PublicUrl,
+				GrpcPort:  uint32(loc.GrpcPort),
 			})
 		}
 		resp.LocationsMap[vidString] = &filer_pb.Locations{
@@ -228,6 +229,12 @@ func (fs *FilerServer) UpdateEntry(ctx context.Context, req *filer_pb.UpdateEntr
 		return &filer_pb.UpdateEntryResponse{}, err
 	}
 
+	if !newEntry.IsDirectory() && !req.IsFromOtherCluster {
+		if err = fs.filer.CheckQuota(newEntry.FullPath, int64(newEntry.Size())-int64(entry.Size()), 0); err != nil {
+			return &filer_pb.UpdateEntryResponse{}, err
+		}
+	}
+
 	if err = fs.filer.UpdateEntry(ctx, entry, newEntry); err == nil {
 		fs.filer.DeleteChunks(garbage)
 
@@ -320,7 +327,11 @@ func (fs *FilerServer) DeleteEntry(ctx context.Context, req *filer_pb.DeleteEntr
 
 	glog.V(4).Infof("DeleteEntry %v", req)
 
-	err = fs.filer.DeleteEntryMetaAndData(ctx, util.JoinPath(req.Directory, req.Name), req.IsRecursive, req.IgnoreRecursiveError, req.IsDeleteData, req.IsFromOtherCluster, req.Signatures)
+	if req.IsAsync && req.IsRecursive {
+		err = fs.filer.DeleteInBackground(ctx, util.JoinPath(req.Directory, req.Name), req.IsDeleteData)
+	} else {
+		err = fs.filer.DeleteEntryMetaAndData(ctx, util.JoinPath(req.Directory, req.Name), req.IsRecursive, req.IgnoreRecursiveError, req.IsDeleteData, req.IsFromOtherCluster, req.Signatures)
+	}
 	resp = &filer_pb.DeleteEntryResponse{}
 	if err != nil {
 		resp.Error = err.Error()
@@ -328,6 +339,37 @@ func (fs *FilerServer) DeleteEntry(ctx context.Context, req *filer_pb.DeleteEntr
 	return resp, nil
 }
 
+func (fs *FilerServer) ListQuotas(ctx context.Context, req *filer_pb.ListQuotasRequest) (resp *filer_pb.ListQuotasResponse, err error) {
+
+	resp = &filer_pb.ListQuotasResponse{}
+	for _, usage := range fs.filer.ListQuotaUsage() {
+		resp.Quotas = append(resp.Quotas, &filer_pb.ListQuotasResponse_Quota{
+			LocationPrefix: usage.LocationPrefix,
+			MaxBytes:       usage.MaxBytes,
+			MaxFileCount:   usage.MaxFileCount,
+			UsedBytes:      usage.UsedBytes,
+			UsedFileCount:  usage.UsedFileCount,
+			IsCounted:      usage.IsCounted,
+		})
+	}
+	return resp, nil
+}
+
+func (fs *FilerServer) ListBackgroundDeletions(ctx context.Context, req *filer_pb.ListBackgroundDeletionsRequest) (resp *filer_pb.ListBackgroundDeletionsResponse, err error) {
+
+	resp = &filer_pb.ListBackgroundDeletionsResponse{}
+	for _, d := range fs.filer.ListBackgroundDeletions() {
+		resp.Deletions = append(resp.Deletions, &filer_pb.ListBackgroundDeletionsResponse_Deletion{
+			Path:         string(d.Path),
+			StartedNs:    d.StartedAt.UnixNano(),
+			DeletedCount: d.DeletedCount,
+			ErrorCount:   d.ErrorCount,
+			LastError:    d.LastError,
+		})
+	}
+	return resp, nil
+}
+
 func (fs *FilerServer) AssignVolume(ctx context.Context, req *filer_pb.AssignVolumeRequest) (resp *filer_pb.AssignVolumeResponse, err error) {
 
 	so := fs.detectStorageOption(req.Path, req.Collection, req.Replication, req.TtlSec, req.DataCenter, req.Rack)
@@ -423,15 +465,17 @@ func (fs *FilerServer) Statistics(ctx context.Context, req *filer_pb.StatisticsR
 func (fs *FilerServer) GetFilerConfiguration(ctx context.Context, req *filer_pb.GetFilerConfigurationRequest) (resp *filer_pb.GetFilerConfigurationResponse, err error) {
 
 	t := &filer_pb.GetFilerConfigurationResponse{
-		Masters:            fs.option.Masters,
-		Collection:         fs.option.Collection,
-		Replication:        fs.option.DefaultReplication,
-		MaxMb:              uint32(fs.option.MaxMB),
-		DirBuckets:         fs.filer.DirBucketsPath,
-		Cipher:             fs.filer.Cipher,
-		Signature:          fs.filer.Signature,
-		MetricsAddress:     fs.metricsAddress,
-		MetricsIntervalSec: int32(fs.metricsIntervalSec),
+		Masters:              fs.option.Masters,
+		Collection:           fs.option.Collection,
+		Replication:          fs.option.DefaultReplication,
+		MaxMb:                uint32(fs.option.MaxMB),
+		DirBuckets:           fs.filer.DirBucketsPath,
+		Cipher:               fs.filer.Cipher,
+		Signature:            fs.filer.Signature,
+		MetricsAddress:       fs.metricsAddress,
+		MetricsIntervalSec:   int32(fs.metricsIntervalSec),
+		MetricsJobLabel:      fs.metricsJobLabel,
+		MetricsInstanceLabel: fs.metricsInstanceLabel,
 	}
 
 	glog.V(4).Infof("GetFilerConfiguration: %v", t)
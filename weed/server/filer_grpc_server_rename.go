@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/chrislusf/seaweedfs/weed/filer"
 	"github.com/chrislusf/seaweedfs/weed/glog"
@@ -15,32 +16,102 @@ func (fs *FilerServer) AtomicRenameEntry(ctx context.Context, req *filer_pb.Atom
 
 	glog.V(1).Infof("AtomicRenameEntry %v", req)
 
-	ctx, err := fs.filer.BeginTransaction(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	oldParent := util.FullPath(filepath.ToSlash(req.OldDirectory))
+	newParent := util.FullPath(filepath.ToSlash(req.NewDirectory))
 
 	oldEntry, err := fs.filer.FindEntry(ctx, oldParent.Child(req.OldName))
 	if err != nil {
-		fs.filer.RollbackTransaction(ctx)
 		return nil, fmt.Errorf("%s/%s not found: %v", req.OldDirectory, req.OldName, err)
 	}
 
+	if oldEntry.IsDirectory() && isSubPath(oldParent.Child(req.OldName), newParent.Child(req.NewName)) {
+		return nil, fmt.Errorf("can not move %s/%s into its own sub directory %s", req.OldDirectory, req.OldName, req.NewDirectory)
+	}
+
+	newParentEntry, err := fs.filer.FindEntry(ctx, newParent)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found: %v", req.NewDirectory, err)
+	}
+	if !newParentEntry.IsDirectory() {
+		return nil, fmt.Errorf("%s is a file", req.NewDirectory)
+	}
+
+	// a move interrupted by a crash on a store without transactions is completed when the filer restarts
+	intent, err := fs.filer.BeginRename(ctx, oldParent.Child(req.OldName), newParent.Child(req.NewName))
+	if err != nil {
+		return nil, fmt.Errorf("record rename %s/%s: %v", req.OldDirectory, req.OldName, err)
+	}
+	renameErr := fs.renameEntry(ctx, oldParent, oldEntry, newParent, req.NewName)
+	// the client is told a failed rename failed, so it is not replayed either
+	if err = fs.filer.EndRename(ctx, intent); err != nil {
+		glog.Errorf("remove rename %s/%s: %v", req.OldDirectory, req.OldName, err)
+	}
+	if renameErr != nil {
+		return nil, renameErr
+	}
+
+	return &filer_pb.AtomicRenameEntryResponse{}, nil
+}
+
+// renameEntry moves the entry in one store transaction, if the store supports transactions.
+func (fs *FilerServer) renameEntry(ctx context.Context, oldParent util.FullPath, oldEntry *filer.Entry, newParent util.FullPath, newName string) error {
+
+	txCtx, err := fs.filer.BeginTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
 	var events MoveEvents
-	moveErr := fs.moveEntry(ctx, oldParent, oldEntry, util.FullPath(filepath.ToSlash(req.NewDirectory)), req.NewName, &events)
+	moveErr := fs.moveEntry(txCtx, oldParent, oldEntry, newParent, newName, &events)
 	if moveErr != nil {
-		fs.filer.RollbackTransaction(ctx)
-		return nil, fmt.Errorf("%s/%s move error: %v", req.OldDirectory, req.OldName, moveErr)
+		fs.filer.RollbackTransaction(txCtx)
+		return fmt.Errorf("%s move error: %v", oldParent.Child(oldEntry.Name()), moveErr)
 	} else {
-		if commitError := fs.filer.CommitTransaction(ctx); commitError != nil {
-			fs.filer.RollbackTransaction(ctx)
-			return nil, fmt.Errorf("%s/%s move commit error: %v", req.OldDirectory, req.OldName, commitError)
+		if commitError := fs.filer.CommitTransaction(txCtx); commitError != nil {
+			fs.filer.RollbackTransaction(txCtx)
+			return fmt.Errorf("%s move commit error: %v", oldParent.Child(oldEntry.Name()), commitError)
 		}
 	}
 
-	return &filer_pb.AtomicRenameEntryResponse{}, nil
+	// only announce the rename and drop the overwritten data after the move is committed
+	for i, newEntry := range events.newEntries {
+		fs.filer.NotifyUpdateEvent(ctx, events.oldEntries[i], newEntry, false, false, nil)
+	}
+	for i, replacedEntry := range events.replacedEntries {
+		fs.filer.DeleteChunks(chunksNotIn(replacedEntry, events.replacingEntries[i]))
+	}
+
+	return nil
+}
+
+// resumeRenames completes the renames interrupted when the filer stopped.
+// The entries already moved are found in the new directory, and the others are moved again.
+func (fs *FilerServer) resumeRenames() {
+	ctx := context.Background()
+	intents, err := fs.filer.PendingRenames(ctx)
+	if err != nil {
+		glog.Errorf("read pending renames: %v", err)
+		return
+	}
+	for _, intent := range intents {
+		oldEntry, findErr := fs.filer.FindEntry(ctx, intent.OldPath)
+		if findErr != nil && findErr != filer_pb.ErrNotFound {
+			glog.Errorf("resume rename %s => %s: %v", intent.OldPath, intent.NewPath, findErr)
+			continue
+		}
+		if oldEntry != nil {
+			glog.V(0).Infof("resume rename %s => %s", intent.OldPath, intent.NewPath)
+			oldParent, _ := intent.OldPath.DirAndName()
+			newParent, newName := intent.NewPath.DirAndName()
+			if err = fs.renameEntry(ctx, util.FullPath(oldParent), oldEntry, util.FullPath(newParent), newName); err != nil {
+				glog.Errorf("resume rename %s => %s: %v", intent.OldPath, intent.NewPath, err)
+				continue
+			}
+		}
+		if err = fs.filer.EndRename(ctx, intent); err != nil {
+			glog.Errorf("remove completed rename %s => %s: %v", intent.OldPath, intent.NewPath, err)
+		}
+	}
 }
 
 func (fs *FilerServer) moveEntry(ctx context.Context, oldParent util.FullPath, entry *filer.Entry, newParent util.FullPath, newName string, events *MoveEvents) error {
@@ -104,18 +175,40 @@ func (fs *FilerServer) moveSelfEntry(ctx context.Context, oldParent util.FullPat
 		return nil
 	}
 
-	// add to new directory
+	// add to new directory, overwriting an existing file of the same name
 	newEntry := &filer.Entry{
-		FullPath: newPath,
-		Attr:     entry.Attr,
-		Chunks:   entry.Chunks,
-		Extended: entry.Extended,
+		FullPath:        newPath,
+		Attr:            entry.Attr,
+		Chunks:          entry.Chunks,
+		Extended:        entry.Extended,
+		HardLinkId:      entry.HardLinkId,
+		HardLinkCounter: entry.HardLinkCounter,
+	}
+	existingEntry, findErr := fs.filer.FindEntry(ctx, newPath)
+	if findErr != nil && findErr != filer_pb.ErrNotFound {
+		return findErr
 	}
-	createErr := fs.filer.CreateEntry(ctx, newEntry, false, false, nil)
-	if createErr != nil {
-		return createErr
+	if existingEntry == nil {
+		if createErr := fs.filer.Store.InsertEntry(ctx, newEntry); createErr != nil {
+			return createErr
+		}
+	} else {
+		if updateErr := fs.filer.UpdateEntry(ctx, existingEntry, newEntry); updateErr != nil {
+			return updateErr
+		}
+		if len(existingEntry.HardLinkId) != 0 && len(newEntry.HardLinkId) == 0 {
+			// the store only drops the old hard link when it is replaced by another one
+			if deleteErr := fs.filer.Store.DeleteHardLink(ctx, existingEntry.HardLinkId); deleteErr != nil {
+				return deleteErr
+			}
+		}
+		if !existingEntry.IsDirectory() && len(existingEntry.HardLinkId) == 0 {
+			events.replacedEntries = append(events.replacedEntries, existingEntry)
+			events.replacingEntries = append(events.replacingEntries, newEntry)
+		}
 	}
 
+	events.oldEntries = append(events.oldEntries, entry)
 	events.newEntries = append(events.newEntries, newEntry)
 
 	if moveFolderSubEntries != nil {
@@ -124,19 +217,37 @@ func (fs *FilerServer) moveSelfEntry(ctx context.Context, oldParent util.FullPat
 		}
 	}
 
-	// delete old entry
-	deleteErr := fs.filer.DeleteEntryMetaAndData(ctx, oldPath, false, false, false, false, nil)
-	if deleteErr != nil {
+	// delete old entry, the children are already moved and the chunks are kept
+	if deleteErr := fs.filer.Store.DeleteMovedEntry(ctx, oldPath); deleteErr != nil {
 		return deleteErr
 	}
 
-	events.oldEntries = append(events.oldEntries, entry)
-
 	return nil
 
 }
 
 type MoveEvents struct {
-	oldEntries []*filer.Entry
-	newEntries []*filer.Entry
+	oldEntries       []*filer.Entry
+	newEntries       []*filer.Entry
+	replacedEntries  []*filer.Entry
+	replacingEntries []*filer.Entry
+}
+
+// isSubPath checks whether p is somewhere under dir
+func isSubPath(dir, p util.FullPath) bool {
+	return strings.HasPrefix(string(p), string(dir)+"/")
+}
+
+// chunksNotIn returns the chunks of the replaced entry that the replacing entry does not reference
+func chunksNotIn(replaced, replacing *filer.Entry) (chunks []*filer_pb.FileChunk) {
+	fileIds := make(map[string]bool)
+	for _, chunk := range replacing.Chunks {
+		fileIds[chunk.GetFileIdString()] = true
+	}
+	for _, chunk := range replaced.Chunks {
+		if !fileIds[chunk.GetFileIdString()] {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return
 }
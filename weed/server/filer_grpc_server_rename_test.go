@@ -0,0 +1,194 @@
+package weed_server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	leveldb2 "github.com/chrislusf/seaweedfs/weed/filer/leveldb2"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func newRenameTestFilerServer(t *testing.T) (fs *FilerServer, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rename")
+	if err != nil {
+		t.Fatalf("temp dir creation: %v", err)
+	}
+	config := viper.New()
+	config.Set("leveldb2.dir", dir)
+	store := &leveldb2.LevelDB2Store{}
+	if err = store.Initialize(config, "leveldb2."); err != nil {
+		t.Fatalf("store initialization: %v", err)
+	}
+	f := filer.NewFiler(nil, nil, "", 0, "", "", "", nil)
+	f.SetStore(store)
+	return &FilerServer{filer: f}, func() {
+		store.Shutdown()
+		os.RemoveAll(dir)
+	}
+}
+
+func createRenameTestEntries(t *testing.T, fs *FilerServer, entries ...*filer.Entry) {
+	for _, entry := range entries {
+		if entry.Mode == 0 {
+			entry.Mode = 0644
+		}
+		if err := fs.filer.CreateEntry(context.Background(), entry, false, false, nil); err != nil {
+			t.Fatalf("create %s: %v", entry.FullPath, err)
+		}
+	}
+}
+
+func findRenameTestEntry(t *testing.T, fs *FilerServer, p util.FullPath) *filer.Entry {
+	entry, err := fs.filer.FindEntry(context.Background(), p)
+	if err == filer_pb.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("find %s: %v", p, err)
+	}
+	return entry
+}
+
+func TestRenameOverwritesFile(t *testing.T) {
+	fs, cleanup := newRenameTestFilerServer(t)
+	defer cleanup()
+
+	createRenameTestEntries(t, fs,
+		&filer.Entry{FullPath: "/a/f", Chunks: []*filer_pb.FileChunk{{FileId: "1,01637037d6", Size: 1}}},
+		&filer.Entry{FullPath: "/b/f", Chunks: []*filer_pb.FileChunk{{FileId: "2,02637037d6", Size: 1}}},
+	)
+
+	if _, err := fs.AtomicRenameEntry(context.Background(), &filer_pb.AtomicRenameEntryRequest{
+		OldDirectory: "/a", OldName: "f", NewDirectory: "/b", NewName: "f",
+	}); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	if entry := findRenameTestEntry(t, fs, "/a/f"); entry != nil {
+		t.Errorf("old entry is not removed")
+	}
+	entry := findRenameTestEntry(t, fs, "/b/f")
+	if entry == nil || len(entry.Chunks) != 1 || entry.Chunks[0].GetFileIdString() != "1,01637037d6" {
+		t.Errorf("renamed entry: %+v", entry)
+	}
+	if _, err := fs.filer.Store.KvGet(context.Background(), fs.filer.LocalKvKey(filer.RenameJournalKey)); err != filer.ErrKvNotFound {
+		t.Errorf("rename journal is not cleared: %v", err)
+	}
+}
+
+func TestRenameHardLinks(t *testing.T) {
+	fs, cleanup := newRenameTestFilerServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	moved, replaced := filer.HardLinkId("moved"), filer.HardLinkId("replaced")
+	createRenameTestEntries(t, fs,
+		&filer.Entry{FullPath: "/a/link", HardLinkId: moved, HardLinkCounter: 2, Chunks: []*filer_pb.FileChunk{{FileId: "1,01637037d6", Size: 1}}},
+		&filer.Entry{FullPath: "/c/link", HardLinkId: moved, HardLinkCounter: 2, Chunks: []*filer_pb.FileChunk{{FileId: "1,01637037d6", Size: 1}}},
+		&filer.Entry{FullPath: "/a/file", Chunks: []*filer_pb.FileChunk{{FileId: "2,02637037d6", Size: 1}}},
+		&filer.Entry{FullPath: "/b/file", HardLinkId: replaced, HardLinkCounter: 1, Chunks: []*filer_pb.FileChunk{{FileId: "3,03637037d6", Size: 1}}},
+	)
+
+	// moving a hard link keeps the link and its counter
+	if _, err := fs.AtomicRenameEntry(ctx, &filer_pb.AtomicRenameEntryRequest{
+		OldDirectory: "/a", OldName: "link", NewDirectory: "/b", NewName: "link",
+	}); err != nil {
+		t.Fatalf("rename hard link: %v", err)
+	}
+	entry := findRenameTestEntry(t, fs, "/b/link")
+	if entry == nil || string(entry.HardLinkId) != string(moved) || entry.HardLinkCounter != 2 || len(entry.Chunks) != 1 {
+		t.Errorf("moved hard link: %+v", entry)
+	}
+	if findRenameTestEntry(t, fs, "/a/link") != nil {
+		t.Errorf("old hard link is not removed")
+	}
+
+	// overwriting the last link of a file queues the file for reaping
+	if _, err := fs.AtomicRenameEntry(ctx, &filer_pb.AtomicRenameEntryRequest{
+		OldDirectory: "/a", OldName: "file", NewDirectory: "/b", NewName: "file",
+	}); err != nil {
+		t.Fatalf("rename over hard link: %v", err)
+	}
+	entry = findRenameTestEntry(t, fs, "/b/file")
+	if entry == nil || len(entry.HardLinkId) != 0 || entry.Chunks[0].GetFileIdString() != "2,02637037d6" {
+		t.Errorf("file over hard link: %+v", entry)
+	}
+	ids, err := fs.filer.Store.ListHardLinksToReap(ctx)
+	if err != nil || len(ids) != 1 || string(ids[0]) != string(replaced) {
+		t.Errorf("hard links to reap: %v %v", ids, err)
+	}
+}
+
+func TestResumeInterruptedRename(t *testing.T) {
+	fs, cleanup := newRenameTestFilerServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	createRenameTestEntries(t, fs,
+		&filer.Entry{FullPath: "/a/d", Attr: filer.Attr{Mode: os.ModeDir | 0755}},
+		&filer.Entry{FullPath: "/a/d/x"},
+		&filer.Entry{FullPath: "/a/d/y"},
+		&filer.Entry{FullPath: "/b", Attr: filer.Attr{Mode: os.ModeDir | 0755}},
+	)
+
+	// the filer stopped after moving the first child
+	if _, err := fs.filer.BeginRename(ctx, "/a/d", "/b/d"); err != nil {
+		t.Fatalf("begin rename: %v", err)
+	}
+	createRenameTestEntries(t, fs, &filer.Entry{FullPath: "/b/d/x"})
+	if err := fs.filer.Store.DeleteEntry(ctx, "/a/d/x"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// another filer on the same store does not resume the rename
+	other := &FilerServer{filer: filer.NewFiler(nil, nil, "other", 8888, "", "", "", nil)}
+	other.filer.Store = fs.filer.Store
+	other.resumeRenames()
+	if findRenameTestEntry(t, other, "/a/d/y") == nil {
+		t.Errorf("rename of another filer is resumed")
+	}
+
+	restarted := &FilerServer{filer: filer.NewFiler(nil, nil, "", 0, "", "", "", nil)}
+	restarted.filer.Store = fs.filer.Store
+	restarted.resumeRenames()
+
+	for _, p := range []util.FullPath{"/b/d", "/b/d/x", "/b/d/y"} {
+		if findRenameTestEntry(t, restarted, p) == nil {
+			t.Errorf("%s is not moved", p)
+		}
+	}
+	for _, p := range []util.FullPath{"/a/d", "/a/d/x", "/a/d/y"} {
+		if findRenameTestEntry(t, restarted, p) != nil {
+			t.Errorf("%s is not removed", p)
+		}
+	}
+	if _, err := restarted.filer.Store.KvGet(ctx, restarted.filer.LocalKvKey(filer.RenameJournalKey)); err != filer.ErrKvNotFound {
+		t.Errorf("rename journal is not cleared: %v", err)
+	}
+}
+
+func TestFailedRenameIsNotResumed(t *testing.T) {
+	fs, cleanup := newRenameTestFilerServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	createRenameTestEntries(t, fs,
+		&filer.Entry{FullPath: "/a/f"},
+		&filer.Entry{FullPath: "/b/f", Attr: filer.Attr{Mode: os.ModeDir | 0755}},
+	)
+
+	if _, err := fs.AtomicRenameEntry(ctx, &filer_pb.AtomicRenameEntryRequest{
+		OldDirectory: "/a", OldName: "f", NewDirectory: "/b", NewName: "f",
+	}); err == nil {
+		t.Fatalf("rename a file over a directory should fail")
+	}
+	if _, err := fs.filer.Store.KvGet(ctx, fs.filer.LocalKvKey(filer.RenameJournalKey)); err != filer.ErrKvNotFound {
+		t.Errorf("failed rename is kept in the journal: %v", err)
+	}
+}
@@ -16,7 +16,7 @@ import (
 
 func (fs *FilerServer) SubscribeMetadata(req *filer_pb.SubscribeMetadataRequest, stream filer_pb.SeaweedFiler_SubscribeMetadataServer) error {
 
-	peerAddress := findClientAddress(stream.Context(), 0)
+	peerAddress := findClientAddress(stream.Context(), 0, 0)
 
 	clientName := fs.addClient(req.ClientName, peerAddress)
 
@@ -60,7 +60,7 @@ func (fs *FilerServer) SubscribeMetadata(req *filer_pb.SubscribeMetadataRequest,
 
 func (fs *FilerServer) SubscribeLocalMetadata(req *filer_pb.SubscribeMetadataRequest, stream filer_pb.SeaweedFiler_SubscribeLocalMetadataServer) error {
 
-	peerAddress := findClientAddress(stream.Context(), 0)
+	peerAddress := findClientAddress(stream.Context(), 0, 0)
 
 	clientName := fs.addClient(req.ClientName, peerAddress)
 
@@ -137,22 +137,7 @@ func (fs *FilerServer) eachEventNotificationFn(req *filer_pb.SubscribeMetadataRe
 			eventNotification.Signatures = append(eventNotification.Signatures, fs.filer.Signature)
 		}
 
-		// get complete path to the file or directory
-		var entryName string
-		if eventNotification.OldEntry != nil {
-			entryName = eventNotification.OldEntry.Name
-		} else if eventNotification.NewEntry != nil {
-			entryName = eventNotification.NewEntry.Name
-		}
-
-		fullpath := util.Join(dirPath, entryName)
-
-		// skip on filer internal meta logs
-		if strings.HasPrefix(fullpath, filer.SystemLogDir) {
-			return nil
-		}
-
-		if !strings.HasPrefix(fullpath, req.PathPrefix) {
+		if !eventMatchesPathPrefix(dirPath, eventNotification, req.PathPrefix) {
 			return nil
 		}
 
@@ -170,6 +155,33 @@ func (fs *FilerServer) eachEventNotificationFn(req *filer_pb.SubscribeMetadataRe
 	}
 }
 
+// eventMatchesPathPrefix checks the old and the new path of the event, so a rename into or out of
+// the watched path prefix is also sent. The filer internal meta logs are skipped.
+func eventMatchesPathPrefix(dirPath string, eventNotification *filer_pb.EventNotification, pathPrefix string) bool {
+
+	var oldPath, newPath string
+	if eventNotification.OldEntry != nil {
+		oldPath = util.Join(dirPath, eventNotification.OldEntry.Name)
+	}
+	if eventNotification.NewEntry != nil {
+		newParentPath := eventNotification.NewParentPath
+		if newParentPath == "" {
+			newParentPath = dirPath
+		}
+		newPath = util.Join(newParentPath, eventNotification.NewEntry.Name)
+	}
+
+	for _, fullpath := range []string{oldPath, newPath} {
+		if fullpath == "" || strings.HasPrefix(fullpath, filer.SystemLogDir) {
+			continue
+		}
+		if strings.HasPrefix(fullpath, pathPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (fs *FilerServer) addClient(clientType string, clientAddress string) (clientName string) {
 	clientName = clientType + "@" + clientAddress
 	glog.V(0).Infof("+ listener %v", clientName)
@@ -0,0 +1,36 @@
+package weed_server
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+)
+
+func TestEventMatchesPathPrefix(t *testing.T) {
+	created := &filer_pb.EventNotification{NewEntry: &filer_pb.Entry{Name: "f"}, NewParentPath: "/a"}
+	renamedIn := &filer_pb.EventNotification{OldEntry: &filer_pb.Entry{Name: "f"}, NewEntry: &filer_pb.Entry{Name: "g"}, NewParentPath: "/a"}
+	renamedOut := &filer_pb.EventNotification{OldEntry: &filer_pb.Entry{Name: "f"}, NewEntry: &filer_pb.Entry{Name: "f"}, NewParentPath: "/b"}
+	deleted := &filer_pb.EventNotification{OldEntry: &filer_pb.Entry{Name: "f"}}
+	systemLog := &filer_pb.EventNotification{NewEntry: &filer_pb.Entry{Name: "01-02.segment"}, NewParentPath: "/topics/.system/log/2020-01-01"}
+
+	for _, tc := range []struct {
+		dirPath string
+		event   *filer_pb.EventNotification
+		prefix  string
+		want    bool
+	}{
+		{"/a", created, "/a/", true},
+		{"/a", created, "/b/", false},
+		{"/b", renamedIn, "/a/", true},
+		{"/b", renamedIn, "/b/", true},
+		{"/b", renamedIn, "/c/", false},
+		{"/a", renamedOut, "/a/", true},
+		{"/a", deleted, "/a/f", true},
+		{"/a", deleted, "/b", false},
+		{"/topics/.system/log/2020-01-01", systemLog, "/", false},
+	} {
+		if got := eventMatchesPathPrefix(tc.dirPath, tc.event, tc.prefix); got != tc.want {
+			t.Errorf("%s %+v prefix %s: got %v", tc.dirPath, tc.event, tc.prefix, got)
+		}
+	}
+}
@@ -30,6 +30,9 @@ import (
 	_ "github.com/chrislusf/seaweedfs/weed/filer/postgres"
 	_ "github.com/chrislusf/seaweedfs/weed/filer/redis"
 	_ "github.com/chrislusf/seaweedfs/weed/filer/redis2"
+	_ "github.com/chrislusf/seaweedfs/weed/filer/redis3"
+	"github.com/chrislusf/seaweedfs/weed/filer/search"
+	_ "github.com/chrislusf/seaweedfs/weed/filer/tikv"
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/notification"
 	_ "github.com/chrislusf/seaweedfs/weed/notification/aws_sqs"
@@ -53,9 +56,15 @@ type FilerOption struct {
 	DisableHttp        bool
 	Host               string
 	Port               uint32
+	GrpcPort           uint32
 	recursiveDelete    bool
 	Cipher             bool
 	Filers             []string
+	SearchIndexDir     string
+	TwoPhaseCommit     bool
+	PendingNeedleTtl   time.Duration
+	MaxBodySizeBytes   int64
+	MaxSymlinkDepth    int
 }
 
 type FilerServer struct {
@@ -65,8 +74,10 @@ type FilerServer struct {
 	grpcDialOption grpc.DialOption
 
 	// metrics read from the master
-	metricsAddress     string
-	metricsIntervalSec int
+	metricsAddress       string
+	metricsIntervalSec   int
+	metricsJobLabel      string
+	metricsInstanceLabel string
 
 	// notifying clients
 	listenersLock sync.Mutex
@@ -74,6 +85,8 @@ type FilerServer struct {
 
 	brokers     map[string]map[string]bool
 	brokersLock sync.Mutex
+
+	searchIndex *search.Index
 }
 
 func NewFilerServer(defaultMux, readonlyMux *http.ServeMux, option *FilerOption) (fs *FilerServer, err error) {
@@ -93,10 +106,11 @@ func NewFilerServer(defaultMux, readonlyMux *http.ServeMux, option *FilerOption)
 		fs.listenersCond.Broadcast()
 	})
 	fs.filer.Cipher = option.Cipher
+	fs.filer.MasterClient.SetAdvertisedGrpcPort(option.GrpcPort)
 
 	fs.checkWithMaster()
 
-	go stats.LoopPushingMetric("filer", stats.SourceName(fs.option.Port), fs.metricsAddress, fs.metricsIntervalSec)
+	go stats.LoopPushingMetric("filer", stats.SourceName(fs.option.Port), fs.metricsAddress, fs.metricsIntervalSec, fs.metricsJobLabel, fs.metricsInstanceLabel)
 	go fs.filer.KeepConnectedToMaster()
 
 	v := util.GetViper()
@@ -121,22 +135,56 @@ func NewFilerServer(defaultMux, readonlyMux *http.ServeMux, option *FilerOption)
 
 	notification.LoadConfiguration(v, "notification.")
 
+	if option.SearchIndexDir != "" {
+		if fs.searchIndex, err = search.NewIndex(option.SearchIndexDir); err != nil {
+			glog.Fatalf("open search index %s: %v", option.SearchIndexDir, err)
+		}
+	}
+
 	handleStaticResources(defaultMux)
 	if !option.DisableHttp {
 		defaultMux.HandleFunc("/", fs.filerHandler)
+		if fs.searchIndex != nil {
+			defaultMux.HandleFunc(searchPath, fs.searchHandler)
+		}
 	}
 	if defaultMux != readonlyMux {
 		readonlyMux.HandleFunc("/", fs.readonlyFilerHandler)
+		if fs.searchIndex != nil {
+			readonlyMux.HandleFunc(searchPath, fs.searchHandler)
+		}
 	}
 
-	fs.filer.AggregateFromPeers(fmt.Sprintf("%s:%d", option.Host, option.Port), option.Filers)
+	fs.filer.AggregateFromPeers(pb.NewServerAddress(option.Host, int(option.Port), int(option.GrpcPort)), option.Filers)
+
+	if fs.searchIndex != nil {
+		if _, err := fs.filer.FindEntry(context.Background(), util.FullPath(searchPath)); err == nil {
+			glog.Warningf("%s is reserved for the full-text search, the entry there is not reachable over http", searchPath)
+		}
+		go fs.loopUpdateSearchIndex()
+	}
 
 	fs.filer.LoadBuckets()
 
 	fs.filer.LoadFilerConf()
 
+	go fs.filer.LoopDeletingExpiredEntries(time.Hour)
+
+	go fs.filer.LoopApplyingBucketLifecycles(time.Hour)
+
+	go fs.filer.LoopReapingHardLinks(time.Minute)
+
+	fs.filer.ResumeBackgroundDeletions()
+
+	fs.resumeRenames()
+
+	go fs.filer.LoopCountingQuotaUsage(time.Hour)
+
 	grace.OnInterrupt(func() {
 		fs.filer.Shutdown()
+		if fs.searchIndex != nil {
+			fs.searchIndex.Close()
+		}
 	})
 
 	return fs, nil
@@ -160,9 +208,8 @@ func (fs *FilerServer) checkWithMaster() {
 					return fmt.Errorf("get master %s configuration: %v", master, err)
 				}
 				fs.metricsAddress, fs.metricsIntervalSec = resp.MetricsAddress, int(resp.MetricsIntervalSeconds)
-				if fs.option.DefaultReplication == "" {
-					fs.option.DefaultReplication = resp.DefaultReplication
-				}
+				fs.metricsJobLabel, fs.metricsInstanceLabel = resp.MetricsJobLabel, resp.MetricsInstanceLabel
+				// an empty default replication lets the master pick the replication of each collection
 				return nil
 			})
 			if readErr == nil {
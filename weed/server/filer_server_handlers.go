@@ -1,10 +1,14 @@
 package weed_server
 
 import (
+	"context"
+	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/chrislusf/seaweedfs/weed/filer"
 	"github.com/chrislusf/seaweedfs/weed/stats"
 )
 
@@ -14,11 +18,18 @@ func (fs *FilerServer) filerHandler(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 	}
+	if (r.Method == "PUT" || r.Method == "POST") && !fs.limitBodySize(w, r) {
+		return
+	}
 	start := time.Now()
 	switch r.Method {
 	case "GET":
 		stats.FilerRequestCounter.WithLabelValues("get").Inc()
-		fs.GetOrHeadHandler(w, r, true)
+		if r.URL.Query().Get("op") == "getChecksum" {
+			fs.GetChecksumHandler(w, r)
+		} else {
+			fs.GetOrHeadHandler(w, r, true)
+		}
 		stats.FilerRequestHistogram.WithLabelValues("get").Observe(time.Since(start).Seconds())
 	case "HEAD":
 		stats.FilerRequestCounter.WithLabelValues("head").Inc()
@@ -40,10 +51,26 @@ func (fs *FilerServer) filerHandler(w http.ResponseWriter, r *http.Request) {
 			fs.PostHandler(w, r)
 		}
 		stats.FilerRequestHistogram.WithLabelValues("put").Observe(time.Since(start).Seconds())
+	case "PATCH":
+		stats.FilerRequestCounter.WithLabelValues("patch").Inc()
+		if r.URL.Query().Get("op") == "setChecksum" {
+			fs.SetChecksumHandler(w, r)
+		} else {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		stats.FilerRequestHistogram.WithLabelValues("patch").Observe(time.Since(start).Seconds())
 	case "POST":
+		if r.URL.Query().Get("method") == "QUERY" {
+			fs.queryHandler(w, r)
+			stats.FilerRequestHistogram.WithLabelValues("query").Observe(time.Since(start).Seconds())
+			return
+		}
 		stats.FilerRequestCounter.WithLabelValues("post").Inc()
 		fs.PostHandler(w, r)
 		stats.FilerRequestHistogram.WithLabelValues("post").Observe(time.Since(start).Seconds())
+	case "QUERY":
+		fs.queryHandler(w, r)
+		stats.FilerRequestHistogram.WithLabelValues("query").Observe(time.Since(start).Seconds())
 	case "OPTIONS":
 		stats.FilerRequestCounter.WithLabelValues("options").Inc()
 		OptionsHandler(w, r, false)
@@ -61,12 +88,19 @@ func (fs *FilerServer) readonlyFilerHandler(w http.ResponseWriter, r *http.Reque
 	switch r.Method {
 	case "GET":
 		stats.FilerRequestCounter.WithLabelValues("get").Inc()
-		fs.GetOrHeadHandler(w, r, true)
+		if r.URL.Query().Get("op") == "getChecksum" {
+			fs.GetChecksumHandler(w, r)
+		} else {
+			fs.GetOrHeadHandler(w, r, true)
+		}
 		stats.FilerRequestHistogram.WithLabelValues("get").Observe(time.Since(start).Seconds())
 	case "HEAD":
 		stats.FilerRequestCounter.WithLabelValues("head").Inc()
 		fs.GetOrHeadHandler(w, r, false)
 		stats.FilerRequestHistogram.WithLabelValues("head").Observe(time.Since(start).Seconds())
+	case "QUERY":
+		fs.queryHandler(w, r)
+		stats.FilerRequestHistogram.WithLabelValues("query").Observe(time.Since(start).Seconds())
 	case "OPTIONS":
 		stats.FilerRequestCounter.WithLabelValues("options").Inc()
 		OptionsHandler(w, r, true)
@@ -76,9 +110,50 @@ func (fs *FilerServer) readonlyFilerHandler(w http.ResponseWriter, r *http.Reque
 
 func OptionsHandler(w http.ResponseWriter, r *http.Request, isReadOnly bool) {
 	if isReadOnly {
-		w.Header().Add("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Add("Access-Control-Allow-Methods", "GET, QUERY, OPTIONS")
 	} else {
-		w.Header().Add("Access-Control-Allow-Methods", "PUT, POST, GET, DELETE, OPTIONS")
+		w.Header().Add("Access-Control-Allow-Methods", "PUT, POST, PATCH, GET, DELETE, QUERY, OPTIONS")
 	}
 	w.Header().Add("Access-Control-Allow-Headers", "*")
 }
+
+// limitBodySize fails the request early if the declared size is over the limit,
+// and caps the body for requests without a declared size.
+func (fs *FilerServer) limitBodySize(w http.ResponseWriter, r *http.Request) bool {
+	limit := fs.option.MaxBodySizeBytes
+	if limit <= 0 {
+		return true
+	}
+	if r.ContentLength > limit {
+		writeJsonError(w, r, http.StatusRequestEntityTooLarge, fmt.Errorf("request body of %d bytes is over the limit of %d bytes", r.ContentLength, limit))
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return true
+}
+
+func isRequestBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
+
+// checkQuota fails the upload early if the declared size does not fit in the quota of its location
+func (fs *FilerServer) checkQuota(w http.ResponseWriter, r *http.Request) bool {
+	if r.ContentLength <= 0 {
+		return true
+	}
+	addBytes := r.ContentLength
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		if entry, err := fs.filer.FindEntry(context.Background(), util.FullPath(r.URL.Path)); err == nil {
+			addBytes -= int64(entry.Size())
+		}
+	}
+	if err := fs.filer.CheckQuota(util.FullPath(r.URL.Path), addBytes, 0); err != nil {
+		writeJsonError(w, r, http.StatusForbidden, err)
+		return false
+	}
+	return true
+}
+
+func isQuotaExceeded(err error) bool {
+	return err != nil && strings.Contains(err.Error(), filer.ErrQuotaExceeded.Error())
+}
@@ -0,0 +1,123 @@
+package weed_server
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// checksums set after upload are kept in the entry's extended attributes,
+// so they are also returned as headers when reading the file.
+const checksumKeyPrefix = "Seaweed-Checksum-"
+
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+func checksumKey(algorithm string) string {
+	return http.CanonicalHeaderKey(checksumKeyPrefix + algorithm)
+}
+
+// set a checksum on an existing file, optionally verified against the file content
+// curl -X PATCH "http://localhost:8888/path/to/a/file?op=setChecksum&algorithm=sha256&value=<hex>&verify=true"
+func (fs *FilerServer) SetChecksumHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx := context.Background()
+
+	path := r.URL.Path
+	if strings.HasSuffix(path, "/") {
+		path = path[:len(path)-1]
+	}
+
+	algorithm := strings.ToLower(r.FormValue("algorithm"))
+	newHash, found := checksumAlgorithms[algorithm]
+	if !found {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("unsupported checksum algorithm %q, expect md5, sha1, or sha256", algorithm))
+		return
+	}
+	value := strings.ToLower(r.FormValue("value"))
+	if decoded, err := hex.DecodeString(value); err != nil || len(decoded) != newHash().Size() {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("invalid %s checksum %q, expect %d hex digits", algorithm, value, newHash().Size()*2))
+		return
+	}
+
+	existingEntry, err := fs.filer.FindEntry(ctx, util.FullPath(path))
+	if err != nil {
+		writeJsonError(w, r, http.StatusNotFound, err)
+		return
+	}
+	if existingEntry.IsDirectory() {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("%s is a directory", path))
+		return
+	}
+
+	if r.FormValue("verify") == "true" {
+		h := newHash()
+		if err = filer.StreamContent(fs.filer.MasterClient, h, existingEntry.Chunks, 0, int64(existingEntry.Size())); err != nil {
+			glog.V(0).Infof("read %s to verify checksum: %v", path, err)
+			writeJsonError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != value {
+			writeJsonError(w, r, http.StatusConflict, fmt.Errorf("%s checksum of %s is %s, not %s", algorithm, path, actual, value))
+			return
+		}
+	}
+
+	if existingEntry.Extended == nil {
+		existingEntry.Extended = make(map[string][]byte)
+	}
+	existingEntry.Extended[checksumKey(algorithm)] = []byte(value)
+
+	if dbErr := fs.filer.CreateEntry(ctx, existingEntry, false, false, nil); dbErr != nil {
+		glog.V(0).Infof("failing to update %s checksum : %v", path, dbErr)
+		writeJsonError(w, r, http.StatusInternalServerError, dbErr)
+		return
+	}
+
+	writeJsonQuiet(w, r, http.StatusOK, entryChecksums(existingEntry))
+}
+
+// get the checksums of a file, as a map from the algorithm to the hex value
+// curl "http://localhost:8888/path/to/a/file?op=getChecksum"
+func (fs *FilerServer) GetChecksumHandler(w http.ResponseWriter, r *http.Request) {
+
+	path := r.URL.Path
+	if strings.HasSuffix(path, "/") {
+		path = path[:len(path)-1]
+	}
+
+	existingEntry, err := fs.filer.FindEntry(context.Background(), util.FullPath(path))
+	if err != nil {
+		writeJsonError(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	writeJsonQuiet(w, r, http.StatusOK, entryChecksums(existingEntry))
+}
+
+// entryChecksums also includes the md5 computed during the upload, if any.
+func entryChecksums(entry *filer.Entry) map[string]string {
+	checksums := make(map[string]string)
+	if len(entry.Attr.Md5) > 0 {
+		checksums["md5"] = hex.EncodeToString(entry.Attr.Md5)
+	}
+	for algorithm := range checksumAlgorithms {
+		if value, found := entry.Extended[checksumKey(algorithm)]; found {
+			checksums[algorithm] = string(value)
+		}
+	}
+	return checksums
+}
@@ -0,0 +1,52 @@
+package weed_server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// create a symlink, with the target relative to the symlink's directory unless absolute
+// curl -X POST "http://localhost:8888/path/to/link?op=symlink&target=../file"
+// create a hard link to an existing file
+// curl -X POST "http://localhost:8888/path/to/link?op=link&from=/path/to/file"
+func (fs *FilerServer) LinkHandler(w http.ResponseWriter, r *http.Request) {
+
+	ctx := context.Background()
+
+	path := r.URL.Path
+	query := r.URL.Query()
+	op, target, from := query.Get("op"), query.Get("target"), query.Get("from")
+	if strings.HasSuffix(path, "/") || (op == "symlink" && target == "") || (op == "link" && from == "") {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("%s %s: missing link name, target or from", op, path))
+		return
+	}
+
+	var err error
+	if op == "symlink" {
+		err = fs.filer.CreateSymlink(ctx, util.FullPath(path), target, OS_UID, OS_GID)
+	} else {
+		err = fs.filer.CreateHardLink(ctx, util.FullPath(from), util.FullPath(path))
+	}
+	if err != nil {
+		glog.V(1).Infof("%s %s: %v", op, path, err)
+		httpStatus := http.StatusInternalServerError
+		switch {
+		case err == filer_pb.ErrNotFound:
+			httpStatus = http.StatusNotFound
+		case strings.HasPrefix(err.Error(), "EEXIST"):
+			httpStatus = http.StatusConflict
+		case isQuotaExceeded(err):
+			httpStatus = http.StatusForbidden
+		}
+		writeJsonError(w, r, httpStatus, err)
+		return
+	}
+
+	writeJsonQuiet(w, r, http.StatusCreated, nil)
+}
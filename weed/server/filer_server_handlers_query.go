@@ -0,0 +1,184 @@
+package weed_server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 10000
+	maxQueryScanned   = 1000000 // entries listed by one query, matched or not
+	maxQueryBodySize  = 1 << 20
+)
+
+// FilerQuery is the json body of a "QUERY /path/to/dir" request.
+// Unset predicates match everything.
+//
+//	{
+//	  "recursive": true,
+//	  "limit": 100,
+//	  "size": {"min": 1024, "max": 1048576},
+//	  "mtime": {"after": "2020-11-01T00:00:00Z", "before": "2020-12-01T00:00:00Z"},
+//	  "mimeTypes": ["image/*", "application/pdf"]
+//	}
+type FilerQuery struct {
+	Recursive bool            `json:"recursive"`
+	Limit     int             `json:"limit"`
+	Size      *FilerQuerySize `json:"size,omitempty"`
+	Mtime     *FilerQueryTime `json:"mtime,omitempty"`
+	MimeTypes []string        `json:"mimeTypes,omitempty"`
+}
+
+type FilerQuerySize struct {
+	Min *uint64 `json:"min,omitempty"`
+	Max *uint64 `json:"max,omitempty"`
+}
+
+type FilerQueryTime struct {
+	After  *time.Time `json:"after,omitempty"`
+	Before *time.Time `json:"before,omitempty"`
+}
+
+type FilerQueryResult struct {
+	Path      string
+	Entries   []*filer.Entry
+	Truncated bool // more entries may match
+	scanned   int
+}
+
+func (q *FilerQuery) matches(entry *filer.Entry) bool {
+	if q.Size != nil {
+		size := entry.Size()
+		if q.Size.Min != nil && size < *q.Size.Min {
+			return false
+		}
+		if q.Size.Max != nil && size > *q.Size.Max {
+			return false
+		}
+	}
+	if q.Mtime != nil {
+		if q.Mtime.After != nil && !entry.Mtime.After(*q.Mtime.After) {
+			return false
+		}
+		if q.Mtime.Before != nil && !entry.Mtime.Before(*q.Mtime.Before) {
+			return false
+		}
+	}
+	if len(q.MimeTypes) > 0 {
+		mime := entry.Mime
+		if i := strings.Index(mime, ";"); i >= 0 {
+			mime = mime[:i]
+		}
+		found := false
+		for _, pattern := range q.MimeTypes {
+			if matched, _ := filepath.Match(pattern, mime); matched {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// queryHandler serves the experimental HTTP QUERY method, also accepted as "POST /path?method=QUERY".
+// Directories are only traversed, and only files are matched against the predicates.
+func (fs *FilerServer) queryHandler(w http.ResponseWriter, r *http.Request) {
+
+	stats.FilerRequestCounter.WithLabelValues("query").Inc()
+
+	path := r.URL.Path
+	if strings.HasSuffix(path, "/") && len(path) > 1 {
+		path = path[:len(path)-1]
+	}
+
+	query := &FilerQuery{}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxQueryBodySize))
+	if err != nil {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("read query: %v", err))
+		return
+	}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, query); err != nil {
+			writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("parse query: %v", err))
+			return
+		}
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultQueryLimit
+	}
+	if query.Limit > maxQueryLimit {
+		query.Limit = maxQueryLimit
+	}
+
+	if _, err = fs.filer.FindEntry(r.Context(), util.FullPath(path)); err != nil {
+		if err == filer_pb.ErrNotFound {
+			writeJsonError(w, r, http.StatusNotFound, err)
+		} else {
+			glog.V(0).Infof("query %s: %v", path, err)
+			writeJsonError(w, r, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	result := &FilerQueryResult{Path: path}
+	if err = fs.queryDirectory(r.Context(), util.FullPath(path), query, result); err != nil {
+		glog.V(0).Infof("query %s: %v", path, err)
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	// one more entry than the limit is collected to tell whether the result is truncated
+	if len(result.Entries) > query.Limit {
+		result.Entries = result.Entries[:query.Limit]
+		result.Truncated = true
+	}
+
+	writeJsonQuiet(w, r, http.StatusOK, result)
+}
+
+func (fs *FilerServer) queryDirectory(ctx context.Context, dirPath util.FullPath, query *FilerQuery, result *FilerQueryResult) error {
+	lastFileName := ""
+	for {
+		entries, err := fs.filer.ListDirectoryEntries(ctx, dirPath, lastFileName, false, 1024, "")
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDirectory() {
+				if query.Recursive {
+					if err = fs.queryDirectory(ctx, entry.FullPath, query, result); err != nil {
+						return err
+					}
+				}
+			} else if query.matches(entry) {
+				result.Entries = append(result.Entries, entry)
+			}
+			if len(result.Entries) > query.Limit {
+				return nil
+			}
+			if result.scanned++; result.scanned >= maxQueryScanned {
+				result.Truncated = true
+				return nil
+			}
+		}
+		if len(entries) < 1024 {
+			return nil
+		}
+		lastFileName = entries[len(entries)-1].Name()
+	}
+}
@@ -0,0 +1,88 @@
+package weed_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+)
+
+func TestFilerQueryMatches(t *testing.T) {
+
+	query := &FilerQuery{}
+	err := json.Unmarshal([]byte(`{
+		"size": {"min": 100, "max": 1000},
+		"mtime": {"after": "2020-11-01T00:00:00Z"},
+		"mimeTypes": ["image/*", "application/pdf"]
+	}`), query)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	mtime := time.Date(2020, 11, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		attr filer.Attr
+		want bool
+	}{
+		{filer.Attr{FileSize: 500, Mtime: mtime, Mime: "image/png"}, true},
+		{filer.Attr{FileSize: 500, Mtime: mtime, Mime: "application/pdf; charset=binary"}, true},
+		{filer.Attr{FileSize: 50, Mtime: mtime, Mime: "image/png"}, false},
+		{filer.Attr{FileSize: 5000, Mtime: mtime, Mime: "image/png"}, false},
+		{filer.Attr{FileSize: 500, Mtime: mtime.AddDate(0, -1, 0), Mime: "image/png"}, false},
+		{filer.Attr{FileSize: 500, Mtime: mtime, Mime: "text/plain"}, false},
+	}
+	for i, tt := range tests {
+		if got := query.matches(&filer.Entry{Attr: tt.attr}); got != tt.want {
+			t.Errorf("case %d %+v: got %v, want %v", i, tt.attr, got, tt.want)
+		}
+	}
+
+}
+
+func TestFilerQueryTruncated(t *testing.T) {
+	fs, cleanup := newRenameTestFilerServer(t)
+	defer cleanup()
+
+	createRenameTestEntries(t, fs,
+		&filer.Entry{FullPath: "/q/a"},
+		&filer.Entry{FullPath: "/q/d/b"},
+	)
+
+	tests := []struct {
+		path      string
+		body      string
+		status    int
+		entries   int
+		truncated bool
+	}{
+		{"/q", `{"recursive": true, "limit": 2}`, http.StatusOK, 2, false},
+		{"/q", `{"recursive": true, "limit": 1}`, http.StatusOK, 1, true},
+		{"/q", `{"limit": 1}`, http.StatusOK, 1, false},
+		{"/missing", `{}`, http.StatusNotFound, 0, false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		fs.queryHandler(w, httptest.NewRequest("QUERY", tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.status {
+			t.Errorf("query %s %s: status %d, want %d", tt.path, tt.body, w.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var result struct {
+			Entries   []interface{}
+			Truncated bool
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("unmarshal %s: %v", w.Body.String(), err)
+		}
+		if len(result.Entries) != tt.entries || result.Truncated != tt.truncated {
+			t.Errorf("query %s %s: %d entries, truncated %v", tt.path, tt.body, len(result.Entries), result.Truncated)
+		}
+	}
+}
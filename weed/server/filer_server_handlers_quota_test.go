@@ -0,0 +1,33 @@
+package weed_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+)
+
+func TestFilerQuotaExceededStatus(t *testing.T) {
+	fs, cleanup := newRenameTestFilerServer(t)
+	defer cleanup()
+
+	createRenameTestEntries(t, fs, &filer.Entry{FullPath: "/q/a", Attr: filer.Attr{FileSize: 10}})
+	fs.filer.FilerConf.AddLocationConf(&filer_pb.FilerConf_PathConf{LocationPrefix: "/q/", MaxBytes: 100, MaxFileCount: 1})
+	fs.filer.CountQuotaUsage(false)
+
+	// the write can not succeed by retrying, so it is a client error
+	r := httptest.NewRequest("PUT", "/q/b", strings.NewReader(strings.Repeat("x", 200)))
+	w := httptest.NewRecorder()
+	if fs.checkQuota(w, r) || w.Code != http.StatusForbidden {
+		t.Errorf("upload over the byte quota: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = httptest.NewRecorder()
+	fs.LinkHandler(w, httptest.NewRequest("POST", "/q/l?op=symlink&target=a", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("symlink over the file count quota: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
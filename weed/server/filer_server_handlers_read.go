@@ -28,7 +28,15 @@ func (fs *FilerServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request,
 		path = path[:len(path)-1]
 	}
 
-	entry, err := fs.filer.FindEntry(context.Background(), util.FullPath(path))
+	// follow symlinks to the target content, unless asked for the symlink itself
+	noFollow := r.FormValue("noFollow") == "true"
+	var entry *filer.Entry
+	var err error
+	if noFollow || fs.option.MaxSymlinkDepth <= 0 {
+		entry, err = fs.filer.FindEntry(context.Background(), util.FullPath(path))
+	} else {
+		entry, err = fs.filer.FindEntryFollowingSymlinks(context.Background(), util.FullPath(path), fs.option.MaxSymlinkDepth)
+	}
 	if err != nil {
 		if path == "/" {
 			fs.listDirectoryHandler(w, r)
@@ -38,6 +46,9 @@ func (fs *FilerServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request,
 			glog.V(1).Infof("Not found %s: %v", path, err)
 			stats.FilerRequestCounter.WithLabelValues("read.notfound").Inc()
 			w.WriteHeader(http.StatusNotFound)
+		} else if err == filer.ErrTooManySymlinks {
+			glog.V(1).Infof("read %s: %v", path, err)
+			w.WriteHeader(http.StatusLoopDetected)
 		} else {
 			glog.V(0).Infof("Internal %s: %v", path, err)
 			stats.FilerRequestCounter.WithLabelValues("read.internalerror").Inc()
@@ -46,7 +57,16 @@ func (fs *FilerServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if entry.IsSymlink() {
+		writeJsonQuiet(w, r, http.StatusOK, entry)
+		return
+	}
+
 	if entry.IsDirectory() {
+		if entry.FullPath != util.FullPath(path) {
+			// list the directory the symlink points to
+			r.URL.Path = string(entry.FullPath) + "/"
+		}
 		if fs.option.DisableDirListing {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
@@ -100,17 +120,15 @@ func (fs *FilerServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request,
 	}
 
 	//set tag count
-	if r.Method == "GET" {
-		tagCount := 0
-		for k := range entry.Extended {
-			if strings.HasPrefix(k, xhttp.AmzObjectTagging+"-") {
-				tagCount++
-			}
-		}
-		if tagCount > 0 {
-			w.Header().Set(xhttp.AmzTagCount, strconv.Itoa(tagCount))
+	tagCount := 0
+	for k := range entry.Extended {
+		if strings.HasPrefix(k, xhttp.AmzObjectTagging+"-") {
+			tagCount++
 		}
 	}
+	if tagCount > 0 {
+		w.Header().Set(xhttp.AmzTagCount, strconv.Itoa(tagCount))
+	}
 
 	// set etag
 	etag := filer.ETagEntry(entry)
@@ -45,7 +45,7 @@ func (fs *FilerServer) PutTaggingHandler(w http.ResponseWriter, r *http.Request)
 
 	if dbErr := fs.filer.CreateEntry(ctx, existingEntry, false, false, nil); dbErr != nil {
 		glog.V(0).Infof("failing to update %s tagging : %v", path, dbErr)
-		writeJsonError(w, r, http.StatusInternalServerError, err)
+		writeJsonError(w, r, http.StatusInternalServerError, dbErr)
 		return
 	}
 
@@ -53,8 +53,9 @@ func (fs *FilerServer) PutTaggingHandler(w http.ResponseWriter, r *http.Request)
 	return
 }
 
-// remove all Seaweed- prefixed attributes
+// remove all Seaweed- prefixed attributes, or only the named ones
 // curl -X DELETE http://localhost:8888/path/to/a/file?tagging
+// curl -X DELETE "http://localhost:8888/path/to/a/file?tagging=Seaweed-Name1,Seaweed-Name2"
 func (fs *FilerServer) DeleteTaggingHandler(w http.ResponseWriter, r *http.Request) {
 
 	ctx := context.Background()
@@ -78,9 +79,17 @@ func (fs *FilerServer) DeleteTaggingHandler(w http.ResponseWriter, r *http.Reque
 		existingEntry.Extended = make(map[string][]byte)
 	}
 
+	var names map[string]bool
+	if tagging := r.URL.Query().Get("tagging"); tagging != "" {
+		names = make(map[string]bool)
+		for _, name := range strings.Split(tagging, ",") {
+			names[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
 	hasDeletion := false
 	for header, _ := range existingEntry.Extended {
-		if strings.HasPrefix(header, needle.PairNamePrefix) {
+		if strings.HasPrefix(header, needle.PairNamePrefix) && (names == nil || names[header]) {
 			delete(existingEntry.Extended, header)
 			hasDeletion = true
 		}
@@ -93,7 +102,7 @@ func (fs *FilerServer) DeleteTaggingHandler(w http.ResponseWriter, r *http.Reque
 
 	if dbErr := fs.filer.CreateEntry(ctx, existingEntry, false, false, nil); dbErr != nil {
 		glog.V(0).Infof("failing to delete %s tagging : %v", path, dbErr)
-		writeJsonError(w, r, http.StatusInternalServerError, err)
+		writeJsonError(w, r, http.StatusInternalServerError, dbErr)
 		return
 	}
 
@@ -13,6 +13,7 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -29,17 +30,27 @@ type FilerPostResult struct {
 	Url   string `json:"url,omitempty"`
 }
 
-func (fs *FilerServer) assignNewFileInfo(so *operation.StorageOption) (fileId, urlLocation string, auth security.EncodedJwt, err error) {
+func (fs *FilerServer) assignNewFileInfo(ctx context.Context, so *operation.StorageOption) (fileId, urlLocation string, auth security.EncodedJwt, err error) {
+
+	_, span := tracing.StartSpan(ctx, "filer.assign", tracing.SpanKindClient)
+	defer span.Finish()
 
 	stats.FilerRequestCounter.WithLabelValues("assign").Inc()
 	start := time.Now()
-	defer func() { stats.FilerRequestHistogram.WithLabelValues("assign").Observe(time.Since(start).Seconds()) }()
+	defer func() {
+		tracing.Observe(stats.FilerRequestHistogram.WithLabelValues("assign"), time.Since(start).Seconds(), span)
+	}()
 
 	ar, altRequest := so.ToAssignRequests(1)
+	ar.Traceparent = span.Traceparent()
+	if altRequest != nil {
+		altRequest.Traceparent = span.Traceparent()
+	}
 
 	assignResult, ae := operation.Assign(fs.filer.GetMaster(), fs.grpcDialOption, ar, altRequest)
 	if ae != nil {
 		glog.Errorf("failing to assign a file id: %v", ae)
+		span.SetError(ae)
 		err = ae
 		return
 	}
@@ -48,15 +59,32 @@ func (fs *FilerServer) assignNewFileInfo(so *operation.StorageOption) (fileId, u
 	if so.Fsync {
 		urlLocation += "?fsync=true"
 	}
+	if fs.option.TwoPhaseCommit {
+		if so.Fsync {
+			urlLocation += "&pending=true"
+		} else {
+			urlLocation += "?pending=true"
+		}
+	}
 	auth = assignResult.Auth
 	return
 }
 
 func (fs *FilerServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 
-	ctx := context.Background()
+	ctx, span := tracing.StartRequestSpan(context.Background(), r, "filer.upload")
+	defer span.Finish()
 
 	query := r.URL.Query()
+	if op := query.Get("op"); op == "symlink" || op == "link" {
+		fs.LinkHandler(w, r)
+		return
+	}
+
+	if !fs.checkQuota(w, r) {
+		return
+	}
+
 	so := fs.detectStorageOption0(r.RequestURI,
 		query.Get("collection"),
 		query.Get("replication"),
@@ -89,6 +117,20 @@ func (fs *FilerServer) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 		objectPath = objectPath[0 : len(objectPath)-1]
 	}
 
+	if isRecursive && r.FormValue("async") == "true" {
+		if err := fs.filer.DeleteInBackground(context.Background(), util.FullPath(objectPath), !skipChunkDeletion); err != nil {
+			glog.V(1).Infoln("deleting", objectPath, "in background:", err.Error())
+			httpStatus := http.StatusInternalServerError
+			if err == filer_pb.ErrNotFound {
+				httpStatus = http.StatusNotFound
+			}
+			writeJsonError(w, r, httpStatus, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
 	err := fs.filer.DeleteEntryMetaAndData(context.Background(), util.FullPath(objectPath), isRecursive, ignoreRecursiveError, !skipChunkDeletion, false, nil)
 	if err != nil {
 		glog.V(1).Infoln("deleting", objectPath, ":", err.Error())
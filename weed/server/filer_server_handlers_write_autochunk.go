@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"strconv"
@@ -22,6 +23,7 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
@@ -41,7 +43,7 @@ func (fs *FilerServer) autoChunk(ctx context.Context, w http.ResponseWriter, r *
 	stats.FilerRequestCounter.WithLabelValues("postAutoChunk").Inc()
 	start := time.Now()
 	defer func() {
-		stats.FilerRequestHistogram.WithLabelValues("postAutoChunk").Observe(time.Since(start).Seconds())
+		tracing.Observe(stats.FilerRequestHistogram.WithLabelValues("postAutoChunk"), time.Since(start).Seconds(), tracing.FromContext(ctx))
 	}()
 
 	var reply *FilerPostResult
@@ -57,7 +59,14 @@ func (fs *FilerServer) autoChunk(ctx context.Context, w http.ResponseWriter, r *
 		reply, md5bytes, err = fs.doPutAutoChunk(ctx, w, r, chunkSize, so)
 	}
 	if err != nil {
-		writeJsonError(w, r, http.StatusInternalServerError, err)
+		tracing.FromContext(ctx).SetError(err)
+		if isRequestBodyTooLarge(err) {
+			writeJsonError(w, r, http.StatusRequestEntityTooLarge, err)
+		} else if isQuotaExceeded(err) {
+			writeJsonError(w, r, http.StatusForbidden, err)
+		} else {
+			writeJsonError(w, r, http.StatusInternalServerError, err)
+		}
 	} else if reply != nil {
 		if len(md5bytes) > 0 {
 			w.Header().Set("Content-MD5", util.Base64Encode(md5bytes))
@@ -87,7 +96,7 @@ func (fs *FilerServer) doPostAutoChunk(ctx context.Context, w http.ResponseWrite
 		contentType = ""
 	}
 
-	fileChunks, md5Hash, chunkOffset, err := fs.uploadReaderToChunks(w, r, part1, chunkSize, fileName, contentType, so)
+	fileChunks, md5Hash, chunkOffset, err := fs.uploadReaderToChunks(ctx, w, r, part1, chunkSize, fileName, contentType, so)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -109,7 +118,11 @@ func (fs *FilerServer) doPutAutoChunk(ctx context.Context, w http.ResponseWriter
 	fileName := ""
 	contentType := ""
 
-	fileChunks, md5Hash, chunkOffset, err := fs.uploadReaderToChunks(w, r, r.Body, chunkSize, fileName, contentType, so)
+	if filerResult, md5bytes, found := fs.reuseUnchangedFile(ctx, r, chunkSize, so); found {
+		return filerResult, md5bytes, nil
+	}
+
+	fileChunks, md5Hash, chunkOffset, err := fs.uploadReaderToChunks(ctx, w, r, r.Body, chunkSize, fileName, contentType, so)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -147,10 +160,10 @@ func (fs *FilerServer) saveMetaData(ctx context.Context, r *http.Request, fileNa
 		}
 	}
 
-	// fix the crTime
+	// fix the crTime, the ttl of the new chunks counts from now
 	existingEntry, err := fs.filer.FindEntry(ctx, util.FullPath(path))
 	crTime := time.Now()
-	if err == nil && existingEntry != nil {
+	if err == nil && existingEntry != nil && so.TtlSeconds == 0 {
 		crTime = existingEntry.Crtime
 	}
 
@@ -190,16 +203,29 @@ func (fs *FilerServer) saveMetaData(ctx context.Context, r *http.Request, fileNa
 		}
 	}
 
+	if err = fs.checkPendingChunks(entry.Chunks); err != nil {
+		fs.filer.DeleteChunks(entry.Chunks)
+		filerResult.Error = err.Error()
+		return filerResult, err
+	}
+
+	_, span := tracing.StartSpan(ctx, "filer.store.insert", tracing.SpanKindInternal)
+	defer span.Finish()
 	if dbErr := fs.filer.CreateEntry(ctx, entry, false, false, nil); dbErr != nil {
+		span.SetError(dbErr)
 		fs.filer.DeleteChunks(entry.Chunks)
 		replyerr = dbErr
 		filerResult.Error = dbErr.Error()
 		glog.V(0).Infof("failing to write %s to filer server : %v", path, dbErr)
+		return filerResult, replyerr
+	}
+	if replyerr = fs.confirmChunks(ctx, entry); replyerr != nil {
+		filerResult.Error = replyerr.Error()
 	}
 	return filerResult, replyerr
 }
 
-func (fs *FilerServer) uploadReaderToChunks(w http.ResponseWriter, r *http.Request, reader io.Reader, chunkSize int32, fileName, contentType string, so *operation.StorageOption) ([]*filer_pb.FileChunk, hash.Hash, int64, error) {
+func (fs *FilerServer) uploadReaderToChunks(ctx context.Context, w http.ResponseWriter, r *http.Request, reader io.Reader, chunkSize int32, fileName, contentType string, so *operation.StorageOption) ([]*filer_pb.FileChunk, hash.Hash, int64, error) {
 	var fileChunks []*filer_pb.FileChunk
 
 	md5Hash := md5.New()
@@ -211,14 +237,24 @@ func (fs *FilerServer) uploadReaderToChunks(w http.ResponseWriter, r *http.Reque
 		limitedReader := io.LimitReader(partReader, int64(chunkSize))
 
 		// assign one file id for one chunk
-		fileId, urlLocation, auth, assignErr := fs.assignNewFileInfo(so)
+		fileId, urlLocation, auth, assignErr := fs.assignNewFileInfo(ctx, so)
 		if assignErr != nil {
+			fs.filer.DeleteChunks(fileChunks)
 			return nil, nil, 0, assignErr
 		}
 
 		// upload the chunk to the volume server
-		uploadResult, uploadErr := fs.doUpload(urlLocation, w, r, limitedReader, fileName, contentType, nil, auth)
+		_, span := tracing.StartSpan(ctx, "filer.upload_chunk", tracing.SpanKindClient)
+		span.SetAttribute("fid", fileId)
+		var pairMap map[string]string
+		if span != nil {
+			pairMap = map[string]string{tracing.TraceparentHeader: span.Traceparent()}
+		}
+		uploadResult, uploadErr := fs.doUpload(urlLocation, w, r, limitedReader, fileName, contentType, pairMap, auth)
+		span.SetError(uploadErr)
+		span.Finish()
 		if uploadErr != nil {
+			fs.filer.DeleteChunks(fileChunks)
 			return nil, nil, 0, uploadErr
 		}
 
@@ -259,7 +295,7 @@ func (fs *FilerServer) saveAsChunk(so *operation.StorageOption) filer.SaveDataAs
 
 	return func(reader io.Reader, name string, offset int64) (*filer_pb.FileChunk, string, string, error) {
 		// assign one file id for one chunk
-		fileId, urlLocation, auth, assignErr := fs.assignNewFileInfo(so)
+		fileId, urlLocation, auth, assignErr := fs.assignNewFileInfo(context.Background(), so)
 		if assignErr != nil {
 			return nil, "", "", assignErr
 		}
@@ -329,12 +365,18 @@ func (fs *FilerServer) saveAmzMetaData(r *http.Request, entry *filer.Entry) {
 		entry.Extended[xhttp.AmzStorageClass] = []byte(sc)
 	}
 
+	if versionId := r.Header.Get(xhttp.AmzVersionId); versionId != "" {
+		entry.Extended[xhttp.AmzVersionId] = []byte(versionId)
+	}
+
 	if tags := r.Header.Get(xhttp.AmzObjectTagging); tags != "" {
-		for _, v := range strings.Split(tags, "&") {
-			tag := strings.Split(v, "=")
-			if len(tag) == 2 {
-				entry.Extended[xhttp.AmzObjectTagging+"-"+tag[0]] = []byte(tag[1])
+		// the tags are url query encoded
+		if parsedTags, err := url.ParseQuery(tags); err == nil {
+			for k, v := range parsedTags {
+				entry.Extended[xhttp.AmzObjectTagging+"-"+k] = []byte(v[0])
 			}
+		} else {
+			glog.V(0).Infof("parse tags %s: %v", tags, err)
 		}
 	}
 
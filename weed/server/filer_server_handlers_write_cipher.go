@@ -18,7 +18,7 @@ import (
 // handling single chunk POST or PUT upload
 func (fs *FilerServer) encrypt(ctx context.Context, w http.ResponseWriter, r *http.Request, so *operation.StorageOption) (filerResult *FilerPostResult, err error) {
 
-	fileId, urlLocation, auth, err := fs.assignNewFileInfo(so)
+	fileId, urlLocation, auth, err := fs.assignNewFileInfo(ctx, so)
 
 	if err != nil || fileId == "" || urlLocation == "" {
 		return nil, fmt.Errorf("fail to allocate volume for %s, collection:%s, datacenter:%s", r.URL.Path, so.Collection, so.DataCenter)
@@ -30,9 +30,9 @@ func (fs *FilerServer) encrypt(ctx context.Context, w http.ResponseWriter, r *ht
 
 	sizeLimit := int64(fs.option.MaxMB) * 1024 * 1024
 
-	pu, err := needle.ParseUpload(r, sizeLimit)
+	pu, err := needle.ParseUpload(r, sizeLimit, 0, "")
 	uncompressedData := pu.Data
-	if pu.IsGzipped {
+	if pu.IsCompressed() {
 		uncompressedData = pu.UncompressedData
 	}
 	if pu.MimeType == "" {
@@ -79,12 +79,20 @@ func (fs *FilerServer) encrypt(ctx context.Context, w http.ResponseWriter, r *ht
 		Size: int64(pu.OriginalDataSize),
 	}
 
+	if err = fs.checkPendingChunks(entry.Chunks); err != nil {
+		fs.filer.DeleteChunks(entry.Chunks)
+		filerResult.Error = err.Error()
+		return
+	}
 	if dbErr := fs.filer.CreateEntry(ctx, entry, false, false, nil); dbErr != nil {
 		fs.filer.DeleteChunks(entry.Chunks)
 		err = dbErr
 		filerResult.Error = dbErr.Error()
 		return
 	}
+	if err = fs.confirmChunks(ctx, entry); err != nil {
+		filerResult.Error = err.Error()
+	}
 
 	return
 }
@@ -0,0 +1,70 @@
+package weed_server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+)
+
+// checkPendingChunks is called before saving the metadata. Chunks written as pending longer ago
+// than -pendingNeedleTtlMinutes may already be deleted by the volume servers, so the upload fails.
+func (fs *FilerServer) checkPendingChunks(chunks []*filer_pb.FileChunk) error {
+	if !fs.option.TwoPhaseCommit {
+		return nil
+	}
+	// leave some time to save the metadata and confirm
+	margin := fs.option.PendingNeedleTtl / 2
+	if margin > time.Minute {
+		margin = time.Minute
+	}
+	cutoff := time.Now().Add(-fs.option.PendingNeedleTtl + margin).UnixNano()
+	for _, chunk := range chunks {
+		if chunk.Mtime < cutoff {
+			return fmt.Errorf("chunk %s was written %v ago, longer than the pending needle ttl %v", chunk.GetFileIdString(), time.Since(time.Unix(0, chunk.Mtime)).Round(time.Second), fs.option.PendingNeedleTtl)
+		}
+	}
+	return nil
+}
+
+// confirmChunks is the second phase of the two phase commit, after the metadata is saved.
+// Chunks not confirmed are deleted by the volume servers after -pendingNeedleTtlMinutes,
+// so if confirming fails, the entry is deleted and the upload fails.
+func (fs *FilerServer) confirmChunks(ctx context.Context, entry *filer.Entry) error {
+	if !fs.option.TwoPhaseCommit || len(entry.Chunks) == 0 {
+		return nil
+	}
+
+	err := fs.doConfirmChunks(entry.Chunks)
+	if err == nil {
+		return nil
+	}
+	glog.Errorf("confirm chunks of %s: %v", entry.FullPath, err)
+	if deleteErr := fs.filer.DeleteEntryMetaAndData(ctx, entry.FullPath, false, false, true, false, nil); deleteErr != nil {
+		glog.Errorf("delete unconfirmed %s: %v", entry.FullPath, deleteErr)
+	}
+	return fmt.Errorf("confirm chunks: %v", err)
+}
+
+func (fs *FilerServer) doConfirmChunks(chunks []*filer_pb.FileChunk) error {
+	dataChunks, manifestChunks, err := filer.ResolveChunkManifest(fs.filer.MasterClient.LookupFileId, chunks)
+	if err != nil {
+		return fmt.Errorf("resolve manifest: %v", err)
+	}
+	var fileIds []string
+	for _, chunk := range append(dataChunks, manifestChunks...) {
+		fileIds = append(fileIds, chunk.GetFileIdString())
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = operation.ConfirmFilesWithLookupVolumeId(fs.grpcDialOption, fileIds, filer.LookupByMasterClientFn(fs.filer.MasterClient)); err == nil {
+			return nil
+		}
+		time.Sleep(time.Duration(i+1) * time.Second)
+	}
+	return err
+}
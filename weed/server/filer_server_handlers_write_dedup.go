@@ -0,0 +1,80 @@
+package weed_server
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// reuseUnchangedFile skips the upload of a PUT whose Content-MD5 and length match the existing file,
+// if the chunks of the file are still stored. The chunks are only looked up in the volume indexes.
+// The body is read and hashed, and is put back for the upload if it does not match, so only bodies
+// fitting in one chunk are considered. Requests with their own metadata, a ttl or a mode are always uploaded.
+func (fs *FilerServer) reuseUnchangedFile(ctx context.Context, r *http.Request, chunkSize int32, so *operation.StorageOption) (filerResult *FilerPostResult, md5bytes []byte, found bool) {
+	contentMd5 := r.Header.Get("Content-MD5")
+	if contentMd5 == "" || r.ContentLength <= 0 || r.ContentLength > int64(chunkSize) || so.TtlSeconds > 0 || r.URL.Query().Get("mode") != "" || strings.HasSuffix(r.URL.Path, "/") {
+		return nil, nil, false
+	}
+	for header := range r.Header {
+		if strings.HasPrefix(header, "X-Amz-") || strings.HasPrefix(header, needle.PairNamePrefix) {
+			return nil, nil, false
+		}
+	}
+	md5bytes = util.Base64Md5ToBytes(contentMd5)
+	if len(md5bytes) == 0 {
+		return nil, nil, false
+	}
+
+	entry, err := fs.filer.FindEntry(ctx, util.FullPath(r.URL.Path))
+	if err != nil || entry.IsDirectory() || len(entry.Chunks) == 0 || len(entry.Extended) > 0 {
+		return nil, nil, false
+	}
+	if !bytes.Equal(entry.Md5, md5bytes) || int64(entry.FileSize) != r.ContentLength || entry.TtlSec > 0 || entry.Mime != "" {
+		return nil, nil, false
+	}
+	if entry.Collection != so.Collection || (so.Replication != "" && entry.Replication != so.Replication) {
+		return nil, nil, false
+	}
+
+	// the Content-MD5 header is only a hint, the body received must match the existing file
+	data := make([]byte, r.ContentLength)
+	n, err := io.ReadFull(r.Body, data)
+	r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(data[:n]), r.Body))
+	if err != nil {
+		return nil, nil, false
+	}
+	hash := md5.Sum(data)
+	if !bytes.Equal(hash[:], md5bytes) {
+		return nil, nil, false
+	}
+
+	var fileIds []string
+	for _, chunk := range entry.Chunks {
+		fileIds = append(fileIds, chunk.GetFileIdString())
+	}
+	if err = operation.HeadFilesWithLookupVolumeId(fs.grpcDialOption, fileIds, filer.LookupByMasterClientFn(fs.filer.MasterClient)); err != nil {
+		glog.V(1).Infof("upload unchanged %s again: %v", entry.FullPath, err)
+		return nil, nil, false
+	}
+
+	// only the modification time changes, the file keeps its mode and owner
+	entry.Mtime = time.Now()
+	if err = fs.filer.CreateEntry(ctx, entry, false, false, nil); err != nil {
+		glog.V(0).Infof("update unchanged %s: %v", entry.FullPath, err)
+		return nil, nil, false
+	}
+	glog.V(3).Infof("reused unchanged %s", entry.FullPath)
+
+	return &FilerPostResult{Size: int64(entry.FileSize)}, md5bytes, true
+}
@@ -0,0 +1,149 @@
+package weed_server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/filer"
+	"github.com/chrislusf/seaweedfs/weed/filer/search"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+	"github.com/chrislusf/seaweedfs/weed/util/log_buffer"
+)
+
+const (
+	searchIndexMaxFileSize = 8 * 1024 * 1024
+	searchDefaultLimit     = 100
+	// reserved while the search is enabled, hiding a file or folder with exactly this path from http,
+	// but not the files under it, like /filer/search/a.txt
+	searchPath = "/filer/search"
+)
+
+// loopUpdateSearchIndex follows the aggregated metadata changes and keeps the full-text index up to date
+func (fs *FilerServer) loopUpdateSearchIndex() {
+
+	lastPersistTime := time.Now()
+	lastReadTime := time.Unix(0, fs.searchIndex.LastProcessedTsNs())
+	glog.V(0).Infof("search index follows metadata changes from %v", lastReadTime)
+
+	eachLogEntryFn := eachLogEntryFn(func(dirPath string, eventNotification *filer_pb.EventNotification, tsNs int64) error {
+		fs.updateSearchIndex(dirPath, eventNotification)
+		if lastPersistTime.Add(time.Minute).Before(time.Now()) {
+			if err := fs.searchIndex.SetLastProcessedTsNs(tsNs); err != nil {
+				glog.Errorf("search index update offset: %v", err)
+			}
+			lastPersistTime = time.Now()
+		}
+		return nil
+	})
+
+	processedTsNs, err := fs.filer.ReadPersistedLogBuffer(lastReadTime, eachLogEntryFn)
+	if err != nil {
+		glog.Errorf("search index reading from persisted logs: %v", err)
+	}
+	if processedTsNs != 0 {
+		lastReadTime = time.Unix(0, processedTsNs)
+	}
+
+	for {
+		lastReadTime, err = fs.filer.MetaAggregator.MetaLogBuffer.LoopProcessLogData(lastReadTime, func() bool {
+			fs.filer.MetaAggregator.ListenersLock.Lock()
+			fs.filer.MetaAggregator.ListenersCond.Wait()
+			fs.filer.MetaAggregator.ListenersLock.Unlock()
+			return true
+		}, eachLogEntryFn)
+		if err != nil {
+			glog.Errorf("search index processed to %v: %v", lastReadTime, err)
+			time.Sleep(3127 * time.Millisecond)
+			if err != log_buffer.ResumeError {
+				return
+			}
+		}
+	}
+
+}
+
+func (fs *FilerServer) updateSearchIndex(dirPath string, eventNotification *filer_pb.EventNotification) {
+
+	oldEntry, newEntry := eventNotification.OldEntry, eventNotification.NewEntry
+
+	var newPath util.FullPath
+	if newEntry != nil {
+		newPath = util.NewFullPath(eventNotification.NewParentPath, newEntry.Name)
+	}
+
+	if oldEntry != nil && !oldEntry.IsDirectory {
+		oldPath := util.NewFullPath(dirPath, oldEntry.Name)
+		if oldPath != newPath {
+			if err := fs.searchIndex.DeleteFile(oldPath); err != nil {
+				glog.Errorf("search index delete %s: %v", oldPath, err)
+			}
+		}
+	}
+
+	if newEntry == nil || newEntry.IsDirectory || newEntry.Attributes == nil {
+		return
+	}
+	if !search.IsIndexable(newEntry.Name, newEntry.Attributes.Mime) {
+		return
+	}
+	fileSize := filer.FileSize(newEntry)
+	if fileSize > searchIndexMaxFileSize {
+		glog.V(1).Infof("search index skips %s: size %d", newPath, fileSize)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := filer.StreamContent(fs.filer.MasterClient, &buf, newEntry.Chunks, 0, int64(fileSize)); err != nil {
+		glog.Errorf("search index read %s: %v", newPath, err)
+		return
+	}
+	text, err := search.ExtractText(newEntry.Name, newEntry.Attributes.Mime, buf.Bytes())
+	if err != nil {
+		glog.V(1).Infof("search index extract text from %s: %v", newPath, err)
+		return
+	}
+	if err := fs.searchIndex.IndexFile(newPath, text); err != nil {
+		glog.Errorf("search index %s: %v", newPath, err)
+	}
+
+}
+
+// searchHandler serves GET /filer/search?q=keyword&path=/docs&limit=100
+func (fs *FilerServer) searchHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "GET" {
+		writeJsonError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("%s is reserved for the full-text search, unsupported method %s", searchPath, r.Method))
+		return
+	}
+
+	queryString := r.FormValue("q")
+	if queryString == "" {
+		writeJsonError(w, r, http.StatusBadRequest, fmt.Errorf("missing query parameter q"))
+		return
+	}
+
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil || limit <= 0 {
+		limit = searchDefaultLimit
+	}
+
+	hits, err := fs.searchIndex.Search(queryString, r.FormValue("path"), limit)
+	if err != nil {
+		glog.V(0).Infof("search %s: %v", queryString, err)
+		writeJsonError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJsonQuiet(w, r, http.StatusOK, struct {
+		Query string              `json:"query"`
+		Hits  []*search.SearchHit `json:"hits"`
+	}{
+		Query: queryString,
+		Hits:  hits,
+	})
+}
@@ -0,0 +1,99 @@
+package weed_server
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/spf13/viper"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/notification"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+const eventNotifierPrefix = "master.notification."
+
+// eventNotifier sends the topology events to the webhooks and the message queue in the background.
+// The events are dropped if they come faster than they can be sent.
+type eventNotifier struct {
+	webhookUrls []string
+	eventTypes  map[string]bool // empty for all event types
+	queue       notification.MessageQueue
+	client      *http.Client
+	eventChan   chan *master_pb.TopologyEvent
+}
+
+// newEventNotifier returns nil if no webhook or message queue is configured.
+func newEventNotifier(config *viper.Viper) (*eventNotifier, error) {
+	n := &eventNotifier{
+		webhookUrls: config.GetStringSlice(eventNotifierPrefix + "webhook_urls"),
+		eventTypes:  make(map[string]bool),
+		client:      &http.Client{Timeout: 10 * time.Second},
+		eventChan:   make(chan *master_pb.TopologyEvent, 1024),
+	}
+	for _, eventType := range config.GetStringSlice(eventNotifierPrefix + "events") {
+		n.eventTypes[eventType] = true
+	}
+	queue, err := notification.NewMessageQueue(config, eventNotifierPrefix)
+	if err != nil {
+		return nil, err
+	}
+	n.queue = queue
+	if len(n.webhookUrls) == 0 && n.queue == nil {
+		return nil, nil
+	}
+	glog.V(0).Infof("send topology events to webhooks %v, message queue: %v", n.webhookUrls, n.queue != nil)
+	go n.loop()
+	return n, nil
+}
+
+func (n *eventNotifier) notify(event *master_pb.TopologyEvent) {
+	if len(n.eventTypes) > 0 && !n.eventTypes[event.Type] {
+		return
+	}
+	select {
+	case n.eventChan <- event:
+	default:
+		glog.Warningf("drop topology event %s: too many pending events", event.Type)
+	}
+}
+
+func (n *eventNotifier) loop() {
+	for event := range n.eventChan {
+		if n.queue != nil {
+			if err := n.queue.SendMessage(event.Type, event); err != nil {
+				glog.Errorf("send topology event %s to %s: %v", event.Type, n.queue.GetName(), err)
+			}
+		}
+		if len(n.webhookUrls) == 0 {
+			continue
+		}
+		body, err := (&jsonpb.Marshaler{}).MarshalToString(event)
+		if err != nil {
+			glog.Errorf("marshal topology event %s: %v", event.Type, err)
+			continue
+		}
+		for _, url := range n.webhookUrls {
+			if err := n.post(url, []byte(body)); err != nil {
+				glog.Errorf("send topology event %s to %s: %v", event.Type, url, err)
+			}
+		}
+	}
+}
+
+func (n *eventNotifier) post(url string, body []byte) error {
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -2,16 +2,15 @@ package weed_server
 
 import (
 	"context"
-	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/storage/backend"
 	"net"
 	"strings"
 	"time"
 
-	"github.com/chrislusf/raft"
 	"google.golang.org/grpc/peer"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"github.com/chrislusf/seaweedfs/weed/topology"
@@ -19,6 +18,8 @@ import (
 
 func (ms *MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServer) error {
 	var dn *topology.DataNode
+	// a stopping volume server reports all its volumes as deleted right before it disconnects
+	var stoppedVolumeCount int
 
 	defer func() {
 		if dn != nil {
@@ -27,10 +28,17 @@ func (ms *MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServ
 			//  the unregister and register can race with each other
 			ms.Topo.UnRegisterDataNode(dn)
 			glog.V(0).Infof("unregister disconnected volume server %s:%d", dn.Ip, dn.Port)
+			if len(dn.GetEcShards()) > 0 {
+				ms.scheduleEcRebuild(dn)
+			}
+			if volumeCount := len(dn.GetVolumes()) + stoppedVolumeCount; volumeCount > 0 {
+				ms.scheduleReplicationRepair(dn, volumeCount)
+			}
 
 			message := &master_pb.VolumeLocation{
 				Url:       dn.Url(),
 				PublicUrl: dn.PublicUrl,
+				GrpcPort:  uint32(dn.GrpcPort),
 			}
 			for _, v := range dn.GetVolumes() {
 				message.DeletedVids = append(message.DeletedVids, uint32(v.Id))
@@ -50,6 +58,7 @@ func (ms *MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServ
 		}
 	}()
 
+	sentCollectionSettingsVersion := int64(-1)
 	for {
 		heartbeat, err := stream.Recv()
 		if err != nil {
@@ -70,25 +79,44 @@ func (ms *MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServ
 			dn = rack.GetOrCreateDataNode(heartbeat.Ip,
 				int(heartbeat.Port), heartbeat.PublicUrl,
 				int64(heartbeat.MaxVolumeCount))
+			dn.GrpcPort = int(heartbeat.GrpcPort)
+			dn.Tags = heartbeat.Tags
 			glog.V(0).Infof("added volume server %v:%d", heartbeat.GetIp(), heartbeat.GetPort())
+			ms.Topo.NotifyDataNodeEvent(topology.EventVolumeServerUp, dn)
 			if err := stream.Send(&master_pb.HeartbeatResponse{
-				VolumeSizeLimit: uint64(ms.option.VolumeSizeLimitMB) * 1024 * 1024,
+				VolumeSizeLimit:       uint64(ms.option.VolumeSizeLimitMB) * 1024 * 1024,
+				AcceptsChangedVolumes: true,
 			}); err != nil {
 				glog.Warningf("SendHeartbeat.Send volume size to %s:%d %v", dn.Ip, dn.Port, err)
 				return err
 			}
 		}
 
-		if heartbeat.MaxVolumeCount != 0 && dn.GetMaxVolumeCount() != int64(heartbeat.MaxVolumeCount) {
+		// only the full heartbeats have the max volume counts by disk type,
+		// where the max volume count can be 0 if all dirs are low on disk space
+		isFullHeartbeat := len(heartbeat.MaxVolumeCounts) > 0
+		if (heartbeat.MaxVolumeCount != 0 || isFullHeartbeat) && dn.GetMaxVolumeCount() != int64(heartbeat.MaxVolumeCount) {
 			delta := int64(heartbeat.MaxVolumeCount) - dn.GetMaxVolumeCount()
 			dn.UpAdjustMaxVolumeCountDelta(delta)
 		}
+		if isFullHeartbeat {
+			dn.SetMaxVolumeCounts(heartbeat.MaxVolumeCounts)
+			dn.SetDiskSpaceLowDirs(heartbeat.DiskSpaceLowDirs)
+		}
 
 		glog.V(4).Infof("master received heartbeat %s", heartbeat.String())
 		message := &master_pb.VolumeLocation{
 			Url:        dn.Url(),
 			PublicUrl:  dn.PublicUrl,
 			DataCenter: string(dn.GetDataCenter().Id()),
+			GrpcPort:   uint32(dn.GrpcPort),
+		}
+		if len(heartbeat.ChangedVolumes) > 0 {
+			// the delta heartbeat only has the volumes added or changed since the last heartbeat
+			for _, v := range ms.Topo.SyncDataNodeChangedVolumes(heartbeat.ChangedVolumes, dn) {
+				glog.V(0).Infof("master see new volume %d from %s", uint32(v.Id), dn.Url())
+				message.NewVids = append(message.NewVids, uint32(v.Id))
+			}
 		}
 		if len(heartbeat.NewVolumes) > 0 || len(heartbeat.DeletedVolumes) > 0 {
 			// process delta volume ids if exists for fast volume id updates
@@ -105,6 +133,9 @@ func (ms *MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServ
 		if len(heartbeat.Volumes) > 0 || heartbeat.HasNoVolumes {
 			// process heartbeat.Volumes
 			newVolumes, deletedVolumes := ms.Topo.SyncDataNodeRegistration(heartbeat.Volumes, dn)
+			if heartbeat.HasNoVolumes {
+				stoppedVolumeCount = len(deletedVolumes)
+			}
 
 			for _, v := range newVolumes {
 				glog.V(0).Infof("master see new volume %d from %s", uint32(v.Id), dn.Url())
@@ -164,9 +195,16 @@ func (ms *MasterServer) SendHeartbeat(stream master_pb.Seaweed_SendHeartbeatServ
 			glog.Warningf("SendHeartbeat find leader: %v", err)
 			return err
 		}
-		if err := stream.Send(&master_pb.HeartbeatResponse{
+		resp := &master_pb.HeartbeatResponse{
 			Leader: newLeader,
-		}); err != nil {
+		}
+		// and the compression and write concern of the collections, when changed
+		if compressions, version := ms.Topo.GetCollectionCompressions(); version != sentCollectionSettingsVersion {
+			resp.CollectionCompressions = &master_pb.CollectionCompressions{Compressions: compressions}
+			resp.CollectionWriteConcerns = &master_pb.CollectionWriteConcerns{WriteConcerns: ms.Topo.GetCollectionWriteConcerns()}
+			sentCollectionSettingsVersion = version
+		}
+		if err := stream.Send(resp); err != nil {
 			glog.Warningf("SendHeartbeat.Send response to to %s:%d %v", dn.Ip, dn.Port, err)
 			return err
 		}
@@ -186,7 +224,7 @@ func (ms *MasterServer) KeepConnected(stream master_pb.Seaweed_KeepConnectedServ
 		return ms.informNewLeader(stream)
 	}
 
-	peerAddress := findClientAddress(stream.Context(), req.GrpcPort)
+	peerAddress := findClientAddress(stream.Context(), req.GrpcPort, req.AdvertisedGrpcPort)
 
 	// buffer by 1 so we don't end up getting stuck writing to stopChan forever
 	stopChan := make(chan bool, 1)
@@ -235,7 +273,7 @@ func (ms *MasterServer) informNewLeader(stream master_pb.Seaweed_KeepConnectedSe
 	leader, err := ms.Topo.Leader()
 	if err != nil {
 		glog.Errorf("topo leader: %v", err)
-		return raft.NotLeaderError
+		return errNotLeader()
 	}
 	if err := stream.Send(&master_pb.VolumeLocation{
 		Leader: leader,
@@ -269,7 +307,7 @@ func (ms *MasterServer) deleteClient(clientName string) {
 	ms.clientChansLock.Unlock()
 }
 
-func findClientAddress(ctx context.Context, grpcPort uint32) string {
+func findClientAddress(ctx context.Context, grpcPort uint32, advertisedGrpcPort uint32) string {
 	// fmt.Printf("FromContext %+v\n", ctx)
 	pr, ok := peer.FromContext(ctx)
 	if !ok {
@@ -285,7 +323,7 @@ func findClientAddress(ctx context.Context, grpcPort uint32) string {
 	}
 	if tcpAddr, ok := pr.Addr.(*net.TCPAddr); ok {
 		externalIP := tcpAddr.IP
-		return fmt.Sprintf("%s:%d", externalIP, grpcPort)
+		return pb.NewServerAddress(externalIP.String(), int(grpcPort), int(advertisedGrpcPort))
 	}
 	return pr.Addr.String()
 
@@ -315,6 +353,9 @@ func (ms *MasterServer) GetMasterConfiguration(ctx context.Context, req *master_
 		StorageBackends:        backend.ToPbStorageBackends(),
 		DefaultReplication:     ms.option.DefaultReplicaPlacement,
 		Leader:                 leader,
+		MetricsJobLabel:        ms.option.MetricsJobLabel,
+		MetricsInstanceLabel:   ms.option.MetricsInstanceLabel,
+		GarbageThreshold:       ms.option.GarbageThreshold,
 	}
 
 	return resp, nil
@@ -2,7 +2,6 @@ package weed_server
 
 import (
 	"context"
-	"fmt"
 	"math/rand"
 	"sync"
 	"time"
@@ -121,7 +120,7 @@ func (ms *MasterServer) LeaseAdminToken(ctx context.Context, req *master_pb.Leas
 			return resp, nil
 		}
 		// refuse since still locked
-		return resp, fmt.Errorf("already locked")
+		return resp, masterError(master_pb.ErrorDetail_ALREADY_LOCKED, "already locked")
 	}
 	// for fresh lease request
 	ts, token := ms.adminLocks.generateToken(req.LockName)
@@ -3,17 +3,21 @@ package weed_server
 import (
 	"context"
 
-	"github.com/chrislusf/raft"
-
 	"github.com/chrislusf/seaweedfs/weed/operation"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+	"github.com/chrislusf/seaweedfs/weed/storage/backend"
+	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
 func (ms *MasterServer) CollectionList(ctx context.Context, req *master_pb.CollectionListRequest) (*master_pb.CollectionListResponse, error) {
 
 	if !ms.Topo.IsLeader() {
-		return nil, raft.NotLeaderError
+		return nil, errNotLeader()
 	}
 
 	resp := &master_pb.CollectionListResponse{}
@@ -30,7 +34,7 @@ func (ms *MasterServer) CollectionList(ctx context.Context, req *master_pb.Colle
 func (ms *MasterServer) CollectionDelete(ctx context.Context, req *master_pb.CollectionDeleteRequest) (*master_pb.CollectionDeleteResponse, error) {
 
 	if !ms.Topo.IsLeader() {
-		return nil, raft.NotLeaderError
+		return nil, errNotLeader()
 	}
 
 	resp := &master_pb.CollectionDeleteResponse{}
@@ -58,7 +62,7 @@ func (ms *MasterServer) doDeleteNormalCollection(collectionName string) error {
 	}
 
 	for _, server := range collection.ListVolumeServers() {
-		err := operation.WithVolumeServerClient(server.Url(), ms.grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		err := operation.WithVolumeServerClient(server.ServerAddress(), ms.grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
 			_, deleteErr := client.DeleteCollection(context.Background(), &volume_server_pb.DeleteCollectionRequest{
 				Collection: collectionName,
 			})
@@ -93,3 +97,89 @@ func (ms *MasterServer) doDeleteEcCollection(collectionName string) error {
 
 	return nil
 }
+
+// SetCollectionSettings replaces the overrides of one collection, or removes them if all fields are empty.
+// Unset fields use the master defaults. The size limit also stops writes to the existing volumes over it,
+// the compression reaches the volume servers with their next heartbeat response, and the replication
+// and disk type only apply to the volumes grown later.
+func (ms *MasterServer) SetCollectionSettings(ctx context.Context, req *master_pb.SetCollectionSettingsRequest) (*master_pb.SetCollectionSettingsResponse, error) {
+	if !ms.Topo.IsLeader() {
+		return nil, errNotLeader()
+	}
+	settings := req.GetSettings()
+	if settings == nil {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "missing collection settings")
+	}
+	if settings.VolumeSizeLimitMb*1024*1024 > types.MaxPossibleVolumeSize {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "volume size limit %dMB is larger than %dMB", settings.VolumeSizeLimitMb, types.MaxPossibleVolumeSize/1024/1024)
+	}
+	if settings.Replication != "" {
+		if _, err := super_block.NewReplicaPlacementFromString(settings.Replication); err != nil {
+			return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "replication %s: %v", settings.Replication, err)
+		}
+	}
+	if settings.DiskType != "" {
+		settings.DiskType = storage.ToDiskType(settings.DiskType)
+	}
+	if !util.IsValidCompression(settings.Compression) {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "compression %s: %v", settings.Compression, util.UnsupportedCompression)
+	}
+	if settings.GarbageThreshold < 0 || settings.GarbageThreshold > 1 {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "garbage threshold %v is not between 0 and 1", settings.GarbageThreshold)
+	}
+	if !topology.IsValidWriteConcern(settings.WriteConcern) {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "write concern %s is not one, quorum or all", settings.WriteConcern)
+	}
+	if settings.TierDest != "" {
+		if _, found := backend.BackendStorages[settings.TierDest]; !found {
+			return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "storage backend %s is not configured in master.toml", settings.TierDest)
+		}
+	}
+
+	if _, err := ms.Topo.RaftServer.Do(&topology.CollectionSettingsCommand{
+		CollectionSettings: topology.CollectionSettings{
+			Collection:          settings.Collection,
+			VolumeSizeLimitMB:   settings.VolumeSizeLimitMb,
+			Replication:         settings.Replication,
+			DiskType:            settings.DiskType,
+			Compression:         settings.Compression,
+			GarbageThreshold:    settings.GarbageThreshold,
+			WriteConcern:        settings.WriteConcern,
+			TierDest:            settings.TierDest,
+			TierFullPercent:     settings.TierFullPercent,
+			TierQuietForSeconds: settings.TierQuietForSeconds,
+		},
+	}); err != nil {
+		return nil, masterError(master_pb.ErrorDetail_UNKNOWN, "set collection %s settings: %v", settings.Collection, err)
+	}
+	return &master_pb.SetCollectionSettingsResponse{}, nil
+}
+
+func (ms *MasterServer) ListCollectionSettings(ctx context.Context, req *master_pb.ListCollectionSettingsRequest) (*master_pb.ListCollectionSettingsResponse, error) {
+	resp := &master_pb.ListCollectionSettingsResponse{}
+	for _, settings := range ms.Topo.ListCollectionSettings() {
+		resp.Settings = append(resp.Settings, &master_pb.CollectionSettings{
+			Collection:          settings.Collection,
+			VolumeSizeLimitMb:   settings.VolumeSizeLimitMB,
+			Replication:         settings.Replication,
+			DiskType:            settings.DiskType,
+			Compression:         settings.Compression,
+			GarbageThreshold:    settings.GarbageThreshold,
+			WriteConcern:        settings.WriteConcern,
+			TierDest:            settings.TierDest,
+			TierFullPercent:     settings.TierFullPercent,
+			TierQuietForSeconds: settings.TierQuietForSeconds,
+		})
+	}
+	return resp, nil
+}
+
+// defaultReplication is the replication of the collection settings, or the master default.
+func (ms *MasterServer) defaultReplication(collection string) string {
+	return util.Nvl(ms.Topo.GetDefaultReplication(collection), ms.option.DefaultReplicaPlacement)
+}
+
+// diskType is the disk type asked for, or the disk type of the collection settings.
+func (ms *MasterServer) diskType(collection, diskType string) string {
+	return util.Nvl(diskType, ms.Topo.GetDefaultDiskType(collection))
+}
@@ -0,0 +1,43 @@
+package weed_server
+
+import (
+	"fmt"
+
+	"github.com/chrislusf/raft"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+var errorCodeToGrpcCode = map[master_pb.ErrorDetail_ErrorCode]codes.Code{
+	master_pb.ErrorDetail_NOT_LEADER:                 codes.Unavailable,
+	master_pb.ErrorDetail_INVALID_ARGUMENT:           codes.InvalidArgument,
+	master_pb.ErrorDetail_NO_WRITABLE_VOLUME:         codes.ResourceExhausted,
+	master_pb.ErrorDetail_REPLICATION_NOT_ACHIEVABLE: codes.FailedPrecondition,
+	master_pb.ErrorDetail_QUOTA_EXCEEDED:             codes.ResourceExhausted,
+	master_pb.ErrorDetail_RATE_LIMITED:               codes.ResourceExhausted,
+	master_pb.ErrorDetail_VOLUME_NOT_FOUND:           codes.NotFound,
+	master_pb.ErrorDetail_ALREADY_LOCKED:             codes.FailedPrecondition,
+}
+
+// masterError builds a gRPC status error carrying a master_pb.ErrorDetail,
+// so clients can check the error code instead of matching the message.
+func masterError(code master_pb.ErrorDetail_ErrorCode, format string, args ...interface{}) error {
+	grpcCode, found := errorCodeToGrpcCode[code]
+	if !found {
+		grpcCode = codes.Internal
+	}
+	st := status.New(grpcCode, fmt.Sprintf(format, args...))
+	detailed, err := st.WithDetails(&master_pb.ErrorDetail{Code: code})
+	if err != nil {
+		glog.Errorf("attach error detail %v: %v", code, err)
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func errNotLeader() error {
+	return masterError(master_pb.ErrorDetail_NOT_LEADER, "%v", raft.NotLeaderError)
+}
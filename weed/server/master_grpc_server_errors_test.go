@@ -0,0 +1,23 @@
+package weed_server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func TestMasterErrorCode(t *testing.T) {
+
+	err := masterError(master_pb.ErrorDetail_NO_WRITABLE_VOLUME, "No free volumes left!")
+	if code := operation.MasterErrorCode(err); code != master_pb.ErrorDetail_NO_WRITABLE_VOLUME {
+		t.Errorf("unexpected error code %v", code)
+	}
+	if !operation.IsMasterError(errNotLeader(), master_pb.ErrorDetail_NOT_LEADER) {
+		t.Errorf("expected not leader error")
+	}
+	if code := operation.MasterErrorCode(fmt.Errorf("plain error")); code != master_pb.ErrorDetail_UNKNOWN {
+		t.Errorf("unexpected error code %v for plain error", code)
+	}
+}
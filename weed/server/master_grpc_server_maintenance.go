@@ -0,0 +1,47 @@
+package weed_server
+
+import (
+	"context"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+)
+
+// SetMaintenance stops or resumes assigning file ids and growing volumes on a data center, a rack, or one data node.
+// The volumes there stay readable, and the state is kept for a data node that is not connected yet.
+func (ms *MasterServer) SetMaintenance(ctx context.Context, req *master_pb.SetMaintenanceRequest) (*master_pb.SetMaintenanceResponse, error) {
+	if !ms.Topo.IsLeader() {
+		return nil, errNotLeader()
+	}
+	target := topology.MaintenanceTarget{
+		DataCenter: req.Target.GetDataCenter(),
+		Rack:       req.Target.GetRack(),
+		DataNode:   req.Target.GetDataNode(),
+	}
+	if target.DataNode != "" && (target.DataCenter != "" || target.Rack != "") {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "set either the data node, or the data center and rack")
+	}
+	if target.DataNode == "" && target.DataCenter == "" {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "missing data center or data node")
+	}
+
+	if _, err := ms.Topo.RaftServer.Do(&topology.MaintenanceCommand{
+		MaintenanceTarget: target,
+		Enabled:           req.Enabled,
+	}); err != nil {
+		return nil, masterError(master_pb.ErrorDetail_UNKNOWN, "set maintenance of %s: %v", target, err)
+	}
+	return &master_pb.SetMaintenanceResponse{}, nil
+}
+
+func (ms *MasterServer) ListMaintenance(ctx context.Context, req *master_pb.ListMaintenanceRequest) (*master_pb.ListMaintenanceResponse, error) {
+	resp := &master_pb.ListMaintenanceResponse{}
+	for _, target := range ms.Topo.ListMaintenance() {
+		resp.Targets = append(resp.Targets, &master_pb.MaintenanceTarget{
+			DataCenter: target.DataCenter,
+			Rack:       target.Rack,
+			DataNode:   target.DataNode,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,169 @@
+package weed_server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/chrislusf/raft"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func (ms *MasterServer) RaftListClusterServers(ctx context.Context, req *master_pb.RaftListClusterServersRequest) (*master_pb.RaftListClusterServersResponse, error) {
+	if ms.Topo.RaftServer == nil {
+		return nil, masterError(master_pb.ErrorDetail_UNKNOWN, "raft server is not started")
+	}
+	raftServer := ms.Topo.RaftServer
+	leader := raftServer.Leader()
+
+	resp := &master_pb.RaftListClusterServersResponse{}
+	resp.ClusterServers = append(resp.ClusterServers, &master_pb.RaftListClusterServersResponse_ClusterServer{
+		Id:       raftServer.Name(),
+		Address:  pb.ServerToGrpcAddress(raftServer.Name()),
+		IsLeader: raftServer.Name() == leader,
+	})
+	for _, peer := range raftServer.Peers() {
+		resp.ClusterServers = append(resp.ClusterServers, &master_pb.RaftListClusterServersResponse_ClusterServer{
+			Id:       peer.Name,
+			Address:  peer.ConnectionString,
+			IsLeader: peer.Name == leader,
+		})
+	}
+	sort.Slice(resp.ClusterServers, func(i, j int) bool {
+		return resp.ClusterServers[i].Id < resp.ClusterServers[j].Id
+	})
+	return resp, nil
+}
+
+// RaftAddServer adds a master to the raft cluster. The change is committed through the raft log,
+// so all masters, including the ones restarted with -resumeState, know the new member.
+func (ms *MasterServer) RaftAddServer(ctx context.Context, req *master_pb.RaftAddServerRequest) (*master_pb.RaftAddServerResponse, error) {
+	if !ms.Topo.IsLeader() {
+		return nil, errNotLeader()
+	}
+	if req.Id == "" {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "missing master id <host>:<port>")
+	}
+	address := req.Address
+	if address == "" {
+		var err error
+		if address, err = pb.ParseServerToGrpcAddress(req.Id); err != nil {
+			return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "master id %s: %v", req.Id, err)
+		}
+	}
+	if _, found := ms.Topo.RaftServer.Peers()[req.Id]; found || req.Id == ms.Topo.RaftServer.Name() {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "master %s is already in the raft cluster", req.Id)
+	}
+
+	glog.V(0).Infof("add master %s at %s to the raft cluster", req.Id, address)
+	if _, err := ms.Topo.RaftServer.Do(&raft.DefaultJoinCommand{
+		Name:             req.Id,
+		ConnectionString: address,
+	}); err != nil {
+		return nil, masterError(master_pb.ErrorDetail_UNKNOWN, "add master %s: %v", req.Id, err)
+	}
+	return &master_pb.RaftAddServerResponse{}, nil
+}
+
+// RaftRemoveServer removes a master from the raft cluster, committed through the raft log.
+// The master should be stopped first; the leader can not remove itself.
+func (ms *MasterServer) RaftRemoveServer(ctx context.Context, req *master_pb.RaftRemoveServerRequest) (*master_pb.RaftRemoveServerResponse, error) {
+	if !ms.Topo.IsLeader() {
+		return nil, errNotLeader()
+	}
+	if req.Id == ms.Topo.RaftServer.Name() {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "master %s is the leader, stop it and remove it from the new leader", req.Id)
+	}
+	if _, found := ms.Topo.RaftServer.Peers()[req.Id]; !found {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "master %s is not in the raft cluster", req.Id)
+	}
+
+	glog.V(0).Infof("remove master %s from the raft cluster", req.Id)
+	if _, err := ms.Topo.RaftServer.Do(&raft.DefaultLeaveCommand{
+		Name: req.Id,
+	}); err != nil {
+		return nil, masterError(master_pb.ErrorDetail_UNKNOWN, "remove master %s: %v", req.Id, err)
+	}
+	return &master_pb.RaftRemoveServerResponse{}, nil
+}
+
+// RaftTakeOverLeadership is called by a leader that is shutting down. The follower shortens its election
+// timeout, so it starts an election soon after the leader stops sending heartbeats.
+func (ms *MasterServer) RaftTakeOverLeadership(ctx context.Context, req *master_pb.RaftTakeOverLeadershipRequest) (*master_pb.RaftTakeOverLeadershipResponse, error) {
+	raftServer := ms.Topo.RaftServer
+	if raftServer == nil {
+		return nil, masterError(master_pb.ErrorDetail_UNKNOWN, "raft server is not started")
+	}
+	if raftServer.State() != raft.Follower || raftServer.Leader() != req.Leader {
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "master %s is %s with leader %s, not a follower of %s",
+			raftServer.Name(), raftServer.State(), raftServer.Leader(), req.Leader)
+	}
+
+	glog.V(0).Infof("take over the raft leadership from %s", req.Leader)
+	raftServer.SetElectionTimeout(raftTakeOverElectionTimeout)
+	time.AfterFunc(raftElectionTimeout, func() {
+		raftServer.SetElectionTimeout(raftElectionTimeout)
+	})
+	return &master_pb.RaftTakeOverLeadershipResponse{}, nil
+}
+
+// handOverLeadership asks the most recently active follower to take over, stops the raft server,
+// and waits for the follower to see the new leader.
+func (ms *MasterServer) handOverLeadership() {
+	raftServer := ms.Topo.RaftServer
+	if !ms.Topo.IsLeader() {
+		raftServer.Stop()
+		return
+	}
+
+	var follower *raft.Peer
+	for _, peer := range raftServer.Peers() {
+		if follower == nil || peer.LastActivity().After(follower.LastActivity()) {
+			follower = peer
+		}
+	}
+	if follower == nil {
+		raftServer.Stop()
+		return
+	}
+
+	err := pb.WithMasterClient(follower.Name, ms.grpcDialOption, func(client master_pb.SeaweedClient) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := client.RaftTakeOverLeadership(ctx, &master_pb.RaftTakeOverLeadershipRequest{
+			Leader: raftServer.Name(),
+		})
+		return err
+	})
+	if err != nil {
+		glog.Warningf("hand over the raft leadership to %s: %v", follower.Name, err)
+		raftServer.Stop()
+		return
+	}
+	glog.V(0).Infof("hand over the raft leadership to %s", follower.Name)
+
+	// the follower applies the shorter election timeout when it receives the next heartbeat
+	time.Sleep(2 * raftServer.HeartbeatInterval())
+	raftServer.Stop()
+
+	for start := time.Now(); time.Since(start) < raftElectionTimeout; time.Sleep(100 * time.Millisecond) {
+		var leader string
+		pb.WithMasterClient(follower.Name, ms.grpcDialOption, func(client master_pb.SeaweedClient) error {
+			resp, err := client.RaftListClusterServers(context.Background(), &master_pb.RaftListClusterServersRequest{})
+			for _, server := range resp.GetClusterServers() {
+				if server.IsLeader && server.Id != raftServer.Name() {
+					leader = server.Id
+				}
+			}
+			return err
+		})
+		if leader != "" {
+			glog.V(0).Infof("%s becomes the new leader after %v", leader, time.Since(start))
+			return
+		}
+	}
+	glog.Warningf("no new leader elected in %v", raftElectionTimeout)
+}
@@ -0,0 +1,40 @@
+package weed_server
+
+import (
+	"context"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+)
+
+// SetVacuumWindows replaces the daily windows, in the local time of the masters, when the periodic
+// vacuum may compact volumes. A vacuum still running when its window closes stops before the next volume.
+// No windows lets it run at any time. The vacuum requested through /vol/vacuum ignores the windows.
+func (ms *MasterServer) SetVacuumWindows(ctx context.Context, req *master_pb.SetVacuumWindowsRequest) (*master_pb.SetVacuumWindowsResponse, error) {
+	if !ms.Topo.IsLeader() {
+		return nil, errNotLeader()
+	}
+	var windows []topology.VacuumWindow
+	for _, w := range req.Windows {
+		window, err := topology.ParseVacuumWindow(w)
+		if err != nil {
+			return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "%v", err)
+		}
+		windows = append(windows, window)
+	}
+
+	if _, err := ms.Topo.RaftServer.Do(&topology.VacuumWindowsCommand{
+		Windows: windows,
+	}); err != nil {
+		return nil, masterError(master_pb.ErrorDetail_UNKNOWN, "set vacuum windows: %v", err)
+	}
+	return &master_pb.SetVacuumWindowsResponse{}, nil
+}
+
+func (ms *MasterServer) ListVacuumWindows(ctx context.Context, req *master_pb.ListVacuumWindowsRequest) (*master_pb.ListVacuumWindowsResponse, error) {
+	resp := &master_pb.ListVacuumWindowsResponse{}
+	for _, w := range ms.Topo.ListVacuumWindows() {
+		resp.Windows = append(resp.Windows, string(w))
+	}
+	return resp, nil
+}
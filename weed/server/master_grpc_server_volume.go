@@ -2,31 +2,34 @@ package weed_server
 
 import (
 	"context"
-	"fmt"
-	"github.com/chrislusf/raft"
 
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
 	"github.com/chrislusf/seaweedfs/weed/topology"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 )
 
 func (ms *MasterServer) LookupVolume(ctx context.Context, req *master_pb.LookupVolumeRequest) (*master_pb.LookupVolumeResponse, error) {
+	defer observeMasterRequest("lookup")()
 
 	if !ms.Topo.IsLeader() {
-		return nil, raft.NotLeaderError
+		return nil, errNotLeader()
 	}
 
 	resp := &master_pb.LookupVolumeResponse{}
-	volumeLocations := ms.lookupVolumeId(req.VolumeIds, req.Collection)
+	volumeLocations := ms.lookupVolumeId(req.VolumeIds, req.Collection, req.DataCenter, req.Rack)
 
 	for _, result := range volumeLocations {
 		var locations []*master_pb.Location
 		for _, loc := range result.Locations {
 			locations = append(locations, &master_pb.Location{
-				Url:       loc.Url,
-				PublicUrl: loc.PublicUrl,
+				Url:        loc.Url,
+				PublicUrl:  loc.PublicUrl,
+				GrpcPort:   uint32(loc.GrpcPort),
+				DataCenter: loc.DataCenter,
+				Rack:       loc.Rack,
 			})
 		}
 		resp.VolumeIdLocations = append(resp.VolumeIdLocations, &master_pb.LookupVolumeResponse_VolumeIdLocation{
@@ -40,9 +43,14 @@ func (ms *MasterServer) LookupVolume(ctx context.Context, req *master_pb.LookupV
 }
 
 func (ms *MasterServer) Assign(ctx context.Context, req *master_pb.AssignRequest) (*master_pb.AssignResponse, error) {
+	defer observeMasterRequest("assign")()
+
+	_, span := tracing.StartGrpcSpan(ctx, "master.assign")
+	defer span.Finish()
+	span.SetAttribute("collection", req.Collection)
 
 	if !ms.Topo.IsLeader() {
-		return nil, raft.NotLeaderError
+		return nil, errNotLeader()
 	}
 
 	if req.Count == 0 {
@@ -50,15 +58,15 @@ func (ms *MasterServer) Assign(ctx context.Context, req *master_pb.AssignRequest
 	}
 
 	if req.Replication == "" {
-		req.Replication = ms.option.DefaultReplicaPlacement
+		req.Replication = ms.defaultReplication(req.Collection)
 	}
 	replicaPlacement, err := super_block.NewReplicaPlacementFromString(req.Replication)
 	if err != nil {
-		return nil, err
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "replication %s: %v", req.Replication, err)
 	}
 	ttl, err := needle.ReadTTL(req.Ttl)
 	if err != nil {
-		return nil, err
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "ttl %s: %v", req.Ttl, err)
 	}
 
 	option := &topology.VolumeGrowOption{
@@ -70,24 +78,25 @@ func (ms *MasterServer) Assign(ctx context.Context, req *master_pb.AssignRequest
 		Rack:               req.Rack,
 		DataNode:           req.DataNode,
 		MemoryMapMaxSizeMb: req.MemoryMapMaxSizeMb,
+		DiskType:           ms.diskType(req.Collection, req.DiskType),
 	}
 
 	if !ms.Topo.HasWritableVolume(option) {
 		if ms.Topo.FreeSpace() <= 0 {
-			return nil, fmt.Errorf("No free volumes left!")
+			return nil, masterError(master_pb.ErrorDetail_NO_WRITABLE_VOLUME, "No free volumes left!")
 		}
 		ms.vgLock.Lock()
 		if !ms.Topo.HasWritableVolume(option) {
 			if _, err = ms.vg.AutomaticGrowByType(option, ms.grpcDialOption, ms.Topo, int(req.WritableVolumeCount)); err != nil {
 				ms.vgLock.Unlock()
-				return nil, fmt.Errorf("Cannot grow volume group! %v", err)
+				return nil, masterError(master_pb.ErrorDetail_REPLICATION_NOT_ACHIEVABLE, "Cannot grow volume group! %v", err)
 			}
 		}
 		ms.vgLock.Unlock()
 	}
 	fid, count, dn, err := ms.Topo.PickForWrite(req.Count, option)
 	if err != nil {
-		return nil, fmt.Errorf("%v", err)
+		return nil, masterError(master_pb.ErrorDetail_NO_WRITABLE_VOLUME, "%v", err)
 	}
 
 	return &master_pb.AssignResponse{
@@ -102,19 +111,19 @@ func (ms *MasterServer) Assign(ctx context.Context, req *master_pb.AssignRequest
 func (ms *MasterServer) Statistics(ctx context.Context, req *master_pb.StatisticsRequest) (*master_pb.StatisticsResponse, error) {
 
 	if !ms.Topo.IsLeader() {
-		return nil, raft.NotLeaderError
+		return nil, errNotLeader()
 	}
 
 	if req.Replication == "" {
-		req.Replication = ms.option.DefaultReplicaPlacement
+		req.Replication = ms.defaultReplication(req.Collection)
 	}
 	replicaPlacement, err := super_block.NewReplicaPlacementFromString(req.Replication)
 	if err != nil {
-		return nil, err
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "replication %s: %v", req.Replication, err)
 	}
 	ttl, err := needle.ReadTTL(req.Ttl)
 	if err != nil {
-		return nil, err
+		return nil, masterError(master_pb.ErrorDetail_INVALID_ARGUMENT, "ttl %s: %v", req.Ttl, err)
 	}
 
 	volumeLayout := ms.Topo.GetVolumeLayout(req.Collection, replicaPlacement, ttl)
@@ -134,7 +143,7 @@ func (ms *MasterServer) Statistics(ctx context.Context, req *master_pb.Statistic
 func (ms *MasterServer) VolumeList(ctx context.Context, req *master_pb.VolumeListRequest) (*master_pb.VolumeListResponse, error) {
 
 	if !ms.Topo.IsLeader() {
-		return nil, raft.NotLeaderError
+		return nil, errNotLeader()
 	}
 
 	resp := &master_pb.VolumeListResponse{
@@ -148,7 +157,7 @@ func (ms *MasterServer) VolumeList(ctx context.Context, req *master_pb.VolumeLis
 func (ms *MasterServer) LookupEcVolume(ctx context.Context, req *master_pb.LookupEcVolumeRequest) (*master_pb.LookupEcVolumeResponse, error) {
 
 	if !ms.Topo.IsLeader() {
-		return nil, raft.NotLeaderError
+		return nil, errNotLeader()
 	}
 
 	resp := &master_pb.LookupEcVolumeResponse{}
@@ -156,7 +165,7 @@ func (ms *MasterServer) LookupEcVolume(ctx context.Context, req *master_pb.Looku
 	ecLocations, found := ms.Topo.LookupEcShards(needle.VolumeId(req.VolumeId))
 
 	if !found {
-		return resp, fmt.Errorf("ec volume %d not found", req.VolumeId)
+		return resp, masterError(master_pb.ErrorDetail_VOLUME_NOT_FOUND, "ec volume %d not found", req.VolumeId)
 	}
 
 	resp.VolumeId = req.VolumeId
@@ -167,6 +176,7 @@ func (ms *MasterServer) LookupEcVolume(ctx context.Context, req *master_pb.Looku
 			locations = append(locations, &master_pb.Location{
 				Url:       string(dn.Id()),
 				PublicUrl: dn.PublicUrl,
+				GrpcPort:  uint32(dn.GrpcPort),
 			})
 		}
 		resp.ShardIdLocations = append(resp.ShardIdLocations, &master_pb.LookupEcVolumeResponse_EcShardIdLocation{
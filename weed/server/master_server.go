@@ -16,23 +16,27 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/sequence"
 	"github.com/chrislusf/seaweedfs/weed/shell"
+	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/topology"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"github.com/chrislusf/seaweedfs/weed/wdclient"
 )
 
 const (
-	SequencerType     = "master.sequencer.type"
-	SequencerEtcdUrls = "master.sequencer.sequencer_etcd_urls"
+	SequencerType        = "master.sequencer.type"
+	SequencerEtcdUrls    = "master.sequencer.sequencer_etcd_urls"
+	SequencerSnowflakeId = "master.sequencer.sequencer_snowflake_id"
 )
 
 type MasterOption struct {
 	Host              string
 	Port              int
+	GrpcPort          int
 	MetaFolder        string
 	VolumeSizeLimitMB uint
 	VolumePreallocate bool
@@ -43,6 +47,9 @@ type MasterOption struct {
 	DisableHttp             bool
 	MetricsAddress          string
 	MetricsIntervalSec      int
+	MetricsJobLabel         string
+	MetricsInstanceLabel    string
+	SequencerType           string // overrides master.sequencer.type in master.toml if not empty
 }
 
 type MasterServer struct {
@@ -66,6 +73,21 @@ type MasterServer struct {
 	MasterClient *wdclient.MasterClient
 
 	adminLocks *AdminLocks
+
+	// the shell to run the admin scripts, the ec rebuild and the replication repair, nil if all are disabled
+	adminShell      *shell.CommandEnv
+	adminScriptLock sync.Mutex
+
+	ecRebuildDelay time.Duration
+	ecRebuildTimer *time.Timer
+	ecRebuildLock  sync.Mutex
+
+	replicationRepairDelay time.Duration
+	replicationRepairTimer *time.Timer
+	replicationRepairLock  sync.Mutex
+
+	quorumLostTimer *time.Timer
+	quorumLostLock  sync.Mutex
 }
 
 func NewMasterServer(r *mux.Router, option *MasterOption, peers []string) *MasterServer {
@@ -104,6 +126,18 @@ func NewMasterServer(r *mux.Router, option *MasterOption, peers []string) *Maste
 	}
 	ms.Topo = topology.NewTopology("topo", seq, uint64(ms.option.VolumeSizeLimitMB)*1024*1024, 5, replicationAsMin)
 	ms.vg = topology.NewDefaultVolumeGrowth()
+	placementPolicy, err := topology.LoadPlacementPolicy(v)
+	if err != nil {
+		glog.Fatalf("load placement policy: %v", err)
+	}
+	ms.Topo.PlacementPolicy = placementPolicy
+	eventNotifier, err := newEventNotifier(v)
+	if err != nil {
+		glog.Fatalf("load master.notification: %v", err)
+	}
+	if eventNotifier != nil {
+		ms.Topo.EventListener = eventNotifier.notify
+	}
 	glog.V(0).Infoln("Volume Size Limit is", ms.option.VolumeSizeLimitMB, "MB")
 
 	ms.guard = security.NewGuard(ms.option.WhiteList, signingKey, expiresAfterSec, readSigningKey, readExpiresAfterSec)
@@ -120,6 +154,7 @@ func NewMasterServer(r *mux.Router, option *MasterOption, peers []string) *Maste
 		r.HandleFunc("/vol/status", ms.proxyToLeader(ms.guard.WhiteList(ms.volumeStatusHandler)))
 		r.HandleFunc("/vol/vacuum", ms.proxyToLeader(ms.guard.WhiteList(ms.volumeVacuumHandler)))
 		r.HandleFunc("/submit", ms.guard.WhiteList(ms.submitFromMasterServerHandler))
+		r.HandleFunc("/metrics", ms.guard.WhiteList(stats.MetricsHandler().ServeHTTP))
 		/*
 			r.HandleFunc("/stats/health", ms.guard.WhiteList(statsHealthHandler))
 			r.HandleFunc("/stats/counter", ms.guard.WhiteList(statsCounterHandler))
@@ -130,6 +165,8 @@ func NewMasterServer(r *mux.Router, option *MasterOption, peers []string) *Maste
 
 	ms.Topo.StartRefreshWritableVolumes(ms.grpcDialOption, ms.option.GarbageThreshold, ms.preallocateSize)
 
+	go ms.loopCollectingMetrics(masterMetricsInterval)
+
 	ms.startAdminScripts()
 
 	return ms
@@ -137,10 +174,21 @@ func NewMasterServer(r *mux.Router, option *MasterOption, peers []string) *Maste
 
 func (ms *MasterServer) SetRaftServer(raftServer *RaftServer) {
 	ms.Topo.RaftServer = raftServer.raftServer
+	ms.registerRaftMetrics()
 	ms.Topo.RaftServer.AddEventListener(raft.LeaderChangeEventType, func(e raft.Event) {
 		glog.V(0).Infof("leader change event: %+v => %+v", e.PrevValue(), e.Value())
 		if ms.Topo.RaftServer.Leader() != "" {
+			ms.cancelQuorumLostAlert()
 			glog.V(0).Infoln("[", ms.Topo.RaftServer.Name(), "]", ms.Topo.RaftServer.Leader(), "becomes leader.")
+			// the event is dispatched with the raft lock held, so IsLeader() would block
+			if ms.Topo.RaftServer.Leader() == ms.Topo.RaftServer.Name() {
+				ms.Topo.NotifyEvent(&master_pb.TopologyEvent{
+					Type:   topology.EventLeaderChange,
+					Leader: ms.Topo.RaftServer.Leader(),
+				})
+			}
+		} else {
+			ms.scheduleQuorumLostAlert(fmt.Sprintf("%v", e.PrevValue()))
 		}
 	})
 	if ms.Topo.IsLeader() {
@@ -152,6 +200,14 @@ func (ms *MasterServer) SetRaftServer(raftServer *RaftServer) {
 	}
 }
 
+// StepDown hands the raft leadership over to a follower if this master is the leader,
+// and stops taking part in the raft cluster. It is called when the master shuts down.
+func (ms *MasterServer) StepDown() {
+	if ms.Topo.RaftServer != nil {
+		ms.handOverLeadership()
+	}
+}
+
 func (ms *MasterServer) proxyToLeader(f http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if ms.Topo.IsLeader() {
@@ -159,7 +215,7 @@ func (ms *MasterServer) proxyToLeader(f http.HandlerFunc) http.HandlerFunc {
 		} else if ms.Topo.RaftServer != nil && ms.Topo.RaftServer.Leader() != "" {
 			ms.bounedLeaderChan <- 1
 			defer func() { <-ms.bounedLeaderChan }()
-			targetUrl, err := url.Parse("http://" + ms.Topo.RaftServer.Leader())
+			targetUrl, err := url.Parse("http://" + pb.ServerToHttpAddress(ms.Topo.RaftServer.Leader()))
 			if err != nil {
 				writeJsonError(w, r, http.StatusInternalServerError,
 					fmt.Errorf("Leader URL http://%s Parse Error: %v", ms.Topo.RaftServer.Leader(), err))
@@ -190,7 +246,14 @@ func (ms *MasterServer) startAdminScripts() {
 	v := util.GetViper()
 	adminScripts := v.GetString("master.maintenance.scripts")
 	glog.V(0).Infof("adminScripts:\n%v", adminScripts)
-	if adminScripts == "" {
+
+	v.SetDefault("master.maintenance.ec_rebuild_delay_minutes", 10)
+	ecRebuildDelay := time.Duration(v.GetInt("master.maintenance.ec_rebuild_delay_minutes")) * time.Minute
+
+	v.SetDefault("master.maintenance.replication_repair_delay_minutes", 0)
+	replicationRepairDelay := time.Duration(v.GetInt("master.maintenance.replication_repair_delay_minutes")) * time.Minute
+
+	if adminScripts == "" && ecRebuildDelay <= 0 && replicationRepairDelay <= 0 {
 		return
 	}
 
@@ -206,13 +269,16 @@ func (ms *MasterServer) startAdminScripts() {
 		scriptLines = append(scriptLines, "unlock")
 	}
 
-	masterAddress := fmt.Sprintf("%s:%d", ms.option.Host, ms.option.Port)
+	masterAddress := pb.NewServerAddress(ms.option.Host, ms.option.Port, ms.option.GrpcPort)
 
 	var shellOptions shell.ShellOptions
 	shellOptions.GrpcDialOption = security.LoadClientTLS(v, "grpc.master")
 	shellOptions.Masters = &masterAddress
 
-	shellOptions.FilerHost, shellOptions.FilerPort, err = util.ParseHostPort(filerHostPort)
+	shellOptions.FilerHost, shellOptions.FilerPort, err = util.ParseHostPort(pb.ServerToHttpAddress(filerHostPort))
+	if err == nil {
+		shellOptions.FilerGrpcAddress, err = pb.ParseFilerGrpcAddress(filerHostPort)
+	}
 	shellOptions.Directory = "/"
 	if err != nil {
 		glog.V(0).Infof("failed to parse master.filer.default = %s : %v\n", filerHostPort, err)
@@ -221,26 +287,41 @@ func (ms *MasterServer) startAdminScripts() {
 
 	commandEnv := shell.NewCommandEnv(shellOptions)
 
-	reg, _ := regexp.Compile(`'.*?'|".*?"|\S+`)
-
 	go commandEnv.MasterClient.KeepConnectedToMaster()
 
+	ms.adminShell = commandEnv
+	ms.ecRebuildDelay = ecRebuildDelay
+	ms.replicationRepairDelay = replicationRepairDelay
+
+	if adminScripts == "" {
+		return
+	}
+
 	go func() {
 		commandEnv.MasterClient.WaitUntilConnected()
 
 		c := time.Tick(time.Duration(sleepMinutes) * time.Minute)
 		for range c {
 			if ms.Topo.IsLeader() {
-				for _, line := range scriptLines {
-					for _, c := range strings.Split(line, ";") {
-						processEachCmd(reg, c, commandEnv)
-					}
-				}
+				ms.runAdminScript(scriptLines)
 			}
 		}
 	}()
 }
 
+// runAdminScript runs the shell commands, one script at a time.
+func (ms *MasterServer) runAdminScript(scriptLines []string) {
+	ms.adminScriptLock.Lock()
+	defer ms.adminScriptLock.Unlock()
+
+	reg, _ := regexp.Compile(`'.*?'|".*?"|\S+`)
+	for _, line := range scriptLines {
+		for _, c := range strings.Split(line, ";") {
+			processEachCmd(reg, c, ms.adminShell)
+		}
+	}
+}
+
 func processEachCmd(reg *regexp.Regexp, line string, commandEnv *shell.CommandEnv) {
 	cmds := reg.FindAllString(line, -1)
 	if len(cmds) == 0 {
@@ -266,6 +347,9 @@ func (ms *MasterServer) createSequencer(option *MasterOption) sequence.Sequencer
 	var seq sequence.Sequencer
 	v := util.GetViper()
 	seqType := strings.ToLower(v.GetString(SequencerType))
+	if option.SequencerType != "" {
+		seqType = strings.ToLower(option.SequencerType)
+	}
 	glog.V(1).Infof("[%s] : [%s]", SequencerType, seqType)
 	switch strings.ToLower(seqType) {
 	case "etcd":
@@ -277,6 +361,14 @@ func (ms *MasterServer) createSequencer(option *MasterOption) sequence.Sequencer
 			glog.Error(err)
 			seq = nil
 		}
+	case "snowflake":
+		var err error
+		snowflakeId := v.GetInt(SequencerSnowflakeId)
+		seq, err = sequence.NewSnowflakeSequencer(fmt.Sprintf("%s:%d", option.Host, option.Port), snowflakeId)
+		if err != nil {
+			glog.Error(err)
+			seq = nil
+		}
 	default:
 		seq = sequence.NewMemorySequencer()
 	}
@@ -0,0 +1,36 @@
+package weed_server
+
+import (
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+)
+
+// scheduleEcRebuild runs "ec.rebuild -force" after master.maintenance.ec_rebuild_delay_minutes, when a volume server
+// with ec shards is gone. The volume server may come back within the delay, and the servers gone within the delay
+// are rebuilt together.
+func (ms *MasterServer) scheduleEcRebuild(dn *topology.DataNode) {
+	if ms.adminShell == nil || ms.ecRebuildDelay <= 0 {
+		return
+	}
+
+	ms.ecRebuildLock.Lock()
+	defer ms.ecRebuildLock.Unlock()
+
+	if ms.ecRebuildTimer != nil {
+		glog.V(0).Infof("volume server %s with %d ec shards is gone, ec rebuild is already scheduled", dn.Url(), dn.GetEcShardCount())
+		return
+	}
+	glog.V(0).Infof("volume server %s with %d ec shards is gone, rebuild the missing ec shards in %v", dn.Url(), dn.GetEcShardCount(), ms.ecRebuildDelay)
+	ms.ecRebuildTimer = time.AfterFunc(ms.ecRebuildDelay, func() {
+		ms.ecRebuildLock.Lock()
+		ms.ecRebuildTimer = nil
+		ms.ecRebuildLock.Unlock()
+
+		if !ms.Topo.IsLeader() {
+			return
+		}
+		ms.runAdminScript([]string{"lock", "ec.rebuild -force", "unlock"})
+	})
+}
@@ -3,6 +3,7 @@ package weed_server
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -12,7 +13,7 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 )
 
-func (ms *MasterServer) lookupVolumeId(vids []string, collection string) (volumeLocations map[string]operation.LookupResult) {
+func (ms *MasterServer) lookupVolumeId(vids []string, collection, dataCenter, rack string) (volumeLocations map[string]operation.LookupResult) {
 	volumeLocations = make(map[string]operation.LookupResult)
 	for _, vid := range vids {
 		commaSep := strings.Index(vid, ",")
@@ -22,14 +23,16 @@ func (ms *MasterServer) lookupVolumeId(vids []string, collection string) (volume
 		if _, ok := volumeLocations[vid]; ok {
 			continue
 		}
-		volumeLocations[vid] = ms.findVolumeLocation(collection, vid)
+		volumeLocations[vid] = ms.findVolumeLocation(collection, vid, dataCenter, rack)
 	}
 	return
 }
 
 // If "fileId" is provided, this returns the fileId location and a JWT to update or delete the file.
 // If "volumeId" is provided, this only returns the volumeId location
+// If "dataCenter" or "rack" is provided, the locations in the same data center and rack are listed first
 func (ms *MasterServer) dirLookupHandler(w http.ResponseWriter, r *http.Request) {
+	defer observeMasterRequest("lookup")()
 	vid := r.FormValue("volumeId")
 	if vid != "" {
 		// backward compatible
@@ -46,7 +49,7 @@ func (ms *MasterServer) dirLookupHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 	collection := r.FormValue("collection") // optional, but can be faster if too many collections
-	location := ms.findVolumeLocation(collection, vid)
+	location := ms.findVolumeLocation(collection, vid, r.FormValue("dataCenter"), r.FormValue("rack"))
 	httpStatus := http.StatusOK
 	if location.Error != "" || location.Locations == nil {
 		httpStatus = http.StatusNotFound
@@ -59,8 +62,9 @@ func (ms *MasterServer) dirLookupHandler(w http.ResponseWriter, r *http.Request)
 }
 
 // findVolumeLocation finds the volume location from master topo if it is leader,
-// or from master client if not leader
-func (ms *MasterServer) findVolumeLocation(collection, vid string) operation.LookupResult {
+// or from master client if not leader.
+// The locations are ranked by the locality to the requester's dataCenter and rack, if provided.
+func (ms *MasterServer) findVolumeLocation(collection, vid, dataCenter, rack string) operation.LookupResult {
 	var locations []operation.Location
 	var err error
 	if ms.Topo.IsLeader() {
@@ -70,19 +74,26 @@ func (ms *MasterServer) findVolumeLocation(collection, vid string) operation.Loo
 		} else {
 			machines := ms.Topo.Lookup(collection, volumeId)
 			for _, loc := range machines {
-				locations = append(locations, operation.Location{Url: loc.Url(), PublicUrl: loc.PublicUrl})
+				locations = append(locations, operation.Location{
+					Url:        loc.Url(),
+					PublicUrl:  loc.PublicUrl,
+					GrpcPort:   loc.GrpcPort,
+					DataCenter: string(loc.GetDataCenter().Id()),
+					Rack:       string(loc.GetRack().Id()),
+				})
 			}
 		}
 	} else {
 		machines, getVidLocationsErr := ms.MasterClient.GetVidLocations(vid)
 		for _, loc := range machines {
-			locations = append(locations, operation.Location{Url: loc.Url, PublicUrl: loc.PublicUrl})
+			locations = append(locations, operation.Location{Url: loc.Url, PublicUrl: loc.PublicUrl, GrpcPort: loc.GrpcPort, DataCenter: loc.DataCenter})
 		}
 		err = getVidLocationsErr
 	}
 	if len(locations) == 0 && err == nil {
 		err = fmt.Errorf("volume id %s not found", vid)
 	}
+	rankLocationsByLocality(locations, dataCenter, rack)
 	ret := operation.LookupResult{
 		VolumeId:  vid,
 		Locations: locations,
@@ -93,7 +104,29 @@ func (ms *MasterServer) findVolumeLocation(collection, vid string) operation.Loo
 	return ret
 }
 
+// rankLocationsByLocality moves the locations in the same data center and rack to the front,
+// followed by the ones in the same data center. The order is kept within each group.
+// The rack is only compared within the same data center, since rack names are not unique across data centers.
+func rankLocationsByLocality(locations []operation.Location, dataCenter, rack string) {
+	if dataCenter == "" {
+		return
+	}
+	locality := func(loc operation.Location) int {
+		if loc.DataCenter != dataCenter {
+			return 2
+		}
+		if rack != "" && loc.Rack == rack {
+			return 0
+		}
+		return 1
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locality(locations[i]) < locality(locations[j])
+	})
+}
+
 func (ms *MasterServer) dirAssignHandler(w http.ResponseWriter, r *http.Request) {
+	defer observeMasterRequest("assign")()
 	stats.AssignRequest()
 	requestedCount, e := strconv.ParseUint(r.FormValue("count"), 10, 64)
 	if e != nil || requestedCount == 0 {
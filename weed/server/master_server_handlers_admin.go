@@ -9,6 +9,7 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
 	"github.com/chrislusf/seaweedfs/weed/storage/backend/memory_map"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
@@ -25,7 +26,7 @@ func (ms *MasterServer) collectionDeleteHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 	for _, server := range collection.ListVolumeServers() {
-		err := operation.WithVolumeServerClient(server.Url(), ms.grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		err := operation.WithVolumeServerClient(server.ServerAddress(), ms.grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
 			_, deleteErr := client.DeleteCollection(context.Background(), &volume_server_pb.DeleteCollectionRequest{
 				Collection: collection.Name,
 			})
@@ -52,7 +53,9 @@ func (ms *MasterServer) dirStatusHandler(w http.ResponseWriter, r *http.Request)
 func (ms *MasterServer) volumeVacuumHandler(w http.ResponseWriter, r *http.Request) {
 	gcString := r.FormValue("garbageThreshold")
 	gcThreshold := ms.option.GarbageThreshold
-	if gcString != "" {
+	// the garbageThreshold parameter applies to all collections, over their own thresholds
+	forceThreshold := gcString != ""
+	if forceThreshold {
 		var err error
 		gcThreshold, err = strconv.ParseFloat(gcString, 32)
 		if err != nil {
@@ -62,7 +65,7 @@ func (ms *MasterServer) volumeVacuumHandler(w http.ResponseWriter, r *http.Reque
 		}
 	}
 	// glog.Infoln("garbageThreshold =", gcThreshold)
-	ms.Topo.Vacuum(ms.grpcDialOption, gcThreshold, ms.preallocateSize)
+	ms.Topo.Vacuum(ms.grpcDialOption, gcThreshold, forceThreshold, ms.preallocateSize)
 	ms.dirStatusHandler(w, r)
 }
 
@@ -74,11 +77,22 @@ func (ms *MasterServer) volumeGrowHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if targetServer := r.FormValue("targetServer"); targetServer != "" {
+		// place the first copy of each volume on this server
+		dn := ms.Topo.FindDataNode(targetServer)
+		if dn == nil {
+			writeJsonError(w, r, http.StatusNotAcceptable, fmt.Errorf("volume server %s not found", targetServer))
+			return
+		}
+		option.DataCenter, option.Rack, option.DataNode = string(dn.GetDataCenter().Id()), string(dn.GetRack().Id()), string(dn.Id())
+	}
+
+	var vids []needle.VolumeId
 	if count, err = strconv.Atoi(r.FormValue("count")); err == nil {
 		if ms.Topo.FreeSpace() < int64(count*option.ReplicaPlacement.GetCopyCount()) {
 			err = fmt.Errorf("only %d volumes left, not enough for %d", ms.Topo.FreeSpace(), count*option.ReplicaPlacement.GetCopyCount())
 		} else {
-			count, err = ms.vg.GrowByCountAndType(ms.grpcDialOption, count, option, ms.Topo)
+			count, vids, err = ms.vg.GrowVolumes(ms.grpcDialOption, count, option, ms.Topo)
 		}
 	} else {
 		err = fmt.Errorf("can not parse parameter count %s", r.FormValue("count"))
@@ -87,7 +101,7 @@ func (ms *MasterServer) volumeGrowHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		writeJsonError(w, r, http.StatusNotAcceptable, err)
 	} else {
-		writeJsonQuiet(w, r, http.StatusOK, map[string]interface{}{"count": count})
+		writeJsonQuiet(w, r, http.StatusOK, map[string]interface{}{"count": count, "volumeIds": vids})
 	}
 }
 
@@ -101,7 +115,7 @@ func (ms *MasterServer) volumeStatusHandler(w http.ResponseWriter, r *http.Reque
 func (ms *MasterServer) redirectHandler(w http.ResponseWriter, r *http.Request) {
 	vid, _, _, _, _ := parseURLPath(r.URL.Path)
 	collection := r.FormValue("collection")
-	location := ms.findVolumeLocation(collection, vid)
+	location := ms.findVolumeLocation(collection, vid, "", "")
 	if location.Error == "" {
 		loc := location.Locations[rand.Intn(len(location.Locations))]
 		var url string
@@ -118,9 +132,9 @@ func (ms *MasterServer) redirectHandler(w http.ResponseWriter, r *http.Request)
 
 func (ms *MasterServer) selfUrl(r *http.Request) string {
 	if r.Host != "" {
-		return r.Host
+		return pb.NewServerAddressWithGrpcPort(r.Host, ms.option.GrpcPort)
 	}
-	return "localhost:" + strconv.Itoa(ms.option.Port)
+	return pb.NewServerAddress("localhost", ms.option.Port, ms.option.GrpcPort)
 }
 func (ms *MasterServer) submitFromMasterServerHandler(w http.ResponseWriter, r *http.Request) {
 	if ms.Topo.IsLeader() {
@@ -143,7 +157,7 @@ func (ms *MasterServer) HasWritableVolume(option *topology.VolumeGrowOption) boo
 func (ms *MasterServer) getVolumeGrowOption(r *http.Request) (*topology.VolumeGrowOption, error) {
 	replicationString := r.FormValue("replication")
 	if replicationString == "" {
-		replicationString = ms.option.DefaultReplicaPlacement
+		replicationString = ms.defaultReplication(r.FormValue("collection"))
 	}
 	replicaPlacement, err := super_block.NewReplicaPlacementFromString(replicationString)
 	if err != nil {
@@ -174,6 +188,7 @@ func (ms *MasterServer) getVolumeGrowOption(r *http.Request) (*topology.VolumeGr
 		Rack:               r.FormValue("rack"),
 		DataNode:           r.FormValue("dataNode"),
 		MemoryMapMaxSizeMb: memoryMapMaxSizeMb,
+		DiskType:           ms.diskType(r.FormValue("collection"), r.FormValue("diskType")),
 	}
 	return volumeGrowOption, nil
 }
@@ -0,0 +1,38 @@
+package weed_server
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+)
+
+func TestRankLocationsByLocality(t *testing.T) {
+	locations := []operation.Location{
+		{Url: "a", DataCenter: "dc2", Rack: "rack1"},
+		{Url: "b", DataCenter: "dc1", Rack: "rack2"},
+		{Url: "c", DataCenter: "dc1", Rack: "rack1"},
+		{Url: "d", DataCenter: "dc2", Rack: "rack1"},
+		{Url: "e", DataCenter: "dc1", Rack: "rack2"},
+	}
+	for _, tc := range []struct {
+		dataCenter, rack string
+		want             string
+	}{
+		{"", "", "abcde"},
+		{"", "rack1", "abcde"},
+		{"dc1", "", "bcead"},
+		{"dc1", "rack1", "cbead"},
+		{"dc2", "rack1", "adbce"},
+		{"dc3", "rack1", "abcde"},
+	} {
+		ranked := append([]operation.Location(nil), locations...)
+		rankLocationsByLocality(ranked, tc.dataCenter, tc.rack)
+		var got string
+		for _, loc := range ranked {
+			got += loc.Url
+		}
+		if got != tc.want {
+			t.Errorf("rank by %q %q: got %s, want %s", tc.dataCenter, tc.rack, got, tc.want)
+		}
+	}
+}
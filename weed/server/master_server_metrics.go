@@ -0,0 +1,99 @@
+package weed_server
+
+import (
+	"time"
+
+	"github.com/chrislusf/raft"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/storage/erasure_coding"
+)
+
+const masterMetricsInterval = 15 * time.Second
+
+// loopCollectingMetrics updates the volume metrics from the topology.
+// Only the leader has the topology, so the other masters report nothing.
+func (ms *MasterServer) loopCollectingMetrics(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		ms.collectMetrics()
+	}
+}
+
+func (ms *MasterServer) collectMetrics() {
+	type volumeKey struct{ collection, volumeType string }
+	volumeCounts := make(map[volumeKey]int)
+	volumeSizes := make(map[string]uint64)
+
+	if ms.Topo.IsLeader() {
+		topologyInfo := ms.Topo.ToTopologyInfo()
+		for _, dc := range topologyInfo.DataCenterInfos {
+			for _, rack := range dc.RackInfos {
+				for _, dn := range rack.DataNodeInfos {
+					for _, v := range dn.VolumeInfos {
+						collection := stats.CollectionLabel(v.Collection)
+						volumeCounts[volumeKey{collection, "volume"}]++
+						if v.ReadOnly {
+							volumeCounts[volumeKey{collection, "read_only"}]++
+						}
+						volumeSizes[collection] += v.Size
+					}
+					for _, ecShards := range dn.EcShardInfos {
+						collection := stats.CollectionLabel(ecShards.Collection)
+						volumeCounts[volumeKey{collection, "ec_shards"}] += erasure_coding.ShardBits(ecShards.EcIndexBits).ShardIdCount()
+					}
+				}
+			}
+		}
+	}
+
+	stats.MasterVolumeGauge.Reset()
+	for key, count := range volumeCounts {
+		stats.MasterVolumeGauge.WithLabelValues(key.collection, key.volumeType).Set(float64(count))
+	}
+	stats.MasterVolumeSizeGauge.Reset()
+	for collection, size := range volumeSizes {
+		stats.MasterVolumeSizeGauge.WithLabelValues(collection).Set(float64(size))
+	}
+}
+
+// observeMasterRequest counts the request. Call the returned function when the request is done.
+func observeMasterRequest(requestType string) func() {
+	stats.MasterRequestCounter.WithLabelValues(requestType).Inc()
+	start := time.Now()
+	return func() {
+		stats.MasterRequestHistogram.WithLabelValues(requestType).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (ms *MasterServer) registerRaftMetrics() {
+	raftGauge := func(name, help string, value func(s raft.Server) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			if ms.Topo.RaftServer == nil {
+				return 0
+			}
+			return value(ms.Topo.RaftServer)
+		})
+	}
+	stats.RegisterCollector(raftGauge("is_leader", "1 if this master is the raft leader, 0 otherwise.", func(s raft.Server) float64 {
+		if s.State() == raft.Leader {
+			return 1
+		}
+		return 0
+	}))
+	stats.RegisterCollector(raftGauge("raft_term", "Current raft term.", func(s raft.Server) float64 {
+		return float64(s.Term())
+	}))
+	stats.RegisterCollector(raftGauge("raft_commit_index", "Index of the last committed raft log entry.", func(s raft.Server) float64 {
+		return float64(s.CommitIndex())
+	}))
+	stats.RegisterCollector(raftGauge("raft_members", "Number of masters in the raft cluster, including this one.", func(s raft.Server) float64 {
+		return float64(s.MemberCount())
+	}))
+}
@@ -0,0 +1,46 @@
+package weed_server
+
+import (
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/alert"
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// every election starts with an empty leader, including the handover on shutdown,
+// so the quorum is only considered lost if no leader is elected for several election timeouts
+const quorumLostAlertDelay = 3 * raftElectionTimeout
+
+func (ms *MasterServer) scheduleQuorumLostAlert(previousLeader string) {
+	ms.quorumLostLock.Lock()
+	defer ms.quorumLostLock.Unlock()
+
+	if ms.quorumLostTimer != nil {
+		return
+	}
+	ms.quorumLostTimer = time.AfterFunc(quorumLostAlertDelay, func() {
+		ms.quorumLostLock.Lock()
+		ms.quorumLostTimer = nil
+		ms.quorumLostLock.Unlock()
+
+		if ms.Topo.RaftServer.Leader() != "" {
+			return
+		}
+		glog.Errorf("master %s has no leader for %v", ms.Topo.RaftServer.Name(), quorumLostAlertDelay)
+		alert.Raise(alert.MasterQuorumLost, "master "+ms.Topo.RaftServer.Name()+" lost raft quorum", map[string]string{
+			"previousLeader": previousLeader,
+		})
+	})
+}
+
+// cancelQuorumLostAlert is called when a leader is elected.
+func (ms *MasterServer) cancelQuorumLostAlert() {
+	ms.quorumLostLock.Lock()
+	if ms.quorumLostTimer != nil {
+		ms.quorumLostTimer.Stop()
+		ms.quorumLostTimer = nil
+	}
+	ms.quorumLostLock.Unlock()
+
+	alert.Resolve(alert.MasterQuorumLost, "master "+ms.Topo.RaftServer.Name()+" follows leader "+ms.Topo.RaftServer.Leader())
+}
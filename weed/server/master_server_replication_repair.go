@@ -0,0 +1,36 @@
+package weed_server
+
+import (
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+)
+
+// scheduleReplicationRepair runs "volume.fix.replication" after master.maintenance.replication_repair_delay_minutes,
+// when a volume server with volumes is gone. The volume server may come back within the delay, and the servers
+// gone within the delay are repaired together.
+func (ms *MasterServer) scheduleReplicationRepair(dn *topology.DataNode, volumeCount int) {
+	if ms.adminShell == nil || ms.replicationRepairDelay <= 0 {
+		return
+	}
+
+	ms.replicationRepairLock.Lock()
+	defer ms.replicationRepairLock.Unlock()
+
+	if ms.replicationRepairTimer != nil {
+		glog.V(0).Infof("volume server %s with %d volumes is gone, replication repair is already scheduled", dn.Url(), volumeCount)
+		return
+	}
+	glog.V(0).Infof("volume server %s with %d volumes is gone, add back the missing replicas in %v", dn.Url(), volumeCount, ms.replicationRepairDelay)
+	ms.replicationRepairTimer = time.AfterFunc(ms.replicationRepairDelay, func() {
+		ms.replicationRepairLock.Lock()
+		ms.replicationRepairTimer = nil
+		ms.replicationRepairLock.Unlock()
+
+		if !ms.Topo.IsLeader() {
+			return
+		}
+		ms.runAdminScript([]string{"lock", "volume.fix.replication", "unlock"})
+	})
+}
@@ -15,9 +15,16 @@ import (
 	"github.com/chrislusf/raft"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"github.com/chrislusf/seaweedfs/weed/topology"
 )
 
+const (
+	raftElectionTimeout = 10 * time.Second
+	// used by the follower taking over from a leader that is shutting down
+	raftTakeOverElectionTimeout = time.Second
+)
+
 type RaftServer struct {
 	peers      []string // initial peers to join with
 	raftServer raft.Server
@@ -32,26 +39,57 @@ type StateMachine struct {
 	topo *topology.Topology
 }
 
+// raftState is saved in the raft snapshots, compatible with the older snapshots of only the max volume id
+type raftState struct {
+	MaxVolumeId needle.VolumeId               `json:"maxVolumeId"`
+	MaxFileId   uint64                        `json:"maxFileId,omitempty"`
+	Maintenance []topology.MaintenanceTarget  `json:"maintenance,omitempty"`
+	Collections []topology.CollectionSettings `json:"collections,omitempty"`
+	Vacuum      []topology.VacuumWindow       `json:"vacuumWindows,omitempty"`
+}
+
 func (s StateMachine) Save() ([]byte, error) {
-	state := topology.MaxVolumeIdCommand{
+	state := raftState{
 		MaxVolumeId: s.topo.GetMaxVolumeId(),
+		Maintenance: s.topo.ListMaintenance(),
+		Collections: s.topo.ListCollectionSettings(),
+		Vacuum:      s.topo.ListVacuumWindows(),
+	}
+	if nextFileId := s.topo.Sequence.Peek(); nextFileId > 0 {
+		state.MaxFileId = nextFileId - 1
 	}
 	glog.V(1).Infof("Save raft state %+v", state)
 	return json.Marshal(state)
 }
 
 func (s StateMachine) Recovery(data []byte) error {
-	state := topology.MaxVolumeIdCommand{}
+	state := raftState{}
 	err := json.Unmarshal(data, &state)
 	if err != nil {
 		return err
 	}
 	glog.V(1).Infof("Recovery raft state %+v", state)
 	s.topo.UpAdjustMaxVolumeId(state.MaxVolumeId)
+	if state.MaxFileId > 0 {
+		s.topo.Sequence.SetMax(state.MaxFileId)
+	}
+	for _, target := range s.topo.ListMaintenance() {
+		s.topo.SetMaintenance(target, false)
+	}
+	for _, target := range state.Maintenance {
+		s.topo.SetMaintenance(target, true)
+	}
+	for _, settings := range s.topo.ListCollectionSettings() {
+		s.topo.SetCollectionSettings(topology.CollectionSettings{Collection: settings.Collection})
+	}
+	for _, settings := range state.Collections {
+		s.topo.SetCollectionSettings(settings)
+	}
+	s.topo.SetVacuumWindows(state.Vacuum)
 	return nil
 }
 
-func NewRaftServer(grpcDialOption grpc.DialOption, peers []string, serverAddr, dataDir string, topo *topology.Topology, raftResumeState bool) (*RaftServer, error) {
+func NewRaftServer(grpcDialOption grpc.DialOption, peers []string, serverAddr, dataDir string, topo *topology.Topology, raftResumeState bool, snapshotInterval time.Duration) (*RaftServer, error) {
 	s := &RaftServer{
 		peers:      peers,
 		serverAddr: serverAddr,
@@ -64,6 +102,9 @@ func NewRaftServer(grpcDialOption grpc.DialOption, peers []string, serverAddr, d
 	}
 
 	raft.RegisterCommand(&topology.MaxVolumeIdCommand{})
+	raft.RegisterCommand(&topology.MaintenanceCommand{})
+	raft.RegisterCommand(&topology.CollectionSettingsCommand{})
+	raft.RegisterCommand(&topology.VacuumWindowsCommand{})
 
 	var err error
 	transporter := raft.NewGrpcTransporter(grpcDialOption)
@@ -75,7 +116,7 @@ func NewRaftServer(grpcDialOption grpc.DialOption, peers []string, serverAddr, d
 		os.RemoveAll(path.Join(s.dataDir, "log"))
 		os.RemoveAll(path.Join(s.dataDir, "snapshot"))
 	}
-	if err := os.MkdirAll(path.Join(s.dataDir, "snapshot"), 0600); err != nil {
+	if err := os.MkdirAll(path.Join(s.dataDir, "snapshot"), 0700); err != nil {
 		return nil, err
 	}
 
@@ -86,7 +127,7 @@ func NewRaftServer(grpcDialOption grpc.DialOption, peers []string, serverAddr, d
 		return nil, err
 	}
 	s.raftServer.SetHeartbeatInterval(time.Duration(300+rand.Intn(150)) * time.Millisecond)
-	s.raftServer.SetElectionTimeout(10 * time.Second)
+	s.raftServer.SetElectionTimeout(raftElectionTimeout)
 	if err := s.raftServer.LoadSnapshot(); err != nil {
 		return nil, err
 	}
@@ -128,9 +169,35 @@ func NewRaftServer(grpcDialOption grpc.DialOption, peers []string, serverAddr, d
 
 	glog.V(0).Infof("current cluster leader: %v", s.raftServer.Leader())
 
+	if snapshotInterval > 0 {
+		go s.takeSnapshots(snapshotInterval)
+	}
+
 	return s, nil
 }
 
+// takeSnapshots saves the state machine and compacts the raft log periodically,
+// in addition to the snapshots raft takes when the log grows over 400 entries.
+// Raft keeps the last 200 entries after each snapshot for the slow followers.
+func (s *RaftServer) takeSnapshots(interval time.Duration) {
+	var lastCommitIndex uint64
+	for range time.Tick(interval) {
+		if s.raftServer.State() == raft.Stopped {
+			return
+		}
+		commitIndex := s.raftServer.CommitIndex()
+		if commitIndex == lastCommitIndex {
+			continue
+		}
+		if err := s.raftServer.TakeSnapshot(); err != nil {
+			glog.Warningf("raft snapshot: %v", err)
+			continue
+		}
+		lastCommitIndex = commitIndex
+		glog.V(1).Infof("raft snapshot at commit index %d, %d log entries", commitIndex, len(s.raftServer.LogEntries()))
+	}
+}
+
 func (s *RaftServer) Peers() (members []string) {
 	peers := s.raftServer.Peers()
 
@@ -24,3 +24,12 @@ func (s *RaftServer) StatusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJsonQuiet(w, r, http.StatusOK, ret)
 }
+
+// HealthzHandler returns 200 if the master knows the raft leader, and 503 otherwise.
+func (s *RaftServer) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	if s.topo.RaftServer == nil || s.topo.RaftServer.Leader() == "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
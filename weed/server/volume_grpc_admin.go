@@ -41,6 +41,7 @@ func (vs *VolumeServer) AllocateVolume(ctx context.Context, req *volume_server_p
 		req.Ttl,
 		req.Preallocate,
 		req.MemoryMapMaxSizeMb,
+		req.DiskType,
 	)
 
 	if err != nil {
@@ -191,6 +192,7 @@ func (vs *VolumeServer) VolumeServerStatus(ctx context.Context, req *volume_serv
 	}
 
 	resp.MemoryStatus = stats.MemStat()
+	resp.ReplicationFailures = vs.store.ReplicationFailures()
 
 	return resp, nil
 
@@ -246,3 +248,29 @@ func (vs *VolumeServer) VolumeNeedleStatus(ctx context.Context, req *volume_serv
 	return resp, nil
 
 }
+
+func (vs *VolumeServer) VolumeNeedleHead(ctx context.Context, req *volume_server_pb.VolumeNeedleHeadRequest) (*volume_server_pb.VolumeNeedleHeadResponse, error) {
+
+	resp := &volume_server_pb.VolumeNeedleHeadResponse{}
+
+	volumeId := needle.VolumeId(req.VolumeId)
+	needleId := types.NeedleId(req.NeedleId)
+
+	var size types.Size
+	var err error
+	if vs.store.HasVolume(volumeId) {
+		size, err = vs.store.HeadVolumeNeedle(volumeId, needleId)
+	} else if _, hasEcVolume := vs.store.FindEcVolume(volumeId); hasEcVolume {
+		size, err = vs.store.HeadEcShardNeedle(volumeId, needleId)
+	} else {
+		return nil, fmt.Errorf("volume not found %d", req.VolumeId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp.NeedleId = req.NeedleId
+	resp.Size = uint32(size)
+	return resp, nil
+
+}
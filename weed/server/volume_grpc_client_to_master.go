@@ -3,8 +3,11 @@ package weed_server
 import (
 	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/operation"
+	"math/rand"
+	"sync/atomic"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc"
 
 	"github.com/chrislusf/seaweedfs/weed/pb"
@@ -19,6 +22,10 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
+// a full heartbeat every this many pulses reconciles the master with all volumes,
+// the heartbeats in between only have the changed volumes, if the master accepts them
+const fullHeartbeatPulses = 12
+
 func (vs *VolumeServer) GetMaster() string {
 	return vs.currentMaster
 }
@@ -33,6 +40,7 @@ func (vs *VolumeServer) checkWithMaster() (err error) {
 					return fmt.Errorf("get master %s configuration: %v", master, err)
 				}
 				vs.metricsAddress, vs.metricsIntervalSec = resp.MetricsAddress, int(resp.MetricsIntervalSeconds)
+				vs.metricsJobLabel, vs.metricsInstanceLabel = resp.MetricsJobLabel, resp.MetricsInstanceLabel
 				backend.LoadFromPbStorageBackends(resp.StorageBackends)
 				return nil
 			})
@@ -52,9 +60,17 @@ func (vs *VolumeServer) heartbeat() {
 	glog.V(0).Infof("Volume server start with seed master nodes: %v", vs.SeedMasterNodes)
 	vs.store.SetDataCenter(vs.dataCenter)
 	vs.store.SetRack(vs.rack)
+	vs.store.SetTags(vs.tags)
 
 	grpcDialOption := security.LoadClientTLS(util.GetViper(), "grpc.volume")
 
+	if vs.startupJitter > 0 {
+		// spread out the first heartbeats when many volume servers restart at the same time
+		jitter := time.Duration(rand.Int63n(int64(vs.startupJitter)))
+		glog.V(0).Infof("wait %v before the first heartbeat", jitter)
+		time.Sleep(jitter)
+	}
+
 	var err error
 	var newLeader string
 	for vs.isHeartbeating {
@@ -115,6 +131,7 @@ func (vs *VolumeServer) doHeartbeat(masterNode, masterGrpcAddress string, grpcDi
 	vs.currentMaster = masterNode
 
 	doneChan := make(chan error, 1)
+	var acceptsChangedVolumes int32
 
 	go func() {
 		for {
@@ -131,6 +148,15 @@ func (vs *VolumeServer) doHeartbeat(masterNode, masterGrpcAddress string, grpcDi
 					}
 				}
 			}
+			if in.GetAcceptsChangedVolumes() {
+				atomic.StoreInt32(&acceptsChangedVolumes, 1)
+			}
+			if in.GetCollectionCompressions() != nil {
+				vs.setCollectionCompressions(in.GetCollectionCompressions().GetCompressions())
+			}
+			if in.GetCollectionWriteConcerns() != nil {
+				vs.setCollectionWriteConcerns(in.GetCollectionWriteConcerns().GetWriteConcerns())
+			}
 			if in.GetLeader() != "" && vs.currentMaster != in.GetLeader() {
 				glog.V(0).Infof("Volume Server found a new master newLeader: %v instead of %v", in.GetLeader(), vs.currentMaster)
 				newLeader = in.GetLeader()
@@ -140,7 +166,10 @@ func (vs *VolumeServer) doHeartbeat(masterNode, masterGrpcAddress string, grpcDi
 		}
 	}()
 
-	if err = stream.Send(vs.store.CollectHeartbeat()); err != nil {
+	reported := make(reportedVolumes)
+	heartbeat := vs.store.CollectHeartbeat()
+	reported.reset(heartbeat.Volumes)
+	if err = stream.Send(heartbeat); err != nil {
 		glog.V(0).Infof("Volume Server Failed to talk with master %s: %v", masterNode, err)
 		return "", err
 	}
@@ -152,6 +181,7 @@ func (vs *VolumeServer) doHeartbeat(masterNode, masterGrpcAddress string, grpcDi
 
 	volumeTickChan := time.Tick(sleepInterval)
 	ecShardTickChan := time.Tick(17 * sleepInterval)
+	pulses := 0
 
 	for {
 		select {
@@ -185,6 +215,7 @@ func (vs *VolumeServer) doHeartbeat(masterNode, masterGrpcAddress string, grpcDi
 				},
 			}
 			glog.V(1).Infof("volume server %s:%d deletes volume %d", vs.store.Ip, vs.store.Port, volumeMessage.Id)
+			delete(reported, volumeMessage.Id)
 			if err = stream.Send(deltaBeat); err != nil {
 				glog.V(0).Infof("Volume Server Failed to update to master %s: %v", masterNode, err)
 				return "", err
@@ -204,7 +235,14 @@ func (vs *VolumeServer) doHeartbeat(masterNode, masterGrpcAddress string, grpcDi
 		case <-volumeTickChan:
 			glog.V(4).Infof("volume server %s:%d heartbeat", vs.store.Ip, vs.store.Port)
 			vs.store.MaybeAdjustVolumeMax()
-			if err = stream.Send(vs.store.CollectHeartbeat()); err != nil {
+			heartbeat := vs.store.CollectHeartbeat()
+			pulses++
+			if atomic.LoadInt32(&acceptsChangedVolumes) == 1 && pulses%fullHeartbeatPulses != 0 {
+				reported.toDeltaHeartbeat(heartbeat)
+			} else {
+				reported.reset(heartbeat.Volumes)
+			}
+			if err = stream.Send(heartbeat); err != nil {
 				glog.V(0).Infof("Volume Server Failed to talk with master %s: %v", masterNode, err)
 				return "", err
 			}
@@ -238,3 +276,44 @@ func (vs *VolumeServer) doHeartbeat(masterNode, masterGrpcAddress string, grpcDi
 		}
 	}
 }
+
+// reportedVolumes are the volumes last reported to the master, to only send the changed volumes.
+type reportedVolumes map[uint32]*master_pb.VolumeInformationMessage
+
+// reset remembers the volumes of a full heartbeat.
+func (r reportedVolumes) reset(volumes []*master_pb.VolumeInformationMessage) {
+	for vid := range r {
+		delete(r, vid)
+	}
+	for _, v := range volumes {
+		r[v.Id] = v
+	}
+}
+
+// toDeltaHeartbeat replaces all volumes of the full heartbeat with the volumes added, changed or deleted since the last heartbeat.
+func (r reportedVolumes) toDeltaHeartbeat(heartbeat *master_pb.Heartbeat) {
+	current := make(map[uint32]bool, len(heartbeat.Volumes))
+	for _, v := range heartbeat.Volumes {
+		current[v.Id] = true
+		if last, found := r[v.Id]; !found || !proto.Equal(last, v) {
+			heartbeat.ChangedVolumes = append(heartbeat.ChangedVolumes, v)
+			r[v.Id] = v
+		}
+	}
+	for vid, v := range r {
+		if current[vid] {
+			continue
+		}
+		heartbeat.DeletedVolumes = append(heartbeat.DeletedVolumes, &master_pb.VolumeShortInformationMessage{
+			Id:               v.Id,
+			Collection:       v.Collection,
+			ReplicaPlacement: v.ReplicaPlacement,
+			Version:          v.Version,
+			Ttl:              v.Ttl,
+			DiskType:         v.DiskType,
+		})
+		delete(r, vid)
+	}
+	heartbeat.Volumes = nil
+	heartbeat.HasNoVolumes = false
+}
@@ -0,0 +1,44 @@
+package weed_server
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func TestDeltaHeartbeat(t *testing.T) {
+	reported := make(reportedVolumes)
+	reported.reset([]*master_pb.VolumeInformationMessage{
+		{Id: 1, Size: 100},
+		{Id: 2, Size: 200},
+		{Id: 3, Size: 300},
+	})
+
+	heartbeat := &master_pb.Heartbeat{
+		Volumes: []*master_pb.VolumeInformationMessage{
+			{Id: 1, Size: 100},
+			{Id: 2, Size: 250},
+			{Id: 4, Size: 400},
+		},
+	}
+	reported.toDeltaHeartbeat(heartbeat)
+	if len(heartbeat.Volumes) != 0 || heartbeat.HasNoVolumes {
+		t.Errorf("delta heartbeat should not have all volumes")
+	}
+	if len(heartbeat.ChangedVolumes) != 2 || heartbeat.ChangedVolumes[0].Id != 2 || heartbeat.ChangedVolumes[1].Id != 4 {
+		t.Errorf("changed volumes %v, expected 2 and 4", heartbeat.ChangedVolumes)
+	}
+	if len(heartbeat.DeletedVolumes) != 1 || heartbeat.DeletedVolumes[0].Id != 3 {
+		t.Errorf("deleted volumes %v, expected 3", heartbeat.DeletedVolumes)
+	}
+
+	// nothing changed since the last delta heartbeat, and the last volume is deleted
+	heartbeat = &master_pb.Heartbeat{HasNoVolumes: true}
+	reported.toDeltaHeartbeat(heartbeat)
+	if len(heartbeat.ChangedVolumes) != 0 || len(heartbeat.DeletedVolumes) != 3 || heartbeat.HasNoVolumes {
+		t.Errorf("unexpected delta heartbeat %v", heartbeat)
+	}
+	if len(reported) != 0 {
+		t.Errorf("reported volumes %v, expected none", reported)
+	}
+}
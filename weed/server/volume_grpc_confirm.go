@@ -0,0 +1,29 @@
+package weed_server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+)
+
+func (vs *VolumeServer) ConfirmNeedles(ctx context.Context, req *volume_server_pb.ConfirmNeedlesRequest) (*volume_server_pb.ConfirmNeedlesResponse, error) {
+
+	resp := &volume_server_pb.ConfirmNeedlesResponse{}
+
+	var fileIds []*needle.FileId
+	for _, fid := range req.FileIds {
+		fileId, err := needle.ParseFileIdFromString(fid)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %v", fid, err)
+		}
+		fileIds = append(fileIds, fileId)
+	}
+
+	if err := vs.store.ConfirmPendingNeedles(fileIds); err != nil {
+		return nil, fmt.Errorf("confirm needles: %v", err)
+	}
+
+	return resp, nil
+}
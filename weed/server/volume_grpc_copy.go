@@ -76,7 +76,14 @@ func (vs *VolumeServer) VolumeCopy(ctx context.Context, req *volume_server_pb.Vo
 			return err
 		}
 
-		os.Remove(volumeFileName + ".note")
+		// the needles written by a two phase commit and not confirmed yet
+		if err := vs.doCopyFile(client, false, req.Collection, req.VolumeId, volFileInfoResp.CompactionRevision, math.MaxInt64, volumeFileName, storage.PendingLogExt, false, true); err != nil {
+			return err
+		}
+
+		if !req.KeepUnmounted {
+			os.Remove(volumeFileName + ".note")
+		}
 
 		return nil
 	})
@@ -96,6 +103,7 @@ func (vs *VolumeServer) VolumeCopy(ctx context.Context, req *volume_server_pb.Vo
 			os.Remove(idxFileName)
 			os.Remove(datFileName)
 			os.Remove(volumeFileName + ".vif")
+			os.Remove(volumeFileName + storage.PendingLogExt)
 		}
 	}()
 
@@ -103,10 +111,12 @@ func (vs *VolumeServer) VolumeCopy(ctx context.Context, req *volume_server_pb.Vo
 		return nil, err
 	}
 
-	// mount the volume
-	err = vs.store.MountVolume(needle.VolumeId(req.VolumeId))
-	if err != nil {
-		return nil, fmt.Errorf("failed to mount volume %d: %v", req.VolumeId, err)
+	// a moved volume keeps its note, and is mounted after tailing the source volume
+	if !req.KeepUnmounted {
+		err = vs.store.MountVolume(needle.VolumeId(req.VolumeId))
+		if err != nil {
+			return nil, fmt.Errorf("failed to mount volume %d: %v", req.VolumeId, err)
+		}
 	}
 
 	return &volume_server_pb.VolumeCopyResponse{
@@ -129,7 +139,7 @@ func (vs *VolumeServer) doCopyFile(client volume_server_pb.VolumeServerClient, i
 		return fmt.Errorf("failed to start copying volume %d %s file: %v", vid, ext, err)
 	}
 
-	err = writeToFile(copyFileClient, baseFileName+ext, util.NewWriteThrottler(vs.compactionBytePerSecond), isAppend)
+	err = writeToFile(copyFileClient, baseFileName+ext, vs.copyLimiter(isEcVolume), isAppend)
 	if err != nil {
 		return fmt.Errorf("failed to copy %s file: %v", baseFileName+ext, err)
 	}
@@ -163,7 +173,7 @@ func checkCopyFiles(originFileInf *volume_server_pb.ReadVolumeFileStatusResponse
 	return nil
 }
 
-func writeToFile(client volume_server_pb.VolumeServer_CopyFileClient, fileName string, wt *util.WriteThrottler, isAppend bool) error {
+func writeToFile(client volume_server_pb.VolumeServer_CopyFileClient, fileName string, wt util.Throttler, isAppend bool) error {
 	glog.V(4).Infof("writing to %s", fileName)
 	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
 	if isAppend {
@@ -208,6 +218,49 @@ func (vs *VolumeServer) ReadVolumeFileStatus(ctx context.Context, req *volume_se
 	return resp, nil
 }
 
+// VolumeChecksum reads the whole .dat and .idx files, to verify a copied volume against its source.
+func (vs *VolumeServer) VolumeChecksum(ctx context.Context, req *volume_server_pb.VolumeChecksumRequest) (*volume_server_pb.VolumeChecksumResponse, error) {
+	v := vs.store.GetVolume(needle.VolumeId(req.VolumeId))
+	if v == nil {
+		return nil, fmt.Errorf("not found volume id %d", req.VolumeId)
+	}
+	if v.HasRemoteFile() {
+		return nil, fmt.Errorf("volume %d is tiered to remote storage", req.VolumeId)
+	}
+
+	resp := &volume_server_pb.VolumeChecksumResponse{}
+	var err error
+	if resp.DatFileSize, resp.DatFileCrc, err = fileChecksum(v.FileName() + ".dat"); err != nil {
+		return nil, err
+	}
+	if resp.IdxFileSize, resp.IdxFileCrc, err = fileChecksum(v.FileName() + ".idx"); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func fileChecksum(fileName string) (size uint64, crc uint32, err error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	var c needle.CRC
+	buffer := make([]byte, BufferSizeLimit)
+	for {
+		n, readErr := file.Read(buffer)
+		c = c.Update(buffer[:n])
+		size += uint64(n)
+		if readErr == io.EOF {
+			return size, uint32(c), nil
+		}
+		if readErr != nil {
+			return 0, 0, fmt.Errorf("read %s: %v", fileName, readErr)
+		}
+	}
+}
+
 // CopyFile client pulls the volume related file from the source server.
 // if req.CompactionRevision != math.MaxUint32, it ensures the compact revision is as expected
 // The copying still stop at req.StopOffset, but you can set it to math.MaxUint64 in order to read all data.
@@ -244,7 +297,7 @@ func (vs *VolumeServer) CopyFile(req *volume_server_pb.CopyFileRequest, stream v
 
 	file, err := os.Open(fileName)
 	if err != nil {
-		if req.IgnoreSourceFileNotFound && err == os.ErrNotExist {
+		if req.IgnoreSourceFileNotFound && os.IsNotExist(err) {
 			return nil
 		}
 		return err
@@ -252,6 +305,7 @@ func (vs *VolumeServer) CopyFile(req *volume_server_pb.CopyFileRequest, stream v
 	defer file.Close()
 
 	buffer := make([]byte, BufferSizeLimit)
+	limiter := vs.copyLimiter(req.IsEcVolume)
 
 	for bytesToRead > 0 {
 		bytesread, err := file.Read(buffer)
@@ -278,6 +332,7 @@ func (vs *VolumeServer) CopyFile(req *volume_server_pb.CopyFileRequest, stream v
 		}
 
 		bytesToRead -= int64(bytesread)
+		limiter.MaybeSlowdown(int64(bytesread))
 
 	}
 
@@ -0,0 +1,132 @@
+package weed_server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+// VolumeScrub starts to scrub the volumes in the background. See VolumeScrubStatus for the progress.
+func (vs *VolumeServer) VolumeScrub(ctx context.Context, req *volume_server_pb.VolumeScrubRequest) (*volume_server_pb.VolumeScrubResponse, error) {
+	var vids []needle.VolumeId
+	for _, vid := range req.VolumeIds {
+		if v := vs.store.GetVolume(needle.VolumeId(vid)); v == nil {
+			return nil, fmt.Errorf("volume %d not found", vid)
+		}
+		vids = append(vids, needle.VolumeId(vid))
+	}
+	maxMBPS := int(req.MaxMbps)
+	if maxMBPS == 0 {
+		maxMBPS = vs.scrubMaxMBPS
+	}
+	if err := vs.store.StartScrubbing(maxMBPS, vids); err != nil {
+		return nil, err
+	}
+	glog.V(0).Infof("start scrubbing volumes %v at %dMB/s", req.VolumeIds, maxMBPS)
+	return &volume_server_pb.VolumeScrubResponse{}, nil
+}
+
+func (vs *VolumeServer) VolumeScrubStatus(ctx context.Context, req *volume_server_pb.VolumeScrubStatusRequest) (*volume_server_pb.VolumeScrubStatusResponse, error) {
+	status := vs.store.GetScrubStatus()
+	resp := &volume_server_pb.VolumeScrubStatusResponse{
+		Running:             status.Running,
+		VolumeCount:         uint32(status.VolumeCount),
+		ScrubbedVolumeCount: uint32(status.ScrubbedVolumeCount),
+		NeedleCount:         uint64(status.NeedleCount),
+		CorruptedCount:      uint32(status.CorruptedCount),
+		RepairedCount:       uint32(status.RepairedCount),
+	}
+	if !status.StartedAt.IsZero() {
+		resp.StartedAtNs = status.StartedAt.UnixNano()
+	}
+	if !status.FinishedAt.IsZero() {
+		resp.FinishedAtNs = status.FinishedAt.UnixNano()
+	}
+	for _, finding := range status.Findings {
+		resp.Findings = append(resp.Findings, &volume_server_pb.ScrubFinding{
+			VolumeId:    uint32(finding.VolumeId),
+			NeedleId:    uint64(finding.NeedleId),
+			Offset:      finding.Offset,
+			Size:        uint32(finding.Size),
+			Error:       finding.Error,
+			FoundAtNs:   finding.FoundAt.UnixNano(),
+			Repaired:    finding.Repaired,
+			RepairError: finding.RepairError,
+		})
+	}
+	return resp, nil
+}
+
+func (vs *VolumeServer) ReadNeedleBlob(ctx context.Context, req *volume_server_pb.ReadNeedleBlobRequest) (*volume_server_pb.ReadNeedleBlobResponse, error) {
+	blob, size, err := vs.store.ReadNeedleBlob(needle.VolumeId(req.VolumeId), types.NeedleId(req.NeedleId))
+	if err != nil {
+		return nil, fmt.Errorf("read volume %d needle %d: %v", req.VolumeId, req.NeedleId, err)
+	}
+	resp := &volume_server_pb.ReadNeedleBlobResponse{
+		NeedleBlob: blob,
+		Size:       uint32(size),
+	}
+	if v := vs.store.GetVolume(needle.VolumeId(req.VolumeId)); v != nil {
+		resp.EncryptionKeyId = v.EncryptionKeyId()
+	}
+	return resp, nil
+}
+
+func (vs *VolumeServer) WriteNeedleBlob(ctx context.Context, req *volume_server_pb.WriteNeedleBlobRequest) (*volume_server_pb.WriteNeedleBlobResponse, error) {
+	v := vs.store.GetVolume(needle.VolumeId(req.VolumeId))
+	if v == nil {
+		return nil, fmt.Errorf("volume %d not found", req.VolumeId)
+	}
+	if req.EncryptionKeyId != v.EncryptionKeyId() {
+		return nil, fmt.Errorf("volume %d is encrypted with key %q instead of %q", req.VolumeId, v.EncryptionKeyId(), req.EncryptionKeyId)
+	}
+	err := vs.store.WriteNeedleBlob(needle.VolumeId(req.VolumeId), types.NeedleId(req.NeedleId), types.Size(req.Size), req.NeedleBlob)
+	if err != nil {
+		return nil, fmt.Errorf("write volume %d needle %d: %v", req.VolumeId, req.NeedleId, err)
+	}
+	return &volume_server_pb.WriteNeedleBlobResponse{}, nil
+}
+
+// readNeedleFromReplicas reads a healthy copy of the needle from the other replicas of the volume,
+// to repair the needle found corrupted by scrubbing.
+func (vs *VolumeServer) readNeedleFromReplicas(vid needle.VolumeId, key types.NeedleId) (blob []byte, size types.Size, err error) {
+	lookup, err := operation.LookupVolumeIds(vs.GetMaster(), vs.grpcDialOption, []string{vid.String()})
+	if err != nil {
+		return nil, 0, fmt.Errorf("lookup volume %d: %v", vid, err)
+	}
+	self := fmt.Sprintf("%s:%d", vs.store.Ip, vs.store.Port)
+	var keyId string
+	if v := vs.store.GetVolume(vid); v != nil {
+		keyId = v.EncryptionKeyId()
+	}
+	err = fmt.Errorf("volume %d has no other replicas", vid)
+	for _, location := range lookup[vid.String()].Locations {
+		if location.Url == self {
+			continue
+		}
+		err = operation.WithVolumeServerClient(location.ServerAddress(), vs.grpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+			resp, readErr := client.ReadNeedleBlob(context.Background(), &volume_server_pb.ReadNeedleBlobRequest{
+				VolumeId: uint32(vid),
+				NeedleId: uint64(key),
+			})
+			if readErr != nil {
+				return readErr
+			}
+			if resp.EncryptionKeyId != keyId {
+				return fmt.Errorf("the replica is encrypted with key %q instead of %q", resp.EncryptionKeyId, keyId)
+			}
+			blob, size = resp.NeedleBlob, types.Size(resp.Size)
+			return nil
+		})
+		if err == nil {
+			return blob, size, nil
+		}
+		glog.V(0).Infof("read volume %d needle %s from %s: %v", vid, key, location.Url, err)
+	}
+	return nil, 0, err
+}
@@ -3,6 +3,7 @@ package weed_server
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
@@ -11,6 +12,7 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/storage"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
 )
 
 func (vs *VolumeServer) VolumeTailSender(req *volume_server_pb.VolumeTailSenderRequest, stream volume_server_pb.VolumeServer_VolumeTailSenderServer) error {
@@ -70,6 +72,7 @@ func sendNeedlesSince(stream volume_server_pb.VolumeServer_VolumeTailSenderServe
 
 	scanner := &VolumeFileScanner4Tailing{
 		stream: stream,
+		v:      v,
 	}
 
 	err = storage.ScanVolumeFileFrom(v.Version(), v.DataBackend, foundOffset.ToAcutalOffset(), scanner)
@@ -80,25 +83,69 @@ func sendNeedlesSince(stream volume_server_pb.VolumeServer_VolumeTailSenderServe
 
 func (vs *VolumeServer) VolumeTailReceiver(ctx context.Context, req *volume_server_pb.VolumeTailReceiverRequest) (*volume_server_pb.VolumeTailReceiverResponse, error) {
 
-	resp := &volume_server_pb.VolumeTailReceiverResponse{}
+	resp := &volume_server_pb.VolumeTailReceiverResponse{
+		LastAppendAtNs: req.SinceNs,
+	}
 
 	v := vs.store.GetVolume(needle.VolumeId(req.VolumeId))
 	if v == nil {
-		return resp, fmt.Errorf("receiver not found volume id %d", req.VolumeId)
+		return vs.tailToUnmountedVolume(req, resp)
 	}
 
 	defer glog.V(1).Infof("receive tailing volume %d finished", v.Id)
 
-	return resp, operation.TailVolumeFromSource(req.SourceVolumeServer, vs.grpcDialOption, v.Id, req.SinceNs, int(req.IdleTimeoutSeconds), func(n *needle.Needle) error {
-		_, err := vs.store.WriteVolumeNeedle(v.Id, n, false)
+	return resp, operation.TailVolumeFromSource(req.SourceVolumeServer, vs.grpcDialOption, v.Id, req.SinceNs, int(req.IdleTimeoutSeconds), func(n *needle.Needle) (err error) {
+		appendAtNs := n.AppendAtNs
+		if n.Size == 0 {
+			_, err = vs.store.DeleteVolumeNeedle(v.Id, n)
+		} else {
+			_, err = vs.store.WriteVolumeNeedle(v.Id, n, false)
+		}
+		if err == nil {
+			resp.LastAppendAtNs = appendAtNs
+		}
 		return err
 	})
 
 }
 
+// tailToUnmountedVolume catches up a volume copied here but not mounted yet, as volume.move does.
+// The volume is only mounted, and so reported to the master, after it has caught up with the source.
+func (vs *VolumeServer) tailToUnmountedVolume(req *volume_server_pb.VolumeTailReceiverRequest, resp *volume_server_pb.VolumeTailReceiverResponse) (*volume_server_pb.VolumeTailReceiverResponse, error) {
+
+	vid := needle.VolumeId(req.VolumeId)
+	v, err := vs.store.OpenUnmountedVolume(vid)
+	if err != nil {
+		return resp, fmt.Errorf("receiver not found volume id %d: %v", req.VolumeId, err)
+	}
+
+	err = operation.TailVolumeFromSource(req.SourceVolumeServer, vs.grpcDialOption, vid, req.SinceNs, int(req.IdleTimeoutSeconds), func(n *needle.Needle) error {
+		appendAtNs := n.AppendAtNs
+		if err := v.WriteTailedNeedle(n); err != nil {
+			return err
+		}
+		resp.LastAppendAtNs = appendAtNs
+		return nil
+	})
+	v.Close()
+	glog.V(1).Infof("receive tailing unmounted volume %d finished: %v", vid, err)
+	if err != nil || !req.MountAfterTailing {
+		return resp, err
+	}
+
+	// the copy is complete now, so it should survive restarts
+	os.Remove(v.FileName() + ".note")
+	if err = vs.store.MountVolume(vid); err != nil {
+		return resp, fmt.Errorf("failed to mount volume %d: %v", vid, err)
+	}
+
+	return resp, nil
+}
+
 // generate the volume idx
 type VolumeFileScanner4Tailing struct {
 	stream                   volume_server_pb.VolumeServer_VolumeTailSenderServer
+	v                        *storage.Volume
 	lastProcessedTimestampNs uint64
 }
 
@@ -113,6 +160,18 @@ func (scanner *VolumeFileScanner4Tailing) ReadNeedleBody() bool {
 func (scanner *VolumeFileScanner4Tailing) VisitNeedle(n *needle.Needle, offset int64, needleHeader, needleBody []byte) error {
 	isLastChunk := false
 
+	// the receivers get the clear data, and encrypt it with their own keys
+	if scanner.v.EncryptionKeyId() != "" && len(n.Data) > 0 {
+		if err := scanner.v.DecryptNeedle(n); err != nil {
+			return err
+		}
+		needleBytes, err := n.ToBytes(scanner.v.Version())
+		if err != nil {
+			return err
+		}
+		needleHeader, needleBody = needleBytes[:types.NeedleHeaderSize], needleBytes[types.NeedleHeaderSize:]
+	}
+
 	// need to send body by chunks
 	for i := 0; i < len(needleBody); i += BufferSizeLimit {
 		stopOffset := i + BufferSizeLimit
@@ -0,0 +1,39 @@
+package weed_server
+
+import (
+	"context"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// copyLimiter is shared by the copies of volumes or ec shards, on both the sending and the receiving side.
+func (vs *VolumeServer) copyLimiter(isEcVolume bool) *util.BandwidthLimiter {
+	if isEcVolume {
+		return vs.ecLimiter
+	}
+	return vs.replicationLimiter
+}
+
+// VolumeServerThrottle changes the bandwidth limits until the volume server restarts.
+func (vs *VolumeServer) VolumeServerThrottle(ctx context.Context, req *volume_server_pb.VolumeServerThrottleRequest) (*volume_server_pb.VolumeServerThrottleResponse, error) {
+
+	setLimit(vs.compactionLimiter, "compaction", req.CompactionMbps)
+	setLimit(vs.replicationLimiter, "replication", req.ReplicationMbps)
+	setLimit(vs.ecLimiter, "ec", req.EcMbps)
+
+	return &volume_server_pb.VolumeServerThrottleResponse{
+		CompactionMbps:  int32(vs.compactionLimiter.Limit() / 1024 / 1024),
+		ReplicationMbps: int32(vs.replicationLimiter.Limit() / 1024 / 1024),
+		EcMbps:          int32(vs.ecLimiter.Limit() / 1024 / 1024),
+	}, nil
+}
+
+func setLimit(limiter *util.BandwidthLimiter, name string, mbps int32) {
+	if mbps < 0 {
+		return
+	}
+	glog.V(0).Infof("limit %s bandwidth to %d MB/s", name, mbps)
+	limiter.SetLimit(int64(mbps) * 1024 * 1024)
+}
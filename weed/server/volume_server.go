@@ -3,6 +3,8 @@ package weed_server
 import (
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -11,6 +13,7 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/security/kms"
 	"github.com/chrislusf/seaweedfs/weed/storage"
 )
 
@@ -20,32 +23,57 @@ type VolumeServer struct {
 	pulseSeconds    int
 	dataCenter      string
 	rack            string
+	tags            []string
 	store           *storage.Store
 	guard           *security.Guard
 	grpcDialOption  grpc.DialOption
 
-	needleMapKind           storage.NeedleMapType
-	FixJpgOrientation       bool
-	ReadRedirect            bool
-	compactionBytePerSecond int64
-	metricsAddress          string
-	metricsIntervalSec      int
-	fileSizeLimitBytes      int64
-	isHeartbeating          bool
-	stopChan                chan bool
+	needleMapKind          storage.NeedleMapType
+	FixJpgOrientation      bool
+	ReadRedirect           bool
+	compactionLimiter      *util.BandwidthLimiter
+	replicationLimiter     *util.BandwidthLimiter
+	ecLimiter              *util.BandwidthLimiter
+	metricsAddress         string
+	metricsIntervalSec     int
+	metricsJobLabel        string
+	metricsInstanceLabel   string
+	fileSizeLimitBytes     int64
+	autoCompressThreshold  int64
+	compression            string
+	collectionCompressions map[string]string // from the collection settings on the master
+	compressionsLock       sync.RWMutex
+	cdnOriginSecret        string
+	startupJitter          time.Duration
+	scrubMaxMBPS           int
+	isHeartbeating         bool
+	stopChan               chan bool
+
+	collectionWriteConcerns map[string]string // from the collection settings on the master
+	writeConcernsLock       sync.RWMutex
 }
 
 func NewVolumeServer(adminMux, publicMux *http.ServeMux, ip string,
-	port int, publicUrl string,
-	folders []string, maxCounts []int, minFreeSpacePercents []float32,
+	port int, grpcPort int, publicUrl string,
+	folders []string, maxCounts []int, minFreeSpacePercents, resumeFreeSpacePercents []float32, diskTypes []string,
 	needleMapKind storage.NeedleMapType,
 	masterNodes []string, pulseSeconds int,
-	dataCenter string, rack string,
+	dataCenter string, rack string, tags []string,
 	whiteList []string,
 	fixJpgOrientation bool,
 	readRedirect bool,
-	compactionMBPerSecond int,
+	compactionMBPerSecond, replicationMBPerSecond, ecMBPerSecond int,
 	fileSizeLimitMB int,
+	cdnOriginSecret string,
+	recentWriteCacheSize, recentWriteCacheSizeMB int,
+	startupJitterMs int,
+	prewarmCacheOnStart bool,
+	prewarmMaxMBPS int,
+	pendingNeedleTtlMinutes int,
+	autoCompressThresholdBytes int,
+	compression string,
+	scrubCron string,
+	scrubMaxMBPS int,
 ) *VolumeServer {
 
 	v := util.GetViper()
@@ -59,27 +87,53 @@ func NewVolumeServer(adminMux, publicMux *http.ServeMux, ip string,
 	readExpiresAfterSec := v.GetInt("jwt.signing.read.expires_after_seconds")
 
 	vs := &VolumeServer{
-		pulseSeconds:            pulseSeconds,
-		dataCenter:              dataCenter,
-		rack:                    rack,
-		needleMapKind:           needleMapKind,
-		FixJpgOrientation:       fixJpgOrientation,
-		ReadRedirect:            readRedirect,
-		grpcDialOption:          security.LoadClientTLS(util.GetViper(), "grpc.volume"),
-		compactionBytePerSecond: int64(compactionMBPerSecond) * 1024 * 1024,
-		fileSizeLimitBytes:      int64(fileSizeLimitMB) * 1024 * 1024,
-		isHeartbeating:          true,
-		stopChan:                make(chan bool),
+		pulseSeconds:          pulseSeconds,
+		dataCenter:            dataCenter,
+		rack:                  rack,
+		tags:                  tags,
+		needleMapKind:         needleMapKind,
+		FixJpgOrientation:     fixJpgOrientation,
+		ReadRedirect:          readRedirect,
+		grpcDialOption:        security.LoadClientTLS(util.GetViper(), "grpc.volume"),
+		compactionLimiter:     util.NewBandwidthLimiter(int64(compactionMBPerSecond) * 1024 * 1024),
+		replicationLimiter:    util.NewBandwidthLimiter(int64(replicationMBPerSecond) * 1024 * 1024),
+		ecLimiter:             util.NewBandwidthLimiter(int64(ecMBPerSecond) * 1024 * 1024),
+		fileSizeLimitBytes:    int64(fileSizeLimitMB) * 1024 * 1024,
+		autoCompressThreshold: int64(autoCompressThresholdBytes),
+		compression:           compression,
+		cdnOriginSecret:       cdnOriginSecret,
+		startupJitter:         time.Duration(startupJitterMs) * time.Millisecond,
+		isHeartbeating:        true,
+		stopChan:              make(chan bool),
 	}
 	vs.SeedMasterNodes = masterNodes
 
 	vs.checkWithMaster()
 
-	vs.store = storage.NewStore(vs.grpcDialOption, port, ip, publicUrl, folders, maxCounts, minFreeSpacePercents, vs.needleMapKind)
+	kms.LoadConfiguration(v, "volume.encryption.")
+
+	vs.store = storage.NewStore(vs.grpcDialOption, port, grpcPort, ip, publicUrl, folders, maxCounts, minFreeSpacePercents, resumeFreeSpacePercents, diskTypes, vs.needleMapKind, recentWriteCacheSize, recentWriteCacheSizeMB)
+	if prewarmCacheOnStart {
+		vs.store.EnablePrewarm(prewarmMaxMBPS)
+	}
+	if err := vs.store.EnablePendingNeedles(time.Duration(pendingNeedleTtlMinutes) * time.Minute); err != nil {
+		glog.Errorf("load pending needles: %v", err)
+	}
+	vs.scrubMaxMBPS = scrubMaxMBPS
+	vs.store.NeedleRepairer = vs.readNeedleFromReplicas
+	if scrubCron != "" {
+		schedule, err := util.ParseCron(scrubCron)
+		if err != nil {
+			glog.Fatalf("scrubCron: %v", err)
+		}
+		vs.store.EnableScrubbing(schedule, scrubMaxMBPS)
+	}
 	vs.guard = security.NewGuard(whiteList, signingKey, expiresAfterSec, readSigningKey, readExpiresAfterSec)
 
 	handleStaticResources(adminMux)
 	adminMux.HandleFunc("/status", vs.statusHandler)
+	adminMux.HandleFunc("/metrics", vs.guard.WhiteList(stats.MetricsHandler().ServeHTTP))
+	stats.RegisterCollector(&volumeMetricsCollector{store: vs.store})
 	if signingKey == "" || enableUiAccess {
 		// only expose the volume server details for safe environments
 		adminMux.HandleFunc("/ui/index.html", vs.uiStatusHandler)
@@ -97,7 +151,7 @@ func NewVolumeServer(adminMux, publicMux *http.ServeMux, ip string,
 	}
 
 	go vs.heartbeat()
-	go stats.LoopPushingMetric("volumeServer", fmt.Sprintf("%s:%d", ip, port), vs.metricsAddress, vs.metricsIntervalSec)
+	go stats.LoopPushingMetric("volumeServer", fmt.Sprintf("%s:%d", ip, port), vs.metricsAddress, vs.metricsIntervalSec, vs.metricsJobLabel, vs.metricsInstanceLabel)
 
 	return vs
 }
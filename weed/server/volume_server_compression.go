@@ -0,0 +1,32 @@
+package weed_server
+
+import (
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// compressionOf returns the codec to compress the uploads to the volume, by the collection settings on the master,
+// or else "weed volume -compression".
+func (vs *VolumeServer) compressionOf(vid needle.VolumeId) string {
+	if v := vs.store.GetVolume(vid); v != nil {
+		vs.compressionsLock.RLock()
+		compression, found := vs.collectionCompressions[v.Collection]
+		vs.compressionsLock.RUnlock()
+		if found {
+			return compression
+		}
+	}
+	return util.Nvl(vs.compression, util.GzipCompression)
+}
+
+func (vs *VolumeServer) setCollectionCompressions(compressions map[string]string) {
+	vs.compressionsLock.Lock()
+	defer vs.compressionsLock.Unlock()
+	for collection, compression := range compressions {
+		if vs.collectionCompressions[collection] != compression {
+			glog.V(0).Infof("collection %s uses compression %s", collection, compression)
+		}
+	}
+	vs.collectionCompressions = compressions
+}
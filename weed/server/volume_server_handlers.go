@@ -9,6 +9,7 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/security"
 	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 )
 
 /*
@@ -58,10 +59,10 @@ func (vs *VolumeServer) publicReadOnlyHandler(w http.ResponseWriter, r *http.Req
 	switch r.Method {
 	case "GET":
 		stats.ReadRequest()
-		vs.GetOrHeadHandler(w, r)
+		vs.cdnOriginGuard(vs.GetOrHeadHandler)(w, r)
 	case "HEAD":
 		stats.ReadRequest()
-		vs.GetOrHeadHandler(w, r)
+		vs.cdnOriginGuard(vs.GetOrHeadHandler)(w, r)
 	case "OPTIONS":
 		stats.ReadRequest()
 		w.Header().Add("Access-Control-Allow-Methods", "GET, OPTIONS")
@@ -69,6 +70,24 @@ func (vs *VolumeServer) publicReadOnlyHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func (vs *VolumeServer) cdnOriginGuard(f http.HandlerFunc) http.HandlerFunc {
+	if vs.cdnOriginSecret == "" {
+		return f
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		vid, fid, _, _, _ := parseURLPath(r.URL.Path)
+		if sepIndex := strings.LastIndex(fid, "_"); sepIndex > 0 {
+			fid = fid[:sepIndex]
+		}
+		if err := security.VerifyCdnToken(r, vs.cdnOriginSecret, vid+","+fid); err != nil {
+			glog.V(1).Infof("cdn token verification %s from %s: %v", r.URL.Path, r.RemoteAddr, err)
+			writeJsonError(w, r, http.StatusForbidden, err)
+			return
+		}
+		f(w, r)
+	}
+}
+
 func (vs *VolumeServer) maybeCheckJwtAuthorization(r *http.Request, vid, fid string, isWrite bool) bool {
 
 	var signingKey security.SigningKey
@@ -112,3 +131,22 @@ func (vs *VolumeServer) maybeCheckJwtAuthorization(r *http.Request, vid, fid str
 	glog.V(1).Infof("unexpected jwt from %s: %v", r.RemoteAddr, tokenStr)
 	return false
 }
+
+// collectionLabel is the collection of the requested volume, if the metrics are labeled by collection.
+func (vs *VolumeServer) collectionLabel(r *http.Request) string {
+	if !stats.LabelByCollection {
+		return ""
+	}
+	vid, _, _, _, _ := parseURLPath(r.URL.Path)
+	volumeId, err := needle.NewVolumeId(vid)
+	if err != nil {
+		return ""
+	}
+	if v := vs.store.GetVolume(volumeId); v != nil {
+		return stats.CollectionLabel(v.Collection)
+	}
+	if ecVolume, found := vs.store.FindEcVolume(volumeId); found {
+		return stats.CollectionLabel(ecVolume.Collection)
+	}
+	return ""
+}
@@ -29,9 +29,10 @@ func (vs *VolumeServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request)
 
 	// println(r.Method + " " + r.URL.Path)
 
-	stats.VolumeServerRequestCounter.WithLabelValues("get").Inc()
+	collection := vs.collectionLabel(r)
+	stats.VolumeServerRequestCounter.WithLabelValues(collection, "get").Inc()
 	start := time.Now()
-	defer func() { stats.VolumeServerRequestHistogram.WithLabelValues("get").Observe(time.Since(start).Seconds()) }()
+	defer func() { stats.VolumeServerRequestHistogram.WithLabelValues(collection, "get").Observe(time.Since(start).Seconds()) }()
 
 	n := new(needle.Needle)
 	vid, fid, filename, ext, _ := parseURLPath(r.URL.Path)
@@ -87,6 +88,20 @@ func (vs *VolumeServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request)
 		ReadDeleted: r.FormValue("readDeleted") == "true",
 	}
 
+	if r.Method == "HEAD" && !readOption.ReadDeleted {
+		// answer a HEAD for a missing or deleted needle from the index, without reading the data file
+		if hasVolume {
+			_, err = vs.store.HeadVolumeNeedle(volumeId, n.Id)
+		} else {
+			_, err = vs.store.HeadEcShardNeedle(volumeId, n.Id)
+		}
+		if err != nil {
+			glog.V(3).Infof("head %s isNormalVolume %v error: %v", r.URL.Path, hasVolume, err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+
 	var count int
 	if hasVolume {
 		count, err = vs.store.ReadVolumeNeedle(volumeId, n, readOption)
@@ -159,8 +174,8 @@ func (vs *VolumeServer) GetOrHeadHandler(w http.ResponseWriter, r *http.Request)
 			if n.Data, err = util.DecompressData(n.Data); err != nil {
 				glog.V(0).Infoln("ungzip error:", err, r.URL.Path)
 			}
-		// } else if strings.Contains(r.Header.Get("Accept-Encoding"), "zstd") && util.IsZstdContent(n.Data) {
-		//	w.Header().Set("Content-Encoding", "zstd")
+		} else if strings.Contains(r.Header.Get("Accept-Encoding"), "zstd") && n.IsZstd() {
+			w.Header().Set("Content-Encoding", "zstd")
 		} else if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") && util.IsGzippedContent(n.Data) {
 			w.Header().Set("Content-Encoding", "gzip")
 		} else {
@@ -1,6 +1,7 @@
 package weed_server
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -13,15 +14,20 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"github.com/chrislusf/seaweedfs/weed/topology"
+	"github.com/chrislusf/seaweedfs/weed/tracing"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
 func (vs *VolumeServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 
-	stats.VolumeServerRequestCounter.WithLabelValues("post").Inc()
+	_, span := tracing.StartRequestSpan(context.Background(), r, "volume.write")
+	defer span.Finish()
+
+	collection := vs.collectionLabel(r)
+	stats.VolumeServerRequestCounter.WithLabelValues(collection, "post").Inc()
 	start := time.Now()
 	defer func() {
-		stats.VolumeServerRequestHistogram.WithLabelValues("post").Observe(time.Since(start).Seconds())
+		tracing.Observe(stats.VolumeServerRequestHistogram.WithLabelValues(collection, "post"), time.Since(start).Seconds(), span)
 	}()
 
 	if e := r.ParseForm(); e != nil {
@@ -43,14 +49,20 @@ func (vs *VolumeServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reqNeedle, originalSize, contentMd5, ne := needle.CreateNeedleFromRequest(r, vs.FixJpgOrientation, vs.fileSizeLimitBytes)
+	reqNeedle, originalSize, contentMd5, ne := needle.CreateNeedleFromRequest(r, vs.FixJpgOrientation, vs.fileSizeLimitBytes, vs.autoCompressThreshold, vs.compressionOf(volumeId))
 	if ne != nil {
 		writeJsonError(w, r, http.StatusBadRequest, ne)
 		return
 	}
 
+	writeConcern, err := vs.writeConcernOf(volumeId, r)
+	if err != nil {
+		writeJsonError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
 	ret := operation.UploadResult{}
-	isUnchanged, writeError := topology.ReplicatedWrite(vs.GetMaster(), vs.store, volumeId, reqNeedle, r)
+	isUnchanged, writeError := topology.ReplicatedWrite(vs.GetMaster(), vs.store, volumeId, reqNeedle, r, writeConcern)
 
 	// http 204 status code does not allow body
 	if writeError == nil && isUnchanged {
@@ -63,6 +75,7 @@ func (vs *VolumeServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 	if writeError != nil {
 		httpStatus = http.StatusInternalServerError
 		ret.Error = writeError.Error()
+		span.SetError(writeError)
 	}
 	if reqNeedle.HasName() {
 		ret.Name = string(reqNeedle.Name)
@@ -77,10 +90,11 @@ func (vs *VolumeServer) PostHandler(w http.ResponseWriter, r *http.Request) {
 
 func (vs *VolumeServer) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 
-	stats.VolumeServerRequestCounter.WithLabelValues("delete").Inc()
+	collection := vs.collectionLabel(r)
+	stats.VolumeServerRequestCounter.WithLabelValues(collection, "delete").Inc()
 	start := time.Now()
 	defer func() {
-		stats.VolumeServerRequestHistogram.WithLabelValues("delete").Observe(time.Since(start).Seconds())
+		stats.VolumeServerRequestHistogram.WithLabelValues(collection, "delete").Observe(time.Since(start).Seconds())
 	}()
 
 	n := new(needle.Needle)
@@ -0,0 +1,47 @@
+package weed_server
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+var (
+	volumeSizeDesc = prometheus.NewDesc("SeaweedFS_volumeServer_volume_size_bytes",
+		"Content size of each volume.", []string{"collection", "volume"}, nil)
+	volumeFileCountDesc = prometheus.NewDesc("SeaweedFS_volumeServer_volume_files",
+		"Number of files in each volume, including deleted ones not yet vacuumed.", []string{"collection", "volume"}, nil)
+	volumeDeletedBytesDesc = prometheus.NewDesc("SeaweedFS_volumeServer_volume_deleted_bytes",
+		"Size of the deleted files in each volume, reclaimed by vacuum.", []string{"collection", "volume"}, nil)
+	volumeReadOnlyDesc = prometheus.NewDesc("SeaweedFS_volumeServer_volume_read_only",
+		"1 if the volume is read only, 0 otherwise.", []string{"collection", "volume"}, nil)
+)
+
+// volumeMetricsCollector reports the per volume metrics when scraped,
+// so deleted or moved volumes do not leave stale series behind.
+type volumeMetricsCollector struct {
+	store *storage.Store
+}
+
+func (c *volumeMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- volumeSizeDesc
+	ch <- volumeFileCountDesc
+	ch <- volumeDeletedBytesDesc
+	ch <- volumeReadOnlyDesc
+}
+
+func (c *volumeMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, v := range c.store.VolumeInfos() {
+		labels := []string{v.Collection, strconv.Itoa(int(v.Id))}
+		readOnly := 0.0
+		if v.ReadOnly {
+			readOnly = 1
+		}
+		ch <- prometheus.MustNewConstMetric(volumeSizeDesc, prometheus.GaugeValue, float64(v.Size), labels...)
+		ch <- prometheus.MustNewConstMetric(volumeFileCountDesc, prometheus.GaugeValue, float64(v.FileCount), labels...)
+		ch <- prometheus.MustNewConstMetric(volumeDeletedBytesDesc, prometheus.GaugeValue, float64(v.DeletedByteCount), labels...)
+		ch <- prometheus.MustNewConstMetric(volumeReadOnlyDesc, prometheus.GaugeValue, readOnly, labels...)
+	}
+}
@@ -0,0 +1,38 @@
+package weed_server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/topology"
+)
+
+// writeConcernOf returns when to acknowledge the write, by the writeConcern parameter of the request,
+// or else by the collection settings on the master.
+func (vs *VolumeServer) writeConcernOf(vid needle.VolumeId, r *http.Request) (string, error) {
+	if writeConcern := r.FormValue("writeConcern"); writeConcern != "" {
+		if !topology.IsValidWriteConcern(writeConcern) {
+			return "", fmt.Errorf("write concern %s is not one, quorum or all", writeConcern)
+		}
+		return writeConcern, nil
+	}
+	if v := vs.store.GetVolume(vid); v != nil {
+		vs.writeConcernsLock.RLock()
+		defer vs.writeConcernsLock.RUnlock()
+		return vs.collectionWriteConcerns[v.Collection], nil
+	}
+	return "", nil
+}
+
+func (vs *VolumeServer) setCollectionWriteConcerns(writeConcerns map[string]string) {
+	vs.writeConcernsLock.Lock()
+	defer vs.writeConcernsLock.Unlock()
+	for collection, writeConcern := range writeConcerns {
+		if vs.collectionWriteConcerns[collection] != writeConcern {
+			glog.V(0).Infof("collection %s uses write concern %s", collection, writeConcern)
+		}
+	}
+	vs.collectionWriteConcerns = writeConcerns
+}
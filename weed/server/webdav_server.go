@@ -2,9 +2,11 @@ package weed_server
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"io"
 	"math"
+	"net/http"
 	"os"
 	"path"
 	"strings"
@@ -44,24 +46,47 @@ type WebDavServer struct {
 	filer          *filer.Filer
 	grpcDialOption grpc.DialOption
 	Handler        *webdav.Handler
+	user           string
+	password       string
 }
 
 func NewWebDavServer(option *WebDavOption) (ws *WebDavServer, err error) {
 
 	fs, _ := NewWebDavFileSystem(option)
 
+	v := util.GetViper()
 	ws = &WebDavServer{
 		option:         option,
-		grpcDialOption: security.LoadClientTLS(util.GetViper(), "grpc.filer"),
+		grpcDialOption: security.LoadClientTLS(v, "grpc.filer"),
 		Handler: &webdav.Handler{
 			FileSystem: fs,
 			LockSystem: webdav.NewMemLS(),
 		},
+		user:     v.GetString("webdav.user"),
+		password: v.GetString("webdav.password"),
+	}
+	if ws.user != "" {
+		glog.V(0).Infof("webdav requires basic authentication as user %s", ws.user)
 	}
 
 	return ws, nil
 }
 
+// ServeHTTP checks the basic authentication configured in security.toml, if any.
+func (ws *WebDavServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ws.user != "" {
+		user, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(ws.user)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(ws.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="SeaweedFS"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	ws.Handler.ServeHTTP(w, r)
+}
+
 // adapted from https://github.com/mattn/davfs/blob/master/plugin/mysql/mysql.go
 
 type WebDavFileSystem struct {
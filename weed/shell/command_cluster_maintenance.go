@@ -0,0 +1,126 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandClusterMaintenance{})
+}
+
+type commandClusterMaintenance struct {
+}
+
+func (c *commandClusterMaintenance) Name() string {
+	return "cluster.maintenance"
+}
+
+func (c *commandClusterMaintenance) Help() string {
+	return `put a data center, rack or volume server into maintenance, or take it out
+
+	cluster.maintenance                                      # list the data centers, racks and volume servers in maintenance
+	cluster.maintenance -dataCenter=dc1 -enable              # stop assigning writes to dc1
+	cluster.maintenance -dataCenter=dc1 -rack=rack1 -enable  # stop assigning writes to rack1 in dc1
+	cluster.maintenance -node=<host>:<port> -enable          # stop assigning writes to one volume server
+	cluster.maintenance -dataCenter=dc1 -disable             # resume assigning writes to dc1
+
+	The master stops assigning file ids to volumes with any replica in maintenance, and does not
+	grow new volumes there. The volumes are still readable, so the machines can be patched without
+	write errors. The maintenance state is kept across master restarts and leader changes.
+
+`
+}
+
+func (c *commandClusterMaintenance) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	maintenanceCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	dataCenter := maintenanceCommand.String("dataCenter", "", "the data center")
+	rack := maintenanceCommand.String("rack", "", "the rack in the data center, empty for the whole data center")
+	node := maintenanceCommand.String("node", "", "the volume server <host>:<port>, instead of the data center and rack")
+	enable := maintenanceCommand.Bool("enable", false, "put into maintenance")
+	disable := maintenanceCommand.Bool("disable", false, "take out of maintenance")
+	if err = maintenanceCommand.Parse(args); err != nil {
+		return nil
+	}
+
+	if *enable || *disable {
+		if *enable && *disable {
+			return fmt.Errorf("only one of -enable and -disable")
+		}
+		if *dataCenter == "" && *node == "" {
+			return fmt.Errorf("missing -dataCenter or -node")
+		}
+		if err = commandEnv.confirmIsLocked(); err != nil {
+			return
+		}
+		err = setMaintenance(commandEnv, &master_pb.MaintenanceTarget{
+			DataCenter: *dataCenter,
+			Rack:       *rack,
+			DataNode:   *node,
+		}, *enable)
+		if err != nil {
+			return err
+		}
+	}
+
+	targets, err := listMaintenance(commandEnv)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		switch {
+		case target.DataNode != "":
+			fmt.Fprintf(writer, "volume server %s\n", target.DataNode)
+		case target.Rack == "":
+			fmt.Fprintf(writer, "data center %s\n", target.DataCenter)
+		default:
+			fmt.Fprintf(writer, "data center %s rack %s\n", target.DataCenter, target.Rack)
+		}
+	}
+	fmt.Fprintf(writer, "Total %d in maintenance.\n", len(targets))
+
+	return nil
+}
+
+func setMaintenance(commandEnv *CommandEnv, target *master_pb.MaintenanceTarget, enabled bool) error {
+	return commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		_, err := client.SetMaintenance(context.Background(), &master_pb.SetMaintenanceRequest{
+			Target:  target,
+			Enabled: enabled,
+		})
+		return err
+	})
+}
+
+func listMaintenance(commandEnv *CommandEnv) (targets []*master_pb.MaintenanceTarget, err error) {
+	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		resp, err := client.ListMaintenance(context.Background(), &master_pb.ListMaintenanceRequest{})
+		if err != nil {
+			return err
+		}
+		targets = resp.Targets
+		return nil
+	})
+	return
+}
+
+func isInMaintenance(targets []*master_pb.MaintenanceTarget, dc, rack, node string) bool {
+	for _, target := range targets {
+		if target.DataNode != "" {
+			if target.DataNode == node {
+				return true
+			}
+			continue
+		}
+		if target.DataCenter == dc && (target.Rack == "" || target.Rack == rack) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandRaftServerAdd{})
+}
+
+type commandRaftServerAdd struct {
+}
+
+func (c *commandRaftServerAdd) Name() string {
+	return "cluster.raft.add"
+}
+
+func (c *commandRaftServerAdd) Help() string {
+	return `add a master to the raft cluster
+
+	cluster.raft.add -id <master host:port> [-address <master grpc host:port>]
+
+	The change is committed through raft, so the other masters do not need to restart.
+	Add the new master first, then start it with -peers listing all the masters, including itself.
+	Grow the cluster one master at a time.
+
+`
+}
+
+func (c *commandRaftServerAdd) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	if err = commandEnv.confirmIsLocked(); err != nil {
+		return
+	}
+
+	raftAddCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	id := raftAddCommand.String("id", "", "the master <host>:<port>")
+	address := raftAddCommand.String("address", "", "the master grpc <host>:<port>, default to the port + 10000")
+	if err = raftAddCommand.Parse(args); err != nil {
+		return nil
+	}
+	if *id == "" {
+		return fmt.Errorf("missing -id")
+	}
+
+	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		_, err := client.RaftAddServer(context.Background(), &master_pb.RaftAddServerRequest{
+			Id:      *id,
+			Address: *address,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(writer, "added master %s\n", *id)
+	return nil
+}
@@ -0,0 +1,51 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandRaftClusterPs{})
+}
+
+type commandRaftClusterPs struct {
+}
+
+func (c *commandRaftClusterPs) Name() string {
+	return "cluster.raft.ps"
+}
+
+func (c *commandRaftClusterPs) Help() string {
+	return `list the masters in the raft cluster
+
+	cluster.raft.ps
+
+`
+}
+
+func (c *commandRaftClusterPs) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	var resp *master_pb.RaftListClusterServersResponse
+	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		resp, err = client.RaftListClusterServers(context.Background(), &master_pb.RaftListClusterServersRequest{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, server := range resp.ClusterServers {
+		role := "follower"
+		if server.IsLeader {
+			role = "leader"
+		}
+		fmt.Fprintf(writer, "%s\tgrpc:%s\t%s\n", server.Id, server.Address, role)
+	}
+	fmt.Fprintf(writer, "Total %d masters.\n", len(resp.ClusterServers))
+
+	return nil
+}
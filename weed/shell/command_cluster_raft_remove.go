@@ -0,0 +1,62 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandRaftServerRemove{})
+}
+
+type commandRaftServerRemove struct {
+}
+
+func (c *commandRaftServerRemove) Name() string {
+	return "cluster.raft.remove"
+}
+
+func (c *commandRaftServerRemove) Help() string {
+	return `remove a master from the raft cluster
+
+	cluster.raft.remove -id <master host:port>
+
+	The change is committed through raft, so the other masters do not need to restart.
+	Stop the master first, then remove it, so it does not disturb the remaining masters
+	with new elections. The remaining masters must still have a quorum to commit the change.
+
+`
+}
+
+func (c *commandRaftServerRemove) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	if err = commandEnv.confirmIsLocked(); err != nil {
+		return
+	}
+
+	raftRemoveCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	id := raftRemoveCommand.String("id", "", "the master <host>:<port>")
+	if err = raftRemoveCommand.Parse(args); err != nil {
+		return nil
+	}
+	if *id == "" {
+		return fmt.Errorf("missing -id")
+	}
+
+	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		_, err := client.RaftRemoveServer(context.Background(), &master_pb.RaftRemoveServerRequest{
+			Id: *id,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(writer, "removed master %s\n", *id)
+	return nil
+}
@@ -0,0 +1,129 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandCollectionConfigure{})
+}
+
+type commandCollectionConfigure struct {
+}
+
+func (c *commandCollectionConfigure) Name() string {
+	return "collection.configure"
+}
+
+func (c *commandCollectionConfigure) Help() string {
+	return `override the master volume size limit, default replication, disk type, compression, garbage threshold
+	and write concern of a collection
+
+	collection.configure                                                        # list the collection settings
+	collection.configure -collection=thumbnails -volumeSizeLimitMB=2000 -replication=000 -apply
+	collection.configure -collection=archive -volumeSizeLimitMB=30000 -replication=010 -diskType=hdd -apply
+	collection.configure -collection=sessions -diskType=ssd -apply
+	collection.configure -collection=logs -compression=zstd -apply
+	collection.configure -collection=logs -garbageThreshold=0.1 -apply
+	collection.configure -collection=logs -writeConcern=one -apply
+	collection.configure -collection=logs -tier.dest=s3.default -tier.fullPercent=90 -tier.quietFor=72h -apply
+	collection.configure -collection=thumbnails -apply                          # use the master defaults again
+
+	The volume size limit also applies to the existing volumes of the collection. The replication is used
+	when a write or volume growth request does not specify one, and so is the disk type, e.g. the ssd or hdd
+	set by "weed volume -disk", for the new volumes of the collection. The compression, gzip, zstd or none,
+	overrides "weed volume -compression" for the compressible uploads. The garbage threshold overrides
+	"weed master -garbageThreshold" when vacuuming the collection. The write concern tells when a replicated
+	write is acknowledged: after one copy, with the other replicas written in the background, after a
+	quorum of the copies, or after all copies, the default. An upload can also set it with the
+	"writeConcern" parameter. The tiering policy is applied by "volume.tier.apply". The settings replace
+	all previous settings of the collection, and are kept across master restarts and leader changes.
+
+`
+}
+
+func (c *commandCollectionConfigure) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	configureCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	collection := configureCommand.String("collection", "", "the collection name")
+	volumeSizeLimitMB := configureCommand.Uint64("volumeSizeLimitMB", 0, "the volume size limit in MB, 0 for the master default")
+	replication := configureCommand.String("replication", "", "the default replication, empty for the master default")
+	diskType := configureCommand.String("diskType", "", "the disk type of new volumes, e.g. ssd or hdd, empty for any disk type")
+	compression := configureCommand.String("compression", "", "compress the compressible uploads with gzip, zstd or none, empty for the volume server default")
+	writeConcern := configureCommand.String("writeConcern", "", "acknowledge the writes after one, a quorum or all copies are written, empty for all")
+	garbageThreshold := configureCommand.Float64("garbageThreshold", 0, "vacuum the volumes with more garbage than this ratio, 0 for the master default")
+	tierDest := configureCommand.String("tier.dest", "", "upload the sealed volumes to this storage backend in master.toml, e.g. s3.default")
+	tierFullPercent := configureCommand.Float64("tier.fullPercent", 95, "upload the volumes reaching this percentage of the volume size limit")
+	tierQuietFor := configureCommand.Duration("tier.quietFor", 24*time.Hour, "upload the volumes without writes for this period")
+	apply := configureCommand.Bool("apply", false, "apply the settings")
+	if err = configureCommand.Parse(args); err != nil {
+		return nil
+	}
+
+	if *apply {
+		if err = commandEnv.confirmIsLocked(); err != nil {
+			return
+		}
+		settings := &master_pb.CollectionSettings{
+			Collection:        *collection,
+			VolumeSizeLimitMb: *volumeSizeLimitMB,
+			Replication:       *replication,
+			DiskType:          *diskType,
+			Compression:       *compression,
+			GarbageThreshold:  *garbageThreshold,
+			WriteConcern:      *writeConcern,
+		}
+		if *tierDest != "" {
+			settings.TierDest = *tierDest
+			settings.TierFullPercent = *tierFullPercent
+			settings.TierQuietForSeconds = int64(*tierQuietFor / time.Second)
+		}
+		err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+			_, err := client.SetCollectionSettings(context.Background(), &master_pb.SetCollectionSettingsRequest{
+				Settings: settings,
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var resp *master_pb.ListCollectionSettingsResponse
+	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		resp, err = client.ListCollectionSettings(context.Background(), &master_pb.ListCollectionSettingsRequest{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, settings := range resp.Settings {
+		fmt.Fprintf(writer, "collection:\"%s\" volumeSizeLimit:%dMB replication:%s", settings.Collection, settings.VolumeSizeLimitMb, settings.Replication)
+		if settings.DiskType != "" {
+			fmt.Fprintf(writer, " diskType:%s", settings.DiskType)
+		}
+		if settings.Compression != "" {
+			fmt.Fprintf(writer, " compression:%s", settings.Compression)
+		}
+		if settings.GarbageThreshold > 0 {
+			fmt.Fprintf(writer, " garbageThreshold:%v", settings.GarbageThreshold)
+		}
+		if settings.WriteConcern != "" {
+			fmt.Fprintf(writer, " writeConcern:%s", settings.WriteConcern)
+		}
+		if settings.TierDest != "" {
+			fmt.Fprintf(writer, " tier:%s fullPercent:%.1f quietFor:%v", settings.TierDest, settings.TierFullPercent, time.Duration(settings.TierQuietForSeconds)*time.Second)
+		}
+		fmt.Fprintf(writer, "\n")
+	}
+	fmt.Fprintf(writer, "Total %d collections with settings.\n", len(resp.Settings))
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/filer"
 	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
@@ -39,6 +40,12 @@ func (c *commandFsConfigure) Help() string {
 	# example: configure adding only 1 physical volume for each bucket collection
 	fs.configure -locationPrfix=/buckets/ -volumeGrowthCount=1
 
+	# delete the files under /logs/ 30 days after their last write
+	fs.configure -locationPrfix=/logs/ -ttl=30d
+
+	# limit the files under /home/chris/ to 10GiB and 100000 files, see the usage with fs.quota
+	fs.configure -locationPrfix=/home/chris/ -maxBytes=10737418240 -maxFileCount=100000
+
 	# apply the changes
 	fs.configure -locationPrfix=/my/folder -collection=abc -apply
 
@@ -54,9 +61,11 @@ func (c *commandFsConfigure) Do(args []string, commandEnv *CommandEnv, writer io
 	locationPrefix := fsConfigureCommand.String("locationPrefix", "", "path prefix, required to update the path-specific configuration")
 	collection := fsConfigureCommand.String("collection", "", "assign writes to this collection")
 	replication := fsConfigureCommand.String("replication", "", "assign writes with this replication")
-	ttl := fsConfigureCommand.String("ttl", "", "assign writes with this ttl")
+	ttl := fsConfigureCommand.String("ttl", "", "assign writes with this ttl, the files expire together with their chunks")
 	fsync := fsConfigureCommand.Bool("fsync", false, "fsync for the writes")
 	volumeGrowthCount := fsConfigureCommand.Int("volumeGrowthCount", 0, "the number of physical volumes to add if no writable volumes")
+	maxBytes := fsConfigureCommand.Int64("maxBytes", 0, "quota of the total file size under the location")
+	maxFileCount := fsConfigureCommand.Int64("maxFileCount", 0, "quota of the number of files under the location")
 	isDelete := fsConfigureCommand.Bool("delete", false, "delete the configuration by locationPrefix")
 	apply := fsConfigureCommand.Bool("apply", false, "update and apply filer configuration")
 	if err = fsConfigureCommand.Parse(args); err != nil {
@@ -94,6 +103,8 @@ func (c *commandFsConfigure) Do(args []string, commandEnv *CommandEnv, writer io
 			Ttl:               *ttl,
 			Fsync:             *fsync,
 			VolumeGrowthCount: uint32(*volumeGrowthCount),
+			MaxBytes:          *maxBytes,
+			MaxFileCount:      *maxFileCount,
 		}
 
 		// check collection
@@ -101,13 +112,25 @@ func (c *commandFsConfigure) Do(args []string, commandEnv *CommandEnv, writer io
 			return fmt.Errorf("one s3 bucket goes to one collection and not customizable.")
 		}
 
+		// check ttl
+		if *ttl != "" {
+			if _, err := needle.ReadTTL(*ttl); err != nil {
+				return fmt.Errorf("parse ttl %s: %v", *ttl, err)
+			}
+		}
+
+		// check quota
+		if *maxBytes < 0 || *maxFileCount < 0 {
+			return fmt.Errorf("maxBytes %d and maxFileCount %d should not be negative", *maxBytes, *maxFileCount)
+		}
+
 		// check replication
 		if *replication != "" {
 			rp, err := super_block.NewReplicaPlacementFromString(*replication)
 			if err != nil {
 				return fmt.Errorf("parse replication %s: %v", *replication, err)
 			}
-			if *volumeGrowthCount % rp.GetCopyCount() != 0 {
+			if *volumeGrowthCount%rp.GetCopyCount() != 0 {
 				return fmt.Errorf("volumeGrowthCount %d should be devided by replication copy count %d", *volumeGrowthCount, rp.GetCopyCount())
 			}
 		}
@@ -41,7 +41,7 @@ func (c *commandFsMetaLoad) Do(args []string, commandEnv *CommandEnv, writer io.
 
 	dst, err := os.OpenFile(fileName, os.O_RDONLY, 0644)
 	if err != nil {
-		return nil
+		return err
 	}
 	defer dst.Close()
 
@@ -52,19 +52,19 @@ func (c *commandFsMetaLoad) Do(args []string, commandEnv *CommandEnv, writer io.
 		sizeBuf := make([]byte, 4)
 
 		for {
-			if n, err := dst.Read(sizeBuf); n != 4 {
+			if _, err := io.ReadFull(dst, sizeBuf); err != nil {
 				if err == io.EOF {
 					return nil
 				}
-				return err
+				return fmt.Errorf("read %s: %v", fileName, err)
 			}
 
 			size := util.BytesToUint32(sizeBuf)
 
 			data := make([]byte, int(size))
 
-			if n, err := dst.Read(data); n != len(data) {
-				return err
+			if _, err := io.ReadFull(dst, data); err != nil {
+				return fmt.Errorf("read %s: %v", fileName, err)
 			}
 
 			fullEntry := &filer_pb.FullEntry{}
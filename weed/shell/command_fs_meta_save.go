@@ -69,13 +69,18 @@ func (c *commandFsMetaSave) Do(args []string, commandEnv *CommandEnv, writer io.
 	}
 	defer dst.Close()
 
+	var writeErr error
 	err = doTraverseBfsAndSaving(commandEnv, writer, path, *verbose, func(outputChan chan interface{}) {
 		sizeBuf := make([]byte, 4)
 		for item := range outputChan {
+			if writeErr != nil {
+				continue
+			}
 			b := item.([]byte)
 			util.Uint32toBytes(sizeBuf, uint32(len(b)))
-			dst.Write(sizeBuf)
-			dst.Write(b)
+			if _, writeErr = dst.Write(sizeBuf); writeErr == nil {
+				_, writeErr = dst.Write(b)
+			}
 		}
 	}, func(entry *filer_pb.FullEntry, outputChan chan interface{}) (err error) {
 		bytes, err := proto.Marshal(entry)
@@ -88,6 +93,13 @@ func (c *commandFsMetaSave) Do(args []string, commandEnv *CommandEnv, writer io.
 		return nil
 	})
 
+	if err == nil && writeErr == nil {
+		writeErr = dst.Sync()
+	}
+	if err == nil && writeErr != nil {
+		err = fmt.Errorf("write %s: %v", fileName, writeErr)
+	}
+
 	if err == nil {
 		fmt.Fprintf(writer, "meta data for http://%s:%d%s is saved to %s\n", commandEnv.option.FilerHost, commandEnv.option.FilerPort, path, fileName)
 	}
@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandFsQuota{})
+}
+
+type commandFsQuota struct {
+}
+
+func (c *commandFsQuota) Name() string {
+	return "fs.quota"
+}
+
+func (c *commandFsQuota) Help() string {
+	return `show the usage of the locations with a quota
+
+	fs.quota
+
+	The quotas are configured by "fs.configure -locationPrefix=... -maxBytes=... -maxFileCount=...".
+	The usage is counted by the filer, when it starts or the quota changes, and hourly after that.
+`
+}
+
+func (c *commandFsQuota) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	var resp *filer_pb.ListQuotasResponse
+	err = commandEnv.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
+		resp, err = client.ListQuotas(context.Background(), &filer_pb.ListQuotasRequest{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Quotas) == 0 {
+		fmt.Fprintf(writer, "no quotas\n")
+		return nil
+	}
+
+	for _, quota := range resp.Quotas {
+		if !quota.IsCounted {
+			fmt.Fprintf(writer, "%s\tcounting\n", quota.LocationPrefix)
+			continue
+		}
+		fmt.Fprintf(writer, "%s\tbytes:%s\tfiles:%s\n", quota.LocationPrefix,
+			quotaUsageString(quota.UsedBytes, quota.MaxBytes), quotaUsageString(quota.UsedFileCount, quota.MaxFileCount))
+	}
+
+	return nil
+}
+
+func quotaUsageString(used, max int64) string {
+	if max <= 0 {
+		return fmt.Sprintf("%d/unlimited", used)
+	}
+	return fmt.Sprintf("%d/%d(%.1f%%)", used, max, float64(used)*100/float64(max))
+}
@@ -0,0 +1,193 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/security"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func init() {
+	Commands = append(Commands, &commandFsReassign{})
+}
+
+type commandFsReassign struct {
+}
+
+func (c *commandFsReassign) Name() string {
+	return "fs.reassign"
+}
+
+func (c *commandFsReassign) Help() string {
+	return `move file chunks stored on one volume to newly assigned volumes
+
+	fs.reassign -volumeId=42 -dryRun /data   # list entries under /data with chunks on volume 42
+	fs.reassign -volumeId=42 /data           # copy the chunks and point the entries to the copies
+
+	This is used to empty a volume before it is decommissioned.
+	Each chunk is copied as is, and read back from the new location
+	before the entry is updated. The filer then deletes the old chunks.
+
+	Chunks referenced inside chunk manifests are not checked.
+
+`
+}
+
+func (c *commandFsReassign) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	if err = commandEnv.confirmIsLocked(); err != nil {
+		return
+	}
+
+	reassignCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	volumeId := reassignCommand.Uint("volumeId", 0, "the volume id to move chunks away from")
+	dryRun := reassignCommand.Bool("dryRun", false, "only list the affected entries")
+	if err = reassignCommand.Parse(args); err != nil {
+		return nil
+	}
+	if *volumeId == 0 {
+		return fmt.Errorf("missing -volumeId")
+	}
+	vid := needle.VolumeId(*volumeId)
+
+	path, err := commandEnv.parseUrl(findInputDirectory(reassignCommand.Args()))
+	if err != nil {
+		return err
+	}
+
+	// collect the affected entries first, since updating entries while traversing could visit them again
+	var entriesLock sync.Mutex
+	var dirs []util.FullPath
+	var entries []*filer_pb.Entry
+	err = filer_pb.TraverseBfs(commandEnv, util.FullPath(path), func(parentPath util.FullPath, entry *filer_pb.Entry) {
+		if entry.IsDirectory || !hasChunkOnVolume(entry, vid) {
+			return
+		}
+		entriesLock.Lock()
+		dirs = append(dirs, parentPath)
+		entries = append(entries, entry)
+		entriesLock.Unlock()
+	})
+	if err != nil {
+		return fmt.Errorf("traverse %s: %v", path, err)
+	}
+
+	for i, entry := range entries {
+		fmt.Fprintf(writer, "%s\n", dirs[i].Child(entry.Name))
+		if *dryRun {
+			continue
+		}
+		if err = reassignEntryChunks(commandEnv, writer, dirs[i], entry, vid); err != nil {
+			return fmt.Errorf("reassign %s: %v", dirs[i].Child(entry.Name), err)
+		}
+	}
+	fmt.Fprintf(writer, "%d entries with chunks on volume %d\n", len(entries), vid)
+
+	return nil
+}
+
+func hasChunkOnVolume(entry *filer_pb.Entry, vid needle.VolumeId) bool {
+	for _, chunk := range entry.Chunks {
+		if fid, err := needle.ParseFileIdFromString(chunk.GetFileIdString()); err == nil && fid.VolumeId == vid {
+			return true
+		}
+	}
+	return false
+}
+
+func reassignEntryChunks(commandEnv *CommandEnv, writer io.Writer, dir util.FullPath, entry *filer_pb.Entry, vid needle.VolumeId) error {
+
+	for _, chunk := range entry.Chunks {
+		fid, err := needle.ParseFileIdFromString(chunk.GetFileIdString())
+		if err != nil || fid.VolumeId != vid {
+			continue
+		}
+		newFileId, err := copyChunkToNewVolume(commandEnv, string(dir.Child(entry.Name)), entry.Attributes, chunk.GetFileIdString())
+		if err != nil {
+			return fmt.Errorf("copy chunk %s: %v", chunk.GetFileIdString(), err)
+		}
+		fmt.Fprintf(writer, "  %s => %s\n", chunk.GetFileIdString(), newFileId)
+		chunk.FileId, chunk.Fid = newFileId, nil
+	}
+
+	return commandEnv.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
+		return filer_pb.UpdateEntry(client, &filer_pb.UpdateEntryRequest{
+			Directory: string(dir),
+			Entry:     entry,
+		})
+	})
+}
+
+func copyChunkToNewVolume(commandEnv *CommandEnv, path string, attr *filer_pb.FuseAttributes, fileId string) (newFileId string, err error) {
+
+	urls, err := commandEnv.MasterClient.LookupFileId(fileId)
+	if err != nil || len(urls) == 0 {
+		return "", fmt.Errorf("lookup %s: %v", fileId, err)
+	}
+
+	var host string
+	var auth security.EncodedJwt
+	if err = commandEnv.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
+		request := &filer_pb.AssignVolumeRequest{
+			Count:       1,
+			Replication: attr.Replication,
+			Collection:  attr.Collection,
+			TtlSec:      attr.TtlSec,
+			Path:        path,
+		}
+		resp, assignErr := client.AssignVolume(context.Background(), request)
+		if assignErr != nil {
+			return assignErr
+		}
+		if resp.Error != "" {
+			return fmt.Errorf("assign volume: %v", resp.Error)
+		}
+		newFileId, host, auth = resp.FileId, resp.Url, security.EncodedJwt(resp.Auth)
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	if newFid, parseErr := needle.ParseFileIdFromString(newFileId); parseErr != nil {
+		return "", fmt.Errorf("parse assigned file id %s: %v", newFileId, parseErr)
+	} else if oldFid, _ := needle.ParseFileIdFromString(fileId); newFid.VolumeId == oldFid.VolumeId {
+		return "", fmt.Errorf("volume %d is still writable, mark it readonly first with volume.mark", newFid.VolumeId)
+	}
+
+	// fetch data as is, regardless whether it is encrypted or not
+	filename, header, resp, err := util.DownloadFile(urls[0])
+	if err != nil {
+		return "", fmt.Errorf("read %s: %v", urls[0], err)
+	}
+	defer util.CloseResponse(resp)
+
+	newUrl := fmt.Sprintf("http://%s/%s", host, newFileId)
+	uploadResult, err, _ := operation.Upload(newUrl, filename, false, resp.Body, "gzip" == header.Get("Content-Encoding"), header.Get("Content-Type"), nil, auth)
+	if err != nil {
+		return "", fmt.Errorf("upload to %s: %v", newUrl, err)
+	}
+	if uploadResult.Error != "" {
+		return "", fmt.Errorf("upload to %s: %v", newUrl, uploadResult.Error)
+	}
+
+	// confirm the copy is readable before the entry points to it
+	verifyResp, err := http.Get(newUrl)
+	if err != nil {
+		return "", fmt.Errorf("verify %s: %v", newUrl, err)
+	}
+	io.Copy(ioutil.Discard, verifyResp.Body)
+	verifyResp.Body.Close()
+	if verifyResp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("verify %s: %s", newUrl, verifyResp.Status)
+	}
+
+	return newFileId, nil
+}
@@ -0,0 +1,87 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func init() {
+	Commands = append(Commands, &commandFsRm{})
+}
+
+type commandFsRm struct {
+}
+
+func (c *commandFsRm) Name() string {
+	return "fs.rm"
+}
+
+func (c *commandFsRm) Help() string {
+	return `remove a file or a folder, together with the file content
+
+	fs.rm /dir/file_name
+	fs.rm -r /dir            # remove the folder and everything under it
+	fs.rm -r -async /dir     # return right away, the filer removes the folder in the background
+
+	The progress of the background removals is shown by fs.rm.status.
+`
+}
+
+func (c *commandFsRm) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	fsRmCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	isRecursive := fsRmCommand.Bool("r", false, "remove the folder recursively")
+	isAsync := fsRmCommand.Bool("async", false, "with -r, remove the folder in the background on the filer")
+	if err = fsRmCommand.Parse(args); err != nil {
+		return nil
+	}
+
+	if fsRmCommand.NArg() == 0 {
+		return fmt.Errorf("need a file or folder to remove")
+	}
+
+	for _, arg := range fsRmCommand.Args() {
+		path, parseErr := commandEnv.parseUrl(arg)
+		if parseErr != nil {
+			return parseErr
+		}
+		if path == "/" {
+			return fmt.Errorf("can not remove the root folder")
+		}
+
+		dir, name := util.FullPath(path).DirAndName()
+
+		err = commandEnv.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
+			resp, deleteErr := client.DeleteEntry(context.Background(), &filer_pb.DeleteEntryRequest{
+				Directory:    dir,
+				Name:         name,
+				IsDeleteData: true,
+				IsRecursive:  *isRecursive,
+				IsAsync:      *isAsync,
+			})
+			if deleteErr != nil {
+				return deleteErr
+			}
+			if resp.Error != "" {
+				return fmt.Errorf("%s", resp.Error)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("remove %s: %v", path, err)
+		}
+
+		if *isRecursive && *isAsync {
+			fmt.Fprintf(writer, "removing %s in background\n", path)
+		} else {
+			fmt.Fprintf(writer, "removed %s\n", path)
+		}
+	}
+
+	return nil
+}
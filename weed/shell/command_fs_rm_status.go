@@ -0,0 +1,61 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/filer_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandFsRmStatus{})
+}
+
+type commandFsRmStatus struct {
+}
+
+func (c *commandFsRmStatus) Name() string {
+	return "fs.rm.status"
+}
+
+func (c *commandFsRmStatus) Help() string {
+	return `show the progress of the folders removed in the background by "fs.rm -r -async"
+
+	fs.rm.status
+
+	Only the running removals are listed. The filer logs the result of a finished removal.
+`
+}
+
+func (c *commandFsRmStatus) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	var resp *filer_pb.ListBackgroundDeletionsResponse
+	err = commandEnv.WithFilerClient(func(client filer_pb.SeaweedFilerClient) error {
+		resp, err = client.ListBackgroundDeletions(context.Background(), &filer_pb.ListBackgroundDeletionsRequest{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Deletions) == 0 {
+		fmt.Fprintf(writer, "no background removals\n")
+		return nil
+	}
+
+	for _, d := range resp.Deletions {
+		startedAt := time.Unix(0, d.StartedNs)
+		status := fmt.Sprintf("running for %v", time.Since(startedAt).Round(time.Second))
+		if d.FinishedNs > 0 {
+			status = fmt.Sprintf("finished in %v", time.Unix(0, d.FinishedNs).Sub(startedAt).Round(time.Second))
+		}
+		fmt.Fprintf(writer, "%s\t%s\tremoved:%d\terrors:%d\n", d.Path, status, d.DeletedCount, d.ErrorCount)
+		if d.LastError != "" {
+			fmt.Fprintf(writer, "\tlast error: %s\n", d.LastError)
+		}
+	}
+
+	return nil
+}
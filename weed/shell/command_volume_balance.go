@@ -28,7 +28,15 @@ func (c *commandVolumeBalance) Name() string {
 func (c *commandVolumeBalance) Help() string {
 	return `balance all volumes among volume servers
 
-	volume.balance [-collection ALL|EACH_COLLECTION|<collection_name>] [-force] [-dataCenter=<data_center_name>]
+	volume.balance [-collection ALL|EACH_COLLECTION|<collection_name>] [-force] [-dataCenter=<data_center_name>] [-goal=volumeCount|diskUsage|writeLoad]
+
+	Without -force, this only prints the planned moves, and the load of each rack before and after them.
+
+	The -goal decides what is evened out among the volume servers, relative to their max volume counts:
+		volumeCount: the number of volumes, the default
+		diskUsage:   the volume sizes
+		writeLoad:   the write rates of the volumes, as reported in the latest heartbeats
+	The moves always keep the volume replicas placed as their replication requires, across racks and data centers.
 
 	Algorithm:
 
@@ -40,7 +48,7 @@ func (c *commandVolumeBalance) Help() string {
 	}
 
 	func balanceWritableVolumes(){
-		idealWritableVolumeRatio = totalWritableVolumes / totalNumberOfMaxVolumes
+		idealWritableVolumeRatio = totalWritableVolumeLoad / totalNumberOfMaxVolumes
 		for hasMovedOneVolume {
 			sort all volume servers ordered by the localWritableVolumeRatio = localWritableVolumes to localVolumeMax
 			pick the volume server B with the highest localWritableVolumeRatio y
@@ -70,9 +78,14 @@ func (c *commandVolumeBalance) Do(args []string, commandEnv *CommandEnv, writer
 	collection := balanceCommand.String("collection", "EACH_COLLECTION", "collection name, or use \"ALL_COLLECTIONS\" across collections, \"EACH_COLLECTION\" for each collection")
 	dc := balanceCommand.String("dataCenter", "", "only apply the balancing for this dataCenter")
 	applyBalancing := balanceCommand.Bool("force", false, "apply the balancing plan.")
+	goalName := balanceCommand.String("goal", "volumeCount", "even out the \"volumeCount\", the \"diskUsage\", or the \"writeLoad\"")
 	if err = balanceCommand.Parse(args); err != nil {
 		return nil
 	}
+	volumeLoad, found := volumeBalanceGoals[*goalName]
+	if !found {
+		return fmt.Errorf("unknown goal %s", *goalName)
+	}
 
 	var resp *master_pb.VolumeListResponse
 	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
@@ -85,6 +98,11 @@ func (c *commandVolumeBalance) Do(args []string, commandEnv *CommandEnv, writer
 
 	volumeServers := collectVolumeServersByDc(resp.TopologyInfo, *dc)
 	volumeReplicas, _ := collectVolumeReplicaLocations(resp)
+	for _, n := range volumeServers {
+		n.volumeLoad = volumeLoad
+	}
+	rackLoadsBefore := collectRackLoads(volumeServers)
+	defer printRackLoads(writer, *goalName, rackLoadsBefore, volumeServers)
 
 	if *collection == "EACH_COLLECTION" {
 		collections, err := ListCollectionNames(commandEnv, true, false)
@@ -167,14 +185,36 @@ type Node struct {
 	selectedVolumes map[uint32]*master_pb.VolumeInformationMessage
 	dc              string
 	rack            string
+	volumeLoad      func(v *master_pb.VolumeInformationMessage) float64 // nil to count the volumes
+}
+
+// volumeBalanceGoals are what volume.balance can even out, by the load of each volume
+var volumeBalanceGoals = map[string]func(v *master_pb.VolumeInformationMessage) float64{
+	"volumeCount": func(v *master_pb.VolumeInformationMessage) float64 { return 1 },
+	"diskUsage":   func(v *master_pb.VolumeInformationMessage) float64 { return float64(v.Size) },
+	"writeLoad":   func(v *master_pb.VolumeInformationMessage) float64 { return float64(v.WriteBytesPerSecond) },
+}
+
+func (n *Node) loadOf(v *master_pb.VolumeInformationMessage) float64 {
+	if n.volumeLoad == nil {
+		return 1
+	}
+	return n.volumeLoad(v)
+}
+
+func (n *Node) selectedLoad() (load float64) {
+	for _, v := range n.selectedVolumes {
+		load += n.loadOf(v)
+	}
+	return
 }
 
 func (n *Node) localVolumeRatio() float64 {
-	return divide(len(n.selectedVolumes), int(n.info.MaxVolumeCount))
+	return n.selectedLoad() / float64(n.info.MaxVolumeCount)
 }
 
-func (n *Node) localVolumeNextRatio() float64 {
-	return divide(len(n.selectedVolumes)+1, int(n.info.MaxVolumeCount))
+func (n *Node) localVolumeNextRatio(v *master_pb.VolumeInformationMessage) float64 {
+	return (n.selectedLoad() + n.loadOf(v)) / float64(n.info.MaxVolumeCount)
 }
 
 func (n *Node) selectVolumes(fn func(v *master_pb.VolumeInformationMessage) bool) {
@@ -199,13 +239,17 @@ func sortReadOnlyVolumes(volumes []*master_pb.VolumeInformationMessage) {
 }
 
 func balanceSelectedVolume(commandEnv *CommandEnv, volumeReplicas map[uint32][]*VolumeReplica, nodes []*Node, sortCandidatesFn func(volumes []*master_pb.VolumeInformationMessage), applyBalancing bool) (err error) {
-	selectedVolumeCount, volumeMaxCount := 0, 0
+	selectedVolumeLoad, volumeMaxCount := 0.0, 0
 	for _, dn := range nodes {
-		selectedVolumeCount += len(dn.selectedVolumes)
+		selectedVolumeLoad += dn.selectedLoad()
 		volumeMaxCount += int(dn.info.MaxVolumeCount)
 	}
+	if volumeMaxCount == 0 {
+		return nil
+	}
 
-	idealVolumeRatio := divide(selectedVolumeCount, volumeMaxCount)
+	// allow for the float rounding when comparing to the ideal ratio
+	idealVolumeRatio := selectedVolumeLoad / float64(volumeMaxCount) * (1 + 1e-9)
 
 	hasMoved := true
 
@@ -221,14 +265,18 @@ func balanceSelectedVolume(commandEnv *CommandEnv, volumeReplicas map[uint32][]*
 			candidateVolumes = append(candidateVolumes, v)
 		}
 		sortCandidatesFn(candidateVolumes)
+		// try the heaviest volumes first to move less volumes, all volumes weigh the same for the volume count
+		sort.SliceStable(candidateVolumes, func(i, j int) bool {
+			return fullNode.loadOf(candidateVolumes[i]) > fullNode.loadOf(candidateVolumes[j])
+		})
 
 		for i := 0; i < len(nodes)-1; i++ {
 			emptyNode := nodes[i]
-			if !(fullNode.localVolumeRatio() > idealVolumeRatio && emptyNode.localVolumeNextRatio() <= idealVolumeRatio) {
+			if !(fullNode.localVolumeRatio() > idealVolumeRatio && emptyNode.localVolumeRatio() < idealVolumeRatio) {
 				// no more volume servers with empty slots
 				break
 			}
-			hasMoved, err = attemptToMoveOneVolume(commandEnv, volumeReplicas, fullNode, candidateVolumes, emptyNode, applyBalancing)
+			hasMoved, err = attemptToMoveOneVolume(commandEnv, volumeReplicas, fullNode, candidateVolumes, emptyNode, idealVolumeRatio, applyBalancing)
 			if err != nil {
 				return
 			}
@@ -241,9 +289,17 @@ func balanceSelectedVolume(commandEnv *CommandEnv, volumeReplicas map[uint32][]*
 	return nil
 }
 
-func attemptToMoveOneVolume(commandEnv *CommandEnv, volumeReplicas map[uint32][]*VolumeReplica, fullNode *Node, candidateVolumes []*master_pb.VolumeInformationMessage, emptyNode *Node, applyBalancing bool) (hasMoved bool, err error) {
+func attemptToMoveOneVolume(commandEnv *CommandEnv, volumeReplicas map[uint32][]*VolumeReplica, fullNode *Node, candidateVolumes []*master_pb.VolumeInformationMessage, emptyNode *Node, idealVolumeRatio float64, applyBalancing bool) (hasMoved bool, err error) {
 
 	for _, v := range candidateVolumes {
+		if fullNode.loadOf(v) <= 0 {
+			// moving it changes nothing, e.g. a volume without writes for the write load
+			continue
+		}
+		if emptyNode.localVolumeNextRatio(v) > idealVolumeRatio {
+			// the volume would overload the empty node
+			continue
+		}
 		hasMoved, err = maybeMoveOneVolume(commandEnv, volumeReplicas, fullNode, v, emptyNode, applyBalancing)
 		if err != nil {
 			return
@@ -329,6 +385,15 @@ func adjustAfterMove(v *master_pb.VolumeInformationMessage, volumeReplicas map[u
 	if emptyNode.selectedVolumes != nil {
 		emptyNode.selectedVolumes[v.Id] = v
 	}
+	// later selections see the moved volume on the new node, the volume list may be iterated by the callers
+	var volumeInfos []*master_pb.VolumeInformationMessage
+	for _, info := range fullNode.info.VolumeInfos {
+		if info.Id != v.Id {
+			volumeInfos = append(volumeInfos, info)
+		}
+	}
+	fullNode.info.VolumeInfos = volumeInfos
+	emptyNode.info.VolumeInfos = append(emptyNode.info.VolumeInfos[:len(emptyNode.info.VolumeInfos):len(emptyNode.info.VolumeInfos)], v)
 	existingReplicas := volumeReplicas[v.Id]
 	for _, replica := range existingReplicas {
 		if replica.location.dataNode.Id == fullNode.info.Id &&
@@ -341,3 +406,38 @@ func adjustAfterMove(v *master_pb.VolumeInformationMessage, volumeReplicas map[u
 		}
 	}
 }
+
+type rackLoad struct {
+	volumeCount int
+	load        float64
+}
+
+// collectRackLoads sums up all the volumes of the volume servers by data center and rack
+func collectRackLoads(nodes []*Node) map[string]*rackLoad {
+	loads := make(map[string]*rackLoad)
+	for _, n := range nodes {
+		key := fmt.Sprintf("%s %s", n.dc, n.rack)
+		if loads[key] == nil {
+			loads[key] = &rackLoad{}
+		}
+		for _, v := range n.info.VolumeInfos {
+			loads[key].volumeCount++
+			loads[key].load += n.loadOf(v)
+		}
+	}
+	return loads
+}
+
+func printRackLoads(writer io.Writer, goalName string, before map[string]*rackLoad, nodes []*Node) {
+	after := collectRackLoads(nodes)
+
+	var racks []string
+	for rack := range before {
+		racks = append(racks, rack)
+	}
+	sort.Strings(racks)
+	for _, rack := range racks {
+		fmt.Fprintf(writer, "rack %s: %d volumes, %s %.0f => %d volumes, %s %.0f\n", rack,
+			before[rack].volumeCount, goalName, before[rack].load, after[rack].volumeCount, goalName, after[rack].load)
+	}
+}
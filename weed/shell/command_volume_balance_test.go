@@ -169,3 +169,48 @@ func TestIsGoodMove(t *testing.T) {
 	}
 
 }
+
+func TestBalanceGoals(t *testing.T) {
+	newNodes := func(goal string) []*Node {
+		a := &master_pb.DataNodeInfo{Id: "dn1", MaxVolumeCount: 10}
+		b := &master_pb.DataNodeInfo{Id: "dn2", MaxVolumeCount: 10}
+		for i := uint32(1); i <= 4; i++ {
+			a.VolumeInfos = append(a.VolumeInfos, &master_pb.VolumeInformationMessage{Id: i, Size: 100})
+			b.VolumeInfos = append(b.VolumeInfos, &master_pb.VolumeInformationMessage{Id: 10 + i, Size: 10})
+		}
+		nodes := []*Node{
+			{info: a, dc: "dc1", rack: "r1", volumeLoad: volumeBalanceGoals[goal]},
+			{info: b, dc: "dc1", rack: "r2", volumeLoad: volumeBalanceGoals[goal]},
+		}
+		for _, n := range nodes {
+			n.selectVolumes(func(v *master_pb.VolumeInformationMessage) bool { return true })
+		}
+		return nodes
+	}
+	volumeCountOn := func(nodes []*Node, id string) int {
+		for _, n := range nodes {
+			if n.info.Id == id {
+				return len(n.info.VolumeInfos)
+			}
+		}
+		return -1
+	}
+
+	// the same number of volumes on each node
+	nodes := newNodes("volumeCount")
+	if err := balanceSelectedVolume(nil, map[uint32][]*VolumeReplica{}, nodes, sortWritableVolumes, false); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if volumeCountOn(nodes, "dn1") != 4 {
+		t.Errorf("volumeCount: expected no moves, dn1 has %d volumes", volumeCountOn(nodes, "dn1"))
+	}
+
+	// one large volume evens out the disk usage best
+	nodes = newNodes("diskUsage")
+	if err := balanceSelectedVolume(nil, map[uint32][]*VolumeReplica{}, nodes, sortWritableVolumes, false); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if volumeCountOn(nodes, "dn1") != 3 || volumeCountOn(nodes, "dn2") != 5 {
+		t.Errorf("diskUsage: expected to move one volume, dn1 has %d volumes, dn2 has %d", volumeCountOn(nodes, "dn1"), volumeCountOn(nodes, "dn2"))
+	}
+}
@@ -0,0 +1,337 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage/idx"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+func init() {
+	Commands = append(Commands, &commandVolumeCheckDisk{})
+}
+
+type commandVolumeCheckDisk struct {
+}
+
+func (c *commandVolumeCheckDisk) Name() string {
+	return "volume.check.disk"
+}
+
+func (c *commandVolumeCheckDisk) Help() string {
+	return `compare the needles of the volume replicas, and optionally sync the missing needles
+
+	volume.check.disk                      # report the needles that differ between the replicas of each volume
+	volume.check.disk -volumeId=7 -v       # check volume 7, and list every differing needle
+	volume.check.disk -syncMissing         # also copy the needles missing on a replica from the most complete replica
+
+	The index files of all replicas are streamed from the volume servers and compared. It reports
+	needles missing on a replica, and needles deleted on one replica but still live on another.
+	The needle sizes are not compared, since a replica may keep a few more headers than the first copy.
+	The most complete replica is the one with the most live needles.
+
+	volume.check.disk -replicationFailures # only check the volumes with replication failures recorded by the volume servers
+
+	The volume servers record the replicated writes acknowledged before a replica got them, with writeConcern
+	one or quorum, when the replica failed later. These failures are listed first, since the replicas miss needles.
+
+	Only the missing needles are synced. The deleted needles are only reported.
+	A needle deleted on a replica and then vacuumed away looks missing, and would be synced back from a
+	replica that missed the deletion.
+
+`
+}
+
+func (c *commandVolumeCheckDisk) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	checkCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	volumeId := checkCommand.Int("volumeId", 0, "the volume id, 0 for all volumes")
+	syncMissing := checkCommand.Bool("syncMissing", false, "copy the needles missing on a replica from the most complete replica")
+	verbose := checkCommand.Bool("v", false, "list every differing needle")
+	onlyReplicationFailures := checkCommand.Bool("replicationFailures", false, "only check the volumes with recorded replication failures")
+	if err = checkCommand.Parse(args); err != nil {
+		return nil
+	}
+
+	if *syncMissing {
+		if err = commandEnv.confirmIsLocked(); err != nil {
+			return
+		}
+	}
+
+	var resp *master_pb.VolumeListResponse
+	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		resp, err = client.VolumeList(context.Background(), &master_pb.VolumeListRequest{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	volumeReplicas, allLocations := collectVolumeReplicaLocations(resp)
+	failedVolumes := reportReplicationFailures(commandEnv, writer, allLocations)
+	var vids []uint32
+	for vid, replicas := range volumeReplicas {
+		if *volumeId != 0 && vid != uint32(*volumeId) {
+			continue
+		}
+		if *onlyReplicationFailures && !failedVolumes[vid] {
+			continue
+		}
+		if len(replicas) < 2 {
+			continue
+		}
+		vids = append(vids, vid)
+	}
+	sort.Slice(vids, func(i, j int) bool {
+		return vids[i] < vids[j]
+	})
+	if *volumeId != 0 && len(vids) == 0 {
+		return fmt.Errorf("volume %d not found with more than one replica", *volumeId)
+	}
+
+	for _, vid := range vids {
+		if err = c.checkVolume(commandEnv, writer, vid, volumeReplicas[vid], *syncMissing, *verbose); err != nil {
+			return fmt.Errorf("check volume %d: %v", vid, err)
+		}
+	}
+	return nil
+}
+
+func (c *commandVolumeCheckDisk) checkVolume(commandEnv *CommandEnv, writer io.Writer, vid uint32, replicas []*VolumeReplica, syncMissing, verbose bool) error {
+
+	var needles []replicaNeedles
+	for _, replica := range replicas {
+		n, err := readReplicaNeedles(commandEnv, vid, replica)
+		if err != nil {
+			return fmt.Errorf("read index from %s: %v", replica.location.dataNode.Id, err)
+		}
+		needles = append(needles, n)
+	}
+
+	divergences := compareReplicaNeedles(needles)
+	if len(divergences) == 0 {
+		fmt.Fprintf(writer, "volume %d: %d replicas are consistent\n", vid, len(replicas))
+		return nil
+	}
+
+	src := mostCompleteReplica(needles)
+	fmt.Fprintf(writer, "volume %d: %d needles differ, the most complete replica is on %s\n", vid, len(divergences), replicas[src].location.dataNode.Id)
+	for i, replica := range replicas {
+		var missing, deleted int
+		for _, d := range divergences {
+			switch size := d.sizes[i]; {
+			case size == 0:
+				missing++
+			case size.IsDeleted():
+				deleted++
+			}
+		}
+		fmt.Fprintf(writer, "  %s: %d live needles, %d missing, %d deleted\n",
+			replica.location.dataNode.Id, needles[i].liveCount(), missing, deleted)
+	}
+	if verbose {
+		for _, d := range divergences {
+			var states []string
+			for i, size := range d.sizes {
+				states = append(states, fmt.Sprintf("%s:%s", replicas[i].location.dataNode.Id, describeNeedleSize(size)))
+			}
+			fmt.Fprintf(writer, "  needle %s %s\n", d.needleId, strings.Join(states, " "))
+		}
+	}
+
+	if !syncMissing {
+		return nil
+	}
+
+	source := replicas[src]
+	var synced int
+	for i, replica := range replicas {
+		if i == src {
+			continue
+		}
+		for _, d := range divergences {
+			if d.sizes[i] != 0 || !d.sizes[src].IsValid() {
+				continue
+			}
+			if err := syncOneNeedle(commandEnv, vid, d.needleId, source, replica); err != nil {
+				return fmt.Errorf("sync needle %s from %s to %s: %v", d.needleId, source.location.dataNode.Id, replica.location.dataNode.Id, err)
+			}
+			synced++
+		}
+	}
+	fmt.Fprintf(writer, "volume %d: synced %d missing needles from %s\n", vid, synced, source.location.dataNode.Id)
+	return nil
+}
+
+// reportReplicationFailures lists the replication failures recorded by the volume servers, and returns their volume ids.
+func reportReplicationFailures(commandEnv *CommandEnv, writer io.Writer, allLocations []location) (failedVolumes map[uint32]bool) {
+	failedVolumes = make(map[uint32]bool)
+	for _, loc := range allLocations {
+		err := operation.WithVolumeServerClient(loc.dataNode.Id, commandEnv.option.GrpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+			resp, err := client.VolumeServerStatus(context.Background(), &volume_server_pb.VolumeServerStatusRequest{})
+			if err != nil {
+				return err
+			}
+			for _, f := range resp.ReplicationFailures {
+				failedVolumes[f.VolumeId] = true
+				fmt.Fprintf(writer, "volume %d: %d writes failed to replicate from %s to %s, last at %v: %s\n",
+					f.VolumeId, f.Count, loc.dataNode.Id, f.Replica, time.Unix(0, f.LastFailedAtNs).Format(time.RFC3339), f.LastError)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(writer, "replication failures of %s: %v\n", loc.dataNode.Id, err)
+		}
+	}
+	return
+}
+
+// replicaNeedles maps the needle ids in the index file of a replica to their latest sizes.
+// The deleted needles have TombstoneFileSize.
+type replicaNeedles map[types.NeedleId]types.Size
+
+func (r replicaNeedles) liveCount() (count int) {
+	for _, size := range r {
+		if size.IsValid() {
+			count++
+		}
+	}
+	return
+}
+
+func readReplicaNeedles(commandEnv *CommandEnv, vid uint32, replica *VolumeReplica) (replicaNeedles, error) {
+	var buf bytes.Buffer
+	err := operation.WithVolumeServerClient(replica.location.dataNode.Id, commandEnv.option.GrpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		copyFileClient, err := client.CopyFile(context.Background(), &volume_server_pb.CopyFileRequest{
+			VolumeId:           vid,
+			Ext:                ".idx",
+			CompactionRevision: math.MaxUint32,
+			StopOffset:         math.MaxInt64,
+			Collection:         replica.info.Collection,
+		})
+		if err != nil {
+			return err
+		}
+		for {
+			resp, receiveErr := copyFileClient.Recv()
+			if receiveErr == io.EOF {
+				return nil
+			}
+			if receiveErr != nil {
+				return receiveErr
+			}
+			buf.Write(resp.FileContent)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseReplicaNeedles(buf.Bytes())
+}
+
+func parseReplicaNeedles(indexContent []byte) (replicaNeedles, error) {
+	// skip the entry being appended while streaming
+	indexContent = indexContent[:len(indexContent)-len(indexContent)%types.NeedleMapEntrySize]
+	needles := make(replicaNeedles)
+	err := idx.WalkIndexFile(bytes.NewReader(indexContent), func(key types.NeedleId, offset types.Offset, size types.Size) error {
+		if offset.IsZero() || !size.IsValid() {
+			size = types.TombstoneFileSize
+		}
+		needles[key] = size
+		return nil
+	})
+	return needles, err
+}
+
+// needleDivergence is a needle live on some replica, but missing or deleted on another replica.
+type needleDivergence struct {
+	needleId types.NeedleId
+	// the needle size on each replica, 0 if missing
+	sizes []types.Size
+}
+
+func compareReplicaNeedles(replicas []replicaNeedles) (divergences []needleDivergence) {
+	seen := make(map[types.NeedleId]bool)
+	for _, needles := range replicas {
+		for key := range needles {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			d := needleDivergence{needleId: key}
+			var live, notLive bool
+			for _, other := range replicas {
+				size := other[key]
+				if size.IsValid() {
+					live = true
+				} else {
+					notLive = true
+				}
+				d.sizes = append(d.sizes, size)
+			}
+			if live && notLive {
+				divergences = append(divergences, d)
+			}
+		}
+	}
+	sort.Slice(divergences, func(i, j int) bool {
+		return divergences[i].needleId < divergences[j].needleId
+	})
+	return
+}
+
+func mostCompleteReplica(replicas []replicaNeedles) (index int) {
+	most := -1
+	for i, needles := range replicas {
+		if count := needles.liveCount(); count > most {
+			index, most = i, count
+		}
+	}
+	return
+}
+
+func describeNeedleSize(size types.Size) string {
+	switch {
+	case size == 0:
+		return "missing"
+	case size.IsDeleted():
+		return "deleted"
+	}
+	return fmt.Sprintf("%d", size)
+}
+
+func syncOneNeedle(commandEnv *CommandEnv, vid uint32, key types.NeedleId, source, target *VolumeReplica) error {
+	var blob *volume_server_pb.ReadNeedleBlobResponse
+	err := operation.WithVolumeServerClient(source.location.dataNode.Id, commandEnv.option.GrpcDialOption, func(client volume_server_pb.VolumeServerClient) (err error) {
+		blob, err = client.ReadNeedleBlob(context.Background(), &volume_server_pb.ReadNeedleBlobRequest{
+			VolumeId: vid,
+			NeedleId: uint64(key),
+		})
+		return
+	})
+	if err != nil {
+		return err
+	}
+	return operation.WithVolumeServerClient(target.location.dataNode.Id, commandEnv.option.GrpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+		_, err := client.WriteNeedleBlob(context.Background(), &volume_server_pb.WriteNeedleBlobRequest{
+			VolumeId:        vid,
+			NeedleId:        uint64(key),
+			NeedleBlob:      blob.NeedleBlob,
+			Size:            blob.Size,
+			EncryptionKeyId: blob.EncryptionKeyId,
+		})
+		return err
+	})
+}
@@ -0,0 +1,53 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/needle_map"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+func TestCompareReplicaNeedles(t *testing.T) {
+	var index []byte
+	for _, entry := range []struct {
+		key    types.NeedleId
+		offset int64
+		size   types.Size
+	}{
+		{1, 8, 100},
+		{2, 120, 200},
+		{3, 336, 300},
+		{2, 0, types.TombstoneFileSize},
+		{4, 648, 400},
+		{5, 1056, 50},
+	} {
+		index = append(index, needle_map.ToBytes(entry.key, types.ToOffset(entry.offset), entry.size)...)
+	}
+	// a partially appended entry
+	index = append(index, 0, 0, 0)
+
+	first, err := parseReplicaNeedles(index)
+	if err != nil {
+		t.Fatalf("parse index: %v", err)
+	}
+	second := replicaNeedles{1: 100, 2: 200, 3: 333}
+
+	divergences := compareReplicaNeedles([]replicaNeedles{first, second})
+	expected := []needleDivergence{
+		{needleId: 2, sizes: []types.Size{types.TombstoneFileSize, 200}},
+		{needleId: 4, sizes: []types.Size{400, 0}},
+		{needleId: 5, sizes: []types.Size{50, 0}},
+	}
+	if len(divergences) != len(expected) {
+		t.Fatalf("divergences %+v, expected %+v", divergences, expected)
+	}
+	for i, d := range divergences {
+		if d.needleId != expected[i].needleId || d.sizes[0] != expected[i].sizes[0] || d.sizes[1] != expected[i].sizes[1] {
+			t.Errorf("divergence %d: %+v, expected %+v", i, d, expected[i])
+		}
+	}
+
+	if src := mostCompleteReplica([]replicaNeedles{second, first}); src != 1 {
+		t.Errorf("most complete replica %d, expected 1", src)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
 	"io"
 	"sort"
+	"strings"
+	"sync"
 
 	"github.com/chrislusf/seaweedfs/weed/operation"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
@@ -32,15 +34,16 @@ func (c *commandVolumeFixReplication) Help() string {
 
 	This command also finds all under-replicated volumes, and finds volume servers with free slots.
 	If the free slots satisfy the replication requirement, the volume content is copied over and mounted.
+	Volume servers, racks or data centers in maintenance do not receive new replicas.
 
 	volume.fix.replication -n # do not take action
 	volume.fix.replication    # actually deleting or copying the volume files and mount the volume
+	volume.fix.replication -maxParallelization 8   # copy up to 8 replicas at the same time
 
 	Note:
-		* each time this will only add back one replica for one volume id. If there are multiple replicas
-		  are missing, e.g. multiple volume servers are new, you may need to run this multiple times.
 		* do not run this too quickly within seconds, since the new volume replica may take a few seconds 
 		  to register itself to the master.
+		* the master can run this automatically, see master.maintenance.replication_repair_delay_minutes in master.toml
 
 `
 }
@@ -53,11 +56,15 @@ func (c *commandVolumeFixReplication) Do(args []string, commandEnv *CommandEnv,
 
 	volFixReplicationCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
 	skipChange := volFixReplicationCommand.Bool("n", false, "skip the changes")
+	maxParallelization := volFixReplicationCommand.Int("maxParallelization", 4, "copy up to this many replicas at the same time")
 	if err = volFixReplicationCommand.Parse(args); err != nil {
 		return nil
 	}
 
 	takeAction := !*skipChange
+	if *maxParallelization < 1 {
+		return fmt.Errorf("maxParallelization should be at least 1")
+	}
 
 	var resp *master_pb.VolumeListResponse
 	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
@@ -100,7 +107,12 @@ func (c *commandVolumeFixReplication) Do(args []string, commandEnv *CommandEnv,
 	// find the most under populated data nodes
 	keepDataNodesSorted(allLocations)
 
-	return c.fixUnderReplicatedVolumes(commandEnv, writer, takeAction, underReplicatedVolumeIds, volumeReplicas, allLocations)
+	maintenanceTargets, err := listMaintenance(commandEnv)
+	if err != nil {
+		return err
+	}
+
+	return c.fixUnderReplicatedVolumes(commandEnv, writer, takeAction, *maxParallelization, underReplicatedVolumeIds, volumeReplicas, allLocations, maintenanceTargets)
 
 }
 
@@ -141,48 +153,88 @@ func (c *commandVolumeFixReplication) fixOverReplicatedVolumes(commandEnv *Comma
 	return nil
 }
 
-func (c *commandVolumeFixReplication) fixUnderReplicatedVolumes(commandEnv *CommandEnv, writer io.Writer, takeAction bool, underReplicatedVolumeIds []uint32, volumeReplicas map[uint32][]*VolumeReplica, allLocations []location) error {
+type replicaCopy struct {
+	vid uint32
+	src *VolumeReplica
+	dst location
+}
+
+func (c *commandVolumeFixReplication) fixUnderReplicatedVolumes(commandEnv *CommandEnv, writer io.Writer, takeAction bool, maxParallelization int, underReplicatedVolumeIds []uint32, volumeReplicas map[uint32][]*VolumeReplica, allLocations []location, maintenanceTargets []*master_pb.MaintenanceTarget) error {
+
+	sort.Slice(underReplicatedVolumeIds, func(i, j int) bool {
+		return underReplicatedVolumeIds[i] < underReplicatedVolumeIds[j]
+	})
+
+	// plan all the missing replicas first, so one run adds back all of them
+	var copies []replicaCopy
 	for _, vid := range underReplicatedVolumeIds {
 		replicas := volumeReplicas[vid]
 		replica := pickOneReplicaToCopyFrom(replicas)
 		replicaPlacement, _ := super_block.NewReplicaPlacementFromByte(byte(replica.info.ReplicaPlacement))
-		foundNewLocation := false
-		for _, dst := range allLocations {
-			// check whether data nodes satisfy the constraints
-			if dst.dataNode.FreeVolumeCount > 0 && satisfyReplicaPlacement(replicaPlacement, replicas, dst) {
-				// ask the volume server to replicate the volume
-				foundNewLocation = true
-				fmt.Fprintf(writer, "replicating volume %d %s from %s to dataNode %s ...\n", replica.info.Id, replicaPlacement, replica.location.dataNode.Id, dst.dataNode.Id)
-
-				if !takeAction {
+		for len(replicas) < replicaPlacement.GetCopyCount() {
+			foundNewLocation := false
+			for _, dst := range allLocations {
+				if isInMaintenance(maintenanceTargets, dst.dc, dst.rack, dst.dataNode.Id) {
+					continue
+				}
+				// check whether data nodes satisfy the constraints
+				if dst.dataNode.FreeVolumeCount > 0 && satisfyReplicaPlacement(replicaPlacement, replicas, dst) {
+					foundNewLocation = true
+					fmt.Fprintf(writer, "replicating volume %d %s from %s to dataNode %s ...\n", replica.info.Id, replicaPlacement, replica.location.dataNode.Id, dst.dataNode.Id)
+					copies = append(copies, replicaCopy{vid: vid, src: replica, dst: dst})
+
+					// count the new replica for the next one, and adjust free volume count
+					newLocation := dst
+					replicas = append(replicas, &VolumeReplica{location: &newLocation, info: replica.info})
+					dst.dataNode.FreeVolumeCount--
+					keepDataNodesSorted(allLocations)
 					break
 				}
+			}
+			if !foundNewLocation {
+				fmt.Fprintf(writer, "failed to place volume %d replica as %s, existing:%+v\n", replica.info.Id, replicaPlacement, len(replicas))
+				break
+			}
+		}
+	}
 
-				err := operation.WithVolumeServerClient(dst.dataNode.Id, commandEnv.option.GrpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
-					_, replicateErr := volumeServerClient.VolumeCopy(context.Background(), &volume_server_pb.VolumeCopyRequest{
-						VolumeId:       replica.info.Id,
-						SourceDataNode: replica.location.dataNode.Id,
-					})
-					if replicateErr != nil {
-						return fmt.Errorf("copying from %s => %s : %v", replica.location.dataNode.Id, dst.dataNode.Id, replicateErr)
-					}
-					return nil
-				})
+	if !takeAction || len(copies) == 0 {
+		return nil
+	}
 
-				if err != nil {
-					return err
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var copyErrors []string
+	limiter := make(chan struct{}, maxParallelization)
+	for _, rc := range copies {
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(rc replicaCopy) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			err := operation.WithVolumeServerClient(rc.dst.dataNode.Id, commandEnv.option.GrpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
+				_, replicateErr := volumeServerClient.VolumeCopy(context.Background(), &volume_server_pb.VolumeCopyRequest{
+					VolumeId:       rc.vid,
+					SourceDataNode: rc.src.location.dataNode.Id,
+				})
+				if replicateErr != nil {
+					return fmt.Errorf("copying volume %d from %s => %s : %v", rc.vid, rc.src.location.dataNode.Id, rc.dst.dataNode.Id, replicateErr)
 				}
-
-				// adjust free volume count
-				dst.dataNode.FreeVolumeCount--
-				keepDataNodesSorted(allLocations)
-				break
+				return nil
+			})
+			if err != nil {
+				errLock.Lock()
+				copyErrors = append(copyErrors, err.Error())
+				errLock.Unlock()
 			}
-		}
-		if !foundNewLocation {
-			fmt.Fprintf(writer, "failed to place volume %d replica as %s, existing:%+v\n", replica.info.Id, replicaPlacement, len(replicas))
-		}
+		}(rc)
+	}
+	wg.Wait()
 
+	if len(copyErrors) > 0 {
+		return fmt.Errorf("%d of %d replicas failed: %s", len(copyErrors), len(copies), strings.Join(copyErrors, "; "))
 	}
 	return nil
 }
@@ -1,6 +1,8 @@
 package shell
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
@@ -271,3 +273,34 @@ func runTests(tests []testcase, t *testing.T) {
 		}
 	}
 }
+
+func TestFixUnderReplicatedVolumesPlansAllMissingReplicas(t *testing.T) {
+	rp, _ := super_block.NewReplicaPlacementFromString("002")
+	volumeInfo := &master_pb.VolumeInformationMessage{Id: 7, ReplicaPlacement: uint32(rp.Byte())}
+
+	allLocations := []location{
+		{"dc1", "r1", &master_pb.DataNodeInfo{Id: "dn1", FreeVolumeCount: 5}},
+		{"dc1", "r1", &master_pb.DataNodeInfo{Id: "dn2", FreeVolumeCount: 5}},
+		{"dc1", "r1", &master_pb.DataNodeInfo{Id: "dn3", FreeVolumeCount: 9}},
+		{"dc1", "r1", &master_pb.DataNodeInfo{Id: "dn4", FreeVolumeCount: 5}},
+	}
+	existing := allLocations[0]
+	volumeReplicas := map[uint32][]*VolumeReplica{
+		7: {{location: &existing, info: volumeInfo}},
+	}
+	maintenanceTargets := []*master_pb.MaintenanceTarget{{DataNode: "dn3"}}
+
+	var output bytes.Buffer
+	c := &commandVolumeFixReplication{}
+	if err := c.fixUnderReplicatedVolumes(nil, &output, false, 1, []uint32{7}, volumeReplicas, allLocations, maintenanceTargets); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+
+	// both missing replicas are placed, and the server in maintenance is skipped
+	if strings.Count(output.String(), "replicating volume 7") != 2 {
+		t.Errorf("expected 2 replicas, got:\n%s", output.String())
+	}
+	if strings.Contains(output.String(), "dn3") {
+		t.Errorf("placed a replica on a server in maintenance:\n%s", output.String())
+	}
+}
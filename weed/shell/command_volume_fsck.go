@@ -327,7 +327,7 @@ func (c *commandVolumeFsck) purgeFileIdsForOneVolume(volumeId uint32, fileIds []
 				resultChan <- deleteResults
 			}
 
-		}(location.Url, fileIds)
+		}(location.ServerAddress(), fileIds)
 	}
 	wg.Wait()
 	close(resultChan)
@@ -82,6 +82,9 @@ func writeRackInfo(writer io.Writer, t *master_pb.RackInfo) statistics {
 }
 func writeDataNodeInfo(writer io.Writer, t *master_pb.DataNodeInfo) statistics {
 	fmt.Fprintf(writer, "      DataNode %s volume:%d/%d active:%d free:%d remote:%d\n", t.Id, t.VolumeCount, t.MaxVolumeCount, t.ActiveVolumeCount, t.FreeVolumeCount, t.RemoteVolumeCount)
+	for _, dir := range t.DiskSpaceLowDirs {
+		fmt.Fprintf(writer, "        low disk space in %s, the volumes there are read only\n", dir)
+	}
 	var s statistics
 	sort.Slice(t.VolumeInfos, func(i, j int) bool {
 		return t.VolumeInfos[i].Id < t.VolumeInfos[j].Id
@@ -33,12 +33,16 @@ func (c *commandVolumeMove) Help() string {
 	This command move a live volume from one volume server to another volume server. Here are the steps:
 
 	1. This command asks the target volume server to copy the source volume from source volume server, remember the last entry's timestamp.
-	2. This command asks the target volume server to mount the new volume
-		Now the master will mark this volume id as readonly.
-	3. This command asks the target volume server to tail the source volume for updates after the timestamp, for 1 minutes to drain the requests.
-	4. This command asks the source volume server to unmount the source volume
-		Now the master will mark this volume id as writable.
+		The source volume stays writable, and the copied volume is not mounted on the target yet.
+	2. This command asks the target volume server to tail the source volume for updates after the timestamp, until there are no more updates.
+	3. This command asks the source volume server to mark the source volume as readonly.
+	4. This command asks the target volume server to tail the remaining updates, and then mount the new volume.
+		Now the master knows the new volume location.
 	5. This command asks the source volume server to delete the source volume
+		Now the master will mark this volume id as writable.
+
+	If anything fails before the target volume is mounted, the source volume is marked writable again,
+	and the master never sees the incomplete copy.
 
 `
 }
@@ -69,19 +73,40 @@ func (c *commandVolumeMove) Do(args []string, commandEnv *CommandEnv, writer io.
 }
 
 // LiveMoveVolume moves one volume from one source volume server to one target volume server, with idleTimeout to drain the incoming requests.
+// The volume stays writable while it is copied and tailed, and the target volume is only mounted after it has caught up.
 func LiveMoveVolume(grpcDialOption grpc.DialOption, volumeId needle.VolumeId, sourceVolumeServer, targetVolumeServer string, idleTimeout time.Duration) (err error) {
 
 	log.Printf("copying volume %d from %s to %s", volumeId, sourceVolumeServer, targetVolumeServer)
-	lastAppendAtNs, err := copyVolume(grpcDialOption, volumeId, sourceVolumeServer, targetVolumeServer)
+	lastAppendAtNs, err := copyVolumeUnmounted(grpcDialOption, volumeId, sourceVolumeServer, targetVolumeServer)
 	if err != nil {
 		return fmt.Errorf("copy volume %d from %s to %s: %v", volumeId, sourceVolumeServer, targetVolumeServer, err)
 	}
 
 	log.Printf("tailing volume %d from %s to %s", volumeId, sourceVolumeServer, targetVolumeServer)
-	if err = tailVolume(grpcDialOption, volumeId, sourceVolumeServer, targetVolumeServer, lastAppendAtNs, idleTimeout); err != nil {
+	if lastAppendAtNs, err = tailVolumeUnmounted(grpcDialOption, volumeId, sourceVolumeServer, targetVolumeServer, lastAppendAtNs, idleTimeout, false); err != nil {
 		return fmt.Errorf("tail volume %d from %s to %s: %v", volumeId, sourceVolumeServer, targetVolumeServer, err)
 	}
 
+	isReadOnly, err := isVolumeReadOnly(grpcDialOption, volumeId, sourceVolumeServer)
+	if err != nil {
+		return fmt.Errorf("check volume %d on %s: %v", volumeId, sourceVolumeServer, err)
+	}
+	if !isReadOnly {
+		if err = markVolumeWritable(grpcDialOption, volumeId, sourceVolumeServer, false); err != nil {
+			return fmt.Errorf("mark volume %d on %s readonly: %v", volumeId, sourceVolumeServer, err)
+		}
+	}
+
+	log.Printf("catching up volume %d from %s to %s", volumeId, sourceVolumeServer, targetVolumeServer)
+	if _, err = tailVolumeUnmounted(grpcDialOption, volumeId, sourceVolumeServer, targetVolumeServer, lastAppendAtNs, time.Second, true); err != nil {
+		if !isReadOnly {
+			if writableErr := markVolumeWritable(grpcDialOption, volumeId, sourceVolumeServer, true); writableErr != nil {
+				log.Printf("failed to mark volume %d as writable on %s: %v", volumeId, sourceVolumeServer, writableErr)
+			}
+		}
+		return fmt.Errorf("catch up volume %d from %s to %s: %v", volumeId, sourceVolumeServer, targetVolumeServer, err)
+	}
+
 	log.Printf("deleting volume %d from %s", volumeId, sourceVolumeServer)
 	if err = deleteVolume(grpcDialOption, volumeId, sourceVolumeServer); err != nil {
 		return fmt.Errorf("delete volume %d from %s: %v", volumeId, sourceVolumeServer, err)
@@ -91,6 +116,53 @@ func LiveMoveVolume(grpcDialOption grpc.DialOption, volumeId needle.VolumeId, so
 	return nil
 }
 
+// copyVolumeUnmounted copies the volume files without marking the source volume readonly, and leaves the copy unmounted.
+func copyVolumeUnmounted(grpcDialOption grpc.DialOption, volumeId needle.VolumeId, sourceVolumeServer, targetVolumeServer string) (lastAppendAtNs uint64, err error) {
+	err = operation.WithVolumeServerClient(targetVolumeServer, grpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
+		resp, replicateErr := volumeServerClient.VolumeCopy(context.Background(), &volume_server_pb.VolumeCopyRequest{
+			VolumeId:       uint32(volumeId),
+			SourceDataNode: sourceVolumeServer,
+			KeepUnmounted:  true,
+		})
+		if replicateErr == nil {
+			lastAppendAtNs = resp.LastAppendAtNs
+		}
+		return replicateErr
+	})
+	return
+}
+
+// tailVolumeUnmounted catches up the unmounted copy, and returns the timestamp of the last update from the source.
+func tailVolumeUnmounted(grpcDialOption grpc.DialOption, volumeId needle.VolumeId, sourceVolumeServer, targetVolumeServer string, sinceNs uint64, idleTimeout time.Duration, mountAfterTailing bool) (lastAppendAtNs uint64, err error) {
+	err = operation.WithVolumeServerClient(targetVolumeServer, grpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
+		resp, replicateErr := volumeServerClient.VolumeTailReceiver(context.Background(), &volume_server_pb.VolumeTailReceiverRequest{
+			VolumeId:           uint32(volumeId),
+			SinceNs:            sinceNs,
+			IdleTimeoutSeconds: uint32(idleTimeout.Seconds()),
+			SourceVolumeServer: sourceVolumeServer,
+			MountAfterTailing:  mountAfterTailing,
+		})
+		if replicateErr == nil {
+			lastAppendAtNs = resp.LastAppendAtNs
+		}
+		return replicateErr
+	})
+	return
+}
+
+func isVolumeReadOnly(grpcDialOption grpc.DialOption, volumeId needle.VolumeId, volumeServer string) (isReadOnly bool, err error) {
+	err = operation.WithVolumeServerClient(volumeServer, grpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
+		resp, statusErr := volumeServerClient.VolumeStatus(context.Background(), &volume_server_pb.VolumeStatusRequest{
+			VolumeId: uint32(volumeId),
+		})
+		if statusErr == nil {
+			isReadOnly = resp.IsReadOnly
+		}
+		return statusErr
+	})
+	return
+}
+
 func copyVolume(grpcDialOption grpc.DialOption, volumeId needle.VolumeId, sourceVolumeServer, targetVolumeServer string) (lastAppendAtNs uint64, err error) {
 
 	// check to see if the volume is already read-only and if its not then we need
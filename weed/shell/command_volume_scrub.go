@@ -0,0 +1,135 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandVolumeScrub{})
+}
+
+type commandVolumeScrub struct {
+}
+
+func (c *commandVolumeScrub) Name() string {
+	return "volume.scrub"
+}
+
+func (c *commandVolumeScrub) Help() string {
+	return `verify the needle checksums on the volume servers, and repair the corrupted needles
+
+	volume.scrub                                      # show the scrubbing progress and findings of all volume servers
+	volume.scrub -node=<volume server host:port>      # show the scrubbing progress and findings of one volume server
+	volume.scrub -start                               # scrub all volumes on all volume servers
+	volume.scrub -start -volumeId=7 -maxMBPS=50       # scrub all replicas of volume 7
+
+	The volume servers read all needles in the background and check their checksums, also at the
+	times set by "weed volume -scrubCron". The corrupted needles are quarantined, so the reads fail
+	over to the other replicas, and are repaired with a healthy copy from another replica.
+
+`
+}
+
+func (c *commandVolumeScrub) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	scrubCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	node := scrubCommand.String("node", "", "the volume server <host>:<port>, empty for all volume servers")
+	volumeId := scrubCommand.Int("volumeId", 0, "the volume id, 0 for all volumes")
+	maxMBPS := scrubCommand.Int("maxMBPS", 0, "limit the scrubbing reads in mega bytes per second, 0 for the -scrubMaxMBPS of the volume server")
+	start := scrubCommand.Bool("start", false, "start scrubbing")
+	if err = scrubCommand.Parse(args); err != nil {
+		return nil
+	}
+
+	topologyInfo, err := collectTopologyInfo(commandEnv)
+	if err != nil {
+		return err
+	}
+
+	var servers []string
+	eachDataNode(topologyInfo, func(dc string, rack RackId, dn *master_pb.DataNodeInfo) {
+		if *node != "" && dn.Id != *node {
+			return
+		}
+		if *volumeId != 0 && !hasVolume(dn, uint32(*volumeId)) {
+			return
+		}
+		servers = append(servers, dn.Id)
+	})
+	if len(servers) == 0 {
+		return fmt.Errorf("no volume servers found")
+	}
+
+	if *start {
+		req := &volume_server_pb.VolumeScrubRequest{
+			MaxMbps: int32(*maxMBPS),
+		}
+		if *volumeId != 0 {
+			req.VolumeIds = []uint32{uint32(*volumeId)}
+		}
+		for _, server := range servers {
+			err = operation.WithVolumeServerClient(server, commandEnv.option.GrpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+				_, err := client.VolumeScrub(context.Background(), req)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("start scrubbing on %s: %v", server, err)
+			}
+			fmt.Fprintf(writer, "%s starts scrubbing\n", server)
+		}
+		return nil
+	}
+
+	for _, server := range servers {
+		var resp *volume_server_pb.VolumeScrubStatusResponse
+		err = operation.WithVolumeServerClient(server, commandEnv.option.GrpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+			resp, err = client.VolumeScrubStatus(context.Background(), &volume_server_pb.VolumeScrubStatusRequest{})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("scrubbing status of %s: %v", server, err)
+		}
+		printScrubStatus(writer, server, resp)
+	}
+
+	return nil
+}
+
+func hasVolume(dn *master_pb.DataNodeInfo, vid uint32) bool {
+	for _, v := range dn.VolumeInfos {
+		if v.Id == vid {
+			return true
+		}
+	}
+	return false
+}
+
+func printScrubStatus(writer io.Writer, server string, resp *volume_server_pb.VolumeScrubStatusResponse) {
+	switch {
+	case resp.StartedAtNs == 0:
+		fmt.Fprintf(writer, "%s never scrubbed\n", server)
+		return
+	case resp.Running:
+		fmt.Fprintf(writer, "%s scrubbing since %v: %d/%d volumes", server, time.Unix(0, resp.StartedAtNs).Format(time.RFC3339), resp.ScrubbedVolumeCount, resp.VolumeCount)
+	default:
+		fmt.Fprintf(writer, "%s scrubbed at %v in %v: %d volumes", server, time.Unix(0, resp.StartedAtNs).Format(time.RFC3339),
+			time.Duration(resp.FinishedAtNs-resp.StartedAtNs).Round(time.Millisecond), resp.ScrubbedVolumeCount)
+	}
+	fmt.Fprintf(writer, ", %d needles, %d corrupted, %d repaired\n", resp.NeedleCount, resp.CorruptedCount, resp.RepairedCount)
+	for _, finding := range resp.Findings {
+		state := "repaired"
+		if !finding.Repaired {
+			state = "quarantined, not repaired: " + finding.RepairError
+		}
+		fmt.Fprintf(writer, "  %v volume %d needle %x offset %d size %d: %s, %s\n", time.Unix(0, finding.FoundAtNs).Format(time.RFC3339),
+			finding.VolumeId, finding.NeedleId, finding.Offset, finding.Size, finding.Error, state)
+	}
+}
@@ -33,6 +33,10 @@ func (c *commandVolumeServerEvacuate) Help() string {
 	The volumes on the volume servers will be redistributed.
 
 	Usually this is used to prepare to shutdown or upgrade the volume server.
+	With -force, the volume server is put into maintenance first, so the master stops
+	assigning writes to it and growing new volumes on it while its volumes are moved.
+	It stays in maintenance after all data is moved away, and can be stopped safely.
+	Use "cluster.maintenance -node <host:port> -disable" to take it back into service.
 
 	Sometimes a volume can not be moved because there are no
 	good destination to meet the replication requirement. 
@@ -60,11 +64,30 @@ func (c *commandVolumeServerEvacuate) Do(args []string, commandEnv *CommandEnv,
 		return fmt.Errorf("need to specify volume server by -node=<host>:<port>")
 	}
 
-	return volumeServerEvacuate(commandEnv, *volumeServer, *skipNonMoveable, *applyChange, writer)
+	if *applyChange {
+		if err = setMaintenance(commandEnv, &master_pb.MaintenanceTarget{DataNode: *volumeServer}, true); err != nil {
+			return fmt.Errorf("put %s into maintenance: %v", *volumeServer, err)
+		}
+		fmt.Fprintf(writer, "%s is in maintenance, and will not receive new writes\n", *volumeServer)
+	}
+
+	skipped, err := volumeServerEvacuate(commandEnv, *volumeServer, *skipNonMoveable, *applyChange, writer)
+	if err != nil {
+		return err
+	}
+	if !*applyChange {
+		return nil
+	}
+	if skipped > 0 {
+		fmt.Fprintf(writer, "%d volumes are still on %s, it is not safe to stop it yet\n", skipped, *volumeServer)
+	} else {
+		fmt.Fprintf(writer, "all data is moved away from %s, it is safe to stop it now\n", *volumeServer)
+	}
+	return nil
 
 }
 
-func volumeServerEvacuate(commandEnv *CommandEnv, volumeServer string, skipNonMoveable, applyChange bool, writer io.Writer) (err error) {
+func volumeServerEvacuate(commandEnv *CommandEnv, volumeServer string, skipNonMoveable, applyChange bool, writer io.Writer) (skipped int, err error) {
 	// 1. confirm the volume server is part of the cluster
 	// 2. collect all other volume servers, sort by empty slots
 	// 3. move to any other volume server as long as it satisfy the replication requirements
@@ -76,26 +99,33 @@ func volumeServerEvacuate(commandEnv *CommandEnv, volumeServer string, skipNonMo
 		return err
 	})
 	if err != nil {
-		return err
+		return 0, err
+	}
+	// do not move to the other volume servers in maintenance
+	maintenance, err := listMaintenance(commandEnv)
+	if err != nil {
+		return 0, err
 	}
 
-	if err := evacuateNormalVolumes(commandEnv, resp, volumeServer, skipNonMoveable, applyChange, writer); err != nil {
-		return err
+	skippedVolumes, err := evacuateNormalVolumes(commandEnv, resp, maintenance, volumeServer, skipNonMoveable, applyChange, writer)
+	if err != nil {
+		return 0, err
 	}
 
-	if err := evacuateEcVolumes(commandEnv, resp, volumeServer, skipNonMoveable, applyChange, writer); err != nil {
-		return err
+	skippedEcVolumes, err := evacuateEcVolumes(commandEnv, resp, maintenance, volumeServer, skipNonMoveable, applyChange, writer)
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return skippedVolumes + skippedEcVolumes, nil
 }
 
-func evacuateNormalVolumes(commandEnv *CommandEnv, resp *master_pb.VolumeListResponse, volumeServer string, skipNonMoveable, applyChange bool, writer io.Writer) error {
+func evacuateNormalVolumes(commandEnv *CommandEnv, resp *master_pb.VolumeListResponse, maintenance []*master_pb.MaintenanceTarget, volumeServer string, skipNonMoveable, applyChange bool, writer io.Writer) (skipped int, err error) {
 	// find this volume server
 	volumeServers := collectVolumeServersByDc(resp.TopologyInfo, "")
-	thisNode, otherNodes := nodesOtherThan(volumeServers, volumeServer)
+	thisNode, otherNodes := nodesOtherThan(volumeServers, volumeServer, maintenance)
 	if thisNode == nil {
-		return fmt.Errorf("%s is not found in this cluster", volumeServer)
+		return 0, fmt.Errorf("%s is not found in this cluster", volumeServer)
 	}
 
 	// move away normal volumes
@@ -103,43 +133,45 @@ func evacuateNormalVolumes(commandEnv *CommandEnv, resp *master_pb.VolumeListRes
 	for _, vol := range thisNode.info.VolumeInfos {
 		hasMoved, err := moveAwayOneNormalVolume(commandEnv, volumeReplicas, vol, thisNode, otherNodes, applyChange)
 		if err != nil {
-			return fmt.Errorf("move away volume %d from %s: %v", vol.Id, volumeServer, err)
+			return 0, fmt.Errorf("move away volume %d from %s: %v", vol.Id, volumeServer, err)
 		}
 		if !hasMoved {
 			if skipNonMoveable {
 				replicaPlacement, _ := super_block.NewReplicaPlacementFromByte(byte(vol.ReplicaPlacement))
 				fmt.Fprintf(writer, "skipping non moveable volume %d replication:%s\n", vol.Id, replicaPlacement.String())
+				skipped++
 			} else {
-				return fmt.Errorf("failed to move volume %d from %s", vol.Id, volumeServer)
+				return 0, fmt.Errorf("failed to move volume %d from %s", vol.Id, volumeServer)
 			}
 		}
 	}
-	return nil
+	return skipped, nil
 }
 
-func evacuateEcVolumes(commandEnv *CommandEnv, resp *master_pb.VolumeListResponse, volumeServer string, skipNonMoveable, applyChange bool, writer io.Writer) error {
+func evacuateEcVolumes(commandEnv *CommandEnv, resp *master_pb.VolumeListResponse, maintenance []*master_pb.MaintenanceTarget, volumeServer string, skipNonMoveable, applyChange bool, writer io.Writer) (skipped int, err error) {
 	// find this ec volume server
 	ecNodes, _ := collectEcVolumeServersByDc(resp.TopologyInfo, "")
-	thisNode, otherNodes := ecNodesOtherThan(ecNodes, volumeServer)
+	thisNode, otherNodes := ecNodesOtherThan(ecNodes, volumeServer, maintenance)
 	if thisNode == nil {
-		return fmt.Errorf("%s is not found in this cluster\n", volumeServer)
+		return 0, fmt.Errorf("%s is not found in this cluster\n", volumeServer)
 	}
 
 	// move away ec volumes
 	for _, ecShardInfo := range thisNode.info.EcShardInfos {
 		hasMoved, err := moveAwayOneEcVolume(commandEnv, ecShardInfo, thisNode, otherNodes, applyChange)
 		if err != nil {
-			return fmt.Errorf("move away volume %d from %s: %v", ecShardInfo.Id, volumeServer, err)
+			return 0, fmt.Errorf("move away volume %d from %s: %v", ecShardInfo.Id, volumeServer, err)
 		}
 		if !hasMoved {
 			if skipNonMoveable {
 				fmt.Fprintf(writer, "failed to move away ec volume %d from %s\n", ecShardInfo.Id, volumeServer)
+				skipped++
 			} else {
-				return fmt.Errorf("failed to move away ec volume %d from %s", ecShardInfo.Id, volumeServer)
+				return 0, fmt.Errorf("failed to move away ec volume %d from %s", ecShardInfo.Id, volumeServer)
 			}
 		}
 	}
-	return nil
+	return skipped, nil
 }
 
 func moveAwayOneEcVolume(commandEnv *CommandEnv, ecShardInfo *master_pb.VolumeEcShardInformationMessage, thisNode *EcNode, otherNodes []*EcNode, applyChange bool) (hasMoved bool, err error) {
@@ -191,23 +223,29 @@ func moveAwayOneNormalVolume(commandEnv *CommandEnv, volumeReplicas map[uint32][
 	return
 }
 
-func nodesOtherThan(volumeServers []*Node, thisServer string) (thisNode *Node, otherNodes []*Node) {
+func nodesOtherThan(volumeServers []*Node, thisServer string, maintenance []*master_pb.MaintenanceTarget) (thisNode *Node, otherNodes []*Node) {
 	for _, node := range volumeServers {
 		if node.info.Id == thisServer {
 			thisNode = node
 			continue
 		}
+		if isInMaintenance(maintenance, node.dc, node.rack, node.info.Id) {
+			continue
+		}
 		otherNodes = append(otherNodes, node)
 	}
 	return
 }
 
-func ecNodesOtherThan(volumeServers []*EcNode, thisServer string) (thisNode *EcNode, otherNodes []*EcNode) {
+func ecNodesOtherThan(volumeServers []*EcNode, thisServer string, maintenance []*master_pb.MaintenanceTarget) (thisNode *EcNode, otherNodes []*EcNode) {
 	for _, node := range volumeServers {
 		if node.info.Id == thisServer {
 			thisNode = node
 			continue
 		}
+		if isInMaintenance(maintenance, node.dc, string(node.rack), node.info.Id) {
+			continue
+		}
 		otherNodes = append(otherNodes, node)
 	}
 	return
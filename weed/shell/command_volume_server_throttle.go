@@ -0,0 +1,95 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandVolumeServerThrottle{})
+}
+
+type commandVolumeServerThrottle struct {
+}
+
+func (c *commandVolumeServerThrottle) Name() string {
+	return "volumeServer.throttle"
+}
+
+func (c *commandVolumeServerThrottle) Help() string {
+	return `show or change the bandwidth limits of the background traffic on the volume servers
+
+	volumeServer.throttle                                          # show the limits of all volume servers
+	volumeServer.throttle -replicationMBps=50 -ecMBps=50           # limit copying volumes and ec shards on all volume servers
+	volumeServer.throttle -node=<host:port> -compactionMBps=0      # remove the compaction limit of one volume server
+
+	The compaction limit is shared by all vacuuming on the volume server. The replication limit is shared by
+	copying volumes to and from other volume servers, e.g. by volume.fix.replication, volume.balance, volume.move,
+	and "weed backup". The ec limit is shared by copying the ec shards, e.g. by ec.encode, ec.rebuild and ec.balance.
+
+	The changes last until the volume servers restart. Set the -compactionMBps, -replicationMBps and -ecMBps options
+	of "weed volume" to keep them.
+
+`
+}
+
+func (c *commandVolumeServerThrottle) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	throttleCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	node := throttleCommand.String("node", "", "the volume server <host>:<port>, empty for all volume servers")
+	compactionMBps := throttleCommand.Int("compactionMBps", -1, "limit the compaction in mega bytes per second, 0 for no limit")
+	replicationMBps := throttleCommand.Int("replicationMBps", -1, "limit copying volumes in mega bytes per second, 0 for no limit")
+	ecMBps := throttleCommand.Int("ecMBps", -1, "limit copying ec shards in mega bytes per second, 0 for no limit")
+	if err = throttleCommand.Parse(args); err != nil {
+		return nil
+	}
+
+	topologyInfo, err := collectTopologyInfo(commandEnv)
+	if err != nil {
+		return err
+	}
+
+	var servers []string
+	eachDataNode(topologyInfo, func(dc string, rack RackId, dn *master_pb.DataNodeInfo) {
+		if *node != "" && dn.Id != *node {
+			return
+		}
+		servers = append(servers, dn.Id)
+	})
+	if len(servers) == 0 {
+		return fmt.Errorf("no volume servers found")
+	}
+
+	req := &volume_server_pb.VolumeServerThrottleRequest{
+		CompactionMbps:  int32(*compactionMBps),
+		ReplicationMbps: int32(*replicationMBps),
+		EcMbps:          int32(*ecMBps),
+	}
+	for _, server := range servers {
+		var resp *volume_server_pb.VolumeServerThrottleResponse
+		err = operation.WithVolumeServerClient(server, commandEnv.option.GrpcDialOption, func(client volume_server_pb.VolumeServerClient) error {
+			resp, err = client.VolumeServerThrottle(context.Background(), req)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("throttle %s: %v", server, err)
+		}
+		fmt.Fprintf(writer, "%s compaction:%s replication:%s ec:%s\n", server,
+			mbpsString(resp.CompactionMbps), mbpsString(resp.ReplicationMbps), mbpsString(resp.EcMbps))
+	}
+
+	return nil
+}
+
+func mbpsString(mbps int32) string {
+	if mbps <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%dMB/s", mbps)
+}
@@ -0,0 +1,113 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+)
+
+func init() {
+	Commands = append(Commands, &commandVolumeTierApply{})
+}
+
+type commandVolumeTierApply struct {
+}
+
+func (c *commandVolumeTierApply) Name() string {
+	return "volume.tier.apply"
+}
+
+func (c *commandVolumeTierApply) Help() string {
+	return `upload the sealed volumes to the remote tier by the tiering policies of the collections
+
+	volume.tier.apply            # list the volumes to upload
+	volume.tier.apply -force     # upload them
+
+	The tiering policies are set by "collection.configure -tier.dest=s3.default ...".
+	A volume is uploaded to the tier.dest of its collection after it reaches tier.fullPercent
+	of the collection volume size limit, and has no writes for tier.quietFor.
+
+	Add "volume.tier.apply -force" to the scripts in [master.maintenance] of master.toml
+	to apply the policies periodically.
+
+`
+}
+
+func (c *commandVolumeTierApply) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	applyCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	applyChanges := applyCommand.Bool("force", false, "upload the volumes")
+	if err = applyCommand.Parse(args); err != nil {
+		return nil
+	}
+
+	if *applyChanges {
+		if err = commandEnv.confirmIsLocked(); err != nil {
+			return
+		}
+	}
+
+	var settingsResp *master_pb.ListCollectionSettingsResponse
+	var volumeListResp *master_pb.VolumeListResponse
+	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		if settingsResp, err = client.ListCollectionSettings(context.Background(), &master_pb.ListCollectionSettingsRequest{}); err != nil {
+			return err
+		}
+		volumeListResp, err = client.VolumeList(context.Background(), &master_pb.VolumeListRequest{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, settings := range settingsResp.Settings {
+		if settings.TierDest == "" {
+			continue
+		}
+		volumeSizeLimitMb := settings.VolumeSizeLimitMb
+		if volumeSizeLimitMb == 0 {
+			volumeSizeLimitMb = volumeListResp.VolumeSizeLimitMb
+		}
+		vids := collectVolumeIdsForTierUpload(volumeListResp.TopologyInfo, settings, volumeSizeLimitMb)
+		for _, vid := range vids {
+			fmt.Fprintf(writer, "upload volume %d of collection %s to %s\n", vid, settings.Collection, settings.TierDest)
+			if !*applyChanges {
+				continue
+			}
+			if err = doVolumeTierUpload(commandEnv, writer, settings.Collection, vid, settings.TierDest, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func collectVolumeIdsForTierUpload(topologyInfo *master_pb.TopologyInfo, settings *master_pb.CollectionSettings, volumeSizeLimitMb uint64) (vids []needle.VolumeId) {
+	nowUnixSeconds := time.Now().Unix()
+	vidMap := make(map[uint32]bool)
+	eachDataNode(topologyInfo, func(dc string, rack RackId, dn *master_pb.DataNodeInfo) {
+		for _, v := range dn.VolumeInfos {
+			if v.Collection != settings.Collection || v.RemoteStorageName != "" {
+				continue
+			}
+			if v.ModifiedAtSecond+settings.TierQuietForSeconds < nowUnixSeconds &&
+				float64(v.Size) > settings.TierFullPercent/100*float64(volumeSizeLimitMb)*1024*1024 {
+				vidMap[v.Id] = true
+			}
+		}
+	})
+	for vid := range vidMap {
+		vids = append(vids, needle.VolumeId(vid))
+	}
+	sort.Slice(vids, func(i, j int) bool {
+		return vids[i] < vids[j]
+	})
+	return
+}
@@ -0,0 +1,101 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+)
+
+func init() {
+	Commands = append(Commands, &commandVolumeVacuumConfigure{})
+}
+
+type commandVolumeVacuumConfigure struct {
+}
+
+func (c *commandVolumeVacuumConfigure) Name() string {
+	return "volume.vacuum.configure"
+}
+
+func (c *commandVolumeVacuumConfigure) Help() string {
+	return `limit the automatic vacuum to daily time windows, and show the garbage thresholds
+
+	volume.vacuum.configure                                      # show the vacuum windows and garbage thresholds
+	volume.vacuum.configure -windows=01:00-05:00 -apply          # vacuum only between 1am and 5am
+	volume.vacuum.configure -windows=22:00-02:00,12:00-13:00 -apply
+	volume.vacuum.configure -apply                               # vacuum at any time again
+
+	The windows are in the local time of the master, and a window wraps past midnight if it ends before
+	it starts. The master checks the volumes every 15 minutes within the windows, and stops vacuuming
+	when the window closes. The vacuum started by "curl http://<master>:9333/vol/vacuum" runs at any time.
+
+	The garbage threshold of a collection is set by "collection.configure -garbageThreshold", and
+	defaults to "weed master -garbageThreshold". The settings are kept across master restarts and
+	leader changes.
+
+`
+}
+
+func (c *commandVolumeVacuumConfigure) Do(args []string, commandEnv *CommandEnv, writer io.Writer) (err error) {
+
+	configureCommand := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	windows := configureCommand.String("windows", "", "comma separated daily time windows, e.g. 01:00-05:00, empty to vacuum at any time")
+	apply := configureCommand.Bool("apply", false, "apply the vacuum windows")
+	if err = configureCommand.Parse(args); err != nil {
+		return nil
+	}
+
+	if *apply {
+		if err = commandEnv.confirmIsLocked(); err != nil {
+			return
+		}
+		req := &master_pb.SetVacuumWindowsRequest{}
+		for _, w := range strings.Split(*windows, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				req.Windows = append(req.Windows, w)
+			}
+		}
+		err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+			_, err := client.SetVacuumWindows(context.Background(), req)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var windowsResp *master_pb.ListVacuumWindowsResponse
+	var configuration *master_pb.GetMasterConfigurationResponse
+	var settingsResp *master_pb.ListCollectionSettingsResponse
+	err = commandEnv.MasterClient.WithClient(func(client master_pb.SeaweedClient) error {
+		if windowsResp, err = client.ListVacuumWindows(context.Background(), &master_pb.ListVacuumWindowsRequest{}); err != nil {
+			return err
+		}
+		if configuration, err = client.GetMasterConfiguration(context.Background(), &master_pb.GetMasterConfigurationRequest{}); err != nil {
+			return err
+		}
+		settingsResp, err = client.ListCollectionSettings(context.Background(), &master_pb.ListCollectionSettingsRequest{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(windowsResp.Windows) == 0 {
+		fmt.Fprintf(writer, "vacuum windows: any time\n")
+	} else {
+		fmt.Fprintf(writer, "vacuum windows: %s\n", strings.Join(windowsResp.Windows, ","))
+	}
+	fmt.Fprintf(writer, "garbage threshold: %v\n", configuration.GarbageThreshold)
+	for _, settings := range settingsResp.Settings {
+		if settings.GarbageThreshold > 0 {
+			fmt.Fprintf(writer, "  collection:\"%s\" garbageThreshold:%v\n", settings.Collection, settings.GarbageThreshold)
+		}
+	}
+
+	return nil
+}
@@ -20,9 +20,10 @@ type ShellOptions struct {
 	Masters        *string
 	GrpcDialOption grpc.DialOption
 	// shell transient context
-	FilerHost string
-	FilerPort int64
-	Directory string
+	FilerHost        string
+	FilerPort        int64
+	FilerGrpcAddress string
+	Directory        string
 }
 
 type CommandEnv struct {
@@ -97,8 +98,7 @@ var _ = filer_pb.FilerClient(&CommandEnv{})
 
 func (ce *CommandEnv) WithFilerClient(fn func(filer_pb.SeaweedFilerClient) error) error {
 
-	filerGrpcAddress := fmt.Sprintf("%s:%d", ce.option.FilerHost, ce.option.FilerPort+10000)
-	return pb.WithGrpcFilerClient(filerGrpcAddress, ce.option.GrpcDialOption, fn)
+	return pb.WithGrpcFilerClient(ce.option.FilerGrpcAddress, ce.option.GrpcDialOption, fn)
 
 }
 
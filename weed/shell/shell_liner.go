@@ -53,6 +53,27 @@ func RunShell(options ShellOptions) {
 	}
 }
 
+// RunCommand runs one shell command without the interactive shell, holding the admin lock while it runs.
+func RunCommand(options ShellOptions, args []string, writer io.Writer) error {
+
+	for _, c := range Commands {
+		if c.Name() != args[0] {
+			continue
+		}
+
+		commandEnv := NewCommandEnv(options)
+		go commandEnv.MasterClient.KeepConnectedToMaster()
+		commandEnv.MasterClient.WaitUntilConnected()
+
+		commandEnv.locker.RequestLock()
+		defer commandEnv.locker.ReleaseLock()
+
+		return c.Do(args[1:], commandEnv, writer)
+	}
+
+	return fmt.Errorf("unknown command: %s", args[0])
+}
+
 func processEachCmd(reg *regexp.Regexp, cmd string, commandEnv *CommandEnv) bool {
 	cmds := reg.FindAllString(cmd, -1)
 	if len(cmds) == 0 {
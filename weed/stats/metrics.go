@@ -26,6 +26,14 @@ var (
 			Help:      "Counter of filer requests.",
 		}, []string{"type"})
 
+	FilerAutoDeletedFilesCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "filer",
+			Name:      "auto_deleted_files_total",
+			Help:      "Counter of files deleted after their ttl or by bucket lifecycle rules.",
+		})
+
 	FilerRequestHistogram = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: "SeaweedFS",
@@ -58,7 +66,7 @@ var (
 			Subsystem: "volumeServer",
 			Name:      "request_total",
 			Help:      "Counter of volume server requests.",
-		}, []string{"type"})
+		}, []string{"collection", "type"})
 
 	VolumeServerRequestHistogram = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -67,7 +75,7 @@ var (
 			Name:      "request_seconds",
 			Help:      "Bucketed histogram of volume server request processing time.",
 			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 24),
-		}, []string{"type"})
+		}, []string{"collection", "type"})
 
 	VolumeServerVolumeCounter = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -109,6 +117,63 @@ var (
 			Help:      "Resource usage",
 		}, []string{"name", "type"})
 
+	VolumeServerPendingNeedlesGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "pending_needles_total",
+			Help:      "Number of needles written by a two phase commit and not confirmed yet.",
+		})
+
+	VolumeServerReplicationFailureCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "replication_failures",
+			Help:      "Counter of acknowledged writes that failed later on a replica.",
+		})
+
+	VolumeServerBitrotErrorsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "volumeServer",
+			Name:      "bitrot_errors_total",
+			Help:      "Counter of needles found corrupted by scrubbing.",
+		})
+
+	MasterVolumeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "volumes",
+			Help:      "Number of volume replicas, read only volume replicas, or ec shards in the cluster.",
+		}, []string{"collection", "type"})
+
+	MasterVolumeSizeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "volume_size_bytes",
+			Help:      "Total size of the volume replicas in the cluster.",
+		}, []string{"collection"})
+
+	MasterRequestCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "request_total",
+			Help:      "Counter of master requests.",
+		}, []string{"type"})
+
+	MasterRequestHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "SeaweedFS",
+			Subsystem: "master",
+			Name:      "request_seconds",
+			Help:      "Bucketed histogram of master request processing time.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 24),
+		}, []string{"type"})
+
 	S3RequestCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "SeaweedFS",
@@ -130,6 +195,7 @@ func init() {
 
 	Gather.MustRegister(FilerRequestCounter)
 	Gather.MustRegister(FilerRequestHistogram)
+	Gather.MustRegister(FilerAutoDeletedFilesCounter)
 	Gather.MustRegister(FilerStoreCounter)
 	Gather.MustRegister(FilerStoreHistogram)
 	Gather.MustRegister(prometheus.NewGoCollector())
@@ -141,12 +207,22 @@ func init() {
 	Gather.MustRegister(VolumeServerReadOnlyVolumeGauge)
 	Gather.MustRegister(VolumeServerDiskSizeGauge)
 	Gather.MustRegister(VolumeServerResourceGauge)
+	Gather.MustRegister(VolumeServerPendingNeedlesGauge)
+	Gather.MustRegister(VolumeServerBitrotErrorsCounter)
+	Gather.MustRegister(VolumeServerReplicationFailureCounter)
+
+	Gather.MustRegister(MasterVolumeGauge)
+	Gather.MustRegister(MasterVolumeSizeGauge)
+	Gather.MustRegister(MasterRequestCounter)
+	Gather.MustRegister(MasterRequestHistogram)
 
 	Gather.MustRegister(S3RequestCounter)
 	Gather.MustRegister(S3RequestHistogram)
 }
 
-func LoopPushingMetric(name, instance, addr string, intervalSeconds int) {
+// LoopPushingMetric pushes to the gateway with the job label set to jobLabel, or the name if empty,
+// and the instance label prefixed with instanceLabel if not empty.
+func LoopPushingMetric(name, instance, addr string, intervalSeconds int, jobLabel, instanceLabel string) {
 
 	if addr == "" || intervalSeconds == 0 {
 		return
@@ -154,7 +230,15 @@ func LoopPushingMetric(name, instance, addr string, intervalSeconds int) {
 
 	glog.V(0).Infof("%s server sends metrics to %s every %d seconds", name, addr, intervalSeconds)
 
-	pusher := push.New(addr, name).Gatherer(Gather).Grouping("instance", instance)
+	job := name
+	if jobLabel != "" {
+		job = jobLabel
+	}
+	if instanceLabel != "" {
+		instance = instanceLabel + "-" + instance
+	}
+
+	pusher := push.New(addr, job).Gatherer(Gather).Grouping("instance", instance)
 
 	for {
 		err := pusher.Push()
@@ -169,11 +253,26 @@ func LoopPushingMetric(name, instance, addr string, intervalSeconds int) {
 	}
 }
 
+// MetricsHandler serves all metrics registered in Gather for Prometheus to scrape.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(Gather, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// RegisterCollector registers metrics collected at scraping time, once per process
+// even if several servers of the same type run in one process.
+func RegisterCollector(c prometheus.Collector) {
+	if err := Gather.Register(c); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			glog.Errorf("register metrics: %v", err)
+		}
+	}
+}
+
 func StartMetricsServer(port int) {
 	if port == 0 {
 		return
 	}
-	http.Handle("/metrics", promhttp.HandlerFor(Gather, promhttp.HandlerOpts{}))
+	http.Handle("/metrics", MetricsHandler())
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
 }
 
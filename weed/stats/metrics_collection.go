@@ -0,0 +1,33 @@
+package stats
+
+import "sync"
+
+// The collection label is empty unless LabelByCollection is set, so by default
+// the metrics are aggregated over all collections. Past MaxCollectionLabels
+// distinct collections, the other collections share the OtherCollectionsLabel,
+// to limit the number of time series.
+var (
+	LabelByCollection   bool
+	MaxCollectionLabels = 100
+
+	collectionLabels     = make(map[string]struct{})
+	collectionLabelsLock sync.Mutex
+)
+
+const OtherCollectionsLabel = "_other"
+
+func CollectionLabel(collection string) string {
+	if !LabelByCollection {
+		return ""
+	}
+	collectionLabelsLock.Lock()
+	defer collectionLabelsLock.Unlock()
+	if _, found := collectionLabels[collection]; found {
+		return collection
+	}
+	if len(collectionLabels) >= MaxCollectionLabels {
+		return OtherCollectionsLabel
+	}
+	collectionLabels[collection] = struct{}{}
+	return collection
+}
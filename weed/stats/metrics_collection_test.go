@@ -0,0 +1,26 @@
+package stats
+
+import "testing"
+
+func TestCollectionLabel(t *testing.T) {
+	defer func(enabled bool, limit int) {
+		LabelByCollection, MaxCollectionLabels = enabled, limit
+	}(LabelByCollection, MaxCollectionLabels)
+
+	LabelByCollection = false
+	if label := CollectionLabel("photos"); label != "" {
+		t.Errorf("disabled: label %q", label)
+	}
+
+	LabelByCollection, MaxCollectionLabels = true, 2
+	for _, c := range []struct{ collection, label string }{
+		{"photos", "photos"},
+		{"", ""},
+		{"logs", OtherCollectionsLabel},
+		{"photos", "photos"},
+	} {
+		if label := CollectionLabel(c.collection); label != c.label {
+			t.Errorf("collection %q: label %q, expected %q", c.collection, label, c.label)
+		}
+	}
+}
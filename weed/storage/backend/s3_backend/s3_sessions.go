@@ -29,7 +29,9 @@ func createSession(awsAccessKeyId, awsSecretAccessKey, region, endpoint string)
 	sessionsLock.Lock()
 	defer sessionsLock.Unlock()
 
-	if t, found := s3Sessions[region]; found {
+	// backends in the same region can use different endpoints, e.g. AWS S3 and Google Cloud Storage
+	sessionKey := region + "|" + endpoint + "|" + awsAccessKeyId
+	if t, found := s3Sessions[sessionKey]; found {
 		return t, nil
 	}
 
@@ -48,7 +50,7 @@ func createSession(awsAccessKeyId, awsSecretAccessKey, region, endpoint string)
 
 	t := s3.New(sess)
 
-	s3Sessions[region] = t
+	s3Sessions[sessionKey] = t
 
 	return t, nil
 
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chrislusf/seaweedfs/weed/alert"
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/stats"
 	"github.com/chrislusf/seaweedfs/weed/storage/erasure_coding"
@@ -16,11 +17,16 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
+// checks often, since the disks can fill up quickly under heavy writes
+const diskSpaceCheckInterval = 10 * time.Second
+
 type DiskLocation struct {
 	Directory              string
 	MaxVolumeCount         int
 	OriginalMaxVolumeCount int
-	MinFreeSpacePercent    float32
+	MinFreeSpacePercent    float32 // the volumes are read only below this free space
+	ResumeFreeSpacePercent float32 // the volumes are writable again above this free space
+	DiskType               string  // e.g. hdd or ssd
 	volumes                map[needle.VolumeId]*Volume
 	volumesLock            sync.RWMutex
 
@@ -31,8 +37,12 @@ type DiskLocation struct {
 	isDiskSpaceLow bool
 }
 
-func NewDiskLocation(dir string, maxVolumeCount int, minFreeSpacePercent float32) *DiskLocation {
-	location := &DiskLocation{Directory: dir, MaxVolumeCount: maxVolumeCount, OriginalMaxVolumeCount: maxVolumeCount, MinFreeSpacePercent: minFreeSpacePercent}
+func NewDiskLocation(dir string, maxVolumeCount int, minFreeSpacePercent, resumeFreeSpacePercent float32, diskType string) *DiskLocation {
+	if resumeFreeSpacePercent < minFreeSpacePercent {
+		resumeFreeSpacePercent = minFreeSpacePercent
+	}
+	location := &DiskLocation{Directory: dir, MaxVolumeCount: maxVolumeCount, OriginalMaxVolumeCount: maxVolumeCount,
+		MinFreeSpacePercent: minFreeSpacePercent, ResumeFreeSpacePercent: resumeFreeSpacePercent, DiskType: ToDiskType(diskType)}
 	location.volumes = make(map[needle.VolumeId]*Volume)
 	location.ecVolumes = make(map[needle.VolumeId]*erasure_coding.EcVolume)
 	go location.CheckDiskSpace()
@@ -311,6 +321,8 @@ func (l *DiskLocation) UnUsedSpace(volumeSizeLimit uint64) (unUsedSpace uint64)
 	return
 }
 
+// CheckDiskSpace marks the volumes read only when the free space drops below MinFreeSpacePercent,
+// and writable again only after it is back above ResumeFreeSpacePercent, to avoid flapping.
 func (l *DiskLocation) CheckDiskSpace() {
 	for {
 		if dir, e := filepath.Abs(l.Directory); e == nil {
@@ -318,16 +330,26 @@ func (l *DiskLocation) CheckDiskSpace() {
 			stats.VolumeServerResourceGauge.WithLabelValues(l.Directory, "all").Set(float64(s.All))
 			stats.VolumeServerResourceGauge.WithLabelValues(l.Directory, "used").Set(float64(s.Used))
 			stats.VolumeServerResourceGauge.WithLabelValues(l.Directory, "free").Set(float64(s.Free))
-			if (s.PercentFree < l.MinFreeSpacePercent) != l.isDiskSpaceLow {
-				l.isDiskSpaceLow = !l.isDiskSpaceLow
-			}
-			if l.isDiskSpaceLow {
-				glog.V(0).Infof("dir %s freePercent %.2f%% < min %.2f%%, isLowDiskSpace: %v", dir, s.PercentFree, l.MinFreeSpacePercent, l.isDiskSpaceLow)
-			} else {
-				glog.V(4).Infof("dir %s freePercent %.2f%% < min %.2f%%, isLowDiskSpace: %v", dir, s.PercentFree, l.MinFreeSpacePercent, l.isDiskSpaceLow)
+			switch {
+			case !l.isDiskSpaceLow && s.PercentFree < l.MinFreeSpacePercent:
+				l.isDiskSpaceLow = true
+				glog.Warningf("dir %s freePercent %.2f%% < min %.2f%%, volumes are read only", dir, s.PercentFree, l.MinFreeSpacePercent)
+				alert.Raise(alert.VolumeDiskFull, fmt.Sprintf("volume dir %s is low on disk space, volumes are read only", dir), map[string]string{
+					"dir":         dir,
+					"freePercent": fmt.Sprintf("%.2f", s.PercentFree),
+				})
+			case l.isDiskSpaceLow && s.PercentFree >= l.ResumeFreeSpacePercent:
+				l.isDiskSpaceLow = false
+				glog.V(0).Infof("dir %s freePercent %.2f%% >= resume %.2f%%, volumes are writable again", dir, s.PercentFree, l.ResumeFreeSpacePercent)
+			default:
+				glog.V(4).Infof("dir %s freePercent %.2f%%, isLowDiskSpace: %v", dir, s.PercentFree, l.isDiskSpaceLow)
 			}
 		}
-		time.Sleep(time.Minute)
+		time.Sleep(diskSpaceCheckInterval)
 	}
 
 }
+
+func (l *DiskLocation) IsDiskSpaceLow() bool {
+	return l.isDiskSpaceLow
+}
@@ -0,0 +1,23 @@
+package storage
+
+import "strings"
+
+// HardDriveType is the disk type of the folders without a "weed volume -disk" type.
+const HardDriveType = "hdd"
+
+// ToDiskType normalizes the disk type, e.g. "SSD" to "ssd", and empty to "hdd".
+func ToDiskType(diskType string) string {
+	diskType = strings.ToLower(strings.TrimSpace(diskType))
+	if diskType == "" {
+		return HardDriveType
+	}
+	return diskType
+}
+
+// DiskType is the disk type of the folder the volume is in.
+func (v *Volume) DiskType() string {
+	if v.location == nil {
+		return HardDriveType
+	}
+	return v.location.DiskType
+}
@@ -41,7 +41,7 @@ func NewEcVolumeShard(dirname string, collection string, id needle.VolumeId, sha
 	}
 	v.ecdFileSize = ecdFi.Size()
 
-	stats.VolumeServerVolumeCounter.WithLabelValues(v.Collection, "ec_shards").Inc()
+	stats.VolumeServerVolumeCounter.WithLabelValues(stats.CollectionLabel(v.Collection), "ec_shards").Inc()
 
 	return
 }
@@ -85,7 +85,7 @@ func (shard *EcVolumeShard) Close() {
 
 func (shard *EcVolumeShard) Destroy() {
 	os.Remove(shard.FileName() + ToExt(int(shard.ShardId)))
-	stats.VolumeServerVolumeCounter.WithLabelValues(shard.Collection, "ec_shards").Dec()
+	stats.VolumeServerVolumeCounter.WithLabelValues(stats.CollectionLabel(shard.Collection), "ec_shards").Dec()
 }
 
 func (shard *EcVolumeShard) ReadAt(buf []byte, offset int64) (int, error) {
@@ -33,6 +33,7 @@ type EcVolume struct {
 	ShardLocationsRefreshTime time.Time
 	ShardLocationsLock        sync.RWMutex
 	Version                   needle.Version
+	EncryptionKeyId           string // the needle data is encrypted with this key, empty if not encrypted
 	ecjFile                   *os.File
 	ecjFileAccessLock         sync.Mutex
 }
@@ -62,6 +63,7 @@ func NewEcVolume(dir string, collection string, vid needle.VolumeId) (ev *EcVolu
 	ev.Version = needle.Version3
 	if volumeInfo, found, _ := pb.MaybeLoadVolumeInfo(baseFileName + ".vif"); found {
 		ev.Version = needle.Version(volumeInfo.Version)
+		ev.EncryptionKeyId = volumeInfo.EncryptionKeyId
 	} else {
 		pb.SaveVolumeInfo(baseFileName+".vif", &volume_server_pb.VolumeInfo{Version: uint32(ev.Version)})
 	}
@@ -48,9 +48,9 @@ func (n *Needle) String() (str string) {
 	return
 }
 
-func CreateNeedleFromRequest(r *http.Request, fixJpgOrientation bool, sizeLimit int64) (n *Needle, originalSize int, contentMd5 string, e error) {
+func CreateNeedleFromRequest(r *http.Request, fixJpgOrientation bool, sizeLimit int64, autoCompressThreshold int64, compression string) (n *Needle, originalSize int, contentMd5 string, e error) {
 	n = new(Needle)
-	pu, e := ParseUpload(r, sizeLimit)
+	pu, e := ParseUpload(r, sizeLimit, autoCompressThreshold, compression)
 	if e != nil {
 		return
 	}
@@ -81,7 +81,9 @@ func CreateNeedleFromRequest(r *http.Request, fixJpgOrientation bool, sizeLimit
 			n.SetHasPairs()
 		}
 	}
-	if pu.IsGzipped {
+	if pu.IsZstd {
+		n.SetIsZstd()
+	} else if pu.IsGzipped {
 		// println(r.URL.Path, "is set to compressed", pu.FileName, pu.IsGzipped, "dataSize", pu.OriginalDataSize)
 		n.SetIsCompressed()
 	}
@@ -23,7 +23,7 @@ type ParsedUpload struct {
 	MimeType         string
 	PairMap          map[string]string
 	IsGzipped        bool
-	// IsZstd           bool
+	IsZstd           bool
 	OriginalDataSize int
 	ModifiedTime     uint64
 	Ttl              *TTL
@@ -32,7 +32,10 @@ type ParsedUpload struct {
 	ContentMd5       string
 }
 
-func ParseUpload(r *http.Request, sizeLimit int64) (pu *ParsedUpload, e error) {
+// ParseUpload reads the uploaded file. Data not compressed by the client is compressed
+// if it is larger than autoCompressThreshold and likely to be compressible, with the codec
+// of the "compression" query parameter, or else the given compression, gzip by default.
+func ParseUpload(r *http.Request, sizeLimit int64, autoCompressThreshold int64, compression string) (pu *ParsedUpload, e error) {
 	pu = &ParsedUpload{}
 	pu.PairMap = make(map[string]string)
 	for k, v := range r.Header {
@@ -56,14 +59,21 @@ func ParseUpload(r *http.Request, sizeLimit int64) (pu *ParsedUpload, e error) {
 	pu.OriginalDataSize = len(pu.Data)
 	pu.UncompressedData = pu.Data
 	// println("received data", len(pu.Data), "isGzipped", pu.IsGzipped, "mime", pu.MimeType, "name", pu.FileName)
-	if pu.IsGzipped {
+	if codec := r.URL.Query().Get("compression"); codec != "" {
+		if !util.IsValidCompression(codec) {
+			e = fmt.Errorf("compression %s: %v", codec, util.UnsupportedCompression)
+			return
+		}
+		compression = codec
+	}
+	if pu.IsCompressed() {
 		if unzipped, e := util.DecompressData(pu.Data); e == nil {
 			pu.OriginalDataSize = len(unzipped)
 			pu.UncompressedData = unzipped
 			// println("ungzipped data size", len(unzipped))
 		}
-	} else {
-		ext := filepath.Base(pu.FileName)
+	} else if int64(len(pu.Data)) > autoCompressThreshold && compression != util.NoCompression {
+		ext := filepath.Ext(pu.FileName)
 		mimeType := pu.MimeType
 		if mimeType == "" {
 			mimeType = http.DetectContentType(pu.Data)
@@ -74,10 +84,11 @@ func ParseUpload(r *http.Request, sizeLimit int64) (pu *ParsedUpload, e error) {
 		}
 		if shouldBeCompressed, iAmSure := util.IsCompressableFileType(ext, mimeType); mimeType == "" && !iAmSure || shouldBeCompressed && iAmSure {
 			// println("ext", ext, "iAmSure", iAmSure, "shouldBeCompressed", shouldBeCompressed, "mimeType", pu.MimeType)
-			if compressedData, err := util.GzipData(pu.Data); err == nil {
+			if compressedData, err := util.CompressData(pu.Data, compression); err == nil {
 				if len(compressedData)*10 < len(pu.Data)*9 {
 					pu.Data = compressedData
-					pu.IsGzipped = true
+					pu.IsZstd = compression == util.ZstdCompression
+					pu.IsGzipped = !pu.IsZstd
 				}
 				// println("gzipped data size", len(compressedData))
 			}
@@ -98,9 +109,14 @@ func ParseUpload(r *http.Request, sizeLimit int64) (pu *ParsedUpload, e error) {
 	return
 }
 
+// IsCompressed tells whether the data is compressed, with either gzip or zstd.
+func (pu *ParsedUpload) IsCompressed() bool {
+	return pu.IsGzipped || pu.IsZstd
+}
+
 func parsePut(r *http.Request, sizeLimit int64, pu *ParsedUpload) (e error) {
 	pu.IsGzipped = r.Header.Get("Content-Encoding") == "gzip"
-	// pu.IsZstd = r.Header.Get("Content-Encoding") == "zstd"
+	pu.IsZstd = r.Header.Get("Content-Encoding") == "zstd"
 	pu.MimeType = r.Header.Get("Content-Type")
 	pu.FileName = ""
 	pu.Data, e = ioutil.ReadAll(io.LimitReader(r.Body, sizeLimit+1))
@@ -194,7 +210,7 @@ func parseMultipart(r *http.Request, sizeLimit int64, pu *ParsedUpload) (e error
 		}
 
 		pu.IsGzipped = part.Header.Get("Content-Encoding") == "gzip"
-		// pu.IsZstd = part.Header.Get("Content-Encoding") == "zstd"
+		pu.IsZstd = part.Header.Get("Content-Encoding") == "zstd"
 	}
 
 	return
@@ -0,0 +1,81 @@
+package needle
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func TestParseUploadAutoCompressThreshold(t *testing.T) {
+	data := []byte(strings.Repeat(`{"level":"info","msg":"request served","status":200}`+"\n", 20))
+
+	for _, tc := range []struct {
+		threshold int64
+		gzipped   bool
+	}{
+		{0, true},
+		{int64(len(data)) - 1, true},
+		{int64(len(data)), false},
+	} {
+		r := httptest.NewRequest("PUT", "/3,01637037d6", bytes.NewReader(data))
+		r.Header.Set("Content-Type", "application/json")
+		pu, err := ParseUpload(r, 1024*1024, tc.threshold, "")
+		if err != nil {
+			t.Fatalf("parse upload: %v", err)
+		}
+		if pu.IsGzipped != tc.gzipped {
+			t.Errorf("threshold %d: gzipped %v, expected %v", tc.threshold, pu.IsGzipped, tc.gzipped)
+		}
+		if !bytes.Equal(pu.UncompressedData, data) || pu.OriginalDataSize != len(data) {
+			t.Errorf("threshold %d: uncompressed data changed", tc.threshold)
+		}
+	}
+}
+
+func TestParseUploadZstd(t *testing.T) {
+	data := []byte(strings.Repeat(`{"level":"info","msg":"request served","status":200}`+"\n", 20))
+
+	for _, tc := range []struct {
+		url         string
+		compression string
+		zstd        bool
+		compressed  bool
+	}{
+		{"/3,01637037d6", "", false, true},
+		{"/3,01637037d6", util.ZstdCompression, true, true},
+		{"/3,01637037d6?compression=zstd", util.GzipCompression, true, true},
+		{"/3,01637037d6?compression=gzip", util.ZstdCompression, false, true},
+		{"/3,01637037d6?compression=none", util.ZstdCompression, false, false},
+	} {
+		r := httptest.NewRequest("PUT", tc.url, bytes.NewReader(data))
+		r.Header.Set("Content-Type", "application/json")
+		pu, err := ParseUpload(r, 1024*1024, 0, tc.compression)
+		if err != nil {
+			t.Fatalf("parse upload: %v", err)
+		}
+		if pu.IsZstd != tc.zstd || pu.IsCompressed() != tc.compressed {
+			t.Errorf("%s with %q: zstd %v compressed %v", tc.url, tc.compression, pu.IsZstd, pu.IsCompressed())
+		}
+		if pu.IsZstd != util.IsZstdContent(pu.Data) {
+			t.Errorf("%s with %q: unexpected data encoding", tc.url, tc.compression)
+		}
+		if !bytes.Equal(pu.UncompressedData, data) {
+			t.Errorf("%s with %q: uncompressed data changed", tc.url, tc.compression)
+		}
+	}
+
+	// data compressed by the client
+	compressed, _ := util.ZstdData(data)
+	r := httptest.NewRequest("PUT", "/3,01637037d6", bytes.NewReader(compressed))
+	r.Header.Set("Content-Encoding", "zstd")
+	pu, err := ParseUpload(r, 1024*1024, 0, "")
+	if err != nil {
+		t.Fatalf("parse upload: %v", err)
+	}
+	if !pu.IsZstd || !bytes.Equal(pu.Data, compressed) || !bytes.Equal(pu.UncompressedData, data) {
+		t.Errorf("zstd content encoding: zstd %v, %d bytes", pu.IsZstd, len(pu.Data))
+	}
+}
@@ -19,12 +19,14 @@ const (
 	FlagHasLastModifiedDate = 0x08
 	FlagHasTtl              = 0x10
 	FlagHasPairs            = 0x20
+	FlagIsZstd              = 0x40 // set together with FlagIsCompressed if compressed with zstd instead of gzip
 	FlagIsChunkManifest     = 0x80
 	LastModifiedBytesLength = 5
 	TtlBytesLength          = 2
 )
 
 var ErrorSizeMismatch = errors.New("size mismatch")
+var ErrorCRC = errors.New("CRC error! Data On Disk Corrupted")
 
 func (n *Needle) DiskSize(version Version) int64 {
 	return GetActualSize(n.Size, version)
@@ -127,6 +129,12 @@ func (n *Needle) prepareWriteBuffer(version Version) ([]byte, Size, int64, error
 	return writeBytes, 0, 0, fmt.Errorf("Unsupported Version! (%d)", version)
 }
 
+// ToBytes serializes the needle as appended to the volume data file, with the header and the padding.
+func (n *Needle) ToBytes(version Version) ([]byte, error) {
+	bytes, _, _, err := n.prepareWriteBuffer(version)
+	return bytes, err
+}
+
 func (n *Needle) Append(w backend.BackendStorageFile, version Version) (offset uint64, size Size, actualSize int64, err error) {
 
 	if end, _, e := w.GetStat(); e == nil {
@@ -190,7 +198,7 @@ func (n *Needle) ReadBytes(bytes []byte, offset int64, size Size, version Versio
 		checksum := util.BytesToUint32(bytes[NeedleHeaderSize+size : NeedleHeaderSize+size+NeedleChecksumSize])
 		newChecksum := NewCRC(n.Data)
 		if checksum != newChecksum.Value() {
-			return errors.New("CRC error! Data On Disk Corrupted")
+			return ErrorCRC
 		}
 		n.Checksum = newChecksum
 	}
@@ -356,6 +364,12 @@ func (n *Needle) IsCompressed() bool {
 func (n *Needle) SetIsCompressed() {
 	n.Flags = n.Flags | FlagIsCompressed
 }
+func (n *Needle) IsZstd() bool {
+	return n.Flags&FlagIsZstd > 0
+}
+func (n *Needle) SetIsZstd() {
+	n.Flags = n.Flags | FlagIsCompressed | FlagIsZstd
+}
 func (n *Needle) HasName() bool {
 	return n.Flags&FlagHasName > 0
 }
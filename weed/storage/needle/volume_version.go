@@ -2,6 +2,25 @@ package needle
 
 type Version uint8
 
+/*
+Needle layout on disk:
+
+Version1: Cookie(4) Id(8) Size(4) Data(Size) Checksum(4) Padding
+
+Version2: Cookie(4) Id(8) Size(4) DataSize(4) Data(DataSize) Flags(1)
+	NameSize(1) Name          only if FlagHasName
+	MimeSize(1) Mime          only if FlagHasMime
+	LastModified(5)           only if FlagHasLastModifiedDate
+	Ttl(2)                    only if FlagHasTtl
+	PairsSize(2) Pairs        only if FlagHasPairs
+	Checksum(4) Padding
+
+Version3: same as Version2, with AppendAtNs(8) after the checksum.
+
+Optional fields already take no space when unset. Only the 16 byte
+Cookie+Id+Size header is fixed, which lets the .dat file be scanned
+and the .idx file be rebuilt without any other metadata.
+*/
 const (
 	Version1       = Version(1)
 	Version2       = Version(2)
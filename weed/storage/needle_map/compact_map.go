@@ -11,6 +11,14 @@ const (
 	batch = 100000
 )
 
+var (
+	// IndexSectionSize is the number of entries allocated at once by the in-memory needle index.
+	IndexSectionSize = batch
+	// IndexExactSize, if positive, allocates the in-memory needle index for exactly this many
+	// entries when it is created, instead of allocating by IndexSectionSize as it grows.
+	IndexExactSize = 0
+)
+
 type SectionalNeedleId uint32
 
 const SectionalNeedleIdLimit = 1<<32 - 1
@@ -40,9 +48,13 @@ type Overflow []SectionalNeedleValue
 type OverflowExtra []SectionalNeedleValueExtra
 
 func NewCompactSection(start NeedleId) *CompactSection {
+	return newCompactSection(start, batch)
+}
+
+func newCompactSection(start NeedleId, size int) *CompactSection {
 	return &CompactSection{
-		values:        make([]SectionalNeedleValue, batch),
-		valuesExtra:   make([]SectionalNeedleValueExtra, batch),
+		values:        make([]SectionalNeedleValue, size),
+		valuesExtra:   make([]SectionalNeedleValueExtra, size),
 		overflow:      Overflow(make([]SectionalNeedleValue, 0)),
 		overflowExtra: OverflowExtra(make([]SectionalNeedleValueExtra, 0)),
 		start:         start,
@@ -61,7 +73,7 @@ func (cs *CompactSection) Set(key NeedleId, offset Offset, size Size) (oldOffset
 		//println("key", key, "old size", ret)
 		cs.valuesExtra[i].OffsetHigher, cs.values[i].OffsetLower, cs.values[i].Size = offset.OffsetHigher, offset.OffsetLower, size
 	} else {
-		needOverflow := cs.counter >= batch
+		needOverflow := cs.counter >= len(cs.values)
 		needOverflow = needOverflow || cs.counter > 0 && cs.values[cs.counter-1].Key > skey
 		if needOverflow {
 			//println("start", cs.start, "counter", cs.counter, "key", key)
@@ -171,18 +183,39 @@ func (cs *CompactSection) binarySearchValues(key SectionalNeedleId) int {
 //This map assumes mostly inserting increasing keys
 //This map assumes mostly inserting increasing keys
 type CompactMap struct {
-	list []*CompactSection
+	list        []*CompactSection
+	sectionSize int
+	spare       *CompactSection // preallocated for the first section
 }
 
 func NewCompactMap() *CompactMap {
-	return &CompactMap{}
+	cm := &CompactMap{
+		sectionSize: IndexSectionSize,
+	}
+	if cm.sectionSize <= 0 {
+		cm.sectionSize = batch
+	}
+	if IndexExactSize > 0 {
+		cm.sectionSize = IndexExactSize
+		cm.spare = newCompactSection(0, IndexExactSize)
+	}
+	return cm
+}
+
+func (cm *CompactMap) newSection(start NeedleId) (cs *CompactSection) {
+	if cm.spare != nil {
+		cs, cm.spare = cm.spare, nil
+		cs.start = start
+		return cs
+	}
+	return newCompactSection(start, cm.sectionSize)
 }
 
 func (cm *CompactMap) Set(key NeedleId, offset Offset, size Size) (oldOffset Offset, oldSize Size) {
 	x := cm.binarySearchCompactSection(key)
 	if x < 0 || (key-cm.list[x].start) > SectionalNeedleIdLimit {
 		// println(x, "adding to existing", len(cm.list), "sections, starting", key)
-		cs := NewCompactSection(key)
+		cs := cm.newSection(key)
 		cm.list = append(cm.list, cs)
 		x = len(cm.list) - 1
 		//keep compact section sorted by start
@@ -221,7 +254,7 @@ func (cm *CompactMap) binarySearchCompactSection(key NeedleId) int {
 		return -5
 	}
 	if cm.list[h].start <= key {
-		if cm.list[h].counter < batch || key <= cm.list[h].end {
+		if cm.list[h].counter < len(cm.list[h].values) || key <= cm.list[h].end {
 			return h
 		}
 		return -4
@@ -164,3 +164,23 @@ func TestOverflow(t *testing.T) {
 	println()
 
 }
+
+func TestIndexExactSize(t *testing.T) {
+	IndexExactSize = 1000
+	defer func() {
+		IndexExactSize = 0
+	}()
+
+	m := NewCompactMap()
+	for i := uint32(1); i <= 1001; i++ {
+		m.Set(NeedleId(i), ToOffset(int64(i)), Size(i))
+	}
+	if len(m.list) != 2 || len(m.list[0].values) != 1000 || m.list[0].counter != 1000 || len(m.list[0].overflow) != 0 {
+		t.Fatalf("expecting the first 1000 entries in the preallocated section, %d sections", len(m.list))
+	}
+	for i := uint32(1); i <= 1001; i++ {
+		if v, found := m.Get(NeedleId(i)); !found || v.Size != Size(i) {
+			t.Fatalf("key %d not found", i)
+		}
+	}
+}
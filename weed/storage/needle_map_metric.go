@@ -91,6 +91,9 @@ func (mm *mapMetric) MaybeSetMaxFileKey(key NeedleId) {
 	}
 }
 
+// newNeedleMapMetricFromIndexFile walks the index file backwards to count live and deleted files.
+// The bloom filter only tells whether a newer entry of the same key was already seen during this walk.
+// It is discarded afterwards, so there is no per volume bloom filter to persist in a .bloom file.
 func newNeedleMapMetricFromIndexFile(r *os.File) (mm *mapMetric, err error) {
 	mm = &mapMetric{}
 	var bf *bloom.BloomFilter
@@ -8,6 +8,7 @@ import (
 
 	"google.golang.org/grpc"
 
+	"github.com/chrislusf/seaweedfs/weed/alert"
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
@@ -36,16 +37,24 @@ type Store struct {
 	volumeSizeLimit     uint64 // read from the master
 	Ip                  string
 	Port                int
+	GrpcPort            int
 	PublicUrl           string
 	Locations           []*DiskLocation
-	dataCenter          string // optional informaton, overwriting master setting if exists
-	rack                string // optional information, overwriting master setting if exists
+	dataCenter          string   // optional informaton, overwriting master setting if exists
+	rack                string   // optional information, overwriting master setting if exists
+	tags                []string // for the master placement rules
 	connected           bool
 	NeedleMapType       NeedleMapType
 	NewVolumesChan      chan master_pb.VolumeShortInformationMessage
 	DeletedVolumesChan  chan master_pb.VolumeShortInformationMessage
 	NewEcShardsChan     chan master_pb.VolumeEcShardInformationMessage
 	DeletedEcShardsChan chan master_pb.VolumeEcShardInformationMessage
+	recentWrites        *recentWrites // nil if disabled
+	recency             *recencyLog   // nil unless prewarming is enabled
+	pending             pendingState
+	replicationFailures replicationFailures
+	scrub               scrubState
+	NeedleRepairer      NeedleRepairer // reads the healthy copies of the corrupted needles, nil to not repair
 }
 
 func (s *Store) String() (str string) {
@@ -53,11 +62,11 @@ func (s *Store) String() (str string) {
 	return
 }
 
-func NewStore(grpcDialOption grpc.DialOption, port int, ip, publicUrl string, dirnames []string, maxVolumeCounts []int, minFreeSpacePercents []float32, needleMapKind NeedleMapType) (s *Store) {
-	s = &Store{grpcDialOption: grpcDialOption, Port: port, Ip: ip, PublicUrl: publicUrl, NeedleMapType: needleMapKind}
+func NewStore(grpcDialOption grpc.DialOption, port int, grpcPort int, ip, publicUrl string, dirnames []string, maxVolumeCounts []int, minFreeSpacePercents, resumeFreeSpacePercents []float32, diskTypes []string, needleMapKind NeedleMapType, recentWriteCacheSize, recentWriteCacheSizeMB int) (s *Store) {
+	s = &Store{grpcDialOption: grpcDialOption, Port: port, GrpcPort: grpcPort, Ip: ip, PublicUrl: publicUrl, NeedleMapType: needleMapKind}
 	s.Locations = make([]*DiskLocation, 0)
 	for i := 0; i < len(dirnames); i++ {
-		location := NewDiskLocation(util.ResolvePath(dirnames[i]), maxVolumeCounts[i], minFreeSpacePercents[i])
+		location := NewDiskLocation(util.ResolvePath(dirnames[i]), maxVolumeCounts[i], minFreeSpacePercents[i], resumeFreeSpacePercents[i], diskTypes[i])
 		location.loadExistingVolumes(needleMapKind)
 		s.Locations = append(s.Locations, location)
 		stats.VolumeServerMaxVolumeCounter.Add(float64(maxVolumeCounts[i]))
@@ -68,9 +77,13 @@ func NewStore(grpcDialOption grpc.DialOption, port int, ip, publicUrl string, di
 	s.NewEcShardsChan = make(chan master_pb.VolumeEcShardInformationMessage, 3)
 	s.DeletedEcShardsChan = make(chan master_pb.VolumeEcShardInformationMessage, 3)
 
+	if recentWriteCacheSize > 0 && recentWriteCacheSizeMB > 0 {
+		s.recentWrites = newRecentWrites(recentWriteCacheSize, int64(recentWriteCacheSizeMB)*1024*1024)
+	}
+
 	return
 }
-func (s *Store) AddVolume(volumeId needle.VolumeId, collection string, needleMapKind NeedleMapType, replicaPlacement string, ttlString string, preallocate int64, MemoryMapMaxSizeMb uint32) error {
+func (s *Store) AddVolume(volumeId needle.VolumeId, collection string, needleMapKind NeedleMapType, replicaPlacement string, ttlString string, preallocate int64, MemoryMapMaxSizeMb uint32, diskType string) error {
 	rt, e := super_block.NewReplicaPlacementFromString(replicaPlacement)
 	if e != nil {
 		return e
@@ -79,7 +92,7 @@ func (s *Store) AddVolume(volumeId needle.VolumeId, collection string, needleMap
 	if e != nil {
 		return e
 	}
-	e = s.addVolume(volumeId, collection, needleMapKind, rt, ttl, preallocate, MemoryMapMaxSizeMb)
+	e = s.addVolume(volumeId, collection, needleMapKind, rt, ttl, preallocate, MemoryMapMaxSizeMb, diskType)
 	return e
 }
 func (s *Store) DeleteCollection(collection string) (e error) {
@@ -102,8 +115,20 @@ func (s *Store) findVolume(vid needle.VolumeId) *Volume {
 	return nil
 }
 func (s *Store) FindFreeLocation() (ret *DiskLocation) {
+	return s.FindFreeLocationOfDiskType("")
+}
+
+// FindFreeLocationOfDiskType finds the folder of the disk type with the most free slots, or of any disk type if empty.
+// The folders low on disk space have no free slots.
+func (s *Store) FindFreeLocationOfDiskType(diskType string) (ret *DiskLocation) {
 	max := 0
 	for _, location := range s.Locations {
+		if diskType != "" && location.DiskType != ToDiskType(diskType) {
+			continue
+		}
+		if location.isDiskSpaceLow {
+			continue
+		}
 		currentFreeCount := location.MaxVolumeCount - location.VolumesLen()
 		currentFreeCount *= erasure_coding.DataShardsCount
 		currentFreeCount -= location.EcVolumesLen()
@@ -115,13 +140,13 @@ func (s *Store) FindFreeLocation() (ret *DiskLocation) {
 	}
 	return ret
 }
-func (s *Store) addVolume(vid needle.VolumeId, collection string, needleMapKind NeedleMapType, replicaPlacement *super_block.ReplicaPlacement, ttl *needle.TTL, preallocate int64, memoryMapMaxSizeMb uint32) error {
+func (s *Store) addVolume(vid needle.VolumeId, collection string, needleMapKind NeedleMapType, replicaPlacement *super_block.ReplicaPlacement, ttl *needle.TTL, preallocate int64, memoryMapMaxSizeMb uint32, diskType string) error {
 	if s.findVolume(vid) != nil {
 		return fmt.Errorf("Volume Id %d already exists!", vid)
 	}
-	if location := s.FindFreeLocation(); location != nil {
-		glog.V(0).Infof("In dir %s adds volume:%v collection:%s replicaPlacement:%v ttl:%v",
-			location.Directory, vid, collection, replicaPlacement, ttl)
+	if location := s.FindFreeLocationOfDiskType(diskType); location != nil {
+		glog.V(0).Infof("In dir %s adds volume:%v collection:%s replicaPlacement:%v ttl:%v diskType:%s",
+			location.Directory, vid, collection, replicaPlacement, ttl, location.DiskType)
 		if volume, err := NewVolume(location.Directory, collection, vid, needleMapKind, replicaPlacement, ttl, preallocate, memoryMapMaxSizeMb); err == nil {
 			location.SetVolume(vid, volume)
 			glog.V(0).Infof("add volume %d", vid)
@@ -131,12 +156,16 @@ func (s *Store) addVolume(vid needle.VolumeId, collection string, needleMapKind
 				ReplicaPlacement: uint32(replicaPlacement.Byte()),
 				Version:          uint32(volume.Version()),
 				Ttl:              ttl.ToUint32(),
+				DiskType:         location.DiskType,
 			}
 			return nil
 		} else {
 			return err
 		}
 	}
+	if diskType != "" {
+		return fmt.Errorf("No more free space left on %s disks", ToDiskType(diskType))
+	}
 	return fmt.Errorf("No more free space left")
 }
 
@@ -194,6 +223,9 @@ func (s *Store) SetDataCenter(dataCenter string) {
 func (s *Store) SetRack(rack string) {
 	s.rack = rack
 }
+func (s *Store) SetTags(tags []string) {
+	s.tags = tags
+}
 func (s *Store) GetDataCenter() string {
 	return s.dataCenter
 }
@@ -204,12 +236,21 @@ func (s *Store) GetRack() string {
 func (s *Store) CollectHeartbeat() *master_pb.Heartbeat {
 	var volumeMessages []*master_pb.VolumeInformationMessage
 	maxVolumeCount := 0
+	maxVolumeCounts := make(map[string]uint32)
 	var maxFileKey NeedleId
 	collectionVolumeSize := make(map[string]uint64)
-	collectionVolumeReadOnlyCount := make(map[string]map[string]uint8)
+	collectionVolumeReadOnlyCount := make(map[string]map[string]int)
+	var diskSpaceLowDirs []string
 	for _, location := range s.Locations {
 		var deleteVids []needle.VolumeId
-		maxVolumeCount = maxVolumeCount + location.MaxVolumeCount
+		locationMaxVolumeCount := location.MaxVolumeCount
+		if location.isDiskSpaceLow {
+			// report no free slots, so the master grows no new volumes here
+			locationMaxVolumeCount = location.VolumesLen()
+			diskSpaceLowDirs = append(diskSpaceLowDirs, location.Directory)
+		}
+		maxVolumeCount = maxVolumeCount + locationMaxVolumeCount
+		maxVolumeCounts[location.DiskType] += uint32(locationMaxVolumeCount)
 		location.volumesLock.RLock()
 		for _, v := range location.volumes {
 			curMaxFileKey, volumeMessage := v.ToVolumeInformationMessage()
@@ -225,9 +266,10 @@ func (s *Store) CollectHeartbeat() *master_pb.Heartbeat {
 					glog.V(0).Infoln("volume", v.Id, "is expired.")
 				}
 			}
-			collectionVolumeSize[v.Collection] += volumeMessage.Size
-			if _, exist := collectionVolumeReadOnlyCount[v.Collection]; !exist {
-				collectionVolumeReadOnlyCount[v.Collection] = map[string]uint8{
+			col := stats.CollectionLabel(v.Collection)
+			collectionVolumeSize[col] += volumeMessage.Size
+			if _, exist := collectionVolumeReadOnlyCount[col]; !exist {
+				collectionVolumeReadOnlyCount[col] = map[string]int{
 					"IsReadOnly":       0,
 					"noWriteOrDelete":  0,
 					"noWriteCanDelete": 0,
@@ -235,15 +277,15 @@ func (s *Store) CollectHeartbeat() *master_pb.Heartbeat {
 				}
 			}
 			if v.IsReadOnly() {
-				collectionVolumeReadOnlyCount[v.Collection]["IsReadOnly"] += 1
+				collectionVolumeReadOnlyCount[col]["IsReadOnly"] += 1
 				if v.noWriteOrDelete {
-					collectionVolumeReadOnlyCount[v.Collection]["noWriteOrDelete"] += 1
+					collectionVolumeReadOnlyCount[col]["noWriteOrDelete"] += 1
 				}
 				if v.noWriteCanDelete {
-					collectionVolumeReadOnlyCount[v.Collection]["noWriteCanDelete"] += 1
+					collectionVolumeReadOnlyCount[col]["noWriteCanDelete"] += 1
 				}
 				if v.location.isDiskSpaceLow {
-					collectionVolumeReadOnlyCount[v.Collection]["isDiskSpaceLow"] += 1
+					collectionVolumeReadOnlyCount[col]["isDiskSpaceLow"] += 1
 				}
 			}
 		}
@@ -277,20 +319,25 @@ func (s *Store) CollectHeartbeat() *master_pb.Heartbeat {
 	}
 
 	return &master_pb.Heartbeat{
-		Ip:             s.Ip,
-		Port:           uint32(s.Port),
-		PublicUrl:      s.PublicUrl,
-		MaxVolumeCount: uint32(maxVolumeCount),
-		MaxFileKey:     NeedleIdToUint64(maxFileKey),
-		DataCenter:     s.dataCenter,
-		Rack:           s.rack,
-		Volumes:        volumeMessages,
-		HasNoVolumes:   len(volumeMessages) == 0,
+		Ip:               s.Ip,
+		Port:             uint32(s.Port),
+		GrpcPort:         uint32(s.GrpcPort),
+		PublicUrl:        s.PublicUrl,
+		MaxVolumeCount:   uint32(maxVolumeCount),
+		MaxVolumeCounts:  maxVolumeCounts,
+		MaxFileKey:       NeedleIdToUint64(maxFileKey),
+		DataCenter:       s.dataCenter,
+		Rack:             s.rack,
+		Tags:             s.tags,
+		Volumes:          volumeMessages,
+		HasNoVolumes:     len(volumeMessages) == 0,
+		DiskSpaceLowDirs: diskSpaceLowDirs,
 	}
 
 }
 
 func (s *Store) Close() {
+	s.saveRecencyLog()
 	for _, location := range s.Locations {
 		location.Close()
 	}
@@ -303,6 +350,12 @@ func (s *Store) WriteVolumeNeedle(i needle.VolumeId, n *needle.Needle, fsync boo
 			return
 		}
 		_, _, isUnchanged, err = v.writeNeedle2(n, fsync)
+		if err == nil && s.recentWrites != nil {
+			s.recentWrites.Put(i, n)
+		}
+		if err == nil && s.recency != nil {
+			s.recency.Touch(i, n.Id)
+		}
 		return
 	}
 	glog.V(0).Infoln("volume", i, "not found!")
@@ -315,6 +368,9 @@ func (s *Store) DeleteVolumeNeedle(i needle.VolumeId, n *needle.Needle) (Size, e
 		if v.noWriteOrDelete {
 			return 0, fmt.Errorf("volume %d is read only", i)
 		}
+		if s.recentWrites != nil {
+			s.recentWrites.Delete(i, n.Id)
+		}
 		return v.deleteNeedle2(n)
 	}
 	return 0, fmt.Errorf("volume %d not found on %s:%d", i, s.Ip, s.Port)
@@ -322,10 +378,40 @@ func (s *Store) DeleteVolumeNeedle(i needle.VolumeId, n *needle.Needle) (Size, e
 
 func (s *Store) ReadVolumeNeedle(i needle.VolumeId, n *needle.Needle, readOption *ReadOption) (int, error) {
 	if v := s.findVolume(i); v != nil {
-		return v.readNeedle(n, readOption)
+		if s.recency != nil {
+			s.recency.Touch(i, n.Id)
+		}
+		if s.recentWrites != nil && (readOption == nil || !readOption.ReadDeleted) {
+			if cached := s.recentWrites.Get(i, n.Id); cached != nil {
+				*n = *cached
+				return len(n.Data), nil
+			}
+		}
+		count, err := v.readNeedle(n, readOption)
+		if err == needle.ErrorCRC {
+			raiseChecksumAlert(i, n.Id)
+		}
+		return count, err
 	}
 	return 0, fmt.Errorf("volume %d not found", i)
 }
+
+// raiseChecksumAlert tells the operators about a needle read with a CRC mismatch.
+func raiseChecksumAlert(vid needle.VolumeId, id NeedleId) {
+	alert.Raise(alert.ChecksumError, "CRC error! Data On Disk Corrupted", map[string]string{
+		"volumeId": vid.String(),
+		"needleId": id.String(),
+	})
+}
+
+// HeadVolumeNeedle returns the stored needle size by reading only the needle index
+func (s *Store) HeadVolumeNeedle(i needle.VolumeId, id NeedleId) (Size, error) {
+	if v := s.findVolume(i); v != nil {
+		return v.headNeedle(id)
+	}
+	return 0, fmt.Errorf("volume %d not found", i)
+}
+
 func (s *Store) GetVolume(i needle.VolumeId) *Volume {
 	return s.findVolume(i)
 }
@@ -368,6 +454,7 @@ func (s *Store) MountVolume(i needle.VolumeId) error {
 				ReplicaPlacement: uint32(v.ReplicaPlacement.Byte()),
 				Version:          uint32(v.Version()),
 				Ttl:              v.Ttl.ToUint32(),
+				DiskType:         v.DiskType(),
 			}
 			return nil
 		}
@@ -376,6 +463,25 @@ func (s *Store) MountVolume(i needle.VolumeId) error {
 	return fmt.Errorf("volume %d not found on disk", i)
 }
 
+// OpenUnmountedVolume opens the files of a volume which is on disk but not mounted, e.g. while it is moved here.
+// The master does not know about the volume, and the caller needs to close it before mounting it.
+func (s *Store) OpenUnmountedVolume(i needle.VolumeId) (*Volume, error) {
+	if v := s.findVolume(i); v != nil {
+		return nil, fmt.Errorf("volume %d is already mounted", i)
+	}
+	for _, location := range s.Locations {
+		if fileInfo, found := location.LocateVolume(i); found {
+			_, collection, err := location.volumeIdFromPath(fileInfo)
+			if err != nil {
+				return nil, err
+			}
+			return NewVolume(location.Directory, collection, i, s.NeedleMapType, nil, nil, 0, 0)
+		}
+	}
+
+	return nil, fmt.Errorf("volume %d not found on disk", i)
+}
+
 func (s *Store) UnmountVolume(i needle.VolumeId) error {
 	v := s.findVolume(i)
 	if v == nil {
@@ -387,8 +493,12 @@ func (s *Store) UnmountVolume(i needle.VolumeId) error {
 		ReplicaPlacement: uint32(v.ReplicaPlacement.Byte()),
 		Version:          uint32(v.Version()),
 		Ttl:              v.Ttl.ToUint32(),
+		DiskType:         v.DiskType(),
 	}
 
+	if s.recentWrites != nil {
+		s.recentWrites.DeleteVolume(i)
+	}
 	for _, location := range s.Locations {
 		if err := location.UnloadVolume(i); err == nil {
 			glog.V(0).Infof("UnmountVolume %d", i)
@@ -411,6 +521,10 @@ func (s *Store) DeleteVolume(i needle.VolumeId) error {
 		ReplicaPlacement: uint32(v.ReplicaPlacement.Byte()),
 		Version:          uint32(v.Version()),
 		Ttl:              v.Ttl.ToUint32(),
+		DiskType:         v.DiskType(),
+	}
+	if s.recentWrites != nil {
+		s.recentWrites.DeleteVolume(i)
 	}
 	for _, location := range s.Locations {
 		if err := location.DeleteVolume(i); err == nil {
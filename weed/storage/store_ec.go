@@ -13,6 +13,7 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
 	"github.com/chrislusf/seaweedfs/weed/operation"
+	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
 	"github.com/chrislusf/seaweedfs/weed/stats"
@@ -30,7 +31,7 @@ func (s *Store) CollectErasureCodingHeartbeat() *master_pb.Heartbeat {
 			ecShardMessages = append(ecShardMessages, ecShards.ToVolumeEcShardInformationMessage()...)
 
 			for _, ecShard := range ecShards.Shards {
-				collectionEcShardSize[ecShards.Collection] += ecShard.Size()
+				collectionEcShardSize[stats.CollectionLabel(ecShards.Collection)] += ecShard.Size()
 			}
 		}
 		location.ecVolumesLock.RUnlock()
@@ -119,6 +120,23 @@ func (s *Store) DestroyEcVolume(vid needle.VolumeId) {
 	}
 }
 
+// HeadEcShardNeedle returns the stored needle size by reading only the .ecx index
+func (s *Store) HeadEcShardNeedle(vid needle.VolumeId, id types.NeedleId) (types.Size, error) {
+	for _, location := range s.Locations {
+		if localEcVolume, found := location.FindEcVolume(vid); found {
+			_, size, err := localEcVolume.FindNeedleFromEcx(id)
+			if err != nil {
+				return 0, err
+			}
+			if size.IsDeleted() {
+				return 0, ErrorDeleted
+			}
+			return size, nil
+		}
+	}
+	return 0, fmt.Errorf("ec volume %d not found", vid)
+}
+
 func (s *Store) ReadEcShardNeedle(vid needle.VolumeId, n *needle.Needle) (int, error) {
 	for _, location := range s.Locations {
 		if localEcVolume, found := location.FindEcVolume(vid); found {
@@ -145,9 +163,15 @@ func (s *Store) ReadEcShardNeedle(vid needle.VolumeId, n *needle.Needle) (int, e
 			}
 
 			err = n.ReadBytes(bytes, offset.ToAcutalOffset(), size, localEcVolume.Version)
+			if err == needle.ErrorCRC {
+				raiseChecksumAlert(vid, n.Id)
+			}
 			if err != nil {
 				return 0, fmt.Errorf("readbytes: %v", err)
 			}
+			if err = decryptEcNeedle(localEcVolume, n); err != nil {
+				return 0, err
+			}
 
 			return len(bytes), nil
 		}
@@ -250,7 +274,7 @@ func (s *Store) cachedLookupEcShardLocations(ecVolume *erasure_coding.EcVolume)
 			shardId := erasure_coding.ShardId(shardIdLocations.ShardId)
 			delete(ecVolume.ShardLocations, shardId)
 			for _, loc := range shardIdLocations.Locations {
-				ecVolume.ShardLocations[shardId] = append(ecVolume.ShardLocations[shardId], loc.Url)
+				ecVolume.ShardLocations[shardId] = append(ecVolume.ShardLocations[shardId], pb.NewServerAddressWithGrpcPort(loc.Url, int(loc.GrpcPort)))
 			}
 		}
 		ecVolume.ShardLocationsRefreshTime = time.Now()
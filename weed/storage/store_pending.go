@@ -0,0 +1,384 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	. "github.com/chrislusf/seaweedfs/weed/storage/types"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+/*
+Pending needles are written by a filer as the first phase of a two phase commit.
+The filer confirms them after the file metadata is saved. Needles still pending
+after the ttl belong to an upload whose metadata was never saved, and are deleted.
+
+The state of a volume is kept in an append only log next to its .dat and .idx files,
+so it is copied and moved along with the volume, with one record per change:
+<op:1><needle id:8><unix seconds:8>. A needle is recorded before it is written, and
+the log is synced before a write or a confirmation is acknowledged. The log is
+compacted when opened and when it has collected many removed needles. It is only
+created on the first pending write, so volumes not written by a filer with
+-twoPhaseCommit do not have it.
+*/
+
+const (
+	PendingLogExt           = ".pnd"
+	pendingLogRecordSize    = 1 + NeedleIdSize + 8
+	pendingOpAdd            = byte(1)
+	pendingOpRemove         = byte(0)
+	pendingCheckInterval    = time.Minute
+	pendingNeedleDefaultTtl = time.Hour
+	pendingCompactRecords   = 10000 // removed records in the log before compacting it
+)
+
+type pendingState struct {
+	sync.Mutex
+	ttl time.Duration // zero unless enabled
+}
+
+type pendingNeedles struct {
+	sync.Mutex
+	fileName string
+	file     *os.File           // nil once closed
+	records  int                // in the log
+	entries  map[NeedleId]int64 // unix seconds when written
+}
+
+func (pn *pendingNeedles) append(op byte, id NeedleId, ts int64) error {
+	if pn.file == nil {
+		return fmt.Errorf("%s is closed", pn.fileName)
+	}
+	record := make([]byte, pendingLogRecordSize)
+	record[0] = op
+	NeedleIdToBytes(record[1:1+NeedleIdSize], id)
+	util.Uint64toBytes(record[1+NeedleIdSize:], uint64(ts))
+	if _, err := pn.file.Write(record); err != nil {
+		return fmt.Errorf("append to %s: %v", pn.fileName, err)
+	}
+	pn.records++
+	return nil
+}
+
+func (pn *pendingNeedles) sync() error {
+	if pn.file == nil {
+		return fmt.Errorf("%s is closed", pn.fileName)
+	}
+	if err := pn.file.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %v", pn.fileName, err)
+	}
+	return nil
+}
+
+// compact rewrites the log with only the current pending needles.
+func (pn *pendingNeedles) compact() error {
+	tmpFileName := pn.fileName + ".tmp"
+	f, err := os.OpenFile(tmpFileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	oldFile := pn.file
+	pn.file, pn.records = f, 0
+	for id, ts := range pn.entries {
+		if err = pn.append(pendingOpAdd, id, ts); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpFileName, pn.fileName)
+	}
+	if err != nil {
+		f.Close()
+		pn.file = oldFile
+		return err
+	}
+	if dir, dirErr := os.Open(filepath.Dir(pn.fileName)); dirErr == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	if oldFile != nil {
+		oldFile.Close()
+	}
+	return nil
+}
+
+func (pn *pendingNeedles) close() {
+	pn.Lock()
+	defer pn.Unlock()
+	if pn.file != nil {
+		pn.file.Close()
+		pn.file = nil
+		stats.VolumeServerPendingNeedlesGauge.Sub(float64(len(pn.entries)))
+	}
+}
+
+func loadPendingNeedles(fileName string) (entries map[NeedleId]int64, err error) {
+	entries = make(map[NeedleId]int64)
+	f, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	record := make([]byte, pendingLogRecordSize)
+	for {
+		if _, err = io.ReadFull(reader, record); err != nil {
+			// a partially written last record is ignored
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return entries, nil
+			}
+			return nil, err
+		}
+		id := BytesToNeedleId(record[1 : 1+NeedleIdSize])
+		if record[0] == pendingOpAdd {
+			entries[id] = int64(util.BytesToUint64(record[1+NeedleIdSize:]))
+		} else {
+			delete(entries, id)
+		}
+	}
+}
+
+// pendingNeedles returns the pending needles of the volume, or nil if it has none and create is false.
+func (v *Volume) pendingNeedles(create bool) (*pendingNeedles, error) {
+	v.pendingLock.Lock()
+	defer v.pendingLock.Unlock()
+	if v.pending != nil {
+		return v.pending, nil
+	}
+	fileName := v.FileName() + PendingLogExt
+	if _, err := os.Stat(fileName); os.IsNotExist(err) && !create {
+		return nil, nil
+	}
+	entries, err := loadPendingNeedles(fileName)
+	if err != nil {
+		return nil, err
+	}
+	pn := &pendingNeedles{fileName: fileName, entries: entries}
+	if err = pn.compact(); err != nil {
+		return nil, err
+	}
+	stats.VolumeServerPendingNeedlesGauge.Add(float64(len(entries)))
+	v.pending = pn
+	return pn, nil
+}
+
+func (v *Volume) closePendingNeedles() {
+	v.pendingLock.Lock()
+	defer v.pendingLock.Unlock()
+	if v.pending != nil {
+		v.pending.close()
+		v.pending = nil
+	}
+}
+
+// EnablePendingNeedles accepts pending writes, and deletes the needles not confirmed within the ttl.
+func (s *Store) EnablePendingNeedles(ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = pendingNeedleDefaultTtl
+	}
+	s.pending.Lock()
+	defer s.pending.Unlock()
+	if s.pending.ttl > 0 {
+		s.pending.ttl = ttl
+		return nil
+	}
+	s.pending.ttl = ttl
+
+	go func() {
+		for {
+			for _, v := range s.allVolumes() {
+				s.checkPendingNeedles(v, time.Now())
+			}
+			time.Sleep(pendingCheckInterval)
+		}
+	}()
+	return nil
+}
+
+func (s *Store) allVolumes() (volumes []*Volume) {
+	for _, location := range s.Locations {
+		location.volumesLock.RLock()
+		for _, v := range location.volumes {
+			volumes = append(volumes, v)
+		}
+		location.volumesLock.RUnlock()
+	}
+	return
+}
+
+// MarkPendingNeedle records the needle as pending, before it is written. It returns
+// whether the needle was pending already, e.g. for a retried write.
+func (s *Store) MarkPendingNeedle(vid needle.VolumeId, id NeedleId) (wasPending bool, err error) {
+	s.pending.Lock()
+	enabled := s.pending.ttl > 0
+	s.pending.Unlock()
+	if !enabled {
+		return false, nil
+	}
+	v := s.findVolume(vid)
+	if v == nil {
+		return false, fmt.Errorf("volume %d not found on %s:%d", vid, s.Ip, s.Port)
+	}
+	pn, err := v.pendingNeedles(true)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now().Unix()
+	pn.Lock()
+	defer pn.Unlock()
+	if err = pn.append(pendingOpAdd, id, now); err != nil {
+		return false, err
+	}
+	if err = pn.sync(); err != nil {
+		return false, err
+	}
+	_, wasPending = pn.entries[id]
+	pn.entries[id] = now
+	if !wasPending {
+		stats.VolumeServerPendingNeedlesGauge.Inc()
+	}
+	return wasPending, nil
+}
+
+// UnmarkPendingNeedle clears the pending state of a needle not written, or written unchanged.
+func (s *Store) UnmarkPendingNeedle(vid needle.VolumeId, id NeedleId) {
+	v := s.findVolume(vid)
+	if v == nil {
+		return
+	}
+	pn, err := v.pendingNeedles(false)
+	if err != nil || pn == nil {
+		return
+	}
+	pn.Lock()
+	defer pn.Unlock()
+	if _, found := pn.entries[id]; !found {
+		return
+	}
+	delete(pn.entries, id)
+	stats.VolumeServerPendingNeedlesGauge.Dec()
+	if err = pn.append(pendingOpRemove, id, time.Now().Unix()); err != nil {
+		glog.Errorf("unmark pending needle %d,%s: %v", vid, id, err)
+	}
+}
+
+// ConfirmPendingNeedles clears the pending state of the needles. It fails if a needle is
+// neither pending nor stored, e.g. already deleted for not being confirmed within the ttl.
+func (s *Store) ConfirmPendingNeedles(fileIds []*needle.FileId) error {
+	var missing []string
+	synced := make(map[*pendingNeedles]bool)
+	for _, fid := range fileIds {
+		var pn *pendingNeedles
+		if v := s.findVolume(fid.VolumeId); v != nil {
+			var err error
+			if pn, err = v.pendingNeedles(false); err != nil {
+				return err
+			}
+		}
+		if pn != nil {
+			pn.Lock()
+			_, found := pn.entries[fid.Key]
+			if found {
+				if err := pn.append(pendingOpRemove, fid.Key, time.Now().Unix()); err != nil {
+					pn.Unlock()
+					return err
+				}
+				delete(pn.entries, fid.Key)
+				stats.VolumeServerPendingNeedlesGauge.Dec()
+				synced[pn] = false
+			}
+			pn.Unlock()
+			if found {
+				continue
+			}
+		}
+		// confirmed before, or written unchanged
+		if _, err := s.HeadVolumeNeedle(fid.VolumeId, fid.Key); err != nil {
+			missing = append(missing, fid.String())
+		}
+	}
+	for pn := range synced {
+		pn.Lock()
+		err := pn.sync()
+		pn.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("not stored: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkPendingNeedles deletes the needles of the volume not confirmed within the ttl, and compacts its log.
+func (s *Store) checkPendingNeedles(v *Volume, now time.Time) {
+	pn, err := v.pendingNeedles(false)
+	if err != nil {
+		glog.Errorf("open pending needles of volume %d: %v", v.Id, err)
+		return
+	}
+	if pn == nil {
+		return
+	}
+	s.pending.Lock()
+	cutoff := now.Add(-s.pending.ttl).Unix()
+	s.pending.Unlock()
+
+	var expired []NeedleId
+	pn.Lock()
+	for id, ts := range pn.entries {
+		if ts < cutoff {
+			expired = append(expired, id)
+		}
+	}
+	pn.Unlock()
+
+	for _, id := range expired {
+		// skip the needles confirmed in the meantime, and hold the lock so none is confirmed while deleting
+		pn.Lock()
+		ts, found := pn.entries[id]
+		if !found || ts >= cutoff {
+			pn.Unlock()
+			continue
+		}
+		if err = pn.append(pendingOpRemove, id, now.Unix()); err != nil {
+			glog.Errorf("expire pending needle %d,%s: %v", v.Id, id, err)
+			pn.Unlock()
+			return
+		}
+		delete(pn.entries, id)
+		stats.VolumeServerPendingNeedlesGauge.Dec()
+
+		if _, err := s.DeleteVolumeNeedle(v.Id, &needle.Needle{Id: id}); err != nil {
+			glog.Warningf("delete unconfirmed needle %d,%s: %v", v.Id, id, err)
+		} else {
+			glog.V(0).Infof("deleted unconfirmed needle %d,%s", v.Id, id)
+		}
+		pn.Unlock()
+	}
+
+	pn.Lock()
+	if pn.file != nil && pn.records-len(pn.entries) > pendingCompactRecords {
+		if err := pn.compact(); err != nil {
+			glog.Errorf("compact %s: %v", pn.fileName, err)
+		}
+	}
+	pn.Unlock()
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	. "github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+func TestPendingNeedlesLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pending")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "1"+PendingLogExt)
+	f, err := os.Create(fileName)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	pn := &pendingNeedles{fileName: fileName, file: f}
+	pn.append(pendingOpAdd, 10, 100)
+	pn.append(pendingOpAdd, 11, 101)
+	pn.append(pendingOpAdd, 20, 102)
+	pn.append(pendingOpRemove, 11, 103)
+	// a partially written record
+	f.Write([]byte{pendingOpAdd, 0, 0})
+	f.Close()
+
+	entries, err := loadPendingNeedles(fileName)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(entries) != 2 || entries[10] != 100 || entries[20] != 102 {
+		t.Errorf("unexpected pending needles %v", entries)
+	}
+}
+
+func TestPendingNeedlesConfirm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pending")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		t.Fatalf("volume creation: %v", err)
+	}
+	defer v.Close()
+	location := &DiskLocation{Directory: dir, volumes: map[needle.VolumeId]*Volume{1: v}}
+	s := &Store{Locations: []*DiskLocation{location}}
+	if err = s.EnablePendingNeedles(time.Hour); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	// the log is kept with the volume files
+	fileName := v.FileName() + PendingLogExt
+	if _, err = os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("log created before the first pending write: %v", err)
+	}
+
+	for _, id := range []NeedleId{10, 11} {
+		if wasPending, err := s.MarkPendingNeedle(1, id); err != nil || wasPending {
+			t.Fatalf("mark %d pending: %v %v", id, wasPending, err)
+		}
+	}
+	if wasPending, err := s.MarkPendingNeedle(1, 11); err != nil || !wasPending {
+		t.Errorf("mark 11 pending again: %v %v", wasPending, err)
+	}
+	if err = s.ConfirmPendingNeedles([]*needle.FileId{{VolumeId: 1, Key: 10}}); err != nil {
+		t.Errorf("confirm pending: %v", err)
+	}
+	// neither pending nor stored
+	if err = s.ConfirmPendingNeedles([]*needle.FileId{{VolumeId: 1, Key: 10}}); err == nil {
+		t.Errorf("confirmed a needle not stored")
+	}
+
+	// reopened from the log, as after moving the volume
+	v.closePendingNeedles()
+	pn, err := v.pendingNeedles(false)
+	if err != nil || pn == nil {
+		t.Fatalf("reopen: %v %v", pn, err)
+	}
+	if len(pn.entries) != 1 || pn.entries[11] == 0 {
+		t.Errorf("unexpected pending needles %v", pn.entries)
+	}
+
+	// expired needles are deleted
+	s.checkPendingNeedles(v, time.Now().Add(2*time.Hour))
+	if len(pn.entries) != 0 {
+		t.Errorf("expired needles kept %v", pn.entries)
+	}
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	. "github.com/chrislusf/seaweedfs/weed/storage/types"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+const (
+	recencyLogFileName     = "needle_recency.log"
+	recencyLogSaveInterval = time.Minute
+	recencyLogEntrySize    = 4 + NeedleIdSize
+)
+
+// recencyLog tracks the most recently accessed needles, to warm up the caches after a restart.
+// It is saved as a list of <volume id, needle id>, the most recent first.
+type recencyLog struct {
+	sync.Mutex
+	limit   int
+	entries *list.List
+	index   map[recentWriteKey]*list.Element
+}
+
+func newRecencyLog(limit int) *recencyLog {
+	return &recencyLog{
+		limit:   limit,
+		entries: list.New(),
+		index:   make(map[recentWriteKey]*list.Element),
+	}
+}
+
+func (rl *recencyLog) Touch(vid needle.VolumeId, id NeedleId) {
+	key := recentWriteKey{vid, id}
+
+	rl.Lock()
+	defer rl.Unlock()
+
+	if elem, found := rl.index[key]; found {
+		rl.entries.MoveToFront(elem)
+		return
+	}
+	rl.index[key] = rl.entries.PushFront(key)
+	for rl.entries.Len() > rl.limit {
+		back := rl.entries.Back()
+		rl.entries.Remove(back)
+		delete(rl.index, back.Value.(recentWriteKey))
+	}
+}
+
+func (rl *recencyLog) Bytes() []byte {
+	rl.Lock()
+	defer rl.Unlock()
+
+	data := make([]byte, 0, rl.entries.Len()*recencyLogEntrySize)
+	entry := make([]byte, recencyLogEntrySize)
+	for elem := rl.entries.Front(); elem != nil; elem = elem.Next() {
+		key := elem.Value.(recentWriteKey)
+		util.Uint32toBytes(entry[0:4], uint32(key.vid))
+		NeedleIdToBytes(entry[4:], key.id)
+		data = append(data, entry...)
+	}
+	return data
+}
+
+func (rl *recencyLog) Save(fileName string) error {
+	tmpFileName := fileName + ".tmp"
+	if err := ioutil.WriteFile(tmpFileName, rl.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFileName, fileName)
+}
+
+func loadRecencyLog(fileName string) (keys []recentWriteKey, err error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+recencyLogEntrySize <= len(data); i += recencyLogEntrySize {
+		keys = append(keys, recentWriteKey{
+			vid: needle.VolumeId(util.BytesToUint32(data[i : i+4])),
+			id:  BytesToNeedleId(data[i+4 : i+recencyLogEntrySize]),
+		})
+	}
+	return keys, nil
+}
+
+func (s *Store) recencyLogFileName() string {
+	return filepath.Join(s.Locations[0].Directory, recencyLogFileName)
+}
+
+// EnablePrewarm starts tracking the recently accessed needles, and in the background reads
+// the needles accessed before the last shutdown into the cache, at most maxMBPS.
+func (s *Store) EnablePrewarm(maxMBPS int) {
+	if len(s.Locations) == 0 {
+		return
+	}
+	limit := 10000
+	if s.recentWrites != nil {
+		limit = s.recentWrites.limit
+	}
+	previous, err := loadRecencyLog(s.recencyLogFileName())
+	if err != nil && !os.IsNotExist(err) {
+		glog.Warningf("load %s: %v", s.recencyLogFileName(), err)
+	}
+	s.recency = newRecencyLog(limit)
+
+	go func() {
+		s.prewarm(previous, maxMBPS)
+		for range time.Tick(recencyLogSaveInterval) {
+			s.saveRecencyLog()
+		}
+	}()
+}
+
+func (s *Store) prewarm(keys []recentWriteKey, maxMBPS int) {
+	if len(keys) == 0 {
+		return
+	}
+	if s.recentWrites != nil {
+		keys = s.recentWrites.fitting(keys, s.findVolume)
+	}
+	start := time.Now()
+	throttler := util.NewWriteThrottler(int64(maxMBPS) * 1024 * 1024)
+	var count, bytes int
+	// read the least recent first, so the most recent ends at the front of the cache
+	for i := len(keys) - 1; i >= 0; i-- {
+		v := s.findVolume(keys[i].vid)
+		if v == nil {
+			continue
+		}
+		n := &needle.Needle{Id: keys[i].id}
+		size, err := v.readNeedle(n, nil)
+		if err != nil || size < 0 {
+			continue
+		}
+		if s.recentWrites != nil {
+			s.recentWrites.Put(keys[i].vid, n)
+		}
+		s.recency.Touch(keys[i].vid, keys[i].id)
+		count++
+		bytes += size
+		throttler.MaybeSlowdown(int64(size))
+	}
+	glog.V(0).Infof("prewarmed %d of %d recently accessed needles, %d bytes in %v", count, len(keys), bytes, time.Since(start))
+}
+
+func (s *Store) saveRecencyLog() {
+	if s.recency == nil {
+		return
+	}
+	if err := s.recency.Save(s.recencyLogFileName()); err != nil {
+		glog.Warningf("save %s: %v", s.recencyLogFileName(), err)
+	}
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	. "github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+func TestRecencyLogSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recency")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	rl := newRecencyLog(3)
+	rl.Touch(1, 10)
+	rl.Touch(1, 11)
+	rl.Touch(2, 20)
+	rl.Touch(1, 10) // moves to the front
+	rl.Touch(3, 30) // evicts 1,11
+
+	fileName := filepath.Join(dir, recencyLogFileName)
+	if err = rl.Save(fileName); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	keys, err := loadRecencyLog(fileName)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	expected := []recentWriteKey{{needle.VolumeId(3), NeedleId(30)}, {1, 10}, {2, 20}}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("entry %d: expected %v, got %v", i, expected[i], keys[i])
+		}
+	}
+}
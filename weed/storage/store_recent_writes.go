@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	. "github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+type recentWriteKey struct {
+	vid needle.VolumeId
+	id  NeedleId
+}
+
+type recentWriteEntry struct {
+	key recentWriteKey
+	n   *needle.Needle
+}
+
+// recentWrites keeps the last written needles in memory, so a read right after
+// a write does not depend on the data already being visible on disk.
+// It is limited both in number of needles and in bytes. Needles larger than
+// 1/8 of the byte limit are not kept, so a few large files do not evict the rest.
+type recentWrites struct {
+	sync.Mutex
+	limit    int
+	maxBytes int64
+	bytes    int64
+	entries  *list.List
+	index    map[recentWriteKey]*list.Element
+}
+
+func newRecentWrites(limit int, maxBytes int64) *recentWrites {
+	return &recentWrites{
+		limit:    limit,
+		maxBytes: maxBytes,
+		entries:  list.New(),
+		index:    make(map[recentWriteKey]*list.Element),
+	}
+}
+
+func (rw *recentWrites) Put(vid needle.VolumeId, n *needle.Needle) {
+	copied := *n
+	key := recentWriteKey{vid, n.Id}
+	size := int64(len(n.Data))
+
+	rw.Lock()
+	defer rw.Unlock()
+
+	if elem, found := rw.index[key]; found {
+		rw.removeElement(elem)
+	}
+	if size > rw.maxBytes/8 {
+		return
+	}
+	rw.index[key] = rw.entries.PushFront(&recentWriteEntry{key: key, n: &copied})
+	rw.bytes += size
+	for rw.entries.Len() > rw.limit || rw.bytes > rw.maxBytes {
+		rw.removeElement(rw.entries.Back())
+	}
+}
+
+// Get returns a copy of the needle, or nil if it is not cached or its ttl has expired.
+func (rw *recentWrites) Get(vid needle.VolumeId, id NeedleId) *needle.Needle {
+	rw.Lock()
+	defer rw.Unlock()
+
+	elem, found := rw.index[recentWriteKey{vid, id}]
+	if !found {
+		return nil
+	}
+	n := elem.Value.(*recentWriteEntry).n
+	if n.HasTtl() && n.HasLastModifiedDate() && n.Ttl.Minutes() > 0 &&
+		uint64(time.Now().Unix()) >= n.LastModified+uint64(n.Ttl.Minutes()*60) {
+		rw.removeElement(elem)
+		return nil
+	}
+	rw.entries.MoveToFront(elem)
+	copied := *n
+	return &copied
+}
+
+func (rw *recentWrites) Delete(vid needle.VolumeId, id NeedleId) {
+	rw.Lock()
+	defer rw.Unlock()
+
+	if elem, found := rw.index[recentWriteKey{vid, id}]; found {
+		rw.removeElement(elem)
+	}
+}
+
+func (rw *recentWrites) DeleteVolume(vid needle.VolumeId) {
+	rw.Lock()
+	defer rw.Unlock()
+
+	for elem := rw.entries.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*recentWriteEntry).key.vid == vid {
+			rw.removeElement(elem)
+		}
+		elem = next
+	}
+}
+
+// fitting returns the leading keys whose needles fit in the cache, by the sizes in the needle index.
+func (rw *recentWrites) fitting(keys []recentWriteKey, findVolume func(vid needle.VolumeId) *Volume) []recentWriteKey {
+	var bytes int64
+	for i, key := range keys {
+		if i >= rw.limit {
+			return keys[:i]
+		}
+		v := findVolume(key.vid)
+		if v == nil {
+			continue
+		}
+		size, err := v.headNeedle(key.id)
+		if err != nil || int64(size) > rw.maxBytes/8 {
+			continue
+		}
+		if bytes += int64(size); bytes > rw.maxBytes {
+			return keys[:i]
+		}
+	}
+	return keys
+}
+
+func (rw *recentWrites) removeElement(elem *list.Element) {
+	entry := elem.Value.(*recentWriteEntry)
+	rw.entries.Remove(elem)
+	delete(rw.index, entry.key)
+	rw.bytes -= int64(len(entry.n.Data))
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	. "github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+func TestRecentWritesEviction(t *testing.T) {
+
+	rw := newRecentWrites(2, 1024)
+
+	rw.Put(1, &needle.Needle{Id: 1, Cookie: 11, Data: []byte("one")})
+	rw.Put(1, &needle.Needle{Id: 2, Data: []byte("two")})
+	if n := rw.Get(1, 1); n == nil || string(n.Data) != "one" || n.Cookie != 11 {
+		t.Fatalf("needle 1: %+v", n)
+	}
+
+	// needle 2 is now the least recently used
+	rw.Put(2, &needle.Needle{Id: 1, Data: []byte("three")})
+	if n := rw.Get(1, 2); n != nil {
+		t.Errorf("needle 2 should be evicted")
+	}
+	if n := rw.Get(2, 1); n == nil || string(n.Data) != "three" {
+		t.Errorf("volume 2 needle 1: %+v", n)
+	}
+
+	rw.Delete(1, 1)
+	if n := rw.Get(1, 1); n != nil {
+		t.Errorf("needle 1 should be deleted")
+	}
+
+	rw.DeleteVolume(2)
+	if rw.entries.Len() != 0 || len(rw.index) != 0 {
+		t.Errorf("expected empty cache, got %d entries", rw.entries.Len())
+	}
+
+}
+
+func TestRecentWritesByteLimit(t *testing.T) {
+
+	rw := newRecentWrites(10, 80)
+
+	// larger than 1/8 of the limit, and replacing a cached needle removes it
+	rw.Put(1, &needle.Needle{Id: 1, Data: []byte("small")})
+	rw.Put(1, &needle.Needle{Id: 1, Data: make([]byte, 11)})
+	if n := rw.Get(1, 1); n != nil {
+		t.Errorf("large needle should not be cached")
+	}
+
+	for i := 1; i <= 9; i++ {
+		rw.Put(1, &needle.Needle{Id: NeedleId(i), Data: make([]byte, 10)})
+	}
+	if rw.entries.Len() != 8 || rw.bytes != 80 {
+		t.Errorf("expected 8 entries of 80 bytes, got %d entries of %d bytes", rw.entries.Len(), rw.bytes)
+	}
+	if n := rw.Get(1, 1); n != nil {
+		t.Errorf("needle 1 should be evicted")
+	}
+
+}
+
+func TestRecentWritesExpiredTtl(t *testing.T) {
+
+	rw := newRecentWrites(10, 1024)
+
+	ttl, _ := needle.ReadTTL("1m")
+	n := &needle.Needle{Id: NeedleId(1), Data: []byte("data"), Ttl: ttl, LastModified: 1}
+	n.SetHasTtl()
+	n.SetHasLastModifiedDate()
+	rw.Put(1, n)
+
+	if cached := rw.Get(1, 1); cached != nil {
+		t.Errorf("expired needle should not be served")
+	}
+
+}
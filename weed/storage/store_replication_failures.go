@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/volume_server_pb"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+)
+
+// replicationFailures counts the writes acknowledged before a replica got them, where the replica
+// failed later. These replicas miss needles, to be found and synced by "volume.check.disk".
+type replicationFailures struct {
+	sync.Mutex
+	failures map[replicationFailureKey]*volume_server_pb.ReplicationFailure
+}
+
+type replicationFailureKey struct {
+	vid     needle.VolumeId
+	replica string
+}
+
+func (s *Store) RecordReplicationFailure(vid needle.VolumeId, replica string, err error) {
+	stats.VolumeServerReplicationFailureCounter.Inc()
+	s.replicationFailures.Lock()
+	defer s.replicationFailures.Unlock()
+	if s.replicationFailures.failures == nil {
+		s.replicationFailures.failures = make(map[replicationFailureKey]*volume_server_pb.ReplicationFailure)
+	}
+	key := replicationFailureKey{vid, replica}
+	failure, found := s.replicationFailures.failures[key]
+	if !found {
+		failure = &volume_server_pb.ReplicationFailure{VolumeId: uint32(vid), Replica: replica}
+		s.replicationFailures.failures[key] = failure
+	}
+	failure.Count++
+	failure.LastFailedAtNs = time.Now().UnixNano()
+	failure.LastError = err.Error()
+}
+
+// ReplicationFailures lists the failures since the volume server started, by volume id.
+func (s *Store) ReplicationFailures() (failures []*volume_server_pb.ReplicationFailure) {
+	s.replicationFailures.Lock()
+	for _, failure := range s.replicationFailures.failures {
+		failures = append(failures, &volume_server_pb.ReplicationFailure{
+			VolumeId:       failure.VolumeId,
+			Replica:        failure.Replica,
+			Count:          failure.Count,
+			LastFailedAtNs: failure.LastFailedAtNs,
+			LastError:      failure.LastError,
+		})
+	}
+	s.replicationFailures.Unlock()
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].VolumeId != failures[j].VolumeId {
+			return failures[i].VolumeId < failures[j].VolumeId
+		}
+		return failures[i].Replica < failures[j].Replica
+	})
+	return
+}
@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/stats"
+	"github.com/chrislusf/seaweedfs/weed/storage/idx"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	. "github.com/chrislusf/seaweedfs/weed/storage/types"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// maxScrubFindings is how many of the most recent corrupted needles are kept in the scrub status
+const maxScrubFindings = 100
+
+var ErrorQuarantined = errors.New("quarantined, the data on disk is corrupted")
+
+// ScrubFinding is a needle found corrupted by scrubbing.
+type ScrubFinding struct {
+	VolumeId    needle.VolumeId
+	NeedleId    NeedleId
+	Offset      int64
+	Size        Size
+	Error       string
+	FoundAt     time.Time
+	Repaired    bool
+	RepairError string
+}
+
+// ScrubStatus is the progress of the running scrubbing, or the result of the last one.
+type ScrubStatus struct {
+	Running             bool
+	StartedAt           time.Time
+	FinishedAt          time.Time
+	VolumeCount         int
+	ScrubbedVolumeCount int
+	NeedleCount         int
+	CorruptedCount      int
+	RepairedCount       int
+	Findings            []ScrubFinding // the most recent last
+}
+
+// NeedleRepairer reads a healthy copy of the needle from another replica of the volume.
+type NeedleRepairer func(vid needle.VolumeId, key NeedleId) (blob []byte, size Size, err error)
+
+type scrubState struct {
+	sync.Mutex
+	status ScrubStatus
+}
+
+// EnableScrubbing reads all needles in all volumes at the scheduled times, to find silent
+// data corruption by the needle checksums. The reads are limited to maxMBPS.
+func (s *Store) EnableScrubbing(schedule *util.CronSchedule, maxMBPS int) {
+	go func() {
+		for {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				return
+			}
+			glog.V(1).Infof("next scrubbing at %v", next)
+			time.Sleep(time.Until(next))
+			if _, err := s.Scrub(maxMBPS, nil); err != nil {
+				glog.Warningf("scheduled scrubbing: %v", err)
+			}
+		}
+	}()
+}
+
+// StartScrubbing scrubs the volumes, or all volumes if empty, in the background.
+func (s *Store) StartScrubbing(maxMBPS int, vids []needle.VolumeId) error {
+	if !s.beginScrubbing(len(vids)) {
+		return fmt.Errorf("scrubbing is already running")
+	}
+	go s.scrubVolumes(maxMBPS, vids)
+	return nil
+}
+
+// Scrub checks the volumes, or all volumes if empty, once, and returns the number of corrupted needles.
+// The corrupted needles are quarantined, and repaired from the other replicas if possible.
+func (s *Store) Scrub(maxMBPS int, vids []needle.VolumeId) (corrupted int, err error) {
+	if !s.beginScrubbing(len(vids)) {
+		return 0, fmt.Errorf("scrubbing is already running")
+	}
+	return s.scrubVolumes(maxMBPS, vids), nil
+}
+
+func (s *Store) GetScrubStatus() ScrubStatus {
+	s.scrub.Lock()
+	defer s.scrub.Unlock()
+	status := s.scrub.status
+	status.Findings = append([]ScrubFinding(nil), s.scrub.status.Findings...)
+	return status
+}
+
+func (s *Store) beginScrubbing(volumeCount int) bool {
+	s.scrub.Lock()
+	defer s.scrub.Unlock()
+	if s.scrub.status.Running {
+		return false
+	}
+	s.scrub.status = ScrubStatus{
+		Running:   true,
+		StartedAt: time.Now(),
+		Findings:  s.scrub.status.Findings,
+	}
+	return true
+}
+
+func (s *Store) scrubVolumes(maxMBPS int, vids []needle.VolumeId) (corrupted int) {
+	start := time.Now()
+	throttler := util.NewWriteThrottler(int64(maxMBPS) * 1024 * 1024)
+
+	if len(vids) == 0 {
+		for _, location := range s.Locations {
+			location.volumesLock.RLock()
+			for vid := range location.volumes {
+				vids = append(vids, vid)
+			}
+			location.volumesLock.RUnlock()
+		}
+	}
+	s.updateScrubStatus(func(status *ScrubStatus) {
+		status.VolumeCount = len(vids)
+	})
+
+	var needleCount int
+	for _, vid := range vids {
+		v := s.findVolume(vid)
+		if v == nil || v.HasRemoteFile() {
+			s.updateScrubStatus(func(status *ScrubStatus) {
+				status.ScrubbedVolumeCount++
+			})
+			continue
+		}
+		count, errCount, err := v.scrub(throttler, func(finding ScrubFinding) {
+			if repairErr := s.repairNeedle(v, finding); repairErr != nil {
+				finding.RepairError = repairErr.Error()
+				glog.Warningf("repair volume %d needle %s: %v", v.Id, finding.NeedleId, repairErr)
+			} else {
+				finding.Repaired = true
+				glog.V(0).Infof("repaired volume %d needle %s from another replica", v.Id, finding.NeedleId)
+			}
+			s.updateScrubStatus(func(status *ScrubStatus) {
+				status.CorruptedCount++
+				if finding.Repaired {
+					status.RepairedCount++
+				}
+				status.Findings = append(status.Findings, finding)
+				if len(status.Findings) > maxScrubFindings {
+					status.Findings = status.Findings[len(status.Findings)-maxScrubFindings:]
+				}
+			})
+		})
+		if err != nil {
+			glog.Warningf("scrub volume %d: %v", vid, err)
+		}
+		needleCount += count
+		corrupted += errCount
+		s.updateScrubStatus(func(status *ScrubStatus) {
+			status.ScrubbedVolumeCount++
+			status.NeedleCount += count
+		})
+	}
+	s.updateScrubStatus(func(status *ScrubStatus) {
+		status.Running = false
+		status.FinishedAt = time.Now()
+	})
+	glog.V(0).Infof("scrubbed %d needles in %d volumes in %v, %d corrupted", needleCount, len(vids), time.Since(start), corrupted)
+	return
+}
+
+func (s *Store) updateScrubStatus(fn func(status *ScrubStatus)) {
+	s.scrub.Lock()
+	defer s.scrub.Unlock()
+	fn(&s.scrub.status)
+}
+
+// repairNeedle replaces the corrupted needle with a healthy copy from another replica.
+func (s *Store) repairNeedle(v *Volume, finding ScrubFinding) error {
+	if v.ReplicaPlacement == nil || v.ReplicaPlacement.GetCopyCount() < 2 {
+		return fmt.Errorf("no other replicas")
+	}
+	if s.NeedleRepairer == nil {
+		return fmt.Errorf("repairing is not enabled")
+	}
+	blob, size, err := s.NeedleRepairer(v.Id, finding.NeedleId)
+	if err != nil {
+		return err
+	}
+	if size != finding.Size {
+		return fmt.Errorf("the replica has size %d, expected %d", size, finding.Size)
+	}
+	return v.writeRepairedNeedle(finding.NeedleId, ToOffset(finding.Offset), size, blob)
+}
+
+// ReadNeedleBlob reads the needle, and verifies its checksum.
+func (s *Store) ReadNeedleBlob(vid needle.VolumeId, key NeedleId) (blob []byte, size Size, err error) {
+	v := s.findVolume(vid)
+	if v == nil {
+		return nil, 0, fmt.Errorf("volume %d not found", vid)
+	}
+	v.dataFileAccessLock.RLock()
+	nv, ok := v.nm.Get(key)
+	v.dataFileAccessLock.RUnlock()
+	if !ok || nv.Offset.IsZero() || !nv.Size.IsValid() {
+		return nil, 0, ErrorNotFound
+	}
+	if v.isQuarantined(key, nv.Offset) {
+		return nil, 0, ErrorQuarantined
+	}
+	blob, version, found, err := v.readLiveNeedleBlob(key, nv.Offset, nv.Size)
+	if !found {
+		return nil, 0, ErrorNotFound
+	}
+	if err == nil {
+		n := &needle.Needle{Id: key}
+		err = n.ReadBytes(blob, nv.Offset.ToAcutalOffset(), nv.Size, version)
+	}
+	return blob, nv.Size, err
+}
+
+// WriteNeedleBlob appends a needle copied from another replica, if this replica has never seen the needle.
+// A needle deleted on this replica is not brought back.
+func (s *Store) WriteNeedleBlob(vid needle.VolumeId, key NeedleId, size Size, blob []byte) error {
+	v := s.findVolume(vid)
+	if v == nil {
+		return fmt.Errorf("volume %d not found", vid)
+	}
+	return v.writeMissingNeedle(key, size, blob)
+}
+
+func (v *Volume) scrub(throttler *util.WriteThrottler, onCorrupted func(ScrubFinding)) (count, corrupted int, err error) {
+	indexFile, err := os.Open(v.FileName() + ".idx")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer indexFile.Close()
+
+	err = idx.WalkIndexFile(indexFile, func(key NeedleId, offset Offset, size Size) error {
+		if offset.IsZero() || !size.IsValid() {
+			return nil
+		}
+		blob, version, found, readErr := v.readLiveNeedleBlob(key, offset, size)
+		if !found {
+			// deleted, overwritten, or the volume is unloaded
+			return nil
+		}
+		count++
+		throttler.MaybeSlowdown(int64(len(blob)))
+		if readErr == nil {
+			n := &needle.Needle{Id: key}
+			readErr = n.ReadBytes(blob, offset.ToAcutalOffset(), size, version)
+		}
+		if readErr == needle.ErrorCRC {
+			corrupted++
+			stats.VolumeServerBitrotErrorsCounter.Inc()
+			glog.Errorf("bit rot in volume %d needle %s at offset %d size %d", v.Id, key, offset.ToAcutalOffset(), size)
+			raiseChecksumAlert(v.Id, key)
+			v.quarantine(key, offset)
+			if onCorrupted != nil {
+				onCorrupted(ScrubFinding{
+					VolumeId: v.Id,
+					NeedleId: key,
+					Offset:   offset.ToAcutalOffset(),
+					Size:     size,
+					Error:    readErr.Error(),
+					FoundAt:  time.Now(),
+				})
+			}
+		} else if readErr != nil {
+			glog.V(0).Infof("scrub volume %d needle %s at offset %d: %v", v.Id, key, offset.ToAcutalOffset(), readErr)
+		}
+		return nil
+	})
+	return
+}
+
+// readLiveNeedleBlob reads the needle if the index entry is still the current one.
+func (v *Volume) readLiveNeedleBlob(key NeedleId, offset Offset, size Size) (blob []byte, version needle.Version, found bool, err error) {
+	v.dataFileAccessLock.RLock()
+	defer v.dataFileAccessLock.RUnlock()
+
+	if v.nm == nil || v.DataBackend == nil {
+		return nil, 0, false, nil
+	}
+	nv, ok := v.nm.Get(key)
+	if !ok || nv.Offset != offset || nv.Size != size {
+		return nil, 0, false, nil
+	}
+	version = v.Version()
+	blob, err = needle.ReadNeedleBlob(v.DataBackend, offset.ToAcutalOffset(), size, version)
+	return blob, version, true, err
+}
+
+// writeRepairedNeedle appends the healthy copy of the corrupted needle, and points the index to it,
+// if the corrupted needle is still the current one.
+func (v *Volume) writeRepairedNeedle(key NeedleId, offset Offset, size Size, blob []byte) error {
+	n, err := parseReplicaNeedle(key, size, blob, v.Version())
+	if err != nil {
+		return err
+	}
+
+	v.dataFileAccessLock.Lock()
+	defer v.dataFileAccessLock.Unlock()
+
+	if v.nm == nil || v.DataBackend == nil {
+		return fmt.Errorf("volume %d is unloaded", v.Id)
+	}
+	nv, ok := v.nm.Get(key)
+	if !ok || nv.Offset != offset || nv.Size != size {
+		// overwritten or deleted since found corrupted
+		v.unquarantine(key)
+		return nil
+	}
+	if err = v.appendReplicaNeedle(n); err != nil {
+		return err
+	}
+	v.unquarantine(key)
+	return nil
+}
+
+// parseReplicaNeedle checks the needle copied from a replica is complete and has the expected id.
+func parseReplicaNeedle(key NeedleId, size Size, blob []byte, version needle.Version) (*needle.Needle, error) {
+	if !size.IsValid() {
+		return nil, fmt.Errorf("invalid needle size %d", size)
+	}
+	if int64(len(blob)) != needle.GetActualSize(size, version) {
+		return nil, fmt.Errorf("needle blob has %d bytes, expected %d", len(blob), needle.GetActualSize(size, version))
+	}
+	n := &needle.Needle{}
+	if err := n.ReadBytes(blob, 0, size, version); err != nil {
+		return nil, fmt.Errorf("the copy from the replica: %v", err)
+	}
+	if n.Id != key {
+		return nil, fmt.Errorf("the copy from the replica has needle id %s", n.Id)
+	}
+	return n, nil
+}
+
+// appendReplicaNeedle appends a needle copied from a replica, and points the index to it.
+// The append time is taken again, and kept after the last append, since the incremental copy
+// and the tailing look up the needles by binary search on the append time.
+// The caller holds the dataFileAccessLock.
+func (v *Volume) appendReplicaNeedle(n *needle.Needle) error {
+	if v.IsReadOnly() {
+		return fmt.Errorf("volume %d is read only", v.Id)
+	}
+	size := n.Size
+	n.AppendAtNs = uint64(time.Now().UnixNano())
+	if n.AppendAtNs <= v.lastAppendAtNs {
+		n.AppendAtNs = v.lastAppendAtNs + 1
+	}
+	offset, _, _, err := n.Append(v.DataBackend, v.Version())
+	if err != nil {
+		return fmt.Errorf("volume %d append needle: %v", v.Id, err)
+	}
+	if n.Size != size {
+		v.DataBackend.Truncate(int64(offset))
+		return fmt.Errorf("volume %d append needle %s: size %d, expected %d", v.Id, n.Id, n.Size, size)
+	}
+	v.lastAppendAtNs = n.AppendAtNs
+	if err = v.nm.Put(n.Id, ToOffset(int64(offset)), n.Size); err != nil {
+		return fmt.Errorf("volume %d index needle: %v", v.Id, err)
+	}
+	return nil
+}
+
+// quarantine refuses the reads of the needle at the offset, until it is repaired, overwritten or deleted.
+func (v *Volume) quarantine(key NeedleId, offset Offset) {
+	v.quarantineLock.Lock()
+	defer v.quarantineLock.Unlock()
+	if v.quarantined == nil {
+		v.quarantined = make(map[NeedleId]Offset)
+	}
+	v.quarantined[key] = offset
+}
+
+func (v *Volume) unquarantine(key NeedleId) {
+	v.quarantineLock.Lock()
+	defer v.quarantineLock.Unlock()
+	delete(v.quarantined, key)
+}
+
+func (v *Volume) isQuarantined(key NeedleId, offset Offset) bool {
+	v.quarantineLock.Lock()
+	defer v.quarantineLock.Unlock()
+	quarantinedOffset, found := v.quarantined[key]
+	return found && quarantinedOffset == offset
+}
+
+// writeMissingNeedle appends the needle and points the index to it, if the needle is not in the index yet.
+func (v *Volume) writeMissingNeedle(key NeedleId, size Size, blob []byte) error {
+	n, err := parseReplicaNeedle(key, size, blob, v.Version())
+	if err != nil {
+		return err
+	}
+
+	v.dataFileAccessLock.Lock()
+	defer v.dataFileAccessLock.Unlock()
+
+	if v.nm == nil || v.DataBackend == nil {
+		return fmt.Errorf("volume %d is unloaded", v.Id)
+	}
+	if _, found := v.nm.Get(key); found {
+		// written or deleted since found missing
+		return nil
+	}
+	return v.appendReplicaNeedle(n)
+}
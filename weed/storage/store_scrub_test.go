@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func TestScrubVolume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrub")
+	if err != nil {
+		t.Fatalf("temp dir creation: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		t.Fatalf("volume creation: %v", err)
+	}
+	defer v.Close()
+
+	var corruptOffset uint64
+	for i := uint64(1); i <= 3; i++ {
+		offset, _, _, err := v.writeNeedle2(newScrubTestNeedle(i), false)
+		if err != nil {
+			t.Fatalf("write needle: %v", err)
+		}
+		if i == 2 {
+			corruptOffset = offset
+		}
+	}
+	// overwritten and deleted needles are not checked
+	v.writeNeedle2(newScrubTestNeedle(3), false)
+	v.deleteNeedle2(newEmptyNeedle(1))
+
+	throttler := util.NewWriteThrottler(0)
+	if count, corrupted, err := v.scrub(throttler, nil); err != nil || count != 2 || corrupted != 0 {
+		t.Fatalf("scrub: %d needles, %d corrupted, %v", count, corrupted, err)
+	}
+	nv, _ := v.nm.Get(types.NeedleId(2))
+	healthyBlob, _, _, _ := v.readLiveNeedleBlob(types.NeedleId(2), nv.Offset, nv.Size)
+
+	// flip a byte in the data of the second needle
+	b := make([]byte, 1)
+	dataOffset := int64(corruptOffset) + types.NeedleHeaderSize + 4 + 10
+	v.DataBackend.ReadAt(b, dataOffset)
+	b[0] ^= 0xff
+	v.DataBackend.WriteAt(b, dataOffset)
+
+	var findings []ScrubFinding
+	if count, corrupted, err := v.scrub(throttler, func(finding ScrubFinding) {
+		findings = append(findings, finding)
+	}); err != nil || count != 2 || corrupted != 1 {
+		t.Errorf("scrub after corruption: %d needles, %d corrupted, %v", count, corrupted, err)
+	}
+	if len(findings) != 1 || findings[0].NeedleId != 2 || findings[0].Offset != int64(corruptOffset) {
+		t.Fatalf("findings: %+v", findings)
+	}
+	if _, err := v.readNeedle(newEmptyNeedle(2), nil); err != ErrorQuarantined {
+		t.Errorf("read corrupted needle: %v", err)
+	}
+
+	// repair with the copy from a healthy replica
+	if err := v.writeRepairedNeedle(types.NeedleId(2), nv.Offset, nv.Size, healthyBlob); err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	if _, err := v.readNeedle(newEmptyNeedle(2), nil); err != nil {
+		t.Errorf("read repaired needle: %v", err)
+	}
+	if count, corrupted, err := v.scrub(throttler, nil); err != nil || count != 2 || corrupted != 0 {
+		t.Errorf("scrub after repair: %d needles, %d corrupted, %v", count, corrupted, err)
+	}
+}
+
+func newScrubTestNeedle(id uint64) *needle.Needle {
+	n := newEmptyNeedle(id)
+	n.Data = make([]byte, 100)
+	rand.Read(n.Data)
+	n.Checksum = needle.NewCRC(n.Data)
+	return n
+}
+
+func TestRepairKeepsAppendOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrub")
+	if err != nil {
+		t.Fatalf("temp dir creation: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		t.Fatalf("volume creation: %v", err)
+	}
+	defer v.Close()
+
+	for i := uint64(1); i <= 3; i++ {
+		if _, _, _, err := v.writeNeedle2(newScrubTestNeedle(i), false); err != nil {
+			t.Fatalf("write needle: %v", err)
+		}
+	}
+	nv, _ := v.nm.Get(types.NeedleId(1))
+	healthyBlob, _, _, _ := v.readLiveNeedleBlob(types.NeedleId(1), nv.Offset, nv.Size)
+	lastNs := v.lastAppendAtNs
+
+	// the copy from the replica must be of the same needle
+	if err := v.writeRepairedNeedle(types.NeedleId(2), nv.Offset, nv.Size, healthyBlob); err == nil {
+		t.Errorf("repaired with the copy of another needle")
+	}
+	if err := v.writeRepairedNeedle(types.NeedleId(1), nv.Offset, nv.Size, healthyBlob[:len(healthyBlob)-1]); err == nil {
+		t.Errorf("repaired with a truncated copy")
+	}
+	v.noWriteOrDelete = true
+	if err := v.writeRepairedNeedle(types.NeedleId(1), nv.Offset, nv.Size, healthyBlob); err == nil {
+		t.Errorf("repaired a read only volume")
+	}
+	v.noWriteOrDelete = false
+
+	// the copy of the first needle is appended after the third one
+	if err := v.writeRepairedNeedle(types.NeedleId(1), nv.Offset, nv.Size, healthyBlob); err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	if v.lastAppendAtNs <= lastNs {
+		t.Errorf("last append at %d, not after %d", v.lastAppendAtNs, lastNs)
+	}
+	repaired, _ := v.nm.Get(types.NeedleId(1))
+	if repairedNs, err := v.readAppendAtNs(repaired.Offset); err != nil || repairedNs != v.lastAppendAtNs {
+		t.Errorf("repaired needle appended at %d, expected %d: %v", repairedNs, v.lastAppendAtNs, err)
+	}
+	if _, err := v.readNeedle(newEmptyNeedle(1), nil); err != nil {
+		t.Errorf("read repaired needle: %v", err)
+	}
+	if _, _, _, err := v.writeNeedle2(newScrubTestNeedle(4), false); err != nil {
+		t.Fatalf("write needle: %v", err)
+	}
+
+	// an incremental copy since the third needle gets the repaired needle and the fourth one
+	offset, isLast, err := v.BinarySearchByAppendAtNs(lastNs)
+	if err != nil || isLast || offset != repaired.Offset {
+		t.Errorf("search since %d: offset %v, expected %v, last %v: %v", lastNs, offset, repaired.Offset, isLast, err)
+	}
+	if _, isLast, err = v.BinarySearchByAppendAtNs(v.lastAppendAtNs); err != nil || !isLast {
+		t.Errorf("search since the last append: last %v: %v", isLast, err)
+	}
+}
+
+func TestSyncMissingNeedleKeepsAppendOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrub")
+	if err != nil {
+		t.Fatalf("temp dir creation: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(dir+"/replica", 0755)
+	os.Mkdir(dir+"/local", 0755)
+
+	replica, err := NewVolume(dir+"/replica", "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		t.Fatalf("volume creation: %v", err)
+	}
+	defer replica.Close()
+	v, err := NewVolume(dir+"/local", "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		t.Fatalf("volume creation: %v", err)
+	}
+	defer v.Close()
+
+	// the needle missing here was written earlier on the replica
+	if _, _, _, err := replica.writeNeedle2(newScrubTestNeedle(2), false); err != nil {
+		t.Fatalf("write needle: %v", err)
+	}
+	nv, _ := replica.nm.Get(types.NeedleId(2))
+	blob, _, _, _ := replica.readLiveNeedleBlob(types.NeedleId(2), nv.Offset, nv.Size)
+	if _, _, _, err := v.writeNeedle2(newScrubTestNeedle(1), false); err != nil {
+		t.Fatalf("write needle: %v", err)
+	}
+	lastNs := v.lastAppendAtNs
+
+	v.noWriteCanDelete = true
+	if err := v.writeMissingNeedle(types.NeedleId(2), nv.Size, blob); err == nil {
+		t.Errorf("synced to a read only volume")
+	}
+	v.noWriteCanDelete = false
+
+	if err := v.writeMissingNeedle(types.NeedleId(2), nv.Size, blob); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if v.lastAppendAtNs <= lastNs {
+		t.Errorf("last append at %d, not after %d", v.lastAppendAtNs, lastNs)
+	}
+	synced, _ := v.nm.Get(types.NeedleId(2))
+	offset, isLast, err := v.BinarySearchByAppendAtNs(lastNs)
+	if err != nil || isLast || offset != synced.Offset {
+		t.Errorf("search since %d: offset %v, expected %v, last %v: %v", lastNs, offset, synced.Offset, isLast, err)
+	}
+	if _, err := v.readNeedle(newEmptyNeedle(2), nil); err != nil {
+		t.Errorf("read synced needle: %v", err)
+	}
+}
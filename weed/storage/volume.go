@@ -5,6 +5,7 @@ import (
 	"path"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
@@ -38,13 +39,25 @@ type Volume struct {
 	lastModifiedTsSeconds uint64 // unix time in seconds
 	lastAppendAtNs        uint64 // unix time in nanoseconds
 
+	writtenBytes      uint64 // the needle data written, for the write rate in the heartbeats
+	lastWrittenBytes  uint64
+	lastWriteRateAtNs int64
+
 	lastCompactIndexOffset uint64
 	lastCompactRevision    uint16
+	compactCipher          *needleCipher // re-encrypts the compacted volume with the current key
 
 	isCompacting bool
 
 	volumeInfo *volume_server_pb.VolumeInfo
 	location   *DiskLocation
+	cipher     *needleCipher // nil if the needle data is not encrypted
+
+	quarantined    map[types.NeedleId]types.Offset // the corrupted needles found by scrubbing
+	quarantineLock sync.Mutex
+
+	pending     *pendingNeedles // nil until the pending needles log is opened
+	pendingLock sync.Mutex
 }
 
 func NewVolume(dirname string, collection string, id needle.VolumeId, needleMapKind NeedleMapType, replicaPlacement *super_block.ReplicaPlacement, ttl *needle.TTL, preallocate int64, memoryMapMaxSizeMb uint32) (v *Volume, e error) {
@@ -157,6 +170,7 @@ func (v *Volume) IndexFileSize() uint64 {
 
 // Close cleanly shuts down this volume
 func (v *Volume) Close() {
+	v.closePendingNeedles()
 	v.dataFileAccessLock.Lock()
 	defer v.dataFileAccessLock.Unlock()
 	if v.nm != nil {
@@ -166,7 +180,7 @@ func (v *Volume) Close() {
 	if v.DataBackend != nil {
 		_ = v.DataBackend.Close()
 		v.DataBackend = nil
-		stats.VolumeServerVolumeCounter.WithLabelValues(v.Collection, "volume").Dec()
+		stats.VolumeServerVolumeCounter.WithLabelValues(stats.CollectionLabel(v.Collection), "volume").Dec()
 	}
 }
 
@@ -246,6 +260,9 @@ func (v *Volume) ToVolumeInformationMessage() (types.NeedleId, *master_pb.Volume
 		Ttl:              v.Ttl.ToUint32(),
 		CompactRevision:  uint32(v.SuperBlock.CompactionRevision),
 		ModifiedAtSecond: modTime.Unix(),
+		DiskType:         v.DiskType(),
+
+		WriteBytesPerSecond: v.writeBytesPerSecond(),
 	}
 
 	volumeInfo.RemoteStorageName, volumeInfo.RemoteStorageKey = v.RemoteStorageNameKey()
@@ -253,6 +270,18 @@ func (v *Volume) ToVolumeInformationMessage() (types.NeedleId, *master_pb.Volume
 	return maxFileKey, volumeInfo
 }
 
+// writeBytesPerSecond is the write rate since it was called last time, i.e., since the last heartbeat.
+func (v *Volume) writeBytesPerSecond() uint64 {
+	now := time.Now().UnixNano()
+	written := atomic.LoadUint64(&v.writtenBytes)
+	lastWritten := atomic.SwapUint64(&v.lastWrittenBytes, written)
+	lastAt := atomic.SwapInt64(&v.lastWriteRateAtNs, now)
+	if lastAt == 0 || now <= lastAt {
+		return 0
+	}
+	return uint64(float64(written-lastWritten) / time.Duration(now-lastAt).Seconds())
+}
+
 func (v *Volume) RemoteStorageNameKey() (storageName, storageKey string) {
 	if v.volumeInfo == nil {
 		return
@@ -266,5 +295,5 @@ func (v *Volume) RemoteStorageNameKey() (storageName, storageKey string) {
 func (v *Volume) IsReadOnly() bool {
 	v.noWriteLock.RLock()
 	defer v.noWriteLock.RUnlock()
-	return v.noWriteOrDelete || v.noWriteCanDelete || v.location.isDiskSpaceLow
+	return v.noWriteOrDelete || v.noWriteCanDelete || (v.location != nil && v.location.isDiskSpaceLow)
 }
@@ -39,6 +39,10 @@ func CheckAndFixVolumeDataIntegrity(v *Volume, indexFile *os.File) (lastAppendAt
 		if err != nil {
 			glog.Warningf("CheckAndFixVolumeDataIntegrity truncate idx file %s from %d to %d: %v", indexFile.Name(), indexSize, healthyIndexSize, err)
 		}
+		if err == nil && healthyIndexSize == 0 {
+			// all indexed needles were partially written, keep only the super block
+			err = truncatePartialNeedle(v.DataBackend, int64(v.SuperBlock.BlockSize()))
+		}
 	}
 	return
 }
@@ -58,7 +62,14 @@ func doCheckAndFixVolumeData(v *Volume, indexFile *os.File, indexOffset int64) (
 			return lastAppendAtNs, fmt.Errorf("verifyNeedleIntegrity %s failed: %v", indexFile.Name(), err)
 		}
 	} else {
-		if lastAppendAtNs, err = verifyNeedleIntegrity(v.DataBackend, v.Version(), offset.ToAcutalOffset(), key, size); err != nil {
+		lastAppendAtNs, err = verifyNeedleIntegrity(v.DataBackend, v.Version(), offset.ToAcutalOffset(), key, size)
+		if err == needle.ErrorCRC {
+			// refuse to read it until scrubbing repairs it from a replica
+			glog.Warningf("volume %d needle %#x at %d fails CRC check, quarantined", v.Id, key, offset.ToAcutalOffset())
+			v.quarantine(key, offset)
+			return lastAppendAtNs, nil
+		}
+		if err != nil {
 			return lastAppendAtNs, err
 		}
 	}
@@ -110,18 +121,25 @@ func verifyNeedleIntegrity(datFile backend.BackendStorageFile, v needle.Version,
 		if err != nil {
 			return 0, fmt.Errorf("stat file %s: %v", datFile.Name(), err)
 		}
-		if fileSize == fileTailOffset {
-			return n.AppendAtNs, nil
+		if fileSize < fileTailOffset {
+			// the last needle was partially written, drop its index entry
+			glog.Warningf("data file %s has %d bytes, less than expected %d bytes!", datFile.Name(), fileSize, fileTailOffset)
+			return 0, io.EOF
+		}
+		// a needle with its full length but a wrong CRC may be corrupted instead of partially written, so it is kept
+		crcErr := n.ReadData(datFile, offset, size, v)
+		if crcErr != nil && crcErr != needle.ErrorCRC {
+			return n.AppendAtNs, fmt.Errorf("read data [%d,%d) : %v", offset, offset+int64(size), crcErr)
+		}
+		if n.Id != key {
+			return n.AppendAtNs, fmt.Errorf("index key %#x does not match needle's Id %#x", key, n.Id)
 		}
 		if fileSize > fileTailOffset {
-			glog.Warningf("Truncate %s from %d bytes to %d bytes!", datFile.Name(), fileSize, fileTailOffset)
-			err = datFile.Truncate(fileTailOffset)
-			if err == nil {
-				return n.AppendAtNs, nil
+			if err = truncatePartialNeedle(datFile, fileTailOffset); err != nil {
+				return n.AppendAtNs, err
 			}
-			return n.AppendAtNs, fmt.Errorf("truncate file %s: %v", datFile.Name(), err)
 		}
-		glog.Warningf("data file %s has %d bytes, less than expected %d bytes!", datFile.Name(), fileSize, fileTailOffset)
+		return n.AppendAtNs, crcErr
 	}
 	if err = n.ReadData(datFile, offset, size, v); err != nil {
 		return n.AppendAtNs, fmt.Errorf("read data [%d,%d) : %v", offset, offset+int64(size), err)
@@ -132,6 +150,22 @@ func verifyNeedleIntegrity(datFile backend.BackendStorageFile, v needle.Version,
 	return n.AppendAtNs, err
 }
 
+// truncatePartialNeedle removes the bytes after the last good needle boundary
+func truncatePartialNeedle(datFile backend.BackendStorageFile, goodSize int64) error {
+	fileSize, _, err := datFile.GetStat()
+	if err != nil {
+		return fmt.Errorf("stat file %s: %v", datFile.Name(), err)
+	}
+	if fileSize <= goodSize {
+		return nil
+	}
+	glog.Warningf("Truncate %s from %d bytes to %d bytes!", datFile.Name(), fileSize, goodSize)
+	if err = datFile.Truncate(goodSize); err != nil {
+		return fmt.Errorf("truncate file %s: %v", datFile.Name(), err)
+	}
+	return nil
+}
+
 func verifyDeletedNeedleIntegrity(datFile backend.BackendStorageFile, v needle.Version, key NeedleId) (lastAppendAtNs uint64, err error) {
 	n := new(needle.Needle)
 	size := n.DiskSize(v)
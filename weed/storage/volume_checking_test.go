@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle_map"
+	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+func TestRepairPartialLastNeedle(t *testing.T) {
+	dir, v, goodSize, last := writeThreeNeedles(t)
+	defer os.RemoveAll(dir)
+
+	// cut the last needle, as if it did not fully reach the disk
+	if err := os.Truncate(v.FileName()+".dat", last.Offset.ToAcutalOffset()+types.NeedleHeaderSize+4); err != nil {
+		t.Fatalf("truncate dat file: %v", err)
+	}
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, nil, nil, 0, 0)
+	if err != nil {
+		t.Fatalf("volume loading: %v", err)
+	}
+	defer v.Close()
+
+	if v.noWriteOrDelete {
+		t.Errorf("repaired volume should be writable")
+	}
+	if datSize, _, _ := v.FileStat(); int64(datSize) != goodSize {
+		t.Errorf("dat file size %d, expected %d", datSize, goodSize)
+	}
+	if _, err = v.readNeedle(newEmptyNeedle(3), nil); err != ErrorNotFound {
+		t.Errorf("partial needle should be dropped: %v", err)
+	}
+	if _, err = v.readNeedle(newEmptyNeedle(2), nil); err != nil {
+		t.Errorf("read needle 2: %v", err)
+	}
+}
+
+func TestQuarantineCorruptedLastNeedle(t *testing.T) {
+	dir, v, _, last := writeThreeNeedles(t)
+	defer os.RemoveAll(dir)
+	fullSize := last.Offset.ToAcutalOffset() + needle.GetActualSize(last.Size, v.Version())
+
+	// damage the content of the last needle, keeping its length
+	datFile, err := os.OpenFile(v.FileName()+".dat", os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("open dat file: %v", err)
+	}
+	datFile.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, last.Offset.ToAcutalOffset()+types.NeedleHeaderSize+4)
+	datFile.Close()
+
+	v, err = NewVolume(dir, "", 1, NeedleMapInMemory, nil, nil, 0, 0)
+	if err != nil {
+		t.Fatalf("volume loading: %v", err)
+	}
+	defer v.Close()
+
+	if v.noWriteOrDelete {
+		t.Errorf("volume should stay writable")
+	}
+	if datSize, _, _ := v.FileStat(); int64(datSize) != fullSize {
+		t.Errorf("dat file size %d, expected %d", datSize, fullSize)
+	}
+	if _, err = v.readNeedle(newEmptyNeedle(3), nil); err != ErrorQuarantined {
+		t.Errorf("corrupted needle should be quarantined: %v", err)
+	}
+}
+
+func writeThreeNeedles(t *testing.T) (dir string, v *Volume, goodSize int64, last *needle_map.NeedleValue) {
+	dir, err := ioutil.TempDir("", "repair")
+	if err != nil {
+		t.Fatalf("temp dir creation: %v", err)
+	}
+	v, err = NewVolume(dir, "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		t.Fatalf("volume creation: %v", err)
+	}
+	for i := uint64(1); i <= 3; i++ {
+		n := &needle.Needle{Id: types.Uint64ToNeedleId(i), Data: []byte("some data to be written")}
+		n.Checksum = needle.NewCRC(n.Data)
+		if _, _, _, err = v.writeNeedle2(n, false); err != nil {
+			t.Fatalf("write needle %d: %v", i, err)
+		}
+	}
+	second, _ := v.nm.Get(types.Uint64ToNeedleId(2))
+	goodSize = second.Offset.ToAcutalOffset() + needle.GetActualSize(second.Size, v.Version())
+	last, _ = v.nm.Get(types.Uint64ToNeedleId(3))
+	v.Close()
+	return
+}
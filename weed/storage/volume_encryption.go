@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/chrislusf/seaweedfs/weed/pb"
+	"github.com/chrislusf/seaweedfs/weed/security/kms"
+	"github.com/chrislusf/seaweedfs/weed/storage/erasure_coding"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle_map"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+// needleCipher encrypts the needle data of a volume with AES-256-GCM. The key id is kept in the .vif file
+// of the volume, and the key is looked up from the key manager configured in security.toml.
+// The checksum on disk is of the encrypted data, so the volumes can be checked and scrubbed without the key.
+type needleCipher struct {
+	keyId string
+	key   util.CipherKey
+}
+
+// newNeedleCipher returns nil for an empty key id, i.e. the volume is not encrypted.
+func newNeedleCipher(keyId string) (*needleCipher, error) {
+	if keyId == "" {
+		return nil, nil
+	}
+	key, err := kms.GetKey(keyId)
+	if err != nil {
+		return nil, err
+	}
+	return &needleCipher{keyId: keyId, key: key}, nil
+}
+
+func (c *needleCipher) KeyId() string {
+	if c == nil {
+		return ""
+	}
+	return c.keyId
+}
+
+// encrypt returns a copy of the needle with the data encrypted, to append to the volume.
+func (c *needleCipher) encrypt(n *needle.Needle) (*needle.Needle, error) {
+	if c == nil || len(n.Data) == 0 {
+		return n, nil
+	}
+	data, err := util.Encrypt(n.Data, c.key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt needle %s: %v", n.Id, err)
+	}
+	encrypted := *n
+	encrypted.Data = data
+	encrypted.Checksum = needle.NewCRC(data)
+	return &encrypted, nil
+}
+
+// decrypt decrypts the needle data read from the volume, and restores the checksum of the clear data.
+func (c *needleCipher) decrypt(n *needle.Needle) error {
+	if c == nil || len(n.Data) == 0 {
+		return nil
+	}
+	data, err := util.Decrypt(n.Data, c.key)
+	if err != nil {
+		return fmt.Errorf("decrypt needle %s with key %s: %v", n.Id, c.keyId, err)
+	}
+	n.Data, n.DataSize, n.Checksum = data, uint32(len(data)), needle.NewCRC(data)
+	return nil
+}
+
+// reencrypt decrypts the needle read with one cipher and encrypts it with another, for key rotation.
+func reencrypt(n *needle.Needle, from, to *needleCipher) (*needle.Needle, error) {
+	if from.KeyId() == to.KeyId() {
+		return n, nil
+	}
+	if err := from.decrypt(n); err != nil {
+		return nil, err
+	}
+	return to.encrypt(n)
+}
+
+// EncryptionKeyId returns the id of the key encrypting the needle data, or empty if not encrypted.
+func (v *Volume) EncryptionKeyId() string {
+	return v.cipher.KeyId()
+}
+
+// DecryptNeedle decrypts the needle data read directly from the volume data file.
+func (v *Volume) DecryptNeedle(n *needle.Needle) error {
+	return v.cipher.decrypt(n)
+}
+
+// SetEncryptionKeyId sets the key of an empty volume, e.g. to mirror the data of another volume server.
+func (v *Volume) SetEncryptionKeyId(keyId string) error {
+	if v.FileCount() > 0 {
+		return fmt.Errorf("volume %d is not empty", v.Id)
+	}
+	c, err := newNeedleCipher(keyId)
+	if err != nil {
+		return err
+	}
+	v.cipher = c
+	v.volumeInfo.EncryptionKeyId = keyId
+	return v.SaveVolumeInfo()
+}
+
+// prepareCompactCipher picks the key of the compacted volume: the current key if encryption is configured,
+// so vacuuming re-encrypts the volumes after the key is rotated, or else the key of the volume.
+func (v *Volume) prepareCompactCipher() (err error) {
+	if kms.CurrentKeyId() == "" || kms.CurrentKeyId() == v.cipher.KeyId() {
+		v.compactCipher = v.cipher
+		return nil
+	}
+	if v.compactCipher, err = newNeedleCipher(kms.CurrentKeyId()); err != nil {
+		return fmt.Errorf("volume %d: %v", v.Id, err)
+	}
+	return nil
+}
+
+// reencryptNeedleBlob re-encrypts a needle written during compaction with the key of the compacted volume,
+// and returns the new index entry for its size.
+func (v *Volume) reencryptNeedleBlob(blob []byte, key types.NeedleId, offset types.Offset, size types.Size) (newBlob, idxEntry []byte, err error) {
+	n := new(needle.Needle)
+	if err = n.ReadBytes(blob, offset.ToAcutalOffset(), size, v.Version()); err != nil {
+		return nil, nil, fmt.Errorf("read needle %s: %v", key, err)
+	}
+	if n, err = reencrypt(n, v.cipher, v.compactCipher); err != nil {
+		return nil, nil, err
+	}
+	if newBlob, err = n.ToBytes(v.Version()); err != nil {
+		return nil, nil, err
+	}
+	return newBlob, needle_map.ToBytes(key, offset, n.Size), nil
+}
+
+// NeedleDecrypter returns the function to decrypt the needles scanned from the volume data file,
+// for the tools working on the volume files directly.
+func NeedleDecrypter(dirname string, collection string, id needle.VolumeId) (func(n *needle.Needle) error, error) {
+	volumeInfo, _, err := pb.MaybeLoadVolumeInfo(VolumeFileName(dirname, collection, int(id)) + ".vif")
+	if err != nil {
+		return nil, err
+	}
+	c, err := newNeedleCipher(volumeInfo.EncryptionKeyId)
+	if err != nil {
+		return nil, fmt.Errorf("volume %d is encrypted: %v", id, err)
+	}
+	return c.decrypt, nil
+}
+
+// decryptEcNeedle decrypts the needle read from the ec shards of an encrypted volume.
+func decryptEcNeedle(ev *erasure_coding.EcVolume, n *needle.Needle) error {
+	c, err := newNeedleCipher(ev.EncryptionKeyId)
+	if err != nil {
+		return fmt.Errorf("ec volume %d is encrypted: %v", ev.VolumeId, err)
+	}
+	return c.decrypt(n)
+}
+
+// loadCipher loads the key of the volume, or sets the current key for a new volume.
+func (v *Volume) loadCipher(isNewVolume bool) (err error) {
+	if isNewVolume && v.volumeInfo.EncryptionKeyId != kms.CurrentKeyId() {
+		v.volumeInfo.EncryptionKeyId = kms.CurrentKeyId()
+		if err = v.SaveVolumeInfo(); err != nil {
+			return fmt.Errorf("save volume %d key: %v", v.Id, err)
+		}
+	}
+	if v.cipher, err = newNeedleCipher(v.volumeInfo.EncryptionKeyId); err != nil {
+		return fmt.Errorf("volume %d is encrypted: %v", v.Id, err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/chrislusf/seaweedfs/weed/security/kms"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+	"github.com/chrislusf/seaweedfs/weed/util"
+)
+
+func configureTestKeys(keyIds ...string) {
+	var keys []string
+	for _, keyId := range keyIds {
+		key := bytes.Repeat([]byte(keyId[len(keyId)-1:]), 32)
+		keys = append(keys, keyId+":"+base64.StdEncoding.EncodeToString(key))
+	}
+	config := viper.New()
+	config.Set("volume.encryption.local.enabled", true)
+	config.Set("volume.encryption.local.keys", keys)
+	kms.LoadConfiguration(config, "volume.encryption.")
+}
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "encryption")
+	if err != nil {
+		t.Fatalf("temp dir creation: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		kms.Keys = nil
+	}()
+
+	configureTestKeys("k1", "k2")
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		t.Fatalf("volume creation: %v", err)
+	}
+	if v.EncryptionKeyId() != "k1" {
+		t.Fatalf("new volume key %q, expected k1", v.EncryptionKeyId())
+	}
+
+	clear := &needle.Needle{Id: types.Uint64ToNeedleId(1000), Data: []byte("the clear text should not be found in the volume file")}
+	clear.Checksum = needle.NewCRC(clear.Data)
+	if _, _, _, err = v.writeNeedle2(clear, false); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	infos := make([]*needleInfo, 400)
+	for i := 1; i <= 200; i++ {
+		doSomeWritesDeletes(i, v, t, infos)
+	}
+
+	// rotate the key, and re-encrypt the volume by vacuuming, with some writes during the compaction
+	configureTestKeys("k2", "k1")
+	if err = v.Compact2(0, util.NewWriteThrottler(0)); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	for i := 201; i <= 400; i++ {
+		doSomeWritesDeletes(i, v, t, infos)
+	}
+	if err = v.CommitCompact(); err != nil {
+		t.Fatalf("commit compact: %v", err)
+	}
+	if v.EncryptionKeyId() != "k2" {
+		t.Fatalf("vacuumed volume key %q, expected k2", v.EncryptionKeyId())
+	}
+	v.Close()
+
+	datFile, _ := ioutil.ReadFile(v.FileName() + ".dat")
+	if bytes.Contains(datFile, clear.Data) {
+		t.Fatalf("found the clear data in the volume file")
+	}
+
+	// the older key is not needed any more
+	configureTestKeys("k2")
+	v, err = NewVolume(dir, "", 1, NeedleMapInMemory, nil, nil, 0, 0)
+	if err != nil {
+		t.Fatalf("volume reloading: %v", err)
+	}
+	defer v.Close()
+
+	n := &needle.Needle{Id: clear.Id}
+	if _, err = v.readNeedle(n, nil); err != nil || !bytes.Equal(n.Data, clear.Data) || n.Checksum != clear.Checksum {
+		t.Fatalf("read the clear data: %v %q", err, n.Data)
+	}
+	for i, info := range infos {
+		if info.size == 0 {
+			continue
+		}
+		n := newEmptyNeedle(uint64(i + 1))
+		size, err := v.readNeedle(n, nil)
+		if err != nil {
+			t.Fatalf("read file %d: %v", i+1, err)
+		}
+		if info.size != types.Size(size) || info.crc != n.Checksum {
+			t.Fatalf("read file %d: size %d checksum %v, expected %d %v", i+1, size, n.Checksum, info.size, info.crc)
+		}
+	}
+
+	// the checksum on disk is of the encrypted data, so the volume can be scrubbed without the key
+	kms.Keys = nil
+	if _, corrupted, err := v.scrub(util.NewWriteThrottler(0), nil); err != nil || corrupted != 0 {
+		t.Fatalf("scrub: %d corrupted, %v", corrupted, err)
+	}
+}
@@ -68,6 +68,13 @@ func (v *Volume) load(alsoLoadIndex bool, createDatIfMissing bool, needleMapKind
 		}
 	}
 
+	// the volumes loaded without index are only scanned for the raw needles, no need for the key
+	if alsoLoadIndex {
+		if err = v.loadCipher(!alreadyHasSuperBlock); err != nil {
+			return err
+		}
+	}
+
 	if alreadyHasSuperBlock {
 		err = v.readSuperBlock()
 	} else {
@@ -144,7 +151,7 @@ func (v *Volume) load(alsoLoadIndex bool, createDatIfMissing bool, needleMapKind
 		v.SaveVolumeInfo()
 	}
 
-	stats.VolumeServerVolumeCounter.WithLabelValues(v.Collection, "volume").Inc()
+	stats.VolumeServerVolumeCounter.WithLabelValues(stats.CollectionLabel(v.Collection), "volume").Inc()
 
 	return err
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
@@ -28,8 +29,15 @@ func (v *Volume) isFileUnchanged(n *needle.Needle) bool {
 
 	nv, ok := v.nm.Get(n.Id)
 	if ok && !nv.Offset.IsZero() && nv.Size.IsValid() {
+		if len(n.Data) > 0 && nv.Size < Size(len(n.Data)) {
+			// the stored needle is too small to hold the same data, no need to read it
+			return false
+		}
 		oldNeedle := new(needle.Needle)
 		err := oldNeedle.ReadData(v.DataBackend, nv.Offset.ToAcutalOffset(), nv.Size, v.Version())
+		if err == nil {
+			err = v.cipher.decrypt(oldNeedle)
+		}
 		if err != nil {
 			glog.V(0).Infof("Failed to check updated file at offset %d size %d: %v", nv.Offset.ToAcutalOffset(), nv.Size, err)
 			return false
@@ -69,6 +77,7 @@ func removeVolumeFiles(filename string) {
 	os.Remove(filename + ".cpx")
 	os.RemoveAll(filename + ".ldb")
 	os.Remove(filename + ".note")
+	os.Remove(filename + PendingLogExt)
 }
 
 func (v *Volume) asyncRequestAppend(request *needle.AsyncRequest) {
@@ -109,9 +118,15 @@ func (v *Volume) syncWrite(n *needle.Needle) (offset uint64, size Size, isUnchan
 
 	// append to dat file
 	n.AppendAtNs = uint64(time.Now().UnixNano())
-	if offset, size, _, err = n.Append(v.DataBackend, v.Version()); err != nil {
+	toAppend, err := v.cipher.encrypt(n)
+	if err != nil {
 		return
 	}
+	if offset, _, _, err = toAppend.Append(v.DataBackend, v.Version()); err != nil {
+		return
+	}
+	n.Size, size = toAppend.Size, Size(len(n.Data))
+	atomic.AddUint64(&v.writtenBytes, uint64(len(n.Data)))
 
 	v.lastAppendAtNs = n.AppendAtNs
 
@@ -148,6 +163,17 @@ func (v *Volume) writeNeedle2(n *needle.Needle, fsync bool) (offset uint64, size
 	}
 }
 
+// WriteTailedNeedle replays one needle tailed from another copy of this volume.
+// Deletions are appended as needles without data, and are replayed as deletes.
+func (v *Volume) WriteTailedNeedle(n *needle.Needle) (err error) {
+	if n.Size == 0 {
+		_, err = v.deleteNeedle2(n)
+		return
+	}
+	_, _, _, err = v.writeNeedle2(n, false)
+	return
+}
+
 func (v *Volume) doWriteRequest(n *needle.Needle) (offset uint64, size Size, isUnchanged bool, err error) {
 	// glog.V(4).Infof("writing needle %s", needle.NewFileIdFromNeedle(v.Id, n).String())
 	if v.isFileUnchanged(n) {
@@ -173,9 +199,15 @@ func (v *Volume) doWriteRequest(n *needle.Needle) (offset uint64, size Size, isU
 
 	// append to dat file
 	n.AppendAtNs = uint64(time.Now().UnixNano())
-	if offset, size, _, err = n.Append(v.DataBackend, v.Version()); err != nil {
+	toAppend, err := v.cipher.encrypt(n)
+	if err != nil {
 		return
 	}
+	if offset, _, _, err = toAppend.Append(v.DataBackend, v.Version()); err != nil {
+		return
+	}
+	n.Size, size = toAppend.Size, Size(len(n.Data))
+	atomic.AddUint64(&v.writtenBytes, uint64(len(n.Data)))
 	v.lastAppendAtNs = n.AppendAtNs
 
 	// add to needle map
@@ -279,10 +311,16 @@ func (v *Volume) readNeedle(n *needle.Needle, readOption *ReadOption) (int, erro
 	if readSize == 0 {
 		return 0, nil
 	}
+	if v.isQuarantined(n.Id, nv.Offset) {
+		return 0, ErrorQuarantined
+	}
 	err := n.ReadData(v.DataBackend, nv.Offset.ToAcutalOffset(), readSize, v.Version())
 	if err == needle.ErrorSizeMismatch && OffsetSize == 4 {
 		err = n.ReadData(v.DataBackend, nv.Offset.ToAcutalOffset()+int64(MaxPossibleVolumeSize), readSize, v.Version())
 	}
+	if err == nil {
+		err = v.cipher.decrypt(n)
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -303,6 +341,25 @@ func (v *Volume) readNeedle(n *needle.Needle, readOption *ReadOption) (int, erro
 	return -1, ErrorNotFound
 }
 
+// headNeedle looks up the needle size from the needle map only, without reading the data file.
+// Unlike readNeedle, it does not verify the cookie or the ttl expiration.
+func (v *Volume) headNeedle(id NeedleId) (Size, error) {
+	v.dataFileAccessLock.RLock()
+	defer v.dataFileAccessLock.RUnlock()
+
+	if v.nm == nil {
+		return 0, ErrorNotFound
+	}
+	nv, ok := v.nm.Get(id)
+	if !ok || nv.Offset.IsZero() {
+		return 0, ErrorNotFound
+	}
+	if nv.Size.IsDeleted() {
+		return 0, ErrorDeleted
+	}
+	return nv.Size, nil
+}
+
 func (v *Volume) startWorker() {
 	go func() {
 		chanClosed := false
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chrislusf/seaweedfs/weed/storage/backend"
+	"github.com/chrislusf/seaweedfs/weed/storage/needle"
+	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
+	"github.com/chrislusf/seaweedfs/weed/storage/types"
+)
+
+func TestHeadNeedle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "example")
+	if err != nil {
+		t.Fatalf("temp dir creation: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		t.Fatalf("volume creation: %v", err)
+	}
+	defer v.Close()
+
+	n := newRandomNeedle(1)
+	if _, _, _, err = v.writeNeedle2(n, false); err != nil {
+		t.Fatalf("write needle: %v", err)
+	}
+
+	headSize, err := v.headNeedle(n.Id)
+	if err != nil || headSize != n.Size {
+		t.Errorf("head needle: size %d, expected %d, err %v", headSize, n.Size, err)
+	}
+
+	if _, err = v.headNeedle(types.Uint64ToNeedleId(2)); err != ErrorNotFound {
+		t.Errorf("head missing needle: %v", err)
+	}
+
+	v.deleteNeedle2(newEmptyNeedle(1))
+	if _, err = v.headNeedle(n.Id); err != ErrorDeleted {
+		t.Errorf("head deleted needle: %v", err)
+	}
+
+	v.Close()
+	if _, err = v.headNeedle(n.Id); err != ErrorNotFound {
+		t.Errorf("head needle of closed volume: %v", err)
+	}
+}
+
+func BenchmarkHeadNeedle(b *testing.B) {
+	benchmarkNeedleLookup(b, func(v *Volume, id types.NeedleId) error {
+		_, err := v.headNeedle(id)
+		return err
+	})
+}
+
+func BenchmarkReadNeedle(b *testing.B) {
+	benchmarkNeedleLookup(b, func(v *Volume, id types.NeedleId) error {
+		_, err := v.readNeedle(newEmptyNeedle(uint64(id)), nil)
+		return err
+	})
+}
+
+// the 80% HEAD workload, with the HEAD requests answered by reading the needles, or from the index
+func BenchmarkMixedHeadByRead(b *testing.B) {
+	benchmarkMixedHead(b, func(v *Volume, id types.NeedleId) error {
+		_, err := v.readNeedle(newEmptyNeedle(uint64(id)), nil)
+		return err
+	})
+}
+
+func BenchmarkMixedHeadByIndex(b *testing.B) {
+	benchmarkMixedHead(b, func(v *Volume, id types.NeedleId) error {
+		_, err := v.headNeedle(id)
+		return err
+	})
+}
+
+func benchmarkMixedHead(b *testing.B, headFn func(v *Volume, id types.NeedleId) error) {
+	var dataFile *readCountingFile
+	benchmarkNeedleLookup(b, func(v *Volume, id types.NeedleId) error {
+		if dataFile == nil {
+			dataFile = &readCountingFile{BackendStorageFile: v.DataBackend}
+			v.DataBackend = dataFile
+		}
+		if id%5 == 0 {
+			_, err := v.readNeedle(newEmptyNeedle(uint64(id)), nil)
+			return err
+		}
+		return headFn(v, id)
+	})
+	b.ReportMetric(float64(atomic.LoadInt64(&dataFile.reads))/float64(b.N), "reads/op")
+}
+
+type readCountingFile struct {
+	backend.BackendStorageFile
+	reads int64
+}
+
+func (f *readCountingFile) ReadAt(p []byte, off int64) (int, error) {
+	atomic.AddInt64(&f.reads, 1)
+	return f.BackendStorageFile.ReadAt(p, off)
+}
+
+func benchmarkNeedleLookup(b *testing.B, lookupFn func(v *Volume, id types.NeedleId) error) {
+	dir, err := ioutil.TempDir("", "example")
+	if err != nil {
+		b.Fatalf("temp dir creation: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := NewVolume(dir, "", 1, NeedleMapInMemory, &super_block.ReplicaPlacement{}, &needle.TTL{}, 0, 0)
+	if err != nil {
+		b.Fatalf("volume creation: %v", err)
+	}
+	defer v.Close()
+
+	const needleCount = 1000
+	for i := 1; i <= needleCount; i++ {
+		if _, _, _, err := v.writeNeedle2(newRandomNeedle(uint64(i)), false); err != nil {
+			b.Fatalf("write needle %d: %v", i, err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := lookupFn(v, types.Uint64ToNeedleId(uint64(i%needleCount+1))); err != nil {
+			b.Fatalf("lookup needle: %v", err)
+		}
+	}
+}
@@ -34,7 +34,7 @@ func (v *Volume) garbageLevel() float64 {
 }
 
 // compact a volume based on deletions in .dat files
-func (v *Volume) Compact(preallocate int64, compactionBytePerSecond int64) error {
+func (v *Volume) Compact(preallocate int64, writeThrottler util.Throttler) error {
 
 	if v.MemoryMapMaxSizeMb != 0 { //it makes no sense to compact in memory
 		return nil
@@ -52,6 +52,9 @@ func (v *Volume) Compact(preallocate int64, compactionBytePerSecond int64) error
 	filePath := v.FileName()
 	v.lastCompactIndexOffset = v.IndexFileSize()
 	v.lastCompactRevision = v.SuperBlock.CompactionRevision
+	if err := v.prepareCompactCipher(); err != nil {
+		return err
+	}
 	glog.V(3).Infof("creating copies for volume %d ,last offset %d...", v.Id, v.lastCompactIndexOffset)
 	if err := v.DataBackend.Sync(); err != nil {
 		glog.V(0).Infof("compact fail to sync volume %d", v.Id)
@@ -59,11 +62,11 @@ func (v *Volume) Compact(preallocate int64, compactionBytePerSecond int64) error
 	if err := v.nm.Sync(); err != nil {
 		glog.V(0).Infof("compact fail to sync volume idx %d", v.Id)
 	}
-	return v.copyDataAndGenerateIndexFile(filePath+".cpd", filePath+".cpx", preallocate, compactionBytePerSecond)
+	return v.copyDataAndGenerateIndexFile(filePath+".cpd", filePath+".cpx", preallocate, writeThrottler)
 }
 
 // compact a volume based on deletions in .idx files
-func (v *Volume) Compact2(preallocate int64, compactionBytePerSecond int64) error {
+func (v *Volume) Compact2(preallocate int64, writeThrottler util.Throttler) error {
 
 	if v.MemoryMapMaxSizeMb != 0 { //it makes no sense to compact in memory
 		return nil
@@ -78,6 +81,9 @@ func (v *Volume) Compact2(preallocate int64, compactionBytePerSecond int64) erro
 	filePath := v.FileName()
 	v.lastCompactIndexOffset = v.IndexFileSize()
 	v.lastCompactRevision = v.SuperBlock.CompactionRevision
+	if err := v.prepareCompactCipher(); err != nil {
+		return err
+	}
 	glog.V(3).Infof("creating copies for volume %d ...", v.Id)
 	if err := v.DataBackend.Sync(); err != nil {
 		glog.V(0).Infof("compact2 fail to sync volume dat %d: %v", v.Id, err)
@@ -85,7 +91,7 @@ func (v *Volume) Compact2(preallocate int64, compactionBytePerSecond int64) erro
 	if err := v.nm.Sync(); err != nil {
 		glog.V(0).Infof("compact2 fail to sync volume idx %d: %v", v.Id, err)
 	}
-	return copyDataBasedOnIndexFile(filePath+".dat", filePath+".idx", filePath+".cpd", filePath+".cpx", v.SuperBlock, v.Version(), preallocate, compactionBytePerSecond)
+	return copyDataBasedOnIndexFile(filePath+".dat", filePath+".idx", filePath+".cpd", filePath+".cpx", v.SuperBlock, v.Version(), preallocate, writeThrottler, v.cipher, v.compactCipher)
 }
 
 func (v *Volume) CommitCompact() error {
@@ -110,7 +116,7 @@ func (v *Volume) CommitCompact() error {
 		}
 	}
 	v.DataBackend = nil
-	stats.VolumeServerVolumeCounter.WithLabelValues(v.Collection, "volume").Dec()
+	stats.VolumeServerVolumeCounter.WithLabelValues(stats.CollectionLabel(v.Collection), "volume").Dec()
 
 	var e error
 	if e = v.makeupDiff(v.FileName()+".cpd", v.FileName()+".cpx", v.FileName()+".dat", v.FileName()+".idx"); e != nil {
@@ -141,7 +147,15 @@ func (v *Volume) CommitCompact() error {
 		if e = os.Rename(v.FileName()+".cpx", v.FileName()+".idx"); e != nil {
 			return fmt.Errorf("rename %s: %v", v.FileName()+".cpx", e)
 		}
+		if v.compactCipher != nil && v.compactCipher.KeyId() != v.cipher.KeyId() {
+			glog.V(0).Infof("volume %d is re-encrypted with key %s", v.Id, v.compactCipher.KeyId())
+			v.volumeInfo.EncryptionKeyId = v.compactCipher.KeyId()
+			if e = v.SaveVolumeInfo(); e != nil {
+				return fmt.Errorf("save volume %d key: %v", v.Id, e)
+			}
+		}
 	}
+	v.compactCipher = nil
 
 	//glog.V(3).Infof("Pretending to be vacuuming...")
 	//time.Sleep(20 * time.Second)
@@ -282,6 +296,11 @@ func (v *Volume) makeupDiff(newDatFileName, newIdxFileName, oldDatFileName, oldI
 			if err != nil {
 				return fmt.Errorf("ReadNeedleBlob %s key %d offset %d size %d failed: %v", oldDatFile.Name(), key, increIdxEntry.offset.ToAcutalOffset(), increIdxEntry.size, err)
 			}
+			if v.cipher.KeyId() != v.compactCipher.KeyId() {
+				if needleBytes, idxEntryBytes, err = v.reencryptNeedleBlob(needleBytes, key, increIdxEntry.offset, increIdxEntry.size); err != nil {
+					return err
+				}
+			}
 			dst.Write(needleBytes)
 			util.Uint32toBytes(idxEntryBytes[8:12], uint32(offset/NeedlePaddingSize))
 		} else { //deleted needle
@@ -314,7 +333,7 @@ type VolumeFileScanner4Vacuum struct {
 	nm             *needle_map.MemDb
 	newOffset      int64
 	now            uint64
-	writeThrottler *util.WriteThrottler
+	writeThrottler util.Throttler
 }
 
 func (scanner *VolumeFileScanner4Vacuum) VisitSuperBlock(superBlock super_block.SuperBlock) error {
@@ -336,12 +355,16 @@ func (scanner *VolumeFileScanner4Vacuum) VisitNeedle(n *needle.Needle, offset in
 	nv, ok := scanner.v.nm.Get(n.Id)
 	glog.V(4).Infoln("needle expected offset ", offset, "ok", ok, "nv", nv)
 	if ok && nv.Offset.ToAcutalOffset() == offset && nv.Size > 0 && nv.Size.IsValid() {
-		if err := scanner.nm.Set(n.Id, ToOffset(scanner.newOffset), n.Size); err != nil {
-			return fmt.Errorf("cannot put needle: %s", err)
+		n, err := reencrypt(n, scanner.v.cipher, scanner.v.compactCipher)
+		if err != nil {
+			return err
 		}
 		if _, _, _, err := n.Append(scanner.dstBackend, scanner.v.Version()); err != nil {
 			return fmt.Errorf("cannot append needle: %s", err)
 		}
+		if err := scanner.nm.Set(n.Id, ToOffset(scanner.newOffset), n.Size); err != nil {
+			return fmt.Errorf("cannot put needle: %s", err)
+		}
 		delta := n.DiskSize(scanner.version)
 		scanner.newOffset += delta
 		scanner.writeThrottler.MaybeSlowdown(delta)
@@ -350,7 +373,7 @@ func (scanner *VolumeFileScanner4Vacuum) VisitNeedle(n *needle.Needle, offset in
 	return nil
 }
 
-func (v *Volume) copyDataAndGenerateIndexFile(dstName, idxName string, preallocate int64, compactionBytePerSecond int64) (err error) {
+func (v *Volume) copyDataAndGenerateIndexFile(dstName, idxName string, preallocate int64, writeThrottler util.Throttler) (err error) {
 	var (
 		dst backend.BackendStorageFile
 	)
@@ -367,7 +390,7 @@ func (v *Volume) copyDataAndGenerateIndexFile(dstName, idxName string, prealloca
 		now:            uint64(time.Now().Unix()),
 		nm:             nm,
 		dstBackend:     dst,
-		writeThrottler: util.NewWriteThrottler(compactionBytePerSecond),
+		writeThrottler: writeThrottler,
 	}
 	err = ScanVolumeFile(v.dir, v.Collection, v.Id, v.needleMapKind, scanner)
 	if err != nil {
@@ -378,7 +401,7 @@ func (v *Volume) copyDataAndGenerateIndexFile(dstName, idxName string, prealloca
 	return
 }
 
-func copyDataBasedOnIndexFile(srcDatName, srcIdxName, dstDatName, datIdxName string, sb super_block.SuperBlock, version needle.Version, preallocate int64, compactionBytePerSecond int64) (err error) {
+func copyDataBasedOnIndexFile(srcDatName, srcIdxName, dstDatName, datIdxName string, sb super_block.SuperBlock, version needle.Version, preallocate int64, writeThrottler util.Throttler, srcCipher, dstCipher *needleCipher) (err error) {
 	var (
 		srcDatBackend, dstDatBackend backend.BackendStorageFile
 		dataFile                     *os.File
@@ -407,8 +430,6 @@ func copyDataBasedOnIndexFile(srcDatName, srcIdxName, dstDatName, datIdxName str
 	dstDatBackend.WriteAt(sb.Bytes(), 0)
 	newOffset := int64(sb.BlockSize())
 
-	writeThrottler := util.NewWriteThrottler(compactionBytePerSecond)
-
 	oldNm.AscendingVisit(func(value needle_map.NeedleValue) error {
 
 		offset, size := value.Offset, value.Size
@@ -427,12 +448,15 @@ func copyDataBasedOnIndexFile(srcDatName, srcIdxName, dstDatName, datIdxName str
 			return nil
 		}
 
-		if err = newNm.Set(n.Id, ToOffset(newOffset), n.Size); err != nil {
-			return fmt.Errorf("cannot put needle: %s", err)
+		if n, err = reencrypt(n, srcCipher, dstCipher); err != nil {
+			return err
 		}
 		if _, _, _, err = n.Append(dstDatBackend, sb.Version); err != nil {
 			return fmt.Errorf("cannot append needle: %s", err)
 		}
+		if err = newNm.Set(n.Id, ToOffset(newOffset), n.Size); err != nil {
+			return fmt.Errorf("cannot put needle: %s", err)
+		}
 		delta := n.DiskSize(version)
 		newOffset += delta
 		writeThrottler.MaybeSlowdown(delta)
@@ -29,3 +29,49 @@ func (c *MaxVolumeIdCommand) Apply(server raft.Server) (interface{}, error) {
 
 	return nil, nil
 }
+
+// MaintenanceCommand puts a data center, rack or data node into maintenance, or takes it out, on all masters.
+type MaintenanceCommand struct {
+	MaintenanceTarget
+	Enabled bool `json:"enabled"`
+}
+
+func (c *MaintenanceCommand) CommandName() string {
+	return "Maintenance"
+}
+
+func (c *MaintenanceCommand) Apply(server raft.Server) (interface{}, error) {
+	topo := server.Context().(*Topology)
+	topo.SetMaintenance(c.MaintenanceTarget, c.Enabled)
+	return nil, nil
+}
+
+// CollectionSettingsCommand sets the volume size limit and default replication of a collection on all masters.
+type CollectionSettingsCommand struct {
+	CollectionSettings
+}
+
+func (c *CollectionSettingsCommand) CommandName() string {
+	return "CollectionSettings"
+}
+
+func (c *CollectionSettingsCommand) Apply(server raft.Server) (interface{}, error) {
+	topo := server.Context().(*Topology)
+	topo.SetCollectionSettings(c.CollectionSettings)
+	return nil, nil
+}
+
+// VacuumWindowsCommand sets the time windows of the automatic vacuum on all masters.
+type VacuumWindowsCommand struct {
+	Windows []VacuumWindow `json:"windows"`
+}
+
+func (c *VacuumWindowsCommand) CommandName() string {
+	return "VacuumWindows"
+}
+
+func (c *VacuumWindowsCommand) Apply(server raft.Server) (interface{}, error) {
+	topo := server.Context().(*Topology)
+	topo.SetVacuumWindows(c.Windows)
+	return nil, nil
+}
@@ -40,6 +40,13 @@ func (c *Collection) GetOrCreateVolumeLayout(rp *super_block.ReplicaPlacement, t
 	return vl.(*VolumeLayout)
 }
 
+func (c *Collection) setVolumeSizeLimit(volumeSizeLimit uint64) {
+	c.volumeSizeLimit = volumeSizeLimit
+	for _, vl := range c.storageType2VolumeLayout.Items() {
+		vl.(*VolumeLayout).setVolumeSizeLimit(volumeSizeLimit)
+	}
+}
+
 func (c *Collection) Lookup(vid needle.VolumeId) []*DataNode {
 	for _, vl := range c.storageType2VolumeLayout.Items() {
 		if vl != nil {
@@ -0,0 +1,121 @@
+package topology
+
+import (
+	"sort"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// CollectionSettings overrides the master volume size limit, default replication, disk type, compression,
+// garbage threshold and write concern for a collection.
+type CollectionSettings struct {
+	Collection        string  `json:"collection"`
+	VolumeSizeLimitMB uint64  `json:"volumeSizeLimitMB,omitempty"` // 0 for the master default
+	Replication       string  `json:"replication,omitempty"`       // empty for the master default
+	DiskType          string  `json:"diskType,omitempty"`          // e.g. ssd, empty for any disk type
+	Compression       string  `json:"compression,omitempty"`       // gzip, zstd or none, empty for the volume server default
+	GarbageThreshold  float64 `json:"garbageThreshold,omitempty"`  // vacuum the volumes with more garbage than this ratio, 0 for the master default
+	WriteConcern      string  `json:"writeConcern,omitempty"`      // one, quorum or all, empty for all
+
+	// the tiering policy, applied by "volume.tier.apply"
+	TierDest            string  `json:"tierDest,omitempty"` // the storage backend, e.g. s3.default, empty to keep the volumes local
+	TierFullPercent     float64 `json:"tierFullPercent,omitempty"`
+	TierQuietForSeconds int64   `json:"tierQuietForSeconds,omitempty"`
+}
+
+func (s CollectionSettings) isEmpty() bool {
+	return s.VolumeSizeLimitMB == 0 && s.Replication == "" && s.DiskType == "" && s.Compression == "" && s.GarbageThreshold == 0 && s.WriteConcern == "" && s.TierDest == ""
+}
+
+// SetCollectionSettings replaces the settings of the collection, or removes them if empty.
+// The new volume size limit also applies to the existing volumes of the collection.
+func (t *Topology) SetCollectionSettings(settings CollectionSettings) {
+	t.collectionSettingsLock.Lock()
+	if settings.isEmpty() {
+		delete(t.collectionSettings, settings.Collection)
+		glog.V(0).Infof("collection %s uses the master default settings", settings.Collection)
+	} else {
+		t.collectionSettings[settings.Collection] = settings
+		glog.V(0).Infof("collection %s uses volume size limit %dMB replication %s disk type %s compression %s garbage threshold %v write concern %s", settings.Collection, settings.VolumeSizeLimitMB, settings.Replication, settings.DiskType, settings.Compression, settings.GarbageThreshold, settings.WriteConcern)
+	}
+	t.collectionSettingsVersion++
+	t.collectionSettingsLock.Unlock()
+
+	if c, found := t.collectionMap.Find(settings.Collection); found {
+		c.(*Collection).setVolumeSizeLimit(t.GetVolumeSizeLimit(settings.Collection))
+	}
+}
+
+func (t *Topology) GetCollectionSettings(collection string) (settings CollectionSettings, found bool) {
+	t.collectionSettingsLock.RLock()
+	defer t.collectionSettingsLock.RUnlock()
+	settings, found = t.collectionSettings[collection]
+	return
+}
+
+func (t *Topology) ListCollectionSettings() (list []CollectionSettings) {
+	t.collectionSettingsLock.RLock()
+	defer t.collectionSettingsLock.RUnlock()
+	for _, settings := range t.collectionSettings {
+		list = append(list, settings)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Collection < list[j].Collection
+	})
+	return
+}
+
+// GetVolumeSizeLimit returns the volume size limit in bytes of the collection.
+func (t *Topology) GetVolumeSizeLimit(collection string) uint64 {
+	if settings, found := t.GetCollectionSettings(collection); found && settings.VolumeSizeLimitMB > 0 {
+		return settings.VolumeSizeLimitMB * 1024 * 1024
+	}
+	return t.volumeSizeLimit
+}
+
+// GetDefaultReplication returns the default replication of the collection, or empty for the master default.
+func (t *Topology) GetDefaultReplication(collection string) string {
+	settings, _ := t.GetCollectionSettings(collection)
+	return settings.Replication
+}
+
+// GetDefaultDiskType returns the disk type of new volumes of the collection, or empty for any disk type.
+func (t *Topology) GetDefaultDiskType(collection string) string {
+	settings, _ := t.GetCollectionSettings(collection)
+	return settings.DiskType
+}
+
+// GetGarbageThreshold returns the garbage threshold of the collection, or the master default.
+func (t *Topology) GetGarbageThreshold(collection string, defaultThreshold float64) float64 {
+	if settings, found := t.GetCollectionSettings(collection); found && settings.GarbageThreshold > 0 {
+		return settings.GarbageThreshold
+	}
+	return defaultThreshold
+}
+
+// GetCollectionCompressions returns the compression codecs of the collections for the volume servers,
+// and the version of the collection settings to tell whether they changed since the last call.
+func (t *Topology) GetCollectionCompressions() (compressions map[string]string, version int64) {
+	t.collectionSettingsLock.RLock()
+	defer t.collectionSettingsLock.RUnlock()
+	compressions = make(map[string]string)
+	for collection, settings := range t.collectionSettings {
+		if settings.Compression != "" {
+			compressions[collection] = settings.Compression
+		}
+	}
+	return compressions, t.collectionSettingsVersion
+}
+
+// GetCollectionWriteConcerns returns the write concerns of the collections for the volume servers.
+func (t *Topology) GetCollectionWriteConcerns() (writeConcerns map[string]string) {
+	t.collectionSettingsLock.RLock()
+	defer t.collectionSettingsLock.RUnlock()
+	writeConcerns = make(map[string]string)
+	for collection, settings := range t.collectionSettings {
+		if settings.WriteConcern != "" {
+			writeConcerns[collection] = settings.WriteConcern
+		}
+	}
+	return writeConcerns
+}
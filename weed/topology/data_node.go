@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"github.com/chrislusf/seaweedfs/weed/util"
 	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/chrislusf/seaweedfs/weed/pb"
 	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
 	"github.com/chrislusf/seaweedfs/weed/storage/erasure_coding"
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
@@ -16,13 +18,17 @@ import (
 
 type DataNode struct {
 	NodeImpl
-	volumes      map[needle.VolumeId]storage.VolumeInfo
-	Ip           string
-	Port         int
-	PublicUrl    string
-	LastSeen     int64 // unix time in seconds
-	ecShards     map[needle.VolumeId]*erasure_coding.EcVolumeInfo
-	ecShardsLock sync.RWMutex
+	volumes          map[needle.VolumeId]storage.VolumeInfo
+	Ip               string
+	Port             int
+	GrpcPort         int // 0 for the default port + 10000
+	PublicUrl        string
+	Tags             []string         // for the placement rules
+	maxVolumeCounts  map[string]int64 // by disk type, nil if not reported
+	diskSpaceLowDirs []string         // the volume dirs with read only volumes and no free slots
+	LastSeen         int64            // unix time in seconds
+	ecShards         map[needle.VolumeId]*erasure_coding.EcVolumeInfo
+	ecShardsLock     sync.RWMutex
 }
 
 func NewDataNode(id string) *DataNode {
@@ -114,7 +120,12 @@ func (dn *DataNode) DeltaUpdateVolumes(newVolumes, deletedVolumes []storage.Volu
 	dn.Lock()
 	defer dn.Unlock()
 
-	for _, v := range deletedVolumes {
+	for _, deleted := range deletedVolumes {
+		// the same deletion can come from both the delta heartbeat and the volume deletion message
+		v, found := dn.volumes[deleted.Id]
+		if !found {
+			continue
+		}
 		delete(dn.volumes, v.Id)
 		dn.UpAdjustVolumeCountDelta(-1)
 		if v.IsRemote() {
@@ -130,6 +141,23 @@ func (dn *DataNode) DeltaUpdateVolumes(newVolumes, deletedVolumes []storage.Volu
 	return
 }
 
+// UpsertVolumes adds or updates the volumes changed since the last heartbeat, keeping the other volumes.
+func (dn *DataNode) UpsertVolumes(changedVolumes []storage.VolumeInfo) (newVolumes, changeRO []storage.VolumeInfo) {
+	dn.Lock()
+	defer dn.Unlock()
+
+	for _, v := range changedVolumes {
+		isNew, isChangedRO := dn.doAddOrUpdateVolume(v)
+		if isNew {
+			newVolumes = append(newVolumes, v)
+		}
+		if isChangedRO {
+			changeRO = append(changeRO, v)
+		}
+	}
+	return
+}
+
 func (dn *DataNode) GetVolumes() (ret []storage.VolumeInfo) {
 	dn.RLock()
 	for _, v := range dn.volumes {
@@ -175,6 +203,11 @@ func (dn *DataNode) Url() string {
 	return dn.Ip + ":" + strconv.Itoa(dn.Port)
 }
 
+// ServerAddress is the address to reach the volume server with grpc, see pb.NewServerAddress
+func (dn *DataNode) ServerAddress() string {
+	return pb.NewServerAddress(dn.Ip, dn.Port, dn.GrpcPort)
+}
+
 func (dn *DataNode) ToMap() interface{} {
 	ret := make(map[string]interface{})
 	ret["Url"] = dn.Url()
@@ -187,9 +220,34 @@ func (dn *DataNode) ToMap() interface{} {
 	return ret
 }
 
+// SetDiskSpaceLowDirs keeps the volume dirs low on disk space, reported by the full heartbeats.
+func (dn *DataNode) SetDiskSpaceLowDirs(dirs []string) {
+	dn.Lock()
+	defer dn.Unlock()
+	if strings.Join(dn.diskSpaceLowDirs, ",") == strings.Join(dirs, ",") {
+		return
+	}
+	if len(dirs) > 0 {
+		glog.Warningf("volume server %s is low on disk space in %v", dn.Id(), dirs)
+	} else {
+		glog.V(0).Infof("volume server %s has enough disk space again", dn.Id())
+	}
+	dn.diskSpaceLowDirs = dirs
+}
+
+func (dn *DataNode) GetDiskSpaceLowDirs() []string {
+	dn.RLock()
+	defer dn.RUnlock()
+	return dn.diskSpaceLowDirs
+}
+
 func (dn *DataNode) ToDataNodeInfo() *master_pb.DataNodeInfo {
 	m := &master_pb.DataNodeInfo{
-		Id:                string(dn.Id()),
+		Id:                dn.ServerAddress(),
+		GrpcPort:          uint32(dn.GrpcPort),
+		Tags:              dn.Tags,
+		MaxVolumeCounts:   dn.GetMaxVolumeCounts(),
+		DiskSpaceLowDirs:  dn.GetDiskSpaceLowDirs(),
 		VolumeCount:       uint64(dn.GetVolumeCount()),
 		MaxVolumeCount:    uint64(dn.GetMaxVolumeCount()),
 		FreeVolumeCount:   uint64(dn.FreeSpace()),
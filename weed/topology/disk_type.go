@@ -0,0 +1,119 @@
+package topology
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+// SetMaxVolumeCounts sets the max volume count of each disk type, reported by "weed volume -disk".
+func (dn *DataNode) SetMaxVolumeCounts(maxVolumeCounts map[string]uint32) {
+	dn.Lock()
+	defer dn.Unlock()
+	dn.maxVolumeCounts = make(map[string]int64, len(maxVolumeCounts))
+	for diskType, count := range maxVolumeCounts {
+		dn.maxVolumeCounts[storage.ToDiskType(diskType)] = int64(count)
+	}
+}
+
+func (dn *DataNode) GetMaxVolumeCounts() map[string]uint64 {
+	dn.RLock()
+	defer dn.RUnlock()
+	if dn.maxVolumeCounts == nil {
+		// older volume servers do not report the disk types, all the folders are hdd
+		return map[string]uint64{storage.HardDriveType: uint64(dn.GetMaxVolumeCount())}
+	}
+	counts := make(map[string]uint64, len(dn.maxVolumeCounts))
+	for diskType, count := range dn.maxVolumeCounts {
+		counts[diskType] = uint64(count)
+	}
+	return counts
+}
+
+// FreeSpaceOfDiskType is the number of free volume slots of the disk type, or of any disk type if empty.
+func (dn *DataNode) FreeSpaceOfDiskType(diskType string) int64 {
+	free := dn.FreeSpace()
+	if diskType == "" {
+		return free
+	}
+	diskType = storage.ToDiskType(diskType)
+	dn.RLock()
+	defer dn.RUnlock()
+	if dn.maxVolumeCounts == nil {
+		if diskType == storage.HardDriveType {
+			return free
+		}
+		return 0
+	}
+	freeOfDiskType := dn.maxVolumeCounts[diskType]
+	for _, v := range dn.volumes {
+		if v.DiskType == diskType && !v.IsRemote() {
+			freeOfDiskType--
+		}
+	}
+	if freeOfDiskType < free {
+		return freeOfDiskType
+	}
+	return free
+}
+
+// freeSpaceOfDiskType sums the free volume slots of the disk type on the data nodes under the node.
+func freeSpaceOfDiskType(node Node, diskType string) (free int64) {
+	if diskType == "" {
+		return node.FreeSpace()
+	}
+	if node.IsDataNode() {
+		return node.(*DataNode).FreeSpaceOfDiskType(diskType)
+	}
+	for _, child := range node.Children() {
+		if childFree := freeSpaceOfDiskType(child, diskType); childFree > 0 {
+			free += childFree
+		}
+	}
+	return
+}
+
+// reserveOneVolumeOfDiskType picks a data node under the node randomly, weighted by the free volume slots of the disk type.
+func reserveOneVolumeOfDiskType(node Node, diskType string) (*DataNode, error) {
+	if diskType == "" {
+		return node.ReserveOneVolume(rand.Int63n(node.FreeSpace()))
+	}
+	free := freeSpaceOfDiskType(node, diskType)
+	if free <= 0 {
+		return nil, fmt.Errorf("no free %s volume slot under %s", storage.ToDiskType(diskType), node.Id())
+	}
+	return pickDataNodeOfDiskType(node, diskType, rand.Int63n(free))
+}
+
+func pickDataNodeOfDiskType(node Node, diskType string, r int64) (*DataNode, error) {
+	for _, child := range node.Children() {
+		free := freeSpaceOfDiskType(child, diskType)
+		if free <= 0 {
+			continue
+		}
+		if r >= free {
+			r -= free
+			continue
+		}
+		if child.IsDataNode() {
+			return child.(*DataNode), nil
+		}
+		return pickDataNodeOfDiskType(child, diskType, r)
+	}
+	return nil, errors.New("No free volume slot found!")
+}
+
+// checkDiskType returns an error if any server has no free volume slot of the disk type.
+func checkDiskType(diskType string, servers []*DataNode) error {
+	if diskType == "" {
+		return nil
+	}
+	for _, dn := range servers {
+		if dn.FreeSpaceOfDiskType(diskType) < 1 {
+			return fmt.Errorf("%s has no free %s volume slot", dn.Id(), storage.ToDiskType(diskType))
+		}
+	}
+	return nil
+}
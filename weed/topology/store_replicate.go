@@ -18,7 +18,35 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
-func ReplicatedWrite(masterNode string, s *storage.Store, volumeId needle.VolumeId, n *needle.Needle, r *http.Request) (isUnchanged bool, err error) {
+// the write concerns tell when a replicated write is acknowledged
+const (
+	WriteConcernOne    = "one"    // after the first copy is written, and the other replicas are written in the background
+	WriteConcernQuorum = "quorum" // after the majority of the copies are written
+	WriteConcernAll    = "all"    // after all copies are written, the default
+)
+
+func IsValidWriteConcern(writeConcern string) bool {
+	switch writeConcern {
+	case "", WriteConcernOne, WriteConcernQuorum, WriteConcernAll:
+		return true
+	}
+	return false
+}
+
+// requiredCopies is how many of the copies are written before the write is acknowledged.
+func requiredCopies(writeConcern string, copyCount int) int {
+	switch writeConcern {
+	case WriteConcernOne:
+		return 1
+	case WriteConcernQuorum:
+		return copyCount/2 + 1
+	}
+	return copyCount
+}
+
+// ReplicatedWrite writes the needle locally, and to the other replicas if this is the initial request.
+// It returns once the copies required by the write concern are written.
+func ReplicatedWrite(masterNode string, s *storage.Store, volumeId needle.VolumeId, n *needle.Needle, r *http.Request, writeConcern string) (isUnchanged bool, err error) {
 
 	//check JWT
 	jwt := security.GetJwt(r)
@@ -40,8 +68,29 @@ func ReplicatedWrite(masterNode string, s *storage.Store, volumeId needle.Volume
 		fsync = true
 	}
 
+	// the needle is deleted unless confirmed, see storage.Store.EnablePendingNeedles
+	pending := r.FormValue("pending") == "true"
+	if pending {
+		// the filer confirms once acknowledged, so a replica marking the needle pending later would delete it
+		writeConcern = WriteConcernAll
+	}
+
+	localCopies := 0
 	if s.GetVolume(volumeId) != nil {
+		localCopies = 1
+		// recorded before the write, so a needle written is never left without its pending state
+		wasPending := false
+		if pending {
+			if wasPending, err = s.MarkPendingNeedle(volumeId, n.Id); err != nil {
+				err = fmt.Errorf("failed to mark needle pending: %v", err)
+				glog.V(0).Infoln(err)
+				return
+			}
+		}
 		isUnchanged, err = s.WriteVolumeNeedle(volumeId, n, fsync)
+		if pending && !wasPending && (err != nil || isUnchanged) {
+			s.UnmarkPendingNeedle(volumeId, n.Id)
+		}
 		if err != nil {
 			err = fmt.Errorf("failed to write to local disk: %v", err)
 			glog.V(0).Infoln(err)
@@ -50,7 +99,10 @@ func ReplicatedWrite(masterNode string, s *storage.Store, volumeId needle.Volume
 	}
 
 	if len(remoteLocations) > 0 { //send to other replica locations
-		if err = distributedOperation(remoteLocations, s, func(location operation.Location) error {
+		requiredRemoteCopies := requiredCopies(writeConcern, localCopies+len(remoteLocations)) - localCopies
+		if err = acknowledgedOperation(remoteLocations, requiredRemoteCopies, func(host string, err error) {
+			s.RecordReplicationFailure(volumeId, host, err)
+		}, func(location operation.Location) error {
 			u := url.URL{
 				Scheme: "http",
 				Host:   location.Url,
@@ -66,6 +118,9 @@ func ReplicatedWrite(masterNode string, s *storage.Store, volumeId needle.Volume
 			if n.IsChunkedManifest() {
 				q.Set("cm", "true")
 			}
+			if pending {
+				q.Set("pending", "true")
+			}
 			u.RawQuery = q.Encode()
 
 			pairMap := make(map[string]string)
@@ -161,6 +216,44 @@ func distributedOperation(locations []operation.Location, store *storage.Store,
 	return ret.Error()
 }
 
+// acknowledgedOperation returns once the operation succeeds on the required number of locations, or fails on
+// too many locations. The operation goes on in the background on the other locations, with their failures
+// passed to backgroundFailed.
+func acknowledgedOperation(locations []operation.Location, required int, backgroundFailed func(host string, err error), op func(location operation.Location) error) error {
+	if required >= len(locations) {
+		return distributedOperation(locations, nil, op)
+	}
+	results := make(chan RemoteResult, len(locations))
+	for _, location := range locations {
+		go func(location operation.Location) {
+			results <- RemoteResult{location.Url, op(location)}
+		}(location)
+	}
+	ret := DistributedOperationResult(make(map[string]error))
+	succeeded, received := 0, 0
+	for succeeded < required && received-succeeded <= len(locations)-required {
+		result := <-results
+		received++
+		if result.Error != nil {
+			ret[result.Host] = result.Error
+		} else {
+			succeeded++
+		}
+	}
+	go func(pending int) {
+		for i := 0; i < pending; i++ {
+			if result := <-results; result.Error != nil {
+				glog.Errorf("background replication to %s: %v", result.Host, result.Error)
+				backgroundFailed(result.Host, result.Error)
+			}
+		}
+	}(len(locations) - received)
+	if succeeded < required {
+		return ret.Error()
+	}
+	return nil
+}
+
 func getWritableRemoteReplications(s *storage.Store, volumeId needle.VolumeId, masterNode string) (
 	remoteLocations []operation.Location, err error) {
 
@@ -0,0 +1,75 @@
+package topology
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/operation"
+)
+
+func TestAcknowledgedOperation(t *testing.T) {
+	locations := []operation.Location{{Url: "fast"}, {Url: "slow"}, {Url: "broken"}}
+	release := make(chan struct{})
+	ignoreFailure := func(host string, err error) {}
+	op := func(location operation.Location) error {
+		switch location.Url {
+		case "slow":
+			<-release
+		case "broken":
+			return fmt.Errorf("disk full")
+		}
+		return nil
+	}
+
+	// one of the copies, without waiting for the slow replica
+	if err := acknowledgedOperation(locations, 1, ignoreFailure, op); err != nil {
+		t.Errorf("one copy: %v", err)
+	}
+
+	// two of the copies can not succeed before the slow replica does
+	done := make(chan error)
+	go func() {
+		done <- acknowledgedOperation(locations, 2, ignoreFailure, op)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("returned before the slow replica: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("two copies: %v", err)
+	}
+
+	// all copies fail with the broken replica
+	if err := acknowledgedOperation(locations, 3, ignoreFailure, op); err == nil {
+		t.Errorf("all copies should fail")
+	}
+
+	// the failures after the acknowledgement are reported
+	failed := make(chan string, 1)
+	lateFailure := func(location operation.Location) error {
+		if location.Url == "broken" {
+			time.Sleep(10 * time.Millisecond)
+			return fmt.Errorf("disk full")
+		}
+		return nil
+	}
+	if err := acknowledgedOperation([]operation.Location{{Url: "fast"}, {Url: "broken"}}, 1, func(host string, err error) { failed <- host }, lateFailure); err != nil {
+		t.Errorf("one copy with a late failure: %v", err)
+	}
+	select {
+	case host := <-failed:
+		if host != "broken" {
+			t.Errorf("unexpected failed replica %s", host)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("the late failure is not reported")
+	}
+
+	if requiredCopies(WriteConcernOne, 3) != 1 || requiredCopies(WriteConcernQuorum, 3) != 2 ||
+		requiredCopies(WriteConcernQuorum, 2) != 2 || requiredCopies("", 3) != 3 {
+		t.Errorf("unexpected required copies")
+	}
+}
@@ -38,6 +38,21 @@ type Topology struct {
 	Configuration *Configuration
 
 	RaftServer raft.Server
+
+	PlacementPolicy *PlacementPolicy
+
+	// EventListener receives the topology events, set before the master starts
+	EventListener func(event *master_pb.TopologyEvent)
+
+	maintenance     map[MaintenanceTarget]struct{}
+	maintenanceLock sync.RWMutex
+
+	collectionSettings        map[string]CollectionSettings
+	collectionSettingsLock    sync.RWMutex
+	collectionSettingsVersion int64
+
+	vacuumWindows     []VacuumWindow
+	vacuumWindowsLock sync.RWMutex
 }
 
 func NewTopology(id string, seq sequence.Sequencer, volumeSizeLimit uint64, pulse int, replicationAsMin bool) *Topology {
@@ -58,6 +73,9 @@ func NewTopology(id string, seq sequence.Sequencer, volumeSizeLimit uint64, puls
 
 	t.Configuration = &Configuration{}
 
+	t.maintenance = make(map[MaintenanceTarget]struct{})
+	t.collectionSettings = make(map[string]CollectionSettings)
+
 	return t
 }
 
@@ -134,12 +152,15 @@ func (t *Topology) PickForWrite(count uint64, option *VolumeGrowOption) (string,
 		return "", 0, nil, fmt.Errorf("no writable volumes available for collection:%s replication:%s ttl:%s", option.Collection, option.ReplicaPlacement.String(), option.Ttl.String())
 	}
 	fileId := t.Sequence.NextFileId(count)
+	if fileId == 0 {
+		return "", 0, nil, fmt.Errorf("failed to allocate %d file ids", count)
+	}
 	return needle.NewFileId(*vid, fileId, rand.Uint32()).String(), count, datanodes.Head(), nil
 }
 
 func (t *Topology) GetVolumeLayout(collectionName string, rp *super_block.ReplicaPlacement, ttl *needle.TTL) *VolumeLayout {
 	return t.collectionMap.Get(collectionName, func() interface{} {
-		return NewCollection(collectionName, t.volumeSizeLimit, t.replicationAsMin)
+		return NewCollection(collectionName, t.GetVolumeSizeLimit(collectionName), t.replicationAsMin)
 	}).(*Collection).GetOrCreateVolumeLayout(rp, ttl)
 }
 
@@ -178,8 +199,16 @@ func (t *Topology) DeleteCollection(collectionName string) {
 
 func (t *Topology) RegisterVolumeLayout(v storage.VolumeInfo, dn *DataNode) {
 	vl := t.GetVolumeLayout(v.Collection, v.ReplicaPlacement, v.Ttl)
+	wasWritable := vl.IsWritable(v.Id)
 	vl.RegisterVolume(&v, dn)
 	vl.EnsureCorrectWritables(&v)
+	if wasWritable && !vl.IsWritable(v.Id) {
+		if v.ReadOnly {
+			t.notifyVolumeEvent(EventVolumeReadOnly, &v, dn)
+		} else if uint64(v.Size) >= t.GetVolumeSizeLimit(v.Collection) {
+			t.notifyVolumeEvent(EventVolumeFull, &v, dn)
+		}
+	}
 }
 func (t *Topology) UnRegisterVolumeLayout(v storage.VolumeInfo, dn *DataNode) {
 	glog.Infof("removing volume info:%+v", v)
@@ -202,6 +231,21 @@ func (t *Topology) GetOrCreateDataCenter(dcName string) *DataCenter {
 	return dc
 }
 
+// FindDataNode finds the volume server by its ip:port or public url.
+func (t *Topology) FindDataNode(url string) *DataNode {
+	for _, c := range t.Children() {
+		for _, r := range c.Children() {
+			for _, d := range r.Children() {
+				dn := d.(*DataNode)
+				if dn.Url() == url || dn.PublicUrl == url {
+					return dn
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (t *Topology) SyncDataNodeRegistration(volumes []*master_pb.VolumeInformationMessage, dn *DataNode) (newVolumes, deletedVolumes []storage.VolumeInfo) {
 	// convert into in memory struct storage.VolumeInfo
 	var volumeInfos []storage.VolumeInfo
@@ -228,6 +272,27 @@ func (t *Topology) SyncDataNodeRegistration(volumes []*master_pb.VolumeInformati
 	return
 }
 
+// SyncDataNodeChangedVolumes applies the volumes added or changed since the last heartbeat, keeping the other volumes.
+func (t *Topology) SyncDataNodeChangedVolumes(volumes []*master_pb.VolumeInformationMessage, dn *DataNode) (newVolumes []storage.VolumeInfo) {
+	var volumeInfos []storage.VolumeInfo
+	for _, v := range volumes {
+		if vi, err := storage.NewVolumeInfo(v); err == nil {
+			volumeInfos = append(volumeInfos, vi)
+		} else {
+			glog.V(0).Infof("Fail to convert changed volume information: %v", err)
+		}
+	}
+	newVolumes, changedVolumes := dn.UpsertVolumes(volumeInfos)
+	for _, v := range newVolumes {
+		t.RegisterVolumeLayout(v, dn)
+	}
+	for _, v := range changedVolumes {
+		vl := t.GetVolumeLayout(v.Collection, v.ReplicaPlacement, v.Ttl)
+		vl.EnsureCorrectWritables(&v)
+	}
+	return
+}
+
 func (t *Topology) IncrementalSyncDataNodeRegistration(newVolumes, deletedVolumes []*master_pb.VolumeShortInformationMessage, dn *DataNode) {
 	var newVis, oldVis []storage.VolumeInfo
 	for _, v := range newVolumes {
@@ -141,7 +141,7 @@ func (t *Topology) ListEcServersByCollection(collection string) (dataNodes []str
 		if ecVolumeLocation.Collection == collection {
 			for _, locations := range ecVolumeLocation.Locations {
 				for _, loc := range locations {
-					dateNodeMap[string(loc.Id())] = true
+					dateNodeMap[loc.ServerAddress()] = true
 				}
 			}
 		}
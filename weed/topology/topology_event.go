@@ -0,0 +1,54 @@
+package topology
+
+import (
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/pb/master_pb"
+	"github.com/chrislusf/seaweedfs/weed/storage"
+)
+
+// types of the topology events sent to Topology.EventListener
+const (
+	EventVolumeServerUp   = "volume_server_up"
+	EventVolumeServerDown = "volume_server_down"
+	EventVolumeFull       = "volume_full"
+	EventVolumeReadOnly   = "volume_read_only"
+	EventLeaderChange     = "leader_change"
+)
+
+// NotifyEvent sets the event time and passes the event to the EventListener, if any.
+func (t *Topology) NotifyEvent(event *master_pb.TopologyEvent) {
+	if t.EventListener == nil {
+		return
+	}
+	event.TsNs = time.Now().UnixNano()
+	t.EventListener(event)
+}
+
+func (t *Topology) NotifyDataNodeEvent(eventType string, dn *DataNode) {
+	t.NotifyEvent(dataNodeEvent(eventType, dn))
+}
+
+func (t *Topology) notifyVolumeEvent(eventType string, v *storage.VolumeInfo, dn *DataNode) {
+	event := dataNodeEvent(eventType, dn)
+	event.VolumeId = uint32(v.Id)
+	event.Collection = v.Collection
+	t.NotifyEvent(event)
+}
+
+func dataNodeEvent(eventType string, dn *DataNode) *master_pb.TopologyEvent {
+	event := &master_pb.TopologyEvent{
+		Type: eventType,
+	}
+	if dn == nil {
+		return event
+	}
+	event.VolumeServer = dn.Url()
+	if rack := dn.Parent(); rack != nil {
+		event.Rack = string(rack.Id())
+		if dataCenter := rack.Parent(); dataCenter != nil {
+			event.DataCenter = string(dataCenter.Id())
+		}
+	}
+	return event
+}
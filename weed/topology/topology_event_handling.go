@@ -14,16 +14,19 @@ func (t *Topology) StartRefreshWritableVolumes(grpcDialOption grpc.DialOption, g
 		for {
 			if t.IsLeader() {
 				freshThreshHold := time.Now().Unix() - 3*t.pulse //3 times of sleep interval
-				t.CollectDeadNodeAndFullVolumes(freshThreshHold, t.volumeSizeLimit)
+				t.CollectDeadNodeAndFullVolumes(freshThreshHold)
 			}
 			time.Sleep(time.Duration(float32(t.pulse*1e3)*(1+rand.Float32())) * time.Millisecond)
 		}
 	}()
 	go func(garbageThreshold float64) {
-		c := time.Tick(15 * time.Minute)
-		for _ = range c {
-			if t.IsLeader() {
-				t.Vacuum(grpcDialOption, garbageThreshold, preallocate)
+		// check every minute, to start soon after a vacuum window opens
+		lastVacuum := time.Now()
+		c := time.Tick(time.Minute)
+		for now := range c {
+			if t.IsLeader() && now.Sub(lastVacuum) >= 15*time.Minute && t.IsVacuumWindowOpen(now) {
+				t.vacuum(grpcDialOption, garbageThreshold, false, preallocate, t.IsVacuumWindowOpen)
+				lastVacuum = time.Now()
 			}
 		}
 	}(garbageThreshold)
@@ -41,6 +44,7 @@ func (t *Topology) SetVolumeCapacityFull(volumeInfo storage.VolumeInfo) bool {
 	if !vl.SetVolumeCapacityFull(volumeInfo.Id) {
 		return false
 	}
+	t.notifyVolumeEvent(EventVolumeFull, &volumeInfo, nil)
 
 	vl.accessLock.RLock()
 	defer vl.accessLock.RUnlock()
@@ -53,11 +57,15 @@ func (t *Topology) SetVolumeCapacityFull(volumeInfo storage.VolumeInfo) bool {
 	return true
 }
 func (t *Topology) UnRegisterDataNode(dn *DataNode) {
+	t.NotifyDataNodeEvent(EventVolumeServerDown, dn)
 	for _, v := range dn.GetVolumes() {
 		glog.V(0).Infoln("Removing Volume", v.Id, "from the dead volume server", dn.Id())
 		vl := t.GetVolumeLayout(v.Collection, v.ReplicaPlacement, v.Ttl)
 		vl.SetVolumeUnavailable(dn, v.Id)
 	}
+	for _, s := range dn.GetEcShards() {
+		t.UnRegisterEcShards(s, dn)
+	}
 	dn.UpAdjustVolumeCountDelta(-dn.GetVolumeCount())
 	dn.UpAdjustRemoteVolumeCountDelta(-dn.GetRemoteVolumeCount())
 	dn.UpAdjustActiveVolumeCountDelta(-dn.GetActiveVolumeCount())
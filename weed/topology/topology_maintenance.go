@@ -0,0 +1,112 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// MaintenanceTarget is a data center, a rack in it, or one data node, where the master stops assigning file ids,
+// so the machines can be patched or drained without write errors. The volumes there are still readable.
+type MaintenanceTarget struct {
+	DataCenter string `json:"dataCenter,omitempty"`
+	Rack       string `json:"rack,omitempty"`     // empty for the whole data center
+	DataNode   string `json:"dataNode,omitempty"` // only set for one data node, without the data center and rack
+}
+
+func (target MaintenanceTarget) String() string {
+	if target.DataNode != "" {
+		return "data node " + target.DataNode
+	}
+	if target.Rack != "" {
+		return "data center " + target.DataCenter + " rack " + target.Rack
+	}
+	return "data center " + target.DataCenter
+}
+
+func (t *Topology) SetMaintenance(target MaintenanceTarget, enabled bool) {
+	t.maintenanceLock.Lock()
+	defer t.maintenanceLock.Unlock()
+	if enabled {
+		t.maintenance[target] = struct{}{}
+		glog.V(0).Infof("%s enters maintenance", target)
+	} else {
+		delete(t.maintenance, target)
+		glog.V(0).Infof("%s leaves maintenance", target)
+	}
+}
+
+func (t *Topology) ListMaintenance() (targets []MaintenanceTarget) {
+	t.maintenanceLock.RLock()
+	defer t.maintenanceLock.RUnlock()
+	for target := range t.maintenance {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].DataCenter != targets[j].DataCenter {
+			return targets[i].DataCenter < targets[j].DataCenter
+		}
+		if targets[i].Rack != targets[j].Rack {
+			return targets[i].Rack < targets[j].Rack
+		}
+		return targets[i].DataNode < targets[j].DataNode
+	})
+	return
+}
+
+func (t *Topology) IsInMaintenance(dataCenter, rack string) bool {
+	t.maintenanceLock.RLock()
+	defer t.maintenanceLock.RUnlock()
+	if len(t.maintenance) == 0 {
+		return false
+	}
+	if _, found := t.maintenance[MaintenanceTarget{DataCenter: dataCenter}]; found {
+		return true
+	}
+	_, found := t.maintenance[MaintenanceTarget{DataCenter: dataCenter, Rack: rack}]
+	return found
+}
+
+func (t *Topology) IsDataNodeInMaintenance(dataNode string) bool {
+	t.maintenanceLock.RLock()
+	defer t.maintenanceLock.RUnlock()
+	if len(t.maintenance) == 0 {
+		return false
+	}
+	_, found := t.maintenance[MaintenanceTarget{DataNode: dataNode}]
+	return found
+}
+
+func (dn *DataNode) IsInMaintenance() bool {
+	rack := dn.Parent()
+	if rack == nil || rack.Parent() == nil || rack.Parent().Parent() == nil {
+		// already removed from the topology
+		return false
+	}
+	dataCenter := rack.Parent()
+	topo := dataCenter.Parent().GetValue().(*Topology)
+	return topo.IsDataNodeInMaintenance(string(dn.Id())) || topo.IsInMaintenance(string(dataCenter.Id()), string(rack.Id()))
+}
+
+// checkMaintenance returns an error if any server is in maintenance.
+func checkMaintenance(servers []*DataNode) error {
+	for _, dn := range servers {
+		if dn.IsInMaintenance() {
+			return fmt.Errorf("%s is in maintenance", dn.Id())
+		}
+	}
+	return nil
+}
+
+func (dnll *VolumeLocationList) IsInMaintenance() bool {
+	if dnll == nil {
+		return false
+	}
+	for _, dn := range dnll.list {
+		if dn.IsInMaintenance() {
+			return true
+		}
+	}
+	return false
+}
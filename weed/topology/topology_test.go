@@ -8,6 +8,7 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/storage/super_block"
 
 	"testing"
+	"time"
 )
 
 func TestRemoveDataCenter(t *testing.T) {
@@ -174,3 +175,71 @@ func TestAddRemoveVolume(t *testing.T) {
 	}
 
 }
+
+func TestCollectionSettings(t *testing.T) {
+	topo := NewTopology("weedfs", sequence.NewMemorySequencer(), 32*1024*1024, 5, false)
+	rp, _ := super_block.NewReplicaPlacementFromString("000")
+	vl := topo.GetVolumeLayout("thumbnails", rp, needle.EMPTY_TTL)
+
+	topo.SetCollectionSettings(CollectionSettings{Collection: "thumbnails", VolumeSizeLimitMB: 2, Replication: "001"})
+	if vl.volumeSizeLimit != 2*1024*1024 || topo.GetVolumeSizeLimit("thumbnails") != 2*1024*1024 {
+		t.Errorf("volume size limit %d, expected 2MB", vl.volumeSizeLimit)
+	}
+	if topo.GetVolumeSizeLimit("archive") != 32*1024*1024 {
+		t.Errorf("archive volume size limit %d, expected the default", topo.GetVolumeSizeLimit("archive"))
+	}
+	if topo.GetDefaultReplication("thumbnails") != "001" || topo.GetDefaultReplication("archive") != "" {
+		t.Errorf("unexpected default replication")
+	}
+
+	topo.SetCollectionSettings(CollectionSettings{Collection: "logs", GarbageThreshold: 0.1})
+	if topo.GetGarbageThreshold("logs", 0.3) != 0.1 || topo.GetGarbageThreshold("thumbnails", 0.3) != 0.3 {
+		t.Errorf("unexpected garbage threshold")
+	}
+	topo.SetCollectionSettings(CollectionSettings{Collection: "logs"})
+
+	topo.SetCollectionSettings(CollectionSettings{Collection: "thumbnails"})
+	if vl.volumeSizeLimit != 32*1024*1024 || len(topo.ListCollectionSettings()) != 0 {
+		t.Errorf("collection settings are not removed")
+	}
+}
+
+func TestVacuumWindows(t *testing.T) {
+	topo := NewTopology("weedfs", sequence.NewMemorySequencer(), 32*1024*1024, 5, false)
+	at := func(clock string) time.Time {
+		tm, _ := time.ParseInLocation("15:04", clock, time.Local)
+		return tm
+	}
+	if !topo.IsVacuumWindowOpen(at("12:00")) {
+		t.Errorf("vacuum without windows should run at any time")
+	}
+
+	var windows []VacuumWindow
+	for _, w := range []string{"01:00-05:00", "22:00-02:00"} {
+		window, err := ParseVacuumWindow(w)
+		if err != nil {
+			t.Fatalf("parse %s: %v", w, err)
+		}
+		windows = append(windows, window)
+	}
+	topo.SetVacuumWindows(windows)
+	for clock, open := range map[string]bool{
+		"00:30": true,
+		"01:00": true,
+		"04:59": true,
+		"05:00": false,
+		"12:00": false,
+		"21:59": false,
+		"23:00": true,
+	} {
+		if topo.IsVacuumWindowOpen(at(clock)) != open {
+			t.Errorf("vacuum window at %s should be open %v", clock, open)
+		}
+	}
+
+	for _, w := range []string{"01:00", "1am-5am", "25:00-02:00", "03:00-03:00"} {
+		if _, err := ParseVacuumWindow(w); err == nil {
+			t.Errorf("vacuum window %s should be invalid", w)
+		}
+	}
+}
@@ -39,7 +39,7 @@ func batchVacuumVolumeCheck(grpcDialOption grpc.DialOption, vl *VolumeLayout, vi
 			if err != nil {
 				glog.V(0).Infof("Checking vacuuming %d on %s: %v", vid, url, err)
 			}
-		}(index, dn.Url(), vid)
+		}(index, dn.ServerAddress(), vid)
 	}
 	vacuumLocationList := NewVolumeLocationList()
 
@@ -82,7 +82,7 @@ func batchVacuumVolumeCompact(grpcDialOption grpc.DialOption, vl *VolumeLayout,
 				glog.V(0).Infof("Complete vacuuming %d on %s", vid, url)
 				ch <- true
 			}
-		}(index, dn.Url(), vid)
+		}(index, dn.ServerAddress(), vid)
 	}
 	isVacuumSuccess := true
 
@@ -104,7 +104,7 @@ func batchVacuumVolumeCommit(grpcDialOption grpc.DialOption, vl *VolumeLayout, v
 	isReadOnly := false
 	for _, dn := range locationlist.list {
 		glog.V(0).Infoln("Start Committing vacuum", vid, "on", dn.Url())
-		err := operation.WithVolumeServerClient(dn.Url(), grpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
+		err := operation.WithVolumeServerClient(dn.ServerAddress(), grpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
 			resp, err := volumeServerClient.VacuumVolumeCommit(context.Background(), &volume_server_pb.VacuumVolumeCommitRequest{
 				VolumeId: uint32(vid),
 			})
@@ -130,7 +130,7 @@ func batchVacuumVolumeCommit(grpcDialOption grpc.DialOption, vl *VolumeLayout, v
 func batchVacuumVolumeCleanup(grpcDialOption grpc.DialOption, vl *VolumeLayout, vid needle.VolumeId, locationlist *VolumeLocationList) {
 	for _, dn := range locationlist.list {
 		glog.V(0).Infoln("Start cleaning up", vid, "on", dn.Url())
-		err := operation.WithVolumeServerClient(dn.Url(), grpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
+		err := operation.WithVolumeServerClient(dn.ServerAddress(), grpcDialOption, func(volumeServerClient volume_server_pb.VolumeServerClient) error {
 			_, err := volumeServerClient.VacuumVolumeCleanup(context.Background(), &volume_server_pb.VacuumVolumeCleanupRequest{
 				VolumeId: uint32(vid),
 			})
@@ -144,7 +144,14 @@ func batchVacuumVolumeCleanup(grpcDialOption grpc.DialOption, vl *VolumeLayout,
 	}
 }
 
-func (t *Topology) Vacuum(grpcDialOption grpc.DialOption, garbageThreshold float64, preallocate int64) int {
+// Vacuum compacts the volumes with more garbage than the garbage threshold of their collection, or than
+// the given default. With forceThreshold, the given threshold applies to all collections.
+func (t *Topology) Vacuum(grpcDialOption grpc.DialOption, garbageThreshold float64, forceThreshold bool, preallocate int64) int {
+	return t.vacuum(grpcDialOption, garbageThreshold, forceThreshold, preallocate, nil)
+}
+
+// vacuum stops before the next volume once isWindowOpen turns false, if set.
+func (t *Topology) vacuum(grpcDialOption grpc.DialOption, garbageThreshold float64, forceThreshold bool, preallocate int64, isWindowOpen func(time.Time) bool) int {
 
 	// if there is vacuum going on, return immediately
 	swapped := atomic.CompareAndSwapInt64(&t.vacuumLockCounter, 0, 1)
@@ -158,17 +165,24 @@ func (t *Topology) Vacuum(grpcDialOption grpc.DialOption, garbageThreshold float
 	glog.V(1).Infof("Start vacuum on demand with threshold: %f", garbageThreshold)
 	for _, col := range t.collectionMap.Items() {
 		c := col.(*Collection)
+		threshold := garbageThreshold
+		if !forceThreshold {
+			threshold = t.GetGarbageThreshold(c.Name, garbageThreshold)
+		}
 		for _, vl := range c.storageType2VolumeLayout.Items() {
 			if vl != nil {
 				volumeLayout := vl.(*VolumeLayout)
-				vacuumOneVolumeLayout(grpcDialOption, volumeLayout, c, garbageThreshold, preallocate)
+				if !vacuumOneVolumeLayout(grpcDialOption, volumeLayout, c, threshold, preallocate, isWindowOpen) {
+					glog.V(0).Infof("vacuum window closed, stop vacuum")
+					return 0
+				}
 			}
 		}
 	}
 	return 0
 }
 
-func vacuumOneVolumeLayout(grpcDialOption grpc.DialOption, volumeLayout *VolumeLayout, c *Collection, garbageThreshold float64, preallocate int64) {
+func vacuumOneVolumeLayout(grpcDialOption grpc.DialOption, volumeLayout *VolumeLayout, c *Collection, garbageThreshold float64, preallocate int64, isWindowOpen func(time.Time) bool) bool {
 
 	volumeLayout.accessLock.RLock()
 	tmpMap := make(map[needle.VolumeId]*VolumeLocationList)
@@ -179,6 +193,10 @@ func vacuumOneVolumeLayout(grpcDialOption grpc.DialOption, volumeLayout *VolumeL
 
 	for vid, locationList := range tmpMap {
 
+		if isWindowOpen != nil && !isWindowOpen(time.Now()) {
+			return false
+		}
+
 		volumeLayout.accessLock.RLock()
 		isReadOnly := volumeLayout.readonlyVolumes.IsTrue(vid)
 		volumeLayout.accessLock.RUnlock()
@@ -197,4 +215,5 @@ func vacuumOneVolumeLayout(grpcDialOption grpc.DialOption, volumeLayout *VolumeL
 			}
 		}
 	}
+	return true
 }
@@ -0,0 +1,92 @@
+package topology
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+// VacuumWindow is a daily time window in the local time of the master, e.g. 01:00-05:00.
+// It wraps past midnight if the end is before the start, e.g. 22:00-02:00.
+type VacuumWindow string
+
+// ParseVacuumWindow checks the window is in the HH:MM-HH:MM format.
+func ParseVacuumWindow(window string) (VacuumWindow, error) {
+	w := VacuumWindow(strings.TrimSpace(window))
+	if _, _, err := w.bounds(); err != nil {
+		return "", err
+	}
+	return w, nil
+}
+
+func (w VacuumWindow) bounds() (start, end time.Duration, err error) {
+	parts := strings.Split(string(w), "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("vacuum window %q is not in the HH:MM-HH:MM format", w)
+	}
+	if start, err = parseTimeOfDay(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("vacuum window %q: %v", w, err)
+	}
+	if end, err = parseTimeOfDay(parts[1]); err != nil {
+		return 0, 0, fmt.Errorf("vacuum window %q: %v", w, err)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("vacuum window %q is empty", w)
+	}
+	return start, end, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("time of day %q is not in the HH:MM format", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w VacuumWindow) contains(now time.Time) bool {
+	start, end, err := w.bounds()
+	if err != nil {
+		return false
+	}
+	timeOfDay := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+	if start < end {
+		return start <= timeOfDay && timeOfDay < end
+	}
+	return start <= timeOfDay || timeOfDay < end
+}
+
+// SetVacuumWindows limits the automatic vacuum to the windows, or allows it at any time if there are no windows.
+func (t *Topology) SetVacuumWindows(windows []VacuumWindow) {
+	t.vacuumWindowsLock.Lock()
+	defer t.vacuumWindowsLock.Unlock()
+	t.vacuumWindows = windows
+	if len(windows) == 0 {
+		glog.V(0).Infof("vacuum at any time")
+	} else {
+		glog.V(0).Infof("vacuum within %v", windows)
+	}
+}
+
+func (t *Topology) ListVacuumWindows() []VacuumWindow {
+	t.vacuumWindowsLock.RLock()
+	defer t.vacuumWindowsLock.RUnlock()
+	return append([]VacuumWindow(nil), t.vacuumWindows...)
+}
+
+// IsVacuumWindowOpen tells whether the automatic vacuum can run at the time.
+func (t *Topology) IsVacuumWindowOpen(now time.Time) bool {
+	t.vacuumWindowsLock.RLock()
+	defer t.vacuumWindowsLock.RUnlock()
+	if len(t.vacuumWindows) == 0 {
+		return true
+	}
+	for _, w := range t.vacuumWindows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
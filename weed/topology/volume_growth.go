@@ -2,7 +2,6 @@ package topology
 
 import (
 	"fmt"
-	"math/rand"
 	"sync"
 
 	"github.com/chrislusf/seaweedfs/weed/storage/needle"
@@ -32,6 +31,7 @@ type VolumeGrowOption struct {
 	Rack               string
 	DataNode           string
 	MemoryMapMaxSizeMb uint32
+	DiskType           string // e.g. hdd or ssd, empty for any disk type
 }
 
 type VolumeGrowth struct {
@@ -39,7 +39,7 @@ type VolumeGrowth struct {
 }
 
 func (o *VolumeGrowOption) String() string {
-	return fmt.Sprintf("Collection:%s, ReplicaPlacement:%v, Ttl:%v, DataCenter:%s, Rack:%s, DataNode:%s", o.Collection, o.ReplicaPlacement, o.Ttl, o.DataCenter, o.Rack, o.DataNode)
+	return fmt.Sprintf("Collection:%s, ReplicaPlacement:%v, Ttl:%v, DataCenter:%s, Rack:%s, DataNode:%s, DiskType:%s", o.Collection, o.ReplicaPlacement, o.Ttl, o.DataCenter, o.Rack, o.DataNode, o.DiskType)
 }
 
 func NewDefaultVolumeGrowth() *VolumeGrowth {
@@ -78,31 +78,74 @@ func (vg *VolumeGrowth) AutomaticGrowByType(option *VolumeGrowOption, grpcDialOp
 	return count, err
 }
 func (vg *VolumeGrowth) GrowByCountAndType(grpcDialOption grpc.DialOption, targetCount int, option *VolumeGrowOption, topo *Topology) (counter int, err error) {
+	counter, _, err = vg.GrowVolumes(grpcDialOption, targetCount, option, topo)
+	return
+}
+
+// GrowVolumes creates targetCount logical volumes, and returns the number of volume replicas
+// and the ids of the volumes created.
+func (vg *VolumeGrowth) GrowVolumes(grpcDialOption grpc.DialOption, targetCount int, option *VolumeGrowOption, topo *Topology) (counter int, vids []needle.VolumeId, err error) {
 	vg.accessLock.Lock()
 	defer vg.accessLock.Unlock()
 
 	for i := 0; i < targetCount; i++ {
-		if c, e := vg.findAndGrow(grpcDialOption, topo, option); e == nil {
+		if vid, c, e := vg.findAndGrow(grpcDialOption, topo, option); e == nil {
 			counter += c
+			vids = append(vids, vid)
 		} else {
 			glog.V(0).Infof("create %d volume, created %d: %v", targetCount, counter, e)
-			return counter, e
+			return counter, vids, e
 		}
 	}
 	return
 }
 
-func (vg *VolumeGrowth) findAndGrow(grpcDialOption grpc.DialOption, topo *Topology, option *VolumeGrowOption) (int, error) {
+func (vg *VolumeGrowth) findAndGrow(grpcDialOption grpc.DialOption, topo *Topology, option *VolumeGrowOption) (needle.VolumeId, int, error) {
 	servers, e := vg.findEmptySlotsForOneVolume(topo, option)
 	if e != nil {
-		return 0, e
+		return 0, 0, e
 	}
 	vid, raftErr := topo.NextVolumeId()
 	if raftErr != nil {
-		return 0, raftErr
+		return 0, 0, raftErr
 	}
 	err := vg.grow(grpcDialOption, topo, vid, option, servers...)
-	return len(servers), err
+	return vid, len(servers), err
+}
+
+// findEmptySlotsForOneVolume picks the servers randomly until they all have a free slot of the disk type,
+// are not in maintenance, satisfy the placement rules, and the preferred tags if possible.
+func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *VolumeGrowOption) (servers []*DataNode, err error) {
+	rules := topo.PlacementPolicy.RulesFor(option.Collection)
+	if len(rules) == 0 && option.DiskType == "" && len(topo.ListMaintenance()) == 0 {
+		return vg.pickEmptySlotsForOneVolume(topo, option)
+	}
+	var fallback []*DataNode
+	var violation error
+	for i := 0; i < placementAttempts; i++ {
+		if servers, err = vg.pickEmptySlotsForOneVolume(topo, option); err != nil {
+			return nil, err
+		}
+		if violation = checkMaintenance(servers); violation != nil {
+			continue
+		}
+		if violation = checkDiskType(option.DiskType, servers); violation != nil {
+			continue
+		}
+		if violation = checkPlacement(rules, servers); violation != nil {
+			continue
+		}
+		if isPreferredPlacement(rules, servers) {
+			return servers, nil
+		}
+		if fallback == nil {
+			fallback = servers
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("no servers out of maintenance satisfy the disk type and placement rules in %d attempts, last: %v", placementAttempts, violation)
 }
 
 // 1. find the main data node
@@ -110,24 +153,30 @@ func (vg *VolumeGrowth) findAndGrow(grpcDialOption grpc.DialOption, topo *Topolo
 // 2.2 collect all racks that have rp.SameRackCount+1
 // 2.2 collect all data centers that have DiffRackCount+rp.SameRackCount+1
 // 2. find rest data nodes
-func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *VolumeGrowOption) (servers []*DataNode, err error) {
+func (vg *VolumeGrowth) pickEmptySlotsForOneVolume(topo *Topology, option *VolumeGrowOption) (servers []*DataNode, err error) {
 	//find main datacenter and other data centers
 	rp := option.ReplicaPlacement
 	mainDataCenter, otherDataCenters, dc_err := topo.PickNodesByWeight(rp.DiffDataCenterCount+1, func(node Node) error {
 		if option.DataCenter != "" && node.IsDataCenter() && node.Id() != NodeId(option.DataCenter) {
 			return fmt.Errorf("Not matching preferred data center:%s", option.DataCenter)
 		}
+		if topo.IsInMaintenance(string(node.Id()), "") {
+			return fmt.Errorf("Data center %s is in maintenance", node.Id())
+		}
 		if len(node.Children()) < rp.DiffRackCount+1 {
 			return fmt.Errorf("Only has %d racks, not enough for %d.", len(node.Children()), rp.DiffRackCount+1)
 		}
-		if node.FreeSpace() < int64(rp.DiffRackCount+rp.SameRackCount+1) {
-			return fmt.Errorf("Free:%d < Expected:%d", node.FreeSpace(), rp.DiffRackCount+rp.SameRackCount+1)
+		if free := freeSpaceOfDiskType(node, option.DiskType); free < int64(rp.DiffRackCount+rp.SameRackCount+1) {
+			return fmt.Errorf("Free:%d < Expected:%d", free, rp.DiffRackCount+rp.SameRackCount+1)
 		}
 		possibleRacksCount := 0
 		for _, rack := range node.Children() {
+			if topo.IsInMaintenance(string(node.Id()), string(rack.Id())) {
+				continue
+			}
 			possibleDataNodesCount := 0
 			for _, n := range rack.Children() {
-				if n.FreeSpace() >= 1 {
+				if freeSpaceOfDiskType(n, option.DiskType) >= 1 && !topo.IsDataNodeInMaintenance(string(n.Id())) {
 					possibleDataNodesCount++
 				}
 			}
@@ -149,8 +198,11 @@ func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *Volum
 		if option.Rack != "" && node.IsRack() && node.Id() != NodeId(option.Rack) {
 			return fmt.Errorf("Not matching preferred rack:%s", option.Rack)
 		}
-		if node.FreeSpace() < int64(rp.SameRackCount+1) {
-			return fmt.Errorf("Free:%d < Expected:%d", node.FreeSpace(), rp.SameRackCount+1)
+		if topo.IsInMaintenance(string(mainDataCenter.Id()), string(node.Id())) {
+			return fmt.Errorf("Rack %s is in maintenance", node.Id())
+		}
+		if free := freeSpaceOfDiskType(node, option.DiskType); free < int64(rp.SameRackCount+1) {
+			return fmt.Errorf("Free:%d < Expected:%d", free, rp.SameRackCount+1)
 		}
 		if len(node.Children()) < rp.SameRackCount+1 {
 			// a bit faster way to test free racks
@@ -158,7 +210,7 @@ func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *Volum
 		}
 		possibleDataNodesCount := 0
 		for _, n := range node.Children() {
-			if n.FreeSpace() >= 1 {
+			if freeSpaceOfDiskType(n, option.DiskType) >= 1 && !topo.IsDataNodeInMaintenance(string(n.Id())) {
 				possibleDataNodesCount++
 			}
 		}
@@ -176,8 +228,11 @@ func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *Volum
 		if option.DataNode != "" && node.IsDataNode() && node.Id() != NodeId(option.DataNode) {
 			return fmt.Errorf("Not matching preferred data node:%s", option.DataNode)
 		}
-		if node.FreeSpace() < 1 {
-			return fmt.Errorf("Free:%d < Expected:%d", node.FreeSpace(), 1)
+		if topo.IsDataNodeInMaintenance(string(node.Id())) {
+			return fmt.Errorf("Data node %s is in maintenance", node.Id())
+		}
+		if free := freeSpaceOfDiskType(node, option.DiskType); free < 1 {
+			return fmt.Errorf("Free:%d < Expected:%d", free, 1)
 		}
 		return nil
 	})
@@ -190,16 +245,14 @@ func (vg *VolumeGrowth) findEmptySlotsForOneVolume(topo *Topology, option *Volum
 		servers = append(servers, server.(*DataNode))
 	}
 	for _, rack := range otherRacks {
-		r := rand.Int63n(rack.FreeSpace())
-		if server, e := rack.ReserveOneVolume(r); e == nil {
+		if server, e := reserveOneVolumeOfDiskType(rack, option.DiskType); e == nil {
 			servers = append(servers, server)
 		} else {
 			return servers, e
 		}
 	}
 	for _, datacenter := range otherDataCenters {
-		r := rand.Int63n(datacenter.FreeSpace())
-		if server, e := datacenter.ReserveOneVolume(r); e == nil {
+		if server, e := reserveOneVolumeOfDiskType(datacenter, option.DiskType); e == nil {
 			servers = append(servers, server)
 		} else {
 			return servers, e
@@ -219,6 +272,10 @@ func (vg *VolumeGrowth) grow(grpcDialOption grpc.DialOption, topo *Topology, vid
 				Ttl:              option.Ttl,
 				Version:          needle.CurrentVersion,
 			}
+			if option.DiskType != "" {
+				// otherwise the volume server reports the disk type it picked
+				vi.DiskType = storage.ToDiskType(option.DiskType)
+			}
 			server.AddOrUpdateVolume(vi)
 			topo.RegisterVolumeLayout(vi, server)
 			glog.V(0).Infoln("Created Volume", vid, "on", server.NodeImpl.String())
@@ -340,3 +340,159 @@ func TestFindEmptySlotsForOneVolumeScheduleByWeight(t *testing.T) {
 		fmt.Printf("%s : %d\n", k, v)
 	}
 }
+
+func TestFindEmptySlotsOnTargetServer(t *testing.T) {
+	topo := setup(topologyLayout)
+	vg := NewDefaultVolumeGrowth()
+	rp, _ := super_block.NewReplicaPlacementFromString("000")
+
+	for _, dc := range topo.Children() {
+		for _, rack := range dc.Children() {
+			for _, n := range rack.Children() {
+				dn := n.(*DataNode)
+				if dn.FreeSpace() < 1 {
+					continue
+				}
+				dn.Ip, dn.Port = "127.0.0.1", 8080
+				if found := topo.FindDataNode(dn.Url()); found != dn {
+					t.Fatalf("find %s: %v", dn.Url(), found)
+				}
+				servers, err := vg.findEmptySlotsForOneVolume(topo, &VolumeGrowOption{
+					ReplicaPlacement: rp,
+					DataCenter:       string(dn.GetDataCenter().Id()),
+					Rack:             string(dn.GetRack().Id()),
+					DataNode:         string(dn.Id()),
+				})
+				if err != nil {
+					t.Fatalf("grow on %s: %v", dn.Id(), err)
+				}
+				if servers[0] != dn {
+					t.Errorf("grow on %s, assigned to %s", dn.Id(), servers[0].Id())
+				}
+				dn.Ip, dn.Port = "", 0
+			}
+		}
+	}
+}
+
+func TestFindEmptySlotsOutsideOfMaintenance(t *testing.T) {
+	topo := setup(topologyLayout)
+	vg := NewDefaultVolumeGrowth()
+	rp, _ := super_block.NewReplicaPlacementFromString("000")
+	volumeGrowOption := &VolumeGrowOption{
+		ReplicaPlacement: rp,
+	}
+
+	// a data node in maintenance is neither the main server nor a replica in another rack
+	topo.SetMaintenance(MaintenanceTarget{DataNode: "server121"}, true)
+	diffRack, _ := super_block.NewReplicaPlacementFromString("010")
+	for i := 0; i < 100; i++ {
+		servers, err := vg.findEmptySlotsForOneVolume(topo, &VolumeGrowOption{ReplicaPlacement: diffRack})
+		if err != nil {
+			t.Fatalf("finding empty slots: %v", err)
+		}
+		for _, server := range servers {
+			if server.Id() == "server121" {
+				t.Fatalf("assigned to server121 in maintenance")
+			}
+		}
+	}
+	topo.SetMaintenance(MaintenanceTarget{DataNode: "server121"}, false)
+
+	topo.SetMaintenance(MaintenanceTarget{DataCenter: "dc1", Rack: "rack1"}, true)
+	for i := 0; i < 100; i++ {
+		servers, err := vg.findEmptySlotsForOneVolume(topo, volumeGrowOption)
+		if err != nil {
+			t.Fatalf("finding empty slots: %v", err)
+		}
+		if servers[0].IsInMaintenance() || servers[0].GetRack().Id() == "rack1" {
+			t.Fatalf("assigned to %s in rack1 in maintenance", servers[0].Id())
+		}
+	}
+
+	topo.SetMaintenance(MaintenanceTarget{DataCenter: "dc1"}, true)
+	for i := 0; i < 100; i++ {
+		servers, err := vg.findEmptySlotsForOneVolume(topo, volumeGrowOption)
+		if err != nil {
+			t.Fatalf("finding empty slots: %v", err)
+		}
+		if servers[0].GetDataCenter().Id() != "dc3" {
+			t.Fatalf("assigned to %s in dc1 in maintenance", servers[0].Id())
+		}
+	}
+}
+
+func TestFindEmptySlotsWithPlacementRules(t *testing.T) {
+	topo := setup(topologyLayout)
+	vg := NewDefaultVolumeGrowth()
+	rp, _ := super_block.NewReplicaPlacementFromString("001")
+	volumeGrowOption := &VolumeGrowOption{
+		ReplicaPlacement: rp,
+	}
+
+	tags := map[NodeId][]string{
+		"server112": {"ssd"},
+		"server121": {"ssd", "chassis=c2"},
+		"server122": {"ssd", "chassis=c1"},
+		"server123": {"ssd", "chassis=c1"},
+	}
+	for _, dc := range topo.Children() {
+		for _, rack := range dc.Children() {
+			for _, n := range rack.Children() {
+				n.(*DataNode).Tags = tags[n.Id()]
+			}
+		}
+	}
+	topo.PlacementPolicy = &PlacementPolicy{Rules: []*PlacementRule{
+		{Name: "ssd", RequireTags: []string{"ssd"}, DistinctTag: "chassis"},
+	}}
+
+	for i := 0; i < 100; i++ {
+		servers, err := vg.findEmptySlotsForOneVolume(topo, volumeGrowOption)
+		if err != nil {
+			t.Fatalf("finding empty slots: %v", err)
+		}
+		if len(servers) != 2 || (servers[0].Id() != "server121" && servers[1].Id() != "server121") {
+			t.Fatalf("assigned to %v, expecting server121 with server122 or server123", servers)
+		}
+	}
+}
+
+func TestFindEmptySlotsOfDiskType(t *testing.T) {
+	topo := setup(topologyLayout)
+	vg := NewDefaultVolumeGrowth()
+	rp, _ := super_block.NewReplicaPlacementFromString("001")
+	volumeGrowOption := &VolumeGrowOption{
+		ReplicaPlacement: rp,
+		DiskType:         "ssd",
+	}
+
+	for _, dc := range topo.Children() {
+		for _, rack := range dc.Children() {
+			for _, n := range rack.Children() {
+				switch n.Id() {
+				case "server122":
+					n.(*DataNode).SetMaxVolumeCounts(map[string]uint32{"hdd": 2, "ssd": 2})
+				case "server123":
+					n.(*DataNode).SetMaxVolumeCounts(map[string]uint32{"hdd": 4, "ssd": 1})
+				}
+			}
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		servers, err := vg.findEmptySlotsForOneVolume(topo, volumeGrowOption)
+		if err != nil {
+			t.Fatalf("finding empty slots: %v", err)
+		}
+		if len(servers) != 2 || servers[0].GetRack().Id() != "rack2" || servers[0].Id() == servers[1].Id() ||
+			servers[0].FreeSpaceOfDiskType("ssd") < 1 || servers[1].FreeSpaceOfDiskType("ssd") < 1 {
+			t.Fatalf("assigned to %v, expecting server122 and server123", servers)
+		}
+	}
+
+	volumeGrowOption.DiskType = "nvme"
+	if servers, err := vg.findEmptySlotsForOneVolume(topo, volumeGrowOption); err == nil {
+		t.Fatalf("assigned to %v without any nvme disks", servers)
+	}
+}
@@ -104,6 +104,7 @@ type VolumeLayout struct {
 	rp               *super_block.ReplicaPlacement
 	ttl              *needle.TTL
 	vid2location     map[needle.VolumeId]*VolumeLocationList
+	diskTypes        map[needle.VolumeId]string
 	writables        []needle.VolumeId   // transient array of writable volume id
 	readonlyVolumes  *volumesBinaryState // readonly volumes
 	oversizedVolumes *volumesBinaryState // oversized volumes
@@ -123,6 +124,7 @@ func NewVolumeLayout(rp *super_block.ReplicaPlacement, ttl *needle.TTL, volumeSi
 		rp:               rp,
 		ttl:              ttl,
 		vid2location:     make(map[needle.VolumeId]*VolumeLocationList),
+		diskTypes:        make(map[needle.VolumeId]string),
 		writables:        *new([]needle.VolumeId),
 		readonlyVolumes:  NewVolumesBinaryState(readOnlyState, rp, ExistCopies()),
 		oversizedVolumes: NewVolumesBinaryState(oversizedState, rp, ExistCopies()),
@@ -147,6 +149,7 @@ func (vl *VolumeLayout) RegisterVolume(v *storage.VolumeInfo, dn *DataNode) {
 		vl.vid2location[v.Id] = NewVolumeLocationList()
 	}
 	vl.vid2location[v.Id].Set(dn)
+	vl.diskTypes[v.Id] = v.DiskType
 	// glog.V(4).Infof("volume %d added to %s len %d copy %d", v.Id, dn.Id(), vl.vid2location[v.Id].Length(), v.ReplicaPlacement.GetCopyCount())
 	for _, dn := range vl.vid2location[v.Id].list {
 		if vInfo, err := dn.GetVolumesById(v.Id); err == nil {
@@ -194,6 +197,7 @@ func (vl *VolumeLayout) UnRegisterVolume(v *storage.VolumeInfo, dn *DataNode) {
 
 		if location.Length() == 0 {
 			delete(vl.vid2location, v.Id)
+			delete(vl.diskTypes, v.Id)
 		}
 
 	}
@@ -227,6 +231,12 @@ func (vl *VolumeLayout) isAllWritable(vid needle.VolumeId) bool {
 	return true
 }
 
+func (vl *VolumeLayout) setVolumeSizeLimit(volumeSizeLimit uint64) {
+	vl.accessLock.Lock()
+	defer vl.accessLock.Unlock()
+	vl.volumeSizeLimit = volumeSizeLimit
+}
+
 func (vl *VolumeLayout) isOversized(v *storage.VolumeInfo) bool {
 	return uint64(v.Size) >= vl.volumeSizeLimit
 }
@@ -273,19 +283,31 @@ func (vl *VolumeLayout) PickForWrite(count uint64, option *VolumeGrowOption) (*n
 		glog.V(0).Infoln("No more writable volumes!")
 		return nil, 0, nil, errors.New("No more writable volumes!")
 	}
-	if option.DataCenter == "" {
+	if option.DataCenter == "" && option.DiskType == "" {
 		vid := vl.writables[rand.Intn(lenWriters)]
 		locationList := vl.vid2location[vid]
-		if locationList != nil {
+		if locationList == nil {
+			return nil, 0, nil, errors.New("Strangely vid " + vid.String() + " is on no machine!")
+		}
+		if !locationList.IsInMaintenance() {
 			return &vid, count, locationList, nil
 		}
-		return nil, 0, nil, errors.New("Strangely vid " + vid.String() + " is on no machine!")
 	}
 	var vid needle.VolumeId
 	var locationList *VolumeLocationList
 	counter := 0
 	for _, v := range vl.writables {
 		volumeLocationList := vl.vid2location[v]
+		if volumeLocationList.IsInMaintenance() || !vl.isOnDiskType(v, option.DiskType) {
+			continue
+		}
+		if option.DataCenter == "" {
+			counter++
+			if rand.Intn(counter) < 1 {
+				vid, locationList = v, volumeLocationList
+			}
+			continue
+		}
 		for _, dn := range volumeLocationList.list {
 			if dn.GetDataCenter().Id() == NodeId(option.DataCenter) {
 				if option.Rack != "" && dn.GetRack().Id() != NodeId(option.Rack) {
@@ -308,11 +330,15 @@ func (vl *VolumeLayout) GetActiveVolumeCount(option *VolumeGrowOption) int {
 	vl.accessLock.RLock()
 	defer vl.accessLock.RUnlock()
 
-	if option.DataCenter == "" {
-		return len(vl.writables)
-	}
 	counter := 0
 	for _, v := range vl.writables {
+		if vl.vid2location[v].IsInMaintenance() || !vl.isOnDiskType(v, option.DiskType) {
+			continue
+		}
+		if option.DataCenter == "" {
+			counter++
+			continue
+		}
 		for _, dn := range vl.vid2location[v].list {
 			if dn.GetDataCenter().Id() == NodeId(option.DataCenter) {
 				if option.Rack != "" && dn.GetRack().Id() != NodeId(option.Rack) {
@@ -328,6 +354,22 @@ func (vl *VolumeLayout) GetActiveVolumeCount(option *VolumeGrowOption) int {
 	return counter
 }
 
+// isOnDiskType checks the disk type of the volume, any disk type if empty.
+func (vl *VolumeLayout) isOnDiskType(vid needle.VolumeId, diskType string) bool {
+	return diskType == "" || vl.diskTypes[vid] == storage.ToDiskType(diskType)
+}
+
+func (vl *VolumeLayout) IsWritable(vid needle.VolumeId) bool {
+	vl.accessLock.RLock()
+	defer vl.accessLock.RUnlock()
+	for _, v := range vl.writables {
+		if v == vid {
+			return true
+		}
+	}
+	return false
+}
+
 func (vl *VolumeLayout) removeFromWritable(vid needle.VolumeId) bool {
 	toDeleteIndex := -1
 	for k, id := range vl.writables {
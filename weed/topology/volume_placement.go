@@ -0,0 +1,147 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+const placementPolicyPrefix = "master.placement"
+
+// placementAttempts is how many random picks of servers are checked against the placement rules
+const placementAttempts = 100
+
+// PlacementRule constrains the servers for the replicas of new volumes, in addition to the replication.
+// Servers are matched by the tags set with "weed volume -tags", e.g. ssd or chassis=c7.
+type PlacementRule struct {
+	Name        string
+	Collection  string   // empty for all collections
+	RequireTags []string // only use servers with all these tags
+	ExcludeTags []string // never use servers with any of these tags
+	PreferTags  []string // prefer servers with all these tags, but fall back to the other servers
+	DistinctTag string   // never place two replicas on servers with the same <DistinctTag>=<value> tag
+}
+
+type PlacementPolicy struct {
+	Rules []*PlacementRule
+}
+
+// LoadPlacementPolicy reads the enabled [master.placement.<name>] rules.
+func LoadPlacementPolicy(config *viper.Viper) (*PlacementPolicy, error) {
+	policy := &PlacementPolicy{}
+	for name := range config.GetStringMap(placementPolicyPrefix) {
+		prefix := placementPolicyPrefix + "." + name + "."
+		if !config.GetBool(prefix + "enabled") {
+			continue
+		}
+		rule := &PlacementRule{
+			Name:        name,
+			Collection:  config.GetString(prefix + "collection"),
+			RequireTags: config.GetStringSlice(prefix + "require_tags"),
+			ExcludeTags: config.GetStringSlice(prefix + "exclude_tags"),
+			PreferTags:  config.GetStringSlice(prefix + "prefer_tags"),
+			DistinctTag: config.GetString(prefix + "distinct_tag"),
+		}
+		if strings.Contains(rule.DistinctTag, "=") {
+			return nil, fmt.Errorf("placement rule %s: distinct_tag %s should be a tag name without =", name, rule.DistinctTag)
+		}
+		glog.V(0).Infof("placement rule %s: %+v", name, *rule)
+		policy.Rules = append(policy.Rules, rule)
+	}
+	sort.Slice(policy.Rules, func(i, j int) bool {
+		return policy.Rules[i].Name < policy.Rules[j].Name
+	})
+	return policy, nil
+}
+
+// RulesFor returns the rules for the collection, including the rules for all collections.
+func (p *PlacementPolicy) RulesFor(collection string) (rules []*PlacementRule) {
+	if p == nil {
+		return nil
+	}
+	for _, rule := range p.Rules {
+		if rule.Collection == "" || rule.Collection == collection {
+			rules = append(rules, rule)
+		}
+	}
+	return
+}
+
+// check returns an error if the servers break the rule.
+func (rule *PlacementRule) check(servers []*DataNode) error {
+	distinctValues := make(map[string]*DataNode)
+	for _, dn := range servers {
+		for _, tag := range rule.RequireTags {
+			if !dn.HasTag(tag) {
+				return fmt.Errorf("rule %s: %s has no tag %s", rule.Name, dn.Id(), tag)
+			}
+		}
+		for _, tag := range rule.ExcludeTags {
+			if dn.HasTag(tag) {
+				return fmt.Errorf("rule %s: %s has tag %s", rule.Name, dn.Id(), tag)
+			}
+		}
+		if rule.DistinctTag == "" {
+			continue
+		}
+		if value, found := dn.TagValue(rule.DistinctTag); found {
+			if other, conflict := distinctValues[value]; conflict {
+				return fmt.Errorf("rule %s: %s and %s have the same tag %s=%s", rule.Name, other.Id(), dn.Id(), rule.DistinctTag, value)
+			}
+			distinctValues[value] = dn
+		}
+	}
+	return nil
+}
+
+func (rule *PlacementRule) isPreferred(servers []*DataNode) bool {
+	for _, dn := range servers {
+		for _, tag := range rule.PreferTags {
+			if !dn.HasTag(tag) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func checkPlacement(rules []*PlacementRule, servers []*DataNode) error {
+	for _, rule := range rules {
+		if err := rule.check(servers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isPreferredPlacement(rules []*PlacementRule, servers []*DataNode) bool {
+	for _, rule := range rules {
+		if !rule.isPreferred(servers) {
+			return false
+		}
+	}
+	return true
+}
+
+func (dn *DataNode) HasTag(tag string) bool {
+	for _, t := range dn.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TagValue returns the value of the <name>=<value> tag.
+func (dn *DataNode) TagValue(name string) (value string, found bool) {
+	for _, t := range dn.Tags {
+		if strings.HasPrefix(t, name+"=") {
+			return t[len(name)+1:], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,183 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+const (
+	exportBatchSize     = 512
+	exportQueueSize     = 4096
+	exportFlushInterval = 5 * time.Second
+)
+
+// Exporter sends finished spans in batches. Spans are dropped if the queue is full.
+type Exporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+	queue       chan *Span
+}
+
+func NewExporter(otlpEndpoint, serviceName string) *Exporter {
+	url := strings.TrimSuffix(otlpEndpoint, "/")
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "http://" + url
+	}
+	return &Exporter{
+		url:         url + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Span, exportQueueSize),
+	}
+}
+
+func (e *Exporter) Export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		glog.V(1).Infof("trace export queue is full, dropping span %s", span.Name)
+	}
+}
+
+func (e *Exporter) Loop() {
+	ticker := time.NewTicker(exportFlushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := e.Send(batch); err != nil {
+			glog.V(0).Infof("export %d spans to %s: %v", len(batch), e.url, err)
+		}
+		batch = nil
+	}
+}
+
+func (e *Exporter) Send(spans []*Span) error {
+	body, err := json.Marshal(e.toOtlp(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
+
+// the OTLP json encoding, see opentelemetry-proto/opentelemetry/proto/collector/trace/v1
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *Exporter) toOtlp(spans []*Span) *otlpRequest {
+	var otlpSpans []otlpSpan
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceId:           hex.EncodeToString(span.Context.TraceId[:]),
+			SpanId:            hex.EncodeToString(span.Context.SpanId[:]),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        toOtlpAttributes(span.Attributes),
+		}
+		if span.ParentSpanId != [8]byte{} {
+			s.ParentSpanId = hex.EncodeToString(span.ParentSpanId[:])
+		}
+		if span.Error != "" {
+			s.Status = otlpStatus{Code: 2, Message: span.Error}
+		}
+		otlpSpans = append(otlpSpans, s)
+	}
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: toOtlpAttributes(map[string]string{"service.name": e.serviceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "seaweedfs"},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+}
+
+func toOtlpAttributes(attributes map[string]string) (kvs []otlpKeyValue) {
+	for k, v := range attributes {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpValue{StringValue: v}})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return
+}
@@ -0,0 +1,253 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/chrislusf/seaweedfs/weed/glog"
+)
+
+/*
+Distributed tracing across filer, master and volume servers.
+
+The span context is carried in the W3C "traceparent" header on http requests,
+and in the "traceparent" metadata on grpc calls:
+	00-<32 hex trace id>-<16 hex parent span id>-<2 hex flags>
+
+Finished spans are batched and exported with OTLP over http, in the json encoding,
+to <endpoint>/v1/traces. When no endpoint is configured, no spans are created
+and all Span methods are no-ops on the nil span.
+*/
+
+const TraceparentHeader = "traceparent"
+
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+)
+
+type SpanContext struct {
+	TraceId [16]byte
+	SpanId  [8]byte
+	Flags   byte
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceId != [16]byte{} && sc.SpanId != [8]byte{}
+}
+
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(sc.TraceId[:]), hex.EncodeToString(sc.SpanId[:]), sc.Flags)
+}
+
+// ParseTraceparent parses a version 00 traceparent value.
+func ParseTraceparent(traceparent string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceId[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanId[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	return sc, sc.IsValid()
+}
+
+type Span struct {
+	Name         string
+	Kind         int
+	Context      SpanContext
+	ParentSpanId [8]byte
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+	Error        string
+
+	lock  sync.Mutex
+	ended bool
+}
+
+type spanKey struct{}
+
+var exporter *Exporter
+
+// Configure starts exporting spans to the OTLP http endpoint. Tracing is off if the endpoint is empty.
+func Configure(otlpEndpoint, serviceName string) {
+	if otlpEndpoint == "" {
+		return
+	}
+	exporter = NewExporter(otlpEndpoint, serviceName)
+	go exporter.Loop()
+	glog.V(0).Infof("exporting traces of %s to %s", serviceName, otlpEndpoint)
+}
+
+func IsEnabled() bool {
+	return exporter != nil
+}
+
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// StartSpan starts a child of the span in ctx, or a new root span.
+func StartSpan(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if !IsEnabled() {
+		return ctx, nil
+	}
+	var parent SpanContext
+	if parentSpan := FromContext(ctx); parentSpan != nil {
+		parent = parentSpan.Context
+	}
+	span := newSpan(name, kind, parent)
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartRemoteSpan starts a child of the caller's span in the traceparent, or a new root span.
+func StartRemoteSpan(ctx context.Context, traceparent string, name string) (context.Context, *Span) {
+	if !IsEnabled() {
+		return ctx, nil
+	}
+	parent, _ := ParseTraceparent(traceparent)
+	span := newSpan(name, SpanKindServer, parent)
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartRequestSpan starts a server span for the http request, continuing the caller's trace if any.
+func StartRequestSpan(ctx context.Context, r *http.Request, name string) (context.Context, *Span) {
+	ctx, span := StartRemoteSpan(ctx, r.Header.Get(TraceparentHeader), name)
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.Path)
+	return ctx, span
+}
+
+// StartGrpcSpan starts a server span for the grpc call, continuing the caller's trace if any.
+func StartGrpcSpan(ctx context.Context, name string) (context.Context, *Span) {
+	if !IsEnabled() {
+		return ctx, nil
+	}
+	var traceparent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(TraceparentHeader); len(values) > 0 {
+			traceparent = values[0]
+		}
+	}
+	return StartRemoteSpan(ctx, traceparent, name)
+}
+
+// OutgoingGrpcContext passes the traceparent to the grpc server.
+func OutgoingGrpcContext(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, TraceparentHeader, traceparent)
+}
+
+func newSpan(name string, kind int, parent SpanContext) *Span {
+	span := &Span{
+		Name:  name,
+		Kind:  kind,
+		Start: time.Now(),
+	}
+	if parent.IsValid() {
+		span.Context.TraceId = parent.TraceId
+		span.ParentSpanId = parent.SpanId
+	} else {
+		rand.Read(span.Context.TraceId[:])
+	}
+	rand.Read(span.Context.SpanId[:])
+	span.Context.Flags = 0x01
+	return span
+}
+
+// Traceparent is the header value to pass this span as the parent to another server.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return s.Context.Traceparent()
+}
+
+func (s *Span) TraceId() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.Context.TraceId[:])
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]string)
+	}
+	s.Attributes[key] = value
+	s.lock.Unlock()
+}
+
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	s.Error = err.Error()
+	s.lock.Unlock()
+}
+
+// Finish ends the span and queues it for export. Only the first call has effect.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.lock.Unlock()
+	if exporter != nil {
+		exporter.Export(s)
+	}
+}
+
+// Observe records the value, with the trace id of the span as an exemplar if the span is traced.
+func Observe(observer prometheus.Observer, value float64, span *Span) {
+	if span != nil {
+		if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+			exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{"trace_id": span.TraceId()})
+			return
+		}
+	}
+	observer.Observe(value)
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatalf("failed to parse traceparent")
+	}
+	if sc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("unexpected round trip %s", sc.Traceparent())
+	}
+
+	for _, bad := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceparent(bad); ok {
+			t.Errorf("should not parse %q", bad)
+		}
+	}
+}
+
+func TestSpanPropagationAndExport(t *testing.T) {
+
+	var received otlpRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("unmarshal: %v", err)
+		}
+	}))
+	defer collector.Close()
+
+	exporter = NewExporter(collector.URL, "filer")
+	defer func() { exporter = nil }()
+
+	ctx, root := StartSpan(context.Background(), "filer.upload", SpanKindServer)
+	_, child := StartSpan(ctx, "filer.assign", SpanKindClient)
+
+	// the volume server continues the trace from the header
+	r := httptest.NewRequest("POST", "/3,01637037d6", nil)
+	r.Header.Set(TraceparentHeader, child.Traceparent())
+	_, remote := StartRequestSpan(context.Background(), r, "volume.write")
+
+	if remote.TraceId() != root.TraceId() || child.TraceId() != root.TraceId() {
+		t.Errorf("trace ids differ: %s %s %s", root.TraceId(), child.TraceId(), remote.TraceId())
+	}
+	if remote.ParentSpanId != child.Context.SpanId || child.ParentSpanId != root.Context.SpanId {
+		t.Errorf("unexpected parent span ids")
+	}
+
+	remote.Finish()
+	child.Finish()
+	root.Finish()
+	root.Finish()
+
+	var spans []*Span
+	for len(exporter.queue) > 0 {
+		spans = append(spans, <-exporter.queue)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	if err := exporter.Send(spans); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(received.ResourceSpans) != 1 || len(received.ResourceSpans[0].ScopeSpans[0].Spans) != 3 {
+		t.Fatalf("unexpected export %+v", received)
+	}
+	if received.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "filer" {
+		t.Errorf("unexpected resource %+v", received.ResourceSpans[0].Resource)
+	}
+}
+
+func TestDisabledSpansAreNoop(t *testing.T) {
+	ctx, span := StartSpan(context.Background(), "noop", SpanKindInternal)
+	if span != nil || FromContext(ctx) != nil {
+		t.Fatalf("expected no span when tracing is disabled")
+	}
+	span.SetAttribute("k", "v")
+	span.SetError(nil)
+	span.Finish()
+	if span.Traceparent() != "" {
+		t.Errorf("nil span should have no traceparent")
+	}
+}
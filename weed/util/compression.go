@@ -9,13 +9,20 @@ import (
 	"strings"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
-	// "github.com/klauspost/compress/zstd"
+	"github.com/klauspost/compress/zstd"
 )
 
 var (
 	UnsupportedCompression = fmt.Errorf("unsupported compression")
 )
 
+// the compression codecs of the stored needles
+const (
+	GzipCompression = "gzip"
+	ZstdCompression = "zstd"
+	NoCompression   = "none"
+)
+
 func MaybeGzipData(input []byte) []byte {
 	if IsGzippedContent(input) {
 		return input
@@ -55,16 +62,33 @@ func GzipData(input []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// CompressData compresses the input with the codec, gzip or zstd.
+func CompressData(input []byte, codec string) ([]byte, error) {
+	switch codec {
+	case GzipCompression, "":
+		return GzipData(input)
+	case ZstdCompression:
+		return ZstdData(input)
+	}
+	return nil, fmt.Errorf("%s: %v", codec, UnsupportedCompression)
+}
+
+// IsValidCompression checks the codec name of the -compression options.
+func IsValidCompression(codec string) bool {
+	switch codec {
+	case GzipCompression, ZstdCompression, NoCompression, "":
+		return true
+	}
+	return false
+}
 
 func DecompressData(input []byte) ([]byte, error) {
 	if IsGzippedContent(input) {
 		return ungzipData(input)
 	}
-	/*
 	if IsZstdContent(input) {
 		return unzstdData(input)
 	}
-	*/
 	return input, UnsupportedCompression
 }
 
@@ -86,7 +110,6 @@ func IsGzippedContent(data []byte) bool {
 	return data[0] == 31 && data[1] == 139
 }
 
-/*
 var zstdEncoder, _ = zstd.NewWriter(nil)
 
 func ZstdData(input []byte) ([]byte, error) {
@@ -105,7 +128,6 @@ func IsZstdContent(data []byte) bool {
 	}
 	return data[3] == 0xFD && data[2] == 0x2F && data[1] == 0xB5 && data[0] == 0x28
 }
-*/
 
 /*
 * Default not to compressed since compression can be done on client side.
@@ -145,6 +167,9 @@ func IsZstdContent(data []byte) bool {
 		if strings.HasSuffix(mtype, "xml") {
 			return true, true
 		}
+		if strings.HasSuffix(mtype, "json") {
+			return true, true
+		}
 		if strings.HasSuffix(mtype, "script") {
 			return true, true
 		}
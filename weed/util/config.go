@@ -8,6 +8,9 @@ import (
 	"github.com/chrislusf/seaweedfs/weed/glog"
 )
 
+// ConfigurationFileDirectory, if set by the global -config flag, is searched before the default locations.
+var ConfigurationFileDirectory string
+
 type Configuration interface {
 	GetString(key string) string
 	GetBool(key string) bool
@@ -19,7 +22,10 @@ type Configuration interface {
 func LoadConfiguration(configFileName string, required bool) (loaded bool) {
 
 	// find a filer store
-	viper.SetConfigName(configFileName)     // name of config file (without extension)
+	viper.SetConfigName(configFileName) // name of config file (without extension)
+	if ConfigurationFileDirectory != "" {
+		viper.AddConfigPath(ConfigurationFileDirectory)
+	}
 	viper.AddConfigPath(".")                // optionally look for config in the working directory
 	viper.AddConfigPath("$HOME/.seaweedfs") // call multiple times to add many search paths
 	viper.AddConfigPath("/etc/seaweedfs/")  // path to look for the config file in
@@ -33,10 +39,14 @@ func LoadConfiguration(configFileName string, required bool) (loaded bool) {
 		}
 		glog.V(logLevel).Infof("Reading %s: %v", viper.ConfigFileUsed(), err)
 		if required {
-			glog.Fatalf("Failed to load %s.toml file from current directory, or $HOME/.seaweedfs/, or /etc/seaweedfs/"+
+			searchLocations := "current directory, or $HOME/.seaweedfs/, or /etc/seaweedfs/"
+			if ConfigurationFileDirectory != "" {
+				searchLocations = ConfigurationFileDirectory + ", or " + searchLocations
+			}
+			glog.Fatalf("Failed to load %s.toml file from %s"+
 				"\n\nPlease use this command to generate the default %s.toml file\n"+
 				"    weed scaffold -config=%s -output=.\n\n\n",
-				configFileName, configFileName, configFileName)
+				configFileName, searchLocations, configFileName, configFileName)
 		} else {
 			return false
 		}
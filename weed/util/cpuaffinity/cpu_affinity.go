@@ -0,0 +1,39 @@
+package cpuaffinity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseCpuList parses a cpu list like "0,1,2,3" or "0-3,8-11" into cpu ids.
+func ParseCpuList(cpuList string) (cpus []int, err error) {
+	for _, part := range strings.Split(cpuList, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last := part, part
+		if i := strings.Index(part, "-"); i > 0 {
+			first, last = part[:i], part[i+1:]
+		}
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu %q in %q", first, cpuList)
+		}
+		to, err := strconv.Atoi(last)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu %q in %q", last, cpuList)
+		}
+		if from < 0 || to < from {
+			return nil, fmt.Errorf("invalid cpu range %q in %q", part, cpuList)
+		}
+		for cpu := from; cpu <= to; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	if len(cpus) == 0 {
+		return nil, fmt.Errorf("empty cpu list %q", cpuList)
+	}
+	return cpus, nil
+}
@@ -0,0 +1,34 @@
+package cpuaffinity
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// Set pins all threads of the current process to the given cpus.
+// Threads created later by the Go runtime inherit the affinity.
+func Set(cpus []int) error {
+	var set unix.CPUSet
+	set.Zero()
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+
+	tasks, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return unix.SchedSetaffinity(0, &set)
+	}
+	for _, task := range tasks {
+		tid, parseErr := strconv.Atoi(task.Name())
+		if parseErr != nil {
+			continue
+		}
+		if err = unix.SchedSetaffinity(tid, &set); err != nil && err != unix.ESRCH {
+			return fmt.Errorf("set affinity of thread %d: %v", tid, err)
+		}
+	}
+	return nil
+}
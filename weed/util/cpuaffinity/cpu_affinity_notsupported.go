@@ -0,0 +1,12 @@
+// +build !linux
+
+package cpuaffinity
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func Set(cpus []int) error {
+	return fmt.Errorf("cpu affinity is not supported on %s", runtime.GOOS)
+}
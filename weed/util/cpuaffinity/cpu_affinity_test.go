@@ -0,0 +1,29 @@
+package cpuaffinity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCpuList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+	}{
+		{"0,1,2,3", []int{0, 1, 2, 3}},
+		{"0-3", []int{0, 1, 2, 3}},
+		{"0-1, 8-9,16", []int{0, 1, 8, 9, 16}},
+	}
+	for _, tt := range tests {
+		got, err := ParseCpuList(tt.in)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCpuList(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "a", "3-1", "-1", "1-"} {
+		if _, err := ParseCpuList(in); err == nil {
+			t.Errorf("ParseCpuList(%q) should fail", in)
+		}
+	}
+}
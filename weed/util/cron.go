@@ -0,0 +1,103 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a standard 5 field cron expression: minute hour day-of-month month day-of-week.
+// Each field is "*", a number, a range "a-b", a list "a,b", optionally with a step "*/n" or "a-b/n".
+// As in cron, if both day-of-month and day-of-week are restricted, either one matches.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// sunday is both 0 and 7
+var cronFieldRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+func ParseCron(spec string) (*CronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expect 5 fields, found %d", spec, len(fields))
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		var err error
+		if bits[i], err = parseCronField(field, cronFieldRanges[i][0], cronFieldRanges[i][1]); err != nil {
+			return nil, fmt.Errorf("cron %q: %v", spec, err)
+		}
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &CronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (bits uint64, err error) {
+	if field == "*" || strings.HasPrefix(field, "*/") {
+		field = fmt.Sprintf("%d-%d%s", min, max, strings.TrimPrefix(field, "*"))
+	}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if slash := strings.Index(part, "/"); slash >= 0 {
+			if step, err = strconv.Atoi(part[slash+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:slash]
+		}
+		low, high := part, part
+		if dash := strings.Index(part, "-"); dash >= 0 {
+			low, high = part[:dash], part[dash+1:]
+		}
+		start, startErr := strconv.Atoi(low)
+		end, endErr := strconv.Atoi(high)
+		if startErr != nil || endErr != nil || start < min || end > max || start > end {
+			return 0, fmt.Errorf("invalid %q, expect values in [%d, %d]", part, min, max)
+		}
+		for x := start; x <= end; x += step {
+			bits |= 1 << uint(x)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first matching minute after t, or a zero time if there is none within 5 years.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) || c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *CronSchedule) matchesDay(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// a wednesday
+	now := time.Date(2021, 3, 10, 14, 30, 15, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2021, 3, 10, 14, 31, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2021, 3, 11, 2, 0, 0, 0, time.UTC)},
+		{"*/20 1-3 * * *", time.Date(2021, 3, 11, 1, 0, 0, 0, time.UTC)},
+		{"45 14,16 * * *", time.Date(2021, 3, 10, 14, 45, 0, 0, time.UTC)},
+		{"0 3 * * 0", time.Date(2021, 3, 14, 3, 0, 0, 0, time.UTC)},
+		{"0 3 * * 7", time.Date(2021, 3, 14, 3, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := c.Next(now); !got.Equal(tt.want) {
+			t.Errorf("%q: next %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q): expect an error", spec)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadDownwardApiLabels reads the pod labels from a kubernetes downward API volume file,
+// which has one label per line in the form of key="value".
+func ReadDownwardApiLabels(fileName string) (map[string]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	labels := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		eqIndex := strings.Index(line, "=")
+		if eqIndex <= 0 {
+			continue
+		}
+		value := line[eqIndex+1:]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		labels[line[:eqIndex]] = value
+	}
+	return labels, scanner.Err()
+}
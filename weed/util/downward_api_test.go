@@ -0,0 +1,25 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReadDownwardApiLabels(t *testing.T) {
+	f, err := ioutil.TempFile("", "labels")
+	if err != nil {
+		t.Fatalf("temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("app=\"seaweedfs\"\ntopology.kubernetes.io/zone=\"us-east-1a\"\nnode=\"ip-10-0-1-2\\\"x\"\n\n")
+	f.Close()
+
+	labels, err := ReadDownwardApiLabels(f.Name())
+	if err != nil {
+		t.Fatalf("read labels: %v", err)
+	}
+	if len(labels) != 3 || labels["topology.kubernetes.io/zone"] != "us-east-1a" || labels["node"] != `ip-10-0-1-2"x` {
+		t.Errorf("unexpected labels %v", labels)
+	}
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -257,6 +258,11 @@ func ReadUrl(fileUrl string, cipherKey []byte, isContentCompressed bool, isFullC
 }
 
 func ReadUrlAsStream(fileUrl string, cipherKey []byte, isContentGzipped bool, isFullChunk bool, offset int64, size int, fn func(data []byte)) (retryable bool, err error) {
+	return ReadUrlAsStreamWithContext(context.Background(), fileUrl, cipherKey, isContentGzipped, isFullChunk, offset, size, fn)
+}
+
+// ReadUrlAsStreamWithContext is ReadUrlAsStream that stops reading unencrypted content once ctx is cancelled.
+func ReadUrlAsStreamWithContext(ctx context.Context, fileUrl string, cipherKey []byte, isContentGzipped bool, isFullChunk bool, offset int64, size int, fn func(data []byte)) (retryable bool, err error) {
 
 	if cipherKey != nil {
 		return readEncryptedUrl(fileUrl, cipherKey, isContentGzipped, isFullChunk, offset, size, fn)
@@ -266,6 +272,7 @@ func ReadUrlAsStream(fileUrl string, cipherKey []byte, isContentGzipped bool, is
 	if err != nil {
 		return false, err
 	}
+	req = req.WithContext(ctx)
 
 	if isFullChunk {
 		req.Header.Add("Accept-Encoding", "gzip")
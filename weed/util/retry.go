@@ -25,6 +25,8 @@ func Retry(name string, job func() error) (err error) {
 			glog.V(0).Infof("retry %s", name)
 			time.Sleep(waitTime)
 			waitTime += waitTime / 2
+		} else {
+			break
 		}
 	}
 	return err
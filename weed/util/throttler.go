@@ -1,6 +1,14 @@
 package util
 
-import "time"
+import (
+	"sync"
+	"time"
+)
+
+// Throttler slows down the caller to keep the processed bytes under a speed limit.
+type Throttler interface {
+	MaybeSlowdown(delta int64)
+}
 
 type WriteThrottler struct {
 	compactionBytePerSecond int64
@@ -32,3 +40,58 @@ func (wt *WriteThrottler) MaybeSlowdown(delta int64) {
 		}
 	}
 }
+
+// BandwidthLimiter is a token bucket shared by concurrent transfers, to keep their total speed under a limit.
+// The limit can be changed while the transfers are running.
+type BandwidthLimiter struct {
+	sync.Mutex
+	bytesPerSecond int64
+	tokens         float64
+	lastRefillTime time.Time
+}
+
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	return &BandwidthLimiter{
+		bytesPerSecond: bytesPerSecond,
+		lastRefillTime: time.Now(),
+	}
+}
+
+// SetLimit changes the limit, 0 for no limit.
+func (bl *BandwidthLimiter) SetLimit(bytesPerSecond int64) {
+	bl.Lock()
+	defer bl.Unlock()
+	bl.bytesPerSecond = bytesPerSecond
+	bl.tokens, bl.lastRefillTime = 0, time.Now()
+}
+
+func (bl *BandwidthLimiter) Limit() int64 {
+	bl.Lock()
+	defer bl.Unlock()
+	return bl.bytesPerSecond
+}
+
+// MaybeSlowdown takes delta bytes from the bucket, and sleeps until the bucket is refilled for them.
+// The concurrent callers queue up, since each one also waits for the bytes taken before it.
+func (bl *BandwidthLimiter) MaybeSlowdown(delta int64) {
+	bl.Lock()
+	if bl.bytesPerSecond <= 0 {
+		bl.Unlock()
+		return
+	}
+	now := time.Now()
+	bl.tokens += now.Sub(bl.lastRefillTime).Seconds() * float64(bl.bytesPerSecond)
+	// allow a burst of 100 milliseconds
+	if burst := float64(bl.bytesPerSecond) / 10; bl.tokens > burst {
+		bl.tokens = burst
+	}
+	bl.lastRefillTime = now
+	bl.tokens -= float64(delta)
+	var sleepTime time.Duration
+	if bl.tokens < 0 {
+		sleepTime = time.Duration(-bl.tokens / float64(bl.bytesPerSecond) * float64(time.Second))
+	}
+	bl.Unlock()
+
+	time.Sleep(sleepTime)
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBandwidthLimiterSharedByTransfers(t *testing.T) {
+	limiter := NewBandwidthLimiter(10 * 1024 * 1024)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 16; j++ {
+				limiter.MaybeSlowdown(64 * 1024)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 4MB in total at 10MB/s, less the burst of 1MB
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("4MB took %v under the 10MB/s limit", elapsed)
+	}
+
+	limiter.SetLimit(0)
+	start = time.Now()
+	limiter.MaybeSlowdown(100 * 1024 * 1024)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unlimited transfer took %v", elapsed)
+	}
+}
@@ -14,12 +14,14 @@ import (
 )
 
 type MasterClient struct {
-	clientType     string
-	clientHost     string
-	grpcPort       uint32
-	currentMaster  string
-	masters        []string
-	grpcDialOption grpc.DialOption
+	clientType string
+	clientHost string
+	grpcPort   uint32
+	// the grpc port advertised to the master, if it is not grpcPort + 10000
+	advertisedGrpcPort uint32
+	currentMaster      string
+	masters            []string
+	grpcDialOption     grpc.DialOption
 
 	vidMap
 }
@@ -35,6 +37,10 @@ func NewMasterClient(grpcDialOption grpc.DialOption, clientType string, clientHo
 	}
 }
 
+func (mc *MasterClient) SetAdvertisedGrpcPort(grpcPort uint32) {
+	mc.advertisedGrpcPort = grpcPort
+}
+
 func (mc *MasterClient) GetMaster() string {
 	return mc.currentMaster
 }
@@ -106,7 +112,7 @@ func (mc *MasterClient) tryConnectToMaster(master string) (nextHintedLeader stri
 			return err
 		}
 
-		if err = stream.Send(&master_pb.KeepConnectedRequest{Name: mc.clientType, GrpcPort: mc.grpcPort}); err != nil {
+		if err = stream.Send(&master_pb.KeepConnectedRequest{Name: mc.clientType, GrpcPort: mc.grpcPort, AdvertisedGrpcPort: mc.advertisedGrpcPort}); err != nil {
 			glog.V(0).Infof("%s masterClient failed to send to %s: %v", mc.clientType, master, err)
 			return err
 		}
@@ -133,6 +139,7 @@ func (mc *MasterClient) tryConnectToMaster(master string) (nextHintedLeader stri
 				Url:        volumeLocation.Url,
 				PublicUrl:  volumeLocation.PublicUrl,
 				DataCenter: volumeLocation.DataCenter,
+				GrpcPort:   int(volumeLocation.GrpcPort),
 			}
 			for _, newVid := range volumeLocation.NewVids {
 				glog.V(1).Infof("%s: %s masterClient adds volume %d", mc.clientType, loc.Url, newVid)
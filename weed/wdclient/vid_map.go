@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/pb"
 )
 
 const (
@@ -19,6 +20,12 @@ type Location struct {
 	Url        string `json:"url,omitempty"`
 	PublicUrl  string `json:"publicUrl,omitempty"`
 	DataCenter string `json:"dataCenter,omitempty"`
+	GrpcPort   int    `json:"grpcPort,omitempty"`
+}
+
+// ServerAddress is the address to reach the volume server with grpc
+func (l Location) ServerAddress() string {
+	return pb.NewServerAddressWithGrpcPort(l.Url, l.GrpcPort)
 }
 
 type vidMap struct {
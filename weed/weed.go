@@ -18,6 +18,7 @@ import (
 
 	"github.com/chrislusf/seaweedfs/weed/command"
 	"github.com/chrislusf/seaweedfs/weed/glog"
+	"github.com/chrislusf/seaweedfs/weed/util"
 )
 
 var IsDebug *bool
@@ -39,6 +40,7 @@ func main() {
 	glog.MaxSize = 1024 * 1024 * 32
 	rand.Seed(time.Now().UnixNano())
 	flag.Usage = usage
+	flag.StringVar(&util.ConfigurationFileDirectory, "config", "", "directory to search first for configuration files, e.g. security.toml and filer.toml")
 	flag.Parse()
 
 	args := flag.Args()